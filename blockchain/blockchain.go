@@ -295,8 +295,8 @@ func (self *Blockchain) Send(
 	}
 }
 
-func (self *Blockchain) SetImbalanceStepFunction(token ethereum.Address, xBuy []*big.Int, yBuy []*big.Int, xSell []*big.Int, ySell []*big.Int) (*types.Transaction, error) {
-	opts, err := self.GetTxOpts(PRICING_OP, nil, nil, nil)
+func (self *Blockchain) SetImbalanceStepFunction(token ethereum.Address, xBuy []*big.Int, yBuy []*big.Int, xSell []*big.Int, ySell []*big.Int, nonce *big.Int, gasPrice *big.Int) (*types.Transaction, error) {
+	opts, err := self.GetTxOpts(PRICING_OP, nonce, gasPrice, nil)
 	if err != nil {
 		log.Printf("Getting transaction opts failed, err: %s", err)
 		return nil, err
@@ -309,8 +309,8 @@ func (self *Blockchain) SetImbalanceStepFunction(token ethereum.Address, xBuy []
 	}
 }
 
-func (self *Blockchain) SetQtyStepFunction(token ethereum.Address, xBuy []*big.Int, yBuy []*big.Int, xSell []*big.Int, ySell []*big.Int) (*types.Transaction, error) {
-	opts, err := self.GetTxOpts(PRICING_OP, nil, nil, nil)
+func (self *Blockchain) SetQtyStepFunction(token ethereum.Address, xBuy []*big.Int, yBuy []*big.Int, xSell []*big.Int, ySell []*big.Int, nonce *big.Int, gasPrice *big.Int) (*types.Transaction, error) {
+	opts, err := self.GetTxOpts(PRICING_OP, nonce, gasPrice, nil)
 	if err != nil {
 		log.Printf("Getting transaction opts failed, err: %s", err)
 		return nil, err
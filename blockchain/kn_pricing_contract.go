@@ -40,3 +40,54 @@ func (self *Blockchain) GeneratedGetRate(opts blockchain.CallOpts, token ethereu
 	err := self.Call(timeOut, opts, self.pricing, out, "getRate", token, currentBlockNumber, buy, qty)
 	return out, err
 }
+
+func (self *Blockchain) GeneratedGetStepFunctionData(opts blockchain.CallOpts, token ethereum.Address, command *big.Int, param *big.Int) (*big.Int, error) {
+	timeOut := 2 * time.Second
+	out := big.NewInt(0)
+	err := self.Call(timeOut, opts, self.pricing, out, "getStepFunctionData", token, command, param)
+	return out, err
+}
+
+func (self *Blockchain) GeneratedGetTokenBasicData(opts blockchain.CallOpts, token ethereum.Address) (bool, bool, error) {
+	var (
+		ret0 = new(bool)
+		ret1 = new(bool)
+	)
+	out := &[]interface{}{
+		ret0,
+		ret1,
+	}
+	timeOut := 2 * time.Second
+	err := self.Call(timeOut, opts, self.pricing, out, "getTokenBasicData", token)
+	return *ret0, *ret1, err
+}
+
+func (self *Blockchain) GeneratedGetTokenControlInfo(opts blockchain.CallOpts, token ethereum.Address) (*big.Int, *big.Int, *big.Int, error) {
+	var (
+		ret0 = new(*big.Int)
+		ret1 = new(*big.Int)
+		ret2 = new(*big.Int)
+	)
+	out := &[]interface{}{
+		ret0,
+		ret1,
+		ret2,
+	}
+	timeOut := 2 * time.Second
+	err := self.Call(timeOut, opts, self.pricing, out, "getTokenControlInfo", token)
+	return *ret0, *ret1, *ret2, err
+}
+
+func (self *Blockchain) GeneratedValidRateDurationInBlocks(opts blockchain.CallOpts) (*big.Int, error) {
+	timeOut := 2 * time.Second
+	out := big.NewInt(0)
+	err := self.Call(timeOut, opts, self.pricing, out, "validRateDurationInBlocks")
+	return out, err
+}
+
+func (self *Blockchain) GeneratedTokenImbalanceData(opts blockchain.CallOpts, token ethereum.Address, index *big.Int) (*big.Int, error) {
+	timeOut := 2 * time.Second
+	out := big.NewInt(0)
+	err := self.Call(timeOut, opts, self.pricing, out, "tokenImbalanceData", token, index)
+	return out, err
+}
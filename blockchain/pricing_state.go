@@ -0,0 +1,118 @@
+package blockchain
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/common/blockchain"
+	ethereum "github.com/ethereum/go-ethereum/common"
+)
+
+// step function commands of getStepFunctionData, each step function uses 4 consecutive commands:
+// length of x, x[param], length of y, y[param].
+// https://github.com/KyberNetwork/smart-contracts/blob/fed8e09dc6e4365e1597474d9b3f53634eb405d2/contracts/ConversionRates.sol#L185
+const (
+	buyQtyStepFunctionCommand        int64 = 0
+	sellQtyStepFunctionCommand       int64 = 4
+	buyImbalanceStepFunctionCommand  int64 = 8
+	sellImbalanceStepFunctionCommand int64 = 12
+
+	// imbalanceWindowSize is the number of tokenImbalanceData records of a token.
+	imbalanceWindowSize = 5
+)
+
+func (self *Blockchain) getStepFunctionValues(opts blockchain.CallOpts, token ethereum.Address, lenCommand int64) ([]*big.Int, error) {
+	length, err := self.GeneratedGetStepFunctionData(opts, token, big.NewInt(lenCommand), Big0)
+	if err != nil {
+		return nil, err
+	}
+	result := []*big.Int{}
+	for i := int64(0); i < length.Int64(); i++ {
+		value, err := self.GeneratedGetStepFunctionData(opts, token, big.NewInt(lenCommand+1), big.NewInt(i))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+// getStepFunction reads the step function starting at given command from pricing contract.
+func (self *Blockchain) getStepFunction(opts blockchain.CallOpts, token ethereum.Address, command int64) (common.StepFunction, error) {
+	x, err := self.getStepFunctionValues(opts, token, command)
+	if err != nil {
+		return common.StepFunction{}, err
+	}
+	y, err := self.getStepFunctionValues(opts, token, command+2)
+	if err != nil {
+		return common.StepFunction{}, err
+	}
+	return common.NewStepFunction(x, y), nil
+}
+
+// GetTokenPricingState returns all data pricing contract uses to calculate rate
+// of given token at given block. Block 0 means the latest state.
+func (self *Blockchain) GetTokenPricingState(token common.Token, atBlock uint64) (common.TokenPricingState, error) {
+	var (
+		result = common.TokenPricingState{Decimal: token.Decimal}
+		opts   = self.GetCallOpts(atBlock)
+		addr   = ethereum.HexToAddress(token.Address)
+		err    error
+	)
+	if token.IsETH() {
+		return result, fmt.Errorf("%s is not listed in pricing contract", token.ID)
+	}
+	if result.Listed, result.Enabled, err = self.GeneratedGetTokenBasicData(opts, addr); err != nil {
+		return result, err
+	}
+	baseBuys, baseSells, compactBuys, compactSells, blocks, err := self.GeneratedGetTokenRates(
+		opts, self.pricingAddr, []ethereum.Address{addr},
+	)
+	if err != nil {
+		return result, err
+	}
+	if len(baseBuys) != 1 || len(baseSells) != 1 || len(compactBuys) != 1 || len(compactSells) != 1 || len(blocks) != 1 {
+		return result, fmt.Errorf("unexpected rates response for token %s", token.ID)
+	}
+	result.BaseBuy = baseBuys[0]
+	result.BaseSell = baseSells[0]
+	result.CompactBuy = compactBuys[0]
+	result.CompactSell = compactSells[0]
+	result.RateUpdateBlock = blocks[0].Uint64()
+	if index, ok := self.tokenIndices[addr.Hex()]; ok {
+		result.BulkIndex = index.BulkIndex
+		result.IndexInBulk = index.IndexInBulk
+	}
+
+	duration, err := self.GeneratedValidRateDurationInBlocks(opts)
+	if err != nil {
+		return result, err
+	}
+	result.ValidRateDurationInBlocks = duration.Uint64()
+
+	if result.BuyQtyStepFunction, err = self.getStepFunction(opts, addr, buyQtyStepFunctionCommand); err != nil {
+		return result, err
+	}
+	if result.SellQtyStepFunction, err = self.getStepFunction(opts, addr, sellQtyStepFunctionCommand); err != nil {
+		return result, err
+	}
+	if result.BuyImbalanceStepFunction, err = self.getStepFunction(opts, addr, buyImbalanceStepFunctionCommand); err != nil {
+		return result, err
+	}
+	if result.SellImbalanceStepFunction, err = self.getStepFunction(opts, addr, sellImbalanceStepFunctionCommand); err != nil {
+		return result, err
+	}
+
+	if result.MinimalRecordResolution, result.MaxPerBlockImbalance, result.MaxTotalImbalance, err = self.GeneratedGetTokenControlInfo(opts, addr); err != nil {
+		return result, err
+	}
+	for i := int64(0); i < imbalanceWindowSize; i++ {
+		data, err := self.GeneratedTokenImbalanceData(opts, addr, big.NewInt(i))
+		if err != nil {
+			return result, err
+		}
+		result.ImbalanceData = append(result.ImbalanceData, data)
+	}
+	return result, nil
+}
@@ -0,0 +1,44 @@
+package boltutil
+
+import (
+	"bytes"
+
+	"github.com/boltdb/bolt"
+)
+
+// IndexKey returns the key of a secondary index entry, which maps the value
+// of a field to the key of a record. Entries of a field value are ordered by
+// the keys of their records, so a range of records of the value can be
+// scanned without reading other records.
+func IndexKey(field, value string, key []byte) []byte {
+	result := make([]byte, 0, len(field)+len(value)+2+len(key))
+	result = append(result, field...)
+	result = append(result, 0)
+	result = append(result, value...)
+	result = append(result, 0)
+	return append(result, key...)
+}
+
+// IndexedKey returns the key of the record of the index entry of field value.
+func IndexedKey(field, value string, indexKey []byte) []byte {
+	return indexKey[len(field)+len(value)+2:]
+}
+
+// ReverseScan calls fn with keys in [min, max] and their values of the
+// cursor, from the last one, until fn returns false or an error. The bucket
+// of the cursor must not be modified by fn.
+func ReverseScan(c *bolt.Cursor, min, max []byte, fn func(k, v []byte) (bool, error)) error {
+	k, v := c.Seek(max)
+	if k == nil {
+		k, v = c.Last()
+	} else if bytes.Compare(k, max) > 0 {
+		k, v = c.Prev()
+	}
+	for ; k != nil && bytes.Compare(k, min) >= 0; k, v = c.Prev() {
+		next, err := fn(k, v)
+		if err != nil || !next {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package boltutil
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestIndexReverseScan(t *testing.T) {
+	db, teardown := openTestDB(t)
+	defer teardown()
+	if err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket([]byte("index"))
+		if err != nil {
+			return err
+		}
+		for _, entry := range []struct {
+			field, value string
+			key          uint64
+		}{
+			{"token", "KNC", 1}, {"token", "KNC", 3}, {"token", "KNC", 5},
+			{"token", "KNCX", 2}, {"token", "OMG", 4}, {"action", "KNC", 6},
+		} {
+			if err = b.Put(IndexKey(entry.field, entry.value, Uint64ToBytes(entry.key)), []byte{}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	scan := func(value string, from, to uint64, limit int) []uint64 {
+		result := []uint64{}
+		if err := db.View(func(tx *bolt.Tx) error {
+			min := IndexKey("token", value, Uint64ToBytes(from))
+			max := IndexKey("token", value, Uint64ToBytes(to))
+			return ReverseScan(tx.Bucket([]byte("index")).Cursor(), min, max, func(k, v []byte) (bool, error) {
+				result = append(result, BytesToUint64(IndexedKey("token", value, k)))
+				return len(result) < limit, nil
+			})
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	for _, tc := range []struct {
+		name     string
+		value    string
+		from, to uint64
+		limit    int
+		expected []uint64
+	}{
+		{"all entries of value", "KNC", 0, 10, 10, []uint64{5, 3, 1}},
+		{"range of keys", "KNC", 2, 4, 10, []uint64{3}},
+		{"inclusive bounds", "KNC", 1, 5, 10, []uint64{5, 3, 1}},
+		{"stopped by callback", "KNC", 0, 10, 2, []uint64{5, 3}},
+		{"value with common prefix", "KNCX", 0, 10, 10, []uint64{2}},
+		{"last value of bucket", "OMG", 0, 10, 10, []uint64{4}},
+		{"unknown value", "ETH", 0, 10, 10, []uint64{}},
+	} {
+		if result := scan(tc.value, tc.from, tc.to, tc.limit); !reflect.DeepEqual(result, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, result)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package boltutil
+
+import (
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var dbSizeDesc = prometheus.NewDesc(
+	"reserve_bolt_db_size_bytes",
+	"Size of files of bolt databases opened by the process.",
+	[]string{"path"}, nil,
+)
+
+// dbSizeCollector collects sizes of files of opened databases on scrapes.
+type dbSizeCollector struct{}
+
+func (dbSizeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- dbSizeDesc
+}
+
+// Collect sends sizes of files of opened databases, which are skipped if
+// their files can't be read.
+func (dbSizeCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, db := range Opened() {
+		info, err := os.Stat(db.Path())
+		if err != nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(dbSizeDesc, prometheus.GaugeValue, float64(info.Size()), db.Path())
+	}
+}
+
+func init() {
+	prometheus.MustRegister(dbSizeCollector{})
+}
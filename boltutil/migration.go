@@ -0,0 +1,274 @@
+package boltutil
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// SchemaVersionBucket is the bucket storing the schema version of a bolt database.
+const SchemaVersionBucket = "schema_version"
+
+var schemaVersionKey = []byte("version")
+
+// errDryRun is returned inside a dry run transaction to roll it back.
+var errDryRun = errors.New("dry run")
+
+// Migration is a change of the schema or data layout of a bolt database.
+// Migrate must be idempotent: it can run again on a database it has
+// already been applied to if the process stopped before the schema version
+// was recorded.
+type Migration struct {
+	Version     uint64
+	Description string
+	Migrate     func(tx *bolt.Tx) error
+}
+
+// Migrations is the list of migrations of a bolt database, ordered by
+// increasing version.
+type Migrations []Migration
+
+// Validate returns an error if the migrations are not in strictly
+// increasing version order starting from 1.
+func (self Migrations) Validate() error {
+	var prev uint64
+	for _, m := range self {
+		if m.Version <= prev {
+			return fmt.Errorf("migration %d is out of order", m.Version)
+		}
+		if m.Migrate == nil {
+			return fmt.Errorf("migration %d has no migrate function", m.Version)
+		}
+		prev = m.Version
+	}
+	return nil
+}
+
+// Latest returns the schema version after all migrations are applied.
+func (self Migrations) Latest() uint64 {
+	if len(self) == 0 {
+		return 0
+	}
+	return self[len(self)-1].Version
+}
+
+// SchemaVersion returns the schema version stored in the database. A
+// database without a stored schema version has version 0.
+func SchemaVersion(db *bolt.DB) (uint64, error) {
+	var version uint64
+	err := db.View(func(tx *bolt.Tx) error {
+		var vErr error
+		version, vErr = schemaVersion(tx)
+		return vErr
+	})
+	return version, err
+}
+
+func schemaVersion(tx *bolt.Tx) (uint64, error) {
+	b := tx.Bucket([]byte(SchemaVersionBucket))
+	if b == nil {
+		return 0, nil
+	}
+	v := b.Get(schemaVersionKey)
+	if v == nil {
+		return 0, nil
+	}
+	if len(v) != 8 {
+		return 0, fmt.Errorf("invalid schema version %x", v)
+	}
+	return BytesToUint64(v), nil
+}
+
+func putSchemaVersion(tx *bolt.Tx, version uint64) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(SchemaVersionBucket))
+	if err != nil {
+		return err
+	}
+	return b.Put(schemaVersionKey, Uint64ToBytes(version))
+}
+
+// Pending returns the migrations which are not applied to the database yet.
+func (self Migrations) Pending(db *bolt.DB) ([]Migration, error) {
+	version, err := SchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	return self.pendingFrom(version)
+}
+
+func (self Migrations) pendingFrom(version uint64) ([]Migration, error) {
+	if version > self.Latest() {
+		return nil, fmt.Errorf("database schema version %d is newer than the latest known version %d", version, self.Latest())
+	}
+	result := []Migration{}
+	for _, m := range self {
+		if m.Version > version {
+			result = append(result, m)
+		}
+	}
+	return result, nil
+}
+
+// Apply runs the pending migrations in order. Each migration runs in its own
+// transaction together with the update of the schema version, so a failed
+// migration leaves the database at the version of the last successful one.
+// If dryRun is true, all pending migrations run in a single transaction
+// which is rolled back. It returns the migrations which were run.
+func (self Migrations) Apply(db *bolt.DB, dryRun bool) ([]Migration, error) {
+	if err := self.Validate(); err != nil {
+		return nil, err
+	}
+	pending, err := self.Pending(db)
+	if err != nil || len(pending) == 0 {
+		return nil, err
+	}
+	if dryRun {
+		err = db.Update(func(tx *bolt.Tx) error {
+			for _, m := range pending {
+				if mErr := m.Migrate(tx); mErr != nil {
+					return fmt.Errorf("migration %d (%s) failed: %s", m.Version, m.Description, mErr)
+				}
+			}
+			return errDryRun
+		})
+		if err != errDryRun {
+			return nil, err
+		}
+		return pending, nil
+	}
+	for i, m := range pending {
+		err = db.Update(func(tx *bolt.Tx) error {
+			if mErr := m.Migrate(tx); mErr != nil {
+				return mErr
+			}
+			return putSchemaVersion(tx, m.Version)
+		})
+		if err != nil {
+			return pending[:i], fmt.Errorf("migration %d (%s) failed: %s", m.Version, m.Description, err)
+		}
+	}
+	return pending, nil
+}
+
+// Backup copies the database file to a timestamped file next to it and
+// returns the path of the copy.
+func Backup(db *bolt.DB) (string, error) {
+	path := fmt.Sprintf("%s.bak-%d", db.Path(), time.Now().UnixNano()/int64(time.Millisecond))
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(path, 0600)
+	})
+	return path, err
+}
+
+// isEmpty returns true if the database has no bucket, which means it has just
+// been created.
+func isEmpty(tx *bolt.Tx) bool {
+	empty := true
+	// ForEach never returns an error as the callback does not
+	_ = tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		empty = false
+		return nil
+	})
+	return empty
+}
+
+// Migrate brings the database schema to the latest version at startup.
+// A new database is stamped with the latest version without running any
+// migration. Otherwise the database file is backed up before pending
+// migrations are applied.
+func Migrate(db *bolt.DB, migrations Migrations) error {
+	if err := migrations.Validate(); err != nil {
+		return err
+	}
+	var pending []Migration
+	err := db.Update(func(tx *bolt.Tx) error {
+		if isEmpty(tx) {
+			return putSchemaVersion(tx, migrations.Latest())
+		}
+		version, vErr := schemaVersion(tx)
+		if vErr != nil {
+			return vErr
+		}
+		if pending, vErr = migrations.pendingFrom(version); vErr != nil {
+			return vErr
+		}
+		if len(pending) == 0 && tx.Bucket([]byte(SchemaVersionBucket)) == nil {
+			// database created before schema versioning without any migration to run
+			return putSchemaVersion(tx, version)
+		}
+		return nil
+	})
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+	backup, err := Backup(db)
+	if err != nil {
+		return fmt.Errorf("backing up %s before migration failed: %s", db.Path(), err)
+	}
+	log.Printf("%s is backed up to %s before applying %d migration(s)", db.Path(), backup, len(pending))
+	applied, err := migrations.Apply(db, false)
+	for _, m := range applied {
+		log.Printf("%s: applied migration %d (%s)", db.Path(), m.Version, m.Description)
+	}
+	return err
+}
+
+var (
+	openedMu sync.Mutex
+	opened   = map[string]*bolt.DB{}
+)
+
+// Open opens the bolt database at path, brings its schema to the latest version
+// and registers it to be included in backups of the running process until it
+// is closed with Close.
+func Open(path string, migrations Migrations) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = Migrate(db, migrations); err != nil {
+		if cErr := db.Close(); cErr != nil {
+			log.Printf("Closing %s failed: %s", path, cErr)
+		}
+		return nil, err
+	}
+	openedMu.Lock()
+	opened[path] = db
+	openedMu.Unlock()
+	return db, nil
+}
+
+// Close unregisters the database opened with Open and closes it.
+func Close(db *bolt.DB) error {
+	openedMu.Lock()
+	// the path of a closed database is empty, so it is looked up by value
+	for path, o := range opened {
+		if o == db {
+			delete(opened, path)
+		}
+	}
+	openedMu.Unlock()
+	return db.Close()
+}
+
+// Opened returns the databases opened with Open and not closed with Close,
+// ordered by path.
+func Opened() []*bolt.DB {
+	openedMu.Lock()
+	defer openedMu.Unlock()
+	paths := []string{}
+	for path := range opened {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	result := []*bolt.DB{}
+	for _, path := range paths {
+		result = append(result, opened[path])
+	}
+	return result
+}
@@ -0,0 +1,223 @@
+package boltutil
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func openTestDB(t *testing.T) (*bolt.DB, func()) {
+	tmpDir, err := ioutil.TempDir("", "test_migration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := bolt.Open(filepath.Join(tmpDir, "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, func() {
+		if cErr := db.Close(); cErr != nil {
+			t.Error(cErr)
+		}
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}
+}
+
+// countingMigrations returns migrations each increasing the counter key of
+// the test bucket by one.
+func countingMigrations(versions ...uint64) Migrations {
+	result := Migrations{}
+	for _, v := range versions {
+		result = append(result, Migration{
+			Version:     v,
+			Description: "increase counter",
+			Migrate: func(tx *bolt.Tx) error {
+				b, err := tx.CreateBucketIfNotExists([]byte("test"))
+				if err != nil {
+					return err
+				}
+				return b.Put([]byte("counter"), Uint64ToBytes(BytesToUint64(b.Get([]byte("counter")))+1))
+			},
+		})
+	}
+	return result
+}
+
+func readCounter(t *testing.T, db *bolt.DB) uint64 {
+	var counter uint64
+	if err := db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte("test")); b != nil {
+			counter = BytesToUint64(b.Get([]byte("counter")))
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return counter
+}
+
+func createLegacyBucket(t *testing.T, db *bolt.DB) {
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte("legacy"))
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateNewDatabase(t *testing.T) {
+	db, teardown := openTestDB(t)
+	defer teardown()
+
+	if err := Migrate(db, countingMigrations(1, 2)); err != nil {
+		t.Fatal(err)
+	}
+	version, err := SchemaVersion(db)
+	if err != nil || version != 2 {
+		t.Fatalf("expected new database stamped with version 2, got %d, err %v", version, err)
+	}
+	if counter := readCounter(t, db); counter != 0 {
+		t.Fatalf("expected no migration run on new database, got %d", counter)
+	}
+}
+
+func TestMigrateExistingDatabase(t *testing.T) {
+	db, teardown := openTestDB(t)
+	defer teardown()
+	createLegacyBucket(t, db)
+
+	if err := Migrate(db, countingMigrations(1, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if counter := readCounter(t, db); counter != 2 {
+		t.Fatalf("expected 2 migrations run, got %d", counter)
+	}
+	backups, err := filepath.Glob(db.Path() + ".bak-*")
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("expected a backup file, got %v, err %v", backups, err)
+	}
+
+	// running again only applies the new migration
+	if err = Migrate(db, countingMigrations(1, 2, 3)); err != nil {
+		t.Fatal(err)
+	}
+	if counter := readCounter(t, db); counter != 3 {
+		t.Fatalf("expected 3 migrations run, got %d", counter)
+	}
+	if err = Migrate(db, countingMigrations(1, 2, 3)); err != nil {
+		t.Fatal(err)
+	}
+	if counter := readCounter(t, db); counter != 3 {
+		t.Fatalf("expected no migration run again, got %d", counter)
+	}
+
+	if err = Migrate(db, countingMigrations(1)); err == nil {
+		t.Fatal("expected error for database newer than migrations")
+	}
+}
+
+func TestApplyMigrations(t *testing.T) {
+	db, teardown := openTestDB(t)
+	defer teardown()
+	createLegacyBucket(t, db)
+
+	migrations := countingMigrations(1, 2)
+	applied, err := migrations.Apply(db, true)
+	if err != nil || len(applied) != 2 {
+		t.Fatalf("expected 2 migrations in dry run, got %d, err %v", len(applied), err)
+	}
+	if counter := readCounter(t, db); counter != 0 {
+		t.Fatalf("expected dry run to be rolled back, got %d", counter)
+	}
+	if version, _ := SchemaVersion(db); version != 0 {
+		t.Fatalf("expected version unchanged after dry run, got %d", version)
+	}
+
+	failing := append(migrations, Migration{
+		Version:     3,
+		Description: "failing",
+		Migrate:     func(tx *bolt.Tx) error { return errors.New("failed") },
+	})
+	applied, err = failing.Apply(db, false)
+	if err == nil || len(applied) != 2 {
+		t.Fatalf("expected 2 migrations applied before failure, got %d, err %v", len(applied), err)
+	}
+	if version, _ := SchemaVersion(db); version != 2 {
+		t.Fatalf("expected version of last successful migration, got %d", version)
+	}
+}
+
+func TestValidateMigrations(t *testing.T) {
+	var tests = []struct {
+		migrations Migrations
+		valid      bool
+	}{
+		{countingMigrations(), true},
+		{countingMigrations(1, 2, 5), true},
+		{countingMigrations(0), false},
+		{countingMigrations(2, 1), false},
+		{countingMigrations(1, 1), false},
+		{Migrations{{Version: 1}}, false},
+	}
+	for _, tc := range tests {
+		if err := tc.migrations.Validate(); (err == nil) != tc.valid {
+			t.Errorf("migrations %+v: expected valid %v, got error %v", tc.migrations, tc.valid, err)
+		}
+	}
+}
+
+func TestOpened(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test_opened")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}()
+	isOpened := func(db *bolt.DB) bool {
+		for _, o := range Opened() {
+			if o == db {
+				return true
+			}
+		}
+		return false
+	}
+
+	a, err := Open(filepath.Join(tmpDir, "a.db"), Migrations{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Open(filepath.Join(tmpDir, "b.db"), Migrations{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isOpened(a) || !isOpened(b) {
+		t.Fatal("expected opened databases to be registered")
+	}
+	sizes := make(chan prometheus.Metric, len(Opened()))
+	dbSizeCollector{}.Collect(sizes)
+	if len(sizes) != len(Opened()) {
+		t.Errorf("expected sizes of %d opened databases, got %d", len(Opened()), len(sizes))
+	}
+	if err = Close(a); err != nil {
+		t.Fatal(err)
+	}
+	if isOpened(a) {
+		t.Error("expected database closed with Close to be unregistered")
+	}
+	if !isOpened(b) {
+		t.Error("expected other database to stay registered")
+	}
+	if err = Close(b); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/cmd/configuration"
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/common/archive"
+	"github.com/KyberNetwork/reserve-data/common/backup"
+	"github.com/boltdb/bolt"
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupDir         string
+	backupFromArchive bool
+)
+
+// backupConfig returns the backup configuration of the running mode with
+// the directory overwritten by --dir, and the archive if it is needed.
+func backupConfig(needArchive bool) (backup.Config, archive.Archive) {
+	conf, archiveConf, err := configuration.GetBackupConfig(common.RunningMode())
+	if err != nil {
+		log.Fatalf("Reading backup config failed: %s", err)
+	}
+	if backupDir != "" {
+		conf.Dir = backupDir
+	}
+	if conf.Dir == "" {
+		log.Fatal("backup directory is not configured, backup_dir or --dir is required")
+	}
+	if !conf.Archive && !needArchive {
+		return conf, nil
+	}
+	arch, err := archive.NewArchive(archiveConf)
+	if err != nil {
+		log.Fatalf("Creating archive failed: %s", err)
+	}
+	return conf, arch
+}
+
+func backupDatabases(cmd *cobra.Command, args []string) {
+	conf, arch := backupConfig(false)
+	dbs := []*bolt.DB{}
+	defer func() {
+		for _, db := range dbs {
+			if err := db.Close(); err != nil {
+				log.Printf("Closing %s failed: %s", db.Path(), err)
+			}
+		}
+	}()
+	for _, database := range schemaDatabases() {
+		db, err := bolt.Open(database.Path, 0600, &bolt.Options{Timeout: time.Second, ReadOnly: true})
+		if err != nil {
+			log.Printf("%s: opening %s failed, use POST /backup if the core is running: %s", database.Name, database.Path, err)
+			return
+		}
+		dbs = append(dbs, db)
+	}
+	backuper, err := backup.NewBackuper(conf, arch, func() []*bolt.DB { return dbs })
+	if err != nil {
+		log.Printf("Creating backuper failed: %s", err)
+		return
+	}
+	result, err := backuper.Backup()
+	if err != nil {
+		log.Printf("Backup failed: %s", err)
+		return
+	}
+	for _, file := range result.Manifest.Files {
+		log.Printf("%s: %d bytes, sha256 %s", file.Name, file.Size, file.SHA256)
+	}
+	log.Printf("Backed up to %s, archived: %v", result.Path, result.Archived)
+}
+
+func restoreDatabases(cmd *cobra.Command, args []string) {
+	bundle := args[0]
+	if backupFromArchive {
+		conf, arch := backupConfig(true)
+		path, err := backup.Download(arch, bundle, conf.Dir)
+		if err != nil {
+			log.Fatalf("Downloading %s from archive failed: %s", bundle, err)
+		}
+		bundle = path
+	}
+	destinations := map[string]string{}
+	for _, database := range configuration.GetBoltDatabases(common.RunningMode()) {
+		if schemaDBName != "" && database.Name != schemaDBName {
+			continue
+		}
+		destinations[filepath.Base(database.Path)] = database.Path
+	}
+	manifest, installed, err := backup.Restore(bundle, destinations)
+	for _, path := range installed {
+		log.Printf("%s is restored", path)
+	}
+	if err != nil {
+		log.Fatalf("Restoring %s failed: %s", bundle, err)
+	}
+	log.Printf("%d databases are restored from backup at %d", len(installed), manifest.Timestamp)
+}
+
+func init() {
+	var backupCmd = &cobra.Command{
+		Use:     "backup",
+		Short:   "back up bolt databases of the running mode to a bundle, the core must be stopped, use POST /backup otherwise",
+		Example: "KYBER_ENV=dev ./cmd backup --dir /var/backups/reserve",
+		Run:     backupDatabases,
+	}
+	backupCmd.Flags().StringVar(&backupDir, "dir", "", "directory of bundles, default to backup_dir of the config file")
+	backupCmd.Flags().StringVar(&schemaDBName, "db", "", "only back up the database of this name, e.g data, stat, binance")
+	var restoreCmd = &cobra.Command{
+		Use:     "restore <bundle>",
+		Short:   "validate a backup bundle and install its databases for the running mode, the core must be stopped",
+		Example: "KYBER_ENV=dev ./cmd restore backup-1540000000000.tar.gz --from-archive",
+		Args:    cobra.ExactArgs(1),
+		Run:     restoreDatabases,
+	}
+	restoreCmd.Flags().StringVar(&backupDir, "dir", "", "directory to download the bundle to, default to backup_dir of the config file")
+	restoreCmd.Flags().BoolVar(&backupFromArchive, "from-archive", false, "download the bundle of the given name from archive")
+	restoreCmd.Flags().StringVar(&schemaDBName, "db", "", "only restore the database of this name, e.g data, stat, binance")
+	RootCmd.AddCommand(backupCmd, restoreCmd)
+}
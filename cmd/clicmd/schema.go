@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/boltutil"
+	"github.com/KyberNetwork/reserve-data/cmd/configuration"
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/boltdb/bolt"
+	"github.com/spf13/cobra"
+)
+
+var (
+	schemaDBName string
+	schemaDryRun bool
+)
+
+// schemaDatabases returns the bolt databases of the running mode selected by
+// --db which exist on disk.
+func schemaDatabases() []configuration.BoltDatabase {
+	result := []configuration.BoltDatabase{}
+	for _, database := range configuration.GetBoltDatabases(common.RunningMode()) {
+		if schemaDBName != "" && database.Name != schemaDBName {
+			continue
+		}
+		if _, err := os.Stat(database.Path); err != nil {
+			log.Printf("%s: %s is skipped: %s", database.Name, database.Path, err)
+			continue
+		}
+		result = append(result, database)
+	}
+	return result
+}
+
+// openSchemaDatabase opens the database without running migrations, it
+// fails if the core is running and holding the file lock.
+func openSchemaDatabase(database configuration.BoltDatabase) *bolt.DB {
+	db, err := bolt.Open(database.Path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		log.Fatalf("%s: opening %s failed, make sure the core is stopped: %s", database.Name, database.Path, err)
+	}
+	return db
+}
+
+func closeSchemaDatabase(database configuration.BoltDatabase, db *bolt.DB) {
+	if err := db.Close(); err != nil {
+		log.Printf("%s: closing %s failed: %s", database.Name, database.Path, err)
+	}
+}
+
+func listSchemaMigrations(cmd *cobra.Command, args []string) {
+	for _, database := range schemaDatabases() {
+		db := openSchemaDatabase(database)
+		version, err := boltutil.SchemaVersion(db)
+		if err != nil {
+			closeSchemaDatabase(database, db)
+			log.Fatalf("%s: reading schema version failed: %s", database.Name, err)
+		}
+		log.Printf("%s (%s): schema version %d, latest %d", database.Name, database.Path, version, database.Migrations.Latest())
+		for _, m := range database.Migrations {
+			status := "pending"
+			if m.Version <= version {
+				status = "applied"
+			}
+			log.Printf("  %d %s: %s", m.Version, status, m.Description)
+		}
+		closeSchemaDatabase(database, db)
+	}
+}
+
+func applySchemaMigrations(cmd *cobra.Command, args []string) {
+	for _, database := range schemaDatabases() {
+		db := openSchemaDatabase(database)
+		pending, err := database.Migrations.Pending(db)
+		if err != nil {
+			closeSchemaDatabase(database, db)
+			log.Fatalf("%s: %s", database.Name, err)
+		}
+		if len(pending) == 0 {
+			log.Printf("%s: schema is up to date", database.Name)
+			closeSchemaDatabase(database, db)
+			continue
+		}
+		if !schemaDryRun {
+			backup, bErr := boltutil.Backup(db)
+			if bErr != nil {
+				closeSchemaDatabase(database, db)
+				log.Fatalf("%s: backing up %s failed: %s", database.Name, database.Path, bErr)
+			}
+			log.Printf("%s: backed up to %s", database.Name, backup)
+		}
+		applied, err := database.Migrations.Apply(db, schemaDryRun)
+		for _, m := range applied {
+			if schemaDryRun {
+				log.Printf("%s: migration %d (%s) would be applied", database.Name, m.Version, m.Description)
+			} else {
+				log.Printf("%s: migration %d (%s) applied", database.Name, m.Version, m.Description)
+			}
+		}
+		closeSchemaDatabase(database, db)
+		if err != nil {
+			log.Fatalf("%s: %s", database.Name, err)
+		}
+	}
+}
+
+func init() {
+	var schemaCmd = &cobra.Command{
+		Use:   "schema",
+		Short: "manage schema migrations of bolt databases of the running mode, the core must be stopped",
+	}
+	schemaCmd.PersistentFlags().StringVar(&schemaDBName, "db", "", "only manage the database of this name, e.g data, stat, log, rate, user, fee_setrate, binance")
+	var listCmd = &cobra.Command{
+		Use:     "list",
+		Short:   "list schema versions and migrations of bolt databases",
+		Example: "KYBER_ENV=dev ./cmd schema list",
+		Run:     listSchemaMigrations,
+	}
+	var applyCmd = &cobra.Command{
+		Use:     "apply",
+		Short:   "back up bolt databases and apply pending migrations",
+		Example: "KYBER_ENV=dev ./cmd schema apply --db data --dry-run",
+		Run:     applySchemaMigrations,
+	}
+	applyCmd.Flags().BoolVar(&schemaDryRun, "dry-run", false, "run pending migrations and roll them back")
+	schemaCmd.AddCommand(listCmd, applyCmd)
+	RootCmd.AddCommand(schemaCmd)
+}
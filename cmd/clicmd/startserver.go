@@ -6,9 +6,12 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/KyberNetwork/reserve-data"
 	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/common/ratelimit"
+	"github.com/KyberNetwork/reserve-data/data/balancemonitor"
 	"github.com/KyberNetwork/reserve-data/http"
 	"github.com/spf13/cobra"
 )
@@ -71,6 +74,7 @@ func serverStart(_ *cobra.Command, _ []string) {
 			if err := rData.Run(); err != nil {
 				log.Panic(err)
 			}
+			balancemonitor.NewBalanceMonitor(config.BalanceMonitorStorage, bc, config.BalanceMonitorInterval).Run()
 		}
 	}
 
@@ -89,6 +93,10 @@ func serverStart(_ *cobra.Command, _ []string) {
 		}
 	}
 
+	if config.Backuper != nil && !dryrun {
+		config.Backuper.Run()
+	}
+
 	//Create Server
 	servPortStr := fmt.Sprintf(":%d", servPort)
 	server := http.NewHTTPServer(
@@ -98,6 +106,15 @@ func serverStart(_ *cobra.Command, _ []string) {
 		config.EnableAuthentication,
 		config.AuthEngine,
 		kyberENV,
+		config.Backuper,
+		config.APIKeyStorage,
+		config.AuditStorage,
+		config.NonceStore,
+		config.EventHub,
+		config.MetricsToken,
+		CreateReadinessChecker(config, bc, rData),
+		ratelimit.NewLimiter(config.RateLimits, time.Now),
+		config.ConfigStorage,
 	)
 
 	if !dryrun {
@@ -8,11 +8,13 @@ import (
 	"path/filepath"
 	"regexp"
 
+	"github.com/KyberNetwork/reserve-data"
 	"github.com/KyberNetwork/reserve-data/blockchain"
 	"github.com/KyberNetwork/reserve-data/cmd/configuration"
 	"github.com/KyberNetwork/reserve-data/common"
 	"github.com/KyberNetwork/reserve-data/common/archive"
 	"github.com/KyberNetwork/reserve-data/common/blockchain/nonce"
+	"github.com/KyberNetwork/reserve-data/common/readiness"
 	"github.com/KyberNetwork/reserve-data/core"
 	"github.com/KyberNetwork/reserve-data/data"
 	"github.com/KyberNetwork/reserve-data/data/fetcher"
@@ -154,6 +156,11 @@ func CreateDataCore(config *configuration.Config, kyberENV string, bc *blockchai
 		config.ReserveAddress,
 		kyberENV == common.SIMULATION_MODE,
 	)
+	if config.StaleLimits != nil {
+		dataFetcher.SetStaleLimits(config.StaleLimits)
+	}
+	dataFetcher.SetOrderbookValidator(fetcher.NewOrderbookValidator(config.OrderbookValidation))
+	dataFetcher.SetEventPublisher(config.EventHub)
 	for _, ex := range config.FetcherExchanges {
 		dataFetcher.AddExchange(ex)
 	}
@@ -172,6 +179,7 @@ func CreateDataCore(config *configuration.Config, kyberENV string, bc *blockchai
 	)
 
 	rCore := core.NewReserveCore(bc, config.ActivityStorage, config.ReserveAddress)
+	rCore.SetEventPublisher(config.EventHub)
 	return rData, rCore
 }
 
@@ -208,3 +216,20 @@ func CreateStat(config *configuration.Config, kyberENV string, bc *blockchain.Bl
 	)
 	return rStat
 }
+
+// CreateReadinessChecker creates the checker of /readyz, data and exchange
+// checks are added if core is enabled and the stat log check if stat is enabled.
+func CreateReadinessChecker(config *configuration.Config, bc *blockchain.Blockchain, rData reserve.ReserveData) *readiness.Checker {
+	thresholds := config.ReadinessThresholds
+	checker := readiness.NewChecker(thresholds)
+	checker.Add(readiness.BoltCheckName, readiness.BoltCheck())
+	checker.Add(readiness.NodeCheckName, readiness.NodeCheck(bc.CurrentHead, thresholds.MaxHeadLag))
+	if rData != nil {
+		checker.Add(readiness.DataCheckName, readiness.DataCheck(rData.GetDataHealth))
+		checker.Add(readiness.ExchangeCheckName, readiness.ExchangeCheck(rData.GetExchangeStatus, rData.GetDataHealth, thresholds.MaxExchangesDown))
+	}
+	if config.LogStorage != nil {
+		checker.Add(readiness.LogCheckName, readiness.LogCheck(config.LogStorage.LastBlock, bc.CurrentBlock, thresholds.MaxLogBlockLag))
+	}
+	return checker
+}
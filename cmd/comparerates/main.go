@@ -1,7 +1,6 @@
 package comparerates
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"math"
@@ -10,6 +9,7 @@ import (
 
 	"github.com/KyberNetwork/reserve-data/cmd/configuration"
 	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/http/client"
 )
 
 const (
@@ -29,31 +29,57 @@ type AllActionHTTPReply struct {
 	Success bool
 }
 
+// timeParams returns the fromTime and toTime params in millisecond.
+func timeParams(params map[string]string) (int64, *int64, error) {
+	fromTime, err := strconv.ParseInt(params["fromTime"], 10, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("fromTime param is invalid: %s", err)
+	}
+	if params["toTime"] == "" {
+		return fromTime, nil, nil
+	}
+	toTime, err := strconv.ParseInt(params["toTime"], 10, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("toTime param is invalid: %s", err)
+	}
+	return fromTime, &toTime, nil
+}
+
 //GetActivitiesResponse return activities:
 func GetActivitiesResponse(url string, params map[string]string, config configuration.Config) (AllActionHTTPReply, error) {
-	timepoint := common.GetTimepoint()
-	nonce := strconv.FormatUint(timepoint, 10)
 	var allActionRep AllActionHTTPReply
-	params["nonce"] = nonce
-	data, err := GetResponse("GET", fmt.Sprintf("%s/%s", url, "activities"), params, true, config)
+	if config.AuthEngine == nil {
+		log.Fatal("the environment doesn't come with AuthEngine object, try stagging env ")
+	}
+	fromTime, toTime, err := timeParams(params)
 	if err != nil {
 		return allActionRep, err
 	}
-
-	err = json.Unmarshal(data, &allActionRep)
+	resp, err := client.New(url, client.WithSigner(config.AuthEngine.KNSign)).GetActivities(client.GetActivitiesParams{
+		FromTime: &fromTime,
+		ToTime:   toTime,
+	})
+	if err != nil {
+		return allActionRep, err
+	}
+	allActionRep.Success = resp.Success
+	err = resp.Decode(&allActionRep.Data)
 	return allActionRep, err
 }
 
 func GetAllRateResponse(url string, params map[string]string, config configuration.Config) (AllRateHTTPReply, error) {
 	var allRateRep AllRateHTTPReply
-	data, err := GetResponse("GET", fmt.Sprintf("%s/%s", url, "get-all-rates"), params, false, config)
-
+	fromTime, toTime, err := timeParams(params)
 	if err != nil {
 		return allRateRep, err
 	}
-	err = json.Unmarshal(data, &allRateRep)
+	resp, err := client.New(url).GetRates(client.GetRatesParams{FromTime: &fromTime, ToTime: toTime})
+	if err != nil {
+		return allRateRep, err
+	}
+	allRateRep.Success = resp.Success
+	err = resp.Decode(&allRateRep.Data)
 	return allRateRep, err
-
 }
 
 func RateDifference(r1, r2 float64) float64 {
@@ -7,11 +7,17 @@ import (
 
 	"github.com/KyberNetwork/reserve-data/common"
 	"github.com/KyberNetwork/reserve-data/common/archive"
+	"github.com/KyberNetwork/reserve-data/common/backup"
 	"github.com/KyberNetwork/reserve-data/common/blockchain"
+	"github.com/KyberNetwork/reserve-data/common/event"
+	"github.com/KyberNetwork/reserve-data/common/ratelimit"
+	"github.com/KyberNetwork/reserve-data/common/readiness"
 	"github.com/KyberNetwork/reserve-data/core"
 	"github.com/KyberNetwork/reserve-data/data"
+	"github.com/KyberNetwork/reserve-data/data/balancemonitor"
 	"github.com/KyberNetwork/reserve-data/data/datapruner"
 	"github.com/KyberNetwork/reserve-data/data/fetcher"
+	"github.com/KyberNetwork/reserve-data/data/fetcher/head_runner"
 	"github.com/KyberNetwork/reserve-data/data/fetcher/http_runner"
 	"github.com/KyberNetwork/reserve-data/data/storage"
 	"github.com/KyberNetwork/reserve-data/exchange/binance"
@@ -24,6 +30,7 @@ import (
 	statstorage "github.com/KyberNetwork/reserve-data/stat/storage"
 	"github.com/KyberNetwork/reserve-data/world"
 	ethereum "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 const (
@@ -90,7 +97,24 @@ type Config struct {
 	FetcherStorage       fetcher.Storage
 	FetcherGlobalStorage fetcher.GlobalStorage
 	MetricStorage        metric.MetricStorage
-	Archive              archive.Archive
+	// ArchiveConfig chooses the backend of Archive: AWS S3, S3 compatible store or local directory
+	ArchiveConfig archive.Config
+	Archive       archive.Archive
+	// Backuper backs up all opened bolt databases, nil if backup is not configured
+	Backuper *backup.Backuper
+
+	// BalanceMonitorStorage stores thresholds and results of operator and reserve balance checks
+	BalanceMonitorStorage  balancemonitor.Storage
+	BalanceMonitorInterval time.Duration
+
+	// StaleLimits is the maximum age of each kind of fetched data before it is reported as stale
+	StaleLimits common.StaleLimits
+	// OrderbookValidation is the limits of order books before they are rejected
+	OrderbookValidation fetcher.OrderbookValidationConfig
+	// ReadinessThresholds are the limits of readiness checks of /readyz
+	ReadinessThresholds readiness.Thresholds
+	// RateLimits are the limits of HTTP requests by keys and routes
+	RateLimits ratelimit.Limits
 
 	World                *world.TheWorld
 	FetcherRunner        fetcher.FetcherRunner
@@ -105,6 +129,19 @@ type Config struct {
 
 	EnableAuthentication bool
 	AuthEngine           http.Authentication
+	// APIKeyStorage stores API keys of AuthEngine, nil if core is not enabled
+	APIKeyStorage http.APIKeyStorage
+	// AuditStorage stores audit records of mutating APIs, nil if core is not enabled
+	AuditStorage http.AuditStorage
+	// ConfigStorage stores proposals and versions of configs, nil if core is not enabled
+	ConfigStorage http.ConfigStorage
+	// EventHub publishes events of data stored by core, nil if core is not enabled
+	EventHub *event.Hub
+	// NonceStore rejects replayed requests, it is shared by servers using
+	// the same SQL database
+	NonceStore http.NonceStore
+	// MetricsToken is the bearer token of Prometheus scrapes
+	MetricsToken string
 
 	EthereumEndpoint        string
 	BackupEthereumEndpoints []string
@@ -218,7 +255,7 @@ func (self *Config) AddCoreConfig(settingPath SettingPaths, addressConfig common
 		log.Fatalf("Fees file %s cannot found at: %s", minDepositPath, err.Error())
 	}
 
-	dataStorage, err := storage.NewBoltStorage(settingPath.dataStoragePath)
+	dataStorage, err := newCoreStorage(settingPath)
 	if err != nil {
 		panic(err)
 	}
@@ -230,14 +267,37 @@ func (self *Config) AddCoreConfig(settingPath SettingPaths, addressConfig common
 			log.Fatalf("failed to create HTTP runner: %s", err.Error())
 		}
 	} else {
-		fetcherRunner = fetcher.NewTickerRunner(
-			7*time.Second,  // orderbook fetching interval
-			5*time.Second,  // authdata fetching interval
-			3*time.Second,  // rate fetching interval
-			5*time.Second,  // block fetching interval
-			10*time.Second, // global data fetching interval
+		fetcherRunner = newFetcherRunner(settingPath, self.EthereumEndpoint)
+		dataControllerRunner = datapruner.NewStorageControllerTickerRunner(
+			24*time.Hour, // auth data pruning interval
+			time.Hour,    // price history pruning interval
+			24*time.Hour, // audit log pruning interval
 		)
-		dataControllerRunner = datapruner.NewStorageControllerTickerRunner(24 * time.Hour)
+	}
+
+	healthConfig, err := fetcher.GetHealthConfigFromFile(settingPath.secretPath)
+	if err != nil {
+		log.Fatalf("cannot read data health config: %s", err)
+	}
+	staleLimits, err := common.NewStaleLimits(healthConfig.StaleLimits)
+	if err != nil {
+		log.Fatalf("invalid data stale limits: %s", err)
+	}
+	orderbookValidation, err := fetcher.GetOrderbookValidationConfigFromFile(settingPath.secretPath)
+	if err != nil {
+		log.Fatalf("cannot read orderbook validation config: %s", err)
+	}
+	if err = orderbookValidation.Validate(); err != nil {
+		log.Fatalf("invalid orderbook validation config: %s", err)
+	}
+
+	balanceMonitorConfig, err := balancemonitor.GetConfigFromFile(settingPath.secretPath)
+	if err != nil {
+		log.Fatalf("cannot read balance monitor config: %s", err)
+	}
+	balanceMonitorInterval, err := balanceMonitorConfig.CheckInterval()
+	if err != nil {
+		log.Fatalf("invalid balance monitor config: %s", err)
 	}
 
 	pricingSigner := PricingSignerFromConfigFile(settingPath.secretPath)
@@ -248,8 +308,20 @@ func (self *Config) AddCoreConfig(settingPath SettingPaths, addressConfig common
 	self.DataGlobalStorage = dataStorage
 	self.FetcherStorage = dataStorage
 	self.FetcherGlobalStorage = dataStorage
+	self.BalanceMonitorStorage = dataStorage
+	self.BalanceMonitorInterval = balanceMonitorInterval
 	self.MetricStorage = dataStorage
+	self.APIKeyStorage = dataStorage
+	self.AuditStorage = dataStorage
+	self.ConfigStorage = dataStorage
+	self.EventHub = event.NewHub()
+	if nonceStore, ok := dataStorage.(http.NonceStore); ok {
+		self.NonceStore = nonceStore
+	}
+	self.AuthEngine = http.NewKeyStoreAuthentication(dataStorage, self.AuthEngine)
 	self.FetcherRunner = fetcherRunner
+	self.StaleLimits = staleLimits
+	self.OrderbookValidation = orderbookValidation
 	self.DataControllerRunner = dataControllerRunner
 	self.BlockchainSigner = pricingSigner
 	//self.IntermediatorSigner = huoBiintermediatorSigner
@@ -285,6 +357,83 @@ func (self *Config) AddCoreConfig(settingPath SettingPaths, addressConfig common
 	self.Exchanges = coreExchanges
 }
 
+// newFetcherRunner creates a head runner following new blocks of the node at
+// ws_endpoint of the secret file, polling endpoint while the subscription is
+// down, or a ticker runner if ws_endpoint is not configured.
+func newFetcherRunner(settingPath SettingPaths, endpoint string) fetcher.FetcherRunner {
+	conf, err := head_runner.GetConfigFromFile(settingPath.secretPath)
+	if err != nil {
+		log.Panicf("cannot read head runner config: %s", err)
+	}
+	if conf.WSEndpoint == "" {
+		return fetcher.NewTickerRunner(
+			7*time.Second,  // orderbook fetching interval
+			5*time.Second,  // authdata fetching interval
+			3*time.Second,  // rate fetching interval
+			5*time.Second,  // block fetching interval
+			10*time.Second, // global data fetching interval
+		)
+	}
+	client, err := ethclient.Dial(endpoint)
+	if err != nil {
+		log.Panicf("cannot connect to %s: %s", endpoint, err)
+	}
+	// the websocket endpoint is dialed on subscribing, the runner polls the
+	// endpoint while it is unreachable and keeps retrying
+	log.Printf("Fetching blocks and rates on new heads of %s", conf.WSEndpoint)
+	return head_runner.NewHeadRunner(
+		head_runner.NewDialingSubscriber(conf.WSEndpoint), client,
+		7*time.Second,  // orderbook fetching interval
+		5*time.Second,  // authdata fetching interval
+		10*time.Second, // global data fetching interval
+	)
+}
+
+// coreStorage is implemented by all storage backends of core data.
+type coreStorage interface {
+	core.ActivityStorage
+	data.Storage
+	data.GlobalStorage
+	fetcher.Storage
+	fetcher.GlobalStorage
+	balancemonitor.Storage
+	metric.MetricStorage
+	http.APIKeyStorage
+	http.AuditStorage
+	http.ConfigStorage
+}
+
+// newCoreStorage creates the core data storage of the type configured in secret file,
+// which is the bolt file at data storage path by default.
+func newCoreStorage(settingPath SettingPaths) (coreStorage, error) {
+	conf, err := storage.GetConfigFromFile(settingPath.secretPath)
+	if err != nil {
+		return nil, err
+	}
+	if err = conf.Validate(); err != nil {
+		return nil, err
+	}
+	priceHistory, err := conf.PriceHistory()
+	if err != nil {
+		return nil, err
+	}
+	if conf.Type == storage.SQLStorageType {
+		log.Printf("Using %s database for core data", conf.Driver())
+		sqlStorage, sErr := storage.NewSQLStorage(conf.Driver(), conf.SQLDataSource)
+		if sErr != nil {
+			return nil, sErr
+		}
+		sqlStorage.SetPriceHistoryConfig(priceHistory)
+		return sqlStorage, nil
+	}
+	boltStorage, err := storage.NewBoltStorage(settingPath.dataStoragePath)
+	if err != nil {
+		return nil, err
+	}
+	boltStorage.SetPriceHistoryConfig(priceHistory)
+	return boltStorage, nil
+}
+
 func (self *Config) MapTokens() map[string]common.Token {
 	result := map[string]common.Token{}
 	for _, t := range self.SupportedTokens {
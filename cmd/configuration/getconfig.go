@@ -3,9 +3,13 @@ package configuration
 import (
 	"log"
 
+	"github.com/KyberNetwork/reserve-data/boltutil"
 	"github.com/KyberNetwork/reserve-data/common"
 	"github.com/KyberNetwork/reserve-data/common/archive"
+	"github.com/KyberNetwork/reserve-data/common/backup"
 	"github.com/KyberNetwork/reserve-data/common/blockchain"
+	"github.com/KyberNetwork/reserve-data/common/ratelimit"
+	"github.com/KyberNetwork/reserve-data/common/readiness"
 	"github.com/KyberNetwork/reserve-data/http"
 	"github.com/KyberNetwork/reserve-data/world"
 	ethereum "github.com/ethereum/go-ethereum/common"
@@ -120,11 +124,40 @@ func GetConfig(kyberENV string, authEnbl bool, endpointOW string, noCore, enable
 	if !authEnbl {
 		log.Printf("\nWARNING: No authentication mode\n")
 	}
-	awsConf, err := archive.GetAWSconfigFromFile(setPath.secretPath)
+	archiveConf, err := archive.GetArchiveConfigFromFile(setPath.secretPath)
 	if err != nil {
 		panic(err)
 	}
-	s3archive := archive.NewS3Archive(awsConf)
+	arch, err := archive.NewArchive(archiveConf)
+	if err != nil {
+		panic(err)
+	}
+	backupConf, err := backup.GetConfigFromFile(setPath.secretPath)
+	if err != nil {
+		panic(err)
+	}
+	var backuper *backup.Backuper
+	if backupConf.Dir != "" {
+		if backuper, err = backup.NewBackuper(backupConf, arch, boltutil.Opened); err != nil {
+			panic(err)
+		}
+	}
+	readinessConf, err := readiness.GetConfigFromFile(setPath.secretPath)
+	if err != nil {
+		panic(err)
+	}
+	readinessThresholds, err := readinessConf.Thresholds()
+	if err != nil {
+		log.Fatalf("invalid readiness thresholds: %s", err)
+	}
+	rateLimitConf, err := ratelimit.GetConfigFromFile(setPath.secretPath)
+	if err != nil {
+		panic(err)
+	}
+	rateLimits, err := rateLimitConf.Limits()
+	if err != nil {
+		log.Fatalf("invalid rate limits: %s", err)
+	}
 	config := &Config{
 		Blockchain:              blockchain,
 		EthereumEndpoint:        endpoint,
@@ -135,8 +168,14 @@ func GetConfig(kyberENV string, authEnbl bool, endpointOW string, noCore, enable
 		ReserveAddress:          reserveAddr,
 		ChainType:               chainType,
 		AuthEngine:              hmac512auth,
+		NonceStore:              http.NewMemoryNonceStore(http.DefaultNonceStoreCapacity, http.DefaultNonceStoreKeyCapacity, common.GetTimepoint),
+		MetricsToken:            hmac512auth.KNMetrics,
 		EnableAuthentication:    authEnbl,
-		Archive:                 s3archive,
+		ArchiveConfig:           archiveConf,
+		Archive:                 arch,
+		Backuper:                backuper,
+		ReadinessThresholds:     readinessThresholds,
+		RateLimits:              rateLimits,
 		World:                   theWorld,
 	}
 
@@ -0,0 +1,50 @@
+package configuration
+
+import (
+	"path/filepath"
+
+	"github.com/KyberNetwork/reserve-data/boltutil"
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/common/archive"
+	"github.com/KyberNetwork/reserve-data/common/backup"
+	"github.com/KyberNetwork/reserve-data/data/storage"
+	"github.com/KyberNetwork/reserve-data/exchange/binance"
+	"github.com/KyberNetwork/reserve-data/exchange/bittrex"
+	"github.com/KyberNetwork/reserve-data/exchange/huobi"
+	statstorage "github.com/KyberNetwork/reserve-data/stat/storage"
+)
+
+// BoltDatabase is a bolt database file of the core with its schema migrations.
+type BoltDatabase struct {
+	Name       string
+	Path       string
+	Migrations boltutil.Migrations
+}
+
+// GetBoltDatabases returns all bolt databases used in the given environment.
+func GetBoltDatabases(kyberENV string) []BoltDatabase {
+	setPath := GetConfigPaths(kyberENV)
+	return []BoltDatabase{
+		{"data", setPath.dataStoragePath, storage.BoltMigrations},
+		{"analytic", setPath.analyticStoragePath, statstorage.AnalyticMigrations},
+		{"stat", setPath.statStoragePath, statstorage.StatMigrations},
+		{"log", setPath.logStoragePath, statstorage.LogMigrations},
+		{"rate", setPath.rateStoragePath, statstorage.RateMigrations},
+		{"user", setPath.userStoragePath, statstorage.UserMigrations},
+		{"fee_setrate", setPath.feeSetRateStoragePath, statstorage.FeeSetRateMigrations},
+		{"bittrex", filepath.Join(common.CmdDirLocation(), "bittrex.db"), bittrex.BoltMigrations},
+		{"binance", filepath.Join(common.CmdDirLocation(), "binance.db"), binance.BoltMigrations},
+		{"huobi", filepath.Join(common.CmdDirLocation(), "huobi.db"), huobi.BoltMigrations},
+	}
+}
+
+// GetBackupConfig returns the backup and archive configuration of the given environment.
+func GetBackupConfig(kyberENV string) (backup.Config, archive.Config, error) {
+	setPath := GetConfigPaths(kyberENV)
+	backupConf, err := backup.GetConfigFromFile(setPath.secretPath)
+	if err != nil {
+		return backupConf, archive.Config{}, err
+	}
+	archiveConf, err := archive.GetArchiveConfigFromFile(setPath.secretPath)
+	return backupConf, archiveConf, err
+}
@@ -0,0 +1,100 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Actions on exchanges restricted by scopes of API keys.
+const (
+	TradeScope    = "trade"
+	DepositScope  = "deposit"
+	WithdrawScope = "withdraw"
+)
+
+// APIKeySecret is a HMAC secret of an API key. A key has more than one
+// secret while it is rotated, the old ones expire after a grace period.
+type APIKeySecret struct {
+	Secret    string `json:"secret,omitempty"`
+	CreatedAt uint64 `json:"created_at"`
+	// ExpiresAt is the timepoint the secret expires at, 0 if it doesn't.
+	ExpiresAt uint64 `json:"expires_at"`
+}
+
+// APIKey is a named key to sign requests to authenticated APIs.
+type APIKey struct {
+	Name    string         `json:"name"`
+	Secrets []APIKeySecret `json:"secrets"`
+	// Permissions are names of permissions granted to the key, e.g read_only.
+	Permissions []string `json:"permissions"`
+	// Scopes restrict actions on exchanges, e.g trade:binance or withdraw:*.
+	// The key can act on all exchanges if it has no scopes.
+	Scopes []string `json:"scopes"`
+	// ExpiresAt is the timepoint the key expires at, 0 if it doesn't.
+	ExpiresAt uint64 `json:"expires_at"`
+	Disabled  bool   `json:"disabled"`
+	CreatedAt uint64 `json:"created_at"`
+	UpdatedAt uint64 `json:"updated_at"`
+}
+
+// Expired returns true if the key expires before timepoint.
+func (self APIKey) Expired(timepoint uint64) bool {
+	return self.ExpiresAt != 0 && self.ExpiresAt <= timepoint
+}
+
+// ValidSecrets returns secrets of the key not expired at timepoint.
+func (self APIKey) ValidSecrets(timepoint uint64) []string {
+	result := []string{}
+	for _, secret := range self.Secrets {
+		if secret.ExpiresAt == 0 || secret.ExpiresAt > timepoint {
+			result = append(result, secret.Secret)
+		}
+	}
+	return result
+}
+
+// Redacted returns the key without secret values, to be listed.
+func (self APIKey) Redacted() APIKey {
+	secrets := make([]APIKeySecret, len(self.Secrets))
+	for i, secret := range self.Secrets {
+		secret.Secret = ""
+		secrets[i] = secret
+	}
+	self.Secrets = secrets
+	return self
+}
+
+// HasScope returns true if the key is allowed to act in scope, which is an
+// action and an exchange joined by colon, e.g trade:binance.
+func HasScope(scopes []string, scope string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+	action := strings.SplitN(scope, ":", 2)[0]
+	for _, s := range scopes {
+		if s == scope || s == "*" || s == action+":*" {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateScopes returns an error if any scope is not an action on an
+// exchange, e.g trade:binance, all exchanges of an action, e.g trade:*, or *.
+func ValidateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if scope == "*" {
+			continue
+		}
+		parts := strings.SplitN(scope, ":", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return fmt.Errorf("invalid scope %s, expected action:exchange", scope)
+		}
+		switch parts[0] {
+		case TradeScope, DepositScope, WithdrawScope:
+		default:
+			return fmt.Errorf("invalid scope %s, unknown action %s", scope, parts[0])
+		}
+	}
+	return nil
+}
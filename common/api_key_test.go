@@ -0,0 +1,35 @@
+package common
+
+import "testing"
+
+func TestHasScope(t *testing.T) {
+	var tests = []struct {
+		scopes   []string
+		scope    string
+		expected bool
+	}{
+		{nil, "withdraw:binance", true},
+		{[]string{"trade:binance"}, "trade:binance", true},
+		{[]string{"trade:binance"}, "trade:huobi", false},
+		{[]string{"trade:binance"}, "withdraw:binance", false},
+		{[]string{"trade:*"}, "trade:huobi", true},
+		{[]string{"trade:*"}, "deposit:huobi", false},
+		{[]string{"*"}, "deposit:huobi", true},
+	}
+	for _, tc := range tests {
+		if result := HasScope(tc.scopes, tc.scope); result != tc.expected {
+			t.Errorf("HasScope(%v, %s): expected %t, got %t", tc.scopes, tc.scope, tc.expected, result)
+		}
+	}
+}
+
+func TestValidateScopes(t *testing.T) {
+	if err := ValidateScopes([]string{"trade:binance", "withdraw:*", "*"}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	for _, scope := range []string{"trade", "trade:", "transfer:binance"} {
+		if err := ValidateScopes([]string{scope}); err == nil {
+			t.Errorf("expected scope %s to be invalid", scope)
+		}
+	}
+}
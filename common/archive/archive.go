@@ -8,6 +8,12 @@ type Archive interface {
 	// The local file name should be passed in as full file Path.
 	UploadFile(bucketName string, destinationFolder string, filePath string) error
 
+	// ListFiles: return names of all files in a remote folder.
+	ListFiles(bucketName string, destinationFolder string) ([]string, error)
+
+	// DownloadFile: download a remote file to the local file path.
+	DownloadFile(bucketName string, destinationFolder string, fileName string, filePath string) error
+
 	// CheckFileIntergrity: to ensure that the local file and the upload version is identical.
 	CheckFileIntergrity(bucketName string, destinationFolder string, filePath string) (bool, error)
 
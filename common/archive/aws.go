@@ -14,6 +14,10 @@ type AWSConfig struct {
 	ExpiredReserveDataBucketName string `json:"aws_expired_reserve_data_bucket_name"`
 	LogBucketName                string `json:"aws_log_bucket_name"`
 	LogFolderPath                string `json:"aws_log_folder_path"`
+	// Endpoint is the URL of a self-hosted S3 compatible object store like MinIO,
+	// empty to use AWS S3.
+	Endpoint   string `json:"aws_endpoint"`
+	DisableSSL bool   `json:"aws_disable_ssl"`
 }
 
 func GetAWSconfigFromFile(path string) (AWSConfig, error) {
@@ -0,0 +1,60 @@
+package archive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+const (
+	// S3ArchiveType stores files in AWS S3.
+	S3ArchiveType = "s3"
+	// S3CompatibleArchiveType stores files in a self-hosted S3 compatible object store like MinIO.
+	S3CompatibleArchiveType = "s3_compatible"
+	// LocalArchiveType stores files in a local directory.
+	LocalArchiveType = "local"
+)
+
+// Config is the configuration to choose and create an archive.
+// Bucket names and credentials are shared by all archive types.
+type Config struct {
+	AWSConfig
+	// Type is one of S3ArchiveType, S3CompatibleArchiveType and LocalArchiveType, default is S3ArchiveType.
+	Type string `json:"archive_type"`
+	// LocalPath is the root directory of local archive.
+	LocalPath string `json:"archive_local_path"`
+}
+
+// GetArchiveConfigFromFile reads archive configuration from a JSON file.
+func GetArchiveConfigFromFile(path string) (Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	result := Config{}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// NewArchive creates the archive of configured type.
+func NewArchive(conf Config) (Archive, error) {
+	switch conf.Type {
+	case "", S3ArchiveType:
+		if conf.Endpoint != "" {
+			return nil, errors.New("aws_endpoint is only supported by s3_compatible archive")
+		}
+		return NewS3Archive(conf.AWSConfig), nil
+	case S3CompatibleArchiveType:
+		if conf.Endpoint == "" {
+			return nil, errors.New("aws_endpoint is required for s3_compatible archive")
+		}
+		return NewS3Archive(conf.AWSConfig), nil
+	case LocalArchiveType:
+		if conf.LocalPath == "" {
+			return nil, errors.New("archive_local_path is required for local archive")
+		}
+		return NewLocalArchive(conf.LocalPath, conf.AWSConfig), nil
+	}
+	return nil, fmt.Errorf("archive type %s is not supported", conf.Type)
+}
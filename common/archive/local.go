@@ -0,0 +1,132 @@
+package archive
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// localArchive stores files in a local directory, each bucket is a sub directory
+// of the root directory.
+type localArchive struct {
+	rootDir string
+	conf    AWSConfig
+}
+
+func (archive *localArchive) destination(bucketName string, folderPath string, filePath string) string {
+	return filepath.Join(archive.rootDir, bucketName, folderPath, getFileNameFromFilePath(filePath))
+}
+
+// copyFile copies content of srcPath to destPath, creating parent directories of destPath.
+func copyFile(srcPath string, destPath string) (err error) {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cErr := src.Close(); cErr != nil {
+			log.Printf("File close error: %s", cErr.Error())
+		}
+	}()
+	if err = os.MkdirAll(filepath.Dir(destPath), 0750); err != nil {
+		return err
+	}
+	dest, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cErr := dest.Close(); cErr != nil && err == nil {
+			err = cErr
+		}
+	}()
+	_, err = io.Copy(dest, src)
+	return err
+}
+
+func (archive *localArchive) UploadFile(bucketName string, folderPath string, filePath string) error {
+	return copyFile(filePath, archive.destination(bucketName, folderPath, filePath))
+}
+
+func (archive *localArchive) ListFiles(bucketName string, folderPath string) ([]string, error) {
+	result := []string{}
+	files, err := ioutil.ReadDir(filepath.Join(archive.rootDir, bucketName, folderPath))
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if !file.IsDir() {
+			result = append(result, file.Name())
+		}
+	}
+	return result, nil
+}
+
+func (archive *localArchive) DownloadFile(bucketName string, folderPath string, fileName string, filePath string) error {
+	return copyFile(filepath.Join(archive.rootDir, bucketName, folderPath, fileName), filePath)
+}
+
+func (archive *localArchive) RemoveFile(bucketName string, folderPath string, filePath string) error {
+	return os.Remove(archive.destination(bucketName, folderPath, filePath))
+}
+
+// fileChecksum returns sha256 checksum of the file content.
+func fileChecksum(filePath string) ([]byte, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cErr := file.Close(); cErr != nil {
+			log.Printf("File close error: %s", cErr.Error())
+		}
+	}()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+// CheckFileIntergrity compares checksum of the local file and the archived one.
+func (archive *localArchive) CheckFileIntergrity(bucketName string, folderPath string, filePath string) (bool, error) {
+	localSum, err := fileChecksum(filePath)
+	if err != nil {
+		return false, err
+	}
+	archivedSum, err := fileChecksum(archive.destination(bucketName, folderPath, filePath))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(localSum, archivedSum), nil
+}
+
+func (archive *localArchive) GetReserveDataBucketName() string {
+	return archive.conf.ExpiredReserveDataBucketName
+}
+
+func (archive *localArchive) GetStatDataBucketName() string {
+	return archive.conf.ExpiredStatDataBucketName
+}
+
+func (archive *localArchive) GetLogBucketName() string {
+	return archive.conf.LogBucketName
+}
+
+// NewLocalArchive creates an archive stores files in rootDir, using bucket names of conf.
+func NewLocalArchive(rootDir string, conf AWSConfig) *localArchive {
+	return &localArchive{
+		rootDir: rootDir,
+		conf:    conf,
+	}
+}
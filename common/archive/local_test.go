@@ -0,0 +1,73 @@
+package archive
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalArchive(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "local_archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}()
+	filePath := filepath.Join(tmpDir, "ExpiredAuthData")
+	if err = ioutil.WriteFile(filePath, []byte("auth data"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	arch, err := NewArchive(Config{
+		AWSConfig: AWSConfig{ExpiredReserveDataBucketName: "reserve-data"},
+		Type:      LocalArchiveType,
+		LocalPath: filepath.Join(tmpDir, "archive"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket := arch.GetReserveDataBucketName()
+
+	if ok, err := arch.CheckFileIntergrity(bucket, "expired-auth-data/", filePath); err != nil || ok {
+		t.Fatalf("expected file not archived yet, got %t, %v", ok, err)
+	}
+	if err = arch.UploadFile(bucket, "expired-auth-data/", filePath); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := arch.CheckFileIntergrity(bucket, "expired-auth-data/", filePath); err != nil || !ok {
+		t.Fatalf("expected archived file intact, got %t, %v", ok, err)
+	}
+
+	// same size but different content must be detected
+	if err = ioutil.WriteFile(filePath, []byte("auth date"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := arch.CheckFileIntergrity(bucket, "expired-auth-data/", filePath); err != nil || ok {
+		t.Fatalf("expected archived file corrupted, got %t, %v", ok, err)
+	}
+
+	if err = arch.RemoveFile(bucket, "expired-auth-data/", filePath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(tmpDir, "archive", "reserve-data", "expired-auth-data", "ExpiredAuthData")); !os.IsNotExist(err) {
+		t.Fatalf("expected archived file removed, got %v", err)
+	}
+}
+
+func TestNewArchiveInvalidConfig(t *testing.T) {
+	var tests = []Config{
+		{Type: LocalArchiveType},
+		{Type: S3CompatibleArchiveType},
+		{Type: S3ArchiveType, AWSConfig: AWSConfig{Endpoint: "http://127.0.0.1:9000"}},
+		{Type: "ftp"},
+	}
+	for _, conf := range tests {
+		if _, err := NewArchive(conf); err == nil {
+			t.Errorf("expected error for config %+v", conf)
+		}
+	}
+}
@@ -14,9 +14,10 @@ import (
 )
 
 type s3Archive struct {
-	uploader *s3manager.Uploader
-	svc      *s3.S3
-	awsConf  AWSConfig
+	uploader   *s3manager.Uploader
+	downloader *s3manager.Downloader
+	svc        *s3.S3
+	awsConf    AWSConfig
 }
 
 func enforceFolderPath(fp string) string {
@@ -55,6 +56,38 @@ func (archive *s3Archive) RemoveFile(bucketName string, awsfolderPath string, fi
 	return err
 }
 
+func (archive *s3Archive) ListFiles(bucketName string, awsfolderPath string) ([]string, error) {
+	result := []string{}
+	input := &s3.ListObjectsInput{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(enforceFolderPath(awsfolderPath)),
+	}
+	err := archive.svc.ListObjectsPages(input, func(page *s3.ListObjectsOutput, lastPage bool) bool {
+		for _, item := range page.Contents {
+			result = append(result, getFileNameFromFilePath(*item.Key))
+		}
+		return true
+	})
+	return result, err
+}
+
+func (archive *s3Archive) DownloadFile(bucketName string, awsfolderPath string, fileName string, filePath string) (err error) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cErr := file.Close(); cErr != nil && err == nil {
+			err = cErr
+		}
+	}()
+	_, err = archive.downloader.Download(file, &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(enforceFolderPath(awsfolderPath) + fileName),
+	})
+	return err
+}
+
 func getFileNameFromFilePath(filePath string) string {
 	elems := strings.Split(filePath, "/")
 	if len(elems) < 1 {
@@ -118,16 +151,27 @@ func (archive *s3Archive) GetLogBucketName() string {
 	return archive.awsConf.LogBucketName
 }
 
+// NewS3Archive creates an archive stores files in AWS S3, or in a S3 compatible
+// object store if conf.Endpoint is set.
 func NewS3Archive(conf AWSConfig) *s3Archive {
 
 	crdtl := credentials.NewStaticCredentials(conf.AccessKeyID, conf.SecretKey, conf.Token)
-	sess := session.Must(session.NewSession(&aws.Config{
+	awsConfig := &aws.Config{
 		Region:      aws.String(conf.Region),
 		Credentials: crdtl,
-	}))
+	}
+	if conf.Endpoint != "" {
+		// self-hosted object stores don't support virtual hosted-style bucket names
+		awsConfig = awsConfig.WithEndpoint(conf.Endpoint).
+			WithS3ForcePathStyle(true).
+			WithDisableSSL(conf.DisableSSL)
+	}
+	sess := session.Must(session.NewSession(awsConfig))
 	uploader := s3manager.NewUploader(sess)
+	downloader := s3manager.NewDownloader(sess)
 	svc := s3.New(sess)
 	archive := s3Archive{uploader,
+		downloader,
 		svc,
 		conf,
 	}
@@ -0,0 +1,98 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// AuditRecord is a mutating API call, who called it with which params and
+// its result. Records are chained by hashes, each record has the hash of
+// the previous one, so changing or removing a record breaks the chain.
+type AuditRecord struct {
+	// ID is the sequence number of the record, starting from 1.
+	ID        uint64 `json:"id"`
+	Timestamp uint64 `json:"timestamp"`
+	// Key is the API key or the shared secrets signing the call, empty if
+	// authentication is disabled.
+	Key      string              `json:"key"`
+	Method   string              `json:"method"`
+	Endpoint string              `json:"endpoint"`
+	Params   map[string][]string `json:"params"`
+	Success  bool                `json:"success"`
+	Reason   string              `json:"reason,omitempty"`
+	PrevHash string              `json:"prev_hash"`
+	Hash     string              `json:"hash"`
+}
+
+// ComputeHash returns the hex encoded sha256 of the record without its hash.
+func (self AuditRecord) ComputeHash() string {
+	self.Hash = ""
+	// params are encoded with sorted keys, so the encoding is deterministic
+	data, err := json.Marshal(self)
+	if err != nil {
+		panic(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// NextAuditRecord chains record after last, which is nil if record is the
+// first one. Timestamps of records never decrease.
+func NextAuditRecord(last *AuditRecord, record AuditRecord) AuditRecord {
+	record.ID = 1
+	record.PrevHash = ""
+	if last != nil {
+		record.ID = last.ID + 1
+		record.PrevHash = last.Hash
+		if record.Timestamp < last.Timestamp {
+			record.Timestamp = last.Timestamp
+		}
+	}
+	record.Hash = record.ComputeHash()
+	return record
+}
+
+// VerifyAuditRecords returns an error if records are not consecutive records
+// of a valid chain. The first record is only checked to link to nothing if
+// it is the first record of the chain. Removing records from the end keeps
+// the chain valid, the hash of the last record has to be compared with the
+// audit log head logged by the server.
+func VerifyAuditRecords(records []AuditRecord) error {
+	for i, record := range records {
+		if record.Hash != record.ComputeHash() {
+			return fmt.Errorf("audit record %d is modified, its hash doesn't match", record.ID)
+		}
+		if i == 0 {
+			if record.ID == 1 && record.PrevHash != "" {
+				return fmt.Errorf("audit record %d is the first but links to hash %s", record.ID, record.PrevHash)
+			}
+			continue
+		}
+		prev := records[i-1]
+		if record.ID != prev.ID+1 {
+			return fmt.Errorf("audit records %d to %d are missing", prev.ID+1, record.ID-1)
+		}
+		if record.PrevHash != prev.Hash {
+			return fmt.Errorf("audit record %d doesn't link to record %d", record.ID, prev.ID)
+		}
+	}
+	return nil
+}
+
+// AuditFilter selects audit records in [FromTime, ToTime], of a key and an
+// endpoint if they are not empty.
+type AuditFilter struct {
+	FromTime uint64
+	ToTime   uint64
+	Key      string
+	Endpoint string
+}
+
+// Match returns true if record is selected by the filter.
+func (self AuditFilter) Match(record AuditRecord) bool {
+	return record.Timestamp >= self.FromTime && record.Timestamp <= self.ToTime &&
+		(self.Key == "" || record.Key == self.Key) &&
+		(self.Endpoint == "" || record.Endpoint == self.Endpoint)
+}
@@ -0,0 +1,53 @@
+package common
+
+import "testing"
+
+func TestVerifyAuditRecords(t *testing.T) {
+	var records []AuditRecord
+	var last *AuditRecord
+	for i, endpoint := range []string{"/setrates", "/withdraw", "/settargetqty", "/confirm-pwis-equation"} {
+		record := NextAuditRecord(last, AuditRecord{Timestamp: uint64(1000 * i), Key: "pricing", Endpoint: endpoint, Success: true})
+		records = append(records, record)
+		last = &record
+	}
+	if err := VerifyAuditRecords(records); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// a range of records not starting from the first is verifiable
+	if err := VerifyAuditRecords(records[1:]); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tamper := func(fn func([]AuditRecord) []AuditRecord) []AuditRecord {
+		result := make([]AuditRecord, len(records))
+		copy(result, records)
+		return fn(result)
+	}
+	var tests = []struct {
+		msg     string
+		records []AuditRecord
+	}{
+		{"modified record", tamper(func(r []AuditRecord) []AuditRecord {
+			r[1].Success = false
+			return r
+		})},
+		{"modified record with recomputed hash", tamper(func(r []AuditRecord) []AuditRecord {
+			r[1].Key = "another"
+			r[1].Hash = r[1].ComputeHash()
+			return r
+		})},
+		{"removed record", tamper(func(r []AuditRecord) []AuditRecord {
+			return append(r[:1], r[2:]...)
+		})},
+		{"removed first record", tamper(func(r []AuditRecord) []AuditRecord {
+			r[1].ID = 1
+			r[1].Hash = r[1].ComputeHash()
+			return r[1:]
+		})},
+	}
+	for _, tc := range tests {
+		if err := VerifyAuditRecords(tc.records); err == nil {
+			t.Errorf("%s: expected verification to fail", tc.msg)
+		}
+	}
+}
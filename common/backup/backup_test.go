@@ -0,0 +1,334 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/common/archive"
+	"github.com/boltdb/bolt"
+)
+
+func newTestDB(t *testing.T, path, value string) *bolt.DB {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Update(func(tx *bolt.Tx) error {
+		b, cErr := tx.CreateBucketIfNotExists([]byte("test"))
+		if cErr != nil {
+			return cErr
+		}
+		return b.Put([]byte("key"), []byte(value))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func readTestDB(t *testing.T, path string) string {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if cErr := db.Close(); cErr != nil {
+			t.Error(cErr)
+		}
+	}()
+	var value string
+	if err = db.View(func(tx *bolt.Tx) error {
+		value = string(tx.Bucket([]byte("test")).Get([]byte("key")))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func newTestDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "test_backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() {
+		if rErr := os.RemoveAll(dir); rErr != nil {
+			t.Error(rErr)
+		}
+	}
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	dir, teardown := newTestDir(t)
+	defer teardown()
+	if err := os.MkdirAll(filepath.Join(dir, "data"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	core := newTestDB(t, filepath.Join(dir, "data", "core.db"), "core")
+	stats := newTestDB(t, filepath.Join(dir, "data", "stats.db"), "stats")
+
+	// databases are written while they are open
+	path, manifest, err := Create(filepath.Join(dir, "backup"), []*bolt.DB{core, stats}, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != BundleName(1000) || len(manifest.Files) != 2 {
+		t.Fatalf("unexpected bundle %s, manifest %+v", path, manifest)
+	}
+	if _, _, err = Create(filepath.Join(dir, "backup"), []*bolt.DB{core, core}, 2000); err == nil {
+		t.Fatal("expected error for duplicated database names")
+	}
+	for _, db := range []*bolt.DB{core, stats} {
+		if err = db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newTestDB(t, filepath.Join(dir, "data", "core.db"), "changed").Close()
+
+	destinations := map[string]string{
+		"core.db":  filepath.Join(dir, "data", "core.db"),
+		"stats.db": filepath.Join(dir, "restored", "stats.db"),
+	}
+	// nothing is replaced if any database can't be staged
+	if _, _, err = Restore(path, destinations); err == nil {
+		t.Fatal("expected error restoring to a missing directory")
+	}
+	if value := readTestDB(t, destinations["core.db"]); value != "changed" {
+		t.Errorf("expected core.db not to be replaced by a failed restore, got %s", value)
+	}
+	if _, sErr := os.Stat(destinations["core.db"] + ".restoring-1000"); !os.IsNotExist(sErr) {
+		t.Errorf("expected staged core.db to be removed, got %v", sErr)
+	}
+	if err = os.MkdirAll(filepath.Join(dir, "restored"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	restoredManifest, installed, err := Restore(path, destinations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restoredManifest, manifest) || len(installed) != 2 {
+		t.Fatalf("unexpected restore result %+v, %v", restoredManifest, installed)
+	}
+	if value := readTestDB(t, destinations["core.db"]); value != "core" {
+		t.Errorf("expected core.db restored, got %s", value)
+	}
+	if value := readTestDB(t, destinations["stats.db"]); value != "stats" {
+		t.Errorf("expected stats.db restored, got %s", value)
+	}
+	if value := readTestDB(t, destinations["core.db"]+".pre-restore-1000"); value != "changed" {
+		t.Errorf("expected replaced core.db kept, got %s", value)
+	}
+}
+
+// rewriteBundle copies the bundle at path replacing the content of the entry
+// of the given name.
+func rewriteBundle(t *testing.T, path, name string, content []byte) string {
+	in, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	gr, err := gzip.NewReader(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	for {
+		header, nErr := tr.Next()
+		if nErr == io.EOF {
+			break
+		}
+		if nErr != nil {
+			t.Fatal(nErr)
+		}
+		data, rErr := ioutil.ReadAll(tr)
+		if rErr != nil {
+			t.Fatal(rErr)
+		}
+		if header.Name == name {
+			data = content
+			header.Size = int64(len(content))
+		}
+		if err = tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	result := path + ".rewritten"
+	if err = ioutil.WriteFile(result, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestExtractInvalidBundle(t *testing.T) {
+	dir, teardown := newTestDir(t)
+	defer teardown()
+	db := newTestDB(t, filepath.Join(dir, "core.db"), "core")
+	defer db.Close()
+	path, _, err := Create(dir, []*bolt.DB{db}, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		msg    string
+		bundle string
+	}{
+		{"tampered database", rewriteBundle(t, path, "core.db", []byte("not a bolt database"))},
+		{"invalid manifest", rewriteBundle(t, path, manifestName, []byte("{"))},
+		{"missing file in manifest", rewriteBundle(t, path, manifestName, []byte(`{"timestamp": 1000, "files": []}`))},
+	}
+	for i, tc := range tests {
+		extractDir := filepath.Join(dir, "extract", string(rune('a'+i)))
+		if err = os.MkdirAll(extractDir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err = Extract(tc.bundle, extractDir); err == nil {
+			t.Errorf("%s: expected error", tc.msg)
+		}
+	}
+}
+
+func TestOutOfRetention(t *testing.T) {
+	names := []string{BundleName(3000), "core.db", BundleName(1000), BundleName(20000), BundleName(2000)}
+	var tests = []struct {
+		retention int
+		expected  []string
+	}{
+		{0, nil},
+		{4, nil},
+		{2, []string{BundleName(1000), BundleName(2000)}},
+		{1, []string{BundleName(1000), BundleName(2000), BundleName(3000)}},
+	}
+	for _, tc := range tests {
+		if result := outOfRetention(names, tc.retention); !reflect.DeepEqual(result, tc.expected) {
+			t.Errorf("retention %d: expected %v, got %v", tc.retention, tc.expected, result)
+		}
+	}
+}
+
+func TestBackuper(t *testing.T) {
+	dir, teardown := newTestDir(t)
+	defer teardown()
+	db := newTestDB(t, filepath.Join(dir, "core.db"), "core")
+	defer db.Close()
+	arch := archive.NewLocalArchive(filepath.Join(dir, "archive"), archive.AWSConfig{ExpiredReserveDataBucketName: "reserve"})
+
+	conf := Config{Dir: filepath.Join(dir, "backup"), Retention: 2, Archive: true}
+	backuper, err := NewBackuper(conf, arch, func() []*bolt.DB { return []*bolt.DB{db} })
+	if err != nil {
+		t.Fatal(err)
+	}
+	// bundle names are timestamps in millisecond, existing bundles are older
+	if err = os.MkdirAll(conf.Dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, timestamp := range []uint64{1000, 2000} {
+		if err = ioutil.WriteFile(filepath.Join(conf.Dir, BundleName(timestamp)), []byte{}, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result, err := backuper.Backup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Archived {
+		t.Fatal("expected bundle shipped to archive")
+	}
+	files, err := ioutil.ReadDir(conf.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := []string{}
+	for _, file := range files {
+		local = append(local, file.Name())
+	}
+	// ReadDir sorts by file name
+	if !reflect.DeepEqual(local, []string{filepath.Base(result.Path), BundleName(2000)}) {
+		t.Errorf("unexpected local bundles %v", local)
+	}
+	archived, err := arch.ListFiles("reserve", archiveFolder)
+	if err != nil || !reflect.DeepEqual(archived, []string{filepath.Base(result.Path)}) {
+		t.Errorf("unexpected archived bundles %v, err %v", archived, err)
+	}
+
+	downloaded, err := Download(arch, filepath.Base(result.Path), filepath.Join(dir, "download"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	extractDir := filepath.Join(dir, "extract")
+	if err = os.MkdirAll(extractDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = Extract(downloaded, extractDir); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = NewBackuper(Config{Dir: dir, Interval: "1s"}, nil, nil); err == nil {
+		t.Error("expected error for too short interval")
+	}
+	if _, err = NewBackuper(Config{Dir: dir, Archive: true}, nil, nil); err == nil {
+		t.Error("expected error for missing archive")
+	}
+}
+
+func TestBackuperStart(t *testing.T) {
+	dir, teardown := newTestDir(t)
+	defer teardown()
+	db := newTestDB(t, filepath.Join(dir, "core.db"), "core")
+	defer db.Close()
+
+	release := make(chan struct{})
+	backuper, err := NewBackuper(Config{Dir: filepath.Join(dir, "backup")}, nil, func() []*bolt.DB {
+		<-release
+		return []*bolt.DB{db}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = backuper.LastJob(); err == nil {
+		t.Error("expected error when no backup has been started")
+	}
+	job, err := backuper.Start()
+	if err != nil || !job.Running {
+		t.Fatalf("unexpected job %+v, err %v", job, err)
+	}
+	if _, err = backuper.Start(); err == nil {
+		t.Error("expected error starting a backup while another one is running")
+	}
+	close(release)
+	for i := 0; ; i++ {
+		if job, err = backuper.LastJob(); err != nil {
+			t.Fatal(err)
+		}
+		if !job.Running {
+			break
+		}
+		if i == 100 {
+			t.Fatal("backup did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if job.Error != "" || job.Result == nil || len(job.Result.Manifest.Files) != 1 {
+		t.Errorf("unexpected finished job %+v", job)
+	}
+}
@@ -0,0 +1,275 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/common/archive"
+	"github.com/boltdb/bolt"
+)
+
+// archiveFolder is the folder of bundles in the reserve data bucket of archive.
+const archiveFolder = "bolt-backup"
+
+// Config is the configuration of backups, read from the secret config file.
+type Config struct {
+	// Dir is the directory where bundles are written.
+	Dir string `json:"backup_dir"`
+	// Interval of scheduled backups, e.g 6h. Scheduled backups are disabled if it is empty.
+	Interval string `json:"backup_interval"`
+	// Retention is the number of most recent bundles kept, locally and in archive.
+	// All bundles are kept if it is 0.
+	Retention int `json:"backup_retention"`
+	// Archive ships bundles to the reserve data bucket of the configured archive.
+	Archive bool `json:"backup_archive"`
+}
+
+// GetConfigFromFile reads backup configuration from a JSON file.
+func GetConfigFromFile(path string) (Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	result := Config{}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// interval returns the parsed interval, 0 means scheduled backups are disabled.
+func (self Config) interval() (time.Duration, error) {
+	if self.Interval == "" {
+		return 0, nil
+	}
+	interval, err := time.ParseDuration(self.Interval)
+	if err != nil {
+		return 0, err
+	}
+	if interval < time.Minute {
+		return 0, fmt.Errorf("backup interval %s is shorter than a minute", interval)
+	}
+	return interval, nil
+}
+
+// Result is the outcome of a backup.
+type Result struct {
+	Path     string   `json:"path"`
+	Manifest Manifest `json:"manifest"`
+	Archived bool     `json:"archived"`
+}
+
+// Job is the status of a backup started in background.
+type Job struct {
+	Running    bool    `json:"running"`
+	StartedAt  uint64  `json:"started_at"`
+	FinishedAt uint64  `json:"finished_at,omitempty"`
+	Result     *Result `json:"result,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// Backuper creates bundles of the bolt databases opened by the process,
+// ships them to archive and applies the retention.
+type Backuper struct {
+	mu       sync.Mutex
+	conf     Config
+	interval time.Duration
+	arch     archive.Archive
+	dbs      func() []*bolt.DB
+
+	// jobMu protects job, the latest backup started with Start
+	jobMu sync.Mutex
+	job   *Job
+}
+
+// NewBackuper creates a Backuper of databases returned by dbs. arch is
+// only required if the configuration ships bundles to archive.
+func NewBackuper(conf Config, arch archive.Archive, dbs func() []*bolt.DB) (*Backuper, error) {
+	if conf.Dir == "" {
+		return nil, errors.New("backup_dir is required")
+	}
+	if conf.Retention < 0 {
+		return nil, fmt.Errorf("invalid backup retention %d", conf.Retention)
+	}
+	if conf.Archive && arch == nil {
+		return nil, errors.New("archive is required to ship backups")
+	}
+	interval, err := conf.interval()
+	if err != nil {
+		return nil, err
+	}
+	return &Backuper{
+		conf:     conf,
+		interval: interval,
+		arch:     arch,
+		dbs:      dbs,
+	}, nil
+}
+
+// Backup creates a bundle, ships it to archive if configured and removes
+// bundles out of retention. Concurrent calls are serialized.
+func (self *Backuper) Backup() (Result, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	var result Result
+	path, manifest, err := Create(self.conf.Dir, self.dbs(), common.GetTimepoint())
+	if err != nil {
+		return result, err
+	}
+	result.Path = path
+	result.Manifest = manifest
+	if self.conf.Archive {
+		if err = self.ship(path); err != nil {
+			return result, fmt.Errorf("shipping %s to archive failed: %s", path, err)
+		}
+		result.Archived = true
+	}
+	self.prune()
+	return result, nil
+}
+
+// Start starts a backup in background and returns its status, it fails if
+// the previous one started with Start is still running.
+func (self *Backuper) Start() (Job, error) {
+	self.jobMu.Lock()
+	defer self.jobMu.Unlock()
+	if self.job != nil && self.job.Running {
+		return *self.job, errors.New("a backup is already running")
+	}
+	job := &Job{Running: true, StartedAt: common.GetTimepoint()}
+	self.job = job
+	go func() {
+		result, err := self.Backup()
+		self.jobMu.Lock()
+		defer self.jobMu.Unlock()
+		job.Running = false
+		job.FinishedAt = common.GetTimepoint()
+		if err != nil {
+			log.Printf("Backup: backup started at %d failed: %s", job.StartedAt, err)
+			job.Error = err.Error()
+			return
+		}
+		job.Result = &result
+	}()
+	return *job, nil
+}
+
+// LastJob returns the status of the latest backup started with Start.
+func (self *Backuper) LastJob() (Job, error) {
+	self.jobMu.Lock()
+	defer self.jobMu.Unlock()
+	if self.job == nil {
+		return Job{}, errors.New("no backup has been started")
+	}
+	return *self.job, nil
+}
+
+func (self *Backuper) ship(path string) error {
+	bucket := self.arch.GetReserveDataBucketName()
+	if err := self.arch.UploadFile(bucket, archiveFolder, path); err != nil {
+		return err
+	}
+	ok, err := self.arch.CheckFileIntergrity(bucket, archiveFolder, path)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("archived file is corrupted")
+	}
+	return nil
+}
+
+// outOfRetention returns bundle names except the most recent retention ones.
+func outOfRetention(names []string, retention int) []string {
+	bundles := []string{}
+	for _, name := range names {
+		if isBundleName(name) {
+			bundles = append(bundles, name)
+		}
+	}
+	if retention == 0 || len(bundles) <= retention {
+		return nil
+	}
+	// names have the same length as long as timestamps have the same number of digits
+	sort.Slice(bundles, func(i, j int) bool {
+		if len(bundles[i]) != len(bundles[j]) {
+			return len(bundles[i]) < len(bundles[j])
+		}
+		return bundles[i] < bundles[j]
+	})
+	return bundles[:len(bundles)-retention]
+}
+
+// prune removes bundles out of retention, errors are logged as they are
+// retried at the next backup.
+func (self *Backuper) prune() {
+	files, err := ioutil.ReadDir(self.conf.Dir)
+	if err != nil {
+		log.Printf("Backup: listing %s failed: %s", self.conf.Dir, err)
+		return
+	}
+	names := []string{}
+	for _, file := range files {
+		if !file.IsDir() {
+			names = append(names, file.Name())
+		}
+	}
+	for _, name := range outOfRetention(names, self.conf.Retention) {
+		if rErr := os.Remove(filepath.Join(self.conf.Dir, name)); rErr != nil {
+			log.Printf("Backup: removing %s failed: %s", name, rErr)
+		}
+	}
+	if !self.conf.Archive {
+		return
+	}
+	bucket := self.arch.GetReserveDataBucketName()
+	names, err = self.arch.ListFiles(bucket, archiveFolder)
+	if err != nil {
+		log.Printf("Backup: listing archived bundles failed: %s", err)
+		return
+	}
+	for _, name := range outOfRetention(names, self.conf.Retention) {
+		if rErr := self.arch.RemoveFile(bucket, archiveFolder, name); rErr != nil {
+			log.Printf("Backup: removing archived %s failed: %s", name, rErr)
+		}
+	}
+}
+
+// Run starts scheduled backups in background if an interval is configured.
+func (self *Backuper) Run() {
+	if self.interval == 0 {
+		log.Printf("Backup: scheduled backups are disabled")
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(self.interval)
+		for range ticker.C {
+			result, err := self.Backup()
+			if err != nil {
+				log.Printf("Backup: scheduled backup failed: %s", err)
+				continue
+			}
+			log.Printf("Backup: %d databases are backed up to %s", len(result.Manifest.Files), result.Path)
+		}
+	}()
+}
+
+// Download downloads the bundle of the given name from archive to dir and
+// returns its local path.
+func Download(arch archive.Archive, name, dir string) (string, error) {
+	if !isBundleName(name) || name != filepath.Base(name) {
+		return "", fmt.Errorf("%s is not a bundle name", name)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name)
+	return path, arch.DownloadFile(arch.GetReserveDataBucketName(), archiveFolder, name, path)
+}
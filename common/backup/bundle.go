@@ -0,0 +1,394 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// manifestName is the name of the manifest entry in a bundle.
+	manifestName = "manifest.json"
+	// bundlePrefix and bundleSuffix surround the timestamp in bundle file names.
+	bundlePrefix = "backup-"
+	bundleSuffix = ".tar.gz"
+)
+
+// File describes a database file in a bundle.
+type File struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest describes the content of a bundle.
+type Manifest struct {
+	Timestamp uint64 `json:"timestamp"`
+	Files     []File `json:"files"`
+}
+
+// BundleName returns the file name of the bundle created at timestamp.
+func BundleName(timestamp uint64) string {
+	return fmt.Sprintf("%s%d%s", bundlePrefix, timestamp, bundleSuffix)
+}
+
+// isBundleName returns true if name is a file name returned by BundleName.
+func isBundleName(name string) bool {
+	return strings.HasPrefix(name, bundlePrefix) && strings.HasSuffix(name, bundleSuffix)
+}
+
+// Write writes a gzipped tar bundle of the databases to w. A read transaction
+// is started on all databases before any of them is written, so they are
+// snapshotted at about the same time while the databases stay writable.
+// Databases are named by their file names, which must be unique.
+func Write(w io.Writer, dbs []*bolt.DB, timestamp uint64) (manifest Manifest, err error) {
+	manifest = Manifest{Timestamp: timestamp, Files: []File{}}
+	txs := []*bolt.Tx{}
+	defer func() {
+		for _, tx := range txs {
+			if rErr := tx.Rollback(); rErr != nil {
+				log.Printf("Rolling back backup transaction of %s failed: %s", tx.DB().Path(), rErr)
+			}
+		}
+	}()
+	names := map[string]bool{}
+	for _, db := range dbs {
+		name := filepath.Base(db.Path())
+		if names[name] || name == manifestName {
+			return manifest, fmt.Errorf("duplicated database name %s", name)
+		}
+		names[name] = true
+		tx, bErr := db.Begin(false)
+		if bErr != nil {
+			return manifest, fmt.Errorf("starting transaction of %s failed: %s", db.Path(), bErr)
+		}
+		txs = append(txs, tx)
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	modTime := time.Unix(0, int64(timestamp)*int64(time.Millisecond))
+	for _, tx := range txs {
+		file := File{Name: filepath.Base(tx.DB().Path()), Size: tx.Size()}
+		if err = tw.WriteHeader(&tar.Header{Name: file.Name, Mode: 0600, Size: file.Size, ModTime: modTime}); err != nil {
+			return manifest, err
+		}
+		hash := sha256.New()
+		if _, err = tx.WriteTo(io.MultiWriter(tw, hash)); err != nil {
+			return manifest, fmt.Errorf("writing %s failed: %s", file.Name, err)
+		}
+		file.SHA256 = hex.EncodeToString(hash.Sum(nil))
+		manifest.Files = append(manifest.Files, file)
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+	if err = tw.WriteHeader(&tar.Header{Name: manifestName, Mode: 0600, Size: int64(len(data)), ModTime: modTime}); err != nil {
+		return manifest, err
+	}
+	if _, err = tw.Write(data); err != nil {
+		return manifest, err
+	}
+	if err = tw.Close(); err != nil {
+		return manifest, err
+	}
+	return manifest, gw.Close()
+}
+
+// Create writes a bundle of the databases to a new file in dir and returns its path.
+func Create(dir string, dbs []*bolt.DB, timestamp uint64) (string, Manifest, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", Manifest{}, err
+	}
+	path := filepath.Join(dir, BundleName(timestamp))
+	// write to a temporary file first so an incomplete bundle is never left with a bundle name
+	tmp, err := ioutil.TempFile(dir, ".backup-")
+	if err != nil {
+		return "", Manifest{}, err
+	}
+	manifest, err := Write(tmp, dbs, timestamp)
+	if cErr := tmp.Close(); cErr != nil && err == nil {
+		err = cErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		if rErr := os.Remove(tmp.Name()); rErr != nil {
+			log.Printf("Removing temporary backup file %s failed: %s", tmp.Name(), rErr)
+		}
+		return "", manifest, err
+	}
+	return path, manifest, nil
+}
+
+// Extract validates the bundle at path and extracts its database files to dir.
+// Each file is checked against the size and checksum of the manifest and must
+// be a consistent bolt database. It returns the manifest and the extracted
+// file paths by database name.
+func Extract(path, dir string) (Manifest, map[string]string, error) {
+	var manifest Manifest
+	f, err := os.Open(path)
+	if err != nil {
+		return manifest, nil, err
+	}
+	defer func() {
+		if cErr := f.Close(); cErr != nil {
+			log.Printf("Closing %s failed: %s", path, cErr)
+		}
+	}()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return manifest, nil, err
+	}
+	tr := tar.NewReader(gr)
+	extracted := map[string]File{}
+	paths := map[string]string{}
+	hasManifest := false
+	for {
+		header, nErr := tr.Next()
+		if nErr == io.EOF {
+			break
+		}
+		if nErr != nil {
+			return manifest, nil, nErr
+		}
+		name := header.Name
+		if name != filepath.Base(name) || name == "." || name == ".." {
+			return manifest, nil, fmt.Errorf("invalid file name %s in bundle", name)
+		}
+		if name == manifestName {
+			if err = json.NewDecoder(tr).Decode(&manifest); err != nil {
+				return manifest, nil, fmt.Errorf("invalid manifest: %s", err)
+			}
+			hasManifest = true
+			continue
+		}
+		file, eErr := extractFile(tr, filepath.Join(dir, name))
+		if eErr != nil {
+			return manifest, nil, fmt.Errorf("extracting %s failed: %s", name, eErr)
+		}
+		file.Name = name
+		extracted[name] = file
+		paths[name] = filepath.Join(dir, name)
+	}
+	if !hasManifest {
+		return manifest, nil, errors.New("bundle has no manifest")
+	}
+	if len(manifest.Files) != len(extracted) {
+		return manifest, nil, fmt.Errorf("manifest has %d files, bundle has %d", len(manifest.Files), len(extracted))
+	}
+	for _, expected := range manifest.Files {
+		if actual, ok := extracted[expected.Name]; !ok || actual != expected {
+			return manifest, nil, fmt.Errorf("%s does not match the manifest", expected.Name)
+		}
+		if err = checkDB(paths[expected.Name]); err != nil {
+			return manifest, nil, fmt.Errorf("%s is not a valid bolt database: %s", expected.Name, err)
+		}
+	}
+	return manifest, paths, nil
+}
+
+func extractFile(r io.Reader, path string) (file File, err error) {
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return file, err
+	}
+	defer func() {
+		if cErr := out.Close(); cErr != nil && err == nil {
+			err = cErr
+		}
+	}()
+	hash := sha256.New()
+	file.Size, err = io.Copy(io.MultiWriter(out, hash), r)
+	file.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return file, err
+}
+
+// checkDB opens the database at path and checks its consistency.
+func checkDB(path string) error {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cErr := db.Close(); cErr != nil {
+			log.Printf("Closing %s failed: %s", path, cErr)
+		}
+	}()
+	return db.View(func(tx *bolt.Tx) error {
+		var result error
+		// the channel must be drained so the check finishes before the transaction is closed
+		for cErr := range tx.Check() {
+			if result == nil {
+				result = cErr
+			}
+		}
+		return result
+	})
+}
+
+// Restore validates the bundle at path and installs its databases to the
+// paths given by database name. Databases of the bundle without a
+// destination are skipped. An existing file is kept with a .pre-restore
+// suffix. The destination files must not be in use.
+// All databases are staged next to their destinations before any of them is
+// replaced, and replaced files are put back if installing fails, so either
+// all or none of the databases are restored.
+func Restore(path string, destinations map[string]string) (Manifest, []string, error) {
+	tmpDir, err := ioutil.TempDir(filepath.Dir(path), ".restore-")
+	if err != nil {
+		return Manifest{}, nil, err
+	}
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			log.Printf("Removing %s failed: %s", tmpDir, rErr)
+		}
+	}()
+	manifest, extracted, err := Extract(path, tmpDir)
+	if err != nil {
+		return manifest, nil, err
+	}
+	for name, dest := range destinations {
+		if _, ok := extracted[name]; !ok {
+			continue
+		}
+		if err = ensureNotInUse(dest); err != nil {
+			return manifest, nil, err
+		}
+	}
+
+	steps := []*restoreStep{}
+	defer func() {
+		// staged files are left only if restoring failed
+		for _, step := range steps {
+			if rErr := os.Remove(step.staged); rErr != nil && !os.IsNotExist(rErr) {
+				log.Printf("Removing %s failed: %s", step.staged, rErr)
+			}
+		}
+	}()
+	for _, file := range manifest.Files {
+		dest, ok := destinations[file.Name]
+		if !ok {
+			log.Printf("%s has no destination, skipped", file.Name)
+			continue
+		}
+		step := &restoreStep{
+			dest:     dest,
+			staged:   fmt.Sprintf("%s.restoring-%d", dest, manifest.Timestamp),
+			previous: fmt.Sprintf("%s.pre-restore-%d", dest, manifest.Timestamp),
+		}
+		steps = append(steps, step)
+		// extracted files are copied as the destination may be on another device,
+		// the staged copy is on the same device so it can be renamed
+		if err = copyFile(extracted[file.Name], step.staged); err != nil {
+			return manifest, nil, err
+		}
+	}
+
+	for i, step := range steps {
+		if err = step.install(); err != nil {
+			for j := i; j >= 0; j-- {
+				steps[j].rollback()
+			}
+			return manifest, nil, err
+		}
+	}
+	installed := []string{}
+	for _, step := range steps {
+		if step.hasPrevious {
+			log.Printf("%s is moved to %s", step.dest, step.previous)
+		}
+		installed = append(installed, step.dest)
+	}
+	return manifest, installed, nil
+}
+
+// restoreStep replaces a database with its staged copy.
+type restoreStep struct {
+	dest        string
+	staged      string
+	previous    string
+	hasPrevious bool
+	installed   bool
+}
+
+func (self *restoreStep) install() error {
+	if _, err := os.Stat(self.dest); err == nil {
+		if err = os.Rename(self.dest, self.previous); err != nil {
+			return err
+		}
+		self.hasPrevious = true
+	}
+	if err := os.Rename(self.staged, self.dest); err != nil {
+		return err
+	}
+	self.installed = true
+	return nil
+}
+
+// rollback puts the replaced database back, errors are logged as the
+// replaced file is kept with its .pre-restore suffix anyway.
+func (self *restoreStep) rollback() {
+	if self.installed {
+		if err := os.Remove(self.dest); err != nil {
+			log.Printf("Removing %s failed: %s", self.dest, err)
+			return
+		}
+	}
+	if self.hasPrevious {
+		if err := os.Rename(self.previous, self.dest); err != nil {
+			log.Printf("Moving %s back to %s failed: %s", self.previous, self.dest, err)
+		}
+	}
+}
+
+// ensureNotInUse returns an error if the database at path is opened by
+// another process.
+func ensureNotInUse(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return fmt.Errorf("%s is in use, stop the core before restoring: %s", path, err)
+	}
+	return db.Close()
+}
+
+func copyFile(src, dest string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cErr := in.Close(); cErr != nil {
+			log.Printf("Closing %s failed: %s", src, cErr)
+		}
+	}()
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cErr := out.Close(); cErr != nil && err == nil {
+			err = cErr
+		}
+	}()
+	_, err = io.Copy(out, in)
+	return err
+}
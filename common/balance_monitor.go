@@ -0,0 +1,128 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+
+	ethereum "github.com/ethereum/go-ethereum/common"
+)
+
+// Accounts that allowance thresholds can refer to by name instead of address.
+const (
+	ReserveAccount = "reserve"
+	WrapperAccount = "wrapper"
+	NetworkAccount = "network"
+)
+
+// AllowanceThreshold is the minimum amount of a token the owner has to allow the spender to transfer.
+// Owner and spender are either account names (reserve, wrapper, network) or addresses.
+type AllowanceThreshold struct {
+	Token     string  `json:"token"`
+	Owner     string  `json:"owner"`
+	Spender   string  `json:"spender"`
+	Threshold float64 `json:"threshold"`
+}
+
+// Key identifies the allowance in notifications and between checks.
+func (self AllowanceThreshold) Key() string {
+	return fmt.Sprintf("%s-%s-%s", self.Token, self.Owner, self.Spender)
+}
+
+func validAllowanceAccount(account string) bool {
+	switch account {
+	case ReserveAccount, WrapperAccount, NetworkAccount:
+		return true
+	}
+	return ethereum.IsHexAddress(account)
+}
+
+// BalanceThresholds are the minimum balances the reserve needs to operate.
+type BalanceThresholds struct {
+	// Operators are the minimum ETH balances, keyed by operator name.
+	Operators map[string]float64 `json:"operators"`
+	// Tokens are the minimum reserve balances, keyed by token ID.
+	Tokens map[string]float64 `json:"tokens"`
+	// Allowances are the minimum ERC20 allowances, e.g. of the reserve to the wrapper.
+	Allowances []AllowanceThreshold `json:"allowances"`
+	// MinDaysLeft is the minimum days of gas an operator should have at its recent spend rate,
+	// 0 disables the check.
+	MinDaysLeft float64 `json:"min_days_left"`
+}
+
+// Validate returns an error if any threshold is negative or refers to an unsupported token
+// or an unknown account.
+func (self BalanceThresholds) Validate() error {
+	if self.MinDaysLeft < 0 {
+		return errors.New("min days left must not be negative")
+	}
+	for name, threshold := range self.Operators {
+		if threshold < 0 {
+			return fmt.Errorf("threshold of operator %s must not be negative", name)
+		}
+	}
+	for tokenID, threshold := range self.Tokens {
+		if _, err := GetInternalToken(tokenID); err != nil {
+			return fmt.Errorf("token %s is not supported", tokenID)
+		}
+		if threshold < 0 {
+			return fmt.Errorf("threshold of token %s must not be negative", tokenID)
+		}
+	}
+	for _, allowance := range self.Allowances {
+		if _, err := GetInternalToken(allowance.Token); err != nil {
+			return fmt.Errorf("token %s is not supported", allowance.Token)
+		}
+		if !validAllowanceAccount(allowance.Owner) || !validAllowanceAccount(allowance.Spender) {
+			return fmt.Errorf("allowance %s refers to an unknown account", allowance.Key())
+		}
+		if allowance.Threshold < 0 {
+			return fmt.Errorf("threshold of allowance %s must not be negative", allowance.Key())
+		}
+	}
+	return nil
+}
+
+// OperatorBalanceStatus is the ETH balance of an operator against its threshold.
+type OperatorBalanceStatus struct {
+	Name      string  `json:"name"`
+	Address   string  `json:"address"`
+	Balance   float64 `json:"balance"`
+	Threshold float64 `json:"threshold"`
+	// DailySpend is the average ETH spent for gas per day recently.
+	DailySpend float64 `json:"daily_spend"`
+	// DaysLeft is how many days the balance lasts at DailySpend, nil if the operator spent nothing.
+	DaysLeft *float64 `json:"days_left,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	Low      bool     `json:"low"`
+	// LowSince is the timepoint the balance became low, 0 if it is not.
+	LowSince uint64 `json:"low_since"`
+}
+
+// TokenBalanceStatus is the reserve balance of a token against its threshold.
+type TokenBalanceStatus struct {
+	Token     string  `json:"token"`
+	Balance   float64 `json:"balance"`
+	Threshold float64 `json:"threshold"`
+	Error     string  `json:"error,omitempty"`
+	Low       bool    `json:"low"`
+	LowSince  uint64  `json:"low_since"`
+}
+
+// AllowanceStatus is an ERC20 allowance against its threshold.
+type AllowanceStatus struct {
+	AllowanceThreshold
+	OwnerAddress   string  `json:"owner_address"`
+	SpenderAddress string  `json:"spender_address"`
+	Allowance      float64 `json:"allowance"`
+	Error          string  `json:"error,omitempty"`
+	Low            bool    `json:"low"`
+	LowSince       uint64  `json:"low_since"`
+}
+
+// BalanceStatus is the result of a balance monitor check.
+type BalanceStatus struct {
+	Timestamp  uint64                  `json:"timestamp"`
+	Operators  []OperatorBalanceStatus `json:"operators"`
+	Tokens     []TokenBalanceStatus    `json:"tokens"`
+	Allowances []AllowanceStatus       `json:"allowances"`
+}
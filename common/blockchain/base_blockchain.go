@@ -258,6 +258,18 @@ func (self *BaseBlockchain) CurrentBlock() (uint64, error) {
 	return result, err
 }
 
+// CurrentHead returns the number and the time, in millisecond, of the
+// latest block of the node.
+func (self *BaseBlockchain) CurrentHead() (uint64, uint64, error) {
+	timeout, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	header, err := self.client.HeaderByNumber(timeout, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	return header.Number.Uint64(), header.Time.Uint64() * 1000, nil
+}
+
 func (self *BaseBlockchain) PackERC20Data(method string, params ...interface{}) ([]byte, error) {
 	return self.erc20abi.Pack(method, params...)
 }
@@ -375,6 +387,52 @@ func (self *BaseBlockchain) TxStatus(hash ethereum.Hash) (string, uint64, error)
 	return "failed", tx.BlockNumber().Uint64(), nil
 }
 
+// TxCost returns the sender, gas used, gas price and block time of a mined transaction from its receipt.
+func (self *BaseBlockchain) TxCost(hash ethereum.Hash) (common.TxCost, error) {
+	option := context.Background()
+	tx, pending, err := self.TransactionByHash(option, hash)
+	if err != nil {
+		return common.TxCost{}, err
+	}
+	if pending {
+		return common.TxCost{}, fmt.Errorf("transaction %s is not mined yet", hash.Hex())
+	}
+	receipt, err := self.client.TransactionReceipt(option, hash)
+	// incompatibily between geth and parity, receipt is still valid even err is not nil
+	if receipt == nil {
+		if err == nil {
+			err = fmt.Errorf("receipt of transaction %s is not found", hash.Hex())
+		}
+		return common.TxCost{}, err
+	}
+	blockTime, err := self.InterpretTimestamp(tx.BlockNumber().Uint64(), 0)
+	if err != nil {
+		return common.TxCost{}, fmt.Errorf("cannot get block time of transaction %s: %s", hash.Hex(), err)
+	}
+	return common.TxCost{
+		From:        tx.From,
+		GasUsed:     receipt.GasUsed,
+		GasPrice:    tx.tx.GasPrice(),
+		BlockNumber: tx.BlockNumber().Uint64(),
+		BlockTime:   blockTime / uint64(time.Millisecond),
+	}, nil
+}
+
+// GetEthBalance returns the current ETH balance of the address in wei.
+func (self *BaseBlockchain) GetEthBalance(addr ethereum.Address) (*big.Int, error) {
+	timeout, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return self.client.BalanceAt(timeout, addr, nil)
+}
+
+// GetAllowance returns the amount of token the owner currently allows the spender to transfer.
+func (self *BaseBlockchain) GetAllowance(token, owner, spender ethereum.Address) (*big.Int, error) {
+	out := new(*big.Int)
+	contract := &Contract{Address: token, ABI: self.erc20abi}
+	err := self.Call(5*time.Second, self.GetCallOpts(0), contract, out, "allowance", owner, spender)
+	return *out, err
+}
+
 func (self *BaseBlockchain) GetEthRate(timepoint uint64) float64 {
 	rate := self.ethRate.GetUSDRate(timepoint)
 	log.Printf("ETH-USD rate: %f", rate)
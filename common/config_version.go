@@ -0,0 +1,169 @@
+package common
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Config types of the reserve, their proposals and versions are managed by
+// the config APIs.
+const (
+	TargetQtyConfig          = "target_qty"
+	TargetQtyV2Config        = "target_qty_v2"
+	PWIEquationConfig        = "pwi_equation"
+	PWIEquationV2Config      = "pwi_equation_v2"
+	RebalanceQuadraticConfig = "rebalance_quadratic"
+	StableTokenParamsConfig  = "stable_token_params"
+)
+
+// Operations of config changes, named after JSON patch operations.
+const (
+	ConfigAdd     = "add"
+	ConfigRemove  = "remove"
+	ConfigReplace = "replace"
+)
+
+// ErrConfigVersionNotFound is the error of config versions which don't exist.
+var ErrConfigVersionNotFound = errors.New("config version doesn't exist")
+
+// ConfigProposal is a pending change of a config type, it takes effect once
+// it is confirmed.
+type ConfigProposal struct {
+	Type       string          `json:"type"`
+	Value      json.RawMessage `json:"value"`
+	Proposer   string          `json:"proposer"`
+	ProposedAt uint64          `json:"proposed_at"`
+	// RollbackOf is the version restored by the proposal, 0 if it is not a
+	// rollback.
+	RollbackOf uint64 `json:"rollback_of,omitempty"`
+	// Hash identifies the proposal, confirmers give it to confirm exactly
+	// the proposal they reviewed.
+	Hash string `json:"hash"`
+}
+
+// ComputeHash returns the hex encoded sha256 of the proposal without its hash.
+func (self ConfigProposal) ComputeHash() string {
+	self.Hash = ""
+	data, err := json.Marshal(self)
+	if err != nil {
+		panic(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// NewConfigProposal returns a proposal of value, which is compacted so
+// proposals of the same value are stored the same.
+func NewConfigProposal(configType string, value []byte, proposer string, proposedAt uint64) (ConfigProposal, error) {
+	buf := &bytes.Buffer{}
+	if err := json.Compact(buf, value); err != nil {
+		return ConfigProposal{}, fmt.Errorf("invalid value of config %s: %s", configType, err)
+	}
+	result := ConfigProposal{
+		Type:       configType,
+		Value:      json.RawMessage(buf.Bytes()),
+		Proposer:   proposer,
+		ProposedAt: proposedAt,
+	}
+	result.Hash = result.ComputeHash()
+	return result, nil
+}
+
+// ConfigVersion is a confirmed config. Versions of a config type are
+// numbered from 1, the latest one is the current config.
+type ConfigVersion struct {
+	Type        string          `json:"type"`
+	Version     uint64          `json:"version"`
+	Value       json.RawMessage `json:"value"`
+	Proposer    string          `json:"proposer"`
+	ProposedAt  uint64          `json:"proposed_at"`
+	Confirmer   string          `json:"confirmer"`
+	ConfirmedAt uint64          `json:"confirmed_at"`
+	// RollbackOf is the version restored by this version, 0 if it is not a
+	// rollback.
+	RollbackOf uint64 `json:"rollback_of,omitempty"`
+}
+
+// NextConfigVersion returns proposal confirmed by confirmer at confirmedAt as
+// the version after last, which is nil if it is the first version.
+func NextConfigVersion(last *ConfigVersion, proposal ConfigProposal, confirmer string, confirmedAt uint64) ConfigVersion {
+	result := ConfigVersion{
+		Type:        proposal.Type,
+		Version:     1,
+		Value:       proposal.Value,
+		Proposer:    proposal.Proposer,
+		ProposedAt:  proposal.ProposedAt,
+		Confirmer:   confirmer,
+		ConfirmedAt: confirmedAt,
+		RollbackOf:  proposal.RollbackOf,
+	}
+	if last != nil {
+		result.Version = last.Version + 1
+	}
+	return result
+}
+
+// ConfigChange is a change of a value at path between two configs. Path is
+// a JSON pointer, e.g /KNC/a.
+type ConfigChange struct {
+	Op   string      `json:"op"`
+	Path string      `json:"path"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// DiffConfigs returns the changes from one config to another sorted by path.
+// Objects are compared key by key, other values including arrays are
+// compared as a whole.
+func DiffConfigs(from, to []byte) ([]ConfigChange, error) {
+	var fromValue, toValue interface{}
+	if err := json.Unmarshal(from, &fromValue); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(to, &toValue); err != nil {
+		return nil, err
+	}
+	result := []ConfigChange{}
+	diffConfigValues("", fromValue, toValue, &result)
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result, nil
+}
+
+func diffConfigValues(path string, from, to interface{}, result *[]ConfigChange) {
+	fromObject, fromIsObject := from.(map[string]interface{})
+	toObject, toIsObject := to.(map[string]interface{})
+	if !fromIsObject || !toIsObject {
+		if !reflect.DeepEqual(from, to) {
+			*result = append(*result, ConfigChange{Op: ConfigReplace, Path: path, From: from, To: to})
+		}
+		return
+	}
+	for key, fromValue := range fromObject {
+		keyPath := path + "/" + escapeJSONPointer(key)
+		toValue, ok := toObject[key]
+		if !ok {
+			*result = append(*result, ConfigChange{Op: ConfigRemove, Path: keyPath, From: fromValue})
+			continue
+		}
+		diffConfigValues(keyPath, fromValue, toValue, result)
+	}
+	for key, toValue := range toObject {
+		if _, ok := fromObject[key]; !ok {
+			*result = append(*result, ConfigChange{Op: ConfigAdd, Path: path + "/" + escapeJSONPointer(key), To: toValue})
+		}
+	}
+}
+
+// escapeJSONPointer escapes a key as a reference token of JSON pointers.
+func escapeJSONPointer(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
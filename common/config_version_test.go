@@ -0,0 +1,112 @@
+package common
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	var tests = []struct {
+		from     string
+		to       string
+		expected []ConfigChange
+	}{
+		{
+			from:     `{"KNC":{"a":1,"b":2}}`,
+			to:       `{"KNC":{"b":2,"a":1}}`,
+			expected: []ConfigChange{},
+		},
+		{
+			from: `{"KNC":{"a":1,"b":2},"OMG":{"a":1}}`,
+			to:   `{"KNC":{"a":1.5,"c":3},"ZRX":{"a":1}}`,
+			expected: []ConfigChange{
+				{Op: ConfigReplace, Path: "/KNC/a", From: 1.0, To: 1.5},
+				{Op: ConfigRemove, Path: "/KNC/b", From: 2.0},
+				{Op: ConfigAdd, Path: "/KNC/c", To: 3.0},
+				{Op: ConfigRemove, Path: "/OMG", From: map[string]interface{}{"a": 1.0}},
+				{Op: ConfigAdd, Path: "/ZRX", To: map[string]interface{}{"a": 1.0}},
+			},
+		},
+		{
+			// arrays are compared as a whole
+			from: `{"steps":[1,2]}`,
+			to:   `{"steps":[1,3]}`,
+			expected: []ConfigChange{
+				{Op: ConfigReplace, Path: "/steps", From: []interface{}{1.0, 2.0}, To: []interface{}{1.0, 3.0}},
+			},
+		},
+		{
+			from: `{"a/b":1,"c~d":1}`,
+			to:   `{"a/b":2,"c~d":2}`,
+			expected: []ConfigChange{
+				{Op: ConfigReplace, Path: "/a~1b", From: 1.0, To: 2.0},
+				{Op: ConfigReplace, Path: "/c~0d", From: 1.0, To: 2.0},
+			},
+		},
+		{
+			from: `null`,
+			to:   `{"a":1}`,
+			expected: []ConfigChange{
+				{Op: ConfigReplace, Path: "", To: map[string]interface{}{"a": 1.0}},
+			},
+		},
+	}
+	for _, tc := range tests {
+		changes, err := DiffConfigs([]byte(tc.from), []byte(tc.to))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(changes, tc.expected) {
+			t.Errorf("expected changes %+v from %s to %s, got %+v", tc.expected, tc.from, tc.to, changes)
+		}
+	}
+
+	if _, err := DiffConfigs([]byte(`{`), []byte(`{}`)); err == nil {
+		t.Error("expected error of invalid config")
+	}
+}
+
+func TestConfigProposal(t *testing.T) {
+	a, err := NewConfigProposal(RebalanceQuadraticConfig, []byte("{\"KNC\": {\n\"a\": 1}}"), "kn_configuration", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(a.Value) != `{"KNC":{"a":1}}` {
+		t.Errorf("expected value to be compacted, got %s", a.Value)
+	}
+	if a.Hash == "" || a.Hash != a.ComputeHash() {
+		t.Errorf("unexpected hash %s", a.Hash)
+	}
+	b, err := NewConfigProposal(RebalanceQuadraticConfig, []byte(`{"KNC":{"a":1}}`), "kn_configuration", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Hash != b.Hash {
+		t.Error("expected proposals of the same value to have the same hash")
+	}
+	b.RollbackOf = 1
+	if a.Hash == b.ComputeHash() {
+		t.Error("expected rollback to change the hash")
+	}
+	if _, err = NewConfigProposal(RebalanceQuadraticConfig, []byte(`{"KNC":`), "kn_configuration", 1000); err == nil {
+		t.Error("expected error of invalid value")
+	}
+
+	first := NextConfigVersion(nil, a, "kn_confirm_configuration", 2000)
+	expected := ConfigVersion{
+		Type:        RebalanceQuadraticConfig,
+		Version:     1,
+		Value:       json.RawMessage(`{"KNC":{"a":1}}`),
+		Proposer:    "kn_configuration",
+		ProposedAt:  1000,
+		Confirmer:   "kn_confirm_configuration",
+		ConfirmedAt: 2000,
+	}
+	if !reflect.DeepEqual(first, expected) {
+		t.Errorf("expected first version %+v, got %+v", expected, first)
+	}
+	if second := NextConfigVersion(&first, b, "kn_confirm_configuration", 3000); second.Version != 2 || second.RollbackOf != 1 {
+		t.Errorf("unexpected second version %+v", second)
+	}
+}
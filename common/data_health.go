@@ -0,0 +1,120 @@
+package common
+
+import (
+	"fmt"
+	"time"
+)
+
+// Kinds of fetched data, each has its own stale limit.
+const (
+	OrderbookDataKind = "orderbook"
+	AuthDataKind      = "authdata"
+	RateDataKind      = "rates"
+	BlockDataKind     = "block"
+	GoldDataKind      = "gold"
+)
+
+// StaleLimits is the maximum age of each kind of data before it is stale.
+type StaleLimits map[string]time.Duration
+
+// DefaultStaleLimits returns the stale limits used for kinds of data which
+// are not configured.
+func DefaultStaleLimits() StaleLimits {
+	return StaleLimits{
+		OrderbookDataKind: 30 * time.Second,
+		AuthDataKind:      30 * time.Second,
+		RateDataKind:      30 * time.Second,
+		BlockDataKind:     time.Minute,
+		GoldDataKind:      2 * time.Minute,
+	}
+}
+
+// NewStaleLimits parses limits of the given kinds of data, e.g {"orderbook": "30s"},
+// kinds which are not given use the default limits.
+func NewStaleLimits(limits map[string]string) (StaleLimits, error) {
+	result := DefaultStaleLimits()
+	for kind, limit := range limits {
+		if _, ok := result[kind]; !ok {
+			return nil, fmt.Errorf("unknown kind of data %s", kind)
+		}
+		duration, err := time.ParseDuration(limit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stale limit of %s: %s", kind, err)
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("stale limit of %s must be positive", kind)
+		}
+		result[kind] = duration
+	}
+	return result, nil
+}
+
+// Limit returns the stale limit of a kind of data in millisecond.
+func (self StaleLimits) Limit(kind string) uint64 {
+	limit, ok := self[kind]
+	if !ok {
+		limit = DefaultStaleLimits()[kind]
+	}
+	return uint64(limit / time.Millisecond)
+}
+
+// SourceHealth is the fetch health of a kind of data from a source, which is
+// an exchange, the blockchain or gold feeds. Durations and ages are in
+// millisecond.
+type SourceHealth struct {
+	Source string `json:"source"`
+	Data   string `json:"data"`
+	// Fetches, Successes and SuccessRate are of the latest fetches only.
+	Fetches      int     `json:"fetches"`
+	Successes    int     `json:"successes"`
+	SuccessRate  float64 `json:"success_rate"`
+	LastDuration uint64  `json:"last_duration"`
+	AvgDuration  uint64  `json:"avg_duration"`
+	MaxDuration  uint64  `json:"max_duration"`
+	LastFetch    uint64  `json:"last_fetch"`
+	LastSuccess  uint64  `json:"last_success"`
+	LastError    string  `json:"last_error,omitempty"`
+	// DataTime is the time of the data of the last success, DataAge is its age.
+	DataTime   uint64 `json:"data_time"`
+	DataAge    uint64 `json:"data_age"`
+	StaleLimit uint64 `json:"stale_limit"`
+	// Stale is true if there is no success yet or the data is older than the stale limit.
+	Stale bool `json:"stale"`
+}
+
+// SnapshotHealth is the age of the latest stored snapshot of a kind of data,
+// served by /prices, /authdata, /getrates and /gold-feed. Ages are in millisecond.
+type SnapshotHealth struct {
+	Version    Version `json:"version"`
+	Valid      bool    `json:"valid"`
+	Error      string  `json:"error,omitempty"`
+	Age        uint64  `json:"age"`
+	StaleLimit uint64  `json:"stale_limit"`
+	// Stale is true if there is no snapshot or it is older than the stale limit.
+	Stale bool `json:"stale"`
+}
+
+// DataHealth is the freshness of all fetched data, Stale is true if any
+// snapshot or source is stale.
+type DataHealth struct {
+	Timestamp uint64                    `json:"timestamp"`
+	Stale     bool                      `json:"stale"`
+	Snapshots map[string]SnapshotHealth `json:"snapshots"`
+	Sources   []SourceHealth            `json:"sources"`
+}
+
+// NewDataHealth creates the data health of snapshots and sources at timepoint.
+func NewDataHealth(timepoint uint64, snapshots map[string]SnapshotHealth, sources []SourceHealth) DataHealth {
+	result := DataHealth{
+		Timestamp: timepoint,
+		Snapshots: snapshots,
+		Sources:   sources,
+	}
+	for _, snapshot := range snapshots {
+		result.Stale = result.Stale || snapshot.Stale
+	}
+	for _, source := range sources {
+		result.Stale = result.Stale || source.Stale
+	}
+	return result
+}
@@ -0,0 +1,35 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewStaleLimits(t *testing.T) {
+	limits, err := NewStaleLimits(map[string]string{OrderbookDataKind: "10s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits.Limit(OrderbookDataKind) != 10000 {
+		t.Errorf("expected orderbook limit 10000ms, got %d", limits.Limit(OrderbookDataKind))
+	}
+	if limits[AuthDataKind] != DefaultStaleLimits()[AuthDataKind] {
+		t.Errorf("expected default authdata limit, got %s", limits[AuthDataKind])
+	}
+	for _, invalid := range []map[string]string{
+		{"unknown": "10s"},
+		{RateDataKind: "ten seconds"},
+		{RateDataKind: "-1s"},
+	} {
+		if _, err := NewStaleLimits(invalid); err == nil {
+			t.Errorf("expected error for %v", invalid)
+		}
+	}
+
+	health := NewDataHealth(1000, map[string]SnapshotHealth{
+		OrderbookDataKind: {Age: 10, StaleLimit: uint64(time.Second / time.Millisecond)},
+	}, []SourceHealth{{Source: "binance", Data: OrderbookDataKind, Stale: true}})
+	if !health.Stale {
+		t.Error("expected data health with a stale source to be stale")
+	}
+}
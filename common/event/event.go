@@ -0,0 +1,144 @@
+package event
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+// Topics of events published when data is stored.
+const (
+	// PriceTopic events are published when a new price version is stored.
+	PriceTopic = "prices"
+	// AuthDataTopic events are published when a new auth data snapshot is stored.
+	AuthDataTopic = "authdata"
+	// RateTopic events are published when a new rate entry is stored.
+	RateTopic = "rates"
+	// ActivityTopic events are published when an activity is recorded or
+	// its status changes.
+	ActivityTopic = "activities"
+)
+
+// Topics are all topics of events.
+var Topics = []string{PriceTopic, AuthDataTopic, RateTopic, ActivityTopic}
+
+// VersionData is the data of events of new versions of prices, auth data
+// and rates.
+type VersionData struct {
+	Version     uint64 `json:"version"`
+	BlockNumber uint64 `json:"block_number,omitempty"`
+}
+
+// Event is a message pushed to subscribers of its topic.
+type Event struct {
+	ID        uint64      `json:"id"`
+	Topic     string      `json:"topic"`
+	Timestamp uint64      `json:"timestamp"`
+	Data      interface{} `json:"data"`
+	// Dropped is the number of events dropped before this one because the
+	// subscriber was too slow, it should resync by polling if it isn't 0.
+	Dropped uint64 `json:"dropped,omitempty"`
+}
+
+// Publisher publishes events of stored data.
+type Publisher interface {
+	Publish(topic string, data interface{})
+}
+
+// Subscription receives events of its topics.
+type Subscription struct {
+	topics  map[string]bool
+	events  chan Event
+	dropped uint64
+}
+
+// Events returns the channel of events, it is closed on unsubscribing.
+func (self *Subscription) Events() <-chan Event {
+	return self.events
+}
+
+// send delivers event without blocking, it is dropped if the buffer of the
+// subscription is full.
+func (self *Subscription) send(event Event) {
+	event.Dropped = self.dropped
+	select {
+	case self.events <- event:
+		self.dropped = 0
+	default:
+		self.dropped++
+	}
+}
+
+// Hub publishes events to its subscriptions. Publishing never blocks, every
+// subscription has a buffer of events and events are dropped for slow
+// subscribers rather than delaying the fetcher or other subscribers.
+type Hub struct {
+	mu            sync.Mutex
+	lastID        uint64
+	subscriptions map[*Subscription]bool
+}
+
+// NewHub creates a hub without subscriptions.
+func NewHub() *Hub {
+	return &Hub{subscriptions: map[*Subscription]bool{}}
+}
+
+// Publish sends an event of topic with data to subscriptions of topic.
+func (self *Hub) Publish(topic string, data interface{}) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.lastID++
+	event := Event{
+		ID:        self.lastID,
+		Topic:     topic,
+		Timestamp: common.GetTimepoint(),
+		Data:      data,
+	}
+	for subscription := range self.subscriptions {
+		if subscription.topics[topic] {
+			subscription.send(event)
+		}
+	}
+}
+
+// Subscribe returns a subscription of topics, all topics if it is empty,
+// which buffers at most buffer events.
+func (self *Hub) Subscribe(topics []string, buffer int) (*Subscription, error) {
+	if len(topics) == 0 {
+		topics = Topics
+	}
+	subscription := &Subscription{
+		topics: map[string]bool{},
+		events: make(chan Event, buffer),
+	}
+	for _, topic := range topics {
+		if !isTopic(topic) {
+			return nil, fmt.Errorf("unknown topic %s", topic)
+		}
+		subscription.topics[topic] = true
+	}
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.subscriptions[subscription] = true
+	return subscription, nil
+}
+
+// Unsubscribe stops sending events to subscription and closes its channel.
+func (self *Hub) Unsubscribe(subscription *Subscription) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.subscriptions[subscription] {
+		delete(self.subscriptions, subscription)
+		close(subscription.events)
+	}
+}
+
+func isTopic(topic string) bool {
+	for _, t := range Topics {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package event
+
+import (
+	"reflect"
+	"testing"
+)
+
+func receive(subscription *Subscription) []Event {
+	result := []Event{}
+	for {
+		select {
+		case e := <-subscription.Events():
+			result = append(result, e)
+		default:
+			return result
+		}
+	}
+}
+
+func TestHub(t *testing.T) {
+	hub := NewHub()
+	if _, err := hub.Subscribe([]string{"orders"}, 10); err == nil {
+		t.Error("expected error subscribing unknown topic")
+	}
+	prices, err := hub.Subscribe([]string{PriceTopic}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := hub.Subscribe(nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub.Publish(PriceTopic, VersionData{Version: 1})
+	hub.Publish(ActivityTopic, "activity")
+
+	topics := func(events []Event) []string {
+		result := []string{}
+		for _, e := range events {
+			result = append(result, e.Topic)
+		}
+		return result
+	}
+	if result := topics(receive(prices)); !reflect.DeepEqual(result, []string{PriceTopic}) {
+		t.Errorf("expected price events, got %v", result)
+	}
+	if result := topics(receive(all)); !reflect.DeepEqual(result, []string{PriceTopic, ActivityTopic}) {
+		t.Errorf("expected events of all topics, got %v", result)
+	}
+
+	hub.Unsubscribe(prices)
+	hub.Publish(PriceTopic, VersionData{Version: 2})
+	if _, ok := <-prices.Events(); ok {
+		t.Error("expected events of unsubscribed subscription to be closed")
+	}
+	if events := receive(all); len(events) != 1 || events[0].ID != 3 {
+		t.Errorf("expected the third event, got %+v", events)
+	}
+}
+
+func TestHubSlowSubscriber(t *testing.T) {
+	hub := NewHub()
+	slow, err := hub.Subscribe(nil, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fast, err := hub.Subscribe(nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		hub.Publish(RateTopic, VersionData{Version: uint64(i)})
+	}
+	if events := receive(fast); len(events) != 5 {
+		t.Errorf("expected fast subscriber to receive all events, got %d", len(events))
+	}
+	if events := receive(slow); len(events) != 2 || events[1].Dropped != 0 {
+		t.Errorf("expected slow subscriber to receive buffered events, got %+v", events)
+	}
+	hub.Publish(RateTopic, VersionData{Version: 5})
+	events := receive(slow)
+	if len(events) != 1 || events[0].Dropped != 3 || events[0].ID != 6 {
+		t.Errorf("expected event with 3 dropped events before it, got %+v", events)
+	}
+}
@@ -0,0 +1,150 @@
+package common
+
+import (
+	"math/big"
+
+	ethereum "github.com/ethereum/go-ethereum/common"
+)
+
+// dayInMillisecond is the length of a daily gas spend rollup.
+const dayInMillisecond uint64 = 86400000
+
+// TxCost is the gas cost of a mined transaction, read from its receipt.
+type TxCost struct {
+	From        ethereum.Address
+	GasUsed     uint64
+	GasPrice    *big.Int
+	BlockNumber uint64
+	// BlockTime is the timestamp of the block mined the transaction in millisecond.
+	BlockTime uint64
+}
+
+// Fee returns the amount of ETH spent for the transaction.
+func (self TxCost) Fee() float64 {
+	if self.GasPrice == nil {
+		return 0
+	}
+	wei := new(big.Int).Mul(new(big.Int).SetUint64(self.GasUsed), self.GasPrice)
+	return BigToFloat(wei, 18)
+}
+
+// GasSpendRecord is the gas an operator spent for one of our transactions.
+type GasSpendRecord struct {
+	TxHash     string
+	Operator   string
+	Action     string
+	ActivityID ActivityID
+	Failed     bool
+	// Replacements is the number of transactions replaced by this one with a higher gas price.
+	Replacements uint64
+	BlockNumber  uint64
+	GasUsed      uint64
+	GasPrice     *big.Int
+	// Fee is in ETH, FeeUSD is converted with ETH-USD rate at mining time.
+	Fee       float64
+	FeeUSD    float64
+	Timestamp uint64
+}
+
+// NewGasSpendRecord creates a new GasSpendRecord instance.
+func NewGasSpendRecord(txHash, action string, activityID ActivityID, failed bool, replacements uint64, cost TxCost, ethUSDRate float64, timestamp uint64) GasSpendRecord {
+	fee := cost.Fee()
+	return GasSpendRecord{
+		TxHash:       txHash,
+		Operator:     AddrToString(cost.From),
+		Action:       action,
+		ActivityID:   activityID,
+		Failed:       failed,
+		Replacements: replacements,
+		BlockNumber:  cost.BlockNumber,
+		GasUsed:      cost.GasUsed,
+		GasPrice:     cost.GasPrice,
+		Fee:          fee,
+		FeeUSD:       fee * ethUSDRate,
+		Timestamp:    timestamp,
+	}
+}
+
+// PendingGasSpend is a transaction which left pending and is waiting for its gas spend
+// to be recorded. It is persisted, so it survives restarts and failed receipt lookups.
+type PendingGasSpend struct {
+	TxHash     string
+	Action     string
+	ActivityID ActivityID
+	Failed     bool
+	// Replacements is the number of transactions replaced by this one with a higher gas price.
+	Replacements uint64
+}
+
+// GasSpendStat is the aggregation of multiple gas spend records.
+type GasSpendStat struct {
+	TxCount      uint64
+	FailedCount  uint64
+	Replacements uint64
+	GasUsed      uint64
+	Fee          float64
+	FeeUSD       float64
+}
+
+// add aggregates the record to the stat.
+func (self GasSpendStat) add(record GasSpendRecord) GasSpendStat {
+	self.TxCount++
+	if record.Failed {
+		self.FailedCount++
+	}
+	self.Replacements += record.Replacements
+	self.GasUsed += record.GasUsed
+	self.Fee += record.Fee
+	self.FeeUSD += record.FeeUSD
+	return self
+}
+
+// GasSpendBreakdown is the gas spend stat in total, by operator address and by action.
+type GasSpendBreakdown struct {
+	Total      GasSpendStat
+	ByOperator map[string]GasSpendStat
+	ByAction   map[string]GasSpendStat
+}
+
+func newGasSpendBreakdown() GasSpendBreakdown {
+	return GasSpendBreakdown{
+		ByOperator: map[string]GasSpendStat{},
+		ByAction:   map[string]GasSpendStat{},
+	}
+}
+
+func (self *GasSpendBreakdown) add(record GasSpendRecord) {
+	self.Total = self.Total.add(record)
+	self.ByOperator[record.Operator] = self.ByOperator[record.Operator].add(record)
+	self.ByAction[record.Action] = self.ByAction[record.Action].add(record)
+}
+
+// GasSpendDaily is the gas spend breakdown of a UTC day starting at Timestamp.
+type GasSpendDaily struct {
+	Timestamp uint64
+	GasSpendBreakdown
+}
+
+// GasSpendReport is the response of gas spend API.
+type GasSpendReport struct {
+	GasSpendBreakdown
+	Daily []GasSpendDaily
+}
+
+// NewGasSpendReport aggregates the records, which must be sorted by timestamp,
+// to a report with daily rollups.
+func NewGasSpendReport(records []GasSpendRecord) GasSpendReport {
+	result := GasSpendReport{
+		GasSpendBreakdown: newGasSpendBreakdown(),
+		Daily:             []GasSpendDaily{},
+	}
+	for _, record := range records {
+		result.add(record)
+		day := record.Timestamp - record.Timestamp%dayInMillisecond
+		if len(result.Daily) == 0 || result.Daily[len(result.Daily)-1].Timestamp != day {
+			result.Daily = append(result.Daily, GasSpendDaily{Timestamp: day, GasSpendBreakdown: newGasSpendBreakdown()})
+		}
+		result.Daily[len(result.Daily)-1].add(record)
+	}
+	return result
+}
@@ -0,0 +1,54 @@
+package common
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	ethereum "github.com/ethereum/go-ethereum/common"
+)
+
+func TestNewGasSpendReport(t *testing.T) {
+	const (
+		day       uint64 = 86400000
+		operator1        = "0x1111111111111111111111111111111111111111"
+		operator2        = "0x2222222222222222222222222222222222222222"
+	)
+	// 100000 gas at 10 gwei costs 0.001 ETH
+	cost := func(operator string) TxCost {
+		return TxCost{
+			From:     ethereum.HexToAddress(operator),
+			GasUsed:  100000,
+			GasPrice: big.NewInt(10000000000),
+		}
+	}
+	records := []GasSpendRecord{
+		NewGasSpendRecord("0x01", "set_rates", NewActivityID(1, "1"), false, 2, cost(operator1), 200, 3*day+1),
+		NewGasSpendRecord("0x02", "deposit", NewActivityID(2, "2"), false, 0, cost(operator1), 200, 3*day+2),
+		NewGasSpendRecord("0x03", "set_rates", NewActivityID(3, "3"), true, 0, cost(operator2), 300, 4*day),
+	}
+	report := NewGasSpendReport(records)
+
+	almostEqual := func(a, b float64) bool { return math.Abs(a-b) < 1e-12 }
+	if report.Total.TxCount != 3 || report.Total.FailedCount != 1 || report.Total.Replacements != 2 || report.Total.GasUsed != 300000 {
+		t.Errorf("unexpected total %+v", report.Total)
+	}
+	if !almostEqual(report.Total.Fee, 0.003) || !almostEqual(report.Total.FeeUSD, 0.7) {
+		t.Errorf("expected total fee 0.003 ETH, 0.7 USD, got %f ETH, %f USD", report.Total.Fee, report.Total.FeeUSD)
+	}
+	if stat := report.ByOperator[operator1]; stat.TxCount != 2 || !almostEqual(stat.FeeUSD, 0.4) {
+		t.Errorf("unexpected stat of operator1 %+v", stat)
+	}
+	if stat := report.ByAction["set_rates"]; stat.TxCount != 2 || stat.FailedCount != 1 || stat.Replacements != 2 {
+		t.Errorf("unexpected stat of set_rates %+v", stat)
+	}
+	if len(report.Daily) != 2 {
+		t.Fatalf("expected 2 daily rollups, got %d", len(report.Daily))
+	}
+	if report.Daily[0].Timestamp != 3*day || report.Daily[0].Total.TxCount != 2 {
+		t.Errorf("unexpected first daily rollup %+v", report.Daily[0])
+	}
+	if report.Daily[1].Timestamp != 4*day || report.Daily[1].ByOperator[operator2].TxCount != 1 {
+		t.Errorf("unexpected second daily rollup %+v", report.Daily[1])
+	}
+}
@@ -0,0 +1,139 @@
+package common
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+
+	ethereum "github.com/ethereum/go-ethereum/common"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor can't be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor returns the opaque form of a cursor, which is safe in URLs.
+func encodeCursor(cursor string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursor))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", ErrInvalidCursor
+	}
+	return string(data), nil
+}
+
+// ActivityCursor returns the cursor of activities older than id.
+func ActivityCursor(id ActivityID) string {
+	return encodeCursor(id.String())
+}
+
+// ParseActivityCursor returns the ID of the last activity of the previous page.
+func ParseActivityCursor(cursor string) (ActivityID, error) {
+	data, err := decodeCursor(cursor)
+	if err != nil {
+		return ActivityID{}, err
+	}
+	id, err := StringToActivityID(data)
+	if err != nil {
+		return ActivityID{}, ErrInvalidCursor
+	}
+	return id, nil
+}
+
+// TradeLogCursor returns the cursor of trade logs older than timestamp.
+func TradeLogCursor(timestamp uint64) string {
+	return encodeCursor(strconv.FormatUint(timestamp, 10))
+}
+
+// ParseTradeLogCursor returns the timestamp of the last trade log of the
+// previous page.
+func ParseTradeLogCursor(cursor string) (uint64, error) {
+	data, err := decodeCursor(cursor)
+	if err != nil {
+		return 0, err
+	}
+	timestamp, err := strconv.ParseUint(data, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	return timestamp, nil
+}
+
+// ActivityFilter selects activities whose ID timepoints, in nanosecond, are
+// in [FromTime, ToTime]. Empty fields select all activities. Status matches
+// either the exchange status or the mining status.
+type ActivityFilter struct {
+	FromTime    uint64
+	ToTime      uint64
+	Action      string
+	Destination string
+	Token       string
+	Status      string
+	PendingOnly bool
+}
+
+// Match returns true if record is selected by the filter.
+func (self ActivityFilter) Match(record ActivityRecord) bool {
+	if record.ID.Timepoint < self.FromTime || record.ID.Timepoint > self.ToTime {
+		return false
+	}
+	if self.Action != "" && record.Action != self.Action {
+		return false
+	}
+	if self.Destination != "" && record.Destination != self.Destination {
+		return false
+	}
+	if self.Status != "" && record.ExchangeStatus != self.Status && record.MiningStatus != self.Status {
+		return false
+	}
+	if self.PendingOnly && !record.IsPending() {
+		return false
+	}
+	if self.Token == "" {
+		return true
+	}
+	for _, token := range record.Tokens() {
+		if token == self.Token {
+			return true
+		}
+	}
+	return false
+}
+
+// ActivityPage is a page of activities, the latest first. NextCursor is
+// empty on the last page.
+type ActivityPage struct {
+	Activities []ActivityRecord `json:"activities"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// TradeLogFilter selects trade logs whose timestamps, in nanosecond, are in
+// [FromTime, ToTime]. Zero addresses select all trade logs, Token matches
+// either the source or the destination token.
+type TradeLogFilter struct {
+	FromTime uint64
+	ToTime   uint64
+	Reserve  ethereum.Address
+	Token    ethereum.Address
+	User     ethereum.Address
+	Wallet   ethereum.Address
+}
+
+// Match returns true if log is selected by the filter.
+func (self TradeLogFilter) Match(log TradeLog) bool {
+	var zero ethereum.Address
+	return log.Timestamp >= self.FromTime && log.Timestamp <= self.ToTime &&
+		(self.Reserve == zero || log.ReserveAddress == self.Reserve) &&
+		(self.Token == zero || log.SrcAddress == self.Token || log.DestAddress == self.Token) &&
+		(self.User == zero || log.UserAddress == self.User) &&
+		(self.Wallet == zero || log.WalletAddress == self.Wallet)
+}
+
+// TradeLogPage is a page of trade logs, the latest first. NextCursor is
+// empty on the last page.
+type TradeLogPage struct {
+	TradeLogs  []TradeLog `json:"trade_logs"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,21 @@
+package common
+
+import "testing"
+
+func TestPaginationCursors(t *testing.T) {
+	id := NewActivityID(1512189195897392628, "1872552297_OMG|ETH")
+	if parsed, err := ParseActivityCursor(ActivityCursor(id)); err != nil || parsed != id {
+		t.Errorf("expected activity cursor of %v, got %v, err %v", id, parsed, err)
+	}
+	if timestamp, err := ParseTradeLogCursor(TradeLogCursor(1512189195897392628)); err != nil || timestamp != 1512189195897392628 {
+		t.Errorf("expected trade log cursor of 1512189195897392628, got %d, err %v", timestamp, err)
+	}
+	for _, cursor := range []string{"not a cursor", encodeCursor("1000"), ""} {
+		if _, err := ParseActivityCursor(cursor); err != ErrInvalidCursor {
+			t.Errorf("expected activity cursor %q to be invalid, got %v", cursor, err)
+		}
+	}
+	if _, err := ParseTradeLogCursor(ActivityCursor(id)); err != ErrInvalidCursor {
+		t.Errorf("expected activity cursor to be an invalid trade log cursor, got %v", err)
+	}
+}
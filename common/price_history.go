@@ -0,0 +1,82 @@
+package common
+
+// DepthAtSize is the average price to fill an order of Size, in quote
+// token, on one side of an order book. Price is 0 if the book is not deep
+// enough.
+type DepthAtSize struct {
+	Size  float64 `json:"size"`
+	Price float64 `json:"price"`
+}
+
+// PricePoint is the top of book and depth of a pair on an exchange at a timepoint.
+type PricePoint struct {
+	Timestamp uint64        `json:"timestamp"`
+	BestBid   float64       `json:"best_bid"`
+	BestAsk   float64       `json:"best_ask"`
+	Mid       float64       `json:"mid"`
+	BidDepth  []DepthAtSize `json:"bid_depth"`
+	AskDepth  []DepthAtSize `json:"ask_depth"`
+}
+
+// PriceCandle summarizes price points of a pair on an exchange in the
+// interval starting at Timestamp. Open, High, Low and Close are of the mid
+// price, best bid, best ask and depth are of the last point.
+type PriceCandle struct {
+	Timestamp uint64        `json:"timestamp"`
+	Open      float64       `json:"open"`
+	High      float64       `json:"high"`
+	Low       float64       `json:"low"`
+	Close     float64       `json:"close"`
+	BestBid   float64       `json:"best_bid"`
+	BestAsk   float64       `json:"best_ask"`
+	BidDepth  []DepthAtSize `json:"bid_depth"`
+	AskDepth  []DepthAtSize `json:"ask_depth"`
+	// Count is the number of price points in the interval.
+	Count int `json:"count"`
+	// LastTimestamp is the timestamp of the last point, used to keep Close
+	// correct when points are added out of order.
+	LastTimestamp uint64 `json:"last_timestamp"`
+}
+
+// NewPriceCandle returns the candle of a single point starting at timestamp.
+func NewPriceCandle(timestamp uint64, point PricePoint) PriceCandle {
+	return PriceCandle{
+		Timestamp:     timestamp,
+		Open:          point.Mid,
+		High:          point.Mid,
+		Low:           point.Mid,
+		Close:         point.Mid,
+		BestBid:       point.BestBid,
+		BestAsk:       point.BestAsk,
+		BidDepth:      point.BidDepth,
+		AskDepth:      point.AskDepth,
+		Count:         1,
+		LastTimestamp: point.Timestamp,
+	}
+}
+
+// Add adds a price point of the interval to the candle.
+func (self *PriceCandle) Add(point PricePoint) {
+	if point.Mid > self.High {
+		self.High = point.Mid
+	}
+	if point.Mid < self.Low {
+		self.Low = point.Mid
+	}
+	if point.Timestamp >= self.LastTimestamp {
+		self.Close = point.Mid
+		self.BestBid = point.BestBid
+		self.BestAsk = point.BestAsk
+		self.BidDepth = point.BidDepth
+		self.AskDepth = point.AskDepth
+		self.LastTimestamp = point.Timestamp
+	}
+	self.Count++
+}
+
+// PriceHistory is the price time series of a pair on all exchanges at a resolution.
+type PriceHistory struct {
+	Pair       TokenPairID                  `json:"pair"`
+	Resolution string                       `json:"resolution"`
+	Data       map[ExchangeID][]PriceCandle `json:"data"`
+}
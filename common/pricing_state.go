@@ -0,0 +1,99 @@
+package common
+
+import (
+	"math/big"
+)
+
+// StepFunctionResponse is the human friendly format of a step function,
+// X is in token unit and Y is in bps.
+type StepFunctionResponse struct {
+	X []float64
+	Y []int64
+}
+
+// ToStepFunctionResponse converts the step function to its human friendly format.
+func (self StepFunction) ToStepFunctionResponse(decimal int64) StepFunctionResponse {
+	result := StepFunctionResponse{X: []float64{}, Y: []int64{}}
+	for _, x := range self.X {
+		result.X = append(result.X, BigToFloat(x, decimal))
+	}
+	for _, y := range self.Y {
+		result.Y = append(result.Y, y.Int64())
+	}
+	return result
+}
+
+// TokenPricingStateResponse is the human friendly format of a token pricing state to returns in HTTP APIs.
+// Rates are in token per ETH for buy and ETH per token for sell.
+type TokenPricingStateResponse struct {
+	Listed  bool
+	Enabled bool
+
+	RawBaseBuy  *big.Int
+	RawBaseSell *big.Int
+	BaseBuy     float64
+	BaseSell    float64
+	CompactBuy  int8
+	CompactSell int8
+	// Buy and Sell are the base rates after applying compact data.
+	Buy         float64
+	Sell        float64
+	BulkIndex   uint64
+	IndexInBulk uint64
+
+	RateUpdateBlock uint64
+	ValidUntilBlock uint64
+
+	BuyQtyStepFunction        StepFunctionResponse
+	SellQtyStepFunction       StepFunctionResponse
+	BuyImbalanceStepFunction  StepFunctionResponse
+	SellImbalanceStepFunction StepFunctionResponse
+
+	MinimalRecordResolution float64
+	MaxPerBlockImbalance    float64
+	MaxTotalImbalance       float64
+}
+
+// applyCompact returns the rate after applying compact data, compact is in 0.1%.
+func applyCompact(base *big.Int, compact int8) float64 {
+	if base == nil {
+		return 0
+	}
+	rate := BigToFloat(base, ethDecimals)
+	return rate * (1000 + float64(compact)) / 1000
+}
+
+// bigToFloatOrZero converts value to float, nil is considered 0.
+func bigToFloatOrZero(value *big.Int, decimal int64) float64 {
+	if value == nil {
+		return 0
+	}
+	return BigToFloat(value, decimal)
+}
+
+// ToResponse converts the pricing state to its human friendly format.
+func (self TokenPricingState) ToResponse() TokenPricingStateResponse {
+	return TokenPricingStateResponse{
+		Listed:                    self.Listed,
+		Enabled:                   self.Enabled,
+		RawBaseBuy:                self.BaseBuy,
+		RawBaseSell:               self.BaseSell,
+		BaseBuy:                   bigToFloatOrZero(self.BaseBuy, ethDecimals),
+		BaseSell:                  bigToFloatOrZero(self.BaseSell, ethDecimals),
+		CompactBuy:                self.CompactBuy,
+		CompactSell:               self.CompactSell,
+		Buy:                       applyCompact(self.BaseBuy, self.CompactBuy),
+		Sell:                      applyCompact(self.BaseSell, self.CompactSell),
+		BulkIndex:                 self.BulkIndex,
+		IndexInBulk:               self.IndexInBulk,
+		RateUpdateBlock:           self.RateUpdateBlock,
+		ValidUntilBlock:           self.RateUpdateBlock + self.ValidRateDurationInBlocks,
+		BuyQtyStepFunction:        self.BuyQtyStepFunction.ToStepFunctionResponse(self.Decimal),
+		SellQtyStepFunction:       self.SellQtyStepFunction.ToStepFunctionResponse(self.Decimal),
+		BuyImbalanceStepFunction:  self.BuyImbalanceStepFunction.ToStepFunctionResponse(self.Decimal),
+		SellImbalanceStepFunction: self.SellImbalanceStepFunction.ToStepFunctionResponse(self.Decimal),
+		MinimalRecordResolution:   bigToFloatOrZero(self.MinimalRecordResolution, self.Decimal),
+		MaxPerBlockImbalance:      bigToFloatOrZero(self.MaxPerBlockImbalance, self.Decimal),
+		MaxTotalImbalance:         bigToFloatOrZero(self.MaxTotalImbalance, self.Decimal),
+	}
+}
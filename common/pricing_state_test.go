@@ -0,0 +1,30 @@
+package common
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPricingStateToResponse(t *testing.T) {
+	state := recordedPricingState(t)
+	state.BulkIndex, state.IndexInBulk = 1, 3
+	resp := state.ToResponse()
+
+	floatEqual := func(a, b float64) bool { return math.Abs(a-b) < 1e-9*math.Max(1, math.Abs(b)) }
+	if !floatEqual(resp.BaseBuy, 1563.94) || !floatEqual(resp.Buy, 1563.94*1.005) {
+		t.Errorf("unexpected buy rates base: %f, with compact: %f", resp.BaseBuy, resp.Buy)
+	}
+	if !floatEqual(resp.BaseSell, 0.000634) || !floatEqual(resp.Sell, 0.000634*0.997) {
+		t.Errorf("unexpected sell rates base: %f, with compact: %f", resp.BaseSell, resp.Sell)
+	}
+	if resp.ValidUntilBlock != 6000010 || resp.BulkIndex != 1 || resp.IndexInBulk != 3 {
+		t.Errorf("unexpected block or index: %+v", resp)
+	}
+	if len(resp.BuyImbalanceStepFunction.X) != 5 || resp.BuyImbalanceStepFunction.X[0] != -500 ||
+		resp.BuyImbalanceStepFunction.Y[4] != -100 {
+		t.Errorf("unexpected buy imbalance step function: %+v", resp.BuyImbalanceStepFunction)
+	}
+	if resp.MaxTotalImbalance != 8000 || resp.MinimalRecordResolution != 0.001 {
+		t.Errorf("unexpected imbalance limits: %+v", resp)
+	}
+}
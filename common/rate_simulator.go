@@ -0,0 +1,239 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	// imbalanceSlidingWindowSize is the number of imbalance records the pricing contract keeps per token.
+	// https://github.com/KyberNetwork/smart-contracts/blob/fed8e09dc6e4365e1597474d9b3f53634eb405d2/contracts/VolumeImbalanceRecorder.sol#L12
+	imbalanceSlidingWindowSize = 5
+	// maxBpsAdjustment is the maximum absolute bps that can be applied to a rate in one step.
+	maxBpsAdjustment = 100 * 100
+	// maxRateDecimals is the number of decimals of the maximum rate the pricing contract accepts (10^6 * 10^18).
+	maxRateDecimals = 24
+	ethDecimals     = 18
+)
+
+var (
+	bigMaxBps  = big.NewInt(maxBpsAdjustment)
+	bigMaxRate = new(big.Int).Exp(big.NewInt(10), big.NewInt(maxRateDecimals), nil)
+	bigPow2_64 = new(big.Int).Lsh(big.NewInt(1), 64)
+	precision  = new(big.Int).Exp(big.NewInt(10), big.NewInt(ethDecimals), nil)
+)
+
+// StepFunction is the off-chain representation of a step function stored in the pricing contract.
+// The Y value (in bps) of the first X that is greater or equal than the input is applied,
+// the last Y is used if the input is bigger than all X values.
+type StepFunction struct {
+	X []*big.Int
+	Y []*big.Int
+}
+
+// NewStepFunction creates a new StepFunction instance.
+func NewStepFunction(x, y []*big.Int) StepFunction {
+	return StepFunction{
+		X: x,
+		Y: y,
+	}
+}
+
+// Execute returns the bps value of the step function at given point.
+// The contract reverts on an empty step function, we treat it as no adjustment.
+func (self StepFunction) Execute(value *big.Int) *big.Int {
+	if len(self.Y) == 0 {
+		return big.NewInt(0)
+	}
+	for i, x := range self.X {
+		if i >= len(self.Y) {
+			break
+		}
+		if value.Cmp(x) <= 0 {
+			return self.Y[i]
+		}
+	}
+	return self.Y[len(self.Y)-1]
+}
+
+// TokenPricingState is the snapshot of all pricing contract data that is used to
+// calculate the rate of a token.
+type TokenPricingState struct {
+	Listed  bool
+	Enabled bool
+	// Decimal is the number of decimals of the token, used to convert buy quantity from ETH to token.
+	Decimal int64
+
+	BaseBuy     *big.Int
+	BaseSell    *big.Int
+	CompactBuy  int8
+	CompactSell int8
+	// BulkIndex and IndexInBulk are the position of the token compact data in pricing contract.
+	BulkIndex   uint64
+	IndexInBulk uint64
+
+	RateUpdateBlock           uint64
+	ValidRateDurationInBlocks uint64
+
+	BuyQtyStepFunction        StepFunction
+	SellQtyStepFunction       StepFunction
+	BuyImbalanceStepFunction  StepFunction
+	SellImbalanceStepFunction StepFunction
+
+	MinimalRecordResolution *big.Int
+	MaxPerBlockImbalance    *big.Int
+	MaxTotalImbalance       *big.Int
+
+	// ImbalanceData is the raw tokenImbalanceData sliding window of the token.
+	ImbalanceData []*big.Int
+}
+
+// tokenImbalanceData is the decoded form of a tokenImbalanceData record.
+type tokenImbalanceData struct {
+	lastBlockBuyUnitsImbalance *big.Int
+	lastBlock                  uint64
+	totalBuyUnitsImbalance     *big.Int
+	lastRateUpdateBlock        uint64
+}
+
+// toInt64 interprets the lowest 64 bits of given value as a signed integer.
+func toInt64(value *big.Int) *big.Int {
+	masked := new(big.Int).And(value, new(big.Int).Sub(bigPow2_64, big.NewInt(1)))
+	return big.NewInt(int64(masked.Uint64()))
+}
+
+func decodeTokenImbalanceData(input *big.Int) tokenImbalanceData {
+	mask := new(big.Int).Sub(bigPow2_64, big.NewInt(1))
+	return tokenImbalanceData{
+		lastBlockBuyUnitsImbalance: toInt64(input),
+		lastBlock:                  new(big.Int).And(new(big.Int).Rsh(input, 64), mask).Uint64(),
+		totalBuyUnitsImbalance:     toInt64(new(big.Int).Rsh(input, 128)),
+		lastRateUpdateBlock:        new(big.Int).And(new(big.Int).Rsh(input, 192), mask).Uint64(),
+	}
+}
+
+// Imbalance returns the total imbalance since last rate update and the imbalance of current block,
+// both in token wei.
+func (self TokenPricingState) Imbalance(currentBlock uint64) (*big.Int, *big.Int) {
+	var (
+		buyImbalance          = big.NewInt(0)
+		currentBlockImbalance = big.NewInt(0)
+		imbalanceInRange      = big.NewInt(0)
+		latestBlock           uint64
+	)
+	for i := 0; i < imbalanceSlidingWindowSize && i < len(self.ImbalanceData); i++ {
+		if self.ImbalanceData[i] == nil {
+			continue
+		}
+		data := decodeTokenImbalanceData(self.ImbalanceData[i])
+		if data.lastBlock <= currentBlock && data.lastBlock >= self.RateUpdateBlock {
+			imbalanceInRange.Add(imbalanceInRange, data.lastBlockBuyUnitsImbalance)
+		}
+		if data.lastRateUpdateBlock != self.RateUpdateBlock || data.lastBlock < latestBlock {
+			continue
+		}
+		latestBlock = data.lastBlock
+		buyImbalance = data.totalBuyUnitsImbalance
+		if data.lastBlock == currentBlock {
+			currentBlockImbalance = data.lastBlockBuyUnitsImbalance
+		}
+	}
+	if buyImbalance.Sign() == 0 {
+		buyImbalance = imbalanceInRange
+	}
+	resolution := big.NewInt(0)
+	if self.MinimalRecordResolution != nil {
+		resolution = self.MinimalRecordResolution
+	}
+	return new(big.Int).Mul(buyImbalance, resolution), new(big.Int).Mul(currentBlockImbalance, resolution)
+}
+
+// addBps adds the given bps to rate, the same way pricing contract does.
+func addBps(rate *big.Int, bps *big.Int) (*big.Int, error) {
+	if rate.Cmp(bigMaxRate) > 0 {
+		return nil, fmt.Errorf("rate %s is bigger than max rate", rate.Text(10))
+	}
+	if new(big.Int).Abs(bps).Cmp(bigMaxBps) > 0 {
+		return nil, fmt.Errorf("bps adjustment %s is out of range", bps.Text(10))
+	}
+	result := new(big.Int).Mul(rate, new(big.Int).Add(bigMaxBps, bps))
+	return result.Quo(result, bigMaxBps), nil
+}
+
+// calcDstQty returns the amount of destination token received for srcQty at given rate.
+func calcDstQty(srcQty *big.Int, srcDecimals, dstDecimals int64, rate *big.Int) *big.Int {
+	result := new(big.Int).Mul(srcQty, rate)
+	if dstDecimals >= srcDecimals {
+		result.Mul(result, new(big.Int).Exp(big.NewInt(10), big.NewInt(dstDecimals-srcDecimals), nil))
+		return result.Quo(result, precision)
+	}
+	divisor := new(big.Int).Mul(precision, new(big.Int).Exp(big.NewInt(10), big.NewInt(srcDecimals-dstDecimals), nil))
+	return result.Quo(result, divisor)
+}
+
+// SimulateRate emulates the getRate function of pricing contract. For buy, qty is the
+// amount of ETH in wei, for sell, qty is the amount of token in token wei.
+// It returns 0 in the same cases that contract returns 0 and an error in the cases the contract
+// reverts.
+// https://github.com/KyberNetwork/smart-contracts/blob/fed8e09dc6e4365e1597474d9b3f53634eb405d2/contracts/ConversionRates.sol#L226
+func SimulateRate(state TokenPricingState, currentBlock uint64, buy bool, qty *big.Int) (*big.Int, error) {
+	if qty == nil || qty.Sign() < 0 {
+		return nil, errors.New("quantity must be a non negative number")
+	}
+	if !state.Enabled {
+		return big.NewInt(0), nil
+	}
+	if state.MinimalRecordResolution == nil || state.MinimalRecordResolution.Sign() == 0 {
+		return big.NewInt(0), nil
+	}
+	if currentBlock >= state.RateUpdateBlock+state.ValidRateDurationInBlocks {
+		return big.NewInt(0), nil
+	}
+	totalImbalance, blockImbalance := state.Imbalance(currentBlock)
+
+	var (
+		rate                   *big.Int
+		compact                int8
+		qtyStep, imbalanceStep StepFunction
+		tokenQty, imbalanceQty *big.Int
+		err                    error
+	)
+	if buy {
+		rate, compact = state.BaseBuy, state.CompactBuy
+		qtyStep, imbalanceStep = state.BuyQtyStepFunction, state.BuyImbalanceStepFunction
+	} else {
+		rate, compact = state.BaseSell, state.CompactSell
+		qtyStep, imbalanceStep = state.SellQtyStepFunction, state.SellImbalanceStepFunction
+	}
+	if rate == nil {
+		return big.NewInt(0), nil
+	}
+	if rate, err = addBps(rate, big.NewInt(int64(compact)*10)); err != nil {
+		return nil, err
+	}
+	if buy {
+		tokenQty = calcDstQty(qty, ethDecimals, state.Decimal, rate)
+		imbalanceQty = new(big.Int).Set(tokenQty)
+	} else {
+		tokenQty = qty
+		imbalanceQty = new(big.Int).Neg(qty)
+	}
+	totalImbalance.Add(totalImbalance, imbalanceQty)
+
+	if rate, err = addBps(rate, qtyStep.Execute(tokenQty)); err != nil {
+		return nil, err
+	}
+	if rate, err = addBps(rate, imbalanceStep.Execute(totalImbalance)); err != nil {
+		return nil, err
+	}
+
+	if state.MaxTotalImbalance != nil && new(big.Int).Abs(totalImbalance).Cmp(state.MaxTotalImbalance) >= 0 {
+		return big.NewInt(0), nil
+	}
+	blockImbalance.Add(blockImbalance, imbalanceQty)
+	if state.MaxPerBlockImbalance != nil && blockImbalance.Abs(blockImbalance).Cmp(state.MaxPerBlockImbalance) >= 0 {
+		return big.NewInt(0), nil
+	}
+	return rate, nil
+}
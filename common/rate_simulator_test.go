@@ -0,0 +1,180 @@
+package common
+
+import (
+	"math/big"
+	"testing"
+)
+
+func mustBigInt(t *testing.T, s string) *big.Int {
+	result, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		t.Fatalf("invalid number %s", s)
+	}
+	return result
+}
+
+func tokenWei(amount int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(amount), EthToWei(1))
+}
+
+func bps(values ...int64) []*big.Int {
+	result := []*big.Int{}
+	for _, v := range values {
+		result = append(result, big.NewInt(v))
+	}
+	return result
+}
+
+// recordedPricingState returns the pricing state of an 18 decimals token recorded from the contract.
+// It has two imbalance records since the rate update at block 6000000:
+// +200000 units at block 6000003 and -50000 units at block 6000005.
+func recordedPricingState(t *testing.T) TokenPricingState {
+	qtyX := []*big.Int{tokenWei(100), tokenWei(200), tokenWei(300), tokenWei(5000)}
+	return TokenPricingState{
+		Listed:                    true,
+		Enabled:                   true,
+		Decimal:                   18,
+		BaseBuy:                   mustBigInt(t, "1563940000000000000000"),
+		BaseSell:                  mustBigInt(t, "634000000000000"),
+		CompactBuy:                5,
+		CompactSell:               -3,
+		RateUpdateBlock:           6000000,
+		ValidRateDurationInBlocks: 10,
+		BuyQtyStepFunction:        NewStepFunction(qtyX, bps(0, -30, -60, -80)),
+		SellQtyStepFunction:       NewStepFunction(qtyX, bps(0, -30, -60, -80)),
+		BuyImbalanceStepFunction: NewStepFunction(
+			[]*big.Int{tokenWei(-500), tokenWei(0), tokenWei(500), tokenWei(1000), tokenWei(3000)},
+			bps(10, 0, -20, -60, -100),
+		),
+		SellImbalanceStepFunction: NewStepFunction(
+			[]*big.Int{tokenWei(-3000), tokenWei(-1000), tokenWei(-500), tokenWei(0), tokenWei(500)},
+			bps(-100, -60, -20, 0, 10),
+		),
+		MinimalRecordResolution: big.NewInt(1000000000000000),
+		MaxPerBlockImbalance:    tokenWei(4000),
+		MaxTotalImbalance:       tokenWei(8000),
+		ImbalanceData: []*big.Int{
+			mustBigInt(t, "37662610412320084583082793012630186189417488108053050250492054848"),
+			mustBigInt(t, "37662610412320084583065778894284139266244319433021694061047659696"),
+			big.NewInt(0),
+			big.NewInt(0),
+			big.NewInt(0),
+		},
+	}
+}
+
+func TestPricingStateImbalance(t *testing.T) {
+	state := recordedPricingState(t)
+	total, block := state.Imbalance(6000005)
+	if total.Cmp(tokenWei(150)) != 0 || block.Cmp(tokenWei(-50)) != 0 {
+		t.Fatalf("expected imbalance (150, -50) tokens, got (%s, %s)", total.Text(10), block.Text(10))
+	}
+
+	// records of an older rate update are only counted by their last block imbalance
+	state.RateUpdateBlock = 6000004
+	total, block = state.Imbalance(6000005)
+	if total.Cmp(tokenWei(-50)) != 0 || block.Sign() != 0 {
+		t.Fatalf("expected imbalance (-50, 0) tokens, got (%s, %s)", total.Text(10), block.Text(10))
+	}
+}
+
+func TestSimulateRate(t *testing.T) {
+	var tests = []struct {
+		msg      string
+		modify   func(state *TokenPricingState)
+		block    uint64
+		buy      bool
+		qty      *big.Int
+		expected string
+	}{
+		{
+			msg:      "buy with 1 ETH",
+			block:    6000005,
+			buy:      true,
+			qty:      tokenWei(1),
+			expected: "1543593766176000000000",
+		},
+		{
+			msg:      "buy with 0.1 ETH",
+			block:    6000005,
+			buy:      true,
+			qty:      big.NewInt(100000000000000000),
+			expected: "1563910332058200000000",
+		},
+		{
+			msg:      "sell 500 tokens",
+			block:    6000005,
+			buy:      false,
+			qty:      tokenWei(500),
+			expected: "627041216000000",
+		},
+		{
+			msg:      "sell 500 tokens after another rate update",
+			modify:   func(state *TokenPricingState) { state.RateUpdateBlock = 6000004 },
+			block:    6000005,
+			buy:      false,
+			qty:      tokenWei(500),
+			expected: "625787133568000",
+		},
+		{
+			msg: "sell 500 tokens with a new rate",
+			modify: func(state *TokenPricingState) {
+				state.BaseSell, state.CompactSell, state.RateUpdateBlock = big.NewInt(700000000000000), 0, 6000006
+			},
+			block:    6000006,
+			buy:      false,
+			qty:      tokenWei(500),
+			expected: "693011200000000",
+		},
+		{
+			msg:      "rate expired",
+			block:    6000010,
+			buy:      true,
+			qty:      tokenWei(1),
+			expected: "0",
+		},
+		{
+			msg:      "trade disabled",
+			modify:   func(state *TokenPricingState) { state.Enabled = false },
+			block:    6000005,
+			buy:      true,
+			qty:      tokenWei(1),
+			expected: "0",
+		},
+		{
+			msg:      "exceed max total imbalance",
+			block:    6000005,
+			buy:      false,
+			qty:      tokenWei(9000),
+			expected: "0",
+		},
+		{
+			msg:      "exceed max per block imbalance",
+			block:    6000005,
+			buy:      false,
+			qty:      tokenWei(3960),
+			expected: "0",
+		},
+	}
+	for _, tc := range tests {
+		state := recordedPricingState(t)
+		if tc.modify != nil {
+			tc.modify(&state)
+		}
+		rate, err := SimulateRate(state, tc.block, tc.buy, tc.qty)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tc.msg, err)
+		}
+		if rate.Text(10) != tc.expected {
+			t.Errorf("%s: expected rate %s, got %s", tc.msg, tc.expected, rate.Text(10))
+		}
+	}
+}
+
+func TestSimulateRateInvalidBps(t *testing.T) {
+	state := recordedPricingState(t)
+	state.BuyQtyStepFunction = NewStepFunction([]*big.Int{tokenWei(100)}, bps(-10001))
+	if _, err := SimulateRate(state, 6000005, true, tokenWei(1)); err == nil {
+		t.Fatal("expected error for out of range bps adjustment")
+	}
+}
@@ -0,0 +1,344 @@
+// Package ratelimit limits the rate and concurrency of requests by the key
+// making them and the route they call, so no caller can starve the rest of
+// the service.
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// maxIdleBuckets is the number of buckets kept before full ones are
+	// pruned, which bounds the memory of callers identified by IP.
+	maxIdleBuckets = 10000
+	// pruneInterval is the minimum interval between prunes of buckets.
+	pruneInterval = time.Minute
+)
+
+// Limit is the limit of requests of a key.
+type Limit struct {
+	// Rate is the number of requests per second, unlimited if it is 0.
+	Rate float64
+	// Burst is the number of requests allowed at once, at least the rate.
+	Burst int
+	// MaxConcurrent is the number of requests being served at once,
+	// unlimited if it is 0.
+	MaxConcurrent int
+}
+
+// RouteLimit is the limit of requests of a route.
+type RouteLimit struct {
+	// PerKey is the limit of each key on the route, on top of the limit
+	// of the key on all routes.
+	PerKey Limit
+	// MaxConcurrent is the number of requests of all keys being served at
+	// once, unlimited if it is 0.
+	MaxConcurrent int
+	// MaxTimeRange is the longest time range of queries, unlimited if it
+	// is 0.
+	MaxTimeRange time.Duration
+}
+
+// Limits are the limits of requests.
+type Limits struct {
+	// Default is the limit of keys on all routes.
+	Default Limit
+	// Keys are limits of keys on all routes replacing the default one.
+	Keys map[string]Limit
+	// Routes are limits of routes by path pattern, e.g /prices/:base/:quote.
+	Routes map[string]RouteLimit
+	// MaxBodySize is the max size of request bodies in byte, unlimited if
+	// it is 0.
+	MaxBodySize int64
+}
+
+// expensiveRoute is the default limit of stat queries scanning large ranges
+// of the database.
+func expensiveRoute(maxTimeRange time.Duration) RouteLimit {
+	return RouteLimit{
+		PerKey:        Limit{Rate: 1, Burst: 5},
+		MaxConcurrent: 4,
+		MaxTimeRange:  maxTimeRange,
+	}
+}
+
+// DefaultLimits returns the limits used if they are not configured. Keys are
+// not limited on cheap routes.
+func DefaultLimits() Limits {
+	const day = 24 * time.Hour
+	return Limits{
+		Keys: map[string]Limit{},
+		Routes: map[string]RouteLimit{
+			"/get-all-rates":           expensiveRoute(0),
+			"/get-user-list":           expensiveRoute(31 * day),
+			"/get-heat-map":            expensiveRoute(365 * day),
+			"/get-token-heatmap":       expensiveRoute(365 * day),
+			"/get-country-stats":       expensiveRoute(365 * day),
+			"/get-wallet-stats":        expensiveRoute(365 * day),
+			"/get-trade-summary":       expensiveRoute(365 * day),
+			"/get-reserve-rate":        expensiveRoute(31 * day),
+			"/get-price-analytic-data": expensiveRoute(31 * day),
+			"/get-fee-setrate":         expensiveRoute(365 * day),
+		},
+		MaxBodySize: 2 << 20,
+	}
+}
+
+// LimitConfig is the configuration of a Limit.
+type LimitConfig struct {
+	Rate          float64 `json:"rate"`
+	Burst         int     `json:"burst"`
+	MaxConcurrent int     `json:"max_concurrent"`
+}
+
+// RouteLimitConfig is the configuration of a RouteLimit.
+type RouteLimitConfig struct {
+	PerKey        LimitConfig `json:"per_key"`
+	MaxConcurrent int         `json:"max_concurrent"`
+	MaxTimeRange  string      `json:"max_time_range"`
+}
+
+// Config is the configuration of limits, read from the rate_limits object
+// of the secret config file. Configured routes replace their default limits,
+// missing ones use the default limits.
+type Config struct {
+	Default     *LimitConfig                `json:"default"`
+	Keys        map[string]LimitConfig      `json:"keys"`
+	Routes      map[string]RouteLimitConfig `json:"routes"`
+	MaxBodySize *int64                      `json:"max_body_size"`
+}
+
+// GetConfigFromFile reads the configuration of limits from a JSON file.
+func GetConfigFromFile(path string) (Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	result := struct {
+		RateLimits Config `json:"rate_limits"`
+	}{}
+	err = json.Unmarshal(data, &result)
+	return result.RateLimits, err
+}
+
+func (self LimitConfig) limit(name string) (Limit, error) {
+	if self.Rate < 0 || self.Burst < 0 || self.MaxConcurrent < 0 {
+		return Limit{}, fmt.Errorf("limit of %s must not be negative", name)
+	}
+	return Limit{Rate: self.Rate, Burst: self.Burst, MaxConcurrent: self.MaxConcurrent}, nil
+}
+
+// Limits parses the configured limits.
+func (self Config) Limits() (Limits, error) {
+	result := DefaultLimits()
+	var err error
+	if self.Default != nil {
+		if result.Default, err = self.Default.limit("default"); err != nil {
+			return result, err
+		}
+	}
+	for key, conf := range self.Keys {
+		if result.Keys[key], err = conf.limit(key); err != nil {
+			return result, err
+		}
+	}
+	for route, conf := range self.Routes {
+		limit := RouteLimit{MaxConcurrent: conf.MaxConcurrent}
+		if limit.PerKey, err = conf.PerKey.limit(route); err != nil {
+			return result, err
+		}
+		if conf.MaxConcurrent < 0 {
+			return result, fmt.Errorf("max_concurrent of %s must not be negative", route)
+		}
+		if conf.MaxTimeRange != "" {
+			if limit.MaxTimeRange, err = time.ParseDuration(conf.MaxTimeRange); err != nil {
+				return result, fmt.Errorf("invalid max_time_range of %s: %s", route, err)
+			}
+			if limit.MaxTimeRange < 0 {
+				return result, fmt.Errorf("max_time_range of %s must not be negative", route)
+			}
+		}
+		result.Routes[route] = limit
+	}
+	if self.MaxBodySize != nil {
+		if *self.MaxBodySize < 0 {
+			return result, fmt.Errorf("max_body_size must not be negative")
+		}
+		result.MaxBodySize = *self.MaxBodySize
+	}
+	return result, nil
+}
+
+// Error is the reason a request is rejected.
+type Error struct {
+	Reason string
+	// RetryAfter is when the request can be retried.
+	RetryAfter time.Duration
+}
+
+func (self *Error) Error() string {
+	return self.Reason
+}
+
+// bucket is a token bucket refilled at the rate of its limit up to its burst.
+type bucket struct {
+	limit  Limit
+	tokens float64
+	last   time.Time
+}
+
+// refill adds tokens since the last refill, it returns true if the bucket is
+// full.
+func (self *bucket) refill(now time.Time) bool {
+	burst := burst(self.limit)
+	self.tokens = math.Min(burst, self.tokens+now.Sub(self.last).Seconds()*self.limit.Rate)
+	self.last = now
+	return self.tokens >= burst
+}
+
+// wait returns how long until the bucket has a token.
+func (self *bucket) wait() time.Duration {
+	return time.Duration((1 - self.tokens) / self.limit.Rate * float64(time.Second))
+}
+
+// burst returns the burst of limit, at least its rate and 1.
+func burst(limit Limit) float64 {
+	return math.Max(float64(limit.Burst), math.Max(math.Ceil(limit.Rate), 1))
+}
+
+// Limiter admits requests within limits.
+type Limiter struct {
+	limits Limits
+	now    func() time.Time
+
+	mu sync.Mutex
+	// buckets are token buckets of keys and of keys on routes
+	buckets map[string]*bucket
+	// inFlight are numbers of requests being served of keys and routes
+	inFlight  map[string]int
+	lastPrune time.Time
+}
+
+// NewLimiter creates a limiter of limits, now returns the current time.
+func NewLimiter(limits Limits, now func() time.Time) *Limiter {
+	return &Limiter{
+		limits:   limits,
+		now:      now,
+		buckets:  map[string]*bucket{},
+		inFlight: map[string]int{},
+	}
+}
+
+// MaxBodySize returns the max size of request bodies in byte, 0 if it is
+// unlimited.
+func (self *Limiter) MaxBodySize() int64 {
+	return self.limits.MaxBodySize
+}
+
+// MaxTimeRange returns the longest time range of queries of route, 0 if it
+// is unlimited.
+func (self *Limiter) MaxTimeRange(route string) time.Duration {
+	return self.limits.Routes[route].MaxTimeRange
+}
+
+// keyLimit returns the limit of key on all routes.
+func (self *Limiter) keyLimit(key string) Limit {
+	if limit, ok := self.limits.Keys[key]; ok {
+		return limit
+	}
+	return self.limits.Default
+}
+
+// Acquire admits a request of key on route, release must be called once the
+// request is served. It returns an *Error if the request exceeds any limit,
+// nothing is counted for rejected requests.
+func (self *Limiter) Acquire(key, route string) (release func(), err error) {
+	keyLimit := self.keyLimit(key)
+	routeLimit := self.limits.Routes[route]
+	keyID := "key\x00" + key
+	routeID := "route\x00" + route
+	routeKeyID := "route\x00" + route + "\x00" + key
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	for _, concurrency := range []struct {
+		id    string
+		limit int
+		name  string
+	}{
+		{keyID, keyLimit.MaxConcurrent, "key " + key},
+		{routeID, routeLimit.MaxConcurrent, "route " + route},
+		{routeKeyID, routeLimit.PerKey.MaxConcurrent, fmt.Sprintf("key %s on route %s", key, route)},
+	} {
+		if concurrency.limit > 0 && self.inFlight[concurrency.id] >= concurrency.limit {
+			return nil, &Error{
+				Reason:     fmt.Sprintf("too many concurrent requests of %s", concurrency.name),
+				RetryAfter: time.Second,
+			}
+		}
+	}
+	now := self.now()
+	taken := []*bucket{}
+	for _, rate := range []struct {
+		id    string
+		limit Limit
+		name  string
+	}{
+		{keyID, keyLimit, "key " + key},
+		{routeKeyID, routeLimit.PerKey, fmt.Sprintf("key %s on route %s", key, route)},
+	} {
+		if rate.limit.Rate <= 0 {
+			continue
+		}
+		b, ok := self.buckets[rate.id]
+		if !ok {
+			b = &bucket{limit: rate.limit, tokens: burst(rate.limit), last: now}
+			self.buckets[rate.id] = b
+		}
+		b.refill(now)
+		if b.tokens < 1 {
+			return nil, &Error{
+				Reason:     fmt.Sprintf("rate limit of %s is exceeded", rate.name),
+				RetryAfter: b.wait(),
+			}
+		}
+		taken = append(taken, b)
+	}
+	for _, b := range taken {
+		b.tokens--
+	}
+	if len(self.buckets) > maxIdleBuckets && now.Sub(self.lastPrune) > pruneInterval {
+		self.prune(now)
+	}
+	ids := []string{keyID, routeID, routeKeyID}
+	for _, id := range ids {
+		self.inFlight[id]++
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			self.mu.Lock()
+			defer self.mu.Unlock()
+			for _, id := range ids {
+				if self.inFlight[id]--; self.inFlight[id] <= 0 {
+					delete(self.inFlight, id)
+				}
+			}
+		})
+	}, nil
+}
+
+// prune removes full buckets, they are recreated full when they are used.
+func (self *Limiter) prune(now time.Time) {
+	self.lastPrune = now
+	for id, b := range self.buckets {
+		if b.refill(now) {
+			delete(self.buckets, id)
+		}
+	}
+}
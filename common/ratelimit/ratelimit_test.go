@@ -0,0 +1,214 @@
+package ratelimit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (self *clock) Now() time.Time {
+	return self.now
+}
+
+func TestConfigLimits(t *testing.T) {
+	size := int64(1024)
+	negative := int64(-1)
+	var tests = []struct {
+		config Config
+		check  func(Limits) bool
+		err    bool
+	}{
+		{
+			config: Config{},
+			check: func(limits Limits) bool {
+				return limits.Routes["/get-user-list"] == DefaultLimits().Routes["/get-user-list"] &&
+					limits.MaxBodySize == DefaultLimits().MaxBodySize
+			},
+		},
+		{
+			config: Config{
+				Default:     &LimitConfig{Rate: 10, Burst: 20},
+				Keys:        map[string]LimitConfig{"bot": {Rate: 1, MaxConcurrent: 2}},
+				Routes:      map[string]RouteLimitConfig{"/get-user-list": {MaxConcurrent: 1, MaxTimeRange: "24h"}},
+				MaxBodySize: &size,
+			},
+			check: func(limits Limits) bool {
+				return limits.Default == Limit{Rate: 10, Burst: 20} &&
+					limits.Keys["bot"] == Limit{Rate: 1, MaxConcurrent: 2} &&
+					limits.Routes["/get-user-list"] == RouteLimit{MaxConcurrent: 1, MaxTimeRange: 24 * time.Hour} &&
+					limits.Routes["/get-heat-map"] == DefaultLimits().Routes["/get-heat-map"] &&
+					limits.MaxBodySize == 1024
+			},
+		},
+		{config: Config{Default: &LimitConfig{Rate: -1}}, err: true},
+		{config: Config{Keys: map[string]LimitConfig{"bot": {Burst: -1}}}, err: true},
+		{config: Config{Routes: map[string]RouteLimitConfig{"/get-heat-map": {MaxTimeRange: "a year"}}}, err: true},
+		{config: Config{Routes: map[string]RouteLimitConfig{"/get-heat-map": {MaxConcurrent: -1}}}, err: true},
+		{config: Config{MaxBodySize: &negative}, err: true},
+	}
+	for _, tc := range tests {
+		limits, err := tc.config.Limits()
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected error of config %+v", tc.config)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !tc.check(limits) {
+			t.Errorf("unexpected limits %+v of config %+v", limits, tc.config)
+		}
+	}
+}
+
+func TestGetConfigFromFile(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test_ratelimit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}()
+	path := filepath.Join(tmpDir, "config.json")
+	data := `{"rate_limits": {"keys": {"bot": {"rate": 2, "burst": 4}}, "routes": {"/get-heat-map": {"per_key": {"rate": 0.5}, "max_time_range": "720h"}}}}`
+	if err = ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := GetConfigFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limits, err := conf.Limits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits.Keys["bot"] != (Limit{Rate: 2, Burst: 4}) {
+		t.Errorf("unexpected limit of bot %+v", limits.Keys["bot"])
+	}
+	if limits.Routes["/get-heat-map"] != (RouteLimit{PerKey: Limit{Rate: 0.5}, MaxTimeRange: 720 * time.Hour}) {
+		t.Errorf("unexpected limit of heat map %+v", limits.Routes["/get-heat-map"])
+	}
+}
+
+func TestLimiterRate(t *testing.T) {
+	c := &clock{now: time.Unix(1000, 0)}
+	limiter := NewLimiter(Limits{
+		Default: Limit{Rate: 1, Burst: 2},
+		Keys:    map[string]Limit{"bot": {Rate: 10}},
+		Routes:  map[string]RouteLimit{"/heavy": {PerKey: Limit{Rate: 0.5}}},
+	}, c.Now)
+	acquire := func(key, route string) error {
+		release, err := limiter.Acquire(key, route)
+		if err == nil {
+			release()
+		}
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := acquire("reader", "/cheap"); err != nil {
+			t.Fatalf("expected request %d within burst to be admitted: %s", i, err)
+		}
+	}
+	err := acquire("reader", "/cheap")
+	limitErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("expected limit error over burst, got %v", err)
+	}
+	if limitErr.RetryAfter != time.Second {
+		t.Errorf("expected retry after 1s, got %s", limitErr.RetryAfter)
+	}
+	c.now = c.now.Add(time.Second)
+	if err = acquire("reader", "/cheap"); err != nil {
+		t.Errorf("expected request to be admitted after refill: %s", err)
+	}
+
+	// keys have their own budget
+	for i := 0; i < 10; i++ {
+		if err = acquire("bot", "/cheap"); err != nil {
+			t.Fatalf("expected request %d of bot to be admitted: %s", i, err)
+		}
+	}
+	if err = acquire("bot", "/cheap"); err == nil {
+		t.Error("expected request of bot over its rate to be rejected")
+	}
+
+	// routes limit keys on top of their own limits, rejected requests take no tokens
+	c.now = c.now.Add(time.Second)
+	if err = acquire("bot", "/heavy"); err != nil {
+		t.Fatal(err)
+	}
+	if err = acquire("bot", "/heavy"); err == nil {
+		t.Error("expected second request of bot on heavy route to be rejected")
+	}
+	for i := 0; i < 9; i++ {
+		if err = acquire("bot", "/cheap"); err != nil {
+			t.Fatalf("expected rejected requests to take no tokens of bot: %s", err)
+		}
+	}
+}
+
+func TestLimiterConcurrency(t *testing.T) {
+	c := &clock{now: time.Unix(1000, 0)}
+	limiter := NewLimiter(Limits{
+		Default: Limit{MaxConcurrent: 2},
+		Routes:  map[string]RouteLimit{"/heavy": {PerKey: Limit{MaxConcurrent: 1}, MaxConcurrent: 2}},
+	}, c.Now)
+
+	releaseA, err := limiter.Acquire("a", "/heavy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = limiter.Acquire("a", "/heavy"); err == nil {
+		t.Error("expected concurrent request of a on heavy route to be rejected")
+	}
+	releaseB, err := limiter.Acquire("b", "/heavy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = limiter.Acquire("c", "/heavy"); err == nil {
+		t.Error("expected request over concurrency of heavy route to be rejected")
+	}
+	releaseCheap, err := limiter.Acquire("a", "/cheap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = limiter.Acquire("a", "/cheap"); err == nil {
+		t.Error("expected request over concurrency of a to be rejected")
+	}
+
+	releaseA()
+	releaseA()
+	releaseB()
+	releaseCheap()
+	if len(limiter.inFlight) != 0 {
+		t.Errorf("expected no requests in flight, got %v", limiter.inFlight)
+	}
+	if _, err = limiter.Acquire("c", "/heavy"); err != nil {
+		t.Errorf("expected request to be admitted once others are released: %s", err)
+	}
+}
+
+func TestLimiterPrune(t *testing.T) {
+	c := &clock{now: time.Unix(1000, 0)}
+	limiter := NewLimiter(Limits{Default: Limit{Rate: 1}}, c.Now)
+	limiter.buckets["key\x00full"] = &bucket{limit: Limit{Rate: 1}, tokens: 1, last: c.now}
+	limiter.buckets["key\x00empty"] = &bucket{limit: Limit{Rate: 1}, tokens: 0, last: c.now}
+	limiter.prune(c.now)
+	if _, ok := limiter.buckets["key\x00full"]; ok {
+		t.Error("expected full bucket to be pruned")
+	}
+	if _, ok := limiter.buckets["key\x00empty"]; !ok {
+		t.Error("expected empty bucket to be kept")
+	}
+}
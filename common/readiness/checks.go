@@ -0,0 +1,177 @@
+package readiness
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/boltutil"
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/boltdb/bolt"
+)
+
+// Names of checks.
+const (
+	BoltCheckName     = "bolt"
+	NodeCheckName     = "node"
+	DataCheckName     = "data"
+	ExchangeCheckName = "exchanges"
+	LogCheckName      = "stat_logs"
+)
+
+// DBDetails are details of a database of the bolt check.
+type DBDetails struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// BoltCheck checks that all bolt databases opened by the process can be read.
+func BoltCheck() CheckFunc {
+	return func() (interface{}, error) {
+		details := []DBDetails{}
+		for _, db := range boltutil.Opened() {
+			err := db.View(func(tx *bolt.Tx) error {
+				tx.Cursor().First()
+				return nil
+			})
+			if err != nil {
+				return details, fmt.Errorf("reading %s failed: %s", db.Path(), err)
+			}
+			info, err := os.Stat(db.Path())
+			if err != nil {
+				return details, err
+			}
+			details = append(details, DBDetails{Path: db.Path(), Size: info.Size()})
+		}
+		return details, nil
+	}
+}
+
+// HeadFunc returns the number and the time, in millisecond, of the latest block.
+type HeadFunc func() (uint64, uint64, error)
+
+// NodeDetails are details of the node check, times are in millisecond.
+type NodeDetails struct {
+	Block  uint64 `json:"block"`
+	Time   uint64 `json:"time"`
+	Lag    uint64 `json:"lag"`
+	MaxLag uint64 `json:"max_lag"`
+}
+
+// NodeCheck checks that the node is reachable and its latest block is not
+// older than maxLag.
+func NodeCheck(head HeadFunc, maxLag time.Duration) CheckFunc {
+	return func() (interface{}, error) {
+		block, blockTime, err := head()
+		if err != nil {
+			return nil, fmt.Errorf("node is unreachable: %s", err)
+		}
+		details := NodeDetails{Block: block, Time: blockTime, MaxLag: uint64(maxLag / time.Millisecond)}
+		if now := common.GetTimepoint(); now > blockTime {
+			details.Lag = now - blockTime
+		}
+		if details.Lag > details.MaxLag {
+			return details, fmt.Errorf("latest block %d is %d ms old", block, details.Lag)
+		}
+		return details, nil
+	}
+}
+
+// DataCheck checks that the latest prices, auth data and rates are not stale.
+func DataCheck(dataHealth func(timepoint uint64) common.DataHealth) CheckFunc {
+	return func() (interface{}, error) {
+		health := dataHealth(common.GetTimepoint())
+		details := map[string]common.SnapshotHealth{}
+		stale := []string{}
+		for _, kind := range []string{common.OrderbookDataKind, common.AuthDataKind, common.RateDataKind} {
+			snapshot, ok := health.Snapshots[kind]
+			if !ok || snapshot.Stale {
+				stale = append(stale, kind)
+			}
+			details[kind] = snapshot
+		}
+		if len(stale) > 0 {
+			return details, fmt.Errorf("stale data: %s", strings.Join(stale, ", "))
+		}
+		return details, nil
+	}
+}
+
+// ExchangeDetails are details of an exchange of the exchange check.
+type ExchangeDetails struct {
+	// Enabled is false if the exchange is disabled by operators, it is not
+	// checked then.
+	Enabled bool `json:"enabled"`
+	// StaleData are kinds of data of the exchange which are stale.
+	StaleData []string `json:"stale_data,omitempty"`
+	Down      bool     `json:"down"`
+}
+
+// ExchangeCheck checks that at most maxDown enabled exchanges are down, an
+// exchange is down if any kind of data fetched from it is stale. Exchanges
+// disabled on purpose are skipped.
+func ExchangeCheck(statuses func() (common.ExchangesStatus, error), dataHealth func(timepoint uint64) common.DataHealth, maxDown int) CheckFunc {
+	return func() (interface{}, error) {
+		exchanges, err := statuses()
+		if err != nil {
+			return nil, err
+		}
+		details := map[string]ExchangeDetails{}
+		for exchange, status := range exchanges {
+			details[exchange] = ExchangeDetails{Enabled: status.Status}
+		}
+		for _, source := range dataHealth(common.GetTimepoint()).Sources {
+			exchange, ok := details[source.Source]
+			if !ok || !exchange.Enabled || !source.Stale {
+				continue
+			}
+			exchange.StaleData = append(exchange.StaleData, source.Data)
+			exchange.Down = true
+			details[source.Source] = exchange
+		}
+		down := []string{}
+		for exchange, detail := range details {
+			if detail.Down {
+				down = append(down, exchange)
+			}
+		}
+		sort.Strings(down)
+		if len(down) > maxDown {
+			return details, fmt.Errorf("exchanges are down: %s", strings.Join(down, ", "))
+		}
+		return details, nil
+	}
+}
+
+// LogDetails are details of the stat log check.
+type LogDetails struct {
+	LogBlock     uint64 `json:"log_block"`
+	CurrentBlock uint64 `json:"current_block"`
+	Lag          uint64 `json:"lag"`
+	MaxLag       uint64 `json:"max_lag"`
+}
+
+// LogCheck checks that logs are fetched to at most maxLag blocks behind the
+// current block.
+func LogCheck(logBlock, currentBlock func() (uint64, error), maxLag uint64) CheckFunc {
+	return func() (interface{}, error) {
+		last, err := logBlock()
+		if err != nil {
+			return nil, fmt.Errorf("getting last log block failed: %s", err)
+		}
+		current, err := currentBlock()
+		if err != nil {
+			return nil, fmt.Errorf("getting current block failed: %s", err)
+		}
+		details := LogDetails{LogBlock: last, CurrentBlock: current, MaxLag: maxLag}
+		if current > last {
+			details.Lag = current - last
+		}
+		if details.Lag > maxLag {
+			return details, fmt.Errorf("logs are %d blocks behind", details.Lag)
+		}
+		return details, nil
+	}
+}
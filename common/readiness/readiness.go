@@ -0,0 +1,198 @@
+// Package readiness checks whether subsystems of the service work, e.g the
+// databases, the node and the fetchers, to tell orchestrators whether an
+// instance is ready to serve.
+package readiness
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+// Thresholds are the limits of readiness checks.
+type Thresholds struct {
+	// CheckTimeout is the maximum duration of a check before it fails.
+	CheckTimeout time.Duration
+	// MaxHeadLag is the maximum age of the latest block of the node.
+	MaxHeadLag time.Duration
+	// MaxLogBlockLag is the maximum number of blocks the stat log fetcher
+	// is behind the head of the chain.
+	MaxLogBlockLag uint64
+	// MaxExchangesDown is the maximum number of enabled exchanges which are
+	// down, exchanges disabled by operators are not counted.
+	MaxExchangesDown int
+}
+
+// DefaultThresholds returns the thresholds used if they are not configured.
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		CheckTimeout:     5 * time.Second,
+		MaxHeadLag:       2 * time.Minute,
+		MaxLogBlockLag:   200,
+		MaxExchangesDown: 0,
+	}
+}
+
+// Config is the configuration of readiness thresholds, read from the
+// readiness object of the secret config file. Missing thresholds use the
+// default ones.
+type Config struct {
+	CheckTimeout     string  `json:"check_timeout"`
+	MaxHeadLag       string  `json:"max_head_lag"`
+	MaxLogBlockLag   *uint64 `json:"max_log_block_lag"`
+	MaxExchangesDown *int    `json:"max_exchanges_down"`
+}
+
+// GetConfigFromFile reads the readiness configuration from a JSON file.
+func GetConfigFromFile(path string) (Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	result := struct {
+		Readiness Config `json:"readiness"`
+	}{}
+	err = json.Unmarshal(data, &result)
+	return result.Readiness, err
+}
+
+// Thresholds parses the configured thresholds.
+func (self Config) Thresholds() (Thresholds, error) {
+	result := DefaultThresholds()
+	for _, duration := range []struct {
+		name   string
+		value  string
+		result *time.Duration
+	}{
+		{"check_timeout", self.CheckTimeout, &result.CheckTimeout},
+		{"max_head_lag", self.MaxHeadLag, &result.MaxHeadLag},
+	} {
+		if duration.value == "" {
+			continue
+		}
+		value, err := time.ParseDuration(duration.value)
+		if err != nil {
+			return result, fmt.Errorf("invalid %s: %s", duration.name, err)
+		}
+		if value <= 0 {
+			return result, fmt.Errorf("%s must be positive", duration.name)
+		}
+		*duration.result = value
+	}
+	if self.MaxLogBlockLag != nil {
+		result.MaxLogBlockLag = *self.MaxLogBlockLag
+	}
+	if self.MaxExchangesDown != nil {
+		if *self.MaxExchangesDown < 0 {
+			return result, errors.New("max_exchanges_down must not be negative")
+		}
+		result.MaxExchangesDown = *self.MaxExchangesDown
+	}
+	return result, nil
+}
+
+// CheckFunc checks a subsystem, it returns details of the check and an
+// error if the subsystem doesn't work.
+type CheckFunc func() (interface{}, error)
+
+// Result is the result of a check. Duration is in millisecond.
+type Result struct {
+	Name     string      `json:"name"`
+	Healthy  bool        `json:"healthy"`
+	Error    string      `json:"error,omitempty"`
+	Duration uint64      `json:"duration"`
+	Details  interface{} `json:"details,omitempty"`
+}
+
+// Report is the result of all checks, Ready is true if all checks pass.
+type Report struct {
+	Timestamp uint64   `json:"timestamp"`
+	Ready     bool     `json:"ready"`
+	Checks    []Result `json:"checks"`
+}
+
+// Failed returns names of failed checks.
+func (self Report) Failed() []string {
+	result := []string{}
+	for _, check := range self.Checks {
+		if !check.Healthy {
+			result = append(result, check.Name)
+		}
+	}
+	return result
+}
+
+// Checker runs checks of subsystems.
+type Checker struct {
+	thresholds Thresholds
+	checks     map[string]CheckFunc
+}
+
+// NewChecker creates a checker without checks.
+func NewChecker(thresholds Thresholds) *Checker {
+	return &Checker{thresholds: thresholds, checks: map[string]CheckFunc{}}
+}
+
+// Thresholds returns the thresholds of checks.
+func (self *Checker) Thresholds() Thresholds {
+	return self.thresholds
+}
+
+// Add adds a check, it must be called before running checks.
+func (self *Checker) Add(name string, check CheckFunc) {
+	self.checks[name] = check
+}
+
+// Check runs all checks concurrently, a check fails if it doesn't finish
+// in the check timeout.
+func (self *Checker) Check() Report {
+	results := make(chan Result, len(self.checks))
+	for name, check := range self.checks {
+		go func(name string, check CheckFunc) {
+			results <- self.run(name, check)
+		}(name, check)
+	}
+	report := Report{Timestamp: common.GetTimepoint(), Ready: true, Checks: []Result{}}
+	for range self.checks {
+		result := <-results
+		report.Ready = report.Ready && result.Healthy
+		report.Checks = append(report.Checks, result)
+	}
+	sort.Slice(report.Checks, func(i, j int) bool {
+		return report.Checks[i].Name < report.Checks[j].Name
+	})
+	return report
+}
+
+type checkOutput struct {
+	details interface{}
+	err     error
+}
+
+func (self *Checker) run(name string, check CheckFunc) Result {
+	start := time.Now()
+	// buffered, so a check finishing after the timeout doesn't block forever
+	output := make(chan checkOutput, 1)
+	go func() {
+		details, err := check()
+		output <- checkOutput{details, err}
+	}()
+	result := Result{Name: name}
+	select {
+	case o := <-output:
+		result.Details = o.details
+		if o.err != nil {
+			result.Error = o.err.Error()
+		}
+	case <-time.After(self.thresholds.CheckTimeout):
+		result.Error = fmt.Sprintf("check timed out after %s", self.thresholds.CheckTimeout)
+	}
+	result.Healthy = result.Error == ""
+	result.Duration = uint64(time.Since(start) / time.Millisecond)
+	return result
+}
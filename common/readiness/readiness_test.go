@@ -0,0 +1,212 @@
+package readiness
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/boltutil"
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+func TestConfigThresholds(t *testing.T) {
+	logLag := uint64(50)
+	exchangesDown := 1
+	negative := -1
+	var tests = []struct {
+		config   Config
+		expected Thresholds
+		err      bool
+	}{
+		{config: Config{}, expected: DefaultThresholds()},
+		{
+			config: Config{CheckTimeout: "2s", MaxHeadLag: "1m", MaxLogBlockLag: &logLag, MaxExchangesDown: &exchangesDown},
+			expected: Thresholds{
+				CheckTimeout:     2 * time.Second,
+				MaxHeadLag:       time.Minute,
+				MaxLogBlockLag:   50,
+				MaxExchangesDown: 1,
+			},
+		},
+		{config: Config{CheckTimeout: "soon"}, err: true},
+		{config: Config{MaxHeadLag: "-1m"}, err: true},
+		{config: Config{MaxExchangesDown: &negative}, err: true},
+	}
+	for _, tc := range tests {
+		thresholds, err := tc.config.Thresholds()
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected error of config %+v", tc.config)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if thresholds != tc.expected {
+			t.Errorf("expected thresholds %+v, got %+v", tc.expected, thresholds)
+		}
+	}
+}
+
+func TestChecker(t *testing.T) {
+	thresholds := DefaultThresholds()
+	thresholds.CheckTimeout = 50 * time.Millisecond
+	checker := NewChecker(thresholds)
+	checker.Add("ok", func() (interface{}, error) { return "details", nil })
+	report := checker.Check()
+	if !report.Ready || len(report.Checks) != 1 || report.Checks[0].Details != "details" {
+		t.Errorf("expected ready report, got %+v", report)
+	}
+
+	done := make(chan bool)
+	defer close(done)
+	checker.Add("failed", func() (interface{}, error) { return nil, errors.New("node is down") })
+	checker.Add("slow", func() (interface{}, error) {
+		<-done
+		return nil, nil
+	})
+	report = checker.Check()
+	if report.Ready {
+		t.Error("expected report not to be ready")
+	}
+	if failed := report.Failed(); !reflect.DeepEqual(failed, []string{"failed", "slow"}) {
+		t.Errorf("expected failed and slow checks, got %v", failed)
+	}
+	if report.Checks[0].Error != "node is down" || report.Checks[2].Error == "" {
+		t.Errorf("expected errors of failed checks, got %+v", report.Checks)
+	}
+}
+
+func TestChecks(t *testing.T) {
+	now := common.GetTimepoint()
+	// bittrex is disabled by operators
+	statuses := func() (common.ExchangesStatus, error) {
+		return common.ExchangesStatus{"binance": {Status: true}, "huobi": {Status: true}, "bittrex": {Status: false}}, nil
+	}
+	var tests = []struct {
+		name    string
+		check   CheckFunc
+		healthy bool
+	}{
+		{
+			name:    "fresh head",
+			check:   NodeCheck(func() (uint64, uint64, error) { return 100, now - 10000, nil }, time.Minute),
+			healthy: true,
+		},
+		{
+			name:  "old head",
+			check: NodeCheck(func() (uint64, uint64, error) { return 100, now - 120000, nil }, time.Minute),
+		},
+		{
+			name:  "unreachable node",
+			check: NodeCheck(func() (uint64, uint64, error) { return 0, 0, errors.New("connection refused") }, time.Minute),
+		},
+		{
+			name: "fresh data",
+			check: DataCheck(func(uint64) common.DataHealth {
+				return common.DataHealth{Snapshots: map[string]common.SnapshotHealth{
+					common.OrderbookDataKind: {},
+					common.AuthDataKind:      {},
+					common.RateDataKind:      {},
+					common.GoldDataKind:      {Stale: true},
+				}}
+			}),
+			healthy: true,
+		},
+		{
+			name: "stale rates",
+			check: DataCheck(func(uint64) common.DataHealth {
+				return common.DataHealth{Snapshots: map[string]common.SnapshotHealth{
+					common.OrderbookDataKind: {},
+					common.AuthDataKind:      {},
+					common.RateDataKind:      {Stale: true},
+				}}
+			}),
+		},
+		{
+			name: "exchange down within limit",
+			check: ExchangeCheck(statuses, func(uint64) common.DataHealth {
+				return common.DataHealth{Sources: []common.SourceHealth{
+					{Source: "binance", Data: "auth_data"},
+					{Source: "huobi", Data: "auth_data", Stale: true},
+				}}
+			}, 1),
+			healthy: true,
+		},
+		{
+			name: "exchanges down",
+			check: ExchangeCheck(statuses, func(uint64) common.DataHealth {
+				return common.DataHealth{Sources: []common.SourceHealth{
+					{Source: "binance", Data: "orderbook", Stale: true},
+					{Source: "huobi", Data: "auth_data", Stale: true},
+				}}
+			}, 1),
+		},
+		{
+			name: "disabled exchange is not checked",
+			check: ExchangeCheck(statuses, func(uint64) common.DataHealth {
+				return common.DataHealth{Sources: []common.SourceHealth{
+					{Source: "bittrex", Data: "auth_data", Stale: true},
+				}}
+			}, 0),
+			healthy: true,
+		},
+		{
+			name: "logs fetched",
+			check: LogCheck(
+				func() (uint64, error) { return 990, nil },
+				func() (uint64, error) { return 1000, nil }, 100),
+			healthy: true,
+		},
+		{
+			name: "logs behind",
+			check: LogCheck(
+				func() (uint64, error) { return 800, nil },
+				func() (uint64, error) { return 1000, nil }, 100),
+		},
+	}
+	for _, tc := range tests {
+		_, err := tc.check()
+		if healthy := err == nil; healthy != tc.healthy {
+			t.Errorf("%s: expected healthy %t, got error %v", tc.name, tc.healthy, err)
+		}
+	}
+}
+
+func TestBoltCheck(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test_readiness")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}()
+	db, err := boltutil.Open(filepath.Join(tmpDir, "test.db"), boltutil.Migrations{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := BoltCheck()
+	if _, err = check(); err != nil {
+		t.Errorf("expected bolt check to pass, got %s", err)
+	}
+	// a database closed without unregistering it is unexpected
+	if err = db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = check(); err == nil {
+		t.Error("expected bolt check of closed database to fail")
+	}
+	if err = boltutil.Close(db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = check(); err != nil {
+		t.Errorf("expected bolt check to pass once the database is unregistered, got %s", err)
+	}
+}
@@ -303,7 +303,7 @@ func (self ActivityRecord) IsExchangePending() bool {
 
 func (self ActivityRecord) IsBlockchainPending() bool {
 	switch self.Action {
-	case "withdraw", "deposit", "set_rates":
+	case "withdraw", "deposit", "set_rates", "set_step_function":
 		return (self.MiningStatus == "" || self.MiningStatus == "submitted") && self.ExchangeStatus != "failed"
 	}
 	return true
@@ -322,13 +322,46 @@ func (self ActivityRecord) IsPending() bool {
 	case "trade":
 		return (self.ExchangeStatus == "" || self.ExchangeStatus == "submitted") &&
 			self.ExchangeStatus != "failed"
-	case "set_rates":
+	case "set_rates", "set_step_function":
 		return (self.MiningStatus == "" || self.MiningStatus == "submitted") &&
 			self.ExchangeStatus != "failed"
 	}
 	return true
 }
 
+// Tokens returns IDs of tokens of the activity, from its token, base, quote
+// and tokens params. Params are tokens when the activity is recorded and
+// token IDs after it is decoded from storage.
+func (self ActivityRecord) Tokens() []string {
+	result := []string{}
+	add := func(param interface{}) {
+		switch token := param.(type) {
+		case Token:
+			result = append(result, token.ID)
+		case string:
+			result = append(result, token)
+		}
+	}
+	for _, name := range []string{"token", "base", "quote"} {
+		add(self.Params[name])
+	}
+	switch tokens := self.Params["tokens"].(type) {
+	case []Token:
+		for _, token := range tokens {
+			add(token)
+		}
+	case []string:
+		for _, token := range tokens {
+			add(token)
+		}
+	case []interface{}:
+		for _, token := range tokens {
+			add(token)
+		}
+	}
+	return result
+}
+
 type ActivityStatus struct {
 	ExchangeStatus string
 	Tx             string
@@ -384,9 +417,20 @@ type OnePriceResponse struct {
 
 type OnePrice map[ExchangeID]ExchangePrice
 
+// Reasons of order books rejected by validation.
+const (
+	EmptyBookReason      = "empty_book"
+	CrossedBookReason    = "crossed_book"
+	PriceJumpReason      = "price_jump"
+	PriceDeviationReason = "price_deviation"
+)
+
 type ExchangePrice struct {
-	Valid      bool
-	Error      string
+	Valid bool
+	Error string
+	// Reason is why the order book is rejected by validation, empty if it
+	// passes or fetching failed.
+	Reason     string `json:",omitempty"`
 	Timestamp  Timestamp
 	Bids       []PriceEntry
 	Asks       []PriceEntry
@@ -63,3 +63,32 @@ func TestJSONToActivityID(t *testing.T) {
 		}
 	}
 }
+
+func TestActivityRecordTokens(t *testing.T) {
+	knc := NewToken("KNC", "0xdd974d5c2e2928dea5f71b9825b8b646686bd200", 18)
+	omg := NewToken("OMG", "0xd26114cd6ee289accf82350c8d8487fedb8a0c07", 18)
+	records := []ActivityRecord{
+		{Action: "deposit", Params: map[string]interface{}{"token": knc}},
+		{Action: "trade", Params: map[string]interface{}{"base": knc, "quote": omg}},
+		{Action: "set_rates", Params: map[string]interface{}{"tokens": []Token{knc, omg}}},
+		{Action: "set_rates", Params: map[string]interface{}{"tokens": []string{"KNC", "OMG"}}},
+	}
+	expected := [][]string{{"KNC"}, {"KNC", "OMG"}, {"KNC", "OMG"}, {"KNC", "OMG"}}
+	for i, record := range records {
+		if tokens := record.Tokens(); fmt.Sprint(tokens) != fmt.Sprint(expected[i]) {
+			t.Errorf("%s: expected tokens %v, got %v", record.Action, expected[i], tokens)
+		}
+		// tokens are encoded as their IDs in storage
+		data, err := json.Marshal(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := ActivityRecord{}
+		if err = json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if tokens := decoded.Tokens(); fmt.Sprint(tokens) != fmt.Sprint(expected[i]) {
+			t.Errorf("decoded %s: expected tokens %v, got %v", record.Action, expected[i], tokens)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"strconv"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/common/event"
+)
+
+// publishingActivityStorage publishes activities recorded by core.
+type publishingActivityStorage struct {
+	ActivityStorage
+	publisher event.Publisher
+}
+
+func (self publishingActivityStorage) Record(
+	action string,
+	id common.ActivityID,
+	destination string,
+	params map[string]interface{},
+	result map[string]interface{},
+	estatus string,
+	mstatus string,
+	timepoint uint64) error {
+	if err := self.ActivityStorage.Record(action, id, destination, params, result, estatus, mstatus, timepoint); err != nil {
+		return err
+	}
+	self.publisher.Publish(event.ActivityTopic, common.ActivityRecord{
+		Action:         action,
+		ID:             id,
+		Destination:    destination,
+		Params:         params,
+		Result:         result,
+		ExchangeStatus: estatus,
+		MiningStatus:   mstatus,
+		Timestamp:      common.Timestamp(strconv.FormatUint(timepoint, 10)),
+	})
+	return nil
+}
+
+// SetEventPublisher sets the publisher of events of recorded activities.
+func (self *ReserveCore) SetEventPublisher(publisher event.Publisher) {
+	self.activityStorage = publishingActivityStorage{self.activityStorage, publisher}
+}
@@ -20,7 +20,8 @@ type ActivityStorage interface {
 
 	GetActivity(id common.ActivityID) (common.ActivityRecord, error)
 
-	// PendingSetrate return the last pending set rate and number of pending
-	// transactions.
+	// PendingSetrate return the last pending transaction of the pricing
+	// operator, which is set_rates or set_step_function, and number of
+	// pending transactions with its nonce.
 	PendingSetrate(minedNonce uint64) (*common.ActivityRecord, uint64, error)
 }
@@ -23,6 +23,10 @@ type Blockchain interface {
 		block *big.Int,
 		nonce *big.Int,
 		gasPrice *big.Int) (*types.Transaction, error)
+	SetQtyStepFunction(token ethereum.Address, xBuy, yBuy, xSell, ySell []*big.Int, nonce, gasPrice *big.Int) (*types.Transaction, error)
+	SetImbalanceStepFunction(token ethereum.Address, xBuy, yBuy, xSell, ySell []*big.Int, nonce, gasPrice *big.Int) (*types.Transaction, error)
 	SetRateMinedNonce() (uint64, error)
 	GetAddresses() *common.Addresses
+	CurrentBlock() (uint64, error)
+	GetTokenPricingState(token common.Token, atBlock uint64) (common.TokenPricingState, error)
 }
@@ -0,0 +1,33 @@
+package core
+
+import (
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var recordedActivities = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "reserve_core_activities_total",
+	Help: "Number of activities recorded by core, e.g trades, deposits, withdrawals and set rates.",
+}, []string{"action", "exchange_status", "mining_status"})
+
+// instrumentedActivityStorage counts activities recorded by core.
+type instrumentedActivityStorage struct {
+	ActivityStorage
+}
+
+func (self instrumentedActivityStorage) Record(
+	action string,
+	id common.ActivityID,
+	destination string,
+	params map[string]interface{},
+	result map[string]interface{},
+	estatus string,
+	mstatus string,
+	timepoint uint64) error {
+	if err := self.ActivityStorage.Record(action, id, destination, params, result, estatus, mstatus, timepoint); err != nil {
+		return err
+	}
+	recordedActivities.WithLabelValues(action, estatus, mstatus).Inc()
+	return nil
+}
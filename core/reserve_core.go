@@ -6,6 +6,7 @@ import (
 	"log"
 	"math/big"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/KyberNetwork/reserve-data/common"
@@ -27,6 +28,9 @@ type ReserveCore struct {
 	blockchain      Blockchain
 	activityStorage ActivityStorage
 	rm              ethereum.Address
+	// pricingMu serializes transactions of the pricing operator, so they
+	// don't take the same nonce.
+	pricingMu *sync.Mutex
 }
 
 func NewReserveCore(
@@ -35,8 +39,9 @@ func NewReserveCore(
 	rm ethereum.Address) *ReserveCore {
 	return &ReserveCore{
 		blockchain,
-		storage,
+		instrumentedActivityStorage{storage},
 		rm,
+		&sync.Mutex{},
 	}
 }
 
@@ -310,33 +315,72 @@ func calculateNewGasPrice(old *big.Int, count uint64) *big.Int {
 	}
 }
 
-func (self ReserveCore) pendingSetrateInfo(minedNonce uint64) (*big.Int, *big.Int, uint64, error) {
+// pendingPricingTxInfo returns the action, nonce and gas price of the pending
+// transaction of the pricing operator with the highest nonce, and the number
+// of pending transactions with that nonce. The nonce is nil if there is none.
+func (self ReserveCore) pendingPricingTxInfo(minedNonce uint64) (string, *big.Int, *big.Int, uint64, error) {
 	act, count, err := self.activityStorage.PendingSetrate(minedNonce)
 	if err != nil {
-		return nil, nil, 0, err
+		return "", nil, nil, 0, err
 	}
 	if act == nil {
-		return nil, nil, 0, nil
+		return "", nil, nil, 0, nil
 	}
 	nonceStr, ok := act.Result["nonce"].(string)
 	if !ok {
 		nErr := fmt.Errorf("cannot convert result[nonce] (value %v) to string type", act.Result["nonce"])
-		return nil, nil, count, nErr
+		return "", nil, nil, count, nErr
 	}
 	gasPriceStr, ok := act.Result["gasPrice"].(string)
 	if !ok {
 		nErr := fmt.Errorf("cannot convert result[gasPrice] (value %v) to string type", act.Result["gasPrice"])
-		return nil, nil, count, nErr
+		return "", nil, nil, count, nErr
 	}
 	nonce, err := strconv.ParseUint(nonceStr, 10, 64)
 	if err != nil {
-		return nil, nil, count, err
+		return "", nil, nil, count, err
 	}
 	gasPrice, err := strconv.ParseUint(gasPriceStr, 10, 64)
 	if err != nil {
-		return nil, nil, count, err
+		return "", nil, nil, count, err
 	}
-	return big.NewInt(int64(nonce)), big.NewInt(int64(gasPrice)), count, nil
+	return act.Action, big.NewInt(int64(nonce)), big.NewInt(int64(gasPrice)), count, nil
+}
+
+// initialGasPrice returns the gas price of new transactions of the pricing
+// operator, the recommended price if it is not higher than HIGH_BOUND_GAS_PRICE.
+func (self ReserveCore) initialGasPrice() *big.Int {
+	recommendedPrice := self.blockchain.StandardGasPrice()
+	if recommendedPrice == 0 || recommendedPrice > HIGH_BOUND_GAS_PRICE {
+		return common.GweiToWei(10)
+	}
+	return common.GweiToWei(recommendedPrice)
+}
+
+// pricingTxParams returns the nonce and gas price of the next transaction of
+// the pricing operator, which sets rates and step functions. If replace is
+// true and the pending transaction with the highest nonce sets rates, it is
+// replaced by a higher gas price. Otherwise the transaction takes the nonce
+// after pending transactions, so it doesn't replace them.
+func (self ReserveCore) pricingTxParams(replace bool) (*big.Int, *big.Int, error) {
+	minedNonce, err := self.blockchain.SetRateMinedNonce()
+	if err != nil {
+		return nil, nil, errors.New("Couldn't get mined nonce of set rate operator")
+	}
+	action, oldNonce, oldPrice, count, err := self.pendingPricingTxInfo(minedNonce)
+	log.Printf("old action: %s, old nonce: %v, old price: %v, count: %d, err: %v", action, oldNonce, oldPrice, count, err)
+	if err != nil {
+		return nil, nil, errors.New("Couldn't check pending set rate tx pool. Please try later")
+	}
+	if oldNonce == nil {
+		return big.NewInt(int64(minedNonce)), self.initialGasPrice(), nil
+	}
+	if replace && action == "set_rates" {
+		newPrice := calculateNewGasPrice(oldPrice, count)
+		log.Printf("Trying to replace old tx with new price: %s", newPrice.Text(10))
+		return oldNonce, newPrice, nil
+	}
+	return big.NewInt(0).Add(oldNonce, big.NewInt(1)), self.initialGasPrice(), nil
 }
 
 func (self ReserveCore) SetRates(
@@ -346,6 +390,10 @@ func (self ReserveCore) SetRates(
 	block *big.Int,
 	afpMids []*big.Int,
 	additionalMsgs []string) (common.ActivityID, error) {
+	// the lock is held until the tx is recorded, so it is pending to
+	// the next transaction of the pricing operator
+	self.pricingMu.Lock()
+	defer self.pricingMu.Unlock()
 
 	lentokens := len(tokens)
 	lenbuys := len(buys)
@@ -369,42 +417,14 @@ func (self ReserveCore) SetRates(
 				tokenAddrs = append(tokenAddrs, ethereum.HexToAddress(token.Address))
 			}
 			// if there is a pending set rate tx, we replace it
-			var oldNonce *big.Int
-			var oldPrice *big.Int
-			var minedNonce uint64
-			var count uint64
-			minedNonce, err = self.blockchain.SetRateMinedNonce()
-			if err != nil {
-				err = errors.New("Couldn't get mined nonce of set rate operator")
-			} else {
-				oldNonce, oldPrice, count, err = self.pendingSetrateInfo(minedNonce)
-				log.Printf("old nonce: %v, old price: %v, count: %d, err: %v", oldNonce, oldPrice, count, err)
-				if err != nil {
-					err = errors.New("Couldn't check pending set rate tx pool. Please try later")
-				} else {
-					if oldNonce != nil {
-						newPrice := calculateNewGasPrice(oldPrice, count)
-						log.Printf("Trying to replace old tx with new price: %s", newPrice.Text(10))
-						tx, err = self.blockchain.SetRates(
-							tokenAddrs, buys, sells, block,
-							oldNonce,
-							newPrice,
-						)
-					} else {
-						recommendedPrice := self.blockchain.StandardGasPrice()
-						var initPrice *big.Int
-						if recommendedPrice == 0 || recommendedPrice > HIGH_BOUND_GAS_PRICE {
-							initPrice = common.GweiToWei(10)
-						} else {
-							initPrice = common.GweiToWei(recommendedPrice)
-						}
-						tx, err = self.blockchain.SetRates(
-							tokenAddrs, buys, sells, block,
-							big.NewInt(int64(minedNonce)),
-							initPrice,
-						)
-					}
-				}
+			var nonce, gasPrice *big.Int
+			nonce, gasPrice, err = self.pricingTxParams(true)
+			if err == nil {
+				tx, err = self.blockchain.SetRates(
+					tokenAddrs, buys, sells, block,
+					nonce,
+					gasPrice,
+				)
 			}
 		}
 	}
@@ -445,6 +465,113 @@ func (self ReserveCore) SetRates(
 	return uid, err
 }
 
+// SetStepFunction sets the qty or imbalance step function of a token in pricing contract
+// and records the transaction as a set_step_function activity. The transaction is sent
+// by the pricing operator after its pending transactions, with the gas price of set rates.
+func (self ReserveCore) SetStepFunction(token common.Token, stepType string, xBuy, yBuy, xSell, ySell []*big.Int) (common.ActivityID, error) {
+	var (
+		tx       *types.Transaction
+		txhex    = ethereum.Hash{}.Hex()
+		txnonce  = "0"
+		txprice  = "0"
+		nonce    *big.Int
+		gasPrice *big.Int
+		err      error
+		status   string
+	)
+	self.pricingMu.Lock()
+	defer self.pricingMu.Unlock()
+	addr := ethereum.HexToAddress(token.Address)
+	switch stepType {
+	case "qty", "imbalance":
+		nonce, gasPrice, err = self.pricingTxParams(false)
+	default:
+		err = fmt.Errorf("unknown step function type %s", stepType)
+	}
+	if err == nil {
+		if stepType == "qty" {
+			tx, err = self.blockchain.SetQtyStepFunction(addr, xBuy, yBuy, xSell, ySell, nonce, gasPrice)
+		} else {
+			tx, err = self.blockchain.SetImbalanceStepFunction(addr, xBuy, yBuy, xSell, ySell, nonce, gasPrice)
+		}
+	}
+	if err != nil {
+		status = statusFailed
+	} else {
+		status = statusSubmitted
+		txhex = tx.Hash().Hex()
+		txnonce = strconv.FormatUint(tx.Nonce(), 10)
+		txprice = tx.GasPrice().Text(10)
+	}
+	uid := timebasedID(txhex)
+	if sErr := self.activityStorage.Record(
+		"set_step_function",
+		uid,
+		"blockchain",
+		map[string]interface{}{
+			"token": token,
+			"type":  stepType,
+			"xBuy":  xBuy,
+			"yBuy":  yBuy,
+			"xSell": xSell,
+			"ySell": ySell,
+		}, map[string]interface{}{
+			"tx":       txhex,
+			"nonce":    txnonce,
+			"gasPrice": txprice,
+			"error":    common.ErrorToString(err),
+		},
+		"",
+		status,
+		common.GetTimepoint(),
+	); sErr != nil {
+		log.Printf("Core ----------> Recording set step function activity failed: %s", sErr)
+	}
+	log.Printf(
+		"Core ----------> Set %s step function of %s: ==> Result: tx: %s, nonce: %s, price: %s, error: %s",
+		stepType, token.ID, txhex, txnonce, txprice, err,
+	)
+	return uid, err
+}
+
+// GetTokenPricingState returns the current pricing contract data of given token.
+func (self ReserveCore) GetTokenPricingState(token common.Token) (common.TokenPricingState, error) {
+	return self.blockchain.GetTokenPricingState(token, 0)
+}
+
+// SimulateRate previews the rate that pricing contract returns for trading qty of
+// given token at given block, block 0 means the current block.
+// Past blocks are simulated with the pricing state at that block, future blocks
+// with the current state.
+// If newRate is not nil, it replaces the on-chain rate of the simulated side
+// as if it was set at that block. The compact rounding of SetRates is not applied.
+func (self ReserveCore) SimulateRate(token common.Token, buy bool, qty *big.Int, block uint64, newRate *big.Int) (*big.Int, error) {
+	current, err := self.blockchain.CurrentBlock()
+	if err != nil {
+		return nil, err
+	}
+	if block == 0 {
+		block = current
+	}
+	stateBlock := block
+	if block >= current {
+		stateBlock = 0
+	}
+	state, err := self.blockchain.GetTokenPricingState(token, stateBlock)
+	if err != nil {
+		return nil, err
+	}
+	if newRate != nil {
+		if buy {
+			state.BaseBuy, state.CompactBuy = newRate, 0
+		} else {
+			state.BaseSell, state.CompactSell = newRate, 0
+		}
+		state.RateUpdateBlock = block
+	}
+	return common.SimulateRate(state, block, buy, qty)
+}
+
 func sanityCheck(buys, afpMid, sells []*big.Int) error {
 	eth := big.NewFloat(0).SetInt(common.EthToWei(1))
 	for i, s := range sells {
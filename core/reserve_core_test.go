@@ -2,9 +2,12 @@ package core
 
 import (
 	"math/big"
+	"reflect"
+	"strconv"
 	"testing"
 
 	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/common/event"
 	ethereum "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 )
@@ -90,6 +93,14 @@ func (self testBlockchain) SetRates(
 	return tx, nil
 }
 
+func (self testBlockchain) SetQtyStepFunction(token ethereum.Address, xBuy, yBuy, xSell, ySell []*big.Int, nonce, gasPrice *big.Int) (*types.Transaction, error) {
+	return types.NewTransaction(0, token, big.NewInt(0), 300000, big.NewInt(1000000000), []byte{}), nil
+}
+
+func (self testBlockchain) SetImbalanceStepFunction(token ethereum.Address, xBuy, yBuy, xSell, ySell []*big.Int, nonce, gasPrice *big.Int) (*types.Transaction, error) {
+	return types.NewTransaction(0, token, big.NewInt(0), 300000, big.NewInt(1000000000), []byte{}), nil
+}
+
 func (self testBlockchain) StandardGasPrice() float64 {
 	return 0
 }
@@ -102,6 +113,14 @@ func (self testBlockchain) GetAddresses() *common.Addresses {
 	return &common.Addresses{}
 }
 
+func (self testBlockchain) CurrentBlock() (uint64, error) {
+	return 0, nil
+}
+
+func (self testBlockchain) GetTokenPricingState(token common.Token, atBlock uint64) (common.TokenPricingState, error) {
+	return common.TokenPricingState{}, nil
+}
+
 type testActivityStorage struct {
 	PendingDeposit bool
 }
@@ -164,3 +183,169 @@ func TestNotAllowDeposit(t *testing.T) {
 		t.Fatalf("Expected to be able to deposit different token")
 	}
 }
+
+func TestActivityEvents(t *testing.T) {
+	core := getTestCore(false)
+	hub := event.NewHub()
+	subscription, err := hub.Subscribe([]string{event.ActivityTopic}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	core.SetEventPublisher(hub)
+	if _, err = core.Deposit(
+		testExchange{},
+		common.NewToken("KNC", "0x1111111111111111111111111111111111111111", 18),
+		big.NewInt(10),
+		common.GetTimepoint(),
+	); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-subscription.Events():
+		activity, ok := e.Data.(common.ActivityRecord)
+		if !ok || activity.Action != "deposit" || activity.Destination != "bittrex" {
+			t.Errorf("expected deposit activity event, got %+v", e)
+		}
+	default:
+		t.Error("expected event of recorded activity")
+	}
+}
+
+type testSimulateBlockchain struct {
+	testBlockchain
+	current     uint64
+	stateBlocks *[]uint64
+}
+
+func (self testSimulateBlockchain) CurrentBlock() (uint64, error) {
+	return self.current, nil
+}
+
+func (self testSimulateBlockchain) GetTokenPricingState(token common.Token, atBlock uint64) (common.TokenPricingState, error) {
+	*self.stateBlocks = append(*self.stateBlocks, atBlock)
+	return common.TokenPricingState{}, nil
+}
+
+func TestSimulateRateAtBlock(t *testing.T) {
+	var stateBlocks []uint64
+	core := NewReserveCore(
+		testSimulateBlockchain{current: 100, stateBlocks: &stateBlocks},
+		testActivityStorage{},
+		ethereum.Address{},
+	)
+	token := common.NewToken("KNC", "0x1111111111111111111111111111111111111111", 18)
+	for _, block := range []uint64{90, 0, 100, 110} {
+		// no rates are set, only the block of the state is checked
+		_, _ = core.SimulateRate(token, true, big.NewInt(1), block, nil)
+	}
+	expected := []uint64{90, 0, 0, 0}
+	if !reflect.DeepEqual(stateBlocks, expected) {
+		t.Errorf("expected pricing states at blocks %v, got %v", expected, stateBlocks)
+	}
+}
+
+// testPricingBlockchain records nonces and gas prices of pricing transactions.
+type testPricingBlockchain struct {
+	testBlockchain
+	nonces    *[]uint64
+	gasPrices *[]*big.Int
+}
+
+func (self testPricingBlockchain) SetRateMinedNonce() (uint64, error) {
+	return 5, nil
+}
+
+func (self testPricingBlockchain) StandardGasPrice() float64 {
+	return 20
+}
+
+func (self testPricingBlockchain) newTx(nonce, gasPrice *big.Int) *types.Transaction {
+	*self.nonces = append(*self.nonces, nonce.Uint64())
+	*self.gasPrices = append(*self.gasPrices, gasPrice)
+	return types.NewTransaction(nonce.Uint64(), ethereum.Address{}, big.NewInt(0), 300000, gasPrice, []byte{})
+}
+
+func (self testPricingBlockchain) SetRates(tokens []ethereum.Address, buys, sells []*big.Int, block, nonce, gasPrice *big.Int) (*types.Transaction, error) {
+	return self.newTx(nonce, gasPrice), nil
+}
+
+func (self testPricingBlockchain) SetQtyStepFunction(token ethereum.Address, xBuy, yBuy, xSell, ySell []*big.Int, nonce, gasPrice *big.Int) (*types.Transaction, error) {
+	return self.newTx(nonce, gasPrice), nil
+}
+
+// testPendingActivityStorage keeps recorded activities as pending.
+type testPendingActivityStorage struct {
+	testActivityStorage
+	pendings *[]common.ActivityRecord
+}
+
+func (self testPendingActivityStorage) Record(action string, id common.ActivityID, destination string,
+	params map[string]interface{}, result map[string]interface{}, estatus string, mstatus string, timepoint uint64) error {
+	*self.pendings = append(*self.pendings, common.ActivityRecord{Action: action, ID: id, Result: result, MiningStatus: mstatus})
+	return nil
+}
+
+func (self testPendingActivityStorage) PendingSetrate(minedNonce uint64) (*common.ActivityRecord, uint64, error) {
+	var (
+		result   *common.ActivityRecord
+		maxNonce uint64
+		count    uint64
+	)
+	for i, act := range *self.pendings {
+		nonce, err := strconv.ParseUint(act.Result["nonce"].(string), 10, 64)
+		if err != nil {
+			return nil, 0, err
+		}
+		if nonce < minedNonce || nonce < maxNonce {
+			continue
+		}
+		if result == nil || nonce > maxNonce {
+			count = 0
+		}
+		result, maxNonce = &(*self.pendings)[i], nonce
+		count++
+	}
+	return result, count, nil
+}
+
+func TestPricingTransactionNonces(t *testing.T) {
+	var (
+		nonces    []uint64
+		gasPrices []*big.Int
+		pendings  []common.ActivityRecord
+	)
+	core := NewReserveCore(
+		testPricingBlockchain{nonces: &nonces, gasPrices: &gasPrices},
+		testPendingActivityStorage{pendings: &pendings},
+		ethereum.Address{},
+	)
+	token := common.NewToken("KNC", "0x1111111111111111111111111111111111111111", 18)
+	setRates := func() {
+		if _, err := core.SetRates([]common.Token{}, []*big.Int{}, []*big.Int{}, big.NewInt(1), []*big.Int{}, []string{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setStepFunction := func() {
+		if _, err := core.SetStepFunction(token, "qty", nil, nil, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	setRates()        // first tx of the operator takes the mined nonce
+	setRates()        // replaces the pending set rates
+	setStepFunction() // doesn't replace the pending set rates
+	setRates()        // doesn't replace the pending step function
+	setStepFunction()
+	expected := []uint64{5, 5, 6, 7, 8}
+	if !reflect.DeepEqual(nonces, expected) {
+		t.Errorf("expected nonces %v, got %v", expected, nonces)
+	}
+	if gasPrices[0].Cmp(common.GweiToWei(20)) != 0 || gasPrices[1].Cmp(gasPrices[0]) <= 0 {
+		t.Errorf("expected replacing set rates to increase gas price, got %v", gasPrices)
+	}
+	for _, i := range []int{2, 3, 4} {
+		if gasPrices[i].Cmp(common.GweiToWei(20)) != 0 {
+			t.Errorf("expected transaction %d to take the recommended gas price, got %s", i, gasPrices[i])
+		}
+	}
+}
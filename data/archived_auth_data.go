@@ -0,0 +1,249 @@
+package data
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/common/archive"
+)
+
+const (
+	expiredAuthDataFilePrefix = "ExpiredAuthData_at_"
+	// expiredAuthDataTimeLayout is the format of time.Time.String() used in exported file names.
+	expiredAuthDataTimeLayout = "2006-01-02 15:04:05 -0700 MST"
+
+	archivedAuthDataCacheDir      = "./exported/archived-auth-data"
+	maxCachedArchivedAuthDataFile = 4
+	// archivedAuthDataListTTL is how long the listing of archived files is reused,
+	// files are exported at most once per pruning.
+	archivedAuthDataListTTL = 10 * time.Minute
+)
+
+// expiredAuthDataFileName returns the name of the file auth data expired at timepoint is exported to.
+func expiredAuthDataFileName(timepoint uint64) string {
+	return expiredAuthDataFilePrefix + time.Unix(int64(timepoint/1000), 0).UTC().String()
+}
+
+// expiredAuthDataFileTime returns the timepoint an exported auth data file is created.
+func expiredAuthDataFileTime(fileName string) (uint64, bool) {
+	if !strings.HasPrefix(fileName, expiredAuthDataFilePrefix) {
+		return 0, false
+	}
+	t, err := time.Parse(expiredAuthDataTimeLayout, strings.TrimPrefix(fileName, expiredAuthDataFilePrefix))
+	if err != nil {
+		return 0, false
+	}
+	return common.TimeToTimepoint(t), true
+}
+
+// indexedAuthDataFile is a downloaded archived auth data file with the offset of
+// each record, sorted by timestamp.
+type indexedAuthDataFile struct {
+	name       string
+	path       string
+	timestamps []uint64
+	offsets    []int64
+}
+
+// latest returns the offset of the latest record not after timepoint.
+func (self *indexedAuthDataFile) latest(timepoint uint64) (uint64, int64, bool) {
+	i := sort.Search(len(self.timestamps), func(i int) bool { return self.timestamps[i] > timepoint })
+	if i == 0 {
+		return 0, 0, false
+	}
+	return self.timestamps[i-1], self.offsets[i-1], true
+}
+
+func (self *indexedAuthDataFile) read(offset int64) (common.AuthDataRecord, error) {
+	var record common.AuthDataRecord
+	file, err := os.Open(self.path)
+	if err != nil {
+		return record, err
+	}
+	defer func() {
+		if cErr := file.Close(); cErr != nil {
+			log.Printf("File close error: %s", cErr.Error())
+		}
+	}()
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		return record, err
+	}
+	line, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return record, err
+	}
+	err = json.Unmarshal(line, &record)
+	return record, err
+}
+
+func indexAuthDataFile(name, path string) (*indexedAuthDataFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cErr := file.Close(); cErr != nil {
+			log.Printf("File close error: %s", cErr.Error())
+		}
+	}()
+	result := &indexedAuthDataFile{name: name, path: path}
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		line, rErr := reader.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			record := struct{ Timestamp common.Timestamp }{}
+			if err = json.Unmarshal(line, &record); err != nil {
+				return nil, err
+			}
+			timestamp, err := strconv.ParseUint(string(record.Timestamp), 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			result.timestamps = append(result.timestamps, timestamp)
+			result.offsets = append(result.offsets, offset)
+		}
+		offset += int64(len(line))
+		if rErr == io.EOF {
+			break
+		}
+		if rErr != nil {
+			return nil, rErr
+		}
+	}
+	// records are exported in order of timestamp, binary search relies on it
+	if !sort.SliceIsSorted(result.timestamps, func(i, j int) bool { return result.timestamps[i] < result.timestamps[j] }) {
+		return nil, fmt.Errorf("records of archived auth data file %s are not sorted", name)
+	}
+	return result, nil
+}
+
+// exportedAuthDataFile is an archived file and the timepoint it contains records until.
+type exportedAuthDataFile struct {
+	name   string
+	cutoff uint64
+}
+
+// archivedAuthData serves auth data snapshots pruned from storage from the archive.
+// Archived files are downloaded on demand and only the most recently used ones are kept locally.
+type archivedAuthData struct {
+	mu        sync.Mutex
+	arch      archive.Archive
+	folder    string
+	retention uint64
+	cacheDir  string
+	maxFiles  int
+	// cached files, the most recently used is the last one
+	cached []*indexedAuthDataFile
+	// listing of archived files sorted by cutoff, it is refreshed after archivedAuthDataListTTL
+	listed   []exportedAuthDataFile
+	listedAt time.Time
+}
+
+func newArchivedAuthData(arch archive.Archive, folder string, retention uint64, cacheDir string, maxFiles int) *archivedAuthData {
+	return &archivedAuthData{
+		arch:      arch,
+		folder:    folder,
+		retention: retention,
+		cacheDir:  cacheDir,
+		maxFiles:  maxFiles,
+	}
+}
+
+// Archived returns true if auth data at timepoint is out of storage retention at now,
+// so it can only be found in the archive.
+func (self *archivedAuthData) Archived(timepoint, now uint64) bool {
+	return now > self.retention && timepoint < now-self.retention
+}
+
+// files returns the archived files sorted by cutoff, listing the archive only if
+// the previous listing is too old.
+func (self *archivedAuthData) files() ([]exportedAuthDataFile, error) {
+	if self.listed != nil && time.Since(self.listedAt) < archivedAuthDataListTTL {
+		return self.listed, nil
+	}
+	names, err := self.arch.ListFiles(self.arch.GetReserveDataBucketName(), self.folder)
+	if err != nil {
+		return nil, err
+	}
+	files := []exportedAuthDataFile{}
+	for _, name := range names {
+		if exportTime, ok := expiredAuthDataFileTime(name); ok && exportTime > self.retention {
+			files = append(files, exportedAuthDataFile{name, exportTime - self.retention})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].cutoff < files[j].cutoff })
+	self.listed, self.listedAt = files, time.Now()
+	return files, nil
+}
+
+// file returns the indexed file with given name, downloading it if it is not cached.
+func (self *archivedAuthData) file(name string) (*indexedAuthDataFile, error) {
+	for i, f := range self.cached {
+		if f.name == name {
+			self.cached = append(append(self.cached[:i], self.cached[i+1:]...), f)
+			return f, nil
+		}
+	}
+	if err := os.MkdirAll(self.cacheDir, 0730); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(self.cacheDir, name)
+	if err := self.arch.DownloadFile(self.arch.GetReserveDataBucketName(), self.folder, name, path); err != nil {
+		return nil, err
+	}
+	f, err := indexAuthDataFile(name, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(self.cached) >= self.maxFiles {
+		evicted := self.cached[0]
+		self.cached = self.cached[1:]
+		if rErr := os.Remove(evicted.path); rErr != nil {
+			log.Printf("Removing cached archived auth data file %s failed: %s", evicted.path, rErr)
+		}
+	}
+	self.cached = append(self.cached, f)
+	return f, nil
+}
+
+// GetAuthData returns the latest archived snapshot not after timepoint and its version.
+func (self *archivedAuthData) GetAuthData(timepoint uint64) (common.AuthDataSnapshot, common.Version, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	files, err := self.files()
+	if err != nil {
+		return common.AuthDataSnapshot{}, 0, err
+	}
+
+	// a file contains records after the cutoff of the previous file until its cutoff,
+	// so the latest record not after timepoint is in the first file whose cutoff is not
+	// before timepoint, or is the last record of the file before it.
+	i := sort.Search(len(files), func(i int) bool { return files[i].cutoff >= timepoint })
+	if i == len(files) {
+		i = len(files) - 1
+	}
+	for ; i >= 0; i-- {
+		f, err := self.file(files[i].name)
+		if err != nil {
+			return common.AuthDataSnapshot{}, 0, err
+		}
+		if ts, offset, ok := f.latest(timepoint); ok {
+			record, err := f.read(offset)
+			return record.Data, common.Version(ts), err
+		}
+	}
+	return common.AuthDataSnapshot{}, 0, fmt.Errorf("there is no archived auth data at %d", timepoint)
+}
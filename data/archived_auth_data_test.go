@@ -0,0 +1,125 @@
+package data
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/common/archive"
+)
+
+func TestArchivedAuthData(t *testing.T) {
+	const (
+		day       uint64 = 86400000
+		retention        = 10 * day
+		folder           = "expired-auth-data/"
+	)
+	tmpDir, err := ioutil.TempDir("", "archived_auth_data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}()
+	arch, err := archive.NewArchive(archive.Config{
+		AWSConfig: archive.AWSConfig{ExpiredReserveDataBucketName: "reserve-data"},
+		Type:      archive.LocalArchiveType,
+		LocalPath: filepath.Join(tmpDir, "archive"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// exports at day 20, 21 and 22 contain records until day 10, 11 and 12,
+	// there is a record every 6 hours
+	start := 9 * day
+	for export := 20 * day; export <= 22*day; export += day {
+		fileName := filepath.Join(tmpDir, expiredAuthDataFileName(export))
+		content := []byte{}
+		for ; start <= export-retention; start += day / 4 {
+			record := common.NewAuthDataRecord(
+				common.Timestamp(strconv.FormatUint(start, 10)),
+				common.AuthDataSnapshot{Block: start},
+			)
+			line, mErr := json.Marshal(record)
+			if mErr != nil {
+				t.Fatal(mErr)
+			}
+			content = append(append(content, line...), '\n')
+		}
+		if err = ioutil.WriteFile(fileName, content, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err = arch.UploadFile(arch.GetReserveDataBucketName(), folder, fileName); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archived := newArchivedAuthData(arch, folder, retention, filepath.Join(tmpDir, "cache"), 1)
+	var tests = []struct {
+		timepoint uint64
+		expected  uint64
+	}{
+		// last record of the previous file
+		{timepoint: 10*day + 1, expected: 10 * day},
+		{timepoint: 11*day - 1, expected: 10*day + 3*day/4},
+		{timepoint: 11*day + day/2, expected: 11*day + day/2},
+		{timepoint: 30 * day, expected: 12 * day},
+	}
+	for _, tc := range tests {
+		data, version, err := archived.GetAuthData(tc.timepoint)
+		if err != nil {
+			t.Fatalf("unexpected error at %d: %s", tc.timepoint, err)
+		}
+		if uint64(version) != tc.expected || data.Block != tc.expected {
+			t.Errorf("expected auth data at %d, got version %d block %d", tc.expected, version, data.Block)
+		}
+	}
+	if _, _, err = archived.GetAuthData(9*day - 1); err == nil {
+		t.Error("expected error for timepoint before all archived data")
+	}
+	files, err := ioutil.ReadDir(filepath.Join(tmpDir, "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("expected 1 cached file, got %d", len(files))
+	}
+
+	// the listing of archived files is reused until it expires
+	fileName := filepath.Join(tmpDir, expiredAuthDataFileName(23*day))
+	if err = ioutil.WriteFile(fileName, []byte{}, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = arch.UploadFile(arch.GetReserveDataBucketName(), folder, fileName); err != nil {
+		t.Fatal(err)
+	}
+	if listed, lErr := archived.files(); lErr != nil || len(listed) != 3 {
+		t.Errorf("expected cached listing of 3 files, got %v (%v)", listed, lErr)
+	}
+	archived.listedAt = time.Now().Add(-archivedAuthDataListTTL)
+	if listed, lErr := archived.files(); lErr != nil || len(listed) != 4 {
+		t.Errorf("expected listing of 4 files once cache expires, got %v (%v)", listed, lErr)
+	}
+
+	for _, tc := range []struct {
+		timepoint uint64
+		now       uint64
+		expected  bool
+	}{
+		{timepoint: 10 * day, now: 20*day + 1, expected: true},
+		{timepoint: 10 * day, now: 20 * day, expected: false},
+		{timepoint: 0, now: retention, expected: false},
+	} {
+		if archived.Archived(tc.timepoint, tc.now) != tc.expected {
+			t.Errorf("expected archived of %d at %d to be %t", tc.timepoint, tc.now, tc.expected)
+		}
+	}
+}
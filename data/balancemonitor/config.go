@@ -0,0 +1,44 @@
+package balancemonitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// DefaultInterval is the interval between balance checks if it is not configured.
+const DefaultInterval = time.Minute
+
+// Config is the configuration of balance monitor, read from the secret config file.
+type Config struct {
+	// Interval is the duration between balance checks, e.g "1m".
+	Interval string `json:"balance_monitor_interval"`
+}
+
+// GetConfigFromFile reads the balance monitor configuration from a JSON file.
+func GetConfigFromFile(path string) (Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	result := Config{}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// CheckInterval parses the configured interval, it is DefaultInterval if missing.
+func (self Config) CheckInterval() (time.Duration, error) {
+	if self.Interval == "" {
+		return DefaultInterval, nil
+	}
+	result, err := time.ParseDuration(self.Interval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid balance_monitor_interval: %s", err)
+	}
+	if result <= 0 {
+		return 0, errors.New("balance_monitor_interval must be positive")
+	}
+	return result, nil
+}
@@ -0,0 +1,30 @@
+package balancemonitor
+
+import (
+	"math/big"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	ethereum "github.com/ethereum/go-ethereum/common"
+)
+
+// Blockchain contains the methods balance monitor needs to query operator balances
+// and token allowances.
+type Blockchain interface {
+	OperatorAddresses() map[string]ethereum.Address
+	GetEthBalance(addr ethereum.Address) (*big.Int, error)
+	GetAddresses() *common.Addresses
+	GetAllowance(token, owner, spender ethereum.Address) (*big.Int, error)
+}
+
+// Storage is the interface that wraps all database operations of balance monitor.
+type Storage interface {
+	GetBalanceThresholds() (common.BalanceThresholds, error)
+	GetBalanceStatus() (common.BalanceStatus, error)
+	StoreBalanceStatus(status common.BalanceStatus) error
+
+	CurrentAuthDataVersion(timepoint uint64) (common.Version, error)
+	GetAuthData(common.Version) (common.AuthDataSnapshot, error)
+	GetGasSpend(fromTime, toTime uint64) ([]common.GasSpendRecord, error)
+
+	UpdateExchangeNotification(exchange, action, tokenPair string, fromTime, toTime uint64, isWarning bool, msg string) error
+}
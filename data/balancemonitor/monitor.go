@@ -0,0 +1,307 @@
+package balancemonitor
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	ethereum "github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// spendWindow is the period of recent gas spend used to estimate days of gas left.
+	spendWindow uint64 = 7 * 86400000
+	day         uint64 = 86400000
+
+	// notifications are stored as exchange notifications of these pseudo exchanges
+	operatorNotification  = "operator"
+	reserveNotification   = "reserve"
+	allowanceNotification = "allowance"
+	lowBalanceAction      = "lowbalance"
+	lowAllowanceAction    = "lowallowance"
+)
+
+// BalanceMonitor periodically checks the ETH balances of operators, the
+// token balances of reserve and the token allowances against the configured thresholds.
+type BalanceMonitor struct {
+	storage    Storage
+	blockchain Blockchain
+	interval   time.Duration
+}
+
+// NewBalanceMonitor creates a new BalanceMonitor instance that checks balances every interval.
+func NewBalanceMonitor(storage Storage, blockchain Blockchain, interval time.Duration) *BalanceMonitor {
+	return &BalanceMonitor{
+		storage:    storage,
+		blockchain: blockchain,
+		interval:   interval,
+	}
+}
+
+// Run starts checking balances in background.
+func (self *BalanceMonitor) Run() {
+	go func() {
+		ticker := time.NewTicker(self.interval)
+		for t := range ticker.C {
+			if _, err := self.Check(common.TimeToTimepoint(t)); err != nil {
+				log.Printf("Balance monitor: checking balances failed: %s", err)
+			}
+		}
+	}()
+}
+
+// Check compares the current balances and allowances to thresholds, stores the result
+// and raises notifications for balances and allowances that become low or recover.
+func (self *BalanceMonitor) Check(timepoint uint64) (common.BalanceStatus, error) {
+	result := common.BalanceStatus{
+		Timestamp:  timepoint,
+		Operators:  []common.OperatorBalanceStatus{},
+		Tokens:     []common.TokenBalanceStatus{},
+		Allowances: []common.AllowanceStatus{},
+	}
+	thresholds, err := self.storage.GetBalanceThresholds()
+	if err != nil {
+		return result, err
+	}
+	// previous status is used to detect changes, it does not exist at the first check
+	previous, err := self.storage.GetBalanceStatus()
+	if err != nil {
+		log.Printf("Balance monitor: previous balance status is not available: %s", err)
+	}
+	previousOperators := map[string]common.OperatorBalanceStatus{}
+	for _, status := range previous.Operators {
+		previousOperators[status.Name] = status
+	}
+	previousTokens := map[string]common.TokenBalanceStatus{}
+	for _, status := range previous.Tokens {
+		previousTokens[status.Token] = status
+	}
+	previousAllowances := map[string]common.AllowanceStatus{}
+	for _, status := range previous.Allowances {
+		previousAllowances[status.Key()] = status
+	}
+
+	dailySpend, err := self.dailySpend(timepoint)
+	if err != nil {
+		log.Printf("Balance monitor: getting gas spend failed, days left are not estimated: %s", err)
+	}
+	operators := self.blockchain.OperatorAddresses()
+	names := []string{}
+	for name := range operators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		status := self.checkOperator(name, operators[name], thresholds, dailySpend, previousOperators[name], timepoint)
+		result.Operators = append(result.Operators, status)
+	}
+
+	tokenIDs := []string{}
+	for tokenID := range thresholds.Tokens {
+		tokenIDs = append(tokenIDs, tokenID)
+	}
+	sort.Strings(tokenIDs)
+	if len(tokenIDs) > 0 {
+		reserveBalances, err := self.reserveBalances(timepoint)
+		for _, tokenID := range tokenIDs {
+			status := self.checkToken(tokenID, thresholds.Tokens[tokenID], reserveBalances, err, previousTokens[tokenID], timepoint)
+			result.Tokens = append(result.Tokens, status)
+		}
+	}
+
+	if len(thresholds.Allowances) > 0 {
+		addresses := self.blockchain.GetAddresses()
+		for _, threshold := range thresholds.Allowances {
+			status := self.checkAllowance(threshold, addresses, previousAllowances[threshold.Key()], timepoint)
+			result.Allowances = append(result.Allowances, status)
+		}
+	}
+	return result, self.storage.StoreBalanceStatus(result)
+}
+
+// dailySpend returns the average ETH spent per day by each operator address in the spend window.
+func (self *BalanceMonitor) dailySpend(timepoint uint64) (map[string]float64, error) {
+	result := map[string]float64{}
+	fromTime := uint64(0)
+	if timepoint > spendWindow {
+		fromTime = timepoint - spendWindow
+	}
+	records, err := self.storage.GetGasSpend(fromTime, timepoint)
+	if err != nil || len(records) == 0 {
+		return result, err
+	}
+	// when we have less history than the window, average over what we have but at least a day
+	period := timepoint - records[0].Timestamp
+	if period < day {
+		period = day
+	}
+	for _, record := range records {
+		result[record.Operator] += record.Fee
+	}
+	for operator, fee := range result {
+		result[operator] = fee * float64(day) / float64(period)
+	}
+	return result, nil
+}
+
+func (self *BalanceMonitor) reserveBalances(timepoint uint64) (map[string]common.BalanceEntry, error) {
+	version, err := self.storage.CurrentAuthDataVersion(timepoint)
+	if err != nil {
+		return nil, err
+	}
+	authData, err := self.storage.GetAuthData(version)
+	if err != nil {
+		return nil, err
+	}
+	return authData.ReserveBalances, nil
+}
+
+func (self *BalanceMonitor) checkOperator(
+	name string, addr ethereum.Address,
+	thresholds common.BalanceThresholds,
+	dailySpend map[string]float64,
+	previous common.OperatorBalanceStatus,
+	timepoint uint64) common.OperatorBalanceStatus {
+	status := common.OperatorBalanceStatus{
+		Name:      name,
+		Address:   common.AddrToString(addr),
+		Threshold: thresholds.Operators[name],
+	}
+	balance, err := self.blockchain.GetEthBalance(addr)
+	if err != nil {
+		// keep the previous state as we don't know the balance
+		status.Error = err.Error()
+		status.Low, status.LowSince = previous.Low, previous.LowSince
+		return status
+	}
+	status.Balance = common.BigToFloat(balance, 18)
+	status.DailySpend = dailySpend[status.Address]
+	if status.DailySpend > 0 {
+		daysLeft := status.Balance / status.DailySpend
+		status.DaysLeft = &daysLeft
+	}
+
+	var reason string
+	switch {
+	case status.Balance < status.Threshold:
+		reason = fmt.Sprintf("operator %s (%s) has %f ETH, lower than threshold %f ETH", name, status.Address, status.Balance, status.Threshold)
+	case status.DaysLeft != nil && *status.DaysLeft < thresholds.MinDaysLeft:
+		reason = fmt.Sprintf("operator %s (%s) has %f ETH, enough for %.2f days of gas only", name, status.Address, status.Balance, *status.DaysLeft)
+	}
+	status.Low = reason != ""
+	status.LowSince = self.notify(operatorNotification, lowBalanceAction, status.Address, previous.Low, previous.LowSince, reason, timepoint)
+	return status
+}
+
+func (self *BalanceMonitor) checkToken(
+	tokenID string, threshold float64,
+	balances map[string]common.BalanceEntry, balancesErr error,
+	previous common.TokenBalanceStatus,
+	timepoint uint64) common.TokenBalanceStatus {
+	status := common.TokenBalanceStatus{
+		Token:     tokenID,
+		Threshold: threshold,
+	}
+	token, err := common.GetInternalToken(tokenID)
+	if err == nil {
+		err = balancesErr
+	}
+	if err == nil {
+		entry, ok := balances[tokenID]
+		switch {
+		case !ok:
+			err = fmt.Errorf("reserve balance of %s is not available", tokenID)
+		case !entry.Valid:
+			err = fmt.Errorf("reserve balance of %s is invalid: %s", tokenID, entry.Error)
+		default:
+			status.Balance = entry.ToBalanceResponse(token.Decimal).Balance
+		}
+	}
+	if err != nil {
+		status.Error = err.Error()
+		status.Low, status.LowSince = previous.Low, previous.LowSince
+		return status
+	}
+
+	var reason string
+	if status.Balance < threshold {
+		reason = fmt.Sprintf("reserve has %f %s, lower than threshold %f", status.Balance, tokenID, threshold)
+	}
+	status.Low = reason != ""
+	status.LowSince = self.notify(reserveNotification, lowBalanceAction, tokenID, previous.Low, previous.LowSince, reason, timepoint)
+	return status
+}
+
+// accountAddress returns the address of an account named in allowance thresholds.
+func accountAddress(account string, addresses *common.Addresses) ethereum.Address {
+	switch account {
+	case common.ReserveAccount:
+		return addresses.ReserveAddress
+	case common.WrapperAccount:
+		return addresses.WrapperAddress
+	case common.NetworkAccount:
+		return addresses.NetworkAddress
+	}
+	return ethereum.HexToAddress(account)
+}
+
+func (self *BalanceMonitor) checkAllowance(
+	threshold common.AllowanceThreshold,
+	addresses *common.Addresses,
+	previous common.AllowanceStatus,
+	timepoint uint64) common.AllowanceStatus {
+	owner := accountAddress(threshold.Owner, addresses)
+	spender := accountAddress(threshold.Spender, addresses)
+	status := common.AllowanceStatus{
+		AllowanceThreshold: threshold,
+		OwnerAddress:       common.AddrToString(owner),
+		SpenderAddress:     common.AddrToString(spender),
+	}
+	token, err := common.GetInternalToken(threshold.Token)
+	if err == nil {
+		var allowance *big.Int
+		if allowance, err = self.blockchain.GetAllowance(ethereum.HexToAddress(token.Address), owner, spender); err == nil {
+			status.Allowance = common.BigToFloat(allowance, token.Decimal)
+		}
+	}
+	if err != nil {
+		status.Error = err.Error()
+		status.Low, status.LowSince = previous.Low, previous.LowSince
+		return status
+	}
+
+	var reason string
+	if status.Allowance < threshold.Threshold {
+		reason = fmt.Sprintf("%s (%s) allows %s (%s) to transfer %f %s, lower than threshold %f",
+			threshold.Owner, status.OwnerAddress, threshold.Spender, status.SpenderAddress,
+			status.Allowance, threshold.Token, threshold.Threshold)
+	}
+	status.Low = reason != ""
+	status.LowSince = self.notify(allowanceNotification, lowAllowanceAction, threshold.Key(), previous.Low, previous.LowSince, reason, timepoint)
+	return status
+}
+
+// notify raises a warning notification when a balance or allowance becomes low and clears it
+// when it recovers. It returns the timepoint since it is low, 0 if it is not.
+func (self *BalanceMonitor) notify(exchange, action, key string, wasLow bool, lowSince uint64, reason string, timepoint uint64) uint64 {
+	isLow := reason != ""
+	var err error
+	switch {
+	case isLow && !wasLow:
+		lowSince = timepoint
+		log.Printf("Balance monitor: %s", reason)
+		err = self.storage.UpdateExchangeNotification(exchange, action, key, timepoint, 0, true, reason)
+	case !isLow && wasLow:
+		err = self.storage.UpdateExchangeNotification(exchange, action, key, lowSince, timepoint, false, "recovered")
+		lowSince = 0
+	case !isLow:
+		lowSince = 0
+	}
+	if err != nil {
+		log.Printf("Balance monitor: updating notification of %s %s failed: %s", exchange, key, err)
+	}
+	return lowSince
+}
@@ -0,0 +1,180 @@
+package balancemonitor
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/data/storage"
+	ethereum "github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	testOperator = "0x1111111111111111111111111111111111111111"
+	testReserve  = "0x3333333333333333333333333333333333333333"
+	testWrapper  = "0x4444444444444444444444444444444444444444"
+)
+
+type testBlockchain struct {
+	balance   *big.Int
+	allowance *big.Int
+}
+
+func (self *testBlockchain) OperatorAddresses() map[string]ethereum.Address {
+	return map[string]ethereum.Address{"pricing": ethereum.HexToAddress(testOperator)}
+}
+
+func (self *testBlockchain) GetEthBalance(addr ethereum.Address) (*big.Int, error) {
+	return self.balance, nil
+}
+
+func (self *testBlockchain) GetAddresses() *common.Addresses {
+	return &common.Addresses{
+		ReserveAddress: ethereum.HexToAddress(testReserve),
+		WrapperAddress: ethereum.HexToAddress(testWrapper),
+	}
+}
+
+func (self *testBlockchain) GetAllowance(token, owner, spender ethereum.Address) (*big.Int, error) {
+	if owner != ethereum.HexToAddress(testReserve) || spender != ethereum.HexToAddress(testWrapper) {
+		return big.NewInt(0), nil
+	}
+	return self.allowance, nil
+}
+
+func TestBalanceMonitorCheck(t *testing.T) {
+	const timepoint uint64 = 1000 * 86400000
+
+	common.RegisterInternalActiveToken(common.NewToken("KNC", "0x2222222222222222222222222222222222222222", 18))
+
+	tmpDir, err := ioutil.TempDir("", "balance_monitor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}()
+	st, err := storage.NewBoltStorage(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = st.StoreBalanceThresholds(common.BalanceThresholds{
+		Operators: map[string]float64{"pricing": 1},
+		Tokens:    map[string]float64{"KNC": 100},
+		Allowances: []common.AllowanceThreshold{
+			{Token: "KNC", Owner: common.ReserveAccount, Spender: common.WrapperAccount, Threshold: 1000},
+		},
+		MinDaysLeft: 3,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	knc := big.NewInt(0).Mul(big.NewInt(200), common.EthToWei(1))
+	if err = st.StoreAuthSnapshot(&common.AuthDataSnapshot{
+		Valid: true,
+		ReserveBalances: map[string]common.BalanceEntry{
+			"KNC": {Valid: true, Balance: common.RawBalance(*knc)},
+		},
+	}, timepoint-1); err != nil {
+		t.Fatal(err)
+	}
+	// 0.5 ETH spent per day over the last 2 days
+	for i, ts := range []uint64{timepoint - 2*86400000, timepoint - 86400000} {
+		record := common.GasSpendRecord{
+			TxHash:    ethereum.BigToHash(big.NewInt(int64(i + 1))).Hex(),
+			Operator:  testOperator,
+			Fee:       0.5,
+			Timestamp: ts,
+		}
+		if err = st.StoreGasSpend(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var tests = []struct {
+		msg                  string
+		balance              *big.Int
+		allowance            *big.Int
+		expectedLow          bool
+		expectedWarning      bool
+		expectedLowAllowance bool
+	}{
+		{msg: "balance lower than threshold", balance: big.NewInt(500000000000000000), allowance: common.EthToWei(1000),
+			expectedLow: true, expectedWarning: true},
+		{msg: "balance still low", balance: big.NewInt(600000000000000000), allowance: common.EthToWei(999),
+			expectedLow: true, expectedWarning: true, expectedLowAllowance: true},
+		{msg: "not enough days of gas left", balance: common.EthToWei(1), allowance: common.EthToWei(10),
+			expectedLow: true, expectedWarning: true, expectedLowAllowance: true},
+		{msg: "balance recovered", balance: common.EthToWei(2), allowance: common.EthToWei(2000),
+			expectedLow: false, expectedWarning: false},
+	}
+	for i, tc := range tests {
+		monitor := NewBalanceMonitor(st, &testBlockchain{balance: tc.balance, allowance: tc.allowance}, 0)
+		status, err := monitor.Check(timepoint + uint64(i))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tc.msg, err)
+		}
+		if len(status.Operators) != 1 || status.Operators[0].Low != tc.expectedLow {
+			t.Fatalf("%s: unexpected operators status %+v", tc.msg, status.Operators)
+		}
+		if daysLeft := status.Operators[0].DaysLeft; daysLeft == nil {
+			t.Errorf("%s: expected days left to be estimated", tc.msg)
+		}
+		if tc.expectedLow && status.Operators[0].LowSince != timepoint {
+			t.Errorf("%s: expected low since %d, got %d", tc.msg, timepoint, status.Operators[0].LowSince)
+		}
+		if len(status.Tokens) != 1 || status.Tokens[0].Low || status.Tokens[0].Balance != 200 {
+			t.Errorf("%s: unexpected tokens status %+v", tc.msg, status.Tokens)
+		}
+
+		notifications, err := st.GetExchangeNotifications()
+		if err != nil {
+			t.Fatal(err)
+		}
+		noti, ok := notifications[operatorNotification][lowBalanceAction][testOperator]
+		if !ok || noti.IsWarning != tc.expectedWarning {
+			t.Errorf("%s: unexpected notification %+v", tc.msg, noti)
+		}
+
+		if len(status.Allowances) != 1 || status.Allowances[0].Low != tc.expectedLowAllowance ||
+			status.Allowances[0].OwnerAddress != testReserve || status.Allowances[0].SpenderAddress != testWrapper {
+			t.Fatalf("%s: unexpected allowances status %+v", tc.msg, status.Allowances)
+		}
+		noti, ok = notifications[allowanceNotification][lowAllowanceAction][status.Allowances[0].Key()]
+		switch {
+		case tc.expectedLowAllowance && (!ok || !noti.IsWarning):
+			t.Errorf("%s: expected allowance warning, got %+v", tc.msg, noti)
+		case !tc.expectedLowAllowance && ok && noti.IsWarning:
+			t.Errorf("%s: unexpected allowance warning %+v", tc.msg, noti)
+		}
+	}
+}
+
+func TestConfigCheckInterval(t *testing.T) {
+	var tests = []struct {
+		interval string
+		expected time.Duration
+		valid    bool
+	}{
+		{"", DefaultInterval, true},
+		{"5m", 5 * time.Minute, true},
+		{"0s", 0, false},
+		{"one minute", 0, false},
+	}
+	for _, tc := range tests {
+		result, err := Config{Interval: tc.interval}.CheckInterval()
+		if tc.valid != (err == nil) {
+			t.Errorf("interval %q: expected valid %v, got error %v", tc.interval, tc.valid, err)
+			continue
+		}
+		if result != tc.expected {
+			t.Errorf("interval %q: expected %s, got %s", tc.interval, tc.expected, result)
+		}
+	}
+}
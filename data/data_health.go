@@ -0,0 +1,56 @@
+package data
+
+import (
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+// GetDataHealth returns the age of the latest prices, auth data, rates and
+// gold snapshots, and the fetch health of every source at timepoint.
+func (self ReserveData) GetDataHealth(timepoint uint64) common.DataHealth {
+	limits := self.fetcher.StaleLimits()
+	snapshots := map[string]common.SnapshotHealth{}
+
+	version, err := self.storage.CurrentPriceVersion(timepoint)
+	snapshots[common.OrderbookDataKind] = newSnapshotHealth(timepoint, version, limits.Limit(common.OrderbookDataKind), err)
+
+	version, err = self.storage.CurrentAuthDataVersion(timepoint)
+	authHealth := newSnapshotHealth(timepoint, version, limits.Limit(common.AuthDataKind), err)
+	if err == nil {
+		authData, aErr := self.storage.GetAuthData(version)
+		if aErr != nil {
+			authHealth = newSnapshotHealth(timepoint, version, authHealth.StaleLimit, aErr)
+		} else if !authData.Valid {
+			authHealth.Valid = false
+			authHealth.Error = authData.Error
+		}
+	}
+	snapshots[common.AuthDataKind] = authHealth
+
+	version, err = self.storage.CurrentRateVersion(timepoint)
+	snapshots[common.RateDataKind] = newSnapshotHealth(timepoint, version, limits.Limit(common.RateDataKind), err)
+
+	version, err = self.globalStorage.CurrentGoldInfoVersion(timepoint)
+	snapshots[common.GoldDataKind] = newSnapshotHealth(timepoint, version, limits.Limit(common.GoldDataKind), err)
+
+	return common.NewDataHealth(timepoint, snapshots, self.fetcher.SourceHealth(timepoint))
+}
+
+// newSnapshotHealth evaluates a snapshot version, which is the timepoint the
+// snapshot is stored at, err is the error of looking it up.
+func newSnapshotHealth(timepoint uint64, version common.Version, staleLimit uint64, err error) common.SnapshotHealth {
+	result := common.SnapshotHealth{
+		Version:    version,
+		StaleLimit: staleLimit,
+	}
+	if err != nil {
+		result.Error = err.Error()
+		result.Stale = true
+		return result
+	}
+	result.Valid = true
+	if timepoint > uint64(version) {
+		result.Age = timepoint - uint64(version)
+	}
+	result.Stale = result.Age > staleLimit
+	return result
+}
@@ -7,14 +7,22 @@ import (
 // StorageControllerRunner is the controller interface of data pruner jobs.
 type StorageControllerRunner interface {
 	GetAuthBucketTicker() <-chan time.Time
+	GetPriceHistoryTicker() <-chan time.Time
+	GetAuditLogTicker() <-chan time.Time
 	Start() error
 	Stop() error
 }
 
 type ControllerTickerRunner struct {
-	authDuration time.Duration
-	authClock    *time.Ticker
-	signal       chan bool
+	authDuration         time.Duration
+	authClock            *time.Ticker
+	priceHistoryDuration time.Duration
+	priceHistoryClock    *time.Ticker
+	auditLogDuration     time.Duration
+	auditLogClock        *time.Ticker
+	signal               chan bool
+	priceHistorySignal   chan bool
+	auditLogSignal       chan bool
 }
 
 func (self *ControllerTickerRunner) GetAuthBucketTicker() <-chan time.Time {
@@ -24,22 +32,48 @@ func (self *ControllerTickerRunner) GetAuthBucketTicker() <-chan time.Time {
 	return self.authClock.C
 }
 
+func (self *ControllerTickerRunner) GetPriceHistoryTicker() <-chan time.Time {
+	if self.priceHistoryClock == nil {
+		<-self.priceHistorySignal
+	}
+	return self.priceHistoryClock.C
+}
+
+func (self *ControllerTickerRunner) GetAuditLogTicker() <-chan time.Time {
+	if self.auditLogClock == nil {
+		<-self.auditLogSignal
+	}
+	return self.auditLogClock.C
+}
+
 func (self *ControllerTickerRunner) Start() error {
 	self.authClock = time.NewTicker(self.authDuration)
 	self.signal <- true
+	self.priceHistoryClock = time.NewTicker(self.priceHistoryDuration)
+	self.priceHistorySignal <- true
+	self.auditLogClock = time.NewTicker(self.auditLogDuration)
+	self.auditLogSignal <- true
 	return nil
 }
 
 func (self *ControllerTickerRunner) Stop() error {
 	self.authClock.Stop()
+	self.priceHistoryClock.Stop()
+	self.auditLogClock.Stop()
 	return nil
 }
 
 func NewStorageControllerTickerRunner(
-	authDuration time.Duration) *ControllerTickerRunner {
+	authDuration, priceHistoryDuration, auditLogDuration time.Duration) *ControllerTickerRunner {
 	return &ControllerTickerRunner{
 		authDuration,
 		nil,
+		priceHistoryDuration,
+		nil,
+		auditLogDuration,
+		nil,
+		make(chan bool, 1),
+		make(chan bool, 1),
 		make(chan bool, 1),
 	}
 }
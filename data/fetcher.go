@@ -1,7 +1,16 @@
 package data
 
+import (
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
 // Fetcher is the common interface of a fetcher service.
 type Fetcher interface {
 	Run() error
 	Stop() error
+	// SourceHealth returns fetch duration, success rate and data age of every
+	// source at timepoint.
+	SourceHealth(timepoint uint64) []common.SourceHealth
+	// StaleLimits returns the maximum age of each kind of fetched data.
+	StaleLimits() common.StaleLimits
 }
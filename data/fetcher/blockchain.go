@@ -11,6 +11,9 @@ type Blockchain interface {
 	// fetch current raw rates at specific block
 	FetchRates(atBlock uint64, currentBlock uint64) (common.AllRateEntry, error)
 	TxStatus(tx ethereum.Hash) (string, uint64, error)
+	// TxCost returns the gas cost of a mined transaction
+	TxCost(tx ethereum.Hash) (common.TxCost, error)
+	GetEthRate(timepoint uint64) float64
 	CurrentBlock() (uint64, error)
 	SetRateMinedNonce() (uint64, error)
 }
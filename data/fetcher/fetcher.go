@@ -1,12 +1,15 @@
 package fetcher
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"strconv"
 	"sync"
 	"time"
 
 	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/common/event"
 	ethereum "github.com/ethereum/go-ethereum/common"
 )
 
@@ -21,6 +24,10 @@ type Fetcher struct {
 	currentBlock           uint64
 	currentBlockUpdateTime uint64
 	simulationMode         bool
+	health                 *HealthTracker
+	validator              *OrderbookValidator
+	publisher              event.Publisher
+	gasSpends              chan common.PendingGasSpend
 }
 
 func NewFetcher(
@@ -39,9 +46,46 @@ func NewFetcher(
 		runner:         runner,
 		rmaddr:         address,
 		simulationMode: simulationMode,
+		health:         NewHealthTracker(common.DefaultStaleLimits()),
+		validator:      NewOrderbookValidator(OrderbookValidationConfig{}),
+		gasSpends:      make(chan common.PendingGasSpend, gasSpendQueueSize),
 	}
 }
 
+// SetOrderbookValidator sets the validator checking order books before they are stored.
+func (self *Fetcher) SetOrderbookValidator(validator *OrderbookValidator) {
+	self.validator = validator
+}
+
+// SetEventPublisher sets the publisher of events of stored prices, auth data,
+// rates and activities.
+func (self *Fetcher) SetEventPublisher(publisher event.Publisher) {
+	self.publisher = publisher
+}
+
+func (self *Fetcher) publish(topic string, data interface{}) {
+	if self.publisher != nil {
+		self.publisher.Publish(topic, data)
+	}
+}
+
+// SetStaleLimits sets the maximum age of each kind of fetched data before it
+// is reported as stale.
+func (self *Fetcher) SetStaleLimits(limits common.StaleLimits) {
+	self.health.SetStaleLimits(limits)
+}
+
+// StaleLimits returns the maximum age of each kind of fetched data.
+func (self *Fetcher) StaleLimits() common.StaleLimits {
+	return self.health.StaleLimits()
+}
+
+// SourceHealth returns fetch duration, success rate and data age of every
+// source at timepoint.
+func (self *Fetcher) SourceHealth(timepoint uint64) []common.SourceHealth {
+	return self.health.Health(timepoint)
+}
+
 func (self *Fetcher) SetBlockchain(blockchain Blockchain) {
 	self.blockchain = blockchain
 	self.FetchCurrentBlock(common.GetTimepoint())
@@ -78,9 +122,14 @@ func (self *Fetcher) Run() error {
 	}
 	go self.RunOrderbookFetcher()
 	go self.RunAuthDataFetcher()
-	go self.RunRateFetcher()
-	go self.RunBlockFetcher()
+	if headRunner, ok := self.runner.(HeadRunner); ok {
+		go self.RunNewHeadFetcher(headRunner)
+	} else {
+		go self.RunRateFetcher()
+		go self.RunBlockFetcher()
+	}
 	go self.RunGlobalDataFetcher()
+	go self.RunGasSpendRecorder()
 	log.Printf("Fetcher runner is running...")
 	return nil
 }
@@ -97,9 +146,11 @@ func (self *Fetcher) RunGlobalDataFetcher() {
 }
 
 func (self *Fetcher) FetchGlobalData(timepoint uint64) {
-	data, _ := self.theworld.GetGoldInfo()
+	start := time.Now()
+	data, err := self.theworld.GetGoldInfo()
+	self.health.Record(GoldSource, common.GoldDataKind, start, 0, err)
 	data.Timestamp = common.GetTimepoint()
-	err := self.globalStorage.StoreGoldInfo(data)
+	err = self.globalStorage.StoreGoldInfo(data)
 	if err != nil {
 		log.Printf("Storing gold info failed: %s", err.Error())
 	}
@@ -127,10 +178,6 @@ func (self *Fetcher) RunRateFetcher() {
 }
 
 func (self *Fetcher) FetchRate(timepoint uint64) {
-	var (
-		err  error
-		data common.AllRateEntry
-	)
 	// only fetch rates 5s after the block number is updated
 	if !self.simulationMode && self.currentBlockUpdateTime-timepoint <= 5000 {
 		return
@@ -141,8 +188,40 @@ func (self *Fetcher) FetchRate(timepoint uint64) {
 	if self.simulationMode {
 		atBlock = 0
 	}
+	self.fetchRateAtBlock(atBlock, self.currentBlock, timepoint)
+}
+
+// RunNewHeadFetcher fetches the current block and rates on every new head of
+// the head runner.
+func (self *Fetcher) RunNewHeadFetcher(runner HeadRunner) {
+	for {
+		log.Printf("waiting for signal from new head channel")
+		head := <-runner.GetNewHeadTicker()
+		log.Printf("got new head %d in new head channel with timestamp %d", head.Block, common.TimeToTimepoint(head.Time))
+		self.FetchNewHead(head.Block, common.TimeToTimepoint(head.Time))
+	}
+}
+
+// FetchNewHead updates the current block to a new head and fetches rates at
+// that block, which is known to be mined, so there is no need to wait for
+// the block fetcher.
+func (self *Fetcher) FetchNewHead(block, timepoint uint64) {
+	self.currentBlockUpdateTime = common.GetTimepoint()
+	self.currentBlock = block
+	self.health.Record(BlockchainSource, common.BlockDataKind, time.Now(), timepoint, nil)
+	atBlock := block
+	// in simulation mode, just fetches from latest known block
+	if self.simulationMode {
+		atBlock = 0
+	}
+	self.fetchRateAtBlock(atBlock, block, timepoint)
+}
 
-	data, err = self.blockchain.FetchRates(atBlock, self.currentBlock)
+func (self *Fetcher) fetchRateAtBlock(atBlock, currentBlock, timepoint uint64) {
+	start := time.Now()
+	defer observeTick(common.RateDataKind, start)
+	data, err := self.blockchain.FetchRates(atBlock, currentBlock)
+	self.health.Record(BlockchainSource, common.RateDataKind, start, 0, err)
 	if err != nil {
 		log.Printf("Fetching rates from blockchain failed: %s. Will not store it to storage.", err.Error())
 		return
@@ -151,7 +230,9 @@ func (self *Fetcher) FetchRate(timepoint uint64) {
 	log.Printf("Got rates from blockchain: %+v", data)
 	if err = self.storage.StoreRate(data, timepoint); err != nil {
 		log.Printf("Storing rates failed: %s", err.Error())
+		return
 	}
+	self.publish(event.RateTopic, event.VersionData{Version: timepoint, BlockNumber: data.BlockNumber})
 }
 
 func (self *Fetcher) RunAuthDataFetcher() {
@@ -165,6 +246,7 @@ func (self *Fetcher) RunAuthDataFetcher() {
 }
 
 func (self *Fetcher) FetchAllAuthData(timepoint uint64) {
+	defer observeTick(common.AuthDataKind, time.Now())
 	snapshot := common.AuthDataSnapshot{
 		Valid:             true,
 		Timestamp:         common.GetTimestamp(),
@@ -242,6 +324,7 @@ func (self *Fetcher) FetchAuthDataFromBlockchain(
 	var balances map[string]common.BalanceEntry
 	var statuses map[common.ActivityID]common.ActivityStatus
 	var err error
+	start := time.Now()
 	for {
 		preStatuses := self.FetchStatusFromBlockchain(pendings)
 		balances, err = self.FetchBalanceFromBlockchain()
@@ -254,6 +337,7 @@ func (self *Fetcher) FetchAuthDataFromBlockchain(
 			break
 		}
 	}
+	self.health.Record(BlockchainSource, common.AuthDataKind, start, 0, err)
 	if err == nil {
 		for k, v := range balances {
 			allBalances[k] = v
@@ -265,7 +349,9 @@ func (self *Fetcher) FetchAuthDataFromBlockchain(
 }
 
 func (self *Fetcher) FetchCurrentBlock(timepoint uint64) {
+	start := time.Now()
 	block, err := self.blockchain.CurrentBlock()
+	self.health.Record(BlockchainSource, common.BlockDataKind, start, 0, err)
 	if err != nil {
 		log.Printf("Fetching current block failed: %v. Ignored.", err)
 	} else {
@@ -288,10 +374,11 @@ func (self *Fetcher) newNonceValidator() func(common.ActivityRecord) bool {
 	}
 
 	return func(act common.ActivityRecord) bool {
-		// this check only works with set rate transaction as:
+		// this check only works with transactions of the pricing operator,
+		// set rates and step functions, as:
 		//   - account nonce is record in result field of activity
 		//   - the SetRateMinedNonce method is available
-		if act.Action != "set_rates" {
+		if act.Action != "set_rates" && act.Action != "set_step_function" {
 			return false
 		}
 
@@ -314,7 +401,7 @@ func (self *Fetcher) FetchStatusFromBlockchain(pendings []common.ActivityRecord)
 	nonceValidator := self.newNonceValidator()
 
 	for _, activity := range pendings {
-		if activity.IsBlockchainPending() && (activity.Action == "set_rates" || activity.Action == "deposit" || activity.Action == "withdraw") {
+		if activity.IsBlockchainPending() && (activity.Action == "set_rates" || activity.Action == "deposit" || activity.Action == "withdraw" || activity.Action == "set_step_function") {
 			var blockNum uint64
 			var status string
 			var err error
@@ -515,9 +602,16 @@ func (self *Fetcher) PersistSnapshot(
 
 	pendingActivities := []common.ActivityRecord{}
 	for _, activity := range pendings {
+		exchangeStatus, miningStatus := activity.ExchangeStatus, activity.MiningStatus
+		wasBlockchainPending := activity.IsBlockchainPending()
+		wasExchangePending := activity.IsExchangePending()
 		updateActivitywithExchangeStatus(&activity, estatuses, snapshot)
 		updateActivitywithBlockchainStatus(&activity, bstatuses, snapshot)
 		log.Printf("Aggregate statuses, final activity: %+v", activity)
+		self.recordActivityGasSpend(activity, wasBlockchainPending, wasExchangePending, pendings)
+		if activity.Action == "set_rates" && miningStatus != "mined" && activity.MiningStatus == "mined" {
+			observeMinedSetRate(activity, common.GetTimepoint())
+		}
 		if activity.IsPending() {
 			pendingActivities = append(pendingActivities, activity)
 		}
@@ -525,13 +619,20 @@ func (self *Fetcher) PersistSnapshot(
 		if err != nil {
 			snapshot.Valid = false
 			snapshot.Error = err.Error()
+		} else if activity.ExchangeStatus != exchangeStatus || activity.MiningStatus != miningStatus {
+			self.publish(event.ActivityTopic, activity)
 		}
 	}
 	// note: only update status when it's pending status
 	snapshot.ExchangeBalances = allEBalances
 	snapshot.ReserveBalances = bbalances
 	snapshot.PendingActivities = pendingActivities
-	return self.storage.StoreAuthSnapshot(snapshot, timepoint)
+	if err := self.storage.StoreAuthSnapshot(snapshot, timepoint); err != nil {
+		return err
+	}
+	observeSnapshot(snapshot)
+	self.publish(event.AuthDataTopic, event.VersionData{Version: timepoint, BlockNumber: snapshot.Block})
+	return nil
 }
 
 func (self *Fetcher) FetchAuthDataFromExchange(
@@ -548,6 +649,7 @@ func (self *Fetcher) FetchAuthDataFromExchange(
 	var balances common.EBalanceEntry
 	var statuses map[common.ActivityID]common.ActivityStatus
 	var err error
+	start := time.Now()
 	for {
 		preStatuses := self.FetchStatusFromExchange(exchange, pendings, timepoint)
 		balances, err = exchange.FetchEBalanceData(timepoint)
@@ -560,6 +662,11 @@ func (self *Fetcher) FetchAuthDataFromExchange(
 			break
 		}
 	}
+	if err == nil && !balances.Valid {
+		self.health.Record(string(exchange.ID()), common.AuthDataKind, start, 0, errors.New(balances.Error))
+	} else {
+		self.health.Record(string(exchange.ID()), common.AuthDataKind, start, timestampToTimepoint(balances.Timestamp), err)
+	}
 	if err == nil {
 		allBalances.Store(exchange.ID(), balances)
 		for id, activityStatus := range statuses {
@@ -660,6 +767,7 @@ func (self *Fetcher) RunOrderbookFetcher() {
 }
 
 func (self *Fetcher) FetchOrderbook(timepoint uint64) {
+	defer observeTick(common.OrderbookDataKind, time.Now())
 	data := NewConcurrentAllPriceData()
 	// start fetching
 	wait := sync.WaitGroup{}
@@ -669,19 +777,54 @@ func (self *Fetcher) FetchOrderbook(timepoint uint64) {
 	}
 	wait.Wait()
 	data.SetBlockNumber(self.currentBlock)
-	err := self.storage.StorePrice(data.GetData(), timepoint)
+	prices := data.GetData()
+	self.validator.Validate(prices.Data)
+	err := self.storage.StorePrice(prices, timepoint)
 	if err != nil {
 		log.Printf("Storing data failed: %s\n", err)
+		return
 	}
+	self.publish(event.PriceTopic, event.VersionData{Version: timepoint, BlockNumber: prices.Block})
 }
 
 func (self *Fetcher) fetchPriceFromExchange(wg *sync.WaitGroup, exchange Exchange, data *ConcurrentAllPriceData, timepoint uint64) {
 	defer wg.Done()
+	start := time.Now()
 	exdata, err := exchange.FetchPriceData(timepoint)
 	if err != nil {
 		log.Printf("Fetching data from %s failed: %v\n", exchange.Name(), err)
 	}
+	dataTime, err := priceDataTime(exdata, err)
+	self.health.Record(string(exchange.ID()), common.OrderbookDataKind, start, dataTime, err)
 	for pair, exchangeData := range exdata {
 		data.SetOnePrice(exchange.ID(), pair, exchangeData)
 	}
 }
+
+// priceDataTime returns the time of the oldest order book of an exchange,
+// or an error if fetching failed or any order book is invalid.
+func priceDataTime(data map[common.TokenPairID]common.ExchangePrice, err error) (uint64, error) {
+	if err != nil {
+		return 0, err
+	}
+	var oldest uint64
+	for pair, price := range data {
+		if !price.Valid {
+			return 0, fmt.Errorf("%s: %s", pair, price.Error)
+		}
+		if t := timestampToTimepoint(price.Timestamp); t > 0 && (oldest == 0 || t < oldest) {
+			oldest = t
+		}
+	}
+	return oldest, nil
+}
+
+// timestampToTimepoint parses a timestamp set by exchanges, 0 is returned if
+// it is malformed.
+func timestampToTimepoint(timestamp common.Timestamp) uint64 {
+	result, err := strconv.ParseUint(string(timestamp), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return result
+}
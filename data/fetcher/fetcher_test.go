@@ -1,14 +1,18 @@
 package fetcher
 
 import (
+	"errors"
 	"io/ioutil"
 	"log"
+	"math/big"
 	"os"
 	"path"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/common/event"
 	"github.com/KyberNetwork/reserve-data/data/fetcher/http_runner"
 	"github.com/KyberNetwork/reserve-data/data/storage"
 	"github.com/KyberNetwork/reserve-data/world"
@@ -178,3 +182,234 @@ func TestExchangeDown(t *testing.T) {
 		t.Fatalf("Snapshot did not save exchange error")
 	}
 }
+
+func TestCountReplacements(t *testing.T) {
+	newActivity := func(id, action, nonce string) common.ActivityRecord {
+		return common.ActivityRecord{
+			Action: action,
+			ID:     common.NewActivityID(1, id),
+			Result: map[string]interface{}{"nonce": nonce},
+		}
+	}
+	pendings := []common.ActivityRecord{
+		newActivity("1", "set_rates", "10"),
+		newActivity("2", "set_rates", "10"),
+		newActivity("3", "set_rates", "10"),
+		newActivity("4", "set_rates", "11"),
+		newActivity("5", "deposit", "10"),
+		newActivity("6", "set_step_function", "11"),
+		newActivity("7", "set_step_function", "12"),
+	}
+	var tests = []struct {
+		activity common.ActivityRecord
+		expected uint64
+	}{
+		{activity: pendings[0], expected: 2},
+		{activity: pendings[3], expected: 1},
+		{activity: pendings[4], expected: 0},
+		// set rates and set step function share the pricing operator nonces
+		{activity: pendings[5], expected: 1},
+		{activity: pendings[6], expected: 0},
+	}
+	for _, tc := range tests {
+		if result := countReplacements(tc.activity, pendings); result != tc.expected {
+			t.Errorf("expected %d replacements for activity %s, got %d", tc.expected, tc.activity.ID, result)
+		}
+	}
+}
+
+// testBlockchain records blocks of fetched rates.
+type testBlockchain struct {
+	Blockchain
+	atBlocks []uint64
+}
+
+func (self *testBlockchain) FetchRates(atBlock uint64, currentBlock uint64) (common.AllRateEntry, error) {
+	self.atBlocks = append(self.atBlocks, atBlock)
+	return common.AllRateEntry{BlockNumber: currentBlock, Data: map[string]common.RateEntry{}}, nil
+}
+
+func TestFetchNewHead(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test_fetcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}()
+	fstorage, err := storage.NewBoltStorage(path.Join(tmpDir, "test_fetcher.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blockchain := &testBlockchain{}
+	fetcher := NewFetcher(fstorage, fstorage, &world.TheWorld{}, nil, ethereum.Address{}, false)
+	fetcher.blockchain = blockchain
+
+	// rates are fetched at the new head without waiting for the block fetcher
+	timepoint := common.GetTimepoint()
+	fetcher.FetchNewHead(100, timepoint)
+	if fetcher.currentBlock != 100 || len(blockchain.atBlocks) != 1 || blockchain.atBlocks[0] != 100 {
+		t.Fatalf("expected rates fetched at block 100, current block %d, fetched at %v", fetcher.currentBlock, blockchain.atBlocks)
+	}
+	rates, err := fstorage.GetRates(timepoint, timepoint+1)
+	if err != nil || len(rates) != 1 || rates[0].BlockNumber != 100 {
+		t.Errorf("expected rates of block 100 stored, got %+v, err %v", rates, err)
+	}
+}
+
+func TestPersistSnapshotEvents(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test_fetcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}()
+	fstorage, err := storage.NewBoltStorage(path.Join(tmpDir, "test_fetcher.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetcher := NewFetcher(fstorage, fstorage, &world.TheWorld{}, nil, ethereum.Address{}, false)
+	hub := event.NewHub()
+	subscription, err := hub.Subscribe(nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetcher.SetEventPublisher(hub)
+
+	timepoint := common.GetTimepoint()
+	withdraw := common.ActivityRecord{
+		Action:         "withdraw",
+		ID:             common.NewActivityID(timepoint, "withdraw"),
+		Destination:    "binance",
+		Params:         map[string]interface{}{},
+		Result:         map[string]interface{}{"tx": ""},
+		ExchangeStatus: "submitted",
+	}
+	unchanged := withdraw
+	unchanged.ID = common.NewActivityID(timepoint, "unchanged")
+	unchanged.Result = map[string]interface{}{"tx": ""}
+	var ebalances, estatuses, bstatuses sync.Map
+	estatuses.Store(withdraw.ID, common.ActivityStatus{ExchangeStatus: "done", Tx: "0x1"})
+	var snapshot common.AuthDataSnapshot
+	err = fetcher.PersistSnapshot(&ebalances, map[string]common.BalanceEntry{}, &estatuses, &bstatuses,
+		[]common.ActivityRecord{withdraw, unchanged}, &snapshot, timepoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []event.Event
+	for len(events) < 2 {
+		select {
+		case e := <-subscription.Events():
+			events = append(events, e)
+		default:
+			t.Fatalf("expected activity and auth data events, got %+v", events)
+		}
+	}
+	if activity, ok := events[0].Data.(common.ActivityRecord); !ok || activity.ID != withdraw.ID || activity.ExchangeStatus != "done" {
+		t.Errorf("expected event of withdraw done, got %+v", events[0])
+	}
+	if events[1].Topic != event.AuthDataTopic || events[1].Data != (event.VersionData{Version: timepoint}) {
+		t.Errorf("expected auth data event, got %+v", events[1])
+	}
+	if len(subscription.Events()) != 0 {
+		t.Errorf("expected no event of unchanged activity")
+	}
+}
+
+// testGasBlockchain returns costs of transactions mined at block time 5000, after
+// failing the first failures requests, and records timepoints of requested rates.
+type testGasBlockchain struct {
+	Blockchain
+	rateTimepoints []uint64
+	failures       int
+}
+
+func (self *testGasBlockchain) TxCost(tx ethereum.Hash) (common.TxCost, error) {
+	if self.failures > 0 {
+		self.failures--
+		return common.TxCost{}, errors.New("node is down")
+	}
+	return common.TxCost{GasUsed: 21000, GasPrice: big.NewInt(1000000000), BlockNumber: 10, BlockTime: 5000}, nil
+}
+
+func (self *testGasBlockchain) GetEthRate(timepoint uint64) float64 {
+	self.rateTimepoints = append(self.rateTimepoints, timepoint)
+	return 100
+}
+
+func TestRecordGasSpend(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test_fetcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}()
+	fstorage, err := storage.NewBoltStorage(path.Join(tmpDir, "test_fetcher.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blockchain := &testGasBlockchain{failures: 1}
+	fetcher := NewFetcher(fstorage, fstorage, &world.TheWorld{}, nil, ethereum.Address{}, false)
+	fetcher.blockchain = blockchain
+
+	activity := common.ActivityRecord{
+		Action:       "set_rates",
+		ID:           common.NewActivityID(1000, "0x1"),
+		Result:       map[string]interface{}{"tx": "0x1", "nonce": "1"},
+		MiningStatus: "mined",
+	}
+	fetcher.recordActivityGasSpend(activity, true, false, nil)
+	// receipts are fetched by the recorder, not while snapshots are persisted
+	if len(blockchain.rateTimepoints) != 0 || len(fetcher.gasSpends) != 1 {
+		t.Fatalf("expected gas spend to be queued, got %d queued", len(fetcher.gasSpends))
+	}
+	jobs, err := fstorage.GetPendingGasSpends()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].ActivityID != activity.ID {
+		t.Fatalf("expected gas spend job to be persisted, got %+v", jobs)
+	}
+
+	// the first cost request fails, the job is kept and retried after a delay
+	retries := map[string]gasSpendRetry{}
+	now := time.Unix(0, 0)
+	fetcher.processGasSpend(<-fetcher.gasSpends, retries, 0, now)
+	if len(retries) != 1 {
+		t.Fatalf("expected failed gas spend to be retried, got %+v", retries)
+	}
+	if jobs, err = fstorage.GetPendingGasSpends(); err != nil || len(jobs) != 1 {
+		t.Fatalf("expected failed gas spend job to be kept, got %+v (%v)", jobs, err)
+	}
+	fetcher.retryGasSpends(retries, now.Add(gasSpendMinRetryDelay-time.Millisecond))
+	if len(retries) != 1 || len(blockchain.rateTimepoints) != 0 {
+		t.Fatalf("expected gas spend not to be retried before its delay")
+	}
+	fetcher.retryGasSpends(retries, now.Add(gasSpendMinRetryDelay))
+	if len(retries) != 0 {
+		t.Fatalf("expected retried gas spend to be recorded, got %+v", retries)
+	}
+
+	records, err := fstorage.GetGasSpend(0, 10000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Timestamp != 5000 || records[0].FeeUSD != records[0].Fee*100 {
+		t.Fatalf("expected gas spend at block time, got %+v", records)
+	}
+	if len(blockchain.rateTimepoints) != 1 || blockchain.rateTimepoints[0] != 5000 {
+		t.Errorf("expected ETH-USD rate at block time, got rates at %v", blockchain.rateTimepoints)
+	}
+	if jobs, err = fstorage.GetPendingGasSpends(); err != nil || len(jobs) != 0 {
+		t.Errorf("expected recorded gas spend job to be removed, got %+v (%v)", jobs, err)
+	}
+}
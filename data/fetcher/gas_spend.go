@@ -0,0 +1,199 @@
+package fetcher
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	ethereum "github.com/ethereum/go-ethereum/common"
+)
+
+// intermediateTransferAction is the gas spend action of the transactions that forward
+// deposits from intermediate accounts to exchanges.
+const intermediateTransferAction = "intermediate_transfer"
+
+// intermediateExchange is implemented by exchanges which deposit through an intermediate
+// account, like Huobi.
+type intermediateExchange interface {
+	FindTx2(id common.ActivityID) (common.TXEntry, bool)
+}
+
+// pricingActions are the actions sent by the pricing operator, they share its nonces.
+var pricingActions = map[string]bool{
+	"set_rates":         true,
+	"set_step_function": true,
+}
+
+// countReplacements returns the number of other pricing transactions sent with the
+// same nonce as the given activity, which are replaced by the mined one.
+func countReplacements(activity common.ActivityRecord, pendings []common.ActivityRecord) uint64 {
+	if !pricingActions[activity.Action] {
+		return 0
+	}
+	nonce, ok := activity.Result["nonce"].(string)
+	if !ok || nonce == "" {
+		return 0
+	}
+	var result uint64
+	for _, other := range pendings {
+		if other.ID == activity.ID || !pricingActions[other.Action] {
+			continue
+		}
+		if otherNonce, ok := other.Result["nonce"].(string); ok && otherNonce == nonce {
+			result++
+		}
+	}
+	return result
+}
+
+const (
+	// gasSpendQueueSize is the number of transactions waiting for the recorder,
+	// persisting snapshots blocks when the queue is full.
+	gasSpendQueueSize = 1024
+	// gasSpendMinRetryDelay and gasSpendMaxRetryDelay bound the backoff of
+	// transactions whose gas spend failed to be recorded.
+	gasSpendMinRetryDelay = 5 * time.Second
+	gasSpendMaxRetryDelay = 30 * time.Minute
+)
+
+// gasSpendRetry is a transaction whose gas spend is recorded again at next.
+type gasSpendRetry struct {
+	job   common.PendingGasSpend
+	delay time.Duration
+	next  time.Time
+}
+
+// recordGasSpend persists the transaction with given hash to record its gas spend and
+// queues it, so receipts and rates are not fetched while snapshots are persisted.
+// The job is stored before the activity leaves pending, so it is recorded even if the
+// recorder fails or restarts.
+func (self *Fetcher) recordGasSpend(txHash, action string, activityID common.ActivityID, failed bool, replacements uint64) {
+	hash := ethereum.HexToHash(txHash)
+	if hash.Big().Sign() == 0 {
+		return
+	}
+	job := common.PendingGasSpend{
+		TxHash:       hash.Hex(),
+		Action:       action,
+		ActivityID:   activityID,
+		Failed:       failed,
+		Replacements: replacements,
+	}
+	if err := self.storage.StorePendingGasSpend(job); err != nil {
+		log.Printf("Persisting gas spend job of tx %s failed: %s", job.TxHash, err)
+	}
+	self.gasSpends <- job
+}
+
+// RunGasSpendRecorder records gas spend of persisted and queued transactions,
+// retrying failed ones with exponential backoff.
+func (self *Fetcher) RunGasSpendRecorder() {
+	retries := map[string]gasSpendRetry{}
+	jobs, err := self.storage.GetPendingGasSpends()
+	if err != nil {
+		log.Printf("Getting pending gas spend jobs failed: %s", err)
+	}
+	for _, job := range jobs {
+		self.processGasSpend(job, retries, 0, time.Now())
+	}
+	ticker := time.NewTicker(gasSpendMinRetryDelay)
+	defer ticker.Stop()
+	for {
+		select {
+		case job := <-self.gasSpends:
+			self.processGasSpend(job, retries, 0, time.Now())
+		case now := <-ticker.C:
+			self.retryGasSpends(retries, now)
+		}
+	}
+}
+
+// retryGasSpends records the gas spend of failed transactions which are due at now.
+func (self *Fetcher) retryGasSpends(retries map[string]gasSpendRetry, now time.Time) {
+	for txHash, retry := range retries {
+		if now.Before(retry.next) {
+			continue
+		}
+		delete(retries, txHash)
+		self.processGasSpend(retry.job, retries, retry.delay, now)
+	}
+}
+
+// processGasSpend records the gas spend of the job, on failure it is scheduled to
+// retry after double the last delay.
+func (self *Fetcher) processGasSpend(job common.PendingGasSpend, retries map[string]gasSpendRetry, lastDelay time.Duration, now time.Time) {
+	err := self.storeGasSpend(job)
+	if err == nil {
+		return
+	}
+	delay := lastDelay * 2
+	if delay < gasSpendMinRetryDelay {
+		delay = gasSpendMinRetryDelay
+	}
+	if delay > gasSpendMaxRetryDelay {
+		delay = gasSpendMaxRetryDelay
+	}
+	log.Printf("Recording gas spend of tx %s failed, retry in %s: %s", job.TxHash, delay, err)
+	retries[job.TxHash] = gasSpendRetry{job: job, delay: delay, next: now.Add(delay)}
+}
+
+// storeGasSpend stores the cost of a transaction from its receipt, valued by the
+// ETH-USD rate at the time its block was mined. The job is removed from pending
+// gas spends only after the record is stored.
+func (self *Fetcher) storeGasSpend(job common.PendingGasSpend) error {
+	hash := ethereum.HexToHash(job.TxHash)
+	cost, err := self.blockchain.TxCost(hash)
+	if err != nil {
+		return fmt.Errorf("getting cost of tx failed: %s", err)
+	}
+	record := common.NewGasSpendRecord(
+		job.TxHash, job.Action, job.ActivityID, job.Failed, job.Replacements,
+		cost, self.blockchain.GetEthRate(cost.BlockTime), cost.BlockTime,
+	)
+	if err = self.storage.StoreGasSpend(record); err != nil {
+		return err
+	}
+	return self.storage.RemovePendingGasSpend(job.TxHash)
+}
+
+// recordActivityGasSpend records the gas spend of transactions the activity sent when
+// they are done. wasBlockchainPending and wasExchangePending are the activity states
+// before this snapshot updated it.
+// Withdraw transactions are sent by exchanges, so they are not our spend, and lost
+// transactions never made it on chain, so they have no receipt.
+func (self *Fetcher) recordActivityGasSpend(
+	activity common.ActivityRecord,
+	wasBlockchainPending, wasExchangePending bool,
+	pendings []common.ActivityRecord) {
+	switch activity.Action {
+	case "set_rates", "set_step_function", "deposit":
+	default:
+		return
+	}
+	if wasBlockchainPending && !activity.IsBlockchainPending() && activity.MiningStatus != "lost" {
+		txHash, ok := activity.Result["tx"].(string)
+		if ok {
+			self.recordGasSpend(
+				txHash, activity.Action, activity.ID,
+				activity.MiningStatus == "failed",
+				countReplacements(activity, pendings),
+			)
+		}
+	}
+	if activity.Action == "deposit" && wasExchangePending && !activity.IsExchangePending() {
+		for _, exchange := range self.exchanges {
+			if string(exchange.ID()) != activity.Destination {
+				continue
+			}
+			intermediate, ok := exchange.(intermediateExchange)
+			if !ok {
+				break
+			}
+			if tx2, found := intermediate.FindTx2(activity.ID); found {
+				self.recordGasSpend(tx2.Hash, intermediateTransferAction, activity.ID, tx2.MiningStatus == "failed", 0)
+			}
+			break
+		}
+	}
+}
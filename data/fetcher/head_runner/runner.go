@@ -0,0 +1,366 @@
+package head_runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/data/fetcher"
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	defaultPollInterval        = 5 * time.Second
+	defaultResubscribeInterval = 30 * time.Second
+	// requestTimeout is the timeout of subscribing and polling requests to the node.
+	requestTimeout = 10 * time.Second
+)
+
+// Config is the configuration of the head runner, read from the secret config file.
+type Config struct {
+	// WSEndpoint is the websocket endpoint of the node to subscribe to new
+	// heads, the ticker runner is used if it is empty.
+	WSEndpoint string `json:"ws_endpoint"`
+}
+
+// GetConfigFromFile reads head runner configuration from a JSON file.
+func GetConfigFromFile(path string) (Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	result := Config{}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// HeadSubscriber subscribes to new block headers, it is implemented by
+// ethclient.Client connected to a websocket endpoint.
+type HeadSubscriber interface {
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+}
+
+// DialingSubscriber is a HeadSubscriber connecting to a websocket endpoint on
+// subscribing, so the node does not have to be up when the runner is created.
+// The connection is dropped when subscribing fails and dialed again on the
+// next subscribing.
+type DialingSubscriber struct {
+	mu       sync.Mutex
+	endpoint string
+	client   *ethclient.Client
+}
+
+// NewDialingSubscriber creates a DialingSubscriber of the websocket endpoint.
+func NewDialingSubscriber(endpoint string) *DialingSubscriber {
+	return &DialingSubscriber{endpoint: endpoint}
+}
+
+// SubscribeNewHead connects to the endpoint if it is not connected and subscribes to new heads.
+func (self *DialingSubscriber) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.client == nil {
+		client, err := ethclient.DialContext(ctx, self.endpoint)
+		if err != nil {
+			return nil, err
+		}
+		self.client = client
+	}
+	sub, err := self.client.SubscribeNewHead(ctx, ch)
+	if err != nil {
+		self.client.Close()
+		self.client = nil
+	}
+	return sub, err
+}
+
+// HeaderReader reads block headers, the latest one if number is nil. It is
+// implemented by ethclient.Client.
+type HeaderReader interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// HeadRunner is an implementation of fetcher.HeadRunner that subscribes to
+// new heads of a node. If the subscription fails, it polls the latest
+// header until it subscribes again. Order book, auth data and global data
+// tickers are time tickers, auth data also ticks on every new head.
+type HeadRunner struct {
+	subscriber HeadSubscriber
+	reader     HeaderReader
+
+	oduration           time.Duration
+	aduration           time.Duration
+	globalDataDuration  time.Duration
+	pollInterval        time.Duration
+	resubscribeInterval time.Duration
+
+	oclock          *time.Ticker
+	aclock          *time.Ticker
+	globalDataClock *time.Ticker
+
+	headTicker chan fetcher.NewHead
+	aticker    chan time.Time
+
+	mu         sync.Mutex
+	lastBlock  uint64
+	subscribed bool
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// HeadRunnerOption is the option to setup the HeadRunner on creation.
+type HeadRunnerOption func(hr *HeadRunner)
+
+// WithPollInterval sets how often the latest header is polled while the
+// subscription is down, default to 5 seconds.
+func WithPollInterval(interval time.Duration) HeadRunnerOption {
+	return func(hr *HeadRunner) {
+		hr.pollInterval = interval
+	}
+}
+
+// WithResubscribeInterval sets how often subscribing is retried while the
+// subscription is down, default to 30 seconds.
+func WithResubscribeInterval(interval time.Duration) HeadRunnerOption {
+	return func(hr *HeadRunner) {
+		hr.resubscribeInterval = interval
+	}
+}
+
+// NewHeadRunner creates a new instance of HeadRunner subscribing with
+// subscriber and polling with reader, and time tickers of given durations.
+func NewHeadRunner(
+	subscriber HeadSubscriber, reader HeaderReader,
+	oduration, aduration, globalDataDuration time.Duration,
+	options ...HeadRunnerOption) *HeadRunner {
+	runner := &HeadRunner{
+		subscriber:          subscriber,
+		reader:              reader,
+		oduration:           oduration,
+		aduration:           aduration,
+		globalDataDuration:  globalDataDuration,
+		pollInterval:        defaultPollInterval,
+		resubscribeInterval: defaultResubscribeInterval,
+	}
+	for _, option := range options {
+		option(runner)
+	}
+	return runner
+}
+
+// GetNewHeadTicker returns the channel of new heads.
+func (self *HeadRunner) GetNewHeadTicker() <-chan fetcher.NewHead {
+	return self.headTicker
+}
+
+// GetBlockTicker returns a channel which never ticks, blocks are fetched on new heads.
+func (self *HeadRunner) GetBlockTicker() <-chan time.Time {
+	return nil
+}
+
+// GetRateTicker returns a channel which never ticks, rates are fetched on new heads.
+func (self *HeadRunner) GetRateTicker() <-chan time.Time {
+	return nil
+}
+
+// GetOrderbookTicker returns the order book ticker.
+func (self *HeadRunner) GetOrderbookTicker() <-chan time.Time {
+	return self.oclock.C
+}
+
+// GetAuthDataTicker returns the auth data ticker, which ticks on its interval
+// and on every new head.
+func (self *HeadRunner) GetAuthDataTicker() <-chan time.Time {
+	return self.aticker
+}
+
+// GetGlobalDataTicker returns the global data ticker.
+func (self *HeadRunner) GetGlobalDataTicker() <-chan time.Time {
+	return self.globalDataClock.C
+}
+
+// Subscribed returns true if new heads are received from the subscription,
+// false if the runner is polling.
+func (self *HeadRunner) Subscribed() bool {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return self.subscribed
+}
+
+// Start starts the tickers and follows new heads of the chain.
+func (self *HeadRunner) Start() error {
+	if self.quit != nil {
+		return errors.New("runner start already")
+	}
+	self.headTicker = make(chan fetcher.NewHead, 1)
+	self.aticker = make(chan time.Time, 1)
+	self.oclock = time.NewTicker(self.oduration)
+	self.aclock = time.NewTicker(self.aduration)
+	self.globalDataClock = time.NewTicker(self.globalDataDuration)
+	self.quit = make(chan struct{})
+	self.wg.Add(2)
+	go self.forwardAuthDataTicks()
+	go self.followHeads()
+	return nil
+}
+
+// Stop stops the tickers and the subscription.
+func (self *HeadRunner) Stop() error {
+	if self.quit == nil {
+		return errors.New("runner stop already")
+	}
+	close(self.quit)
+	self.wg.Wait()
+	self.quit = nil
+	self.oclock.Stop()
+	self.aclock.Stop()
+	self.globalDataClock.Stop()
+	return nil
+}
+
+func (self *HeadRunner) forwardAuthDataTicks() {
+	defer self.wg.Done()
+	for {
+		select {
+		case <-self.quit:
+			return
+		case t := <-self.aclock.C:
+			self.tickAuthData(t)
+		}
+	}
+}
+
+// tickAuthData ticks the auth data ticker, the tick is dropped if the
+// previous one is not received yet, the same as time.Ticker.
+func (self *HeadRunner) tickAuthData(t time.Time) {
+	select {
+	case self.aticker <- t:
+	default:
+	}
+}
+
+// onHeader sends a new head if the header is newer than the last one, a
+// pending head not received yet is replaced.
+func (self *HeadRunner) onHeader(header *types.Header) {
+	if header == nil || header.Number == nil {
+		return
+	}
+	block := header.Number.Uint64()
+	self.mu.Lock()
+	if block <= self.lastBlock {
+		self.mu.Unlock()
+		return
+	}
+	self.lastBlock = block
+	self.mu.Unlock()
+	head := fetcher.NewHead{Block: block, Time: time.Now()}
+	if header.Time != nil {
+		head.Time = time.Unix(header.Time.Int64(), 0)
+	}
+	for {
+		select {
+		case self.headTicker <- head:
+			self.tickAuthData(time.Now())
+			return
+		default:
+		}
+		// drop the pending head, the latest one is enough to catch up
+		select {
+		case <-self.headTicker:
+		default:
+		}
+	}
+}
+
+func (self *HeadRunner) setSubscribed(subscribed bool) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.subscribed = subscribed
+}
+
+// followHeads receives new heads from the subscription, and polls while it
+// is down.
+func (self *HeadRunner) followHeads() {
+	defer self.wg.Done()
+	for {
+		headers := make(chan *types.Header)
+		sub, err := self.subscribe(headers)
+		if err != nil {
+			log.Printf("HeadRunner: subscribing to new heads failed: %s, polling every %s", err, self.pollInterval)
+			if stopped := self.poll(); stopped {
+				return
+			}
+			continue
+		}
+		self.setSubscribed(true)
+		log.Printf("HeadRunner: subscribed to new heads")
+		// the subscription only sends future heads, catch up with blocks mined while it was down
+		self.pollOnce()
+		if stopped := self.receive(sub, headers); stopped {
+			return
+		}
+		self.setSubscribed(false)
+	}
+}
+
+func (self *HeadRunner) subscribe(headers chan *types.Header) (ethereum.Subscription, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	return self.subscriber.SubscribeNewHead(ctx, headers)
+}
+
+// receive sends new heads of the subscription until it fails, it returns
+// true if the runner is stopped.
+func (self *HeadRunner) receive(sub ethereum.Subscription, headers <-chan *types.Header) bool {
+	defer sub.Unsubscribe()
+	for {
+		select {
+		case <-self.quit:
+			return true
+		case header := <-headers:
+			self.onHeader(header)
+		case err := <-sub.Err():
+			log.Printf("HeadRunner: new head subscription failed: %v, polling every %s", err, self.pollInterval)
+			return false
+		}
+	}
+}
+
+// poll sends the latest header every poll interval until the resubscribe
+// interval passes, it returns true if the runner is stopped.
+func (self *HeadRunner) poll() bool {
+	ticker := time.NewTicker(self.pollInterval)
+	defer ticker.Stop()
+	resubscribe := time.NewTimer(self.resubscribeInterval)
+	defer resubscribe.Stop()
+	self.pollOnce()
+	for {
+		select {
+		case <-self.quit:
+			return true
+		case <-resubscribe.C:
+			return false
+		case <-ticker.C:
+			self.pollOnce()
+		}
+	}
+}
+
+func (self *HeadRunner) pollOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	header, err := self.reader.HeaderByNumber(ctx, nil)
+	if err != nil {
+		log.Printf("HeadRunner: polling latest header failed: %s", err)
+		return
+	}
+	self.onHeader(header)
+}
@@ -0,0 +1,144 @@
+package head_runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/data/fetcher"
+)
+
+const testTimeout = 2 * time.Second
+
+func waitHead(t *testing.T, runner *HeadRunner, expected uint64) fetcher.NewHead {
+	t.Helper()
+	select {
+	case head := <-runner.GetNewHeadTicker():
+		if head.Block != expected {
+			t.Fatalf("expected head %d, got %d", expected, head.Block)
+		}
+		return head
+	case <-time.After(testTimeout):
+		t.Fatalf("expected head %d, got nothing", expected)
+	}
+	return fetcher.NewHead{}
+}
+
+func waitSubscribed(t *testing.T, runner *HeadRunner, subscribed bool) {
+	t.Helper()
+	deadline := time.Now().Add(testTimeout)
+	for runner.Subscribed() != subscribed {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected subscribed %v", subscribed)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHeadRunner(t *testing.T) {
+	chain := NewSimulatedChain()
+	runner := NewHeadRunner(chain, chain, time.Hour, time.Hour, time.Hour,
+		WithPollInterval(10*time.Millisecond),
+		WithResubscribeInterval(100*time.Millisecond),
+	)
+	var _ fetcher.HeadRunner = runner
+	if err := runner.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := runner.Stop(); err != nil {
+			t.Error(err)
+		}
+		if n := chain.Subscribers(); n != 0 {
+			t.Errorf("expected no subscription after stop, got %d", n)
+		}
+	}()
+	waitSubscribed(t, runner, true)
+
+	// heads have the time of their blocks
+	if head := waitHead(t, runner, chain.Mine()); head.Time.Unix() != chain.Head().Time.Int64() {
+		t.Errorf("expected head time %d, got %d", chain.Head().Time.Int64(), head.Time.Unix())
+	}
+	// reserve balances are fetched every block
+	select {
+	case <-runner.GetAuthDataTicker():
+	case <-time.After(testTimeout):
+		t.Fatal("expected auth data tick on new head")
+	}
+
+	// only the latest head is kept for a slow fetcher
+	chain.Mine()
+	chain.Mine()
+	latest := chain.Mine()
+	time.Sleep(50 * time.Millisecond)
+	waitHead(t, runner, latest)
+	select {
+	case head := <-runner.GetNewHeadTicker():
+		t.Fatalf("unexpected head %d", head.Block)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// new heads are polled while the subscription is down
+	chain.Disconnect(false)
+	waitSubscribed(t, runner, false)
+	waitHead(t, runner, chain.Mine())
+	// the same head is polled again but not sent
+	select {
+	case head := <-runner.GetNewHeadTicker():
+		t.Fatalf("unexpected head %d", head.Block)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// the runner subscribes again after reconnecting
+	chain.Reconnect()
+	waitSubscribed(t, runner, true)
+	waitHead(t, runner, chain.Mine())
+}
+
+func TestHeadRunnerNodeDown(t *testing.T) {
+	chain := NewSimulatedChain()
+	chain.Disconnect(true)
+	runner := NewHeadRunner(chain, chain, time.Hour, time.Hour, time.Hour,
+		WithPollInterval(10*time.Millisecond),
+		WithResubscribeInterval(50*time.Millisecond),
+	)
+	if err := runner.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := runner.Stop(); err != nil {
+			t.Error(err)
+		}
+	}()
+	chain.Mine()
+	select {
+	case head := <-runner.GetNewHeadTicker():
+		t.Fatalf("unexpected head %d while node is down", head.Block)
+	case <-time.After(100 * time.Millisecond):
+	}
+	chain.Reconnect()
+	// the head mined while the node was down is polled before subscribing
+	waitHead(t, runner, 1)
+	waitSubscribed(t, runner, true)
+	waitHead(t, runner, chain.Mine())
+}
+
+func TestHeadRunnerUnreachableEndpoint(t *testing.T) {
+	chain := NewSimulatedChain()
+	// nothing listens on the endpoint, the runner polls instead of failing
+	runner := NewHeadRunner(NewDialingSubscriber("ws://127.0.0.1:1"), chain, time.Hour, time.Hour, time.Hour,
+		WithPollInterval(10*time.Millisecond),
+		WithResubscribeInterval(50*time.Millisecond),
+	)
+	if err := runner.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := runner.Stop(); err != nil {
+			t.Error(err)
+		}
+	}()
+	waitHead(t, runner, chain.Mine())
+	if runner.Subscribed() {
+		t.Error("expected runner not to be subscribed")
+	}
+}
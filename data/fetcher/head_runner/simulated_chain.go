@@ -0,0 +1,152 @@
+package head_runner
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// errDisconnected is returned by SimulatedChain while it is disconnected.
+var errDisconnected = errors.New("simulated chain is disconnected")
+
+// simulatedBlockTime is the time between blocks of SimulatedChain in second.
+const simulatedBlockTime = 15
+
+// SimulatedChain is a local chain implementing HeadSubscriber and
+// HeaderReader, blocks are mined on demand. It is used to test runners
+// without a node.
+type SimulatedChain struct {
+	mu sync.Mutex
+	// head is the latest header.
+	head *types.Header
+	subs map[chan *types.Header]bool
+	// down is closed when the chain is disconnected.
+	down chan struct{}
+	// pollingDown makes HeaderByNumber fail.
+	pollingDown bool
+}
+
+// NewSimulatedChain creates a connected chain with the genesis block.
+func NewSimulatedChain() *SimulatedChain {
+	return &SimulatedChain{
+		head: &types.Header{Number: big.NewInt(0), Time: big.NewInt(time.Now().Unix())},
+		subs: map[chan *types.Header]bool{},
+		down: make(chan struct{}),
+	}
+}
+
+// Mine adds a block and sends its header to subscribers, the block number is returned.
+func (self *SimulatedChain) Mine() uint64 {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.head = &types.Header{
+		ParentHash: self.head.Hash(),
+		Number:     new(big.Int).Add(self.head.Number, big.NewInt(1)),
+		Time:       new(big.Int).Add(self.head.Time, big.NewInt(simulatedBlockTime)),
+	}
+	for sub := range self.subs {
+		select {
+		case sub <- self.head:
+		default:
+			// subscribers of a node are dropped if they are too slow, heads are
+			// dropped here as tests only care about the latest one
+		}
+	}
+	return self.head.Number.Uint64()
+}
+
+// Disconnect fails all subscriptions and new subscribing until Reconnect
+// is called. If polling is true, HeaderByNumber fails as well.
+func (self *SimulatedChain) Disconnect(polling bool) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	select {
+	case <-self.down:
+	default:
+		close(self.down)
+	}
+	self.pollingDown = polling
+}
+
+// Reconnect allows subscribing and polling again.
+func (self *SimulatedChain) Reconnect() {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	select {
+	case <-self.down:
+		self.down = make(chan struct{})
+	default:
+	}
+	self.pollingDown = false
+}
+
+// Subscribers returns the number of active subscriptions.
+func (self *SimulatedChain) Subscribers() int {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return len(self.subs)
+}
+
+// SubscribeNewHead subscribes to headers of mined blocks.
+func (self *SimulatedChain) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	down := self.down
+	select {
+	case <-down:
+		return nil, errDisconnected
+	default:
+	}
+	headers := make(chan *types.Header, 16)
+	self.subs[headers] = true
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer func() {
+			self.mu.Lock()
+			delete(self.subs, headers)
+			self.mu.Unlock()
+		}()
+		for {
+			select {
+			case <-quit:
+				return nil
+			case <-down:
+				return errDisconnected
+			case header := <-headers:
+				select {
+				case ch <- header:
+				case <-quit:
+					return nil
+				case <-down:
+					return errDisconnected
+				}
+			}
+		}
+	}), nil
+}
+
+// Head returns the latest header.
+func (self *SimulatedChain) Head() *types.Header {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return self.head
+}
+
+// HeaderByNumber returns the latest header if number is nil, only the
+// latest header is kept.
+func (self *SimulatedChain) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.pollingDown {
+		return nil, errDisconnected
+	}
+	if number != nil && number.Cmp(self.head.Number) != 0 {
+		return nil, ethereum.NotFound
+	}
+	return self.head, nil
+}
@@ -0,0 +1,183 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+const (
+	// BlockchainSource is the source of rates, blocks and reserve balances.
+	BlockchainSource = "blockchain"
+	// GoldSource is the source of gold feeds.
+	GoldSource = "gold"
+
+	// healthWindow is the number of latest fetches of a source the success
+	// rate and durations are computed over.
+	healthWindow = 100
+)
+
+// HealthConfig is the configuration of data health, read from the secret config file.
+type HealthConfig struct {
+	// StaleLimits is the maximum age of each kind of data, e.g {"orderbook": "30s"}.
+	// Missing kinds use the default limits.
+	StaleLimits map[string]string `json:"data_stale_limits"`
+}
+
+// GetHealthConfigFromFile reads data health configuration from a JSON file.
+func GetHealthConfigFromFile(path string) (HealthConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return HealthConfig{}, err
+	}
+	result := HealthConfig{}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// fetchResult is the result of one fetch of a source.
+type fetchResult struct {
+	success  bool
+	duration uint64
+}
+
+// sourceStats are the latest fetches of a kind of data from a source.
+type sourceStats struct {
+	results     []fetchResult
+	next        int
+	lastFetch   uint64
+	lastSuccess uint64
+	lastError   string
+	dataTime    uint64
+}
+
+func (self *sourceStats) add(result fetchResult) {
+	if len(self.results) < healthWindow {
+		self.results = append(self.results, result)
+		return
+	}
+	self.results[self.next] = result
+	self.next = (self.next + 1) % healthWindow
+}
+
+type sourceKey struct {
+	source string
+	data   string
+}
+
+// HealthTracker records duration, success and data time of every fetch of
+// every source, and reports their health against stale limits.
+type HealthTracker struct {
+	mu      sync.RWMutex
+	limits  common.StaleLimits
+	sources map[sourceKey]*sourceStats
+}
+
+// NewHealthTracker creates a tracker with the given stale limits.
+func NewHealthTracker(limits common.StaleLimits) *HealthTracker {
+	return &HealthTracker{
+		limits:  limits,
+		sources: map[sourceKey]*sourceStats{},
+	}
+}
+
+// SetStaleLimits replaces the stale limits.
+func (self *HealthTracker) SetStaleLimits(limits common.StaleLimits) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.limits = limits
+}
+
+// StaleLimits returns the stale limits.
+func (self *HealthTracker) StaleLimits() common.StaleLimits {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	return self.limits
+}
+
+// Record records a fetch of a kind of data from source started at start, it
+// failed if err is not nil. dataTime is the time of the fetched data, in
+// millisecond, the start time is used if it is 0.
+func (self *HealthTracker) Record(source, data string, start time.Time, dataTime uint64, err error) {
+	end := time.Now()
+	result := fetchResult{
+		success:  err == nil,
+		duration: uint64(end.Sub(start) / time.Millisecond),
+	}
+	fetchDuration.WithLabelValues(source, data).Observe(end.Sub(start).Seconds())
+	if err != nil {
+		fetchErrors.WithLabelValues(source, data).Inc()
+	}
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	key := sourceKey{source, data}
+	stats, ok := self.sources[key]
+	if !ok {
+		stats = &sourceStats{}
+		self.sources[key] = stats
+	}
+	stats.add(result)
+	stats.lastFetch = common.TimeToTimepoint(start)
+	if err != nil {
+		stats.lastError = err.Error()
+		return
+	}
+	stats.lastError = ""
+	stats.lastSuccess = common.TimeToTimepoint(end)
+	if dataTime == 0 {
+		dataTime = common.TimeToTimepoint(start)
+	}
+	stats.dataTime = dataTime
+}
+
+// Health returns the health of all sources at timepoint, sorted by source
+// and kind of data.
+func (self *HealthTracker) Health(timepoint uint64) []common.SourceHealth {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	result := []common.SourceHealth{}
+	for key, stats := range self.sources {
+		health := common.SourceHealth{
+			Source:      key.source,
+			Data:        key.data,
+			Fetches:     len(stats.results),
+			LastFetch:   stats.lastFetch,
+			LastSuccess: stats.lastSuccess,
+			LastError:   stats.lastError,
+			DataTime:    stats.dataTime,
+			StaleLimit:  self.limits.Limit(key.data),
+		}
+		var total uint64
+		for _, r := range stats.results {
+			if r.success {
+				health.Successes++
+			}
+			total += r.duration
+			if r.duration > health.MaxDuration {
+				health.MaxDuration = r.duration
+			}
+		}
+		if health.Fetches > 0 {
+			last := (stats.next + health.Fetches - 1) % health.Fetches
+			health.LastDuration = stats.results[last].duration
+			health.AvgDuration = total / uint64(health.Fetches)
+			health.SuccessRate = float64(health.Successes) / float64(health.Fetches)
+		}
+		if stats.dataTime > 0 && timepoint > stats.dataTime {
+			health.DataAge = timepoint - stats.dataTime
+		}
+		health.Stale = stats.dataTime == 0 || health.DataAge > health.StaleLimit
+		result = append(result, health)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Source != result[j].Source {
+			return result[i].Source < result[j].Source
+		}
+		return result[i].Data < result[j].Data
+	})
+	return result
+}
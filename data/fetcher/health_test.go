@@ -0,0 +1,115 @@
+package fetcher
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+func findSourceHealth(t *testing.T, health []common.SourceHealth, source, data string) common.SourceHealth {
+	t.Helper()
+	for _, h := range health {
+		if h.Source == source && h.Data == data {
+			return h
+		}
+	}
+	t.Fatalf("no health of %s %s", source, data)
+	return common.SourceHealth{}
+}
+
+func TestHealthTracker(t *testing.T) {
+	tracker := NewHealthTracker(common.StaleLimits{common.OrderbookDataKind: time.Second})
+	start := time.Now()
+	now := common.TimeToTimepoint(start)
+
+	tracker.Record("binance", common.OrderbookDataKind, start.Add(-20*time.Millisecond), now-500, nil)
+	tracker.Record("binance", common.OrderbookDataKind, start, 0, errors.New("timeout"))
+	tracker.Record(BlockchainSource, common.RateDataKind, start, 0, errors.New("node is down"))
+
+	health := tracker.Health(now + 100)
+	if len(health) != 2 || health[0].Source != "binance" || health[1].Source != BlockchainSource {
+		t.Fatalf("expected health sorted by source, got %+v", health)
+	}
+	binance := findSourceHealth(t, health, "binance", common.OrderbookDataKind)
+	if binance.Fetches != 2 || binance.Successes != 1 || binance.SuccessRate != 0.5 {
+		t.Errorf("expected 1 of 2 fetches succeeded, got %+v", binance)
+	}
+	if binance.MaxDuration < 20 {
+		t.Errorf("expected max duration at least 20ms, got %d", binance.MaxDuration)
+	}
+	if binance.LastError != "timeout" {
+		t.Errorf("expected last error timeout, got %s", binance.LastError)
+	}
+	// the failed fetch doesn't refresh data
+	if binance.DataTime != now-500 || binance.DataAge != 600 || binance.Stale {
+		t.Errorf("expected fresh data of 600ms old, got %+v", binance)
+	}
+	if health = tracker.Health(now + 2000); !findSourceHealth(t, health, "binance", common.OrderbookDataKind).Stale {
+		t.Error("expected data older than stale limit to be stale")
+	}
+	// sources without any success are stale
+	rates := findSourceHealth(t, health, BlockchainSource, common.RateDataKind)
+	if !rates.Stale || rates.StaleLimit != common.DefaultStaleLimits().Limit(common.RateDataKind) {
+		t.Errorf("expected stale rates with default limit, got %+v", rates)
+	}
+
+	// success rate is of the latest fetches only
+	for i := 0; i < healthWindow; i++ {
+		tracker.Record(BlockchainSource, common.RateDataKind, time.Now(), 0, nil)
+	}
+	rates = findSourceHealth(t, tracker.Health(common.GetTimepoint()), BlockchainSource, common.RateDataKind)
+	if rates.Fetches != healthWindow || rates.SuccessRate != 1 || rates.LastError != "" || rates.Stale {
+		t.Errorf("expected all latest fetches succeeded, got %+v", rates)
+	}
+}
+
+func TestPriceDataTime(t *testing.T) {
+	var testCases = []struct {
+		msg      string
+		data     map[common.TokenPairID]common.ExchangePrice
+		err      error
+		expected uint64
+		hasError bool
+	}{
+		{
+			msg: "oldest order book",
+			data: map[common.TokenPairID]common.ExchangePrice{
+				"KNC-ETH": {Valid: true, Timestamp: "2000"},
+				"OMG-ETH": {Valid: true, Timestamp: "1000"},
+				"EOS-ETH": {Valid: true, Timestamp: ""},
+			},
+			expected: 1000,
+		},
+		{
+			msg: "invalid order book",
+			data: map[common.TokenPairID]common.ExchangePrice{
+				"KNC-ETH": {Valid: true, Timestamp: "2000"},
+				"OMG-ETH": {Valid: false, Error: "timeout"},
+			},
+			hasError: true,
+		},
+		{
+			msg:      "fetching failed",
+			err:      errors.New("timeout"),
+			hasError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Logf("running test case for: %s", tc.msg)
+		result, err := priceDataTime(tc.data, tc.err)
+		if tc.hasError {
+			if err == nil {
+				t.Errorf("expected error, got nil")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != tc.expected {
+			t.Errorf("expected data time %d, got %d", tc.expected, result)
+		}
+	}
+}
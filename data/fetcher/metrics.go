@@ -0,0 +1,106 @@
+package fetcher
+
+import (
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	fetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "reserve_fetcher_fetch_duration_seconds",
+		Help: "Duration of fetches of data from sources, e.g exchanges and the blockchain.",
+	}, []string{"source", "data"})
+	fetchErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "reserve_fetcher_fetch_errors_total",
+		Help: "Number of failed fetches of data from sources.",
+	}, []string{"source", "data"})
+	tickDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "reserve_fetcher_tick_duration_seconds",
+		Help: "Duration of fetcher ticks, from fetching all sources to storing the data.",
+	}, []string{"data"})
+	pendingActivities = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "reserve_pending_activities",
+		Help: "Number of pending activities of the latest auth data snapshot.",
+	}, []string{"action", "exchange_status", "mining_status"})
+	exchangeBalances = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "reserve_exchange_balance",
+		Help: "Balances of tokens on exchanges of the latest auth data snapshot.",
+	}, []string{"exchange", "token", "type"})
+	reserveBalances = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "reserve_reserve_balance",
+		Help: "Balances of tokens of the reserve of the latest auth data snapshot.",
+	}, []string{"token"})
+	setRateMiningLatency = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "reserve_set_rate_mining_latency_seconds",
+		Help:    "Time from sending set rates transactions to finding them mined.",
+		Buckets: []float64{5, 10, 15, 30, 60, 120, 300, 600, 1800},
+	})
+	setRateGasPrice = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "reserve_set_rate_gas_price_gwei",
+		Help:    "Gas price paid by mined set rates transactions in gwei.",
+		Buckets: []float64{1, 2, 5, 10, 20, 50, 100, 200, 500},
+	})
+)
+
+// observeTick records the duration of a tick of fetching data since start.
+func observeTick(data string, start time.Time) {
+	tickDuration.WithLabelValues(data).Observe(time.Since(start).Seconds())
+}
+
+// observeSnapshot records pending activities and balances of snapshot,
+// values of previous snapshots are removed.
+func observeSnapshot(snapshot *common.AuthDataSnapshot) {
+	counts := map[[3]string]float64{}
+	for _, activity := range snapshot.PendingActivities {
+		counts[[3]string{activity.Action, activity.ExchangeStatus, activity.MiningStatus}]++
+	}
+	pendingActivities.Reset()
+	for labels, count := range counts {
+		pendingActivities.WithLabelValues(labels[:]...).Set(count)
+	}
+
+	exchangeBalances.Reset()
+	for exchange, balance := range snapshot.ExchangeBalances {
+		for balanceType, balances := range map[string]map[string]float64{
+			"available": balance.AvailableBalance,
+			"locked":    balance.LockedBalance,
+			"deposit":   balance.DepositBalance,
+		} {
+			for token, value := range balances {
+				exchangeBalances.WithLabelValues(string(exchange), token, balanceType).Set(value)
+			}
+		}
+	}
+
+	reserveBalances.Reset()
+	for tokenID, balance := range snapshot.ReserveBalances {
+		token, err := common.GetInternalToken(tokenID)
+		if err != nil || !balance.Valid {
+			continue
+		}
+		reserveBalances.WithLabelValues(tokenID).Set(balance.Balance.ToFloat(token.Decimal))
+	}
+}
+
+// observeMinedSetRate records latency and gas price of a set rates
+// activity which is found mined at timepoint.
+func observeMinedSetRate(activity common.ActivityRecord, timepoint uint64) {
+	sentAt, err := strconv.ParseUint(string(activity.Timestamp), 10, 64)
+	if err == nil && timepoint >= sentAt {
+		setRateMiningLatency.Observe(float64(timepoint-sentAt) / 1000)
+	}
+	gasPrice, ok := activity.Result["gasPrice"].(string)
+	if !ok {
+		return
+	}
+	wei, ok := big.NewInt(0).SetString(gasPrice, 10)
+	if !ok {
+		return
+	}
+	setRateGasPrice.Observe(common.BigToFloat(wei, 9))
+}
@@ -0,0 +1,87 @@
+package fetcher
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+func exposedMetrics(t *testing.T) string {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	encoder := expfmt.NewEncoder(&buf, expfmt.FmtText)
+	for _, family := range families {
+		if err = encoder.Encode(family); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.String()
+}
+
+func TestObserveSnapshot(t *testing.T) {
+	observeSnapshot(&common.AuthDataSnapshot{
+		ExchangeBalances: map[common.ExchangeID]common.EBalanceEntry{
+			"binance": {
+				AvailableBalance: map[string]float64{"KNC": 100},
+				LockedBalance:    map[string]float64{"KNC": 5},
+			},
+		},
+		ReserveBalances: map[string]common.BalanceEntry{
+			"UNKNOWN": {Valid: true},
+		},
+		PendingActivities: []common.ActivityRecord{
+			{Action: "set_rates", MiningStatus: "submitted"},
+			{Action: "set_rates", MiningStatus: "submitted"},
+			{Action: "withdraw", ExchangeStatus: "submitted"},
+		},
+	})
+	observeSnapshot(&common.AuthDataSnapshot{
+		ExchangeBalances: map[common.ExchangeID]common.EBalanceEntry{
+			"binance": {AvailableBalance: map[string]float64{"KNC": 90}},
+		},
+		PendingActivities: []common.ActivityRecord{
+			{Action: "set_rates", MiningStatus: "submitted"},
+		},
+	})
+	metrics := exposedMetrics(t)
+	for _, expected := range []string{
+		`reserve_pending_activities{action="set_rates",exchange_status="",mining_status="submitted"} 1`,
+		`reserve_exchange_balance{exchange="binance",token="KNC",type="available"} 90`,
+	} {
+		if !strings.Contains(metrics, expected) {
+			t.Errorf("expected metrics to contain %s, got:\n%s", expected, metrics)
+		}
+	}
+	// metrics of previous snapshots and unknown tokens are not exposed
+	for _, unexpected := range []string{`action="withdraw"`, `type="locked"`, `token="UNKNOWN"`} {
+		if strings.Contains(metrics, unexpected) {
+			t.Errorf("expected metrics not to contain %s, got:\n%s", unexpected, metrics)
+		}
+	}
+}
+
+func TestObserveMinedSetRate(t *testing.T) {
+	observeMinedSetRate(common.ActivityRecord{
+		Action:    "set_rates",
+		Result:    map[string]interface{}{"gasPrice": "20000000000"},
+		Timestamp: "1000",
+	}, 13000)
+	metrics := exposedMetrics(t)
+	for _, expected := range []string{
+		"reserve_set_rate_mining_latency_seconds_bucket{le=\"15\"} 1\n",
+		"reserve_set_rate_mining_latency_seconds_bucket{le=\"10\"} 0\n",
+		"reserve_set_rate_mining_latency_seconds_sum 12\n",
+		"reserve_set_rate_gas_price_gwei_sum 20\n",
+	} {
+		if !strings.Contains(metrics, expected) {
+			t.Errorf("expected metrics to contain %s, got:\n%s", expected, metrics)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+const (
+	defaultMaxPriceJump      = 0.3
+	defaultMaxPriceDeviation = 0.1
+	defaultMedianWindow      = 20
+	defaultMinExchanges      = 3
+	// minJumpSamples is the number of mid prices of an exchange needed
+	// before jumps are checked.
+	minJumpSamples = 3
+)
+
+// OrderbookValidationConfig is the configuration of order book validation,
+// read from the secret config file. Zero values use the defaults.
+type OrderbookValidationConfig struct {
+	// MaxJump is the maximum relative change of the mid price of an exchange
+	// from the median of its latest mid prices, default to 0.3.
+	MaxJump float64 `json:"orderbook_max_jump"`
+	// MaxDeviation is the maximum relative difference of the mid price of an
+	// exchange from the median of all exchanges, default to 0.1.
+	MaxDeviation float64 `json:"orderbook_max_deviation"`
+	// MedianWindow is the number of latest mid prices of an exchange the
+	// rolling median is computed over, default to 20.
+	MedianWindow int `json:"orderbook_median_window"`
+	// MinExchanges is the number of exchanges with valid order books of a
+	// pair needed to check deviation, default to 3.
+	MinExchanges int `json:"orderbook_min_exchanges"`
+}
+
+// GetOrderbookValidationConfigFromFile reads order book validation
+// configuration from a JSON file.
+func GetOrderbookValidationConfigFromFile(path string) (OrderbookValidationConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return OrderbookValidationConfig{}, err
+	}
+	result := OrderbookValidationConfig{}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// Validate returns an error if any limit is negative.
+func (self OrderbookValidationConfig) Validate() error {
+	if self.MaxJump < 0 || self.MaxDeviation < 0 {
+		return errors.New("orderbook max jump and max deviation must not be negative")
+	}
+	if self.MedianWindow < 0 || self.MinExchanges < 0 {
+		return errors.New("orderbook median window and min exchanges must not be negative")
+	}
+	return nil
+}
+
+// midPrices are the latest mid prices of a pair on an exchange.
+type midPrices struct {
+	prices []float64
+	next   int
+}
+
+func (self *midPrices) add(price float64, window int) {
+	if len(self.prices) < window {
+		self.prices = append(self.prices, price)
+		return
+	}
+	self.prices[self.next] = price
+	self.next = (self.next + 1) % window
+}
+
+// OrderbookValidator rejects order books which are empty, crossed, jump
+// from the rolling median of the exchange or deviate from other exchanges.
+type OrderbookValidator struct {
+	mu      sync.Mutex
+	config  OrderbookValidationConfig
+	history map[common.TokenPairID]map[common.ExchangeID]*midPrices
+}
+
+// NewOrderbookValidator creates a validator, zero values of config use the defaults.
+func NewOrderbookValidator(config OrderbookValidationConfig) *OrderbookValidator {
+	if config.MaxJump == 0 {
+		config.MaxJump = defaultMaxPriceJump
+	}
+	if config.MaxDeviation == 0 {
+		config.MaxDeviation = defaultMaxPriceDeviation
+	}
+	if config.MedianWindow == 0 {
+		config.MedianWindow = defaultMedianWindow
+	}
+	if config.MinExchanges == 0 {
+		config.MinExchanges = defaultMinExchanges
+	}
+	return &OrderbookValidator{
+		config:  config,
+		history: map[common.TokenPairID]map[common.ExchangeID]*midPrices{},
+	}
+}
+
+// Validate marks order books of all pairs invalid with a reason if they fail
+// any check. Books which are already invalid are not checked.
+func (self *OrderbookValidator) Validate(data map[common.TokenPairID]common.OnePrice) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	for pair, onePrice := range data {
+		mids := map[common.ExchangeID]float64{}
+		for exchange, price := range onePrice {
+			if !price.Valid {
+				continue
+			}
+			mid, reason, err := checkBook(price)
+			if err == nil {
+				reason, err = self.checkJump(pair, exchange, mid)
+			}
+			if err != nil {
+				onePrice[exchange] = invalidatePrice(pair, exchange, price, reason, err)
+				continue
+			}
+			mids[exchange] = mid
+		}
+		if len(mids) < self.config.MinExchanges {
+			continue
+		}
+		values := []float64{}
+		for _, mid := range mids {
+			values = append(values, mid)
+		}
+		median := medianOf(values)
+		for exchange, mid := range mids {
+			if change := relativeChange(mid, median); change > self.config.MaxDeviation {
+				err := fmt.Errorf("mid price %f deviates %.2f%% from median %f of all exchanges", mid, change*100, median)
+				onePrice[exchange] = invalidatePrice(pair, exchange, onePrice[exchange], common.PriceDeviationReason, err)
+			}
+		}
+	}
+}
+
+// checkJump records the mid price of an exchange and checks it against the
+// median of the latest ones. Rejected prices are recorded as well, so a
+// lasting move becomes the median after half of the window.
+func (self *OrderbookValidator) checkJump(pair common.TokenPairID, exchange common.ExchangeID, mid float64) (string, error) {
+	exchanges, ok := self.history[pair]
+	if !ok {
+		exchanges = map[common.ExchangeID]*midPrices{}
+		self.history[pair] = exchanges
+	}
+	history, ok := exchanges[exchange]
+	if !ok {
+		history = &midPrices{}
+		exchanges[exchange] = history
+	}
+	var median float64
+	if len(history.prices) >= minJumpSamples {
+		median = medianOf(history.prices)
+	}
+	history.add(mid, self.config.MedianWindow)
+	if median == 0 {
+		return "", nil
+	}
+	if change := relativeChange(mid, median); change > self.config.MaxJump {
+		return common.PriceJumpReason, fmt.Errorf("mid price %f jumps %.2f%% from rolling median %f", mid, change*100, median)
+	}
+	return "", nil
+}
+
+// checkBook returns the mid price of an order book, or the reason and error
+// if it is empty or crossed.
+func checkBook(price common.ExchangePrice) (float64, string, error) {
+	if len(price.Bids) == 0 || len(price.Asks) == 0 {
+		return 0, common.EmptyBookReason, fmt.Errorf("order book has %d bids and %d asks", len(price.Bids), len(price.Asks))
+	}
+	bestBid := price.Bids[0].Rate
+	for _, bid := range price.Bids {
+		bestBid = math.Max(bestBid, bid.Rate)
+	}
+	bestAsk := price.Asks[0].Rate
+	for _, ask := range price.Asks {
+		bestAsk = math.Min(bestAsk, ask.Rate)
+	}
+	if bestBid <= 0 || bestAsk <= 0 {
+		return 0, common.EmptyBookReason, fmt.Errorf("order book has non positive best bid %f or best ask %f", bestBid, bestAsk)
+	}
+	if bestBid >= bestAsk {
+		return 0, common.CrossedBookReason, fmt.Errorf("best bid %f is not lower than best ask %f", bestBid, bestAsk)
+	}
+	return (bestBid + bestAsk) / 2, "", nil
+}
+
+func invalidatePrice(pair common.TokenPairID, exchange common.ExchangeID, price common.ExchangePrice, reason string, err error) common.ExchangePrice {
+	log.Printf("Orderbook validation: %s on %s is invalid (%s): %s", pair, exchange, reason, err)
+	price.Valid = false
+	price.Reason = reason
+	price.Error = err.Error()
+	return price
+}
+
+// medianOf returns the median of prices, the slice is not modified.
+func medianOf(prices []float64) float64 {
+	values := append([]float64{}, prices...)
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+func relativeChange(value, reference float64) float64 {
+	return math.Abs(value-reference) / reference
+}
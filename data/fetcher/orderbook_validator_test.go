@@ -0,0 +1,136 @@
+package fetcher
+
+import (
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+func newTestExchangePrice(bid, ask float64) common.ExchangePrice {
+	return common.ExchangePrice{
+		Valid: true,
+		Bids:  []common.PriceEntry{common.NewPriceEntry(1, bid), common.NewPriceEntry(1, bid*0.99)},
+		Asks:  []common.PriceEntry{common.NewPriceEntry(1, ask), common.NewPriceEntry(1, ask*1.01)},
+	}
+}
+
+func TestOrderbookValidatorBooks(t *testing.T) {
+	var testCases = []struct {
+		msg    string
+		price  common.ExchangePrice
+		reason string
+	}{
+		{
+			msg:   "valid book",
+			price: newTestExchangePrice(0.0010, 0.0011),
+		},
+		{
+			msg:    "empty bids",
+			price:  common.ExchangePrice{Valid: true, Asks: []common.PriceEntry{common.NewPriceEntry(1, 0.0011)}},
+			reason: common.EmptyBookReason,
+		},
+		{
+			msg:    "empty asks",
+			price:  common.ExchangePrice{Valid: true, Bids: []common.PriceEntry{common.NewPriceEntry(1, 0.0010)}},
+			reason: common.EmptyBookReason,
+		},
+		{
+			msg:    "zero price",
+			price:  newTestExchangePrice(0, 0.0011),
+			reason: common.EmptyBookReason,
+		},
+		{
+			msg:    "crossed book",
+			price:  newTestExchangePrice(0.0012, 0.0011),
+			reason: common.CrossedBookReason,
+		},
+		{
+			msg:    "locked book",
+			price:  newTestExchangePrice(0.0011, 0.0011),
+			reason: common.CrossedBookReason,
+		},
+		{
+			msg:   "failed fetching is not checked",
+			price: common.ExchangePrice{Valid: false, Error: "timeout"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Logf("running test case for: %s", tc.msg)
+		validator := NewOrderbookValidator(OrderbookValidationConfig{})
+		data := map[common.TokenPairID]common.OnePrice{
+			"KNC-ETH": {"binance": tc.price},
+		}
+		validator.Validate(data)
+		result := data["KNC-ETH"]["binance"]
+		if result.Reason != tc.reason {
+			t.Errorf("expected reason %q, got %q", tc.reason, result.Reason)
+		}
+		if tc.reason != "" && (result.Valid || result.Error == "") {
+			t.Errorf("expected invalid book with error, got %+v", result)
+		}
+		if tc.reason == "" && result.Valid != tc.price.Valid {
+			t.Errorf("expected valid %v, got %+v", tc.price.Valid, result)
+		}
+	}
+}
+
+func TestOrderbookValidatorJump(t *testing.T) {
+	validator := NewOrderbookValidator(OrderbookValidationConfig{MedianWindow: 5})
+	validate := func(bid float64) common.ExchangePrice {
+		data := map[common.TokenPairID]common.OnePrice{
+			"KNC-ETH": {"binance": newTestExchangePrice(bid, bid*1.01)},
+		}
+		validator.Validate(data)
+		return data["KNC-ETH"]["binance"]
+	}
+	// jumps are not checked until there are enough prices
+	if result := validate(0.001); !result.Valid {
+		t.Fatalf("expected first price valid, got %+v", result)
+	}
+	validate(0.00101)
+	validate(0.00099)
+	if result := validate(0.0011); !result.Valid {
+		t.Errorf("expected 10%% move valid, got %+v", result)
+	}
+	result := validate(0.01)
+	if result.Valid || result.Reason != common.PriceJumpReason {
+		t.Errorf("expected 10x price rejected as jump, got %+v", result)
+	}
+	// a lasting move becomes the median after half of the window
+	validate(0.01)
+	validate(0.01)
+	if result = validate(0.01); !result.Valid {
+		t.Errorf("expected lasting move valid, got %+v", result)
+	}
+}
+
+func TestOrderbookValidatorDeviation(t *testing.T) {
+	validator := NewOrderbookValidator(OrderbookValidationConfig{})
+	data := map[common.TokenPairID]common.OnePrice{
+		"KNC-ETH": {
+			"binance": newTestExchangePrice(0.0010, 0.00101),
+			"huobi":   newTestExchangePrice(0.00101, 0.00102),
+			"bittrex": newTestExchangePrice(0.0015, 0.00151),
+		},
+		// deviation is not checked with less than 3 exchanges
+		"OMG-ETH": {
+			"binance": newTestExchangePrice(0.01, 0.0101),
+			"huobi":   newTestExchangePrice(0.02, 0.0201),
+		},
+	}
+	validator.Validate(data)
+	for exchange, price := range data["KNC-ETH"] {
+		if exchange == "bittrex" {
+			if price.Valid || price.Reason != common.PriceDeviationReason {
+				t.Errorf("expected bittrex rejected for deviation, got %+v", price)
+			}
+		} else if !price.Valid {
+			t.Errorf("expected %s valid, got %+v", exchange, price)
+		}
+	}
+	for exchange, price := range data["OMG-ETH"] {
+		if !price.Valid {
+			t.Errorf("expected %s valid, got %+v", exchange, price)
+		}
+	}
+}
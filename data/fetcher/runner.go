@@ -20,6 +20,24 @@ type FetcherRunner interface {
 	GetBlockTicker() <-chan time.Time
 }
 
+// NewHead is a new block of the chain.
+type NewHead struct {
+	Block uint64
+	// Time is the timestamp of the block.
+	Time time.Time
+}
+
+// HeadRunner is a FetcherRunner driven by new blocks of the chain. Fetcher
+// fetches the current block and rates on every new head, instead of using
+// the block and rate tickers, and the auth data ticker also ticks on new
+// heads so reserve balances are fetched every block.
+type HeadRunner interface {
+	FetcherRunner
+	// GetNewHeadTicker returns the channel of new heads, only the latest head
+	// is kept if fetcher is slower than the chain.
+	GetNewHeadTicker() <-chan NewHead
+}
+
 // TickerRunner is an implementation of FetcherRunner that use simple time ticker.
 type TickerRunner struct {
 	oduration          time.Duration
@@ -12,6 +12,10 @@ type Storage interface {
 
 	GetPendingActivities() ([]common.ActivityRecord, error)
 	UpdateActivity(id common.ActivityID, act common.ActivityRecord) error
+	StoreGasSpend(record common.GasSpendRecord) error
+	StorePendingGasSpend(job common.PendingGasSpend) error
+	GetPendingGasSpends() ([]common.PendingGasSpend, error)
+	RemovePendingGasSpend(txHash string) error
 
 	GetExchangeStatus() (common.ExchangesStatus, error)
 	UpdateExchangeStatus(data common.ExchangesStatus) error
@@ -1,10 +1,9 @@
 package data
 
 import (
-	"fmt"
 	"log"
 	"os"
-	"time"
+	"path/filepath"
 
 	"github.com/KyberNetwork/reserve-data/common"
 	"github.com/KyberNetwork/reserve-data/common/archive"
@@ -18,6 +17,8 @@ type ReserveData struct {
 	storageController datapruner.StorageController
 	globalStorage     GlobalStorage
 	exchanges         []common.Exchange
+	// archivedAuthData serves auth data pruned from storage, nil if there is no archive
+	archivedAuthData *archivedAuthData
 }
 
 func (self ReserveData) CurrentGoldInfoVersion(timepoint uint64) (common.Version, error) {
@@ -71,6 +72,12 @@ func (self ReserveData) GetOnePrice(pairID common.TokenPairID, timepoint uint64)
 	}
 }
 
+// GetPriceHistory returns the price time series of a pair on an exchange, or on
+// all exchanges if it is empty, at a resolution between fromTime and toTime.
+func (self ReserveData) GetPriceHistory(pairID common.TokenPairID, exchange common.ExchangeID, resolution string, fromTime, toTime uint64) (common.PriceHistory, error) {
+	return self.storage.GetPriceHistory(pairID, exchange, resolution, fromTime, toTime)
+}
+
 func (self ReserveData) CurrentAuthDataVersion(timepoint uint64) (common.Version, error) {
 	return self.storage.CurrentAuthDataVersion(timepoint)
 }
@@ -78,30 +85,39 @@ func (self ReserveData) CurrentAuthDataVersion(timepoint uint64) (common.Version
 func (self ReserveData) GetAuthData(timepoint uint64) (common.AuthDataResponse, error) {
 	timestamp := common.GetTimestamp()
 	version, err := self.storage.CurrentAuthDataVersion(timepoint)
+	var data common.AuthDataSnapshot
 	if err != nil {
-		return common.AuthDataResponse{}, err
-	} else {
-		result := common.AuthDataResponse{}
-		data, err := self.storage.GetAuthData(version)
-		returnTime := common.GetTimestamp()
-		result.Version = version
-		result.Timestamp = timestamp
-		result.ReturnTime = returnTime
-		result.Data.Valid = data.Valid
-		result.Data.Error = data.Error
-		result.Data.Timestamp = data.Timestamp
-		result.Data.ReturnTime = data.ReturnTime
-		result.Data.ExchangeBalances = data.ExchangeBalances
-		result.Data.PendingActivities = data.PendingActivities
-		result.Data.Block = data.Block
-		result.Data.ReserveBalances = map[string]common.BalanceResponse{}
-		for tokenID, balance := range data.ReserveBalances {
-			result.Data.ReserveBalances[tokenID] = balance.ToBalanceResponse(
-				common.MustGetInternalToken(tokenID).Decimal,
-			)
+		// auth data older than the retention window is only available in archive,
+		// other errors of storage are returned as is
+		if self.archivedAuthData == nil || !self.archivedAuthData.Archived(timepoint, common.GetTimepoint()) {
+			return common.AuthDataResponse{}, err
 		}
-		return result, err
+		log.Printf("Auth data at %d is not in storage (%s), looking up archive", timepoint, err)
+		if data, version, err = self.archivedAuthData.GetAuthData(timepoint); err != nil {
+			return common.AuthDataResponse{}, err
+		}
+	} else {
+		data, err = self.storage.GetAuthData(version)
 	}
+	result := common.AuthDataResponse{}
+	returnTime := common.GetTimestamp()
+	result.Version = version
+	result.Timestamp = timestamp
+	result.ReturnTime = returnTime
+	result.Data.Valid = data.Valid
+	result.Data.Error = data.Error
+	result.Data.Timestamp = data.Timestamp
+	result.Data.ReturnTime = data.ReturnTime
+	result.Data.ExchangeBalances = data.ExchangeBalances
+	result.Data.PendingActivities = data.PendingActivities
+	result.Data.Block = data.Block
+	result.Data.ReserveBalances = map[string]common.BalanceResponse{}
+	for tokenID, balance := range data.ReserveBalances {
+		result.Data.ReserveBalances[tokenID] = balance.ToBalanceResponse(
+			common.MustGetInternalToken(tokenID).Decimal,
+		)
+	}
+	return result, err
 }
 
 func isDuplicated(oldData, newData map[string]common.RateResponse) bool {
@@ -234,6 +250,26 @@ func (self ReserveData) GetRecords(fromTime, toTime uint64) ([]common.ActivityRe
 	return self.storage.GetAllRecords(fromTime, toTime)
 }
 
+// GetActivityPage returns at most limit activities selected by filter, which
+// are older than cursor if it is not nil, the latest first.
+func (self ReserveData) GetActivityPage(filter common.ActivityFilter, cursor *common.ActivityID, limit int) (common.ActivityPage, error) {
+	return self.storage.GetActivityPage(filter, cursor, limit)
+}
+
+// GetGasSpend returns the gas spent by operators in [fromTime, toTime] with daily rollups.
+func (self ReserveData) GetGasSpend(fromTime, toTime uint64) (common.GasSpendReport, error) {
+	records, err := self.storage.GetGasSpend(fromTime, toTime)
+	if err != nil {
+		return common.GasSpendReport{}, err
+	}
+	return common.NewGasSpendReport(records), nil
+}
+
+// GetBalanceStatus returns the latest operator and reserve balances checked against thresholds.
+func (self ReserveData) GetBalanceStatus() (common.BalanceStatus, error) {
+	return self.storage.GetBalanceStatus()
+}
+
 func (self ReserveData) GetPendingActivities() ([]common.ActivityRecord, error) {
 	return self.storage.GetPendingActivities()
 }
@@ -259,7 +295,7 @@ func (self ReserveData) ControlAuthDataSize() error {
 		t := <-self.storageController.Runner.GetAuthBucketTicker()
 		timepoint := common.TimeToTimepoint(t)
 		log.Printf("DataPruner: got signal in AuthData controller channel with timestamp %d", common.TimeToTimepoint(t))
-		fileName := fmt.Sprintf("./exported/ExpiredAuthData_at_%s", time.Unix(int64(timepoint/1000), 0).UTC())
+		fileName := filepath.Join("./exported", expiredAuthDataFileName(timepoint))
 		nRecord, err := self.storage.ExportExpiredAuthData(common.TimeToTimepoint(t), fileName)
 		if err != nil {
 			log.Printf("ERROR: DataPruner export AuthData operation failed: %s", err)
@@ -328,9 +364,37 @@ func (self ReserveData) RunStorageController() error {
 			log.Printf("Control auth data size error: %s", err.Error())
 		}
 	}()
+	go self.ControlPriceHistorySize()
+	go self.ControlAuditLogSize()
 	return nil
 }
 
+// ControlPriceHistorySize removes price history out of retention every tick
+// of the price history ticker.
+func (self ReserveData) ControlPriceHistorySize() {
+	for t := range self.storageController.Runner.GetPriceHistoryTicker() {
+		nPruned, err := self.storage.PruneExpiredPriceHistory(common.TimeToTimepoint(t))
+		if err != nil {
+			log.Printf("DataPruner: Can not prune price history (%s)", err)
+			continue
+		}
+		log.Printf("DataPruner: pruned %d expired records from price history", nPruned)
+	}
+}
+
+// ControlAuditLogSize removes audit records out of retention every tick of
+// the audit log ticker.
+func (self ReserveData) ControlAuditLogSize() {
+	for t := range self.storageController.Runner.GetAuditLogTicker() {
+		nPruned, err := self.storage.PruneExpiredAuditRecords(common.TimeToTimepoint(t))
+		if err != nil {
+			log.Printf("DataPruner: Can not prune audit log (%s)", err)
+			continue
+		}
+		log.Printf("DataPruner: pruned %d expired records from audit log", nPruned)
+	}
+}
+
 //NewReserveData initiate a new reserve instance
 func NewReserveData(storage Storage,
 	fetcher Fetcher, storageControllerRunner datapruner.StorageControllerRunner,
@@ -340,5 +404,12 @@ func NewReserveData(storage Storage,
 	if err != nil {
 		panic(err)
 	}
-	return &ReserveData{storage, fetcher, storageController, globalStorage, exchanges}
+	var archived *archivedAuthData
+	if arch != nil {
+		archived = newArchivedAuthData(
+			arch, storageController.ExpiredAuthDataPath, storage.AuthDataRetention(),
+			archivedAuthDataCacheDir, maxCachedArchivedAuthDataFile,
+		)
+	}
+	return &ReserveData{storage, fetcher, storageController, globalStorage, exchanges, archived}
 }
@@ -9,6 +9,16 @@ type Storage interface {
 	CurrentPriceVersion(timepoint uint64) (common.Version, error)
 	GetAllPrices(common.Version) (common.AllPriceEntry, error)
 	GetOnePrice(common.TokenPairID, common.Version) (common.OnePrice, error)
+	// GetPriceHistory returns the price time series of a pair on an exchange, or
+	// on all exchanges if it is empty, at a resolution between fromTime and toTime.
+	GetPriceHistory(pair common.TokenPairID, exchange common.ExchangeID, resolution string, fromTime, toTime uint64) (common.PriceHistory, error)
+	// PruneExpiredPriceHistory removes price history out of retention, the
+	// number of removed records is returned.
+	PruneExpiredPriceHistory(timepoint uint64) (uint64, error)
+	// PruneExpiredAuditRecords removes audit records out of retention except
+	// the last one, which the next record is chained to. The number of
+	// removed records is returned.
+	PruneExpiredAuditRecords(timepoint uint64) (uint64, error)
 
 	CurrentAuthDataVersion(timepoint uint64) (common.Version, error)
 	GetAuthData(common.Version) (common.AuthDataSnapshot, error)
@@ -17,12 +27,19 @@ type Storage interface {
 	//Return: Number of records exported (uint64) and error
 	ExportExpiredAuthData(timepoint uint64, filePath string) (uint64, error)
 	PruneExpiredAuthData(timepoint uint64) (uint64, error)
+	// AuthDataRetention is how long auth data is kept before it is exported and pruned.
+	AuthDataRetention() uint64
 	CurrentRateVersion(timepoint uint64) (common.Version, error)
 	GetRate(common.Version) (common.AllRateEntry, error)
 	GetRates(fromTime, toTime uint64) ([]common.AllRateEntry, error)
 
 	GetAllRecords(fromTime, toTime uint64) ([]common.ActivityRecord, error)
+	// GetActivityPage returns at most limit activities selected by filter,
+	// which are older than cursor if it is not nil, the latest first.
+	GetActivityPage(filter common.ActivityFilter, cursor *common.ActivityID, limit int) (common.ActivityPage, error)
 	GetPendingActivities() ([]common.ActivityRecord, error)
+	GetGasSpend(fromTime, toTime uint64) ([]common.GasSpendRecord, error)
+	GetBalanceStatus() (common.BalanceStatus, error)
 
 	GetExchangeStatus() (common.ExchangesStatus, error)
 	UpdateExchangeStatus(data common.ExchangesStatus) error
@@ -0,0 +1,315 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/KyberNetwork/reserve-data/boltutil"
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/boltdb/bolt"
+)
+
+// Auth data snapshots are stored as keyframes, the full snapshot JSON, and
+// deltas, a JSON merge patch (RFC 7396) from the latest keyframe. Most
+// fields of consecutive snapshots are the same, so a delta is a small
+// fraction of a snapshot.
+const (
+	// AUTH_DATA_KEYFRAME_INTERVAL is the max time between a delta and its keyframe.
+	AUTH_DATA_KEYFRAME_INTERVAL uint64 = 3600000 // 1 hour in milisec
+	// authDataMaxDeltaRatio is the max size of a delta relative to the full
+	// snapshot, a keyframe is written instead of larger deltas.
+	authDataMaxDeltaRatio = 0.5
+)
+
+// authDataDeltaPrefix starts every delta value, snapshot values start with {"Valid".
+var authDataDeltaPrefix = []byte(`{"delta_base":`)
+
+// authDataDelta is the stored form of a snapshot which is not a keyframe.
+type authDataDelta struct {
+	// Base is the timepoint of the keyframe the patch applies to.
+	Base  uint64          `json:"delta_base"`
+	Patch json.RawMessage `json:"patch"`
+}
+
+// authDataStore is the access to stored auth data values of a storage
+// backend, used to encode and decode deltas.
+type authDataStore interface {
+	// getAuthData returns the stored value at timepoint, nil if there is none.
+	getAuthData(timepoint uint64) ([]byte, error)
+	// lastAuthData returns the latest stored value, nil if there is none.
+	lastAuthData() (uint64, []byte, error)
+	putAuthData(timepoint uint64, value []byte) error
+}
+
+// decodeJSON decodes data keeping numbers as json.Number, so integers like
+// activity timepoints don't lose precision.
+func decodeJSON(data []byte) (interface{}, error) {
+	var result interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&result)
+	return result, err
+}
+
+// cachedAuthDataStore caches the last value got from the store, which is
+// the keyframe of consecutive deltas.
+type cachedAuthDataStore struct {
+	authDataStore
+	timepoint uint64
+	value     []byte
+}
+
+func (self *cachedAuthDataStore) getAuthData(timepoint uint64) ([]byte, error) {
+	if self.value != nil && self.timepoint == timepoint {
+		return self.value, nil
+	}
+	value, err := self.authDataStore.getAuthData(timepoint)
+	if err != nil || value == nil {
+		return value, err
+	}
+	self.timepoint = timepoint
+	self.value = value
+	return value, nil
+}
+
+func isAuthDataDelta(value []byte) bool {
+	return bytes.HasPrefix(value, authDataDeltaPrefix)
+}
+
+// mergePatch returns the merge patch which transforms from into to, both
+// are decoded JSON values. Removed object members are set to null, arrays
+// and other values are replaced as a whole.
+func mergePatch(from, to interface{}) interface{} {
+	fromObj, fromOK := from.(map[string]interface{})
+	toObj, toOK := to.(map[string]interface{})
+	if !fromOK || !toOK {
+		return to
+	}
+	patch := map[string]interface{}{}
+	for k, toValue := range toObj {
+		fromValue, ok := fromObj[k]
+		if !ok {
+			patch[k] = toValue
+			continue
+		}
+		if reflect.DeepEqual(fromValue, toValue) {
+			continue
+		}
+		patch[k] = mergePatch(fromValue, toValue)
+	}
+	for k := range fromObj {
+		if _, ok := toObj[k]; !ok {
+			patch[k] = nil
+		}
+	}
+	return patch
+}
+
+// applyMergePatch applies the merge patch to target and returns the result,
+// target may be modified.
+func applyMergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for k, v := range patchObj {
+		if v == nil {
+			delete(targetObj, k)
+			continue
+		}
+		targetObj[k] = applyMergePatch(targetObj[k], v)
+	}
+	return targetObj
+}
+
+// encodeAuthData returns the stored form of the snapshot JSON at timepoint:
+// a delta from the keyframe at base, or the snapshot itself if the delta
+// would be too large or the keyframe is too old.
+func encodeAuthData(snapshot []byte, timepoint, base uint64, keyframe []byte) ([]byte, error) {
+	if keyframe == nil || timepoint <= base || timepoint-base >= AUTH_DATA_KEYFRAME_INTERVAL {
+		return snapshot, nil
+	}
+	from, err := decodeJSON(keyframe)
+	if err != nil {
+		return nil, err
+	}
+	to, err := decodeJSON(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := json.Marshal(mergePatch(from, to))
+	if err != nil {
+		return nil, err
+	}
+	delta, err := json.Marshal(authDataDelta{Base: base, Patch: patch})
+	if err != nil {
+		return nil, err
+	}
+	if float64(len(delta)) > authDataMaxDeltaRatio*float64(len(snapshot)) {
+		return snapshot, nil
+	}
+	return delta, nil
+}
+
+// keyframeOf returns the timepoint and value of the keyframe of the stored
+// value at timepoint.
+func keyframeOf(store authDataStore, timepoint uint64, value []byte) (uint64, []byte, error) {
+	if !isAuthDataDelta(value) {
+		return timepoint, value, nil
+	}
+	var delta authDataDelta
+	if err := json.Unmarshal(value, &delta); err != nil {
+		return 0, nil, err
+	}
+	keyframe, err := store.getAuthData(delta.Base)
+	if err != nil {
+		return 0, nil, err
+	}
+	if keyframe == nil || isAuthDataDelta(keyframe) {
+		return 0, nil, fmt.Errorf("keyframe %d of auth data %d doesn't exist", delta.Base, timepoint)
+	}
+	return delta.Base, keyframe, nil
+}
+
+// storeAuthData stores the snapshot at timepoint as a delta from the keyframe
+// of the latest stored snapshot if possible.
+func storeAuthData(store authDataStore, data *common.AuthDataSnapshot, timepoint uint64) error {
+	snapshot, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	lastTimepoint, last, err := store.lastAuthData()
+	if err != nil {
+		return err
+	}
+	var (
+		base     uint64
+		keyframe []byte
+	)
+	// a snapshot replacing or inserted before the latest one is always a keyframe,
+	// so keyframes of existing deltas are never changed
+	if last != nil && timepoint > lastTimepoint {
+		if base, keyframe, err = keyframeOf(store, lastTimepoint, last); err != nil {
+			return err
+		}
+	}
+	value, err := encodeAuthData(snapshot, timepoint, base, keyframe)
+	if err != nil {
+		return err
+	}
+	return store.putAuthData(timepoint, value)
+}
+
+// decodeAuthData returns the snapshot JSON of the stored value at timepoint.
+func decodeAuthData(store authDataStore, timepoint uint64, value []byte) ([]byte, error) {
+	if !isAuthDataDelta(value) {
+		return value, nil
+	}
+	var delta authDataDelta
+	if err := json.Unmarshal(value, &delta); err != nil {
+		return nil, err
+	}
+	_, keyframe, err := keyframeOf(store, timepoint, value)
+	if err != nil {
+		return nil, err
+	}
+	target, err := decodeJSON(keyframe)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := decodeJSON(delta.Patch)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(applyMergePatch(target, patch))
+}
+
+// loadAuthData decodes the snapshot of the stored value at timepoint.
+func loadAuthData(store authDataStore, timepoint uint64, value []byte) (common.AuthDataSnapshot, error) {
+	result := common.AuthDataSnapshot{}
+	snapshot, err := decodeAuthData(store, timepoint, value)
+	if err != nil {
+		return result, err
+	}
+	err = json.Unmarshal(snapshot, &result)
+	return result, err
+}
+
+// authDataEncoder re-encodes stored snapshots in timepoint order, keeping
+// the current keyframe to decide whether a snapshot is stored as a delta.
+type authDataEncoder struct {
+	base     uint64
+	keyframe []byte
+}
+
+// next returns the stored form of the snapshot JSON at timepoint, the
+// snapshots must be given in increasing timepoint order.
+func (self *authDataEncoder) next(timepoint uint64, snapshot []byte) ([]byte, error) {
+	value, err := encodeAuthData(snapshot, timepoint, self.base, self.keyframe)
+	if err != nil {
+		return nil, err
+	}
+	if !isAuthDataDelta(value) {
+		self.base = timepoint
+		self.keyframe = value
+	}
+	return value, nil
+}
+
+// storedAuthData is a stored auth data value.
+type storedAuthData struct {
+	timepoint uint64
+	value     []byte
+}
+
+// rebaseAuthData re-encodes the records, the first snapshots kept after
+// pruning up to the next keyframe, so they don't depend on pruned keyframes.
+// The first record becomes a keyframe.
+func rebaseAuthData(store authDataStore, records []storedAuthData) ([]storedAuthData, error) {
+	result := []storedAuthData{}
+	encoder := &authDataEncoder{}
+	for _, record := range records {
+		snapshot, err := decodeAuthData(store, record.timepoint, record.value)
+		if err != nil {
+			return nil, err
+		}
+		value, err := encoder.next(record.timepoint, snapshot)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, storedAuthData{record.timepoint, value})
+	}
+	return result, nil
+}
+
+// boltAuthDataStore is the auth data bucket of a bolt transaction.
+type boltAuthDataStore struct {
+	b *bolt.Bucket
+}
+
+func (self boltAuthDataStore) getAuthData(timepoint uint64) ([]byte, error) {
+	v := self.b.Get(boltutil.Uint64ToBytes(timepoint))
+	if v == nil {
+		return nil, nil
+	}
+	// values of bolt are only valid in the transaction, and may be reused after writes
+	return append([]byte{}, v...), nil
+}
+
+func (self boltAuthDataStore) lastAuthData() (uint64, []byte, error) {
+	k, v := self.b.Cursor().Last()
+	if k == nil {
+		return 0, nil, nil
+	}
+	return boltutil.BytesToUint64(k), append([]byte{}, v...), nil
+}
+
+func (self boltAuthDataStore) putAuthData(timepoint uint64, value []byte) error {
+	return self.b.Put(boltutil.Uint64ToBytes(timepoint), value)
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"reflect"
 	"strconv"
@@ -37,10 +38,22 @@ const (
 	EXCHANGE_NOTIFICATIONS             string = "exchange_notifications"
 	MAX_NUMBER_VERSION                 int    = 1000
 	MAX_GET_RATES_PERIOD               uint64 = 86400000      //1 days in milisec
-	AUTH_DATA_EXPIRED_DURATION         uint64 = 10 * 86400000 //10day in milisec
+	AUTH_DATA_EXPIRED_DURATION         uint64 = 90 * 86400000 //90day in milisec, snapshots are stored as deltas
+	AUDIT_LOG_RETENTION                uint64 = 365 * 86400000 //1 year in milisec
 	STABLE_TOKEN_PARAMS_BUCKET         string = "stable-token-params"
 	PENDING_STABLE_TOKEN_PARAMS_BUCKET string = "pending-stable-token-params"
 	GOLD_BUCKET                        string = "gold_feeds"
+	GAS_SPEND_BUCKET                   string = "gas_spend"
+	GAS_SPEND_TX_BUCKET                string = "gas_spend_tx"
+	PENDING_GAS_SPEND_BUCKET           string = "pending_gas_spend"
+	MAX_GET_GAS_SPEND_PERIOD           uint64 = 31 * 86400000 //31 days in milisec
+	BALANCE_THRESHOLDS_BUCKET          string = "balance_thresholds"
+	BALANCE_STATUS_BUCKET              string = "balance_status"
+	API_KEY_BUCKET                     string = "api_keys"
+	AUDIT_LOG_BUCKET                   string = "audit_log"
+	ACTIVITY_INDEX_BUCKET              string = "activity_index"
+	CONFIG_PROPOSAL_BUCKET             string = "config_proposals"
+	CONFIG_VERSION_BUCKET              string = "config_versions"
 
 	// PENDING_TARGET_QUANTITY_V2 constant for bucket name for pending target quantity v2
 	PENDING_TARGET_QUANTITY_V2 string = "pending_target_qty_v2"
@@ -57,13 +70,22 @@ const (
 	PENDING_REBALANCE_QUADRATIC = "pending_rebalance_quadratic"
 	// REBALANCE_QUADRATIC stores rebalance quadratic equation
 	REBALANCE_QUADRATIC = "rebalance_quadratic"
+
+	// PENDING_STEP_FUNCTIONS stores pending pricing contract step functions
+	PENDING_STEP_FUNCTIONS = "pending_step_functions"
+	// STEP_FUNCTIONS stores the history of confirmed step functions
+	STEP_FUNCTIONS = "step_functions"
+	// PENDING_STEP_FUNCTION_SUBMISSIONS stores activities of step functions of
+	// the pending step functions already submitted to pricing contract
+	PENDING_STEP_FUNCTION_SUBMISSIONS = "pending_step_function_submissions"
 )
 
 // BoltStorage is the storage implementation of data.Storage interface
 // that uses BoltDB as its storage engine.
 type BoltStorage struct {
-	mu sync.RWMutex
-	db *bolt.DB
+	mu           sync.RWMutex
+	db           *bolt.DB
+	priceHistory PriceHistoryConfig
 }
 
 // NewBoltStorage creates a new BoltStorage instance with the database
@@ -72,7 +94,7 @@ func NewBoltStorage(path string) (*BoltStorage, error) {
 	// init instance
 	var err error
 	var db *bolt.DB
-	db, err = bolt.Open(path, 0600, nil)
+	db, err = boltutil.Open(path, BoltMigrations)
 	if err != nil {
 		return nil, err
 	}
@@ -158,14 +180,60 @@ func NewBoltStorage(path string) (*BoltStorage, error) {
 		if _, cErr := tx.CreateBucketIfNotExists([]byte(REBALANCE_QUADRATIC)); cErr != nil {
 			return cErr
 		}
+
+		if _, cErr := tx.CreateBucketIfNotExists([]byte(PENDING_STEP_FUNCTIONS)); cErr != nil {
+			return cErr
+		}
+		if _, cErr := tx.CreateBucketIfNotExists([]byte(STEP_FUNCTIONS)); cErr != nil {
+			return cErr
+		}
+		if _, cErr := tx.CreateBucketIfNotExists([]byte(PENDING_STEP_FUNCTION_SUBMISSIONS)); cErr != nil {
+			return cErr
+		}
+
+		if _, cErr := tx.CreateBucketIfNotExists([]byte(GAS_SPEND_BUCKET)); cErr != nil {
+			return cErr
+		}
+		if _, cErr := tx.CreateBucketIfNotExists([]byte(GAS_SPEND_TX_BUCKET)); cErr != nil {
+			return cErr
+		}
+		if _, cErr := tx.CreateBucketIfNotExists([]byte(PENDING_GAS_SPEND_BUCKET)); cErr != nil {
+			return cErr
+		}
+
+		if _, cErr := tx.CreateBucketIfNotExists([]byte(BALANCE_THRESHOLDS_BUCKET)); cErr != nil {
+			return cErr
+		}
+		if _, cErr := tx.CreateBucketIfNotExists([]byte(BALANCE_STATUS_BUCKET)); cErr != nil {
+			return cErr
+		}
+		if _, cErr := tx.CreateBucketIfNotExists([]byte(PRICE_HISTORY_BUCKET)); cErr != nil {
+			return cErr
+		}
+		if _, cErr := tx.CreateBucketIfNotExists([]byte(API_KEY_BUCKET)); cErr != nil {
+			return cErr
+		}
+		if _, cErr := tx.CreateBucketIfNotExists([]byte(AUDIT_LOG_BUCKET)); cErr != nil {
+			return cErr
+		}
+		if _, cErr := tx.CreateBucketIfNotExists([]byte(ACTIVITY_INDEX_BUCKET)); cErr != nil {
+			return cErr
+		}
+		if _, cErr := tx.CreateBucketIfNotExists([]byte(CONFIG_PROPOSAL_BUCKET)); cErr != nil {
+			return cErr
+		}
+		if _, cErr := tx.CreateBucketIfNotExists([]byte(CONFIG_VERSION_BUCKET)); cErr != nil {
+			return cErr
+		}
 		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
 	storage := &BoltStorage{
-		mu: sync.RWMutex{},
-		db: db,
+		mu:           sync.RWMutex{},
+		db:           db,
+		priceHistory: DefaultPriceHistoryConfig(),
 	}
 	return storage, nil
 }
@@ -238,6 +306,11 @@ func (self *BoltStorage) StoreGoldInfo(data common.GoldData) error {
 	return err
 }
 
+//AuthDataRetention returns how long auth data is kept before it expires
+func (self *BoltStorage) AuthDataRetention() uint64 {
+	return AUTH_DATA_EXPIRED_DURATION
+}
+
 func (self *BoltStorage) ExportExpiredAuthData(currentTime uint64, fileName string) (nRecord uint64, err error) {
 	expiredTimestampByte := boltutil.Uint64ToBytes(currentTime - AUTH_DATA_EXPIRED_DURATION)
 	outFile, err := os.Create(fileName)
@@ -250,15 +323,16 @@ func (self *BoltStorage) ExportExpiredAuthData(currentTime uint64, fileName stri
 		}
 	}()
 
-	err = self.db.Update(func(tx *bolt.Tx) error {
+	err = self.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(AUTH_DATA_BUCKET))
+		store := boltAuthDataStore{b}
 		c := b.Cursor()
 
 		for k, v := c.First(); k != nil && bytes.Compare(k, expiredTimestampByte) <= 0; k, v = c.Next() {
 			timestamp := boltutil.BytesToUint64(k)
 
-			temp := common.AuthDataSnapshot{}
-			if uErr := json.Unmarshal(v, &temp); uErr != nil {
+			temp, uErr := loadAuthData(store, timestamp, v)
+			if uErr != nil {
 				return uErr
 			}
 			record := common.NewAuthDataRecord(
@@ -275,9 +349,6 @@ func (self *BoltStorage) ExportExpiredAuthData(currentTime uint64, fileName stri
 				return err
 			}
 			nRecord++
-			if err != nil {
-				return err
-			}
 		}
 		return nil
 	})
@@ -285,13 +356,37 @@ func (self *BoltStorage) ExportExpiredAuthData(currentTime uint64, fileName stri
 	return nRecord, err
 }
 
+// PruneExpiredAuthData removes expired auth data. The first remaining
+// snapshots depending on a removed keyframe are re-encoded from a new
+// keyframe before.
 func (self *BoltStorage) PruneExpiredAuthData(currentTime uint64) (nRecord uint64, err error) {
 	expiredTimestampByte := boltutil.Uint64ToBytes(currentTime - AUTH_DATA_EXPIRED_DURATION)
 
 	err = self.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(AUTH_DATA_BUCKET))
+		store := boltAuthDataStore{b}
 		c := b.Cursor()
-		for k, _ := c.First(); k != nil && bytes.Compare(k, expiredTimestampByte) <= 0; k, _ = c.Next() {
+		kept := []storedAuthData{}
+		for k, v := c.Seek(expiredTimestampByte); k != nil; k, v = c.Next() {
+			if bytes.Equal(k, expiredTimestampByte) {
+				continue
+			}
+			if !isAuthDataDelta(v) {
+				break
+			}
+			kept = append(kept, storedAuthData{boltutil.BytesToUint64(k), append([]byte{}, v...)})
+		}
+		rebased, rErr := rebaseAuthData(store, kept)
+		if rErr != nil {
+			return rErr
+		}
+		for _, record := range rebased {
+			if pErr := store.putAuthData(record.timepoint, record.value); pErr != nil {
+				return pErr
+			}
+		}
+		c = b.Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k, expiredTimestampByte) <= 0; k, _ = c.First() {
 			err = b.Delete(k)
 			if err != nil {
 				return err
@@ -407,11 +502,101 @@ func (self *BoltStorage) StorePrice(data common.AllPriceEntry, timepoint uint64)
 		if dataJSON, uErr = json.Marshal(data); uErr != nil {
 			return uErr
 		}
-		return b.Put(boltutil.Uint64ToBytes(timepoint), dataJSON)
+		if uErr = b.Put(boltutil.Uint64ToBytes(timepoint), dataJSON); uErr != nil {
+			return uErr
+		}
+		return recordPriceHistory(boltPriceHistoryStore{tx.Bucket([]byte(PRICE_HISTORY_BUCKET))}, data, timepoint, self.priceHistory.DepthSizes)
 	})
 	return err
 }
 
+//SetPriceHistoryConfig sets retention and depth sizes of price history,
+//it must be called before prices are stored.
+func (self *BoltStorage) SetPriceHistoryConfig(conf PriceHistoryConfig) {
+	self.priceHistory = conf
+}
+
+//GetPriceHistory returns price history of the pair on the exchange, or on all
+//exchanges if it is empty, at the resolution between fromTime and toTime.
+func (self *BoltStorage) GetPriceHistory(pair common.TokenPairID, exchange common.ExchangeID, resolution string, fromTime, toTime uint64) (common.PriceHistory, error) {
+	result := common.PriceHistory{Pair: pair, Resolution: resolution, Data: map[common.ExchangeID][]common.PriceCandle{}}
+	if err := validatePriceHistoryQuery(resolution, fromTime, toTime); err != nil {
+		return result, err
+	}
+	err := self.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(PRICE_HISTORY_BUCKET)).Bucket([]byte(resolution))
+		if b == nil {
+			return nil
+		}
+		if b = b.Bucket([]byte(pair)); b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			if v != nil || (exchange != "" && common.ExchangeID(k) != exchange) {
+				return nil
+			}
+			candles := []common.PriceCandle{}
+			c := b.Bucket(k).Cursor()
+			max := boltutil.Uint64ToBytes(toTime)
+			for tk, tv := c.Seek(boltutil.Uint64ToBytes(fromTime)); tk != nil && bytes.Compare(tk, max) <= 0; tk, tv = c.Next() {
+				candle := common.PriceCandle{}
+				if uErr := json.Unmarshal(tv, &candle); uErr != nil {
+					return uErr
+				}
+				candles = append(candles, candle)
+			}
+			result.Data[common.ExchangeID(k)] = candles
+			return nil
+		})
+	})
+	return result, err
+}
+
+//PruneExpiredPriceHistory removes price history older than the retention of
+//its resolution and returns the number of removed records.
+func (self *BoltStorage) PruneExpiredPriceHistory(currentTime uint64) (uint64, error) {
+	var count uint64
+	err := self.db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(PRICE_HISTORY_BUCKET))
+		for _, resolution := range priceHistoryResolutions {
+			retention := self.priceHistory.Retention[resolution.name]
+			resolutionBk := root.Bucket([]byte(resolution.name))
+			if resolutionBk == nil || retention >= currentTime {
+				continue
+			}
+			expired := boltutil.Uint64ToBytes(currentTime - retention)
+			pairs := [][]byte{}
+			if fErr := resolutionBk.ForEach(func(k, v []byte) error {
+				pairs = append(pairs, append([]byte{}, k...))
+				return nil
+			}); fErr != nil {
+				return fErr
+			}
+			for _, pair := range pairs {
+				pairBk := resolutionBk.Bucket(pair)
+				exchanges := [][]byte{}
+				if fErr := pairBk.ForEach(func(k, v []byte) error {
+					exchanges = append(exchanges, append([]byte{}, k...))
+					return nil
+				}); fErr != nil {
+					return fErr
+				}
+				for _, exchange := range exchanges {
+					c := pairBk.Bucket(exchange).Cursor()
+					for k, _ := c.First(); k != nil && bytes.Compare(k, expired) < 0; k, _ = c.First() {
+						if dErr := c.Delete(); dErr != nil {
+							return dErr
+						}
+						count++
+					}
+				}
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
 func (self *BoltStorage) CurrentAuthDataVersion(timepoint uint64) (common.Version, error) {
 	var result uint64
 	var err error
@@ -432,7 +617,7 @@ func (self *BoltStorage) GetAuthData(version common.Version) (common.AuthDataSna
 		if data == nil {
 			err = fmt.Errorf("version %s doesn't exist", string(version))
 		} else {
-			err = json.Unmarshal(data, &result)
+			result, err = loadAuthData(boltAuthDataStore{b}, uint64(version), data)
 		}
 		return err
 	})
@@ -493,22 +678,13 @@ func (self *BoltStorage) GetRate(version common.Version) (common.AllRateEntry, e
 	return result, err
 }
 
+// StoreAuthSnapshot stores the snapshot as a delta from the latest keyframe
+// if it is small enough, or as a new keyframe.
 func (self *BoltStorage) StoreAuthSnapshot(
 	data *common.AuthDataSnapshot, timepoint uint64) error {
-	var err error
-	err = self.db.Update(func(tx *bolt.Tx) error {
-		var (
-			uErr     error
-			dataJSON []byte
-		)
-		b := tx.Bucket([]byte(AUTH_DATA_BUCKET))
-
-		if dataJSON, uErr = json.Marshal(data); uErr != nil {
-			return uErr
-		}
-		return b.Put(boltutil.Uint64ToBytes(timepoint), dataJSON)
+	return self.db.Update(func(tx *bolt.Tx) error {
+		return storeAuthData(boltAuthDataStore{tx.Bucket([]byte(AUTH_DATA_BUCKET))}, data, timepoint)
 	})
-	return err
 }
 
 //StoreRate store rate history
@@ -577,6 +753,9 @@ func (self *BoltStorage) Record(
 		}
 
 		idByte := id.ToBytes()
+		if err = indexActivity(tx, idByte[:], record); err != nil {
+			return err
+		}
 		err = b.Put(idByte[:], dataJSON)
 		if err != nil {
 			return err
@@ -664,6 +843,135 @@ func (self *BoltStorage) GetAllRecords(fromTime, toTime uint64) ([]common.Activi
 	return result, err
 }
 
+// Fields of the activity index.
+const (
+	activityActionField      = "action"
+	activityDestinationField = "destination"
+	activityTokenField       = "token"
+	activityStatusField      = "status"
+)
+
+// activityIndexKeys returns keys of index entries of the activity stored at
+// key. Both exchange and mining statuses are indexed as status.
+func activityIndexKeys(key []byte, record common.ActivityRecord) [][]byte {
+	result := [][]byte{
+		boltutil.IndexKey(activityActionField, record.Action, key),
+		boltutil.IndexKey(activityDestinationField, record.Destination, key),
+		boltutil.IndexKey(activityStatusField, record.ExchangeStatus, key),
+		boltutil.IndexKey(activityStatusField, record.MiningStatus, key),
+	}
+	for _, token := range record.Tokens() {
+		result = append(result, boltutil.IndexKey(activityTokenField, token, key))
+	}
+	return result
+}
+
+// indexActivity replaces index entries of the activity stored at key with
+// those of record, it must be called before record is stored.
+func indexActivity(tx *bolt.Tx, key []byte, record common.ActivityRecord) error {
+	index := tx.Bucket([]byte(ACTIVITY_INDEX_BUCKET))
+	if v := tx.Bucket([]byte(ACTIVITY_BUCKET)).Get(key); v != nil {
+		old := common.ActivityRecord{}
+		if err := json.Unmarshal(v, &old); err != nil {
+			return err
+		}
+		for _, k := range activityIndexKeys(key, old) {
+			if err := index.Delete(k); err != nil {
+				return err
+			}
+		}
+	}
+	for _, k := range activityIndexKeys(key, record) {
+		if err := index.Put(k, []byte{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// activityIndexOf returns the most selective index field of the filter, it
+// returns false if the filter has none.
+func activityIndexOf(filter common.ActivityFilter) (string, string, bool) {
+	switch {
+	case filter.Token != "":
+		return activityTokenField, filter.Token, true
+	case filter.Destination != "":
+		return activityDestinationField, filter.Destination, true
+	case filter.Action != "":
+		return activityActionField, filter.Action, true
+	case filter.Status != "":
+		return activityStatusField, filter.Status, true
+	}
+	return "", "", false
+}
+
+func activityKey(timepoint uint64) []byte {
+	key := common.NewActivityID(timepoint, "").ToBytes()
+	return key[:]
+}
+
+//GetActivityPage returns at most limit activities selected by filter, which
+//are older than cursor if it is not nil, the latest first. Pending activities
+//are scanned if only they are selected, otherwise an index of the filter is
+//scanned if there is one, so records out of the filter are not read.
+func (self *BoltStorage) GetActivityPage(filter common.ActivityFilter, cursor *common.ActivityID, limit int) (common.ActivityPage, error) {
+	result := common.ActivityPage{Activities: []common.ActivityRecord{}}
+	if limit <= 0 {
+		return result, errors.New("limit must be positive")
+	}
+	toTime := filter.ToTime
+	if cursor != nil {
+		if cursor.Timepoint == 0 {
+			return result, nil
+		}
+		// keys of activities are their timepoints
+		if cursor.Timepoint-1 < toTime {
+			toTime = cursor.Timepoint - 1
+		}
+	}
+	if toTime < filter.FromTime {
+		return result, nil
+	}
+	err := self.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(ACTIVITY_BUCKET))
+		collect := func(v []byte) (bool, error) {
+			record := common.ActivityRecord{}
+			if err := json.Unmarshal(v, &record); err != nil {
+				return false, err
+			}
+			if filter.Match(record) {
+				result.Activities = append(result.Activities, record)
+			}
+			// one more activity tells if there is a next page
+			return len(result.Activities) <= limit, nil
+		}
+		scan := func(k, v []byte) (bool, error) {
+			return collect(v)
+		}
+		if filter.PendingOnly {
+			return boltutil.ReverseScan(tx.Bucket([]byte(PENDING_ACTIVITY_BUCKET)).Cursor(), activityKey(filter.FromTime), activityKey(toTime), scan)
+		}
+		field, value, ok := activityIndexOf(filter)
+		if !ok {
+			return boltutil.ReverseScan(b.Cursor(), activityKey(filter.FromTime), activityKey(toTime), scan)
+		}
+		min := boltutil.IndexKey(field, value, activityKey(filter.FromTime))
+		max := boltutil.IndexKey(field, value, activityKey(toTime))
+		return boltutil.ReverseScan(tx.Bucket([]byte(ACTIVITY_INDEX_BUCKET)).Cursor(), min, max, func(k, _ []byte) (bool, error) {
+			v := b.Get(boltutil.IndexedKey(field, value, k))
+			if v == nil {
+				return true, nil
+			}
+			return collect(v)
+		})
+	})
+	if len(result.Activities) > limit {
+		result.Activities = result.Activities[:limit]
+		result.NextCursor = common.ActivityCursor(result.Activities[limit-1].ID)
+	}
+	return result, err
+}
+
 // interfaceConverstionToUint64 will assert the interface as string
 // and parse it to uint64. Return 0 if anything goes wrong)
 func interfaceConverstionToUint64(intf interface{}) uint64 {
@@ -680,14 +988,17 @@ func interfaceConverstionToUint64(intf interface{}) uint64 {
 	return num
 }
 
+//getLastAndCountPendingSetrate returns the pending transaction of the pricing
+//operator, set_rates or set_step_function, with the highest nonce and gas price,
+//and the number of pending transactions with that nonce.
 func getLastAndCountPendingSetrate(pendings []common.ActivityRecord, minedNonce uint64) (*common.ActivityRecord, uint64, error) {
 	var maxNonce uint64
 	var maxPrice uint64
 	var result *common.ActivityRecord
 	var count uint64
 	for i, act := range pendings {
-		if act.Action == "set_rates" {
-			log.Printf("looking for pending set_rates: %+v", act)
+		if act.Action == "set_rates" || act.Action == "set_step_function" {
+			log.Printf("looking for pending %s: %+v", act.Action, act)
 			nonce := interfaceConverstionToUint64(act.Result["nonce"])
 			if nonce < minedNonce {
 				// this is a stale actitivity, ignore it
@@ -780,7 +1091,7 @@ func (self *BoltStorage) UpdateActivity(id common.ActivityID, activity common.Ac
 			}
 		}
 		b := tx.Bucket([]byte(ACTIVITY_BUCKET))
-		if uErr != nil {
+		if uErr = indexActivity(tx, idBytes[:], activity); uErr != nil {
 			return uErr
 		}
 		return b.Put(idBytes[:], dataJSON)
@@ -875,71 +1186,6 @@ func (self *BoltStorage) GetMetric(tokens []common.Token, fromTime, toTime uint6
 	return result, err
 }
 
-//GetPendingTargetQty return pending target quantity if available
-func (self *BoltStorage) GetPendingTargetQty() (metric.TokenTargetQty, error) {
-	var err error
-	var tokenTargetQty metric.TokenTargetQty
-	err = self.db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(PENDING_TARGET_QUANTITY))
-		_, data := b.Cursor().Last()
-		if data == nil {
-			return errors.New("There no pending target quantity")
-		}
-		if err = json.Unmarshal(data, &tokenTargetQty); err != nil {
-			return err
-		}
-		return err
-	})
-	return tokenTargetQty, err
-}
-
-//StorePendingTargetQty store pending target quantity if there is none
-func (self *BoltStorage) StorePendingTargetQty(data, dataType string) error {
-	var err error
-	timepoint := common.GetTimepoint()
-	tokenTargetQty := metric.TokenTargetQty{}
-	err = self.db.Update(func(tx *bolt.Tx) error {
-		var (
-			uErr     error
-			dataJSON []byte
-		)
-		b := tx.Bucket([]byte(PENDING_TARGET_QUANTITY))
-		_, lastPending := b.Cursor().Last()
-		if lastPending != nil {
-			return errors.New("there is another pending target quantity. please confirm or cancel it before setting new target")
-		}
-
-		tokenTargetQty.ID = timepoint
-		tokenTargetQty.Status = "unconfirmed"
-		tokenTargetQty.Data = data
-		tokenTargetQty.Type, _ = strconv.ParseInt(dataType, 10, 64)
-		idByte := boltutil.Uint64ToBytes(timepoint)
-
-		if dataJSON, uErr = json.Marshal(tokenTargetQty); uErr != nil {
-			return uErr
-		}
-
-		log.Printf("Target to save: %v", dataJSON)
-		return b.Put(idByte, dataJSON)
-	})
-	return err
-}
-
-//RemovePendingTargetQty remove pending target quantity
-func (self *BoltStorage) RemovePendingTargetQty() error {
-	var err error
-	err = self.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(PENDING_TARGET_QUANTITY))
-		k, lastPending := b.Cursor().Last()
-		log.Printf("Last key: %s", k)
-		if lastPending == nil {
-			return errors.New("there is no pending target quantity")
-		}
-		return b.Delete([]byte(k))
-	})
-	return err
-}
-
 //CurrentTargetQtyVersion return current target quantity version
 func (self *BoltStorage) CurrentTargetQtyVersion(timepoint uint64) (common.Version, error) {
 	var result uint64
@@ -975,55 +1221,22 @@ func (self *BoltStorage) GetTokenTargetQty() (metric.TokenTargetQty, error) {
 	return tokenTargetQty, err
 }
 
-//StoreTokenTargetQty store token target quantity in db
-func (self *BoltStorage) StoreTokenTargetQty(id, data string) error {
-	var (
-		err            error
-		tokenTargetQty metric.TokenTargetQty
-		dataJSON       []byte
-	)
-	err = self.db.Update(func(tx *bolt.Tx) error {
-		var (
-			uErr  error
-			idInt uint64
-		)
-		pending := tx.Bucket([]byte(PENDING_TARGET_QUANTITY))
-		_, pendingTargetQty := pending.Cursor().Last()
-
-		if pendingTargetQty == nil {
-			return errors.New("there is no pending target activity to confirm")
-		}
-		// verify confirm data
-		if uErr = json.Unmarshal(pendingTargetQty, &tokenTargetQty); uErr != nil {
-			return uErr
-		}
-		pendingData := tokenTargetQty.Data
-
-		if idInt, uErr = strconv.ParseUint(id, 10, 64); uErr != nil {
-			return uErr
-		}
-		if tokenTargetQty.ID != idInt {
-			return errors.New("pending target quantity ID does not match")
-		}
-		if data != pendingData {
-			return errors.New("pending target quantity data does not match")
-		}
-
-		// Save to confirmed target quantity
-		tokenTargetQty.Status = "confirmed"
-		b := tx.Bucket([]byte(METRIC_TARGET_QUANTITY))
-		dataJSON, uErr = json.Marshal(tokenTargetQty)
-		if uErr != nil {
-			return uErr
-		}
-		idByte := boltutil.Uint64ToBytes(common.GetTimepoint())
-		return b.Put(idByte, dataJSON)
-	})
+// storeTokenTargetQty stores tokenTargetQty as the confirmed target quantity
+// and writes it through to target quantity v2, which is what the core reads.
+func storeTokenTargetQty(tx *bolt.Tx, tokenTargetQty metric.TokenTargetQty) error {
+	tokenTargetQty.Status = "confirmed"
+	dataJSON, err := json.Marshal(tokenTargetQty)
 	if err != nil {
 		return err
 	}
-	// remove pending target qty
-	return self.RemovePendingTargetQty()
+	idByte := boltutil.Uint64ToBytes(common.GetTimepoint())
+	if err = tx.Bucket([]byte(METRIC_TARGET_QUANTITY)).Put(idByte, dataJSON); err != nil {
+		return err
+	}
+	if dataJSON, err = json.Marshal(convertTargetQtyV1toV2(tokenTargetQty)); err != nil {
+		return err
+	}
+	return tx.Bucket([]byte(TARGET_QUANTITY_V2)).Put([]byte(currentTargetQtyKey), dataJSON)
 }
 
 func (self *BoltStorage) GetRebalanceControl() (metric.RebalanceControl, error) {
@@ -1121,72 +1334,25 @@ func (self *BoltStorage) StoreSetrateControl(status bool) error {
 	return err
 }
 
-func (self *BoltStorage) StorePendingPWIEquation(data string) error {
-	var err error
-	timepoint := common.GetTimepoint()
-	saveData := metric.PWIEquation{}
-	err = self.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(PENDING_PWI_EQUATION))
-		c := b.Cursor()
-		_, v := c.First()
-		if v != nil {
-			return errors.New("There is another pending equation, please confirm or reject to set new equation")
-		}
-		idByte := boltutil.Uint64ToBytes(timepoint)
-		saveData.ID = timepoint
-		saveData.Data = data
-		dataJSON, uErr := json.Marshal(saveData)
-		if uErr != nil {
-			return uErr
-		}
-		return b.Put(idByte, dataJSON)
-	})
-	return err
-}
-
-func (self *BoltStorage) GetPendingPWIEquation() (metric.PWIEquation, error) {
-	var err error
-	var result metric.PWIEquation
-	err = self.db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(PENDING_PWI_EQUATION))
-		c := b.Cursor()
-		_, v := c.First()
-		if v == nil {
-			return errors.New("There no pending equation")
-		}
-		return json.Unmarshal(v, &result)
-	})
-	return result, err
-}
-
-func (self *BoltStorage) StorePWIEquation(data string) error {
-	var err error
-	err = self.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(PENDING_PWI_EQUATION))
-		c := b.Cursor()
-		_, v := c.First()
-		if v == nil {
-			return errors.New("There no pending equation")
-		}
-		p := tx.Bucket([]byte(PWI_EQUATION))
-		idByte := boltutil.Uint64ToBytes(common.GetTimepoint())
-		pending := metric.PWIEquation{}
-		if uErr := json.Unmarshal(v, &pending); uErr != nil {
-			return uErr
-		}
-		if pending.Data != data {
-			return errors.New("Confirm data does not match pending data")
-		}
-		saveData, uErr := json.Marshal(pending)
-		if uErr != nil {
-			return uErr
-		}
-		return p.Put(idByte, saveData)
-	})
-	if err == nil {
-		return self.RemovePendingPWIEquation()
+// storePWIEquation stores equation as the confirmed PWI equation and writes
+// it through to PWI equation v2, which is what the core reads.
+func storePWIEquation(tx *bolt.Tx, equation metric.PWIEquation) error {
+	idByte := boltutil.Uint64ToBytes(common.GetTimepoint())
+	saveData, err := json.Marshal(equation)
+	if err != nil {
+		return err
 	}
-	return err
+	if err = tx.Bucket([]byte(PWI_EQUATION)).Put(idByte, saveData); err != nil {
+		return err
+	}
+	eqv2, err := convertPWIEquationV1toV2(equation.Data)
+	if err != nil {
+		return err
+	}
+	if saveData, err = json.Marshal(eqv2); err != nil {
+		return err
+	}
+	return tx.Bucket([]byte(PWI_EQUATION_V2)).Put(idByte, saveData)
 }
 
 func (self *BoltStorage) GetPWIEquation() (metric.PWIEquation, error) {
@@ -1205,25 +1371,6 @@ func (self *BoltStorage) GetPWIEquation() (metric.PWIEquation, error) {
 	return result, err
 }
 
-func (self *BoltStorage) RemovePendingPWIEquation() error {
-	var err error
-	err = self.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(PENDING_PWI_EQUATION))
-		c := b.Cursor()
-		k, _ := c.First()
-		if k != nil {
-			err = b.Delete([]byte(k))
-			if err != nil {
-				return err
-			}
-		} else {
-			err = errors.New("There is no pending data")
-		}
-		return err
-	})
-	return err
-}
-
 // GetExchangeStatus get exchange status to dashboard and analytics
 func (self *BoltStorage) GetExchangeStatus() (common.ExchangesStatus, error) {
 	result := make(common.ExchangesStatus)
@@ -1326,53 +1473,6 @@ func (self *BoltStorage) GetExchangeNotifications() (common.ExchangeNotification
 	return result, err
 }
 
-func (self *BoltStorage) SetStableTokenParams(value []byte) error {
-	var err error
-	k := boltutil.Uint64ToBytes(1)
-	temp := make(map[string]interface{})
-
-	if err = json.Unmarshal(value, &temp); err != nil {
-		return fmt.Errorf("Rejected: Data could not be unmarshalled to defined format: %s", err)
-	}
-	err = self.db.Update(func(tx *bolt.Tx) error {
-		b, uErr := tx.CreateBucketIfNotExists([]byte(PENDING_STABLE_TOKEN_PARAMS_BUCKET))
-		if uErr != nil {
-			return uErr
-		}
-		if b.Get(k) != nil {
-			return errors.New("Currently there is a pending record")
-		}
-		return b.Put(k, value)
-	})
-	return err
-}
-
-func (self *BoltStorage) ConfirmStableTokenParams(value []byte) error {
-	var err error
-	k := boltutil.Uint64ToBytes(1)
-	temp := make(map[string]interface{})
-
-	if err = json.Unmarshal(value, &temp); err != nil {
-		return fmt.Errorf("Rejected: Data could not be unmarshalled to defined format: %s", err)
-	}
-	pending, err := self.GetPendingStableTokenParams()
-	if eq := reflect.DeepEqual(pending, temp); !eq {
-		return errors.New("Rejected: confiming data isn't consistent")
-	}
-
-	err = self.db.Update(func(tx *bolt.Tx) error {
-		b, uErr := tx.CreateBucketIfNotExists([]byte(STABLE_TOKEN_PARAMS_BUCKET))
-		if uErr != nil {
-			return uErr
-		}
-		return b.Put(k, value)
-	})
-	if err != nil {
-		return err
-	}
-	return self.RemovePendingStableTokenParams()
-}
-
 func (self *BoltStorage) GetStableTokenParams() (map[string]interface{}, error) {
 	k := boltutil.Uint64ToBytes(1)
 	result := make(map[string]interface{})
@@ -1390,143 +1490,19 @@ func (self *BoltStorage) GetStableTokenParams() (map[string]interface{}, error)
 	return result, err
 }
 
-func (self *BoltStorage) GetPendingStableTokenParams() (map[string]interface{}, error) {
-	k := boltutil.Uint64ToBytes(1)
-	result := make(map[string]interface{})
-	err := self.db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(PENDING_STABLE_TOKEN_PARAMS_BUCKET))
-		if b == nil {
-			return errors.New("Bucket hasn't exist yet")
-		}
-		record := b.Get(k)
-		if record != nil {
-			return json.Unmarshal(record, &result)
-		}
-		return nil
-	})
-	return result, err
-}
-
-func (self *BoltStorage) RemovePendingStableTokenParams() error {
-	k := boltutil.Uint64ToBytes(1)
-	err := self.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(PENDING_STABLE_TOKEN_PARAMS_BUCKET))
-		if b == nil {
-			return errors.New("Bucket hasn't existed yet")
-		}
-		record := b.Get(k)
-		if record == nil {
-			return errors.New("Bucket is empty")
-		}
-		return b.Delete(k)
-	})
-	return err
-}
-
-//StorePendingTargetQtyV2 store value into pending target qty v2 bucket
-func (self *BoltStorage) StorePendingTargetQtyV2(value []byte) error {
-	var (
-		err         error
-		pendingData metric.TokenTargetQtyV2
-	)
-
-	if err = json.Unmarshal(value, &pendingData); err != nil {
-		return fmt.Errorf("Rejected: Data could not be unmarshalled to defined format: %s", err.Error())
-	}
-	err = self.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(PENDING_TARGET_QUANTITY_V2))
-		k := []byte("current_pending_target_qty")
-		if b.Get(k) != nil {
-			return fmt.Errorf("Currently there is a pending record")
-		}
-		return b.Put(k, value)
-	})
-	return err
-}
-
-//GetPendingTargetQtyV2 return current pending target quantity
-func (self *BoltStorage) GetPendingTargetQtyV2() (metric.TokenTargetQtyV2, error) {
-	result := metric.TokenTargetQtyV2{}
-	err := self.db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(PENDING_TARGET_QUANTITY_V2))
-		k := []byte("current_pending_target_qty")
-		record := b.Get(k)
-		if record == nil {
-			return errors.New("There is no pending target qty")
-		}
-		return json.Unmarshal(record, &result)
-	})
-	return result, err
-}
-
-//ConfirmTargetQtyV2 check if confirm data match pending data and save it to confirm bucket
-//remove pending data from pending bucket
-func (self *BoltStorage) ConfirmTargetQtyV2(value []byte) error {
-	confirmTargetQty := metric.TokenTargetQtyV2{}
-	err := json.Unmarshal(value, &confirmTargetQty)
-	if err != nil {
-		return fmt.Errorf("Rejected: Data could not be unmarshalled to defined format: %s", err)
-	}
-	pending, err := self.GetPendingTargetQtyV2()
-	if eq := reflect.DeepEqual(pending, confirmTargetQty); !eq {
-		return fmt.Errorf("Rejected: confiming data isn't consistent")
-	}
-
-	err = self.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(TARGET_QUANTITY_V2))
-		targetKey := []byte("current_target_qty")
-		if uErr := b.Put(targetKey, value); uErr != nil {
-			return uErr
-		}
-		pendingBk := tx.Bucket([]byte(PENDING_TARGET_QUANTITY_V2))
-		pendingKey := []byte("current_pending_target_qty")
-		return pendingBk.Delete(pendingKey)
-	})
-	return err
-}
-
-// RemovePendingTargetQtyV2 remove pending data from db
-func (self *BoltStorage) RemovePendingTargetQtyV2() error {
-	err := self.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(PENDING_TARGET_QUANTITY_V2))
-		if b == nil {
-			return fmt.Errorf("Bucket hasn't existed yet")
-		}
-		k := []byte("current_pending_target_qty")
-		return b.Delete(k)
-	})
-	return err
-}
-
 // GetTargetQtyV2 return the current target quantity
 func (self *BoltStorage) GetTargetQtyV2() (metric.TokenTargetQtyV2, error) {
 	result := metric.TokenTargetQtyV2{}
 	err := self.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(TARGET_QUANTITY_V2))
-		k := []byte("current_target_qty")
+		k := []byte(currentTargetQtyKey)
 		record := b.Get(k)
 		if record == nil {
-			return nil
+			return errors.New("There is no target quantity")
 		}
 		return json.Unmarshal(record, &result)
 	})
-	if err != nil {
-		return result, err
-	}
-
-	// This block below is for backward compatible for api v1
-	// when the result is empty it means there is not target quantity is set
-	// we need to get current target quantity from v1 bucket and return it as v2 form.
-	if len(result) == 0 {
-		// target qty v1
-		var targetQty metric.TokenTargetQty
-		targetQty, err = self.GetTokenTargetQty()
-		if err != nil {
-			return result, err
-		}
-		result = convertTargetQtyV1toV2(targetQty)
-	}
-	return result, nil
+	return result, err
 }
 
 // This function convert target quantity from v1 to v2
@@ -1560,86 +1536,6 @@ func convertTargetQtyV1toV2(target metric.TokenTargetQty) metric.TokenTargetQtyV
 	return result
 }
 
-// StorePendingPWIEquationV2 stores the given PWIs equation data for later approval.
-func (self *BoltStorage) StorePendingPWIEquationV2(data []byte) error {
-	timepoint := common.GetTimepoint()
-	err := self.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(PENDING_PWI_EQUATION_V2))
-		c := b.Cursor()
-		_, v := c.First()
-		if v != nil {
-			return errors.New("pending PWI equation exists")
-		}
-		return b.Put(boltutil.Uint64ToBytes(timepoint), data)
-	})
-	return err
-}
-
-// GetPendingPWIEquationV2 returns the stored PWIEquationRequestV2 in database.
-func (self *BoltStorage) GetPendingPWIEquationV2() (metric.PWIEquationRequestV2, error) {
-	var (
-		err    error
-		result metric.PWIEquationRequestV2
-	)
-
-	err = self.db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(PENDING_PWI_EQUATION_V2))
-		c := b.Cursor()
-		_, v := c.First()
-		if v == nil {
-			return errors.New("There is no pending equation")
-		}
-		return json.Unmarshal(v, &result)
-	})
-	return result, err
-}
-
-// RemovePendingPWIEquationV2 deletes the pending equation request.
-func (self *BoltStorage) RemovePendingPWIEquationV2() error {
-	err := self.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(PENDING_PWI_EQUATION_V2))
-		c := b.Cursor()
-		k, _ := c.First()
-		if k == nil {
-			return errors.New("There is no pending data")
-		}
-		return b.Delete(k)
-	})
-	return err
-}
-
-// StorePWIEquationV2 moved the pending equation request to
-// PWI_EQUATION_V2 bucket and remove it from pending bucket if the
-// given data matched what stored.
-func (self *BoltStorage) StorePWIEquationV2(data string) error {
-	err := self.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(PENDING_PWI_EQUATION_V2))
-		c := b.Cursor()
-		k, v := c.First()
-		if v == nil {
-			return errors.New("There is no pending equation")
-		}
-		confirmData := metric.PWIEquationRequestV2{}
-		if err := json.Unmarshal([]byte(data), &confirmData); err != nil {
-			return err
-		}
-		currentData := metric.PWIEquationRequestV2{}
-		if err := json.Unmarshal(v, &currentData); err != nil {
-			return err
-		}
-		if eq := reflect.DeepEqual(currentData, confirmData); !eq {
-			return errors.New("Confirm data does not match pending data")
-		}
-		id := boltutil.Uint64ToBytes(common.GetTimepoint())
-		if uErr := tx.Bucket([]byte(PWI_EQUATION_V2)).Put(id, v); uErr != nil {
-			return uErr
-		}
-		// remove pending PWI equations request
-		return b.Delete(k)
-	})
-	return err
-}
-
 func convertPWIEquationV1toV2(data string) (metric.PWIEquationRequestV2, error) {
 	result := metric.PWIEquationRequestV2{}
 	for _, dataConfig := range strings.Split(data, "|") {
@@ -1673,20 +1569,6 @@ func convertPWIEquationV1toV2(data string) (metric.PWIEquationRequestV2, error)
 	return result, nil
 }
 
-func pwiEquationV1toV2(tx *bolt.Tx) (metric.PWIEquationRequestV2, error) {
-	var eqv1 metric.PWIEquation
-	b := tx.Bucket([]byte(PWI_EQUATION))
-	c := b.Cursor()
-	_, v := c.Last()
-	if v == nil {
-		return nil, errors.New("There is no equation")
-	}
-	if err := json.Unmarshal(v, &eqv1); err != nil {
-		return nil, err
-	}
-	return convertPWIEquationV1toV2(eqv1.Data)
-}
-
 // GetPWIEquationV2 returns the current PWI equations from database.
 func (self *BoltStorage) GetPWIEquationV2() (metric.PWIEquationRequestV2, error) {
 	var (
@@ -1694,108 +1576,645 @@ func (self *BoltStorage) GetPWIEquationV2() (metric.PWIEquationRequestV2, error)
 		result metric.PWIEquationRequestV2
 	)
 	err = self.db.View(func(tx *bolt.Tx) error {
-		var vErr error // convert pwi v1 to v2 error
 		b := tx.Bucket([]byte(PWI_EQUATION_V2))
 		c := b.Cursor()
 		_, v := c.Last()
 		if v == nil {
-			log.Println("there no equation in PWI_EQUATION_V2, getting from PWI_EQUATION")
-			result, vErr = pwiEquationV1toV2(tx)
-			return vErr
+			return errors.New("There is no equation")
 		}
 		return json.Unmarshal(v, &result)
 	})
 	return result, err
 }
 
-//StorePendingRebalanceQuadratic store pending data (stand for rebalance quadratic equation) to db
-//data byte for json {"KNC": {"a": 0.9, "b": 1.2, "c": 1.4}}
-func (self *BoltStorage) StorePendingRebalanceQuadratic(data []byte) error {
+//GetRebalanceQuadratic return current confirm rebalance quadratic equation
+func (self *BoltStorage) GetRebalanceQuadratic() (metric.RebalanceQuadraticRequest, error) {
+	var result metric.RebalanceQuadraticRequest
+	err := self.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(REBALANCE_QUADRATIC))
+		c := b.Cursor()
+		k, v := c.Last()
+		if k == nil {
+			return errors.New("there is no rebalance quadratic equation")
+		}
+		return json.Unmarshal(v, &result)
+	})
+	return result, err
+}
+
+//StorePendingStepFunctions store pending step functions to db
+//return error if there is already a pending one
+func (self *BoltStorage) StorePendingStepFunctions(data []byte) error {
 	timepoint := common.GetTimepoint()
 	err := self.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(PENDING_REBALANCE_QUADRATIC))
+		b := tx.Bucket([]byte(PENDING_STEP_FUNCTIONS))
 		c := b.Cursor()
 		k, _ := c.First()
 		if k != nil {
-			return errors.New("pending rebalance quadratic equation exists")
+			return errors.New("pending step functions exist")
 		}
 		return b.Put(boltutil.Uint64ToBytes(timepoint), data)
 	})
 	return err
 }
 
-//GetPendingRebalanceQuadratic return pending rebalance quadratic equation
-func (self *BoltStorage) GetPendingRebalanceQuadratic() (metric.RebalanceQuadraticRequest, error) {
-	var result metric.RebalanceQuadraticRequest
+//GetPendingStepFunctions return pending step functions
+func (self *BoltStorage) GetPendingStepFunctions() (metric.StepFunctionsRequest, error) {
+	var result metric.StepFunctionsRequest
 	err := self.db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(PENDING_REBALANCE_QUADRATIC))
+		b := tx.Bucket([]byte(PENDING_STEP_FUNCTIONS))
 		c := b.Cursor()
 		k, v := c.First()
 		if k == nil {
-			return errors.New("there is no pending rebalance quadratic equation")
+			return errors.New("there is no pending step functions")
 		}
 		return json.Unmarshal(v, &result)
 	})
 	return result, err
 }
 
-//ConfirmRebalanceQuadratic confirm pending equation save it to confirmed bucket
-//and remove pending equation
-func (self *BoltStorage) ConfirmRebalanceQuadratic(data []byte) error {
+//ConfirmStepFunctions save pending step functions to the history bucket
+//and remove them from pending
+func (self *BoltStorage) ConfirmStepFunctions(data []byte) error {
 	err := self.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(PENDING_REBALANCE_QUADRATIC))
+		b := tx.Bucket([]byte(PENDING_STEP_FUNCTIONS))
 		c := b.Cursor()
 		k, v := c.First()
 		if v == nil {
-			return errors.New("there is no pending rebalance quadratic equation")
+			return errors.New("there is no pending step functions")
 		}
-		confirmData := metric.RebalanceQuadraticRequest{}
+		confirmData := metric.StepFunctionsRequest{}
 		if err := json.Unmarshal(data, &confirmData); err != nil {
 			return err
 		}
-		currentData := metric.RebalanceQuadraticRequest{}
+		currentData := metric.StepFunctionsRequest{}
 		if err := json.Unmarshal(v, &currentData); err != nil {
 			return err
 		}
 		if eq := reflect.DeepEqual(currentData, confirmData); !eq {
-			return errors.New("confirm data does not match rebalance quadratic pending data")
+			return errors.New("confirm data does not match pending step functions")
 		}
 		id := boltutil.Uint64ToBytes(common.GetTimepoint())
-		if uErr := tx.Bucket([]byte(REBALANCE_QUADRATIC)).Put(id, v); uErr != nil {
+		if uErr := tx.Bucket([]byte(STEP_FUNCTIONS)).Put(id, v); uErr != nil {
+			return uErr
+		}
+		if uErr := tx.Bucket([]byte(PENDING_STEP_FUNCTION_SUBMISSIONS)).Delete(stepFunctionSubmissionsKey); uErr != nil {
 			return uErr
 		}
-		// remove pending rebalance quadratic equation
 		return b.Delete(k)
 	})
 	return err
 }
 
-//RemovePendingRebalanceQuadratic remove pending rebalance quadratic equation
-//use when admin want to reject a config for rebalance quadratic equation
-func (self *BoltStorage) RemovePendingRebalanceQuadratic() error {
+//RemovePendingStepFunctions remove pending step functions
+//use when admin want to reject them
+func (self *BoltStorage) RemovePendingStepFunctions() error {
 	err := self.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(PENDING_REBALANCE_QUADRATIC))
+		b := tx.Bucket([]byte(PENDING_STEP_FUNCTIONS))
 		c := b.Cursor()
 		k, _ := c.First()
 		if k == nil {
-			return errors.New("there no pending rebalance quadratic equation to delete")
+			return errors.New("there is no pending step functions to delete")
+		}
+		if uErr := tx.Bucket([]byte(PENDING_STEP_FUNCTION_SUBMISSIONS)).Delete(stepFunctionSubmissionsKey); uErr != nil {
+			return uErr
 		}
 		return b.Delete(k)
 	})
 	return err
 }
 
-//GetRebalanceQuadratic return current confirm rebalance quadratic equation
-func (self *BoltStorage) GetRebalanceQuadratic() (metric.RebalanceQuadraticRequest, error) {
-	var result metric.RebalanceQuadraticRequest
+//stepFunctionSubmissionsKey is the key of submissions of the pending step functions
+var stepFunctionSubmissionsKey = boltutil.Uint64ToBytes(1)
+
+//StoreStepFunctionSubmission records the activity of a step function of the pending
+//step functions submitted to pricing contract, so it is not submitted again
+func (self *BoltStorage) StoreStepFunctionSubmission(tokenID, stepType string, id common.ActivityID) error {
+	err := self.db.Update(func(tx *bolt.Tx) error {
+		if k, _ := tx.Bucket([]byte(PENDING_STEP_FUNCTIONS)).Cursor().First(); k == nil {
+			return errors.New("there is no pending step functions")
+		}
+		b := tx.Bucket([]byte(PENDING_STEP_FUNCTION_SUBMISSIONS))
+		submissions := metric.StepFunctionSubmissions{}
+		if v := b.Get(stepFunctionSubmissionsKey); v != nil {
+			if uErr := json.Unmarshal(v, &submissions); uErr != nil {
+				return uErr
+			}
+		}
+		submissions.Add(tokenID, stepType, id)
+		data, uErr := json.Marshal(submissions)
+		if uErr != nil {
+			return uErr
+		}
+		return b.Put(stepFunctionSubmissionsKey, data)
+	})
+	return err
+}
+
+//GetStepFunctionSubmissions returns activities of step functions of the pending
+//step functions submitted to pricing contract
+func (self *BoltStorage) GetStepFunctionSubmissions() (metric.StepFunctionSubmissions, error) {
+	result := metric.StepFunctionSubmissions{}
 	err := self.db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(REBALANCE_QUADRATIC))
-		c := b.Cursor()
-		k, v := c.Last()
-		if k == nil {
-			return errors.New("there is no rebalance quadratic equation")
+		v := tx.Bucket([]byte(PENDING_STEP_FUNCTION_SUBMISSIONS)).Get(stepFunctionSubmissionsKey)
+		if v == nil {
+			return nil
 		}
 		return json.Unmarshal(v, &result)
 	})
 	return result, err
 }
+
+//StoreGasSpend save the gas spend record of a transaction, keyed by its timestamp.
+//A transaction is only recorded once, later records of the same tx are ignored.
+func (self *BoltStorage) StoreGasSpend(record common.GasSpendRecord) error {
+	err := self.db.Update(func(tx *bolt.Tx) error {
+		txBucket := tx.Bucket([]byte(GAS_SPEND_TX_BUCKET))
+		if txBucket.Get([]byte(record.TxHash)) != nil {
+			log.Printf("gas spend of tx %s is already recorded", record.TxHash)
+			return nil
+		}
+		data, uErr := json.Marshal(record)
+		if uErr != nil {
+			return uErr
+		}
+		key := append(boltutil.Uint64ToBytes(record.Timestamp), []byte(record.TxHash)...)
+		if uErr = tx.Bucket([]byte(GAS_SPEND_BUCKET)).Put(key, data); uErr != nil {
+			return uErr
+		}
+		return txBucket.Put([]byte(record.TxHash), key)
+	})
+	return err
+}
+
+//GetGasSpend return gas spend records in [fromTime, toTime] sorted by timestamp
+func (self *BoltStorage) GetGasSpend(fromTime, toTime uint64) ([]common.GasSpendRecord, error) {
+	result := []common.GasSpendRecord{}
+	if toTime < fromTime {
+		return result, errors.New("fromTime must be smaller than toTime")
+	}
+	if toTime-fromTime > MAX_GET_GAS_SPEND_PERIOD {
+		return result, fmt.Errorf("Time range is too broad, it must be smaller or equal to %d miliseconds", MAX_GET_GAS_SPEND_PERIOD)
+	}
+	var err error
+	err = self.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(GAS_SPEND_BUCKET)).Cursor()
+		min := boltutil.Uint64ToBytes(fromTime)
+		max := boltutil.Uint64ToBytes(toTime + 1)
+		for k, v := c.Seek(min); k != nil && bytes.Compare(k, max) < 0; k, v = c.Next() {
+			record := common.GasSpendRecord{}
+			if err = json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+			result = append(result, record)
+		}
+		return nil
+	})
+	return result, err
+}
+
+//StorePendingGasSpend save a transaction waiting for its gas spend to be recorded, keyed by its hash.
+func (self *BoltStorage) StorePendingGasSpend(job common.PendingGasSpend) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return self.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(PENDING_GAS_SPEND_BUCKET)).Put([]byte(job.TxHash), data)
+	})
+}
+
+//GetPendingGasSpends return all transactions waiting for their gas spend to be recorded
+func (self *BoltStorage) GetPendingGasSpends() ([]common.PendingGasSpend, error) {
+	result := []common.PendingGasSpend{}
+	err := self.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(PENDING_GAS_SPEND_BUCKET)).ForEach(func(k, v []byte) error {
+			job := common.PendingGasSpend{}
+			if err := json.Unmarshal(v, &job); err != nil {
+				return err
+			}
+			result = append(result, job)
+			return nil
+		})
+	})
+	return result, err
+}
+
+//RemovePendingGasSpend remove the transaction once its gas spend is recorded
+func (self *BoltStorage) RemovePendingGasSpend(txHash string) error {
+	return self.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(PENDING_GAS_SPEND_BUCKET)).Delete([]byte(txHash))
+	})
+}
+
+// storeSingleValue replaces the only value of the bucket.
+func storeSingleValue(tx *bolt.Tx, bucket string, value interface{}) error {
+	b := tx.Bucket([]byte(bucket))
+	c := b.Cursor()
+	if k, _ := c.First(); k != nil {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	dataJSON, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return b.Put(boltutil.Uint64ToBytes(common.GetTimepoint()), dataJSON)
+}
+
+//StoreBalanceThresholds save the balance thresholds, replacing the current ones
+func (self *BoltStorage) StoreBalanceThresholds(thresholds common.BalanceThresholds) error {
+	return self.db.Update(func(tx *bolt.Tx) error {
+		return storeSingleValue(tx, BALANCE_THRESHOLDS_BUCKET, thresholds)
+	})
+}
+
+//GetBalanceThresholds return the balance thresholds, empty if they are not set
+func (self *BoltStorage) GetBalanceThresholds() (common.BalanceThresholds, error) {
+	result := common.BalanceThresholds{
+		Operators: map[string]float64{},
+		Tokens:    map[string]float64{},
+	}
+	err := self.db.View(func(tx *bolt.Tx) error {
+		_, data := tx.Bucket([]byte(BALANCE_THRESHOLDS_BUCKET)).Cursor().First()
+		if data == nil {
+			return nil
+		}
+		return json.Unmarshal(data, &result)
+	})
+	return result, err
+}
+
+//StoreBalanceStatus save the latest balance monitor status
+func (self *BoltStorage) StoreBalanceStatus(status common.BalanceStatus) error {
+	return self.db.Update(func(tx *bolt.Tx) error {
+		return storeSingleValue(tx, BALANCE_STATUS_BUCKET, status)
+	})
+}
+
+//GetBalanceStatus return the latest balance monitor status
+func (self *BoltStorage) GetBalanceStatus() (common.BalanceStatus, error) {
+	var result common.BalanceStatus
+	err := self.db.View(func(tx *bolt.Tx) error {
+		_, data := tx.Bucket([]byte(BALANCE_STATUS_BUCKET)).Cursor().First()
+		if data == nil {
+			return errors.New("balance status is not available")
+		}
+		return json.Unmarshal(data, &result)
+	})
+	return result, err
+}
+
+// apiKeyNotFound is the error of API keys which don't exist.
+func apiKeyNotFound(name string) error {
+	return fmt.Errorf("API key %s doesn't exist", name)
+}
+
+//CreateAPIKey stores a new API key, it fails if a key of the same name exists
+func (self *BoltStorage) CreateAPIKey(key common.APIKey) error {
+	return self.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(API_KEY_BUCKET))
+		if b.Get([]byte(key.Name)) != nil {
+			return fmt.Errorf("API key %s already exists", key.Name)
+		}
+		return storeAPIKey(b, key)
+	})
+}
+
+//UpdateAPIKey reads the API key of name, changes it with update and stores
+//it back in one transaction, nothing is stored if update fails
+func (self *BoltStorage) UpdateAPIKey(name string, update func(key *common.APIKey) error) (common.APIKey, error) {
+	var result common.APIKey
+	err := self.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(API_KEY_BUCKET))
+		data := b.Get([]byte(name))
+		if data == nil {
+			return apiKeyNotFound(name)
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return err
+		}
+		if err := update(&result); err != nil {
+			return err
+		}
+		result.Name = name
+		return storeAPIKey(b, result)
+	})
+	return result, err
+}
+
+func storeAPIKey(b *bolt.Bucket, key common.APIKey) error {
+	dataJSON, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(key.Name), dataJSON)
+}
+
+//GetAPIKey returns the API key of name
+func (self *BoltStorage) GetAPIKey(name string) (common.APIKey, error) {
+	var result common.APIKey
+	err := self.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket([]byte(API_KEY_BUCKET)).Get([]byte(name))
+		if data == nil {
+			return apiKeyNotFound(name)
+		}
+		return json.Unmarshal(data, &result)
+	})
+	return result, err
+}
+
+//GetAPIKeys returns all API keys sorted by name
+func (self *BoltStorage) GetAPIKeys() ([]common.APIKey, error) {
+	result := []common.APIKey{}
+	err := self.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(API_KEY_BUCKET)).ForEach(func(k, v []byte) error {
+			var key common.APIKey
+			if err := json.Unmarshal(v, &key); err != nil {
+				return err
+			}
+			result = append(result, key)
+			return nil
+		})
+	})
+	return result, err
+}
+
+//RemoveAPIKey removes the API key of name
+func (self *BoltStorage) RemoveAPIKey(name string) error {
+	return self.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(API_KEY_BUCKET))
+		if b.Get([]byte(name)) == nil {
+			return apiKeyNotFound(name)
+		}
+		return b.Delete([]byte(name))
+	})
+}
+
+// auditRecordKey is the key of audit records, timestamps of records never
+// decrease so records are ordered by both timestamp and ID.
+func auditRecordKey(record common.AuditRecord) []byte {
+	return append(boltutil.Uint64ToBytes(record.Timestamp), boltutil.Uint64ToBytes(record.ID)...)
+}
+
+//AppendAuditRecord chains the record after the last audit record and stores it
+func (self *BoltStorage) AppendAuditRecord(record common.AuditRecord) (common.AuditRecord, error) {
+	err := self.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(AUDIT_LOG_BUCKET))
+		var last *common.AuditRecord
+		if _, v := b.Cursor().Last(); v != nil {
+			last = &common.AuditRecord{}
+			if err := json.Unmarshal(v, last); err != nil {
+				return err
+			}
+		}
+		record = common.NextAuditRecord(last, record)
+		dataJSON, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		return b.Put(auditRecordKey(record), dataJSON)
+	})
+	return record, err
+}
+
+//PruneExpiredAuditRecords removes audit records older than AUDIT_LOG_RETENTION
+//except the last record, which the next record is chained to
+func (self *BoltStorage) PruneExpiredAuditRecords(currentTime uint64) (uint64, error) {
+	var count uint64
+	if currentTime <= AUDIT_LOG_RETENTION {
+		return count, nil
+	}
+	expired := currentTime - AUDIT_LOG_RETENTION
+	err := self.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(AUDIT_LOG_BUCKET))
+		last, _ := b.Cursor().Last()
+		last = append([]byte{}, last...)
+		c := b.Cursor()
+		// records are removed from the first, so the cursor goes back to the first after removing
+		for k, _ := c.First(); k != nil && !bytes.Equal(k, last) && boltutil.BytesToUint64(k[:8]) < expired; k, _ = c.First() {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+//GetAuditRecords returns audit records selected by filter sorted by ID
+func (self *BoltStorage) GetAuditRecords(filter common.AuditFilter) ([]common.AuditRecord, error) {
+	result := []common.AuditRecord{}
+	if filter.ToTime < filter.FromTime {
+		return result, errors.New("fromTime must be smaller than toTime")
+	}
+	err := self.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(AUDIT_LOG_BUCKET)).Cursor()
+		for k, v := c.Seek(boltutil.Uint64ToBytes(filter.FromTime)); k != nil && boltutil.BytesToUint64(k[:8]) <= filter.ToTime; k, v = c.Next() {
+			record := common.AuditRecord{}
+			if err := json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+			if filter.Match(record) {
+				result = append(result, record)
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+// configProposalNotFound is the error of config types without pending proposal.
+func configProposalNotFound(configType string) error {
+	return fmt.Errorf("there is no pending proposal of config %s", configType)
+}
+
+// configVersionKey is the key of a config version, versions of a type are
+// sorted by version.
+func configVersionKey(configType string, version uint64) []byte {
+	return append([]byte(configType+"\x00"), boltutil.Uint64ToBytes(version)...)
+}
+
+// lastConfigVersion returns the latest version of configType, nil if it has
+// no versions.
+func lastConfigVersion(tx *bolt.Tx, configType string) (*common.ConfigVersion, error) {
+	c := tx.Bucket([]byte(CONFIG_VERSION_BUCKET)).Cursor()
+	k, v := c.Seek(configVersionKey(configType, math.MaxUint64))
+	if k == nil {
+		k, v = c.Last()
+	} else {
+		k, v = c.Prev()
+	}
+	if k == nil || !bytes.HasPrefix(k, []byte(configType+"\x00")) {
+		return nil, nil
+	}
+	result := &common.ConfigVersion{}
+	return result, json.Unmarshal(v, result)
+}
+
+// appendConfigVersion stores proposal confirmed by confirmer as the next
+// version of its type.
+func appendConfigVersion(tx *bolt.Tx, proposal common.ConfigProposal, confirmer string) (common.ConfigVersion, error) {
+	last, err := lastConfigVersion(tx, proposal.Type)
+	if err != nil {
+		return common.ConfigVersion{}, err
+	}
+	version := common.NextConfigVersion(last, proposal, confirmer, common.GetTimepoint())
+	dataJSON, err := json.Marshal(version)
+	if err != nil {
+		return version, err
+	}
+	return version, tx.Bucket([]byte(CONFIG_VERSION_BUCKET)).Put(configVersionKey(version.Type, version.Version), dataJSON)
+}
+
+// storeConfig stores the value of proposal where the storage of its config
+// type keeps the current config.
+func storeConfig(tx *bolt.Tx, proposal common.ConfigProposal) error {
+	timepoint := boltutil.Uint64ToBytes(common.GetTimepoint())
+	switch proposal.Type {
+	case common.TargetQtyConfig:
+		request := metric.TargetQtyRequest{}
+		if err := json.Unmarshal(proposal.Value, &request); err != nil {
+			return err
+		}
+		return storeTokenTargetQty(tx, metric.TokenTargetQty{
+			ID:   proposal.ProposedAt,
+			Data: request.Data,
+			Type: request.Type,
+		})
+	case common.TargetQtyV2Config:
+		return tx.Bucket([]byte(TARGET_QUANTITY_V2)).Put([]byte(currentTargetQtyKey), proposal.Value)
+	case common.PWIEquationConfig:
+		request := metric.PWIEquationRequest{}
+		if err := json.Unmarshal(proposal.Value, &request); err != nil {
+			return err
+		}
+		return storePWIEquation(tx, metric.PWIEquation{ID: proposal.ProposedAt, Data: request.Data})
+	case common.PWIEquationV2Config:
+		return tx.Bucket([]byte(PWI_EQUATION_V2)).Put(timepoint, proposal.Value)
+	case common.RebalanceQuadraticConfig:
+		return tx.Bucket([]byte(REBALANCE_QUADRATIC)).Put(timepoint, proposal.Value)
+	case common.StableTokenParamsConfig:
+		b, err := tx.CreateBucketIfNotExists([]byte(STABLE_TOKEN_PARAMS_BUCKET))
+		if err != nil {
+			return err
+		}
+		return b.Put(boltutil.Uint64ToBytes(1), proposal.Value)
+	default:
+		return fmt.Errorf("config %s can't be stored", proposal.Type)
+	}
+}
+
+//StoreConfigProposal stores the pending proposal of a config type
+//return error if the type already has a pending proposal
+func (self *BoltStorage) StoreConfigProposal(proposal common.ConfigProposal) error {
+	return self.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(CONFIG_PROPOSAL_BUCKET))
+		if b.Get([]byte(proposal.Type)) != nil {
+			return fmt.Errorf("pending proposal of config %s exists", proposal.Type)
+		}
+		dataJSON, err := json.Marshal(proposal)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(proposal.Type), dataJSON)
+	})
+}
+
+//GetConfigProposal returns the pending proposal of a config type
+func (self *BoltStorage) GetConfigProposal(configType string) (common.ConfigProposal, error) {
+	var result common.ConfigProposal
+	err := self.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket([]byte(CONFIG_PROPOSAL_BUCKET)).Get([]byte(configType))
+		if data == nil {
+			return configProposalNotFound(configType)
+		}
+		return json.Unmarshal(data, &result)
+	})
+	return result, err
+}
+
+//RemoveConfigProposal removes the pending proposal of a config type
+func (self *BoltStorage) RemoveConfigProposal(configType string) error {
+	return self.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(CONFIG_PROPOSAL_BUCKET))
+		if b.Get([]byte(configType)) == nil {
+			return configProposalNotFound(configType)
+		}
+		return b.Delete([]byte(configType))
+	})
+}
+
+//ConfirmConfigProposal removes the pending proposal of a config type, stores
+//it as the current config and the next version in the same transaction,
+//hash must be the hash of the pending proposal
+func (self *BoltStorage) ConfirmConfigProposal(configType, hash, confirmer string) (common.ConfigVersion, error) {
+	var result common.ConfigVersion
+	err := self.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(CONFIG_PROPOSAL_BUCKET))
+		data := b.Get([]byte(configType))
+		if data == nil {
+			return configProposalNotFound(configType)
+		}
+		proposal := common.ConfigProposal{}
+		if err := json.Unmarshal(data, &proposal); err != nil {
+			return err
+		}
+		if proposal.Hash != hash {
+			return fmt.Errorf("confirmed proposal doesn't match the pending proposal of config %s", configType)
+		}
+		err := storeConfig(tx, proposal)
+		if err != nil {
+			return err
+		}
+		if result, err = appendConfigVersion(tx, proposal, confirmer); err != nil {
+			return err
+		}
+		return b.Delete([]byte(configType))
+	})
+	return result, err
+}
+
+//GetConfigVersion returns a version of a config type, the latest one if version is 0
+func (self *BoltStorage) GetConfigVersion(configType string, version uint64) (common.ConfigVersion, error) {
+	var result common.ConfigVersion
+	err := self.db.View(func(tx *bolt.Tx) error {
+		if version == 0 {
+			last, err := lastConfigVersion(tx, configType)
+			if err != nil {
+				return err
+			}
+			if last == nil {
+				return common.ErrConfigVersionNotFound
+			}
+			result = *last
+			return nil
+		}
+		data := tx.Bucket([]byte(CONFIG_VERSION_BUCKET)).Get(configVersionKey(configType, version))
+		if data == nil {
+			return common.ErrConfigVersionNotFound
+		}
+		return json.Unmarshal(data, &result)
+	})
+	return result, err
+}
+
+//GetConfigVersions returns all versions of a config type sorted by version
+func (self *BoltStorage) GetConfigVersions(configType string) ([]common.ConfigVersion, error) {
+	result := []common.ConfigVersion{}
+	prefix := []byte(configType + "\x00")
+	err := self.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(CONFIG_VERSION_BUCKET)).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			version := common.ConfigVersion{}
+			if err := json.Unmarshal(v, &version); err != nil {
+				return err
+			}
+			result = append(result, version)
+		}
+		return nil
+	})
+	return result, err
+}
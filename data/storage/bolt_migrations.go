@@ -0,0 +1,353 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/KyberNetwork/reserve-data/boltutil"
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/metric"
+	"github.com/boltdb/bolt"
+)
+
+const (
+	currentTargetQtyKey = "current_target_qty"
+	pendingTargetQtyKey = "current_pending_target_qty"
+)
+
+// BoltMigrations are the schema migrations of the core bolt database.
+var BoltMigrations = boltutil.Migrations{
+	{
+		Version:     1,
+		Description: "copy current target quantity v1 to target quantity v2 bucket",
+		Migrate:     migrateTargetQtyV1toV2,
+	},
+	{
+		Version:     2,
+		Description: "copy current PWI equation v1 to PWI equation v2 bucket",
+		Migrate:     migratePWIEquationV1toV2,
+	},
+	{
+		Version:     3,
+		Description: "encode auth data snapshots as keyframes and deltas",
+		Migrate:     migrateAuthDataToDeltas,
+	},
+	{
+		Version:     4,
+		Description: "index activities by action, destination, token and status",
+		Migrate:     indexActivities,
+	},
+	{
+		Version:     5,
+		Description: "record current configs as their first versions",
+		Migrate:     recordConfigVersions,
+	},
+	{
+		Version:     6,
+		Description: "move pending configs to config proposals",
+		Migrate:     moveLegacyConfigProposals,
+	},
+}
+
+// legacyConfig is where a config type was kept before config proposals and
+// versions. Keys are the keys of the current and pending configs, the last
+// key if they are nil, which is the timepoint the config is confirmed or
+// proposed at.
+type legacyConfig struct {
+	configType    string
+	bucket        string
+	key           []byte
+	pendingBucket string
+	pendingKey    []byte
+	// convert returns the config value of a stored value, stored values
+	// are config values if it is nil.
+	convert func([]byte) ([]byte, error)
+}
+
+var legacyConfigs = []legacyConfig{
+	{common.TargetQtyConfig, METRIC_TARGET_QUANTITY, nil, PENDING_TARGET_QUANTITY, nil, convertLegacyTargetQty},
+	{common.TargetQtyV2Config, TARGET_QUANTITY_V2, []byte(currentTargetQtyKey), PENDING_TARGET_QUANTITY_V2, []byte(pendingTargetQtyKey), nil},
+	{common.PWIEquationConfig, PWI_EQUATION, nil, PENDING_PWI_EQUATION, nil, convertLegacyPWIEquation},
+	{common.PWIEquationV2Config, PWI_EQUATION_V2, nil, PENDING_PWI_EQUATION_V2, nil, nil},
+	{common.RebalanceQuadraticConfig, REBALANCE_QUADRATIC, nil, PENDING_REBALANCE_QUADRATIC, nil, nil},
+	{common.StableTokenParamsConfig, STABLE_TOKEN_PARAMS_BUCKET, boltutil.Uint64ToBytes(1), PENDING_STABLE_TOKEN_PARAMS_BUCKET, boltutil.Uint64ToBytes(1), nil},
+}
+
+// convertLegacyTargetQty converts a stored target quantity v1 to its config value.
+func convertLegacyTargetQty(value []byte) ([]byte, error) {
+	targetQty := metric.TokenTargetQty{}
+	if err := json.Unmarshal(value, &targetQty); err != nil {
+		return nil, err
+	}
+	return json.Marshal(metric.TargetQtyRequest{Data: targetQty.Data, Type: targetQty.Type})
+}
+
+// convertLegacyPWIEquation converts a stored PWI equation v1 to its config value.
+func convertLegacyPWIEquation(value []byte) ([]byte, error) {
+	eq := metric.PWIEquation{}
+	if err := json.Unmarshal(value, &eq); err != nil {
+		return nil, err
+	}
+	return json.Marshal(metric.PWIEquationRequest{Data: eq.Data})
+}
+
+// legacyConfigEntry returns the entry at key of the bucket, or its last entry
+// if key is nil.
+func legacyConfigEntry(b *bolt.Bucket, key []byte) (k, v []byte) {
+	if key != nil {
+		return key, b.Get(key)
+	}
+	return b.Cursor().Last()
+}
+
+// legacyConfigValue returns the config value at key of the bucket, with the
+// timepoint of the last key if key is nil.
+func legacyConfigValue(tx *bolt.Tx, config legacyConfig, bucket string, key []byte) ([]byte, uint64, error) {
+	b := tx.Bucket([]byte(bucket))
+	if b == nil {
+		return nil, 0, nil
+	}
+	k, value := legacyConfigEntry(b, key)
+	if value == nil {
+		return nil, 0, nil
+	}
+	var timepoint uint64
+	if key == nil {
+		timepoint = boltutil.BytesToUint64(k)
+	}
+	if config.convert == nil {
+		return value, timepoint, nil
+	}
+	value, err := config.convert(value)
+	return value, timepoint, err
+}
+
+// legacyConfigProposals returns the configs pending in legacy buckets as
+// config proposals, which are proposed at the migration time if their
+// timepoints are unknown.
+func legacyConfigProposals(tx *bolt.Tx) ([]common.ConfigProposal, error) {
+	result := []common.ConfigProposal{}
+	for _, config := range legacyConfigs {
+		value, proposedAt, err := legacyConfigValue(tx, config, config.pendingBucket, config.pendingKey)
+		if err != nil {
+			return nil, fmt.Errorf("pending config %s can't be read: %s", config.configType, err)
+		}
+		if value == nil {
+			continue
+		}
+		if proposedAt == 0 {
+			proposedAt = common.GetTimepoint()
+		}
+		proposal, err := common.NewConfigProposal(config.configType, value, "", proposedAt)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, proposal)
+	}
+	return result, nil
+}
+
+// migrateTargetQtyV1toV2 stores the latest target quantity v1 as the current
+// target quantity v2 if there is none.
+func migrateTargetQtyV1toV2(tx *bolt.Tx) error {
+	v2, err := tx.CreateBucketIfNotExists([]byte(TARGET_QUANTITY_V2))
+	if err != nil {
+		return err
+	}
+	if v2.Get([]byte(currentTargetQtyKey)) != nil {
+		return nil
+	}
+	v1 := tx.Bucket([]byte(METRIC_TARGET_QUANTITY))
+	if v1 == nil {
+		return nil
+	}
+	_, v := v1.Cursor().Last()
+	if v == nil {
+		return nil
+	}
+	var targetQty metric.TokenTargetQty
+	if err = json.Unmarshal(v, &targetQty); err != nil {
+		return err
+	}
+	data, err := json.Marshal(convertTargetQtyV1toV2(targetQty))
+	if err != nil {
+		return err
+	}
+	return v2.Put([]byte(currentTargetQtyKey), data)
+}
+
+// migratePWIEquationV1toV2 stores the latest PWI equation v1 as the first PWI
+// equation v2 if there is none, keyed by the timepoint of the v1 equation.
+func migratePWIEquationV1toV2(tx *bolt.Tx) error {
+	v2, err := tx.CreateBucketIfNotExists([]byte(PWI_EQUATION_V2))
+	if err != nil {
+		return err
+	}
+	if k, _ := v2.Cursor().First(); k != nil {
+		return nil
+	}
+	v1 := tx.Bucket([]byte(PWI_EQUATION))
+	if v1 == nil {
+		return nil
+	}
+	k, v := v1.Cursor().Last()
+	if v == nil {
+		return nil
+	}
+	var eqv1 metric.PWIEquation
+	if err = json.Unmarshal(v, &eqv1); err != nil {
+		return err
+	}
+	eqv2, err := convertPWIEquationV1toV2(eqv1.Data)
+	if err != nil {
+		// keep the database usable, the next confirmed equation replaces it
+		log.Printf("PWI equation v1 %s can't be converted to v2: %s", eqv1.Data, err)
+		return nil
+	}
+	data, err := json.Marshal(eqv2)
+	if err != nil {
+		return err
+	}
+	return v2.Put(k, data)
+}
+
+// migrateAuthDataToDeltas re-encodes full auth data snapshots as deltas from
+// the latest keyframe where possible. Existing deltas are kept, so running it
+// again changes nothing.
+func migrateAuthDataToDeltas(tx *bolt.Tx) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(AUTH_DATA_BUCKET))
+	if err != nil {
+		return err
+	}
+	encoder := &authDataEncoder{}
+	c := b.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		timepoint := boltutil.BytesToUint64(k)
+		if isAuthDataDelta(v) {
+			// the keyframe of an existing delta is the current one
+			if encoder.base, encoder.keyframe, err = keyframeOf(boltAuthDataStore{b}, timepoint, v); err != nil {
+				return err
+			}
+			continue
+		}
+		value, err := encoder.next(timepoint, append([]byte{}, v...))
+		if err != nil {
+			return err
+		}
+		if !isAuthDataDelta(value) {
+			continue
+		}
+		key := append([]byte{}, k...)
+		if err = b.Put(key, value); err != nil {
+			return err
+		}
+		// the cursor must be repositioned after the bucket is modified
+		c.Seek(key)
+	}
+	return nil
+}
+
+// indexActivities adds index entries of all activities.
+func indexActivities(tx *bolt.Tx) error {
+	index, err := tx.CreateBucketIfNotExists([]byte(ACTIVITY_INDEX_BUCKET))
+	if err != nil {
+		return err
+	}
+	b := tx.Bucket([]byte(ACTIVITY_BUCKET))
+	if b == nil {
+		return nil
+	}
+	return b.ForEach(func(k, v []byte) error {
+		record := common.ActivityRecord{}
+		if err := json.Unmarshal(v, &record); err != nil {
+			return err
+		}
+		for _, key := range activityIndexKeys(k, record) {
+			if err := index.Put(key, []byte{}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// recordConfigVersions records the current configs as the first versions of
+// their config types, so they can be diffed and rolled back to.
+// Types which already have versions are skipped.
+func recordConfigVersions(tx *bolt.Tx) error {
+	versions, err := tx.CreateBucketIfNotExists([]byte(CONFIG_VERSION_BUCKET))
+	if err != nil {
+		return err
+	}
+	for _, config := range legacyConfigs {
+		last, err := lastConfigVersion(tx, config.configType)
+		if err != nil {
+			return err
+		}
+		if last != nil {
+			continue
+		}
+		value, confirmedAt, err := legacyConfigValue(tx, config, config.bucket, config.key)
+		if err != nil {
+			log.Printf("current config %s can't be read: %s", config.configType, err)
+			continue
+		}
+		if value == nil {
+			continue
+		}
+		proposal, err := common.NewConfigProposal(config.configType, value, "", confirmedAt)
+		if err != nil {
+			log.Printf("current config %s can't be recorded as a version: %s", config.configType, err)
+			continue
+		}
+		version := common.NextConfigVersion(nil, proposal, "", confirmedAt)
+		data, err := json.Marshal(version)
+		if err != nil {
+			return err
+		}
+		if err = versions.Put(configVersionKey(version.Type, version.Version), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveLegacyConfigProposals moves configs pending in legacy buckets to config
+// proposals, so they can still be confirmed or rejected. A legacy pending
+// config is dropped if its type already has a config proposal.
+func moveLegacyConfigProposals(tx *bolt.Tx) error {
+	proposals, err := tx.CreateBucketIfNotExists([]byte(CONFIG_PROPOSAL_BUCKET))
+	if err != nil {
+		return err
+	}
+	pendings, err := legacyConfigProposals(tx)
+	if err != nil {
+		return err
+	}
+	for _, proposal := range pendings {
+		if proposals.Get([]byte(proposal.Type)) != nil {
+			log.Printf("pending config %s is dropped, it already has a proposal: %s", proposal.Type, proposal.Value)
+			continue
+		}
+		data, err := json.Marshal(proposal)
+		if err != nil {
+			return err
+		}
+		if err = proposals.Put([]byte(proposal.Type), data); err != nil {
+			return err
+		}
+	}
+	for _, config := range legacyConfigs {
+		b := tx.Bucket([]byte(config.pendingBucket))
+		if b == nil {
+			continue
+		}
+		if k, v := legacyConfigEntry(b, config.pendingKey); v != nil {
+			if err = b.Delete(k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -7,62 +7,34 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/KyberNetwork/reserve-data/boltutil"
 	"github.com/KyberNetwork/reserve-data/common"
 	"github.com/KyberNetwork/reserve-data/data/testutil"
 	"github.com/KyberNetwork/reserve-data/metric"
+	"github.com/boltdb/bolt"
 )
 
-func TestHasPendingDepositBoltStorage(t *testing.T) {
-	boltFile := "test_bolt.db"
-	tmpDir, err := ioutil.TempDir("", "pending_deposit")
+func newTestBoltStorage(t *testing.T) (testStorage, func()) {
+	tmpDir, err := ioutil.TempDir("", "test_bolt_storage")
 	if err != nil {
 		t.Fatal(err)
 	}
-	storage, err := NewBoltStorage(filepath.Join(tmpDir, boltFile))
+	storage, err := NewBoltStorage(filepath.Join(tmpDir, "test_bolt.db"))
 	if err != nil {
 		t.Fatalf("Couldn't init bolt storage %v", err)
 	}
-	token := common.NewToken("OMG", "0x1111111111111111111111111111111111111111", 18)
-	exchange := common.TestExchange{}
-	timepoint := common.GetTimepoint()
-	out, err := storage.HasPendingDeposit(token, exchange)
-	if err != nil {
-		t.Fatalf(err.Error())
-	}
-	if out != false {
-		t.Fatalf("Expected ram storage to return true false there is no pending deposit for the same currency and exchange")
-	}
-	err = storage.Record(
-		"deposit",
-		common.NewActivityID(1, "1"),
-		string(exchange.ID()),
-		map[string]interface{}{
-			"exchange":  exchange,
-			"token":     token,
-			"amount":    "1.0",
-			"timepoint": timepoint,
-		},
-		map[string]interface{}{
-			"tx":    "",
-			"error": nil,
-		},
-		"",
-		"submitted",
-		common.GetTimepoint())
-	if err != nil {
-		t.Fatalf("Store activity error: %s", err.Error())
-	}
-	out, err = storage.HasPendingDeposit(token, exchange)
-	if err != nil {
-		t.Fatalf(err.Error())
-	}
-	if out != true {
-		t.Fatalf("Expected ram storage to return true when there is pending deposit")
+	return storage, func() {
+		if err := storage.db.Close(); err != nil {
+			t.Error(err)
+		}
+		if err := os.RemoveAll(tmpDir); err != nil {
+			t.Error(err)
+		}
 	}
+}
 
-	if err = os.RemoveAll(tmpDir); err != nil {
-		t.Error(err)
-	}
+func TestBoltStorage(t *testing.T) {
+	runStorageTests(t, newTestBoltStorage)
 }
 
 func TestGlobalStorageBoltImplementation(t *testing.T) {
@@ -101,3 +73,173 @@ func TestConvertTargetQtyV1toV2(t *testing.T) {
 		}
 	}
 }
+
+func TestBoltMigrations(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test_bolt_migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}()
+	path := filepath.Join(tmpDir, "test_bolt.db")
+	// a database created before versioning, with only v1 settings
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		targetQty, cErr := tx.CreateBucketIfNotExists([]byte(METRIC_TARGET_QUANTITY))
+		if cErr != nil {
+			return cErr
+		}
+		if cErr = targetQty.Put(boltutil.Uint64ToBytes(1000), []byte(`{"id": 1000, "data": "KNC_1_2_3_4", "status": "confirmed"}`)); cErr != nil {
+			return cErr
+		}
+		pwi, cErr := tx.CreateBucketIfNotExists([]byte(PWI_EQUATION))
+		if cErr != nil {
+			return cErr
+		}
+		if cErr = pwi.Put(boltutil.Uint64ToBytes(2000), []byte(`{"id": 2000, "data": "KNC_0.1_0.2_0.3"}`)); cErr != nil {
+			return cErr
+		}
+		// configs pending in legacy buckets
+		pendingTargetQty, cErr := tx.CreateBucketIfNotExists([]byte(PENDING_TARGET_QUANTITY))
+		if cErr != nil {
+			return cErr
+		}
+		if cErr = pendingTargetQty.Put(boltutil.Uint64ToBytes(4000), []byte(`{"id": 4000, "data": "KNC_5_6_7_8", "status": "unconfirmed"}`)); cErr != nil {
+			return cErr
+		}
+		pendingPWI, cErr := tx.CreateBucketIfNotExists([]byte(PENDING_PWI_EQUATION_V2))
+		if cErr != nil {
+			return cErr
+		}
+		if cErr = pendingPWI.Put(boltutil.Uint64ToBytes(5000), []byte(`{"KNC":{"bid":{"a":0.4,"b":0.5,"c":0.6}}}`)); cErr != nil {
+			return cErr
+		}
+		authData, cErr := tx.CreateBucketIfNotExists([]byte(AUTH_DATA_BUCKET))
+		if cErr != nil {
+			return cErr
+		}
+		for i, timepoint := range []uint64{1000, 6000, 11000} {
+			data, mErr := json.Marshal(newTestAuthData(timepoint, float64(i)))
+			if mErr != nil {
+				return mErr
+			}
+			if cErr = authData.Put(boltutil.Uint64ToBytes(timepoint), data); cErr != nil {
+				return cErr
+			}
+		}
+		activities, cErr := tx.CreateBucketIfNotExists([]byte(ACTIVITY_BUCKET))
+		if cErr != nil {
+			return cErr
+		}
+		id := common.NewActivityID(3000, "legacy")
+		idBytes := id.ToBytes()
+		return activities.Put(idBytes[:], []byte(`{"Action": "deposit", "ID": "3000|legacy", "Destination": "binance", "Params": {"token": "KNC"}, "ExchangeStatus": "done", "MiningStatus": "mined"}`))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	storage, err := NewBoltStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if cErr := storage.db.Close(); cErr != nil {
+			t.Error(cErr)
+		}
+	}()
+	if version, vErr := boltutil.SchemaVersion(storage.db); vErr != nil || version != BoltMigrations.Latest() {
+		t.Fatalf("expected latest schema version, got %d, err %v", version, vErr)
+	}
+	err = storage.db.View(func(tx *bolt.Tx) error {
+		targetQty := metric.TokenTargetQtyV2{}
+		if uErr := json.Unmarshal(tx.Bucket([]byte(TARGET_QUANTITY_V2)).Get([]byte(currentTargetQtyKey)), &targetQty); uErr != nil {
+			return uErr
+		}
+		if targetQty["KNC"].SetTarget.TotalTarget != 1 {
+			t.Errorf("unexpected migrated target quantity %+v", targetQty)
+		}
+		k, v := tx.Bucket([]byte(PWI_EQUATION_V2)).Cursor().Last()
+		eq := metric.PWIEquationRequestV2{}
+		if uErr := json.Unmarshal(v, &eq); uErr != nil {
+			return uErr
+		}
+		if boltutil.BytesToUint64(k) != 2000 || eq["KNC"]["bid"].A != 0.1 {
+			t.Errorf("unexpected migrated PWI equation %d %+v", boltutil.BytesToUint64(k), eq)
+		}
+		for _, bucket := range []string{PENDING_TARGET_QUANTITY, PENDING_PWI_EQUATION_V2} {
+			if k, _ := tx.Bucket([]byte(bucket)).Cursor().First(); k != nil {
+				t.Errorf("expected pending config moved out of %s", bucket)
+			}
+		}
+		authData := tx.Bucket([]byte(AUTH_DATA_BUCKET))
+		if isAuthDataDelta(authData.Get(boltutil.Uint64ToBytes(1000))) || !isAuthDataDelta(authData.Get(boltutil.Uint64ToBytes(11000))) {
+			t.Error("expected the first auth data snapshot kept as keyframe and later ones encoded as deltas")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, timepoint := range []uint64{1000, 6000, 11000} {
+		snapshot, gErr := storage.GetAuthData(common.Version(timepoint))
+		if gErr != nil {
+			t.Fatal(gErr)
+		}
+		if !sameAuthData(t, newTestAuthData(timepoint, float64(i)), snapshot) {
+			t.Errorf("migrated auth data at %d is not reconstructed", timepoint)
+		}
+	}
+	page, err := storage.GetActivityPage(common.ActivityFilter{ToTime: 10000, Token: "KNC", Status: "mined"}, nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Activities) != 1 || page.Activities[0].ID.EID != "legacy" {
+		t.Errorf("expected the existing activity to be indexed, got %+v", page.Activities)
+	}
+	// migrated configs are recorded as their first versions
+	pwiVersion, err := storage.GetConfigVersion(common.PWIEquationV2Config, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pwiVersion.Version != 1 || pwiVersion.ConfirmedAt != 2000 {
+		t.Errorf("unexpected first version of PWI equation %+v", pwiVersion)
+	}
+	if _, err = storage.GetConfigVersion(common.TargetQtyV2Config, 1); err != nil {
+		t.Errorf("expected target quantity recorded as a version: %s", err)
+	}
+	if _, err = storage.GetConfigVersion(common.RebalanceQuadraticConfig, 0); err != common.ErrConfigVersionNotFound {
+		t.Errorf("expected no versions of rebalance quadratic equation, got %v", err)
+	}
+	targetQtyVersion, err := storage.GetConfigVersion(common.TargetQtyConfig, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if targetQtyVersion.ConfirmedAt != 1000 || string(targetQtyVersion.Value) != `{"data":"KNC_1_2_3_4","type":0}` {
+		t.Errorf("unexpected first version of target quantity v1 %+v", targetQtyVersion)
+	}
+	if pwiV1Version, gErr := storage.GetConfigVersion(common.PWIEquationConfig, 1); gErr != nil || pwiV1Version.ConfirmedAt != 2000 {
+		t.Errorf("unexpected first version of PWI equation v1 %+v, err %v", pwiV1Version, gErr)
+	}
+	// legacy pending configs are moved to config proposals
+	targetQtyProposal, err := storage.GetConfigProposal(common.TargetQtyConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if targetQtyProposal.ProposedAt != 4000 || string(targetQtyProposal.Value) != `{"data":"KNC_5_6_7_8","type":0}` ||
+		targetQtyProposal.Hash != targetQtyProposal.ComputeHash() {
+		t.Errorf("unexpected moved target quantity proposal %+v", targetQtyProposal)
+	}
+	if pwiProposal, gErr := storage.GetConfigProposal(common.PWIEquationV2Config); gErr != nil || pwiProposal.ProposedAt != 5000 {
+		t.Errorf("unexpected moved PWI equation proposal %+v, err %v", pwiProposal, gErr)
+	}
+}
@@ -0,0 +1,297 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/KyberNetwork/reserve-data/boltutil"
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/boltdb/bolt"
+)
+
+// versionedBuckets are the bolt buckets migrated to SQL tables of the same names.
+var versionedBuckets = []string{PRICE_BUCKET, AUTH_DATA_BUCKET, GOLD_BUCKET, METRIC_BUCKET}
+
+// settingBuckets are the bolt buckets migrated to the settings table.
+var settingBuckets = []string{
+	METRIC_TARGET_QUANTITY,
+	ENABLE_REBALANCE,
+	SETRATE_CONTROL,
+	PWI_EQUATION,
+	STABLE_TOKEN_PARAMS_BUCKET,
+	TARGET_QUANTITY_V2,
+	PWI_EQUATION_V2,
+	REBALANCE_QUADRATIC,
+	PENDING_STEP_FUNCTIONS,
+	STEP_FUNCTIONS,
+	PENDING_STEP_FUNCTION_SUBMISSIONS,
+	BALANCE_THRESHOLDS_BUCKET,
+	BALANCE_STATUS_BUCKET,
+}
+
+// singleValueSettings are the settings stored with constant keys in bolt.
+var singleValueSettings = map[string]bool{
+	STABLE_TOKEN_PARAMS_BUCKET:        true,
+	TARGET_QUANTITY_V2:                true,
+	PENDING_STEP_FUNCTION_SUBMISSIONS: true,
+}
+
+// MigrateBoltToSQL copies all data of the bolt storage to the SQL storage in
+// a single transaction, records existing in the SQL storage are replaced.
+// It returns the number of migrated records of each bolt bucket.
+func MigrateBoltToSQL(src *BoltStorage, dst *SQLStorage) (map[string]uint64, error) {
+	result := map[string]uint64{}
+	err := src.db.View(func(btx *bolt.Tx) error {
+		return dst.update(func(tx *sql.Tx) error {
+			for _, bucket := range versionedBuckets {
+				err := btx.Bucket([]byte(bucket)).ForEach(func(k, v []byte) error {
+					result[bucket]++
+					return putVersion(tx, bucket, boltutil.BytesToUint64(k), json.RawMessage(v))
+				})
+				if err != nil {
+					return fmt.Errorf("migrating %s failed: %s", bucket, err)
+				}
+			}
+			migrations := []struct {
+				bucket  string
+				migrate func(*bolt.Tx, *sql.Tx) (uint64, error)
+			}{
+				{RATE_BUCKET, migrateRates},
+				{ACTIVITY_BUCKET, migrateActivities},
+				{EXCHANGE_STATUS, migrateExchangeStatus},
+				{EXCHANGE_NOTIFICATIONS, migrateExchangeNotifications},
+				{GAS_SPEND_BUCKET, migrateGasSpend},
+				{PENDING_GAS_SPEND_BUCKET, migratePendingGasSpend},
+				{PRICE_HISTORY_BUCKET, migratePriceHistory},
+				{API_KEY_BUCKET, migrateAPIKeys},
+				{AUDIT_LOG_BUCKET, migrateAuditLog},
+				{CONFIG_PROPOSAL_BUCKET, migrateConfigProposals},
+				{CONFIG_VERSION_BUCKET, migrateConfigVersions},
+			}
+			for _, m := range migrations {
+				n, err := m.migrate(btx, tx)
+				if err != nil {
+					return fmt.Errorf("migrating %s failed: %s", m.bucket, err)
+				}
+				result[m.bucket] = n
+			}
+			for _, bucket := range settingBuckets {
+				err := btx.Bucket([]byte(bucket)).ForEach(func(k, v []byte) error {
+					timepoint := boltutil.BytesToUint64(k)
+					if singleValueSettings[bucket] {
+						timepoint = currentSettingTimepoint
+					}
+					result[bucket]++
+					return putSetting(tx, bucket, timepoint, v)
+				})
+				if err != nil {
+					return fmt.Errorf("migrating %s failed: %s", bucket, err)
+				}
+			}
+			return nil
+		})
+	})
+	return result, err
+}
+
+func migrateRates(btx *bolt.Tx, tx *sql.Tx) (uint64, error) {
+	var n uint64
+	err := btx.Bucket([]byte(RATE_BUCKET)).ForEach(func(k, v []byte) error {
+		data := common.AllRateEntry{}
+		if err := json.Unmarshal(v, &data); err != nil {
+			return err
+		}
+		n++
+		return putRate(tx, data, boltutil.BytesToUint64(k))
+	})
+	return n, err
+}
+
+// migrateActivities copies activities, those in pending activities bucket are
+// still pending.
+func migrateActivities(btx *bolt.Tx, tx *sql.Tx) (uint64, error) {
+	var n uint64
+	pending := btx.Bucket([]byte(PENDING_ACTIVITY_BUCKET))
+	err := btx.Bucket([]byte(ACTIVITY_BUCKET)).ForEach(func(k, v []byte) error {
+		record := common.ActivityRecord{}
+		if err := json.Unmarshal(v, &record); err != nil {
+			return err
+		}
+		n++
+		return putActivity(tx, record, pending.Get(k) != nil)
+	})
+	return n, err
+}
+
+func migrateExchangeStatus(btx *bolt.Tx, tx *sql.Tx) (uint64, error) {
+	data := common.ExchangesStatus{}
+	err := btx.Bucket([]byte(EXCHANGE_STATUS)).ForEach(func(k, v []byte) error {
+		var exstat common.ExStatus
+		if err := json.Unmarshal(v, &exstat); err != nil {
+			return err
+		}
+		data[string(k)] = exstat
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return uint64(len(data)), putExchangesStatus(tx, data)
+}
+
+// migrateExchangeNotifications copies notifications, which are in a sub bucket per
+// exchange and keyed by action and token joined by underscore.
+func migrateExchangeNotifications(btx *bolt.Tx, tx *sql.Tx) (uint64, error) {
+	var n uint64
+	exchangeBks := btx.Bucket([]byte(EXCHANGE_NOTIFICATIONS))
+	err := exchangeBks.ForEach(func(name, v []byte) error {
+		b := exchangeBks.Bucket(name)
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			actionToken := strings.SplitN(string(k), "_", 2)
+			if len(actionToken) != 2 {
+				return fmt.Errorf("invalid notification key %s", k)
+			}
+			noti := common.ExchangeNotiContent{}
+			if err := json.Unmarshal(v, &noti); err != nil {
+				return err
+			}
+			n++
+			return putExchangeNotification(tx, string(name), actionToken[0], actionToken[1], noti)
+		})
+	})
+	return n, err
+}
+
+func migrateGasSpend(btx *bolt.Tx, tx *sql.Tx) (uint64, error) {
+	var n uint64
+	err := btx.Bucket([]byte(GAS_SPEND_BUCKET)).ForEach(func(k, v []byte) error {
+		record := common.GasSpendRecord{}
+		if err := json.Unmarshal(v, &record); err != nil {
+			return err
+		}
+		n++
+		return putGasSpend(tx, record)
+	})
+	return n, err
+}
+
+func migratePendingGasSpend(btx *bolt.Tx, tx *sql.Tx) (uint64, error) {
+	var n uint64
+	err := btx.Bucket([]byte(PENDING_GAS_SPEND_BUCKET)).ForEach(func(k, v []byte) error {
+		job := common.PendingGasSpend{}
+		if err := json.Unmarshal(v, &job); err != nil {
+			return err
+		}
+		n++
+		return putPendingGasSpend(tx, job)
+	})
+	return n, err
+}
+
+// migratePriceHistory copies price history, which is in a bucket per resolution,
+// pair and exchange.
+func migratePriceHistory(btx *bolt.Tx, tx *sql.Tx) (uint64, error) {
+	var n uint64
+	root := btx.Bucket([]byte(PRICE_HISTORY_BUCKET))
+	if root == nil {
+		return 0, nil
+	}
+	store := sqlPriceHistoryStore{tx}
+	err := root.ForEach(func(resolution, v []byte) error {
+		resolutionBk := root.Bucket(resolution)
+		return resolutionBk.ForEach(func(pair, v []byte) error {
+			pairBk := resolutionBk.Bucket(pair)
+			return pairBk.ForEach(func(exchange, v []byte) error {
+				return pairBk.Bucket(exchange).ForEach(func(k, v []byte) error {
+					var candle common.PriceCandle
+					if err := json.Unmarshal(v, &candle); err != nil {
+						return err
+					}
+					n++
+					return store.putPriceCandle(string(resolution), common.TokenPairID(pair), common.ExchangeID(exchange), candle)
+				})
+			})
+		})
+	})
+	return n, err
+}
+
+func migrateAPIKeys(btx *bolt.Tx, tx *sql.Tx) (uint64, error) {
+	var n uint64
+	err := btx.Bucket([]byte(API_KEY_BUCKET)).ForEach(func(k, v []byte) error {
+		key := common.APIKey{}
+		if err := json.Unmarshal(v, &key); err != nil {
+			return err
+		}
+		n++
+		return putAPIKey(tx, key)
+	})
+	return n, err
+}
+
+// migrateConfigProposals copies config proposals and moves configs pending in
+// legacy buckets to config proposals, unless their types already have one.
+func migrateConfigProposals(btx *bolt.Tx, tx *sql.Tx) (uint64, error) {
+	var n uint64
+	b := btx.Bucket([]byte(CONFIG_PROPOSAL_BUCKET))
+	err := b.ForEach(func(k, v []byte) error {
+		n++
+		_, err := tx.Exec(`INSERT INTO config_proposals (type, data) VALUES ($1, $2)`, string(k), string(v))
+		return err
+	})
+	if err != nil {
+		return n, err
+	}
+	pendings, err := legacyConfigProposals(btx)
+	if err != nil {
+		return n, err
+	}
+	for _, proposal := range pendings {
+		if b.Get([]byte(proposal.Type)) != nil {
+			log.Printf("pending config %s is dropped, it already has a proposal: %s", proposal.Type, proposal.Value)
+			continue
+		}
+		data, err := json.Marshal(proposal)
+		if err != nil {
+			return n, err
+		}
+		n++
+		if _, err = tx.Exec(`INSERT INTO config_proposals (type, data) VALUES ($1, $2)`, proposal.Type, string(data)); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func migrateConfigVersions(btx *bolt.Tx, tx *sql.Tx) (uint64, error) {
+	var n uint64
+	err := btx.Bucket([]byte(CONFIG_VERSION_BUCKET)).ForEach(func(k, v []byte) error {
+		version := common.ConfigVersion{}
+		if err := json.Unmarshal(v, &version); err != nil {
+			return err
+		}
+		n++
+		return putConfigVersion(tx, version)
+	})
+	return n, err
+}
+
+// migrateAuditLog copies audit records as they are, so the chain stays valid.
+func migrateAuditLog(btx *bolt.Tx, tx *sql.Tx) (uint64, error) {
+	var n uint64
+	err := btx.Bucket([]byte(AUDIT_LOG_BUCKET)).ForEach(func(k, v []byte) error {
+		record := common.AuditRecord{}
+		if err := json.Unmarshal(v, &record); err != nil {
+			return err
+		}
+		n++
+		return putAuditRecord(tx, record)
+	})
+	return n, err
+}
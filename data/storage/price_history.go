@@ -0,0 +1,255 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/boltutil"
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/boltdb/bolt"
+)
+
+// Price history is a time series of top of book and depth of every pair and
+// exchange, kept at several resolutions. The raw resolution has a point of
+// every stored price version, other resolutions have OHLC candles of the mid
+// price, updated as prices are stored. Each resolution has its own retention,
+// independent from the MAX_NUMBER_VERSION order books kept in PRICE_BUCKET.
+const (
+	// PRICE_HISTORY_BUCKET has a bucket per resolution, with a bucket per
+	// pair of a bucket per exchange, keyed by timepoint.
+	PRICE_HISTORY_BUCKET string = "price_history"
+
+	// RawPriceHistory is the resolution of price points without downsampling.
+	RawPriceHistory = "raw"
+)
+
+// priceHistoryResolution describes a resolution of price history.
+type priceHistoryResolution struct {
+	name string
+	// width of candles in millisecond, 0 for raw points
+	width uint64
+	// defaultRetention is used if the retention isn't configured.
+	defaultRetention uint64
+	// maxRange is the longest time range of a query, which bounds the size of results.
+	maxRange uint64
+}
+
+const priceHistoryDay uint64 = 86400000 // 1 day in milisec
+
+// priceHistoryResolutions are the supported resolutions, raw points are
+// stored every fetch, about every 10 seconds.
+var priceHistoryResolutions = []priceHistoryResolution{
+	{name: RawPriceHistory, width: 0, defaultRetention: 7 * priceHistoryDay, maxRange: priceHistoryDay},
+	{name: "1m", width: 60000, defaultRetention: 30 * priceHistoryDay, maxRange: 7 * priceHistoryDay},
+	{name: "5m", width: 300000, defaultRetention: 180 * priceHistoryDay, maxRange: 30 * priceHistoryDay},
+	{name: "1h", width: 3600000, defaultRetention: 730 * priceHistoryDay, maxRange: 365 * priceHistoryDay},
+}
+
+func getPriceHistoryResolution(name string) (priceHistoryResolution, error) {
+	for _, resolution := range priceHistoryResolutions {
+		if resolution.name == name {
+			return resolution, nil
+		}
+	}
+	names := []string{}
+	for _, resolution := range priceHistoryResolutions {
+		names = append(names, resolution.name)
+	}
+	return priceHistoryResolution{}, fmt.Errorf("resolution %s is not supported, use one of %s", name, strings.Join(names, ", "))
+}
+
+// candleTimestamp returns the start of the candle of timepoint.
+func (self priceHistoryResolution) candleTimestamp(timepoint uint64) uint64 {
+	if self.width == 0 {
+		return timepoint
+	}
+	return timepoint / self.width * self.width
+}
+
+// PriceHistoryConfig is the retention and depth sizes of price history.
+type PriceHistoryConfig struct {
+	// Retention is how long each resolution is kept, in millisecond.
+	Retention map[string]uint64
+	// DepthSizes are the order sizes, in quote token, of depth at size.
+	DepthSizes []float64
+}
+
+// defaultDepthSizes are the depth sizes in ETH if they aren't configured.
+var defaultDepthSizes = []float64{1, 10, 50}
+
+// DefaultPriceHistoryConfig returns the default retention of all resolutions
+// and default depth sizes.
+func DefaultPriceHistoryConfig() PriceHistoryConfig {
+	result := PriceHistoryConfig{Retention: map[string]uint64{}, DepthSizes: defaultDepthSizes}
+	for _, resolution := range priceHistoryResolutions {
+		result.Retention[resolution.name] = resolution.defaultRetention
+	}
+	return result
+}
+
+// newPriceHistoryConfig returns the configuration of retention given as
+// durations by resolution and depth sizes, missing values are the default.
+func newPriceHistoryConfig(retention map[string]string, depthSizes []float64) (PriceHistoryConfig, error) {
+	result := DefaultPriceHistoryConfig()
+	for name, value := range retention {
+		if _, err := getPriceHistoryResolution(name); err != nil {
+			return result, err
+		}
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return result, fmt.Errorf("invalid retention of price history %s: %s", name, err)
+		}
+		if duration < time.Hour {
+			return result, fmt.Errorf("retention of price history %s is shorter than an hour", name)
+		}
+		result.Retention[name] = uint64(duration / time.Millisecond)
+	}
+	if len(depthSizes) > 0 {
+		for _, size := range depthSizes {
+			if size <= 0 {
+				return result, fmt.Errorf("invalid depth size %f", size)
+			}
+		}
+		result.DepthSizes = append([]float64{}, depthSizes...)
+		sort.Float64s(result.DepthSizes)
+	}
+	return result, nil
+}
+
+// depthAtSize returns the average price to fill size in quote token with
+// the price entries, best first, 0 if they are not enough.
+func depthAtSize(entries []common.PriceEntry, size float64) float64 {
+	var quote, base float64
+	for _, entry := range entries {
+		if entry.Rate <= 0 {
+			continue
+		}
+		remain := size - quote
+		if entry.Quantity*entry.Rate >= remain {
+			return size / (base + remain/entry.Rate)
+		}
+		quote += entry.Quantity * entry.Rate
+		base += entry.Quantity
+	}
+	return 0
+}
+
+// newPricePoint returns the price point of an order book, false if it is
+// invalid or one of its sides is empty.
+func newPricePoint(price common.ExchangePrice, timepoint uint64, depthSizes []float64) (common.PricePoint, bool) {
+	if !price.Valid || len(price.Bids) == 0 || len(price.Asks) == 0 {
+		return common.PricePoint{}, false
+	}
+	point := common.PricePoint{
+		Timestamp: timepoint,
+		BestBid:   price.Bids[0].Rate,
+		BestAsk:   price.Asks[0].Rate,
+		BidDepth:  []common.DepthAtSize{},
+		AskDepth:  []common.DepthAtSize{},
+	}
+	point.Mid = (point.BestBid + point.BestAsk) / 2
+	for _, size := range depthSizes {
+		point.BidDepth = append(point.BidDepth, common.DepthAtSize{Size: size, Price: depthAtSize(price.Bids, size)})
+		point.AskDepth = append(point.AskDepth, common.DepthAtSize{Size: size, Price: depthAtSize(price.Asks, size)})
+	}
+	return point, true
+}
+
+// priceHistoryStore is the access to stored price history of a storage
+// backend.
+type priceHistoryStore interface {
+	// getPriceCandle returns the candle starting at timestamp, nil if there is none.
+	getPriceCandle(resolution string, pair common.TokenPairID, exchange common.ExchangeID, timestamp uint64) (*common.PriceCandle, error)
+	putPriceCandle(resolution string, pair common.TokenPairID, exchange common.ExchangeID, candle common.PriceCandle) error
+}
+
+// recordPriceHistory adds the price points of all order books stored at
+// timepoint to every resolution.
+func recordPriceHistory(store priceHistoryStore, data common.AllPriceEntry, timepoint uint64, depthSizes []float64) error {
+	for pair, onePrice := range data.Data {
+		for exchange, price := range onePrice {
+			point, ok := newPricePoint(price, timepoint, depthSizes)
+			if !ok {
+				continue
+			}
+			for _, resolution := range priceHistoryResolutions {
+				timestamp := resolution.candleTimestamp(timepoint)
+				candle, err := store.getPriceCandle(resolution.name, pair, exchange, timestamp)
+				if err != nil {
+					return err
+				}
+				if candle == nil {
+					newCandle := common.NewPriceCandle(timestamp, point)
+					candle = &newCandle
+				} else {
+					candle.Add(point)
+				}
+				if err = store.putPriceCandle(resolution.name, pair, exchange, *candle); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validatePriceHistoryQuery returns an error if the resolution is not
+// supported or the time range is longer than its max range.
+func validatePriceHistoryQuery(resolution string, fromTime, toTime uint64) error {
+	res, err := getPriceHistoryResolution(resolution)
+	if err != nil {
+		return err
+	}
+	if fromTime > toTime {
+		return fmt.Errorf("fromTime %d is after toTime %d", fromTime, toTime)
+	}
+	if toTime-fromTime > res.maxRange {
+		return fmt.Errorf("time range of resolution %s must be at most %d milliseconds", resolution, res.maxRange)
+	}
+	return nil
+}
+
+// boltPriceHistoryStore is the price history bucket of a bolt transaction.
+type boltPriceHistoryStore struct {
+	b *bolt.Bucket
+}
+
+func (self boltPriceHistoryStore) getPriceCandle(resolution string, pair common.TokenPairID, exchange common.ExchangeID, timestamp uint64) (*common.PriceCandle, error) {
+	b := self.b.Bucket([]byte(resolution))
+	if b != nil {
+		b = b.Bucket([]byte(pair))
+	}
+	if b != nil {
+		b = b.Bucket([]byte(exchange))
+	}
+	if b == nil {
+		return nil, nil
+	}
+	v := b.Get(boltutil.Uint64ToBytes(timestamp))
+	if v == nil {
+		return nil, nil
+	}
+	result := &common.PriceCandle{}
+	return result, json.Unmarshal(v, result)
+}
+
+func (self boltPriceHistoryStore) putPriceCandle(resolution string, pair common.TokenPairID, exchange common.ExchangeID, candle common.PriceCandle) error {
+	b, err := self.b.CreateBucketIfNotExists([]byte(resolution))
+	if err != nil {
+		return err
+	}
+	if b, err = b.CreateBucketIfNotExists([]byte(pair)); err != nil {
+		return err
+	}
+	if b, err = b.CreateBucketIfNotExists([]byte(exchange)); err != nil {
+		return err
+	}
+	dataJSON, err := json.Marshal(candle)
+	if err != nil {
+		return err
+	}
+	return b.Put(boltutil.Uint64ToBytes(candle.Timestamp), dataJSON)
+}
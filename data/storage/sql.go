@@ -0,0 +1,2042 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/metric"
+	// register database drivers supported by SQLStorage
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	// PostgresDriver is the driver name of PostgreSQL, the SQL database used in production.
+	PostgresDriver = "postgres"
+	// SQLiteDriver is the driver name of SQLite, used in tests and for local development.
+	SQLiteDriver = "sqlite3"
+
+	// currentSettingTimepoint is the timepoint of settings which have only one value,
+	// they are kept with constant keys in bolt.
+	currentSettingTimepoint uint64 = 0
+)
+
+// sqlSchema creates tables of SQLStorage. Tables of versioned data use the same
+// names as their bolt buckets and are keyed by timepoint. Pending and confirmed
+// settings are kept in settings table, named by their bolt buckets.
+// Data is stored as JSON, activities and gas spend have extra columns to be queried directly.
+var sqlSchema = []string{
+	`CREATE TABLE IF NOT EXISTS prices (timepoint BIGINT PRIMARY KEY, data TEXT NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS rates (timepoint BIGINT PRIMARY KEY, block_number BIGINT NOT NULL, data TEXT NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS auth_data (timepoint BIGINT PRIMARY KEY, data TEXT NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS gold_feeds (timepoint BIGINT PRIMARY KEY, data TEXT NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS metrics (timepoint BIGINT PRIMARY KEY, data TEXT NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS activities (
+		timepoint BIGINT NOT NULL,
+		eid TEXT NOT NULL,
+		action TEXT NOT NULL,
+		destination TEXT NOT NULL,
+		exchange_status TEXT NOT NULL,
+		mining_status TEXT NOT NULL,
+		is_pending BOOLEAN NOT NULL,
+		data TEXT NOT NULL,
+		PRIMARY KEY (timepoint, eid))`,
+	`CREATE INDEX IF NOT EXISTS activities_pending_idx ON activities (is_pending, action)`,
+	`CREATE INDEX IF NOT EXISTS activities_action_idx ON activities (action, timepoint)`,
+	`CREATE INDEX IF NOT EXISTS activities_destination_idx ON activities (destination, timepoint)`,
+	`CREATE INDEX IF NOT EXISTS activities_exchange_status_idx ON activities (exchange_status, timepoint)`,
+	`CREATE INDEX IF NOT EXISTS activities_mining_status_idx ON activities (mining_status, timepoint)`,
+	`CREATE TABLE IF NOT EXISTS activity_tokens (
+		token TEXT NOT NULL,
+		timepoint BIGINT NOT NULL,
+		eid TEXT NOT NULL,
+		PRIMARY KEY (token, timepoint, eid))`,
+	`CREATE TABLE IF NOT EXISTS exchange_status (exchange TEXT PRIMARY KEY, data TEXT NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS exchange_notifications (
+		exchange TEXT NOT NULL,
+		action TEXT NOT NULL,
+		token TEXT NOT NULL,
+		data TEXT NOT NULL,
+		PRIMARY KEY (exchange, action, token))`,
+	`CREATE TABLE IF NOT EXISTS gas_spend (
+		tx_hash TEXT PRIMARY KEY,
+		timepoint BIGINT NOT NULL,
+		operator TEXT NOT NULL,
+		action TEXT NOT NULL,
+		data TEXT NOT NULL)`,
+	`CREATE INDEX IF NOT EXISTS gas_spend_timepoint_idx ON gas_spend (timepoint)`,
+	`CREATE TABLE IF NOT EXISTS pending_gas_spend (tx_hash TEXT PRIMARY KEY, data TEXT NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS settings (name TEXT NOT NULL, timepoint BIGINT NOT NULL, data TEXT NOT NULL, PRIMARY KEY (name, timepoint))`,
+	`CREATE TABLE IF NOT EXISTS price_history (
+		resolution TEXT NOT NULL,
+		pair TEXT NOT NULL,
+		exchange TEXT NOT NULL,
+		timepoint BIGINT NOT NULL,
+		data TEXT NOT NULL,
+		PRIMARY KEY (resolution, pair, exchange, timepoint))`,
+	`CREATE INDEX IF NOT EXISTS price_history_timepoint_idx ON price_history (resolution, timepoint)`,
+	`CREATE TABLE IF NOT EXISTS api_keys (name TEXT PRIMARY KEY, data TEXT NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS audit_log (
+		id BIGINT PRIMARY KEY,
+		timepoint BIGINT NOT NULL,
+		caller TEXT NOT NULL,
+		endpoint TEXT NOT NULL,
+		data TEXT NOT NULL)`,
+	`CREATE INDEX IF NOT EXISTS audit_log_timepoint_idx ON audit_log (timepoint)`,
+	`CREATE TABLE IF NOT EXISTS nonces (
+		api_key TEXT NOT NULL,
+		nonce BIGINT NOT NULL,
+		expires_at BIGINT NOT NULL,
+		PRIMARY KEY (api_key, nonce))`,
+	`CREATE INDEX IF NOT EXISTS nonces_expires_at_idx ON nonces (expires_at)`,
+	`CREATE TABLE IF NOT EXISTS config_proposals (type TEXT PRIMARY KEY, data TEXT NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS config_versions (
+		type TEXT NOT NULL,
+		version BIGINT NOT NULL,
+		data TEXT NOT NULL,
+		PRIMARY KEY (type, version))`,
+}
+
+// sqlQuerier is implemented by both *sql.DB and *sql.Tx.
+type sqlQuerier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// SQLStorage is the storage implementation of data.Storage, core.ActivityStorage
+// and metric.MetricStorage interfaces that uses a relational database as its
+// storage engine. It behaves the same as BoltStorage.
+type SQLStorage struct {
+	db           *sql.DB
+	priceHistory PriceHistoryConfig
+	// auditMu serializes appending audit records, which read the last record
+	auditMu sync.Mutex
+	// configMu serializes appending config versions, which read the last version
+	configMu sync.Mutex
+	// nonceMu guards noncePrunedAt, the last time expired nonces are removed
+	nonceMu       sync.Mutex
+	noncePrunedAt uint64
+}
+
+// NewSQLStorage creates a new SQLStorage instance connecting to the database
+// with given driver, PostgresDriver or SQLiteDriver, and data source name.
+// Tables are created if they don't exist.
+func NewSQLStorage(driver, dataSource string) (*SQLStorage, error) {
+	switch driver {
+	case PostgresDriver, SQLiteDriver:
+	default:
+		return nil, fmt.Errorf("sql driver %s is not supported", driver)
+	}
+	db, err := sql.Open(driver, dataSource)
+	if err != nil {
+		return nil, err
+	}
+	if driver == SQLiteDriver {
+		// SQLite only allows one writer, in memory databases are per connection
+		db.SetMaxOpenConns(1)
+	}
+	storage := &SQLStorage{db: db, priceHistory: DefaultPriceHistoryConfig()}
+	err = storage.update(func(tx *sql.Tx) error {
+		for _, stmt := range sqlSchema {
+			if _, cErr := tx.Exec(stmt); cErr != nil {
+				return cErr
+			}
+		}
+		return indexActivityTokens(tx)
+	})
+	if err != nil {
+		if cErr := db.Close(); cErr != nil {
+			log.Printf("Closing database failed: %s", cErr)
+		}
+		return nil, err
+	}
+	return storage, nil
+}
+
+// Close closes the database connections.
+func (self *SQLStorage) Close() error {
+	return self.db.Close()
+}
+
+// update runs fn in a transaction, which is committed if fn succeeds and
+// rolled back otherwise.
+func (self *SQLStorage) update(fn func(tx *sql.Tx) error) error {
+	tx, err := self.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		if rErr := tx.Rollback(); rErr != nil {
+			log.Printf("Rolling back transaction failed: %s", rErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// currentVersion returns the most recent timepoint of table not after the given one.
+func currentVersion(q sqlQuerier, table string, timepoint uint64) (common.Version, error) {
+	var result uint64
+	err := q.QueryRow(
+		fmt.Sprintf(`SELECT timepoint FROM %s WHERE timepoint <= $1 ORDER BY timepoint DESC LIMIT 1`, table),
+		timepoint,
+	).Scan(&result)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("There is no data before timepoint %d", timepoint)
+	}
+	return common.Version(result), err
+}
+
+// getVersion decodes the data of table at the given version to result.
+func getVersion(q sqlQuerier, table string, version common.Version, result interface{}) error {
+	var data []byte
+	err := q.QueryRow(
+		fmt.Sprintf(`SELECT data FROM %s WHERE timepoint = $1`, table),
+		uint64(version),
+	).Scan(&data)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("version %d doesn't exist", version)
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, result)
+}
+
+// putVersion stores value to table at timepoint, replacing the existing one.
+func putVersion(q sqlQuerier, table string, timepoint uint64, value interface{}) error {
+	dataJSON, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(
+		fmt.Sprintf(`INSERT INTO %s (timepoint, data) VALUES ($1, $2)
+			ON CONFLICT (timepoint) DO UPDATE SET data = excluded.data`, table),
+		timepoint, string(dataJSON),
+	)
+	return err
+}
+
+// firstSetting returns the oldest value of setting name, nil if there is none.
+func firstSetting(q sqlQuerier, name string) (uint64, []byte, error) {
+	return querySetting(q, `SELECT timepoint, data FROM settings WHERE name = $1 ORDER BY timepoint ASC LIMIT 1`, name)
+}
+
+// lastSetting returns the latest value of setting name, nil if there is none.
+func lastSetting(q sqlQuerier, name string) (uint64, []byte, error) {
+	return querySetting(q, `SELECT timepoint, data FROM settings WHERE name = $1 ORDER BY timepoint DESC LIMIT 1`, name)
+}
+
+func querySetting(q sqlQuerier, query, name string) (uint64, []byte, error) {
+	var (
+		timepoint uint64
+		data      []byte
+	)
+	err := q.QueryRow(query, name).Scan(&timepoint, &data)
+	if err == sql.ErrNoRows {
+		return 0, nil, nil
+	}
+	return timepoint, data, err
+}
+
+// putSetting stores data of setting name at timepoint, replacing the existing one.
+func putSetting(q sqlQuerier, name string, timepoint uint64, data []byte) error {
+	_, err := q.Exec(
+		`INSERT INTO settings (name, timepoint, data) VALUES ($1, $2, $3)
+			ON CONFLICT (name, timepoint) DO UPDATE SET data = excluded.data`,
+		name, timepoint, string(data),
+	)
+	return err
+}
+
+func deleteSetting(q sqlQuerier, name string, timepoint uint64) error {
+	_, err := q.Exec(`DELETE FROM settings WHERE name = $1 AND timepoint = $2`, name, timepoint)
+	return err
+}
+
+// clearSetting removes all values of setting name.
+func clearSetting(q sqlQuerier, name string) error {
+	_, err := q.Exec(`DELETE FROM settings WHERE name = $1`, name)
+	return err
+}
+
+// replaceSetting makes data the only value of setting name.
+func replaceSetting(q sqlQuerier, name string, data []byte) error {
+	if err := clearSetting(q, name); err != nil {
+		return err
+	}
+	return putSetting(q, name, common.GetTimepoint(), data)
+}
+
+// CurrentGoldInfoVersion returns the most recent time point of gold info record.
+// It implements data.GlobalStorage interface.
+func (self *SQLStorage) CurrentGoldInfoVersion(timepoint uint64) (common.Version, error) {
+	return currentVersion(self.db, GOLD_BUCKET, timepoint)
+}
+
+// GetGoldInfo returns gold info at given time point. It implements data.GlobalStorage interface.
+func (self *SQLStorage) GetGoldInfo(version common.Version) (common.GoldData, error) {
+	result := common.GoldData{}
+	err := getVersion(self.db, GOLD_BUCKET, version, &result)
+	return result, err
+}
+
+// StoreGoldInfo stores the given gold information to database. It implements fetcher.GlobalStorage interface.
+func (self *SQLStorage) StoreGoldInfo(data common.GoldData) error {
+	return putVersion(self.db, GOLD_BUCKET, data.Timestamp, data)
+}
+
+// AuthDataRetention returns how long auth data snapshots are kept before they expire.
+func (self *SQLStorage) AuthDataRetention() uint64 {
+	return AUTH_DATA_EXPIRED_DURATION
+}
+
+// ExportExpiredAuthData writes auth data snapshots expired at currentTime to fileName,
+// one JSON record per line.
+func (self *SQLStorage) ExportExpiredAuthData(currentTime uint64, fileName string) (nRecord uint64, err error) {
+	outFile, err := os.Create(fileName)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if cErr := outFile.Close(); cErr != nil {
+			log.Printf("Close file error: %s", cErr.Error())
+		}
+	}()
+
+	// rows are read before decoding, as keyframes of deltas are queried
+	records, err := queryAuthData(self.db,
+		`SELECT timepoint, data FROM auth_data WHERE timepoint <= $1 ORDER BY timepoint ASC`,
+		currentTime-AUTH_DATA_EXPIRED_DURATION,
+	)
+	if err != nil {
+		return 0, err
+	}
+	store := &cachedAuthDataStore{authDataStore: sqlAuthDataStore{self.db}}
+	for _, r := range records {
+		temp, lErr := loadAuthData(store, r.timepoint, r.value)
+		if lErr != nil {
+			return nRecord, lErr
+		}
+		record := common.NewAuthDataRecord(
+			common.Timestamp(strconv.FormatUint(r.timepoint, 10)),
+			temp,
+		)
+		var output []byte
+		if output, err = json.Marshal(record); err != nil {
+			return nRecord, err
+		}
+		if _, err = outFile.WriteString(string(output) + "\n"); err != nil {
+			return nRecord, err
+		}
+		nRecord++
+	}
+	return nRecord, nil
+}
+
+// PruneExpiredAuthData removes auth data snapshots expired at currentTime.
+// The first remaining snapshots depending on a removed keyframe are
+// re-encoded from a new keyframe before.
+func (self *SQLStorage) PruneExpiredAuthData(currentTime uint64) (uint64, error) {
+	var nRecord int64
+	expired := currentTime - AUTH_DATA_EXPIRED_DURATION
+	err := self.update(func(tx *sql.Tx) error {
+		store := sqlAuthDataStore{tx}
+		// deltas of expired keyframes are at most a keyframe interval after them
+		records, err := queryAuthData(tx,
+			`SELECT timepoint, data FROM auth_data WHERE timepoint > $1 AND timepoint < $2 ORDER BY timepoint ASC`,
+			expired, expired+AUTH_DATA_KEYFRAME_INTERVAL,
+		)
+		if err != nil {
+			return err
+		}
+		kept := []storedAuthData{}
+		for _, record := range records {
+			if !isAuthDataDelta(record.value) {
+				break
+			}
+			kept = append(kept, record)
+		}
+		rebased, err := rebaseAuthData(store, kept)
+		if err != nil {
+			return err
+		}
+		for _, record := range rebased {
+			if err = store.putAuthData(record.timepoint, record.value); err != nil {
+				return err
+			}
+		}
+		res, err := tx.Exec(`DELETE FROM auth_data WHERE timepoint <= $1`, expired)
+		if err != nil {
+			return err
+		}
+		nRecord, err = res.RowsAffected()
+		return err
+	})
+	return uint64(nRecord), err
+}
+
+// queryAuthData returns the stored auth data values of the query.
+func queryAuthData(q sqlQuerier, query string, args ...interface{}) ([]storedAuthData, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows)
+	result := []storedAuthData{}
+	for rows.Next() {
+		var record storedAuthData
+		if err = rows.Scan(&record.timepoint, &record.value); err != nil {
+			return nil, err
+		}
+		result = append(result, record)
+	}
+	return result, rows.Err()
+}
+
+// sqlAuthDataStore is the auth_data table accessed by q.
+type sqlAuthDataStore struct {
+	q sqlQuerier
+}
+
+func (self sqlAuthDataStore) getAuthData(timepoint uint64) ([]byte, error) {
+	var data []byte
+	err := self.q.QueryRow(`SELECT data FROM auth_data WHERE timepoint = $1`, timepoint).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (self sqlAuthDataStore) lastAuthData() (uint64, []byte, error) {
+	var (
+		timepoint uint64
+		data      []byte
+	)
+	err := self.q.QueryRow(`SELECT timepoint, data FROM auth_data ORDER BY timepoint DESC LIMIT 1`).Scan(&timepoint, &data)
+	if err == sql.ErrNoRows {
+		return 0, nil, nil
+	}
+	return timepoint, data, err
+}
+
+func (self sqlAuthDataStore) putAuthData(timepoint uint64, value []byte) error {
+	_, err := self.q.Exec(
+		`INSERT INTO auth_data (timepoint, data) VALUES ($1, $2)
+			ON CONFLICT (timepoint) DO UPDATE SET data = excluded.data`,
+		timepoint, string(value),
+	)
+	return err
+}
+
+func closeRows(rows *sql.Rows) {
+	if err := rows.Close(); err != nil {
+		log.Printf("Closing rows failed: %s", err)
+	}
+}
+
+// CurrentPriceVersion returns the most recent price version not after timepoint.
+func (self *SQLStorage) CurrentPriceVersion(timepoint uint64) (common.Version, error) {
+	return currentVersion(self.db, PRICE_BUCKET, timepoint)
+}
+
+// GetAllPrices returns the corresponding AllPriceEntry to a particular Version
+func (self *SQLStorage) GetAllPrices(version common.Version) (common.AllPriceEntry, error) {
+	result := common.AllPriceEntry{}
+	err := getVersion(self.db, PRICE_BUCKET, version, &result)
+	return result, err
+}
+
+// GetOnePrice returns the price of a token pair at a particular Version.
+func (self *SQLStorage) GetOnePrice(pair common.TokenPairID, version common.Version) (common.OnePrice, error) {
+	result, err := self.GetAllPrices(version)
+	if err != nil {
+		return common.OnePrice{}, err
+	}
+	dataPair, exist := result.Data[pair]
+	if exist {
+		return dataPair, nil
+	}
+	return common.OnePrice{}, errors.New("Pair of token is not supported")
+}
+
+// StorePrice stores prices at timepoint, only the latest MAX_NUMBER_VERSION versions are kept,
+// and adds them to price history.
+func (self *SQLStorage) StorePrice(data common.AllPriceEntry, timepoint uint64) error {
+	return self.update(func(tx *sql.Tx) error {
+		_, err := tx.Exec(
+			`DELETE FROM prices WHERE timepoint NOT IN
+				(SELECT timepoint FROM prices ORDER BY timepoint DESC LIMIT $1)`,
+			MAX_NUMBER_VERSION,
+		)
+		if err != nil {
+			log.Printf("Prune out data: %s", err.Error())
+			return err
+		}
+		if err = putVersion(tx, PRICE_BUCKET, timepoint, data); err != nil {
+			return err
+		}
+		return recordPriceHistory(sqlPriceHistoryStore{tx}, data, timepoint, self.priceHistory.DepthSizes)
+	})
+}
+
+// SetPriceHistoryConfig sets retention and depth sizes of price history,
+// it must be called before prices are stored.
+func (self *SQLStorage) SetPriceHistoryConfig(conf PriceHistoryConfig) {
+	self.priceHistory = conf
+}
+
+// GetPriceHistory returns price history of the pair on the exchange, or on all
+// exchanges if it is empty, at the resolution between fromTime and toTime.
+func (self *SQLStorage) GetPriceHistory(pair common.TokenPairID, exchange common.ExchangeID, resolution string, fromTime, toTime uint64) (common.PriceHistory, error) {
+	result := common.PriceHistory{Pair: pair, Resolution: resolution, Data: map[common.ExchangeID][]common.PriceCandle{}}
+	if err := validatePriceHistoryQuery(resolution, fromTime, toTime); err != nil {
+		return result, err
+	}
+	query := `SELECT exchange, data FROM price_history
+		WHERE resolution = $1 AND pair = $2 AND timepoint >= $3 AND timepoint <= $4`
+	args := []interface{}{resolution, string(pair), fromTime, toTime}
+	if exchange != "" {
+		query += ` AND exchange = $5`
+		args = append(args, string(exchange))
+	}
+	rows, err := self.db.Query(query+` ORDER BY exchange, timepoint`, args...)
+	if err != nil {
+		return result, err
+	}
+	defer closeRows(rows)
+	for rows.Next() {
+		var (
+			exchangeID string
+			data       []byte
+			candle     common.PriceCandle
+		)
+		if err = rows.Scan(&exchangeID, &data); err != nil {
+			return result, err
+		}
+		if err = json.Unmarshal(data, &candle); err != nil {
+			return result, err
+		}
+		result.Data[common.ExchangeID(exchangeID)] = append(result.Data[common.ExchangeID(exchangeID)], candle)
+	}
+	return result, rows.Err()
+}
+
+// PruneExpiredPriceHistory removes price history older than the retention of
+// its resolution and returns the number of removed records.
+func (self *SQLStorage) PruneExpiredPriceHistory(currentTime uint64) (uint64, error) {
+	var count uint64
+	err := self.update(func(tx *sql.Tx) error {
+		for _, resolution := range priceHistoryResolutions {
+			retention := self.priceHistory.Retention[resolution.name]
+			if retention >= currentTime {
+				continue
+			}
+			res, err := tx.Exec(
+				`DELETE FROM price_history WHERE resolution = $1 AND timepoint < $2`,
+				resolution.name, currentTime-retention,
+			)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			count += uint64(n)
+		}
+		return nil
+	})
+	return count, err
+}
+
+// CurrentAuthDataVersion returns the most recent auth data version not after timepoint.
+func (self *SQLStorage) CurrentAuthDataVersion(timepoint uint64) (common.Version, error) {
+	return currentVersion(self.db, AUTH_DATA_BUCKET, timepoint)
+}
+
+// GetAuthData returns the auth data snapshot of a particular Version.
+func (self *SQLStorage) GetAuthData(version common.Version) (common.AuthDataSnapshot, error) {
+	store := sqlAuthDataStore{self.db}
+	data, err := store.getAuthData(uint64(version))
+	if err != nil {
+		return common.AuthDataSnapshot{}, err
+	}
+	if data == nil {
+		return common.AuthDataSnapshot{}, fmt.Errorf("version %d doesn't exist", version)
+	}
+	return loadAuthData(store, uint64(version), data)
+}
+
+// StoreAuthSnapshot stores the auth data snapshot at timepoint as a delta from
+// the latest keyframe if it is small enough, or as a new keyframe.
+func (self *SQLStorage) StoreAuthSnapshot(data *common.AuthDataSnapshot, timepoint uint64) error {
+	return self.update(func(tx *sql.Tx) error {
+		return storeAuthData(sqlAuthDataStore{tx}, data, timepoint)
+	})
+}
+
+// CurrentRateVersion return current rate version
+func (self *SQLStorage) CurrentRateVersion(timepoint uint64) (common.Version, error) {
+	return currentVersion(self.db, RATE_BUCKET, timepoint)
+}
+
+// GetRate returns the rates of a particular Version.
+func (self *SQLStorage) GetRate(version common.Version) (common.AllRateEntry, error) {
+	result := common.AllRateEntry{}
+	err := getVersion(self.db, RATE_BUCKET, version, &result)
+	return result, err
+}
+
+// GetRates return rates history, the latest first
+func (self *SQLStorage) GetRates(fromTime, toTime uint64) ([]common.AllRateEntry, error) {
+	result := []common.AllRateEntry{}
+	if toTime-fromTime > MAX_GET_RATES_PERIOD {
+		return result, fmt.Errorf("Time range is too broad, it must be smaller or equal to %d miliseconds", MAX_GET_RATES_PERIOD)
+	}
+	rows, err := self.db.Query(
+		`SELECT data FROM rates WHERE timepoint >= $1 AND timepoint <= $2 ORDER BY timepoint DESC`,
+		fromTime, toTime,
+	)
+	if err != nil {
+		return result, err
+	}
+	defer closeRows(rows)
+	for rows.Next() {
+		var data []byte
+		if err = rows.Scan(&data); err != nil {
+			return result, err
+		}
+		entry := common.AllRateEntry{}
+		if err = json.Unmarshal(data, &entry); err != nil {
+			return result, err
+		}
+		result = append(result, entry)
+	}
+	return result, rows.Err()
+}
+
+// StoreRate store rate history, rates with smaller block number than the latest stored are rejected
+func (self *SQLStorage) StoreRate(data common.AllRateEntry, timepoint uint64) error {
+	log.Printf("Storing rate data to sql: data(%v), timespoint(%v)", data, timepoint)
+	return self.update(func(tx *sql.Tx) error {
+		var lastBlock uint64
+		err := tx.QueryRow(`SELECT block_number FROM rates ORDER BY timepoint DESC LIMIT 1`).Scan(&lastBlock)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		// we still update when blocknumber is not changed because we want
+		// to update the version and timestamp so api users will get
+		// the newest data even it is identical to the old one.
+		if lastBlock > data.BlockNumber {
+			return fmt.Errorf("rejected storing rates with smaller block number: %d, stored: %d",
+				data.BlockNumber,
+				lastBlock)
+		}
+		return putRate(tx, data, timepoint)
+	})
+}
+
+func putRate(q sqlQuerier, data common.AllRateEntry, timepoint uint64) error {
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(
+		`INSERT INTO rates (timepoint, block_number, data) VALUES ($1, $2, $3)
+			ON CONFLICT (timepoint) DO UPDATE SET block_number = excluded.block_number, data = excluded.data`,
+		timepoint, data.BlockNumber, string(dataJSON),
+	)
+	return err
+}
+
+// putActivity stores the activity with its pending flag, replacing the existing one.
+func putActivity(q sqlQuerier, record common.ActivityRecord, isPending bool) error {
+	dataJSON, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(
+		`INSERT INTO activities
+			(timepoint, eid, action, destination, exchange_status, mining_status, is_pending, data)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (timepoint, eid) DO UPDATE SET
+				action = excluded.action,
+				destination = excluded.destination,
+				exchange_status = excluded.exchange_status,
+				mining_status = excluded.mining_status,
+				is_pending = excluded.is_pending,
+				data = excluded.data`,
+		record.ID.Timepoint, record.ID.EID, record.Action, record.Destination,
+		record.ExchangeStatus, record.MiningStatus, isPending, string(dataJSON),
+	)
+	if err != nil {
+		return err
+	}
+	return putActivityTokens(q, record)
+}
+
+// putActivityTokens replaces tokens of the activity, by which activities
+// are queried.
+func putActivityTokens(q sqlQuerier, record common.ActivityRecord) error {
+	_, err := q.Exec(
+		`DELETE FROM activity_tokens WHERE timepoint = $1 AND eid = $2`,
+		record.ID.Timepoint, record.ID.EID,
+	)
+	if err != nil {
+		return err
+	}
+	for _, token := range record.Tokens() {
+		_, err = q.Exec(
+			`INSERT INTO activity_tokens (token, timepoint, eid) VALUES ($1, $2, $3)
+				ON CONFLICT (token, timepoint, eid) DO NOTHING`,
+			token, record.ID.Timepoint, record.ID.EID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// activityTokensIndexed is the setting marking tokens of activities stored
+// before activity_tokens table existed are indexed.
+const activityTokensIndexed = "activity_tokens_indexed"
+
+// indexActivityTokens stores tokens of all activities once.
+func indexActivityTokens(tx *sql.Tx) error {
+	_, data, err := lastSetting(tx, activityTokensIndexed)
+	if err != nil || data != nil {
+		return err
+	}
+	records, err := queryActivities(tx, `SELECT data FROM activities`)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err = putActivityTokens(tx, record); err != nil {
+			return err
+		}
+	}
+	return putSetting(tx, activityTokensIndexed, currentSettingTimepoint, []byte("true"))
+}
+
+func queryActivities(q sqlQuerier, query string, args ...interface{}) ([]common.ActivityRecord, error) {
+	result := []common.ActivityRecord{}
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return result, err
+	}
+	defer closeRows(rows)
+	for rows.Next() {
+		var data []byte
+		if err = rows.Scan(&data); err != nil {
+			return result, err
+		}
+		record := common.ActivityRecord{}
+		if err = json.Unmarshal(data, &record); err != nil {
+			return result, err
+		}
+		result = append(result, record)
+	}
+	return result, rows.Err()
+}
+
+// Record save activity
+func (self *SQLStorage) Record(
+	action string,
+	id common.ActivityID,
+	destination string,
+	params map[string]interface{}, result map[string]interface{},
+	estatus string,
+	mstatus string,
+	timepoint uint64) error {
+	record := common.ActivityRecord{
+		Action:         action,
+		ID:             id,
+		Destination:    destination,
+		Params:         params,
+		Result:         result,
+		ExchangeStatus: estatus,
+		MiningStatus:   mstatus,
+		Timestamp:      common.Timestamp(strconv.FormatUint(timepoint, 10)),
+	}
+	return self.update(func(tx *sql.Tx) error {
+		return putActivity(tx, record, record.IsPending())
+	})
+}
+
+// GetActivity get activity
+func (self *SQLStorage) GetActivity(id common.ActivityID) (common.ActivityRecord, error) {
+	result := common.ActivityRecord{}
+	var data []byte
+	err := self.db.QueryRow(
+		`SELECT data FROM activities WHERE timepoint = $1 AND eid = $2`,
+		id.Timepoint, id.EID,
+	).Scan(&data)
+	if err == sql.ErrNoRows {
+		return result, errors.New("Cannot find that activity")
+	}
+	if err != nil {
+		return result, err
+	}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// GetAllRecords returns activities in [fromTime, toTime], the latest first.
+// toTime 0 means there is no upper bound.
+func (self *SQLStorage) GetAllRecords(fromTime, toTime uint64) ([]common.ActivityRecord, error) {
+	if (toTime-fromTime)/1000000 > MAX_GET_RATES_PERIOD {
+		return []common.ActivityRecord{}, fmt.Errorf("Time range is too broad, it must be smaller or equal to %d miliseconds", MAX_GET_RATES_PERIOD)
+	}
+	if toTime == 0 {
+		toTime = math.MaxInt64
+	}
+	return queryActivities(self.db,
+		`SELECT data FROM activities WHERE timepoint >= $1 AND timepoint <= $2 ORDER BY timepoint DESC, eid DESC`,
+		fromTime, toTime,
+	)
+}
+
+// GetActivityPage returns at most limit activities selected by filter, which
+// are older than cursor if it is not nil, the latest first.
+func (self *SQLStorage) GetActivityPage(filter common.ActivityFilter, cursor *common.ActivityID, limit int) (common.ActivityPage, error) {
+	result := common.ActivityPage{Activities: []common.ActivityRecord{}}
+	if limit <= 0 {
+		return result, errors.New("limit must be positive")
+	}
+	if filter.ToTime < filter.FromTime {
+		return result, nil
+	}
+	toTime := filter.ToTime
+	if toTime > math.MaxInt64 {
+		toTime = math.MaxInt64
+	}
+	args := []interface{}{filter.FromTime, toTime}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	from := `activities a`
+	conditions := []string{`a.timepoint >= $1`, `a.timepoint <= $2`}
+	if filter.Token != "" {
+		from = `activity_tokens t JOIN activities a ON a.timepoint = t.timepoint AND a.eid = t.eid`
+		conditions = append(conditions, `t.token = `+arg(filter.Token))
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, `a.action = `+arg(filter.Action))
+	}
+	if filter.Destination != "" {
+		conditions = append(conditions, `a.destination = `+arg(filter.Destination))
+	}
+	if filter.Status != "" {
+		status := arg(filter.Status)
+		conditions = append(conditions, fmt.Sprintf(`(a.exchange_status = %s OR a.mining_status = %s)`, status, status))
+	}
+	if filter.PendingOnly {
+		conditions = append(conditions, `a.is_pending = `+arg(true))
+	}
+	if cursor != nil {
+		timepoint := arg(cursor.Timepoint)
+		conditions = append(conditions, fmt.Sprintf(`(a.timepoint < %s OR (a.timepoint = %s AND a.eid < %s))`, timepoint, timepoint, arg(cursor.EID)))
+	}
+	// one more activity tells if there is a next page
+	records, err := queryActivities(self.db, fmt.Sprintf(
+		`SELECT a.data FROM %s WHERE %s ORDER BY a.timepoint DESC, a.eid DESC LIMIT %s`,
+		from, strings.Join(conditions, ` AND `), arg(limit+1),
+	), args...)
+	if err != nil {
+		return result, err
+	}
+	result.Activities = records
+	if len(records) > limit {
+		result.Activities = records[:limit]
+		result.NextCursor = common.ActivityCursor(records[limit-1].ID)
+	}
+	return result, nil
+}
+
+// PendingSetrate return pending set rate activity
+func (self *SQLStorage) PendingSetrate(minedNonce uint64) (*common.ActivityRecord, uint64, error) {
+	pendings, err := self.GetPendingActivities()
+	if err != nil {
+		return nil, 0, err
+	}
+	return getLastAndCountPendingSetrate(pendings, minedNonce)
+}
+
+// GetPendingActivities return pending activities, the latest first
+func (self *SQLStorage) GetPendingActivities() ([]common.ActivityRecord, error) {
+	return queryActivities(self.db,
+		`SELECT data FROM activities WHERE is_pending = $1 ORDER BY timepoint DESC, eid DESC`,
+		true,
+	)
+}
+
+// UpdateActivity update activity info. An activity only stays pending if
+// it was pending, as it might be replaced by another activity.
+func (self *SQLStorage) UpdateActivity(id common.ActivityID, activity common.ActivityRecord) error {
+	return self.update(func(tx *sql.Tx) error {
+		var wasPending bool
+		err := tx.QueryRow(
+			`SELECT is_pending FROM activities WHERE timepoint = $1 AND eid = $2`,
+			id.Timepoint, id.EID,
+		).Scan(&wasPending)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		activity.ID = id
+		return putActivity(tx, activity, wasPending && activity.IsPending())
+	})
+}
+
+// HasPendingDeposit check if a deposit is pending
+func (self *SQLStorage) HasPendingDeposit(token common.Token, exchange common.Exchange) (bool, error) {
+	pendings, err := queryActivities(self.db,
+		`SELECT data FROM activities WHERE is_pending = $1 AND action = $2 AND destination = $3`,
+		true, "deposit", string(exchange.ID()),
+	)
+	if err != nil {
+		return false, err
+	}
+	for _, record := range pendings {
+		tokenID, ok := record.Params["token"].(string)
+		if !ok {
+			log.Printf("ERROR: record Params token (%v) can not be converted to string", record.Params["token"])
+			continue
+		}
+		if tokenID == token.ID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// StoreMetric store metric info
+func (self *SQLStorage) StoreMetric(data *metric.MetricEntry, timepoint uint64) error {
+	return putVersion(self.db, METRIC_BUCKET, data.Timestamp, data)
+}
+
+// GetMetric return metric data
+func (self *SQLStorage) GetMetric(tokens []common.Token, fromTime, toTime uint64) (map[string]metric.MetricList, error) {
+	result := map[string]metric.MetricList{}
+	for _, tok := range tokens {
+		result[tok.ID] = metric.MetricList{}
+	}
+	rows, err := self.db.Query(
+		`SELECT data FROM metrics WHERE timepoint >= $1 AND timepoint <= $2 ORDER BY timepoint ASC`,
+		fromTime, toTime,
+	)
+	if err != nil {
+		return result, err
+	}
+	defer closeRows(rows)
+	for rows.Next() {
+		var dataJSON []byte
+		if err = rows.Scan(&dataJSON); err != nil {
+			return result, err
+		}
+		data := metric.MetricEntry{}
+		if err = json.Unmarshal(dataJSON, &data); err != nil {
+			return result, err
+		}
+		for tok, m := range data.Data {
+			metricList, found := result[tok]
+			if found {
+				result[tok] = append(metricList, metric.TokenMetricResponse{
+					Timestamp: data.Timestamp,
+					AfpMid:    m.AfpMid,
+					Spread:    m.Spread,
+				})
+			}
+		}
+	}
+	return result, rows.Err()
+}
+
+// GetTokenTargetQty get target quantity
+func (self *SQLStorage) GetTokenTargetQty() (metric.TokenTargetQty, error) {
+	tokenTargetQty := metric.TokenTargetQty{}
+	timepoint := common.GetTimepoint()
+	var data []byte
+	err := self.db.QueryRow(
+		`SELECT data FROM settings WHERE name = $1 AND timepoint <= $2 ORDER BY timepoint DESC LIMIT 1`,
+		METRIC_TARGET_QUANTITY, timepoint,
+	).Scan(&data)
+	if err == sql.ErrNoRows {
+		return tokenTargetQty, fmt.Errorf("There is no data before timepoint %d", timepoint)
+	}
+	if err != nil {
+		return tokenTargetQty, err
+	}
+	err = json.Unmarshal(data, &tokenTargetQty)
+	return tokenTargetQty, err
+}
+
+// putTokenTargetQty stores tokenTargetQty as the confirmed target quantity and
+// writes it through to target quantity v2, which is what the core reads.
+func putTokenTargetQty(q sqlQuerier, tokenTargetQty metric.TokenTargetQty) error {
+	tokenTargetQty.Status = "confirmed"
+	dataJSON, err := json.Marshal(tokenTargetQty)
+	if err != nil {
+		return err
+	}
+	if err = putSetting(q, METRIC_TARGET_QUANTITY, common.GetTimepoint(), dataJSON); err != nil {
+		return err
+	}
+	if dataJSON, err = json.Marshal(convertTargetQtyV1toV2(tokenTargetQty)); err != nil {
+		return err
+	}
+	return putSetting(q, TARGET_QUANTITY_V2, currentSettingTimepoint, dataJSON)
+}
+
+// GetRebalanceControl returns whether rebalance is enabled, it is disabled if never set.
+func (self *SQLStorage) GetRebalanceControl() (metric.RebalanceControl, error) {
+	result := metric.RebalanceControl{}
+	_, data, err := firstSetting(self.db, ENABLE_REBALANCE)
+	if err != nil {
+		return result, err
+	}
+	if data == nil {
+		return result, self.StoreRebalanceControl(false)
+	}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// StoreRebalanceControl enables or disables rebalance.
+func (self *SQLStorage) StoreRebalanceControl(status bool) error {
+	dataJSON, err := json.Marshal(metric.RebalanceControl{Status: status})
+	if err != nil {
+		return err
+	}
+	return self.update(func(tx *sql.Tx) error {
+		return replaceSetting(tx, ENABLE_REBALANCE, dataJSON)
+	})
+}
+
+// GetSetrateControl returns whether setting rates is enabled, it is disabled if never set.
+func (self *SQLStorage) GetSetrateControl() (metric.SetrateControl, error) {
+	result := metric.SetrateControl{}
+	_, data, err := firstSetting(self.db, SETRATE_CONTROL)
+	if err != nil {
+		return result, err
+	}
+	if data == nil {
+		return result, self.StoreSetrateControl(false)
+	}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// StoreSetrateControl enables or disables setting rates.
+func (self *SQLStorage) StoreSetrateControl(status bool) error {
+	dataJSON, err := json.Marshal(metric.SetrateControl{Status: status})
+	if err != nil {
+		return err
+	}
+	return self.update(func(tx *sql.Tx) error {
+		return replaceSetting(tx, SETRATE_CONTROL, dataJSON)
+	})
+}
+
+// putPWIEquation stores equation as the confirmed PWI equation and writes it
+// through to PWI equation v2, which is what the core reads.
+func putPWIEquation(q sqlQuerier, equation metric.PWIEquation) error {
+	timepoint := common.GetTimepoint()
+	data, err := json.Marshal(equation)
+	if err != nil {
+		return err
+	}
+	if err = putSetting(q, PWI_EQUATION, timepoint, data); err != nil {
+		return err
+	}
+	eqv2, err := convertPWIEquationV1toV2(equation.Data)
+	if err != nil {
+		return err
+	}
+	if data, err = json.Marshal(eqv2); err != nil {
+		return err
+	}
+	return putSetting(q, PWI_EQUATION_V2, timepoint, data)
+}
+
+// GetPWIEquation returns the latest confirmed PWI equation.
+func (self *SQLStorage) GetPWIEquation() (metric.PWIEquation, error) {
+	var result metric.PWIEquation
+	_, v, err := lastSetting(self.db, PWI_EQUATION)
+	if err != nil {
+		return result, err
+	}
+	if v == nil {
+		return result, errors.New("There is no equation")
+	}
+	err = json.Unmarshal(v, &result)
+	return result, err
+}
+
+// GetExchangeStatus get exchange status to dashboard and analytics
+func (self *SQLStorage) GetExchangeStatus() (common.ExchangesStatus, error) {
+	result := make(common.ExchangesStatus)
+	rows, err := self.db.Query(`SELECT exchange, data FROM exchange_status`)
+	if err != nil {
+		return result, err
+	}
+	defer closeRows(rows)
+	for rows.Next() {
+		var (
+			exchange string
+			data     []byte
+			exstat   common.ExStatus
+		)
+		if err = rows.Scan(&exchange, &data); err != nil {
+			return result, err
+		}
+		if _, vErr := common.GetExchange(strings.ToLower(exchange)); vErr != nil {
+			continue
+		}
+		if err = json.Unmarshal(data, &exstat); err != nil {
+			return result, err
+		}
+		result[exchange] = exstat
+	}
+	return result, rows.Err()
+}
+
+// UpdateExchangeStatus stores the status of given exchanges.
+func (self *SQLStorage) UpdateExchangeStatus(data common.ExchangesStatus) error {
+	return self.update(func(tx *sql.Tx) error {
+		return putExchangesStatus(tx, data)
+	})
+}
+
+func putExchangesStatus(q sqlQuerier, data common.ExchangesStatus) error {
+	for k, v := range data {
+		dataJSON, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = q.Exec(
+			`INSERT INTO exchange_status (exchange, data) VALUES ($1, $2)
+				ON CONFLICT (exchange) DO UPDATE SET data = excluded.data`,
+			k, string(dataJSON),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateExchangeNotification stores the notification of an action on a token of an exchange.
+func (self *SQLStorage) UpdateExchangeNotification(
+	exchange, action, token string, fromTime, toTime uint64, isWarning bool, msg string) error {
+	return putExchangeNotification(self.db, exchange, action, token, common.ExchangeNotiContent{
+		FromTime:  fromTime,
+		ToTime:    toTime,
+		IsWarning: isWarning,
+		Message:   msg,
+	})
+}
+
+func putExchangeNotification(q sqlQuerier, exchange, action, token string, noti common.ExchangeNotiContent) error {
+	dataJSON, err := json.Marshal(noti)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(
+		`INSERT INTO exchange_notifications (exchange, action, token, data) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (exchange, action, token) DO UPDATE SET data = excluded.data`,
+		exchange, action, token, string(dataJSON),
+	)
+	return err
+}
+
+// GetExchangeNotifications returns notifications grouped by exchange, action and token.
+func (self *SQLStorage) GetExchangeNotifications() (common.ExchangeNotifications, error) {
+	result := common.ExchangeNotifications{}
+	rows, err := self.db.Query(`SELECT exchange, action, token, data FROM exchange_notifications`)
+	if err != nil {
+		return result, err
+	}
+	defer closeRows(rows)
+	for rows.Next() {
+		var (
+			exchange, action, token string
+			data                    []byte
+			notiContent             common.ExchangeNotiContent
+		)
+		if err = rows.Scan(&exchange, &action, &token, &data); err != nil {
+			return result, err
+		}
+		if err = json.Unmarshal(data, &notiContent); err != nil {
+			return result, err
+		}
+		actionContent, exist := result[exchange]
+		if !exist {
+			actionContent = common.ExchangeActionNoti{}
+			result[exchange] = actionContent
+		}
+		tokenContent, exist := actionContent[action]
+		if !exist {
+			tokenContent = common.ExchangeTokenNoti{}
+			actionContent[action] = tokenContent
+		}
+		tokenContent[token] = notiContent
+	}
+	return result, rows.Err()
+}
+
+// getSettingMap decodes the first value of setting name, empty if there is none.
+func (self *SQLStorage) getSettingMap(name string) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	_, data, err := firstSetting(self.db, name)
+	if err != nil || data == nil {
+		return result, err
+	}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// GetStableTokenParams returns the confirmed stable token params.
+func (self *SQLStorage) GetStableTokenParams() (map[string]interface{}, error) {
+	return self.getSettingMap(STABLE_TOKEN_PARAMS_BUCKET)
+}
+
+// GetTargetQtyV2 return the current target quantity.
+func (self *SQLStorage) GetTargetQtyV2() (metric.TokenTargetQtyV2, error) {
+	result := metric.TokenTargetQtyV2{}
+	_, data, err := firstSetting(self.db, TARGET_QUANTITY_V2)
+	if err != nil {
+		return result, err
+	}
+	if data == nil {
+		return result, errors.New("There is no target quantity")
+	}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// storePendingSetting stores data as the pending value of setting name, failing with
+// existErr if there is one already.
+func (self *SQLStorage) storePendingSetting(name string, data []byte, existErr error) error {
+	timepoint := common.GetTimepoint()
+	return self.update(func(tx *sql.Tx) error {
+		_, v, err := firstSetting(tx, name)
+		if err != nil {
+			return err
+		}
+		if v != nil {
+			return existErr
+		}
+		return putSetting(tx, name, timepoint, data)
+	})
+}
+
+// getSetting decodes the first or last value of setting name to result, failing
+// with notFoundErr if there is none.
+func (self *SQLStorage) getSetting(name string, last bool, result interface{}, notFoundErr error) error {
+	get := firstSetting
+	if last {
+		get = lastSetting
+	}
+	_, data, err := get(self.db, name)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return notFoundErr
+	}
+	return json.Unmarshal(data, result)
+}
+
+// confirmPendingSetting moves the pending value of setting pendingName to the history
+// of setting name if it equals to data decoded as the type of confirmData.
+func (self *SQLStorage) confirmPendingSetting(pendingName, name string, data []byte, confirmData, currentData interface{}, notFoundErr, mismatchErr error) error {
+	return self.update(func(tx *sql.Tx) error {
+		return confirmPendingSetting(tx, pendingName, name, data, confirmData, currentData, notFoundErr, mismatchErr)
+	})
+}
+
+func confirmPendingSetting(tx *sql.Tx, pendingName, name string, data []byte, confirmData, currentData interface{}, notFoundErr, mismatchErr error) error {
+	k, v, err := firstSetting(tx, pendingName)
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		return notFoundErr
+	}
+	if err = json.Unmarshal(data, confirmData); err != nil {
+		return err
+	}
+	if err = json.Unmarshal(v, currentData); err != nil {
+		return err
+	}
+	if eq := reflect.DeepEqual(currentData, confirmData); !eq {
+		return mismatchErr
+	}
+	if err = putSetting(tx, name, common.GetTimepoint(), v); err != nil {
+		return err
+	}
+	return deleteSetting(tx, pendingName, k)
+}
+
+// removePendingSetting removes the pending value of setting name, failing with
+// notFoundErr if there is none.
+func (self *SQLStorage) removePendingSetting(name string, notFoundErr error) error {
+	return self.update(func(tx *sql.Tx) error {
+		return removePendingSetting(tx, name, notFoundErr)
+	})
+}
+
+func removePendingSetting(tx *sql.Tx, name string, notFoundErr error) error {
+	k, v, err := firstSetting(tx, name)
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		return notFoundErr
+	}
+	return deleteSetting(tx, name, k)
+}
+
+// GetPWIEquationV2 returns the current PWI equations.
+func (self *SQLStorage) GetPWIEquationV2() (metric.PWIEquationRequestV2, error) {
+	var result metric.PWIEquationRequestV2
+	_, v, err := lastSetting(self.db, PWI_EQUATION_V2)
+	if err != nil {
+		return result, err
+	}
+	if v == nil {
+		return result, errors.New("There is no equation")
+	}
+	err = json.Unmarshal(v, &result)
+	return result, err
+}
+
+// GetRebalanceQuadratic return current confirm rebalance quadratic equation
+func (self *SQLStorage) GetRebalanceQuadratic() (metric.RebalanceQuadraticRequest, error) {
+	var result metric.RebalanceQuadraticRequest
+	err := self.getSetting(REBALANCE_QUADRATIC, true, &result, errors.New("there is no rebalance quadratic equation"))
+	return result, err
+}
+
+// StorePendingStepFunctions store pending step functions
+func (self *SQLStorage) StorePendingStepFunctions(data []byte) error {
+	return self.storePendingSetting(PENDING_STEP_FUNCTIONS, data, errors.New("pending step functions exist"))
+}
+
+// GetPendingStepFunctions return pending step functions
+func (self *SQLStorage) GetPendingStepFunctions() (metric.StepFunctionsRequest, error) {
+	var result metric.StepFunctionsRequest
+	err := self.getSetting(PENDING_STEP_FUNCTIONS, false, &result, errors.New("there is no pending step functions"))
+	return result, err
+}
+
+// ConfirmStepFunctions save pending step functions to the history and remove them
+// with their submissions from pending
+func (self *SQLStorage) ConfirmStepFunctions(data []byte) error {
+	return self.update(func(tx *sql.Tx) error {
+		err := confirmPendingSetting(
+			tx, PENDING_STEP_FUNCTIONS, STEP_FUNCTIONS, data,
+			&metric.StepFunctionsRequest{}, &metric.StepFunctionsRequest{},
+			errors.New("there is no pending step functions"),
+			errors.New("confirm data does not match pending step functions"),
+		)
+		if err != nil {
+			return err
+		}
+		return clearSetting(tx, PENDING_STEP_FUNCTION_SUBMISSIONS)
+	})
+}
+
+// RemovePendingStepFunctions remove pending step functions and their submissions
+func (self *SQLStorage) RemovePendingStepFunctions() error {
+	return self.update(func(tx *sql.Tx) error {
+		if err := removePendingSetting(tx, PENDING_STEP_FUNCTIONS, errors.New("there is no pending step functions to delete")); err != nil {
+			return err
+		}
+		return clearSetting(tx, PENDING_STEP_FUNCTION_SUBMISSIONS)
+	})
+}
+
+// StoreStepFunctionSubmission records the activity of a step function of the pending
+// step functions submitted to pricing contract, so it is not submitted again
+func (self *SQLStorage) StoreStepFunctionSubmission(tokenID, stepType string, id common.ActivityID) error {
+	return self.update(func(tx *sql.Tx) error {
+		_, pending, err := firstSetting(tx, PENDING_STEP_FUNCTIONS)
+		if err != nil {
+			return err
+		}
+		if pending == nil {
+			return errors.New("there is no pending step functions")
+		}
+		submissions, err := getStepFunctionSubmissions(tx)
+		if err != nil {
+			return err
+		}
+		submissions.Add(tokenID, stepType, id)
+		data, err := json.Marshal(submissions)
+		if err != nil {
+			return err
+		}
+		return putSetting(tx, PENDING_STEP_FUNCTION_SUBMISSIONS, currentSettingTimepoint, data)
+	})
+}
+
+// GetStepFunctionSubmissions returns activities of step functions of the pending
+// step functions submitted to pricing contract
+func (self *SQLStorage) GetStepFunctionSubmissions() (metric.StepFunctionSubmissions, error) {
+	return getStepFunctionSubmissions(self.db)
+}
+
+func getStepFunctionSubmissions(q sqlQuerier) (metric.StepFunctionSubmissions, error) {
+	result := metric.StepFunctionSubmissions{}
+	_, data, err := firstSetting(q, PENDING_STEP_FUNCTION_SUBMISSIONS)
+	if err != nil || data == nil {
+		return result, err
+	}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// StoreGasSpend save the gas spend record of a transaction.
+// A transaction is only recorded once, later records of the same tx are ignored.
+func (self *SQLStorage) StoreGasSpend(record common.GasSpendRecord) error {
+	return putGasSpend(self.db, record)
+}
+
+func putGasSpend(q sqlQuerier, record common.GasSpendRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(
+		`INSERT INTO gas_spend (tx_hash, timepoint, operator, action, data) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (tx_hash) DO NOTHING`,
+		record.TxHash, record.Timestamp, record.Operator, record.Action, string(data),
+	)
+	return err
+}
+
+// GetGasSpend return gas spend records in [fromTime, toTime] sorted by timestamp
+func (self *SQLStorage) GetGasSpend(fromTime, toTime uint64) ([]common.GasSpendRecord, error) {
+	result := []common.GasSpendRecord{}
+	if toTime < fromTime {
+		return result, errors.New("fromTime must be smaller than toTime")
+	}
+	if toTime-fromTime > MAX_GET_GAS_SPEND_PERIOD {
+		return result, fmt.Errorf("Time range is too broad, it must be smaller or equal to %d miliseconds", MAX_GET_GAS_SPEND_PERIOD)
+	}
+	rows, err := self.db.Query(
+		`SELECT data FROM gas_spend WHERE timepoint >= $1 AND timepoint <= $2 ORDER BY timepoint ASC, tx_hash ASC`,
+		fromTime, toTime,
+	)
+	if err != nil {
+		return result, err
+	}
+	defer closeRows(rows)
+	for rows.Next() {
+		var data []byte
+		if err = rows.Scan(&data); err != nil {
+			return result, err
+		}
+		record := common.GasSpendRecord{}
+		if err = json.Unmarshal(data, &record); err != nil {
+			return result, err
+		}
+		result = append(result, record)
+	}
+	return result, rows.Err()
+}
+
+// StorePendingGasSpend save a transaction waiting for its gas spend to be recorded.
+func (self *SQLStorage) StorePendingGasSpend(job common.PendingGasSpend) error {
+	return putPendingGasSpend(self.db, job)
+}
+
+func putPendingGasSpend(q sqlQuerier, job common.PendingGasSpend) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(
+		`INSERT INTO pending_gas_spend (tx_hash, data) VALUES ($1, $2)
+			ON CONFLICT (tx_hash) DO UPDATE SET data = excluded.data`,
+		job.TxHash, string(data),
+	)
+	return err
+}
+
+// GetPendingGasSpends return all transactions waiting for their gas spend to be recorded.
+func (self *SQLStorage) GetPendingGasSpends() ([]common.PendingGasSpend, error) {
+	result := []common.PendingGasSpend{}
+	rows, err := self.db.Query(`SELECT data FROM pending_gas_spend ORDER BY tx_hash ASC`)
+	if err != nil {
+		return result, err
+	}
+	defer closeRows(rows)
+	for rows.Next() {
+		var data []byte
+		if err = rows.Scan(&data); err != nil {
+			return result, err
+		}
+		job := common.PendingGasSpend{}
+		if err = json.Unmarshal(data, &job); err != nil {
+			return result, err
+		}
+		result = append(result, job)
+	}
+	return result, rows.Err()
+}
+
+// RemovePendingGasSpend remove the transaction once its gas spend is recorded.
+func (self *SQLStorage) RemovePendingGasSpend(txHash string) error {
+	_, err := self.db.Exec(`DELETE FROM pending_gas_spend WHERE tx_hash = $1`, txHash)
+	return err
+}
+
+// StoreBalanceThresholds save the balance thresholds, replacing the current ones
+func (self *SQLStorage) StoreBalanceThresholds(thresholds common.BalanceThresholds) error {
+	dataJSON, err := json.Marshal(thresholds)
+	if err != nil {
+		return err
+	}
+	return self.update(func(tx *sql.Tx) error {
+		return replaceSetting(tx, BALANCE_THRESHOLDS_BUCKET, dataJSON)
+	})
+}
+
+// GetBalanceThresholds return the balance thresholds, empty if they are not set
+func (self *SQLStorage) GetBalanceThresholds() (common.BalanceThresholds, error) {
+	result := common.BalanceThresholds{
+		Operators: map[string]float64{},
+		Tokens:    map[string]float64{},
+	}
+	_, data, err := firstSetting(self.db, BALANCE_THRESHOLDS_BUCKET)
+	if err != nil || data == nil {
+		return result, err
+	}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// StoreBalanceStatus save the latest balance monitor status
+func (self *SQLStorage) StoreBalanceStatus(status common.BalanceStatus) error {
+	dataJSON, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	return self.update(func(tx *sql.Tx) error {
+		return replaceSetting(tx, BALANCE_STATUS_BUCKET, dataJSON)
+	})
+}
+
+// GetBalanceStatus return the latest balance monitor status
+func (self *SQLStorage) GetBalanceStatus() (common.BalanceStatus, error) {
+	var result common.BalanceStatus
+	err := self.getSetting(BALANCE_STATUS_BUCKET, false, &result, errors.New("balance status is not available"))
+	return result, err
+}
+
+// sqlPriceHistoryStore is the price_history table accessed by a querier.
+type sqlPriceHistoryStore struct {
+	q sqlQuerier
+}
+
+func (self sqlPriceHistoryStore) getPriceCandle(resolution string, pair common.TokenPairID, exchange common.ExchangeID, timestamp uint64) (*common.PriceCandle, error) {
+	var data []byte
+	err := self.q.QueryRow(
+		`SELECT data FROM price_history WHERE resolution = $1 AND pair = $2 AND exchange = $3 AND timepoint = $4`,
+		resolution, string(pair), string(exchange), timestamp,
+	).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := &common.PriceCandle{}
+	return result, json.Unmarshal(data, result)
+}
+
+func (self sqlPriceHistoryStore) putPriceCandle(resolution string, pair common.TokenPairID, exchange common.ExchangeID, candle common.PriceCandle) error {
+	dataJSON, err := json.Marshal(candle)
+	if err != nil {
+		return err
+	}
+	_, err = self.q.Exec(
+		`INSERT INTO price_history (resolution, pair, exchange, timepoint, data) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (resolution, pair, exchange, timepoint) DO UPDATE SET data = excluded.data`,
+		resolution, string(pair), string(exchange), candle.Timestamp, string(dataJSON),
+	)
+	return err
+}
+
+// CreateAPIKey stores a new API key, it fails if a key of the same name exists.
+func (self *SQLStorage) CreateAPIKey(key common.APIKey) error {
+	return self.update(func(tx *sql.Tx) error {
+		var n int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM api_keys WHERE name = $1`, key.Name).Scan(&n); err != nil {
+			return err
+		}
+		if n != 0 {
+			return fmt.Errorf("API key %s already exists", key.Name)
+		}
+		return putAPIKey(tx, key)
+	})
+}
+
+// UpdateAPIKey reads the API key of name, changes it with update and stores
+// it back in one transaction, nothing is stored if update fails. The key is
+// only replaced if it is unchanged since read, so concurrent updates from
+// other instances are never lost.
+func (self *SQLStorage) UpdateAPIKey(name string, update func(key *common.APIKey) error) (common.APIKey, error) {
+	var result common.APIKey
+	err := self.update(func(tx *sql.Tx) error {
+		var data []byte
+		err := tx.QueryRow(`SELECT data FROM api_keys WHERE name = $1`, name).Scan(&data)
+		if err == sql.ErrNoRows {
+			return apiKeyNotFound(name)
+		}
+		if err != nil {
+			return err
+		}
+		if err = json.Unmarshal(data, &result); err != nil {
+			return err
+		}
+		if err = update(&result); err != nil {
+			return err
+		}
+		result.Name = name
+		dataJSON, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		res, err := tx.Exec(
+			`UPDATE api_keys SET data = $1 WHERE name = $2 AND data = $3`,
+			string(dataJSON), name, string(data),
+		)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("API key %s is changed concurrently, please retry", name)
+		}
+		return nil
+	})
+	return result, err
+}
+
+func putAPIKey(q sqlQuerier, key common.APIKey) error {
+	dataJSON, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(
+		`INSERT INTO api_keys (name, data) VALUES ($1, $2)
+			ON CONFLICT (name) DO UPDATE SET data = excluded.data`,
+		key.Name, string(dataJSON),
+	)
+	return err
+}
+
+func checkAPIKeyAffected(result sql.Result, name string) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return apiKeyNotFound(name)
+	}
+	return nil
+}
+
+// GetAPIKey returns the API key of name.
+func (self *SQLStorage) GetAPIKey(name string) (common.APIKey, error) {
+	var (
+		result common.APIKey
+		data   []byte
+	)
+	err := self.db.QueryRow(`SELECT data FROM api_keys WHERE name = $1`, name).Scan(&data)
+	if err == sql.ErrNoRows {
+		return result, apiKeyNotFound(name)
+	}
+	if err != nil {
+		return result, err
+	}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// GetAPIKeys returns all API keys sorted by name.
+func (self *SQLStorage) GetAPIKeys() ([]common.APIKey, error) {
+	result := []common.APIKey{}
+	rows, err := self.db.Query(`SELECT data FROM api_keys ORDER BY name`)
+	if err != nil {
+		return result, err
+	}
+	defer closeRows(rows)
+	for rows.Next() {
+		var (
+			data []byte
+			key  common.APIKey
+		)
+		if err = rows.Scan(&data); err != nil {
+			return result, err
+		}
+		if err = json.Unmarshal(data, &key); err != nil {
+			return result, err
+		}
+		result = append(result, key)
+	}
+	return result, rows.Err()
+}
+
+// RemoveAPIKey removes the API key of name.
+func (self *SQLStorage) RemoveAPIKey(name string) error {
+	result, err := self.db.Exec(`DELETE FROM api_keys WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	return checkAPIKeyAffected(result, name)
+}
+
+// AppendAuditRecord chains the record after the last audit record and stores it.
+func (self *SQLStorage) AppendAuditRecord(record common.AuditRecord) (common.AuditRecord, error) {
+	self.auditMu.Lock()
+	defer self.auditMu.Unlock()
+	err := self.update(func(tx *sql.Tx) error {
+		var data []byte
+		var last *common.AuditRecord
+		err := tx.QueryRow(`SELECT data FROM audit_log ORDER BY id DESC LIMIT 1`).Scan(&data)
+		switch err {
+		case sql.ErrNoRows:
+		case nil:
+			last = &common.AuditRecord{}
+			if err = json.Unmarshal(data, last); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+		record = common.NextAuditRecord(last, record)
+		return putAuditRecord(tx, record)
+	})
+	return record, err
+}
+
+func putAuditRecord(q sqlQuerier, record common.AuditRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(
+		`INSERT INTO audit_log (id, timepoint, caller, endpoint, data) VALUES ($1, $2, $3, $4, $5)`,
+		record.ID, record.Timestamp, record.Key, record.Endpoint, string(data),
+	)
+	return err
+}
+
+// PruneExpiredAuditRecords removes audit records older than AUDIT_LOG_RETENTION
+// except the last record, which the next record is chained to.
+func (self *SQLStorage) PruneExpiredAuditRecords(currentTime uint64) (uint64, error) {
+	if currentTime <= AUDIT_LOG_RETENTION {
+		return 0, nil
+	}
+	self.auditMu.Lock()
+	defer self.auditMu.Unlock()
+	result, err := self.db.Exec(
+		`DELETE FROM audit_log WHERE timepoint < $1 AND id < (SELECT MAX(id) FROM audit_log)`,
+		currentTime-AUDIT_LOG_RETENTION,
+	)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return uint64(n), err
+}
+
+// GetAuditRecords returns audit records selected by filter sorted by ID.
+func (self *SQLStorage) GetAuditRecords(filter common.AuditFilter) ([]common.AuditRecord, error) {
+	result := []common.AuditRecord{}
+	if filter.ToTime < filter.FromTime {
+		return result, errors.New("fromTime must be smaller than toTime")
+	}
+	toTime := filter.ToTime
+	if toTime > math.MaxInt64 {
+		toTime = math.MaxInt64
+	}
+	rows, err := self.db.Query(
+		`SELECT data FROM audit_log WHERE timepoint >= $1 AND timepoint <= $2
+			AND ($3 = '' OR caller = $3) AND ($4 = '' OR endpoint = $4) ORDER BY id ASC`,
+		filter.FromTime, toTime, filter.Key, filter.Endpoint,
+	)
+	if err != nil {
+		return result, err
+	}
+	defer closeRows(rows)
+	for rows.Next() {
+		var data []byte
+		if err = rows.Scan(&data); err != nil {
+			return result, err
+		}
+		record := common.AuditRecord{}
+		if err = json.Unmarshal(data, &record); err != nil {
+			return result, err
+		}
+		result = append(result, record)
+	}
+	return result, rows.Err()
+}
+
+// noncePruneInterval is the min interval in millisecond between removing
+// expired nonces.
+const noncePruneInterval = 1000
+
+// UseNonce stores nonce of key to expire at expiresAt, it returns false if
+// key used nonce before and it hasn't expired. The database is shared by all
+// servers using it, so a nonce can only be used once among them.
+func (self *SQLStorage) UseNonce(key string, nonce, expiresAt uint64) (bool, error) {
+	timepoint := common.GetTimepoint()
+	self.nonceMu.Lock()
+	prune := timepoint-self.noncePrunedAt >= noncePruneInterval
+	if prune {
+		self.noncePrunedAt = timepoint
+	}
+	self.nonceMu.Unlock()
+	if prune {
+		if _, err := self.db.Exec(`DELETE FROM nonces WHERE expires_at <= $1`, timepoint); err != nil {
+			return false, err
+		}
+	}
+	// expired nonces which are not removed yet can be used again
+	result, err := self.db.Exec(
+		`INSERT INTO nonces (api_key, nonce, expires_at) VALUES ($1, $2, $3)
+			ON CONFLICT (api_key, nonce) DO UPDATE SET expires_at = excluded.expires_at
+			WHERE nonces.expires_at <= $4`,
+		key, nonce, expiresAt, timepoint,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// StoreConfigProposal stores the pending proposal of a config type, it fails
+// if the type already has a pending proposal.
+func (self *SQLStorage) StoreConfigProposal(proposal common.ConfigProposal) error {
+	dataJSON, err := json.Marshal(proposal)
+	if err != nil {
+		return err
+	}
+	return self.update(func(tx *sql.Tx) error {
+		var n int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM config_proposals WHERE type = $1`, proposal.Type).Scan(&n); err != nil {
+			return err
+		}
+		if n != 0 {
+			return fmt.Errorf("pending proposal of config %s exists", proposal.Type)
+		}
+		_, err := tx.Exec(`INSERT INTO config_proposals (type, data) VALUES ($1, $2)`, proposal.Type, string(dataJSON))
+		return err
+	})
+}
+
+func getConfigProposal(q sqlQuerier, configType string) (common.ConfigProposal, error) {
+	var (
+		result common.ConfigProposal
+		data   []byte
+	)
+	err := q.QueryRow(`SELECT data FROM config_proposals WHERE type = $1`, configType).Scan(&data)
+	if err == sql.ErrNoRows {
+		return result, configProposalNotFound(configType)
+	}
+	if err != nil {
+		return result, err
+	}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// GetConfigProposal returns the pending proposal of a config type.
+func (self *SQLStorage) GetConfigProposal(configType string) (common.ConfigProposal, error) {
+	return getConfigProposal(self.db, configType)
+}
+
+// RemoveConfigProposal removes the pending proposal of a config type.
+func (self *SQLStorage) RemoveConfigProposal(configType string) error {
+	result, err := self.db.Exec(`DELETE FROM config_proposals WHERE type = $1`, configType)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return configProposalNotFound(configType)
+	}
+	return nil
+}
+
+// putConfig stores the value of proposal where the storage of its config type
+// keeps the current config.
+func putConfig(q sqlQuerier, proposal common.ConfigProposal) error {
+	timepoint := common.GetTimepoint()
+	switch proposal.Type {
+	case common.TargetQtyConfig:
+		request := metric.TargetQtyRequest{}
+		if err := json.Unmarshal(proposal.Value, &request); err != nil {
+			return err
+		}
+		return putTokenTargetQty(q, metric.TokenTargetQty{
+			ID:   proposal.ProposedAt,
+			Data: request.Data,
+			Type: request.Type,
+		})
+	case common.TargetQtyV2Config:
+		return putSetting(q, TARGET_QUANTITY_V2, currentSettingTimepoint, proposal.Value)
+	case common.PWIEquationConfig:
+		request := metric.PWIEquationRequest{}
+		if err := json.Unmarshal(proposal.Value, &request); err != nil {
+			return err
+		}
+		return putPWIEquation(q, metric.PWIEquation{ID: proposal.ProposedAt, Data: request.Data})
+	case common.PWIEquationV2Config:
+		return putSetting(q, PWI_EQUATION_V2, timepoint, proposal.Value)
+	case common.RebalanceQuadraticConfig:
+		return putSetting(q, REBALANCE_QUADRATIC, timepoint, proposal.Value)
+	case common.StableTokenParamsConfig:
+		return putSetting(q, STABLE_TOKEN_PARAMS_BUCKET, currentSettingTimepoint, proposal.Value)
+	default:
+		return fmt.Errorf("config %s can't be stored", proposal.Type)
+	}
+}
+
+// insertConfigVersion stores proposal confirmed by confirmer as the next
+// version of its type.
+func insertConfigVersion(q sqlQuerier, proposal common.ConfigProposal, confirmer string) (common.ConfigVersion, error) {
+	var last *common.ConfigVersion
+	current, err := getConfigVersion(q, proposal.Type, 0)
+	switch err {
+	case common.ErrConfigVersionNotFound:
+	case nil:
+		last = &current
+	default:
+		return current, err
+	}
+	version := common.NextConfigVersion(last, proposal, confirmer, common.GetTimepoint())
+	return version, putConfigVersion(q, version)
+}
+
+func putConfigVersion(q sqlQuerier, version common.ConfigVersion) error {
+	dataJSON, err := json.Marshal(version)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(
+		`INSERT INTO config_versions (type, version, data) VALUES ($1, $2, $3)`,
+		version.Type, version.Version, string(dataJSON),
+	)
+	return err
+}
+
+// ConfirmConfigProposal removes the pending proposal of a config type, stores
+// it as the current config and the next version in the same transaction, hash
+// must be the hash of the pending proposal.
+func (self *SQLStorage) ConfirmConfigProposal(configType, hash, confirmer string) (common.ConfigVersion, error) {
+	self.configMu.Lock()
+	defer self.configMu.Unlock()
+	var result common.ConfigVersion
+	err := self.update(func(tx *sql.Tx) error {
+		proposal, err := getConfigProposal(tx, configType)
+		if err != nil {
+			return err
+		}
+		if proposal.Hash != hash {
+			return fmt.Errorf("confirmed proposal doesn't match the pending proposal of config %s", configType)
+		}
+		if err = putConfig(tx, proposal); err != nil {
+			return err
+		}
+		if result, err = insertConfigVersion(tx, proposal, confirmer); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`DELETE FROM config_proposals WHERE type = $1`, configType)
+		return err
+	})
+	return result, err
+}
+
+func getConfigVersion(q sqlQuerier, configType string, version uint64) (common.ConfigVersion, error) {
+	var (
+		result common.ConfigVersion
+		data   []byte
+		err    error
+	)
+	if version == 0 {
+		err = q.QueryRow(
+			`SELECT data FROM config_versions WHERE type = $1 ORDER BY version DESC LIMIT 1`, configType,
+		).Scan(&data)
+	} else {
+		err = q.QueryRow(
+			`SELECT data FROM config_versions WHERE type = $1 AND version = $2`, configType, version,
+		).Scan(&data)
+	}
+	if err == sql.ErrNoRows {
+		return result, common.ErrConfigVersionNotFound
+	}
+	if err != nil {
+		return result, err
+	}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// GetConfigVersion returns a version of a config type, the latest one if
+// version is 0.
+func (self *SQLStorage) GetConfigVersion(configType string, version uint64) (common.ConfigVersion, error) {
+	return getConfigVersion(self.db, configType, version)
+}
+
+// GetConfigVersions returns all versions of a config type sorted by version.
+func (self *SQLStorage) GetConfigVersions(configType string) ([]common.ConfigVersion, error) {
+	result := []common.ConfigVersion{}
+	rows, err := self.db.Query(`SELECT data FROM config_versions WHERE type = $1 ORDER BY version ASC`, configType)
+	if err != nil {
+		return result, err
+	}
+	defer closeRows(rows)
+	for rows.Next() {
+		var (
+			data    []byte
+			version common.ConfigVersion
+		)
+		if err = rows.Scan(&data); err != nil {
+			return result, err
+		}
+		if err = json.Unmarshal(data, &version); err != nil {
+			return result, err
+		}
+		result = append(result, version)
+	}
+	return result, rows.Err()
+}
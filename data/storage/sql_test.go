@@ -0,0 +1,278 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/boltutil"
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/data/testutil"
+	"github.com/boltdb/bolt"
+)
+
+func newTestSQLStorage(t *testing.T) *SQLStorage {
+	storage, err := NewSQLStorage(SQLiteDriver, ":memory:")
+	if err != nil {
+		t.Fatalf("Couldn't init sql storage %v", err)
+	}
+	return storage
+}
+
+func TestSQLStorage(t *testing.T) {
+	runStorageTests(t, func(t *testing.T) (testStorage, func()) {
+		storage := newTestSQLStorage(t)
+		return storage, func() {
+			if err := storage.Close(); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+}
+
+func TestGlobalStorageSQLImplementation(t *testing.T) {
+	storage := newTestSQLStorage(t)
+	testutil.NewGlobalStorageTestSuite(t, storage, storage).Run()
+}
+
+func TestMigrateBoltToSQL(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "migrate_bolt_to_sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}()
+	src, err := NewBoltStorage(filepath.Join(tmpDir, "test_bolt.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending := common.NewActivityID(1000, "1")
+	done := common.NewActivityID(2000, "2")
+	for _, id := range []common.ActivityID{pending, done} {
+		err = src.Record("set_rates", id, "blockchain", map[string]interface{}{},
+			map[string]interface{}{"nonce": "1", "gasPrice": "1"}, "", "submitted", id.Timepoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	mined, err := src.GetActivity(done)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mined.MiningStatus = "mined"
+	if err = src.UpdateActivity(done, mined); err != nil {
+		t.Fatal(err)
+	}
+	if err = src.StoreRate(common.AllRateEntry{BlockNumber: 10}, 1000); err != nil {
+		t.Fatal(err)
+	}
+	if err = src.StoreAuthSnapshot(&common.AuthDataSnapshot{Block: 10}, 1000); err != nil {
+		t.Fatal(err)
+	}
+	if err = src.UpdateExchangeNotification("operator", "lowbalance", "0x_1", 1000, 0, true, "low"); err != nil {
+		t.Fatal(err)
+	}
+	if err = src.StoreGasSpend(common.GasSpendRecord{TxHash: "0x01", Timestamp: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	stableParams := []byte(`{"DGX": {"ask_spread": 1}}`)
+	quadratic := []byte(`{"KNC":{"a":1,"b":2,"c":3}}`)
+	// configs pending in legacy buckets, written after the bolt migrations
+	err = src.db.Update(func(tx *bolt.Tx) error {
+		if pErr := tx.Bucket([]byte(PENDING_STABLE_TOKEN_PARAMS_BUCKET)).Put(boltutil.Uint64ToBytes(1), []byte(`{"DGX": {"ask_spread": 2}}`)); pErr != nil {
+			return pErr
+		}
+		return tx.Bucket([]byte(PENDING_REBALANCE_QUADRATIC)).Put(boltutil.Uint64ToBytes(1500), quadratic)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = src.StorePendingStepFunctions([]byte(`{"KNC": {}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err = src.CreateAPIKey(common.APIKey{Name: "pricing", Permissions: []string{"rebalance"}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, endpoint := range []string{"/setrates", "/withdraw"} {
+		if _, err = src.AppendAuditRecord(common.AuditRecord{Timestamp: 1000, Endpoint: endpoint}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	proposal, err := common.NewConfigProposal("stable_token_params", stableParams, "kn_configuration", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = src.StoreConfigProposal(proposal); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = src.ConfirmConfigProposal("stable_token_params", proposal.Hash, "kn_confirm_configuration"); err != nil {
+		t.Fatal(err)
+	}
+	if err = src.StoreConfigProposal(proposal); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := newTestSQLStorage(t)
+	result, err := MigrateBoltToSQL(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result[ACTIVITY_BUCKET] != 2 || result[RATE_BUCKET] != 1 || result[CONFIG_PROPOSAL_BUCKET] != 2 {
+		t.Fatalf("unexpected migrated records %v", result)
+	}
+
+	pendings, err := dst.GetPendingActivities()
+	if err != nil || len(pendings) != 1 || pendings[0].ID != pending {
+		t.Fatalf("expected only the pending activity to be pending, got %+v, err %v", pendings, err)
+	}
+	if activity, aErr := dst.GetActivity(done); aErr != nil || activity.MiningStatus != "mined" {
+		t.Fatalf("unexpected migrated activity %+v, err %v", activity, aErr)
+	}
+	if rates, rErr := dst.GetRates(0, 2000); rErr != nil || len(rates) != 1 || rates[0].BlockNumber != 10 {
+		t.Fatalf("unexpected migrated rates %+v, err %v", rates, rErr)
+	}
+	// a rate older than the migrated one must be rejected
+	if err = dst.StoreRate(common.AllRateEntry{BlockNumber: 9}, 2000); err == nil {
+		t.Fatal("expected rates with smaller block number to be rejected")
+	}
+	if version, vErr := dst.CurrentAuthDataVersion(2000); vErr != nil || version != 1000 {
+		t.Fatalf("unexpected auth data version %d, err %v", version, vErr)
+	}
+	notifications, err := dst.GetExchangeNotifications()
+	if err != nil || !notifications["operator"]["lowbalance"]["0x_1"].IsWarning {
+		t.Fatalf("unexpected migrated notifications %+v, err %v", notifications, err)
+	}
+	if records, gErr := dst.GetGasSpend(0, 2000); gErr != nil || len(records) != 1 {
+		t.Fatalf("unexpected migrated gas spend %+v, err %v", records, gErr)
+	}
+	// legacy pending configs are config proposals, unless their types already have one
+	moved, err := dst.GetConfigProposal(common.RebalanceQuadraticConfig)
+	if err != nil || string(moved.Value) != string(quadratic) || moved.ProposedAt != 1500 {
+		t.Fatalf("unexpected moved proposal %+v, err %v", moved, err)
+	}
+	if kept, gErr := dst.GetConfigProposal(common.StableTokenParamsConfig); gErr != nil || kept.Hash != proposal.Hash {
+		t.Fatalf("expected stable token params proposal kept, got %+v, err %v", kept, gErr)
+	}
+	if _, err = dst.GetPendingStepFunctions(); err != nil {
+		t.Fatal(err)
+	}
+	if key, kErr := dst.GetAPIKey("pricing"); kErr != nil || key.Permissions[0] != "rebalance" {
+		t.Fatalf("unexpected migrated API key %+v, err %v", key, kErr)
+	}
+	records, err := dst.GetAuditRecords(common.AuditFilter{ToTime: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || common.VerifyAuditRecords(records) != nil {
+		t.Fatalf("unexpected migrated audit records %+v", records)
+	}
+	if record, aErr := dst.AppendAuditRecord(common.AuditRecord{Timestamp: 2000}); aErr != nil || record.ID != 3 || record.PrevHash != records[1].Hash {
+		t.Fatalf("expected record chained after migrated records, got %+v, err %v", record, aErr)
+	}
+	// migrated proposals are confirmed after migrated versions
+	if version, cErr := dst.ConfirmConfigProposal("stable_token_params", proposal.Hash, "kn_confirm_configuration"); cErr != nil || version.Version != 2 {
+		t.Fatalf("expected migrated proposal confirmed as version 2, got %+v, err %v", version, cErr)
+	}
+}
+
+func TestSQLStorageNonces(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test_sql_nonces")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}()
+	// servers sharing the same database
+	dataSource := filepath.Join(tmpDir, "test.db") + "?_busy_timeout=5000"
+	var servers []*SQLStorage
+	for i := 0; i < 2; i++ {
+		server, sErr := NewSQLStorage(SQLiteDriver, dataSource)
+		if sErr != nil {
+			t.Fatal(sErr)
+		}
+		defer func() {
+			if cErr := server.Close(); cErr != nil {
+				t.Error(cErr)
+			}
+		}()
+		servers = append(servers, server)
+	}
+
+	now := common.GetTimepoint()
+	const replays = 20
+	var wg sync.WaitGroup
+	results := make(chan bool, 2*replays)
+	for i := 0; i < replays; i++ {
+		for _, server := range servers {
+			wg.Add(1)
+			go func(server *SQLStorage) {
+				defer wg.Done()
+				ok, uErr := server.UseNonce("pricing", now, now+60000)
+				if uErr != nil {
+					t.Error(uErr)
+				}
+				results <- ok
+			}(server)
+		}
+	}
+	wg.Wait()
+	close(results)
+	accepted := 0
+	for ok := range results {
+		if ok {
+			accepted++
+		}
+	}
+	if accepted != 1 {
+		t.Errorf("expected nonce accepted once by all servers, got %d", accepted)
+	}
+
+	if ok, uErr := servers[1].UseNonce("rebalance", now, now+60000); uErr != nil || !ok {
+		t.Errorf("expected nonce of another key to be accepted, got %t, err %v", ok, uErr)
+	}
+	// expired nonces can be used again
+	if ok, uErr := servers[0].UseNonce("pricing", now-1, now); uErr != nil || !ok {
+		t.Fatalf("expected nonce to be accepted, got %t, err %v", ok, uErr)
+	}
+	if ok, uErr := servers[1].UseNonce("pricing", now-1, now+60000); uErr != nil || !ok {
+		t.Errorf("expected expired nonce to be accepted, got %t, err %v", ok, uErr)
+	}
+}
+
+func TestSQLIndexActivityTokens(t *testing.T) {
+	storage := newTestSQLStorage(t)
+	defer func() {
+		if err := storage.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+	id := common.NewActivityID(1000, "1")
+	if err := storage.Record("deposit", id, "binance", map[string]interface{}{"token": "KNC"}, map[string]interface{}{}, "done", "mined", 1000); err != nil {
+		t.Fatal(err)
+	}
+	// a database whose activities are stored before tokens are indexed
+	for _, stmt := range []string{`DELETE FROM activity_tokens`, `DELETE FROM settings`} {
+		if _, err := storage.db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := storage.update(indexActivityTokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := storage.GetActivityPage(common.ActivityFilter{ToTime: 2000, Token: "KNC"}, nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Activities) != 1 || page.Activities[0].ID != id {
+		t.Errorf("expected the existing activity to be indexed, got %+v", page.Activities)
+	}
+}
@@ -0,0 +1,1071 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/metric"
+)
+
+// testStorage is implemented by all storage backends of core data, tests
+// in this file are run against each of them.
+type testStorage interface {
+	Record(action string, id common.ActivityID, destination string,
+		params map[string]interface{}, result map[string]interface{},
+		estatus string, mstatus string, timepoint uint64) error
+	HasPendingDeposit(token common.Token, exchange common.Exchange) (bool, error)
+	GetActivity(id common.ActivityID) (common.ActivityRecord, error)
+	PendingSetrate(minedNonce uint64) (*common.ActivityRecord, uint64, error)
+	GetAllRecords(fromTime, toTime uint64) ([]common.ActivityRecord, error)
+	GetActivityPage(filter common.ActivityFilter, cursor *common.ActivityID, limit int) (common.ActivityPage, error)
+	GetPendingActivities() ([]common.ActivityRecord, error)
+	UpdateActivity(id common.ActivityID, act common.ActivityRecord) error
+
+	StorePrice(data common.AllPriceEntry, timepoint uint64) error
+	CurrentPriceVersion(timepoint uint64) (common.Version, error)
+	GetAllPrices(common.Version) (common.AllPriceEntry, error)
+	StoreRate(data common.AllRateEntry, timepoint uint64) error
+	GetRates(fromTime, toTime uint64) ([]common.AllRateEntry, error)
+	StoreAuthSnapshot(data *common.AuthDataSnapshot, timepoint uint64) error
+	CurrentAuthDataVersion(timepoint uint64) (common.Version, error)
+	PruneExpiredAuthData(timepoint uint64) (uint64, error)
+	GetAuthData(common.Version) (common.AuthDataSnapshot, error)
+	ExportExpiredAuthData(timepoint uint64, filePath string) (uint64, error)
+	SetPriceHistoryConfig(conf PriceHistoryConfig)
+	GetPriceHistory(pair common.TokenPairID, exchange common.ExchangeID, resolution string, fromTime, toTime uint64) (common.PriceHistory, error)
+	PruneExpiredPriceHistory(timepoint uint64) (uint64, error)
+
+	UpdateExchangeNotification(exchange, action, tokenPair string, fromTime, toTime uint64, isWarning bool, msg string) error
+	GetExchangeNotifications() (common.ExchangeNotifications, error)
+
+	StoreGasSpend(record common.GasSpendRecord) error
+	GetGasSpend(fromTime, toTime uint64) ([]common.GasSpendRecord, error)
+	StorePendingGasSpend(job common.PendingGasSpend) error
+	GetPendingGasSpends() ([]common.PendingGasSpend, error)
+	RemovePendingGasSpend(txHash string) error
+
+	CreateAPIKey(key common.APIKey) error
+	UpdateAPIKey(name string, update func(key *common.APIKey) error) (common.APIKey, error)
+	GetAPIKey(name string) (common.APIKey, error)
+	GetAPIKeys() ([]common.APIKey, error)
+	RemoveAPIKey(name string) error
+	AppendAuditRecord(record common.AuditRecord) (common.AuditRecord, error)
+	GetAuditRecords(filter common.AuditFilter) ([]common.AuditRecord, error)
+	PruneExpiredAuditRecords(timepoint uint64) (uint64, error)
+
+	StoreConfigProposal(proposal common.ConfigProposal) error
+	GetConfigProposal(configType string) (common.ConfigProposal, error)
+	RemoveConfigProposal(configType string) error
+	ConfirmConfigProposal(configType, hash, confirmer string) (common.ConfigVersion, error)
+	GetConfigVersion(configType string, version uint64) (common.ConfigVersion, error)
+	GetConfigVersions(configType string) ([]common.ConfigVersion, error)
+
+	metric.MetricStorage
+}
+
+func testHasPendingDeposit(t *testing.T, storage testStorage) {
+	token := common.NewToken("OMG", "0x1111111111111111111111111111111111111111", 18)
+	exchange := common.TestExchange{}
+	timepoint := common.GetTimepoint()
+	out, err := storage.HasPendingDeposit(token, exchange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != false {
+		t.Fatalf("Expected ram storage to return true false there is no pending deposit for the same currency and exchange")
+	}
+	err = storage.Record(
+		"deposit",
+		common.NewActivityID(1, "1"),
+		string(exchange.ID()),
+		map[string]interface{}{
+			"exchange":  exchange,
+			"token":     token,
+			"amount":    "1.0",
+			"timepoint": timepoint,
+		},
+		map[string]interface{}{
+			"tx":    "",
+			"error": nil,
+		},
+		"",
+		"submitted",
+		common.GetTimepoint())
+	if err != nil {
+		t.Fatalf("Store activity error: %s", err.Error())
+	}
+	out, err = storage.HasPendingDeposit(token, exchange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != true {
+		t.Fatalf("Expected ram storage to return true when there is pending deposit")
+	}
+}
+
+func testGasSpend(t *testing.T, storage testStorage) {
+	records := []common.GasSpendRecord{
+		{TxHash: "0x02", Action: "deposit", Timestamp: 2000},
+		{TxHash: "0x01", Action: "set_rates", Timestamp: 1000},
+		// the same tx must not be counted twice
+		{TxHash: "0x01", Action: "set_rates", Timestamp: 3000},
+		{TxHash: "0x03", Action: "set_rates", Timestamp: 5000},
+	}
+	for _, record := range records {
+		if err := storage.StoreGasSpend(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result, err := storage.GetGasSpend(1000, 4000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 2 || result[0].TxHash != "0x01" || result[1].TxHash != "0x02" {
+		t.Fatalf("unexpected gas spend records %+v", result)
+	}
+	if _, err = storage.GetGasSpend(0, MAX_GET_GAS_SPEND_PERIOD+1); err == nil {
+		t.Fatal("expected error for too broad time range")
+	}
+
+	jobs := []common.PendingGasSpend{
+		{TxHash: "0x04", Action: "set_rates", Replacements: 1},
+		{TxHash: "0x05", Action: "deposit"},
+		// storing the same tx again replaces the job
+		{TxHash: "0x04", Action: "set_rates", Replacements: 2},
+	}
+	for _, job := range jobs {
+		if err = storage.StorePendingGasSpend(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = storage.RemovePendingGasSpend("0x05"); err != nil {
+		t.Fatal(err)
+	}
+	pendings, err := storage.GetPendingGasSpends()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pendings, []common.PendingGasSpend{jobs[2]}) {
+		t.Fatalf("unexpected pending gas spend jobs %+v", pendings)
+	}
+}
+
+func testActivities(t *testing.T, storage testStorage) {
+	setRates := func(id common.ActivityID, nonce, gasPrice string) {
+		err := storage.Record("set_rates", id, "blockchain",
+			map[string]interface{}{},
+			map[string]interface{}{"tx": "0x" + nonce + gasPrice, "nonce": nonce, "gasPrice": gasPrice},
+			"", "submitted", id.Timepoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	first := common.NewActivityID(1000, "1")
+	second := common.NewActivityID(2000, "2")
+	third := common.NewActivityID(3000, "3")
+	setRates(first, "1", "10")
+	setRates(second, "1", "20")
+	setRates(third, "2", "10")
+
+	pendings, err := storage.GetPendingActivities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pendings) != 3 || pendings[0].ID != third || pendings[2].ID != first {
+		t.Fatalf("expected 3 pending activities, latest first, got %+v", pendings)
+	}
+	last, count, err := storage.PendingSetrate(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last == nil || last.ID != third || count != 1 {
+		t.Fatalf("expected the set rates with highest nonce to be pending, got %+v, count %d", last, count)
+	}
+
+	done, err := storage.GetActivity(third)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done.MiningStatus = "mined"
+	if err = storage.UpdateActivity(third, done); err != nil {
+		t.Fatal(err)
+	}
+	if done, err = storage.GetActivity(third); err != nil || done.MiningStatus != "mined" {
+		t.Fatalf("expected activity to be updated, got %+v, err %v", done, err)
+	}
+	last, count, err = storage.PendingSetrate(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last == nil || last.ID != second || count != 2 {
+		t.Fatalf("expected the set rates with highest gas price to be pending, got %+v, count %d", last, count)
+	}
+	// an activity which is not pending anymore must not become pending again
+	done.MiningStatus = "submitted"
+	if err = storage.UpdateActivity(third, done); err != nil {
+		t.Fatal(err)
+	}
+	if pendings, err = storage.GetPendingActivities(); err != nil || len(pendings) != 2 {
+		t.Fatalf("expected 2 pending activities, got %+v, err %v", pendings, err)
+	}
+
+	records, err := storage.GetAllRecords(1500, 3000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].ID != third || records[1].ID != second {
+		t.Fatalf("unexpected activities %+v", records)
+	}
+	if _, err = storage.GetActivity(common.NewActivityID(4000, "4")); err == nil {
+		t.Fatal("expected error for not existing activity")
+	}
+
+	// step functions are sent by the pricing operator too
+	stepFunction := common.NewActivityID(5000, "5")
+	err = storage.Record("set_step_function", stepFunction, "blockchain",
+		map[string]interface{}{},
+		map[string]interface{}{"tx": "0x210", "nonce": "2", "gasPrice": "10"},
+		"", "submitted", stepFunction.Timepoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last, count, err = storage.PendingSetrate(1); err != nil || last == nil || last.ID != stepFunction || count != 1 {
+		t.Fatalf("expected the step function with highest nonce to be pending, got %+v, count %d, err %v", last, count, err)
+	}
+}
+
+func testStepFunctionSubmissions(t *testing.T, storage testStorage) {
+	data := []byte(`{"KNC": {}}`)
+	id := common.NewActivityID(1000, "0x1")
+	if err := storage.StoreStepFunctionSubmission("KNC", "qty", id); err == nil {
+		t.Fatal("expected error when there is no pending step functions")
+	}
+	if err := storage.StorePendingStepFunctions(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.StoreStepFunctionSubmission("KNC", "qty", id); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.StoreStepFunctionSubmission("OMG", "imbalance", id); err != nil {
+		t.Fatal(err)
+	}
+	submissions, err := storage.GetStepFunctionSubmissions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if submissions["KNC"]["qty"] != id || submissions["OMG"]["imbalance"] != id || len(submissions["KNC"]) != 1 {
+		t.Fatalf("unexpected step function submissions %+v", submissions)
+	}
+	if err = storage.ConfirmStepFunctions(data); err != nil {
+		t.Fatal(err)
+	}
+	if submissions, err = storage.GetStepFunctionSubmissions(); err != nil || len(submissions) != 0 {
+		t.Fatalf("expected submissions to be removed with pending step functions, got %+v, err %v", submissions, err)
+	}
+
+	if err = storage.StorePendingStepFunctions(data); err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.StoreStepFunctionSubmission("KNC", "qty", id); err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.RemovePendingStepFunctions(); err != nil {
+		t.Fatal(err)
+	}
+	if submissions, err = storage.GetStepFunctionSubmissions(); err != nil || len(submissions) != 0 {
+		t.Fatalf("expected submissions to be removed with pending step functions, got %+v, err %v", submissions, err)
+	}
+}
+
+func testActivityPages(t *testing.T, storage testStorage) {
+	knc := common.NewToken("KNC", "0xdd974d5c2e2928dea5f71b9825b8b646686bd200", 18)
+	omg := common.NewToken("OMG", "0xd26114cd6ee289accf82350c8d8487fedb8a0c07", 18)
+	eth := common.NewToken("ETH", "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee", 18)
+	records := []struct {
+		action, destination string
+		params              map[string]interface{}
+		estatus, mstatus    string
+	}{
+		{"deposit", "binance", map[string]interface{}{"token": knc}, "", "submitted"},
+		{"withdraw", "huobi", map[string]interface{}{"token": omg}, "done", "mined"},
+		{"trade", "binance", map[string]interface{}{"base": knc, "quote": eth}, "done", ""},
+		{"set_rates", "blockchain", map[string]interface{}{"tokens": []common.Token{knc, omg}}, "", "submitted"},
+		{"deposit", "huobi", map[string]interface{}{"token": knc}, "done", "mined"},
+	}
+	for i, record := range records {
+		timepoint := uint64(i+1) * 1000
+		id := common.NewActivityID(timepoint, strconv.Itoa(i+1))
+		if err := storage.Record(record.action, id, record.destination, record.params, map[string]interface{}{}, record.estatus, record.mstatus, timepoint); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the first deposit is done, indexes must follow the update
+	first, err := storage.GetActivity(common.NewActivityID(1000, "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.ExchangeStatus, first.MiningStatus = "done", "mined"
+	if err = storage.UpdateActivity(first.ID, first); err != nil {
+		t.Fatal(err)
+	}
+
+	eids := func(page common.ActivityPage) []string {
+		result := []string{}
+		for _, record := range page.Activities {
+			result = append(result, record.ID.EID)
+		}
+		return result
+	}
+	for _, tc := range []struct {
+		name     string
+		filter   common.ActivityFilter
+		expected []string
+	}{
+		{"all activities", common.ActivityFilter{}, []string{"5", "4", "3", "2", "1"}},
+		{"time range", common.ActivityFilter{FromTime: 2000, ToTime: 4000}, []string{"4", "3", "2"}},
+		{"token", common.ActivityFilter{Token: "KNC"}, []string{"5", "4", "3", "1"}},
+		{"token of set rates", common.ActivityFilter{Token: "OMG"}, []string{"4", "2"}},
+		{"destination", common.ActivityFilter{Destination: "binance"}, []string{"3", "1"}},
+		{"action", common.ActivityFilter{Action: "deposit"}, []string{"5", "1"}},
+		{"mining status", common.ActivityFilter{Status: "mined"}, []string{"5", "2", "1"}},
+		{"updated status", common.ActivityFilter{Status: "submitted"}, []string{"4"}},
+		{"pending only", common.ActivityFilter{PendingOnly: true}, []string{"4"}},
+		{"many filters", common.ActivityFilter{Token: "KNC", Destination: "huobi", Action: "deposit"}, []string{"5"}},
+		{"no match", common.ActivityFilter{Token: "EOS"}, []string{}},
+	} {
+		if tc.filter.ToTime == 0 {
+			tc.filter.ToTime = math.MaxInt64
+		}
+		page, pErr := storage.GetActivityPage(tc.filter, nil, 10)
+		if pErr != nil {
+			t.Fatalf("%s: %s", tc.name, pErr)
+		}
+		if !reflect.DeepEqual(eids(page), tc.expected) || page.NextCursor != "" {
+			t.Errorf("%s: expected activities %v on one page, got %v, next cursor %q", tc.name, tc.expected, eids(page), page.NextCursor)
+		}
+	}
+
+	// walking pages of KNC activities
+	filter := common.ActivityFilter{ToTime: math.MaxInt64, Token: "KNC"}
+	pages := [][]string{}
+	var cursor *common.ActivityID
+	for {
+		page, pErr := storage.GetActivityPage(filter, cursor, 2)
+		if pErr != nil {
+			t.Fatal(pErr)
+		}
+		pages = append(pages, eids(page))
+		if page.NextCursor == "" {
+			break
+		}
+		id, pErr := common.ParseActivityCursor(page.NextCursor)
+		if pErr != nil {
+			t.Fatal(pErr)
+		}
+		cursor = &id
+	}
+	if expected := [][]string{{"5", "4"}, {"3", "1"}}; !reflect.DeepEqual(pages, expected) {
+		t.Errorf("expected pages %v, got %v", expected, pages)
+	}
+	if _, err = storage.GetActivityPage(filter, nil, 0); err == nil {
+		t.Error("expected error for non positive limit")
+	}
+}
+
+func testVersionedData(t *testing.T, storage testStorage) {
+	for _, timepoint := range []uint64{1000, 2000} {
+		price := common.AllPriceEntry{Block: timepoint}
+		if err := storage.StorePrice(price, timepoint); err != nil {
+			t.Fatal(err)
+		}
+	}
+	version, err := storage.CurrentPriceVersion(1500)
+	if err != nil || version != 1000 {
+		t.Fatalf("expected price version 1000, got %d, err %v", version, err)
+	}
+	prices, err := storage.GetAllPrices(version)
+	if err != nil || prices.Block != 1000 {
+		t.Fatalf("unexpected prices %+v, err %v", prices, err)
+	}
+	if _, err = storage.CurrentPriceVersion(999); err == nil {
+		t.Fatal("expected error for timepoint before all prices")
+	}
+	if _, err = storage.GetAllPrices(1500); err == nil {
+		t.Fatal("expected error for not existing version")
+	}
+
+	if err = storage.StoreRate(common.AllRateEntry{BlockNumber: 10}, 1000); err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.StoreRate(common.AllRateEntry{BlockNumber: 9}, 2000); err == nil {
+		t.Fatal("expected rates with smaller block number to be rejected")
+	}
+	if err = storage.StoreRate(common.AllRateEntry{BlockNumber: 11}, 3000); err != nil {
+		t.Fatal(err)
+	}
+	rates, err := storage.GetRates(0, 5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rates) != 2 || rates[0].BlockNumber != 11 || rates[1].BlockNumber != 10 {
+		t.Fatalf("unexpected rates %+v", rates)
+	}
+
+	now := AUTH_DATA_EXPIRED_DURATION + 2000
+	for _, timepoint := range []uint64{1000, 2000, 3000} {
+		if err = storage.StoreAuthSnapshot(&common.AuthDataSnapshot{Block: timepoint}, timepoint); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pruned, err := storage.PruneExpiredAuthData(now)
+	if err != nil || pruned != 2 {
+		t.Fatalf("expected 2 auth data snapshots to be pruned, got %d, err %v", pruned, err)
+	}
+	if version, err = storage.CurrentAuthDataVersion(now); err != nil || version != 3000 {
+		t.Fatalf("expected auth data version 3000, got %d, err %v", version, err)
+	}
+}
+
+// newTestAuthData returns a snapshot at timepoint where only the KNC balance
+// and timestamps change between snapshots.
+func newTestAuthData(timepoint uint64, kncBalance float64) common.AuthDataSnapshot {
+	ts := common.Timestamp(strconv.FormatUint(timepoint, 10))
+	tokens := []string{"ETH", "KNC", "OMG", "EOS", "SNT", "ELF", "POWR", "MANA", "BAT", "REQ", "GTO", "ENG"}
+	balances := map[string]float64{}
+	for i, token := range tokens {
+		balances[token] = float64(i) * 1000.5
+	}
+	balances["KNC"] = kncBalance
+	reserveBalances := map[string]common.BalanceEntry{}
+	for _, token := range tokens {
+		reserveBalances[token] = common.BalanceEntry{Valid: true, Timestamp: ts, ReturnTime: ts, Balance: common.RawBalance(*big.NewInt(1234567890))}
+	}
+	return common.AuthDataSnapshot{
+		Valid:      true,
+		Timestamp:  ts,
+		ReturnTime: ts,
+		ExchangeBalances: map[common.ExchangeID]common.EBalanceEntry{
+			"binance": {Valid: true, Timestamp: ts, ReturnTime: ts, AvailableBalance: balances, LockedBalance: map[string]float64{"KNC": 1}, DepositBalance: map[string]float64{}, Status: true},
+			"huobi":   {Valid: true, Timestamp: ts, ReturnTime: ts, AvailableBalance: map[string]float64{"ETH": 2}, Status: true},
+		},
+		ReserveBalances: reserveBalances,
+		PendingActivities: []common.ActivityRecord{{
+			Action:      "deposit",
+			ID:          common.NewActivityID(1534500000123456789, "0xabc"),
+			Destination: "binance",
+			Params:      map[string]interface{}{"amount": "1.5"},
+			Result:      map[string]interface{}{"tx": "0xabc"},
+			Timestamp:   "1534500000123",
+		}},
+		Block: timepoint / 1000,
+	}
+}
+
+// sameAuthData compares snapshots by their JSON, as decoded JSON numbers and
+// empty maps can't be compared with the original values.
+func sameAuthData(t *testing.T, expected, actual common.AuthDataSnapshot) bool {
+	expectedJSON, err := json.Marshal(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actualJSON, err := json.Marshal(actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reflect.DeepEqual(expectedJSON, actualJSON)
+}
+
+func testAuthDataDeltas(t *testing.T, storage testStorage) {
+	snapshots := map[uint64]common.AuthDataSnapshot{}
+	timepoints := []uint64{1000, 6000, 11000, 11000 + AUTH_DATA_KEYFRAME_INTERVAL, 16000 + AUTH_DATA_KEYFRAME_INTERVAL}
+	for i, timepoint := range timepoints {
+		snapshot := newTestAuthData(timepoint, float64(i))
+		if i == 2 {
+			// an exchange balance is removed and the pending activity is done
+			delete(snapshot.ExchangeBalances, "huobi")
+			snapshot.PendingActivities = []common.ActivityRecord{}
+		}
+		snapshots[timepoint] = snapshot
+		if err := storage.StoreAuthSnapshot(&snapshot, timepoint); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, timepoint := range timepoints {
+		snapshot, err := storage.GetAuthData(common.Version(timepoint))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sameAuthData(t, snapshots[timepoint], snapshot) {
+			t.Errorf("snapshot at %d is not reconstructed, expected %+v, got %+v", timepoint, snapshots[timepoint], snapshot)
+		}
+	}
+
+	tmpDir, err := ioutil.TempDir("", "test_auth_data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}()
+	// snapshots at 1000 and 6000 are expired, the one at 11000 is a delta of the keyframe at 1000
+	now := AUTH_DATA_EXPIRED_DURATION + 6000
+	exported, err := storage.ExportExpiredAuthData(now, filepath.Join(tmpDir, "expired"))
+	if err != nil || exported != 2 {
+		t.Fatalf("expected 2 auth data snapshots to be exported, got %d, err %v", exported, err)
+	}
+	f, err := os.Open(filepath.Join(tmpDir, "expired"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var record common.AuthDataRecord
+		if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		timepoint, _ := strconv.ParseUint(string(record.Timestamp), 10, 64)
+		if !sameAuthData(t, snapshots[timepoint], record.Data) {
+			t.Errorf("exported snapshot at %d is not reconstructed", timepoint)
+		}
+	}
+	if pruned, pErr := storage.PruneExpiredAuthData(now); pErr != nil || pruned != 2 {
+		t.Fatalf("expected 2 auth data snapshots to be pruned, got %d, err %v", pruned, pErr)
+	}
+	for _, timepoint := range timepoints[2:] {
+		snapshot, err := storage.GetAuthData(common.Version(timepoint))
+		if err != nil {
+			t.Fatalf("snapshot at %d can't be read after pruning: %s", timepoint, err)
+		}
+		if !sameAuthData(t, snapshots[timepoint], snapshot) {
+			t.Errorf("snapshot at %d is not reconstructed after pruning", timepoint)
+		}
+	}
+	// deltas are still written after pruning
+	next := newTestAuthData(21000+AUTH_DATA_KEYFRAME_INTERVAL, 10)
+	if err = storage.StoreAuthSnapshot(&next, 21000+AUTH_DATA_KEYFRAME_INTERVAL); err != nil {
+		t.Fatal(err)
+	}
+	if snapshot, gErr := storage.GetAuthData(common.Version(21000 + AUTH_DATA_KEYFRAME_INTERVAL)); gErr != nil || !sameAuthData(t, next, snapshot) {
+		t.Errorf("unexpected snapshot %+v, err %v", snapshot, gErr)
+	}
+}
+
+func newTestPrices(bid, ask float64) common.AllPriceEntry {
+	return common.AllPriceEntry{
+		Block: 1,
+		Data: map[common.TokenPairID]common.OnePrice{
+			"KNC-ETH": {
+				"binance": {
+					Valid: true,
+					Bids:  []common.PriceEntry{{Quantity: 100, Rate: bid}, {Quantity: 1000, Rate: bid - 0.0001}},
+					Asks:  []common.PriceEntry{{Quantity: 100, Rate: ask}},
+				},
+				"huobi": {Valid: false, Error: "timeout"},
+			},
+		},
+	}
+}
+
+func testPriceHistory(t *testing.T, storage testStorage) {
+	conf := DefaultPriceHistoryConfig()
+	conf.Retention[RawPriceHistory] = 3600000
+	conf.DepthSizes = []float64{0.1, 100}
+	storage.SetPriceHistoryConfig(conf)
+
+	// the first candle of every resolution but 1h starts at t0
+	var t0 uint64 = 1540000200000
+	books := []struct {
+		timepoint uint64
+		bid, ask  float64
+	}{
+		{t0, 0.0020, 0.0022},
+		{t0 + 10000, 0.0023, 0.0025},
+		{t0 + 20000, 0.0017, 0.0019},
+		{t0 + 60000, 0.0018, 0.0020},
+	}
+	for _, book := range books {
+		if err := storage.StorePrice(newTestPrices(book.bid, book.ask), book.timepoint); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := storage.GetPriceHistory("KNC-ETH", "", "1m", t0, t0+120000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := history.Data["huobi"]; ok || len(history.Data["binance"]) != 2 {
+		t.Fatalf("expected 2 candles of binance only, got %+v", history.Data)
+	}
+	candle := history.Data["binance"][0]
+	if candle.Timestamp != t0 || candle.Count != 3 || !closeTo(candle.Open, 0.0021) || !closeTo(candle.High, 0.0024) ||
+		!closeTo(candle.Low, 0.0018) || !closeTo(candle.Close, 0.0018) || !closeTo(candle.BestBid, 0.0017) || !closeTo(candle.BestAsk, 0.0019) {
+		t.Errorf("unexpected 1m candle %+v", candle)
+	}
+	// 0.1 ETH is filled by the best price, 100 ETH is more than the book
+	expectedBidDepth := []common.DepthAtSize{{Size: 0.1, Price: 0.0017}, {Size: 100, Price: 0}}
+	if len(candle.BidDepth) != 2 || !closeTo(candle.BidDepth[0].Price, 0.0017) || candle.BidDepth[1] != expectedBidDepth[1] {
+		t.Errorf("expected bid depth %+v, got %+v", expectedBidDepth, candle.BidDepth)
+	}
+	if candle = history.Data["binance"][1]; candle.Timestamp != t0+60000 || candle.Count != 1 {
+		t.Errorf("unexpected 1m candle %+v", candle)
+	}
+
+	history, err = storage.GetPriceHistory("KNC-ETH", "binance", "5m", t0, t0+300000)
+	if err != nil || len(history.Data["binance"]) != 1 {
+		t.Fatalf("expected a 5m candle, got %+v, err %v", history, err)
+	}
+	if candle = history.Data["binance"][0]; candle.Count != 4 || !closeTo(candle.Close, 0.0019) {
+		t.Errorf("unexpected 5m candle %+v", candle)
+	}
+	history, err = storage.GetPriceHistory("KNC-ETH", "binance", RawPriceHistory, t0+10000, t0+20000)
+	if err != nil || len(history.Data["binance"]) != 2 {
+		t.Fatalf("expected 2 raw points, got %+v, err %v", history, err)
+	}
+
+	if _, err = storage.GetPriceHistory("KNC-ETH", "", RawPriceHistory, t0, t0+2*86400000); err == nil {
+		t.Error("expected error for too long time range")
+	}
+	if _, err = storage.GetPriceHistory("KNC-ETH", "", "2m", t0, t0+60000); err == nil {
+		t.Error("expected error for unsupported resolution")
+	}
+
+	// only raw points are out of retention
+	pruned, err := storage.PruneExpiredPriceHistory(t0 + 3600000 + 15000)
+	if err != nil || pruned != 2 {
+		t.Fatalf("expected 2 raw points pruned, got %d, err %v", pruned, err)
+	}
+	history, err = storage.GetPriceHistory("KNC-ETH", "binance", RawPriceHistory, t0, t0+86400000)
+	if err != nil || len(history.Data["binance"]) != 2 || history.Data["binance"][0].Timestamp != t0+20000 {
+		t.Errorf("unexpected raw points after pruning %+v, err %v", history, err)
+	}
+	history, err = storage.GetPriceHistory("KNC-ETH", "binance", "1m", t0, t0+86400000)
+	if err != nil || len(history.Data["binance"]) != 2 {
+		t.Errorf("unexpected 1m candles after pruning %+v, err %v", history, err)
+	}
+}
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) < 1e-12
+}
+
+func TestDepthAtSize(t *testing.T) {
+	entries := []common.PriceEntry{{Quantity: 10, Rate: 0.1}, {Quantity: 20, Rate: 0.05}}
+	var tests = []struct {
+		size     float64
+		expected float64
+	}{
+		{0.5, 0.1},
+		{1, 0.1},
+		// 10 tokens at 0.1 and 10 tokens at 0.05
+		{1.5, 0.075},
+		{2, 2.0 / 30},
+		{2.1, 0},
+	}
+	for _, tc := range tests {
+		if result := depthAtSize(entries, tc.size); !closeTo(result, tc.expected) {
+			t.Errorf("size %f: expected %f, got %f", tc.size, tc.expected, result)
+		}
+	}
+}
+
+func testSettings(t *testing.T, storage testStorage) {
+	// confirmed configs are tested by testConfirmedConfigs
+	if _, err := storage.GetRebalanceQuadratic(); err == nil {
+		t.Fatal("expected error when there is no rebalance quadratic equation")
+	}
+	if _, err := storage.GetPWIEquationV2(); err == nil {
+		t.Fatal("expected error when there is no PWI equation")
+	}
+
+	if err := storage.StoreSetrateControl(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.StoreSetrateControl(true); err != nil {
+		t.Fatal(err)
+	}
+	if control, cErr := storage.GetSetrateControl(); cErr != nil || !control.Status {
+		t.Fatalf("expected set rate to be enabled, got %+v, err %v", control, cErr)
+	}
+
+	thresholds := common.BalanceThresholds{Operators: map[string]float64{"pricing_op": 1}, Tokens: map[string]float64{}}
+	if err := storage.StoreBalanceThresholds(thresholds); err != nil {
+		t.Fatal(err)
+	}
+	if got, gErr := storage.GetBalanceThresholds(); gErr != nil || got.Operators["pricing_op"] != 1 {
+		t.Fatalf("unexpected balance thresholds %+v, err %v", got, gErr)
+	}
+}
+
+func testExchangeNotifications(t *testing.T, storage testStorage) {
+	if err := storage.UpdateExchangeNotification("binance", "withdraw", "KNC", 1000, 2000, true, "failed"); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.UpdateExchangeNotification("binance", "withdraw", "KNC", 1000, 3000, false, "recovered"); err != nil {
+		t.Fatal(err)
+	}
+	notifications, err := storage.GetExchangeNotifications()
+	if err != nil {
+		t.Fatal(err)
+	}
+	noti := notifications["binance"]["withdraw"]["KNC"]
+	if noti.ToTime != 3000 || noti.IsWarning {
+		t.Fatalf("unexpected notifications %+v", notifications)
+	}
+}
+
+// runStorageTests runs all storage tests, each one against a new storage.
+func testAPIKeys(t *testing.T, storage testStorage) {
+	pricing := common.APIKey{
+		Name:        "pricing",
+		Secrets:     []common.APIKeySecret{{Secret: "s1", CreatedAt: 1000}},
+		Permissions: []string{"rebalance"},
+		Scopes:      []string{"trade:binance"},
+		CreatedAt:   1000,
+	}
+	readonly := common.APIKey{
+		Name:        "analytic",
+		Secrets:     []common.APIKeySecret{{Secret: "s2", CreatedAt: 1000}},
+		Permissions: []string{"read_only"},
+		ExpiresAt:   5000,
+	}
+	for _, key := range []common.APIKey{pricing, readonly} {
+		if err := storage.CreateAPIKey(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := storage.CreateAPIKey(pricing); err == nil {
+		t.Error("expected error creating existing key")
+	}
+	got, err := storage.GetAPIKey("pricing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, pricing) {
+		t.Errorf("expected %+v, got %+v", pricing, got)
+	}
+
+	pricing.Secrets = append(pricing.Secrets, common.APIKeySecret{Secret: "s3", CreatedAt: 2000})
+	pricing.Secrets[0].ExpiresAt = 3000
+	pricing.Disabled = true
+	if got, err = storage.UpdateAPIKey("pricing", func(key *common.APIKey) error {
+		*key = pricing
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, pricing) {
+		t.Errorf("expected updated key %+v, got %+v", pricing, got)
+	}
+	if _, err = storage.UpdateAPIKey("pricing", func(key *common.APIKey) error {
+		key.Disabled = false
+		return errors.New("invalid update")
+	}); err == nil {
+		t.Error("expected error of failed update")
+	}
+	if got, err = storage.GetAPIKey("pricing"); err != nil || !got.Disabled {
+		t.Errorf("expected failed update to store nothing, got %+v, %v", got, err)
+	}
+	if _, err = storage.UpdateAPIKey("unknown", func(key *common.APIKey) error { return nil }); err == nil {
+		t.Error("expected error updating unknown key")
+	}
+	keys, err := storage.GetAPIKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0].Name != "analytic" || !reflect.DeepEqual(keys[1], pricing) {
+		t.Errorf("expected keys sorted by name with updated pricing key, got %+v", keys)
+	}
+
+	if err = storage.RemoveAPIKey("analytic"); err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.RemoveAPIKey("analytic"); err == nil {
+		t.Error("expected error removing removed key")
+	}
+	if _, err = storage.GetAPIKey("analytic"); err == nil {
+		t.Error("expected error getting removed key")
+	}
+}
+
+func testAuditLog(t *testing.T, storage testStorage) {
+	records := []common.AuditRecord{
+		{Timestamp: 1000, Key: "pricing", Method: "POST", Endpoint: "/setrates", Params: map[string][]string{"tokens": {"KNC"}}, Success: true},
+		{Timestamp: 2000, Key: "kn_configuration", Method: "POST", Endpoint: "/settargetqty", Success: false, Reason: "invalid"},
+		// timestamp before the last record is moved to the last timestamp
+		{Timestamp: 1500, Key: "pricing", Method: "POST", Endpoint: "/withdraw", Success: true},
+		{Timestamp: 3000, Key: "pricing", Method: "POST", Endpoint: "/setrates", Success: true},
+	}
+	for i, record := range records {
+		appended, err := storage.AppendAuditRecord(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if appended.ID != uint64(i+1) {
+			t.Errorf("expected record ID %d, got %d", i+1, appended.ID)
+		}
+		records[i] = appended
+	}
+	if records[2].Timestamp != 2000 {
+		t.Errorf("expected timestamp 2000, got %d", records[2].Timestamp)
+	}
+
+	all, err := storage.GetAuditRecords(common.AuditFilter{ToTime: math.MaxUint64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(all, records) {
+		t.Errorf("expected records %+v, got %+v", records, all)
+	}
+	if err = common.VerifyAuditRecords(all); err != nil {
+		t.Error(err)
+	}
+
+	var tests = []struct {
+		filter common.AuditFilter
+		ids    []uint64
+	}{
+		{common.AuditFilter{FromTime: 2000, ToTime: 2000}, []uint64{2, 3}},
+		{common.AuditFilter{FromTime: 0, ToTime: 3000, Key: "pricing"}, []uint64{1, 3, 4}},
+		{common.AuditFilter{FromTime: 1000, ToTime: 2999, Key: "pricing", Endpoint: "/setrates"}, []uint64{1}},
+		{common.AuditFilter{FromTime: 3001, ToTime: 4000}, []uint64{}},
+	}
+	for _, tc := range tests {
+		result, gErr := storage.GetAuditRecords(tc.filter)
+		if gErr != nil {
+			t.Fatal(gErr)
+		}
+		ids := []uint64{}
+		for _, record := range result {
+			ids = append(ids, record.ID)
+		}
+		if !reflect.DeepEqual(ids, tc.ids) {
+			t.Errorf("filter %+v: expected records %v, got %v", tc.filter, tc.ids, ids)
+		}
+	}
+	if _, err = storage.GetAuditRecords(common.AuditFilter{FromTime: 2000, ToTime: 1000}); err == nil {
+		t.Error("expected error for invalid time range")
+	}
+
+	// records before 2500 are out of retention
+	pruned, err := storage.PruneExpiredAuditRecords(AUDIT_LOG_RETENTION + 2500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 3 {
+		t.Errorf("expected 3 pruned audit records, got %d", pruned)
+	}
+	// the last record is kept even if it is out of retention
+	if pruned, err = storage.PruneExpiredAuditRecords(AUDIT_LOG_RETENTION + 4000); err != nil || pruned != 0 {
+		t.Errorf("expected the last audit record kept, got %d pruned, %v", pruned, err)
+	}
+	next, err := storage.AppendAuditRecord(common.AuditRecord{Timestamp: 4000, Key: "pricing", Method: "POST", Endpoint: "/setrates"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remaining, err := storage.GetAuditRecords(common.AuditFilter{ToTime: math.MaxUint64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(remaining, []common.AuditRecord{records[3], next}) {
+		t.Errorf("expected records %+v after pruning, got %+v", []common.AuditRecord{records[3], next}, remaining)
+	}
+	if err = common.VerifyAuditRecords(remaining); err != nil {
+		t.Error(err)
+	}
+}
+
+func testConfigVersions(t *testing.T, storage testStorage) {
+	if _, err := storage.GetConfigVersion("target_qty_v2", 0); err != common.ErrConfigVersionNotFound {
+		t.Errorf("expected no versions, got %v", err)
+	}
+	first, err := common.NewConfigProposal("target_qty_v2", []byte(`{"KNC": {"total_target": 1}}`), "kn_configuration", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.StoreConfigProposal(first); err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.StoreConfigProposal(first); err == nil {
+		t.Error("expected error storing a second pending proposal")
+	}
+	// proposals of other types are independent
+	other, err := common.NewConfigProposal("rebalance_quadratic", []byte(`{}`), "kn_configuration", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.StoreConfigProposal(other); err != nil {
+		t.Fatal(err)
+	}
+	pending, err := storage.GetConfigProposal("target_qty_v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pending, first) {
+		t.Errorf("expected pending proposal %+v, got %+v", first, pending)
+	}
+	if _, err = storage.ConfirmConfigProposal("target_qty_v2", "wrong hash", "kn_confirm_configuration"); err == nil {
+		t.Error("expected error confirming a proposal of another hash")
+	}
+	version, err := storage.ConfirmConfigProposal("target_qty_v2", first.Hash, "kn_confirm_configuration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version.Version != 1 || version.Proposer != "kn_configuration" || version.Confirmer != "kn_confirm_configuration" || string(version.Value) != string(first.Value) {
+		t.Errorf("unexpected first version %+v", version)
+	}
+	if _, err = storage.GetConfigProposal("target_qty_v2"); err == nil {
+		t.Error("expected confirmed proposal to be removed")
+	}
+
+	second, err := common.NewConfigProposal("target_qty_v2", []byte(`{"KNC": {"total_target": 2}}`), "", 2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.StoreConfigProposal(second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = storage.ConfirmConfigProposal("target_qty_v2", second.Hash, "kn_confirm_configuration"); err != nil {
+		t.Fatal(err)
+	}
+	latest, err := storage.GetConfigVersion("target_qty_v2", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Version != 2 || string(latest.Value) != string(second.Value) {
+		t.Errorf("unexpected latest version %+v", latest)
+	}
+	got, err := storage.GetConfigVersion("target_qty_v2", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, version) {
+		t.Errorf("expected version %+v, got %+v", version, got)
+	}
+	if _, err = storage.GetConfigVersion("target_qty_v2", 3); err != common.ErrConfigVersionNotFound {
+		t.Errorf("expected missing version, got %v", err)
+	}
+	versions, err := storage.GetConfigVersions("target_qty_v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || versions[0].Version != 1 || versions[1].Version != 2 {
+		t.Errorf("expected versions sorted by version, got %+v", versions)
+	}
+	if versions, err = storage.GetConfigVersions("rebalance_quadratic"); err != nil || len(versions) != 0 {
+		t.Errorf("expected no versions of rebalance quadratic, got %+v %v", versions, err)
+	}
+
+	if err = storage.RemoveConfigProposal("rebalance_quadratic"); err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.RemoveConfigProposal("rebalance_quadratic"); err == nil {
+		t.Error("expected error removing missing proposal")
+	}
+}
+
+func testConfirmedConfigs(t *testing.T, storage testStorage) {
+	confirm := func(configType, value string) {
+		proposal, err := common.NewConfigProposal(configType, []byte(value), "kn_configuration", 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = storage.StoreConfigProposal(proposal); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = storage.ConfirmConfigProposal(configType, proposal.Hash, "kn_confirm_configuration"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	confirm("target_qty_v2", `{"KNC":{"set_target":{"total_target":9}}}`)
+	if targetQty, err := storage.GetTargetQtyV2(); err != nil || targetQty["KNC"].SetTarget.TotalTarget != 9 {
+		t.Errorf("unexpected target quantity v2 %+v, err %v", targetQty, err)
+	}
+	confirm("target_qty", `{"data":"KNC_1_2_3_4","type":1}`)
+	if targetQty, err := storage.GetTokenTargetQty(); err != nil || targetQty.ID != 1000 || targetQty.Data != "KNC_1_2_3_4" || targetQty.Status != "confirmed" {
+		t.Errorf("unexpected target quantity %+v, err %v", targetQty, err)
+	}
+	if targetQty, err := storage.GetTargetQtyV2(); err != nil || targetQty["KNC"].SetTarget.TotalTarget != 1 {
+		t.Errorf("expected target quantity written through to v2, got %+v, err %v", targetQty, err)
+	}
+	confirm("pwi_equation", `{"data":"KNC_1_2_3"}`)
+	if pwi, err := storage.GetPWIEquation(); err != nil || pwi.ID != 1000 || pwi.Data != "KNC_1_2_3" {
+		t.Errorf("unexpected PWI equation %+v, err %v", pwi, err)
+	}
+	if pwi, err := storage.GetPWIEquationV2(); err != nil || pwi["KNC"]["bid"].B != 2 {
+		t.Errorf("expected PWI equation written through to v2, got %+v, err %v", pwi, err)
+	}
+	confirm("pwi_equation_v2", `{"KNC":{"bid":{"a":4},"ask":{"a":5}}}`)
+	if pwi, err := storage.GetPWIEquationV2(); err != nil || pwi["KNC"]["ask"].A != 5 {
+		t.Errorf("unexpected PWI equation v2 %+v, err %v", pwi, err)
+	}
+	confirm("rebalance_quadratic", `{"KNC":{"rebalance_quadratic":{"a":1}}}`)
+	if equation, err := storage.GetRebalanceQuadratic(); err != nil || equation["KNC"].RebalanceQuadratic.A != 1 {
+		t.Errorf("unexpected rebalance quadratic equation %+v, err %v", equation, err)
+	}
+	confirm("stable_token_params", `{"DGX":{"ask_spread":1}}`)
+	if params, err := storage.GetStableTokenParams(); err != nil || params["DGX"] == nil {
+		t.Errorf("unexpected stable token params %+v, err %v", params, err)
+	}
+
+	// configs which can't be stored are neither confirmed nor recorded
+	proposal, err := common.NewConfigProposal("step_functions", []byte(`{}`), "kn_configuration", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.StoreConfigProposal(proposal); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = storage.ConfirmConfigProposal("step_functions", proposal.Hash, "kn_confirm_configuration"); err == nil {
+		t.Error("expected error confirming config which can't be stored")
+	}
+	if _, err = storage.GetConfigProposal("step_functions"); err != nil {
+		t.Errorf("expected proposal to stay pending, got %v", err)
+	}
+	if _, err = storage.GetConfigVersion("step_functions", 0); err != common.ErrConfigVersionNotFound {
+		t.Errorf("expected no versions of step functions, got %v", err)
+	}
+}
+
+func runStorageTests(t *testing.T, newStorage func(t *testing.T) (testStorage, func())) {
+	tests := []struct {
+		name string
+		test func(*testing.T, testStorage)
+	}{
+		{"HasPendingDeposit", testHasPendingDeposit},
+		{"GasSpend", testGasSpend},
+		{"Activities", testActivities},
+		{"ActivityPages", testActivityPages},
+		{"VersionedData", testVersionedData},
+		{"AuthDataDeltas", testAuthDataDeltas},
+		{"PriceHistory", testPriceHistory},
+		{"Settings", testSettings},
+		{"StepFunctionSubmissions", testStepFunctionSubmissions},
+		{"ExchangeNotifications", testExchangeNotifications},
+		{"APIKeys", testAPIKeys},
+		{"AuditLog", testAuditLog},
+		{"ConfigVersions", testConfigVersions},
+		{"ConfirmedConfigs", testConfirmedConfigs},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			storage, cleanup := newStorage(t)
+			defer cleanup()
+			tc.test(t, storage)
+		})
+	}
+}
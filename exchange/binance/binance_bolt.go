@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"sync"
 
+	"github.com/KyberNetwork/reserve-data/boltutil"
 	"github.com/KyberNetwork/reserve-data/common"
 	"github.com/boltdb/bolt"
 )
@@ -17,6 +18,9 @@ const (
 	MAX_GET_TRADE_HISTORY uint64 = 3 * 86400000
 )
 
+//BoltMigrations are the schema migrations of the exchange bolt database
+var BoltMigrations = boltutil.Migrations{}
+
 type BinanceStorage struct {
 	mu sync.RWMutex
 	db *bolt.DB
@@ -26,7 +30,7 @@ func NewBoltStorage(path string) (*BinanceStorage, error) {
 	// init instance
 	var err error
 	var db *bolt.DB
-	db, err = bolt.Open(path, 0600, nil)
+	db, err = boltutil.Open(path, BoltMigrations)
 	if err != nil {
 		return nil, err
 	}
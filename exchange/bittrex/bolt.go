@@ -19,6 +19,9 @@ const (
 	MAX_GET_TRADE_HISTORY   uint64 = 3 * 86400000
 )
 
+//BoltMigrations are the schema migrations of the exchange bolt database
+var BoltMigrations = boltutil.Migrations{}
+
 //BoltStorage storage object for bittrex
 type BoltStorage struct {
 	db *bolt.DB
@@ -29,7 +32,7 @@ func NewBoltStorage(path string) (*BoltStorage, error) {
 	// init instance
 	var err error
 	var db *bolt.DB
-	db, err = bolt.Open(path, 0600, nil)
+	db, err = boltutil.Open(path, BoltMigrations)
 	if err != nil {
 		return nil, err
 	}
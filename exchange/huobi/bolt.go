@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"sync"
 
+	"github.com/KyberNetwork/reserve-data/boltutil"
 	"github.com/KyberNetwork/reserve-data/common"
 	"github.com/boltdb/bolt"
 )
@@ -20,6 +21,9 @@ const (
 	MAX_GET_TRADE_HISTORY uint64 = 3 * 86400000
 )
 
+//BoltMigrations are the schema migrations of the exchange bolt database
+var BoltMigrations = boltutil.Migrations{}
+
 //BoltStorage strage object for using huobi
 //including boltdb
 type BoltStorage struct {
@@ -32,7 +36,7 @@ func NewBoltStorage(path string) (*BoltStorage, error) {
 	// init instance
 	var err error
 	var db *bolt.DB
-	db, err = bolt.Open(path, 0600, nil)
+	db, err = boltutil.Open(path, BoltMigrations)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,232 @@
+package http
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/http/httputil"
+	ethereum "github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultRotationGracePeriod is how long old secrets of a rotated key stay
+	// valid if grace period is not given, in millisecond.
+	defaultRotationGracePeriod uint64 = 86400000
+	// apiKeySecretSize is the number of random bytes of API key secrets.
+	apiKeySecretSize = 32
+)
+
+var apiKeyNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,64}$`)
+
+// apiKeysConfigured responds failure and returns false if API keys are not
+// supported by the server.
+func (self *HTTPServer) apiKeysConfigured(c *gin.Context) bool {
+	if self.apiKeys == nil {
+		httputil.ResponseFailure(c, httputil.WithError(errors.New("API keys are not configured")))
+		return false
+	}
+	return true
+}
+
+func newAPIKeySecret() (string, error) {
+	secret := make([]byte, apiKeySecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return ethereum.Bytes2Hex(secret), nil
+}
+
+// splitList splits a comma separated list, empty items are dropped.
+func splitList(value string) []string {
+	result := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// setAPIKeyFields sets permissions, scopes, expires_at and disabled of key
+// which are given in form.
+func setAPIKeyFields(key *common.APIKey, form url.Values) error {
+	if _, ok := form["permissions"]; ok {
+		names := splitList(form.Get("permissions"))
+		if len(names) == 0 {
+			return errors.New("API key must have at least one permission")
+		}
+		if _, err := parsePermissions(names); err != nil {
+			return err
+		}
+		key.Permissions = names
+	}
+	if _, ok := form["scopes"]; ok {
+		scopes := splitList(form.Get("scopes"))
+		if err := common.ValidateScopes(scopes); err != nil {
+			return err
+		}
+		key.Scopes = scopes
+	}
+	if _, ok := form["expires_at"]; ok {
+		expiresAt, err := strconv.ParseUint(form.Get("expires_at"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid expires_at: %s", err)
+		}
+		key.ExpiresAt = expiresAt
+	}
+	if _, ok := form["disabled"]; ok {
+		disabled, err := strconv.ParseBool(form.Get("disabled"))
+		if err != nil {
+			return fmt.Errorf("invalid disabled: %s", err)
+		}
+		key.Disabled = disabled
+	}
+	return nil
+}
+
+// GetAPIKeys returns all API keys without their secrets.
+func (self *HTTPServer) GetAPIKeys(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{AdminPermission})
+	if !ok || !self.apiKeysConfigured(c) {
+		return
+	}
+	keys, err := self.apiKeys.GetAPIKeys()
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	result := []common.APIKey{}
+	for _, key := range keys {
+		result = append(result, key.Redacted())
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(result))
+}
+
+// CreateAPIKey creates a key with comma separated permissions, and optional
+// comma separated scopes and expires_at. The secret is only returned here.
+func (self *HTTPServer) CreateAPIKey(c *gin.Context) {
+	postForm, ok := self.Authenticated(c, []string{"name", "permissions"}, []Permission{AdminPermission})
+	if !ok || !self.apiKeysConfigured(c) {
+		return
+	}
+	name := postForm.Get("name")
+	if !apiKeyNamePattern.MatchString(name) {
+		httputil.ResponseFailure(c, httputil.WithReason(fmt.Sprintf("Invalid API key name %s", name)))
+		return
+	}
+	timepoint := common.GetTimepoint()
+	key := common.APIKey{Name: name, CreatedAt: timepoint, UpdatedAt: timepoint}
+	if err := setAPIKeyFields(&key, postForm); err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	secret, err := newAPIKeySecret()
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	key.Secrets = []common.APIKeySecret{{Secret: secret, CreatedAt: timepoint}}
+	if err = self.apiKeys.CreateAPIKey(key); err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithMultipleFields(gin.H{
+		"key":    key.Redacted(),
+		"secret": secret,
+	}))
+}
+
+// UpdateAPIKey updates permissions, scopes, expires_at or disabled of a key,
+// only the given ones are changed.
+func (self *HTTPServer) UpdateAPIKey(c *gin.Context) {
+	postForm, ok := self.Authenticated(c, []string{"name"}, []Permission{AdminPermission})
+	if !ok || !self.apiKeysConfigured(c) {
+		return
+	}
+	key, err := self.apiKeys.UpdateAPIKey(postForm.Get("name"), func(key *common.APIKey) error {
+		if err := setAPIKeyFields(key, postForm); err != nil {
+			return err
+		}
+		key.UpdatedAt = common.GetTimepoint()
+		return nil
+	})
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(key.Redacted()))
+}
+
+// RotateAPIKey adds a new secret to a key and returns it. Current secrets
+// stay valid for grace_period millisecond, default to a day, so clients can
+// switch to the new secret without downtime. Expired secrets are removed.
+func (self *HTTPServer) RotateAPIKey(c *gin.Context) {
+	postForm, ok := self.Authenticated(c, []string{"name"}, []Permission{AdminPermission})
+	if !ok || !self.apiKeysConfigured(c) {
+		return
+	}
+	gracePeriod := defaultRotationGracePeriod
+	if value := postForm.Get("grace_period"); value != "" {
+		var err error
+		if gracePeriod, err = strconv.ParseUint(value, 10, 64); err != nil {
+			httputil.ResponseFailure(c, httputil.WithReason(fmt.Sprintf("Invalid grace_period: %s", err)))
+			return
+		}
+	}
+	secret, err := newAPIKeySecret()
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	key, err := self.apiKeys.UpdateAPIKey(postForm.Get("name"), func(key *common.APIKey) error {
+		timepoint := common.GetTimepoint()
+		key.Secrets = rotateSecrets(key.Secrets, secret, timepoint, gracePeriod)
+		key.UpdatedAt = timepoint
+		return nil
+	})
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithMultipleFields(gin.H{
+		"key":    key.Redacted(),
+		"secret": secret,
+	}))
+}
+
+// rotateSecrets drops expired secrets, makes the others expire after grace
+// period and adds the new secret.
+func rotateSecrets(secrets []common.APIKeySecret, secret string, timepoint, gracePeriod uint64) []common.APIKeySecret {
+	expiresAt := timepoint + gracePeriod
+	result := []common.APIKeySecret{}
+	for _, s := range secrets {
+		if s.ExpiresAt != 0 && s.ExpiresAt <= timepoint {
+			continue
+		}
+		if s.ExpiresAt == 0 || s.ExpiresAt > expiresAt {
+			s.ExpiresAt = expiresAt
+		}
+		result = append(result, s)
+	}
+	return append(result, common.APIKeySecret{Secret: secret, CreatedAt: timepoint})
+}
+
+// RemoveAPIKey removes a key, requests signed by it are rejected immediately.
+func (self *HTTPServer) RemoveAPIKey(c *gin.Context) {
+	postForm, ok := self.Authenticated(c, []string{"name"}, []Permission{AdminPermission})
+	if !ok || !self.apiKeysConfigured(c) {
+		return
+	}
+	if err := self.apiKeys.RemoveAPIKey(postForm.Get("name")); err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c)
+}
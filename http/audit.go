@@ -0,0 +1,166 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/http/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// auditContextKey marks requests to be audited in gin context.
+	auditContextKey = "audit"
+	// maxAuditResponseSize is the max size of responses kept to audit their
+	// results, responses of mutating APIs are much smaller.
+	maxAuditResponseSize = 64 * 1024
+)
+
+// AuditStorage is the append only storage of audit records.
+type AuditStorage interface {
+	// AppendAuditRecord chains record after the last record, its ID, hashes
+	// and timestamp are set by the storage.
+	AppendAuditRecord(record common.AuditRecord) (common.AuditRecord, error)
+	// GetAuditRecords returns records selected by filter sorted by ID.
+	GetAuditRecords(filter common.AuditFilter) ([]common.AuditRecord, error)
+}
+
+// auditResponseWriter keeps the response to get the result of audited requests.
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (self *auditResponseWriter) Write(data []byte) (int, error) {
+	if self.body.Len()+len(data) <= maxAuditResponseSize {
+		self.body.Write(data)
+	}
+	return self.ResponseWriter.Write(data)
+}
+
+func (self *auditResponseWriter) WriteString(s string) (int, error) {
+	return self.Write([]byte(s))
+}
+
+// markAudited marks mutating requests to be audited, it is called by
+// Authenticated once the caller is authenticated so all permissioned APIs
+// are covered. Requests failing authentication are not recorded, so
+// anonymous callers can't fill the audit log.
+func markAudited(c *gin.Context) {
+	if c.Request.Method != http.MethodGet {
+		c.Set(auditContextKey, true)
+	}
+}
+
+// auditRequests is the middleware recording requests marked by markAudited
+// to the audit storage, with the key signing them and their results.
+func (self *HTTPServer) auditRequests(c *gin.Context) {
+	if self.audit == nil || c.Request.Method == http.MethodGet {
+		c.Next()
+		return
+	}
+	writer := &auditResponseWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	c.Next()
+	if !c.GetBool(auditContextKey) {
+		return
+	}
+	record := common.AuditRecord{
+		Timestamp: common.GetTimepoint(),
+		Method:    c.Request.Method,
+		Endpoint:  c.Request.URL.Path,
+		Params:    c.Request.Form,
+	}
+	if principal, ok := principalOf(c); ok {
+		record.Key = principal.Key
+	}
+	var result struct {
+		Success bool   `json:"success"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.Unmarshal(writer.body.Bytes(), &result); err != nil {
+		record.Reason = fmt.Sprintf("unknown result, response status %d", writer.Status())
+	} else {
+		record.Success = result.Success
+		record.Reason = result.Reason
+	}
+	appended, err := self.audit.AppendAuditRecord(record)
+	if err != nil {
+		log.Printf("Recording audit of %s %s failed: %s", record.Method, record.Endpoint, err)
+		return
+	}
+	// the hash of the last record is logged out of the audit storage, so
+	// removing records from the end of the chain can be detected
+	log.Printf("Audit log head: record %d, hash %s", appended.ID, appended.Hash)
+}
+
+func (self *HTTPServer) auditConfigured(c *gin.Context) bool {
+	if self.audit == nil {
+		httputil.ResponseFailure(c, httputil.WithError(errors.New("audit log is not configured")))
+		return false
+	}
+	return true
+}
+
+// auditFilter returns the filter of fromTime, toTime, key and endpoint params.
+func (self *HTTPServer) auditFilter(c *gin.Context) (common.AuditFilter, bool) {
+	fromTime, toTime, ok := self.ValidateTimeInput(c)
+	if !ok {
+		return common.AuditFilter{}, false
+	}
+	return common.AuditFilter{
+		FromTime: fromTime,
+		ToTime:   toTime,
+		Key:      c.Query("key"),
+		Endpoint: c.Query("endpoint"),
+	}, true
+}
+
+// GetAuditLog returns audit records between fromTime and toTime, of a key
+// and an endpoint if given.
+func (self *HTTPServer) GetAuditLog(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission, AdminPermission})
+	if !ok || !self.auditConfigured(c) {
+		return
+	}
+	filter, ok := self.auditFilter(c)
+	if !ok {
+		return
+	}
+	records, err := self.audit.GetAuditRecords(filter)
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(records))
+}
+
+// ExportAuditLog returns all audit records between fromTime and toTime as an
+// attachment, with the result of verifying their hash chain.
+func (self *HTTPServer) ExportAuditLog(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission, AdminPermission})
+	if !ok || !self.auditConfigured(c) {
+		return
+	}
+	fromTime, toTime, ok := self.ValidateTimeInput(c)
+	if !ok {
+		return
+	}
+	records, err := self.audit.GetAuditRecords(common.AuditFilter{FromTime: fromTime, ToTime: toTime})
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	fields := gin.H{"data": records, "verified": true}
+	if err = common.VerifyAuditRecords(records); err != nil {
+		fields["verified"] = false
+		fields["verification_error"] = err.Error()
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=audit-log-%d-%d.json", fromTime, toTime))
+	httputil.ResponseSuccess(c, httputil.WithMultipleFields(fields))
+}
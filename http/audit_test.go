@@ -0,0 +1,122 @@
+package http
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/data/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// newSignedRequest returns a request with params signed by secret, params of
+// GET requests are in the query.
+func newSignedRequest(t *testing.T, method, endpoint, secret string, params map[string]string) *http.Request {
+	form := url.Values{}
+	for key, value := range params {
+		form.Set(key, value)
+	}
+	form.Set("nonce", strconv.FormatUint(common.GetTimepoint(), 10))
+	var req *http.Request
+	var err error
+	if method == http.MethodGet {
+		req, err = http.NewRequest(method, endpoint+"?"+form.Encode(), nil)
+	} else {
+		req, err = http.NewRequest(method, endpoint, strings.NewReader(form.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Add("signed", hmacSign(secret, form.Encode()))
+	return req
+}
+
+func TestHTTPServerAuditLog(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test_audit_log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}()
+	st, err := storage.NewBoltStorage(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := HTTPServer{
+		authEnabled: true,
+		auth:        NewKeyStoreAuthentication(st, KNAuthentication{KNReadOnly: "readonly", KNAdmin: "admin"}),
+		apiKeys:     st,
+		audit:       st,
+		r:           gin.Default(),
+	}
+	s.register()
+
+	requests := []*http.Request{
+		newSignedRequest(t, http.MethodPost, "/create-api-key", "admin", map[string]string{"name": "pricing", "permissions": "rebalance"}),
+		newSignedRequest(t, http.MethodPost, "/remove-api-key", "admin", map[string]string{"name": "unknown"}),
+		// requests failing authentication and read requests are not audited
+		newSignedRequest(t, http.MethodPost, "/remove-api-key", "invalid", map[string]string{"name": "pricing"}),
+		newSignedRequest(t, http.MethodGet, "/api-keys", "admin", nil),
+	}
+	for _, req := range requests {
+		s.r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	resp := httptest.NewRecorder()
+	s.r.ServeHTTP(resp, newSignedRequest(t, http.MethodGet, "/audit-log/export", "readonly", map[string]string{"fromTime": "0"}))
+	var exported struct {
+		Success  bool                 `json:"success"`
+		Data     []common.AuditRecord `json:"data"`
+		Verified bool                 `json:"verified"`
+	}
+	if err = json.Unmarshal(resp.Body.Bytes(), &exported); err != nil {
+		t.Fatal(err)
+	}
+	if !exported.Success || !exported.Verified {
+		t.Fatalf("expected verified export, got %s", resp.Body.String())
+	}
+	expected := []struct {
+		key      string
+		endpoint string
+		name     string
+		success  bool
+		reason   string
+	}{
+		{"kn_admin", "/create-api-key", "pricing", true, ""},
+		{"kn_admin", "/remove-api-key", "unknown", false, "API key unknown doesn't exist"},
+	}
+	if len(exported.Data) != len(expected) {
+		t.Fatalf("expected %d audit records, got %+v", len(expected), exported.Data)
+	}
+	for i, e := range expected {
+		record := exported.Data[i]
+		if record.Key != e.key || record.Endpoint != e.endpoint || record.Method != http.MethodPost ||
+			record.Params["name"][0] != e.name || record.Success != e.success || record.Reason != e.reason {
+			t.Errorf("expected audit record %+v, got %+v", e, record)
+		}
+	}
+
+	resp = httptest.NewRecorder()
+	s.r.ServeHTTP(resp, newSignedRequest(t, http.MethodGet, "/audit-log", "readonly", map[string]string{"fromTime": "0", "key": "kn_admin"}))
+	var filtered struct {
+		Data []common.AuditRecord `json:"data"`
+	}
+	if err = json.Unmarshal(resp.Body.Bytes(), &filtered); err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered.Data) != 2 {
+		t.Errorf("expected 2 audit records of kn_admin, got %+v", filtered.Data)
+	}
+}
@@ -4,16 +4,37 @@ import (
 	"crypto/hmac"
 	"crypto/sha512"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
+	"strings"
 
+	"github.com/KyberNetwork/reserve-data/common"
 	ethereum "github.com/ethereum/go-ethereum/common"
 )
 
+// errInvalidSignedToken is returned if the request is not signed by any key.
+var errInvalidSignedToken = errors.New("Invalid signed token")
+
+// Principal is the key a request is signed with.
+type Principal struct {
+	Key         string       `json:"key"`
+	Permissions []Permission `json:"permissions"`
+	Scopes      []string     `json:"scopes,omitempty"`
+}
+
+// HasScope returns true if the key is allowed to act in scope, e.g trade:binance.
+func (self Principal) HasScope(scope string) bool {
+	return common.HasScope(self.Scopes, scope)
+}
+
 // Authentication is the authentication layer of HTTP APIs.
 type Authentication interface {
 	KNSign(message string) string
-	GetPermission(signed string, message string) []Permission
+	// Authenticate returns the principal of the key signing message, keyName
+	// is the key header of the request, which is empty for shared secrets.
+	Authenticate(keyName, signed, message string) (Principal, error)
 }
 
 type KNAuthentication struct {
@@ -21,6 +42,12 @@ type KNAuthentication struct {
 	KNReadOnly      string `json:"kn_readonly"`
 	KNConfiguration string `json:"kn_configuration"`
 	KNConfirmConf   string `json:"kn_confirm_configuration"`
+	// KNAdmin is the secret to manage API keys, admin permission is not
+	// granted to shared secrets if it is empty.
+	KNAdmin string `json:"kn_admin"`
+	// KNMetrics is the bearer token of Prometheus scrapes of metrics, they
+	// are rejected if it is empty.
+	KNMetrics string `json:"kn_metrics"`
 }
 
 func NewKNAuthenticationFromFile(path string) KNAuthentication {
@@ -35,36 +62,29 @@ func NewKNAuthenticationFromFile(path string) KNAuthentication {
 	return result
 }
 
-func (self KNAuthentication) KNSign(msg string) string {
-	mac := hmac.New(sha512.New, []byte(self.KNSecret))
+// hmacSign returns the hex encoded HMAC512 of msg with secret.
+func hmacSign(secret, msg string) string {
+	mac := hmac.New(sha512.New, []byte(secret))
 	if _, err := mac.Write([]byte(msg)); err != nil {
 		log.Printf("Encode message error: %s", err.Error())
 	}
 	return ethereum.Bytes2Hex(mac.Sum(nil))
 }
 
+func (self KNAuthentication) KNSign(msg string) string {
+	return hmacSign(self.KNSecret, msg)
+}
+
 func (self KNAuthentication) knReadonlySign(msg string) string {
-	mac := hmac.New(sha512.New, []byte(self.KNReadOnly))
-	if _, err := mac.Write([]byte(msg)); err != nil {
-		log.Printf("Encode message error: %s", err.Error())
-	}
-	return ethereum.Bytes2Hex(mac.Sum(nil))
+	return hmacSign(self.KNReadOnly, msg)
 }
 
 func (self KNAuthentication) knConfigurationSign(msg string) string {
-	mac := hmac.New(sha512.New, []byte(self.KNConfiguration))
-	if _, err := mac.Write([]byte(msg)); err != nil {
-		log.Printf("Encode message error: %s", err.Error())
-	}
-	return ethereum.Bytes2Hex(mac.Sum(nil))
+	return hmacSign(self.KNConfiguration, msg)
 }
 
 func (self KNAuthentication) knConfirmConfSign(msg string) string {
-	mac := hmac.New(sha512.New, []byte(self.KNConfirmConf))
-	if _, err := mac.Write([]byte(msg)); err != nil {
-		log.Printf("Encode message error: %s", err.Error())
-	}
-	return ethereum.Bytes2Hex(mac.Sum(nil))
+	return hmacSign(self.KNConfirmConf, msg)
 }
 
 func (self KNAuthentication) GetPermission(signed string, message string) []Permission {
@@ -85,5 +105,35 @@ func (self KNAuthentication) GetPermission(signed string, message string) []Perm
 	if signed == confirmConfSigned {
 		result = append(result, ConfirmConfPermission)
 	}
+	if self.KNAdmin != "" && signed == hmacSign(self.KNAdmin, message) {
+		result = append(result, AdminPermission)
+	}
 	return result
 }
+
+// sharedSecretNames are the config keys of shared secrets, requests signed by
+// them are attributed to these names.
+var sharedSecretNames = map[Permission]string{
+	RebalancePermission:   "kn_secret",
+	ReadOnlyPermission:    "kn_readonly",
+	ConfigurePermission:   "kn_configuration",
+	ConfirmConfPermission: "kn_confirm_configuration",
+	AdminPermission:       "kn_admin",
+}
+
+// Authenticate returns the principal of the shared secret signing message,
+// named by its config key. Named API keys are not supported.
+func (self KNAuthentication) Authenticate(keyName, signed, message string) (Principal, error) {
+	if keyName != "" {
+		return Principal{}, fmt.Errorf("API key %s is not supported, sign with shared secrets", keyName)
+	}
+	perms := self.GetPermission(signed, message)
+	if len(perms) == 0 {
+		return Principal{}, errInvalidSignedToken
+	}
+	names := []string{}
+	for _, perm := range perms {
+		names = append(names, sharedSecretNames[perm])
+	}
+	return Principal{Key: strings.Join(names, ","), Permissions: perms}, nil
+}
@@ -0,0 +1,45 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/KyberNetwork/reserve-data/http/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+// Backup starts writing a consistent bundle of all bolt databases of the running
+// process in background while they stay in use, and ships it to archive if configured.
+func (self *HTTPServer) Backup(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ConfigurePermission})
+	if !ok {
+		return
+	}
+	if self.backuper == nil {
+		httputil.ResponseFailure(c, httputil.WithError(errors.New("backup is not configured")))
+		return
+	}
+	job, err := self.backuper.Start()
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err), httputil.WithField("job", job))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(job))
+}
+
+// GetBackup returns the status of the latest backup started with POST /backup.
+func (self *HTTPServer) GetBackup(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ConfigurePermission})
+	if !ok {
+		return
+	}
+	if self.backuper == nil {
+		httputil.ResponseFailure(c, httputil.WithError(errors.New("backup is not configured")))
+		return
+	}
+	job, err := self.backuper.LastJob()
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(job))
+}
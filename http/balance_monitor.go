@@ -0,0 +1,67 @@
+package http
+
+import (
+	"encoding/json"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/http/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+// GetBalanceStatus returns the latest check of operator ETH balances, reserve token
+// balances and token allowances against their thresholds, with days of gas left of each operator.
+func (self *HTTPServer) GetBalanceStatus(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	data, err := self.app.GetBalanceStatus()
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(data))
+}
+
+// GetBalanceThresholds returns the current balance thresholds.
+func (self *HTTPServer) GetBalanceThresholds(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	data, err := self.metric.GetBalanceThresholds()
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(data))
+}
+
+// SetBalanceThresholds replaces the balance thresholds, they take effect at the next check.
+func (self *HTTPServer) SetBalanceThresholds(c *gin.Context) {
+	const dataPostFormKey = "data"
+
+	postForm, ok := self.Authenticated(c, []string{dataPostFormKey}, []Permission{ConfigurePermission})
+	if !ok {
+		return
+	}
+	data := []byte(postForm.Get(dataPostFormKey))
+	if len(data) > MAX_DATA_SIZE {
+		httputil.ResponseFailure(c, httputil.WithError(errDataSizeExceed))
+		return
+	}
+	var thresholds common.BalanceThresholds
+	if err := json.Unmarshal(data, &thresholds); err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	if err := thresholds.Validate(); err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	if err := self.metric.StoreBalanceThresholds(thresholds); err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c)
+}
@@ -0,0 +1,278 @@
+// Package client is the Go client of the reserve data HTTP APIs. It signs
+// requests the way the server authenticates them, operations are generated
+// from the OpenAPI specification of the APIs in http/openapi.yaml.
+package client
+
+//go:generate go run ./gen -spec ../openapi.yaml -out operations.go
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultTimeout is the timeout of requests if the HTTP client is not given.
+const defaultTimeout = 30 * time.Second
+
+// Error is the failure of an operation reported by the server.
+type Error struct {
+	StatusCode int
+	Reason     string
+}
+
+func (self *Error) Error() string {
+	return fmt.Sprintf("request failed with status %d: %s", self.StatusCode, self.Reason)
+}
+
+// Response is the envelope of JSON responses. Most operations respond
+// their result in data, others in fields of the envelope.
+type Response struct {
+	Success bool
+	Reason  string
+	Data    json.RawMessage
+	// Fields are all fields of the envelope.
+	Fields map[string]json.RawMessage
+}
+
+// Decode decodes data of the response to v.
+func (self *Response) Decode(v interface{}) error {
+	return self.DecodeField("data", v)
+}
+
+// DecodeField decodes the field name of the response to v.
+func (self *Response) DecodeField(name string, v interface{}) error {
+	raw, ok := self.Fields[name]
+	if !ok {
+		return fmt.Errorf("response has no field %s", name)
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// Signer signs the url encoded params of a request.
+type Signer func(message string) string
+
+// Sign returns the hex encoded HMAC-SHA512 of message by secret, which is the
+// signed header of requests.
+func Sign(secret, message string) string {
+	mac := hmac.New(sha512.New, []byte(secret))
+	if _, err := mac.Write([]byte(message)); err != nil {
+		log.Printf("Encode message error: %s", err.Error())
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Client calls the APIs of a server.
+type Client struct {
+	baseURL      string
+	keyName      string
+	signer       Signer
+	metricsToken string
+	httpClient   *http.Client
+
+	mu        sync.Mutex
+	lastNonce int64
+}
+
+// Option is the option to setup the Client on creation.
+type Option func(c *Client)
+
+// WithSecret signs requests by a shared secret of the server.
+func WithSecret(secret string) Option {
+	return WithSigner(func(message string) string {
+		return Sign(secret, message)
+	})
+}
+
+// WithAPIKey signs requests by the secret of the API key name.
+func WithAPIKey(name, secret string) Option {
+	return func(c *Client) {
+		WithSecret(secret)(c)
+		c.keyName = name
+	}
+}
+
+// WithSigner signs requests by signer, e.g the KNSign of an authentication.
+func WithSigner(signer Signer) Option {
+	return func(c *Client) {
+		c.signer = signer
+	}
+}
+
+// WithMetricsToken authenticates scrapes of Prometheus metrics by token.
+func WithMetricsToken(token string) Option {
+	return func(c *Client) {
+		c.metricsToken = token
+	}
+}
+
+// WithHTTPClient sends requests by httpClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// New creates a client of the server at baseURL. Requests of signed
+// operations are sent without signature if no secret is given, which is
+// only accepted by servers with authentication disabled.
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// nonce returns the current time in millisecond, it is increased if it is
+// not greater than the previous one as the server rejects reused nonces.
+func (self *Client) nonce() string {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	nonce := time.Now().UnixNano() / int64(time.Millisecond)
+	if nonce <= self.lastNonce {
+		nonce = self.lastNonce + 1
+	}
+	self.lastNonce = nonce
+	return strconv.FormatInt(nonce, 10)
+}
+
+// Do sends a request of path with query params and form params in the body.
+// Signed requests have a nonce param and are signed by the params of both.
+// The caller must close the body of the response.
+func (self *Client) Do(method, path string, query, form url.Values, signed bool) (*http.Response, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	if form == nil {
+		form = url.Values{}
+	}
+	var signature string
+	if signed {
+		if method == http.MethodGet {
+			query.Set("nonce", self.nonce())
+		} else {
+			form.Set("nonce", self.nonce())
+		}
+		// the server signs query and form params together
+		params := url.Values{}
+		for _, values := range []url.Values{query, form} {
+			for k, v := range values {
+				params[k] = append(params[k], v...)
+			}
+		}
+		if self.signer != nil {
+			signature = self.signer(params.Encode())
+		}
+	}
+	target := self.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var body *strings.Reader
+	if method == http.MethodGet {
+		body = strings.NewReader("")
+	} else {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if method != http.MethodGet {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if self.metricsToken != "" {
+		req.Header.Set("Authorization", "Bearer "+self.metricsToken)
+	}
+	if signature != "" {
+		req.Header.Set("signed", signature)
+		if self.keyName != "" {
+			req.Header.Set("key", self.keyName)
+		}
+	}
+	return self.httpClient.Do(req)
+}
+
+// call sends a request and decodes its envelope. The response is returned
+// along with an *Error if the operation failed, e.g the checks of readyz.
+func (self *Client) call(method, path string, query, form url.Values, signed bool) (*Response, error) {
+	resp, err := self.Do(method, path, query, form, signed)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cErr := resp.Body.Close(); cErr != nil {
+			log.Printf("Response body close error: %s", cErr.Error())
+		}
+	}()
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	result := &Response{}
+	if err = json.Unmarshal(raw, &result.Fields); err != nil {
+		return nil, &Error{StatusCode: resp.StatusCode, Reason: fmt.Sprintf("invalid response %q: %s", raw, err)}
+	}
+	if value, ok := result.Fields["success"]; ok {
+		if err = json.Unmarshal(value, &result.Success); err != nil {
+			return nil, err
+		}
+	}
+	if value, ok := result.Fields["reason"]; ok {
+		if err = json.Unmarshal(value, &result.Reason); err != nil {
+			return nil, err
+		}
+	}
+	result.Data = result.Fields["data"]
+	if !result.Success {
+		return result, &Error{StatusCode: resp.StatusCode, Reason: result.Reason}
+	}
+	return result, nil
+}
+
+// String returns a pointer of value for optional params.
+func String(value string) *string {
+	return &value
+}
+
+// Int64 returns a pointer of value for optional params.
+func Int64(value int64) *int64 {
+	return &value
+}
+
+// Bool returns a pointer of value for optional params.
+func Bool(value bool) *bool {
+	return &value
+}
+
+func setString(values url.Values, name string, value *string) {
+	if value != nil {
+		values.Set(name, *value)
+	}
+}
+
+func setInt64(values url.Values, name string, value *int64) {
+	if value != nil {
+		values.Set(name, strconv.FormatInt(*value, 10))
+	}
+}
+
+func setBool(values url.Values, name string, value *bool) {
+	if value != nil {
+		values.Set(name, strconv.FormatBool(*value))
+	}
+}
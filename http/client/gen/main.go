@@ -0,0 +1,223 @@
+// Command gen generates operations of the API client from the OpenAPI
+// specification.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/KyberNetwork/reserve-data/http/openapi"
+)
+
+// initialisms are param words named in upper case by Go conventions.
+var initialisms = map[string]string{
+	"id":         "ID",
+	"exchangeid": "ExchangeID",
+}
+
+// field is a param of an operation.
+type field struct {
+	openapi.Parameter
+	GoName string
+	GoType string
+}
+
+// operation is an operation of the client.
+type operation struct {
+	*openapi.Operation
+	Doc    []string
+	Fields []field
+	// PathExpr is the Go expression of the request path.
+	PathExpr string
+	HasQuery bool
+	HasForm  bool
+}
+
+var operationTemplate = template.Must(template.New("operations").Parse(`// Code generated by gen from the OpenAPI specification; DO NOT EDIT.
+
+package client
+
+import (
+	"net/http"
+	"net/url"
+{{if .Strconv}}	"strconv"
+{{end}})
+{{range .Operations}}
+{{if .Fields}}// {{.OperationID}}Params are the params of {{.OperationID}}.
+type {{.OperationID}}Params struct {
+{{range .Fields}}{{if .Description}}	// {{.Description}}
+{{end}}	{{.GoName}} {{.GoType}}
+{{end}}}
+{{end}}
+{{range .Doc}}// {{.}}
+{{end}}{{if .JSON}}func (self *Client) {{.OperationID}}({{if .Fields}}params {{.OperationID}}Params{{end}}) (*Response, error) {
+{{else}}// The caller must close the body of the response.
+func (self *Client) {{.OperationID}}({{if .Fields}}params {{.OperationID}}Params{{end}}) (*http.Response, error) {
+{{end}}{{if .HasQuery}}	query := url.Values{}
+{{end}}{{if .HasForm}}	form := url.Values{}
+{{end}}{{range .Fields}}{{if ne .In "path"}}{{$values := "query"}}{{if eq .In "form"}}{{$values = "form"}}{{end}}{{if .Required}}{{if eq .GoType "string"}}	{{$values}}.Set("{{.Name}}", params.{{.GoName}})
+{{else if eq .GoType "int64"}}	{{$values}}.Set("{{.Name}}", strconv.FormatInt(params.{{.GoName}}, 10))
+{{else}}	{{$values}}.Set("{{.Name}}", strconv.FormatBool(params.{{.GoName}}))
+{{end}}{{else if eq .GoType "*string"}}	setString({{$values}}, "{{.Name}}", params.{{.GoName}})
+{{else if eq .GoType "*int64"}}	setInt64({{$values}}, "{{.Name}}", params.{{.GoName}})
+{{else}}	setBool({{$values}}, "{{.Name}}", params.{{.GoName}})
+{{end}}{{end}}{{end}}	{{if .JSON}}return self.call{{else}}return self.Do{{end}}(http.Method{{if eq .Method "GET"}}Get{{else}}Post{{end}}, {{.PathExpr}}, {{if .HasQuery}}query{{else}}nil{{end}}, {{if .HasForm}}form{{else}}nil{{end}}, {{.Signed}})
+}
+{{end}}`))
+
+// goName returns the exported Go name of a param, e.g pending_only is
+// PendingOnly and order_id is OrderID.
+func goName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' })
+	result := ""
+	for _, word := range words {
+		if initialism, ok := initialisms[strings.ToLower(word)]; ok {
+			result += initialism
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		result += string(runes)
+	}
+	return result
+}
+
+// goType returns the Go type of a param, optional params are pointers so
+// they are only sent if they are given.
+func goType(param openapi.Parameter) (string, error) {
+	var result string
+	switch param.Schema.Type {
+	case "string":
+		result = "string"
+	case "integer":
+		result = "int64"
+	case "boolean":
+		result = "bool"
+	default:
+		return "", fmt.Errorf("unsupported type %s of param %s", param.Schema.Type, param.Name)
+	}
+	if !param.Required {
+		result = "*" + result
+	}
+	return result, nil
+}
+
+// doc wraps the summary and description of op as a doc comment.
+func doc(op *openapi.Operation) []string {
+	text := op.Summary
+	if op.Description != "" {
+		text += " " + op.Description
+	}
+	if text == "" {
+		text = "calls " + op.Method + " " + op.Path + "."
+	} else {
+		runes := []rune(text)
+		runes[0] = unicode.ToLower(runes[0])
+		text = string(runes)
+	}
+	lines := []string{}
+	line := op.OperationID
+	for _, word := range strings.Fields(text) {
+		if len(line)+len(word)+1 > 76 {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line += " " + word
+	}
+	return append(lines, line)
+}
+
+// pathExpr returns the Go expression of path with its params.
+func pathExpr(path string) string {
+	parts := []string{}
+	for path != "" {
+		start := strings.Index(path, "{")
+		if start < 0 {
+			parts = append(parts, fmt.Sprintf("%q", path))
+			break
+		}
+		if start > 0 {
+			parts = append(parts, fmt.Sprintf("%q", path[:start]))
+		}
+		end := strings.Index(path, "}")
+		parts = append(parts, fmt.Sprintf("url.PathEscape(params.%s)", goName(path[start+1:end])))
+		path = path[end+1:]
+	}
+	return strings.Join(parts, " + ")
+}
+
+func newOperation(op *openapi.Operation) (operation, error) {
+	result := operation{Operation: op, Doc: doc(op)}
+	for _, param := range op.Params() {
+		t, err := goType(param)
+		if err != nil {
+			return result, fmt.Errorf("%s: %s", op.OperationID, err)
+		}
+		switch param.In {
+		case "path":
+			if t != "string" {
+				return result, fmt.Errorf("%s: path param %s is not a required string", op.OperationID, param.Name)
+			}
+		case "query":
+			result.HasQuery = true
+		case openapi.InForm:
+			result.HasForm = true
+		default:
+			return result, fmt.Errorf("%s: unsupported location %s of param %s", op.OperationID, param.In, param.Name)
+		}
+		result.Fields = append(result.Fields, field{Parameter: param, GoName: goName(param.Name), GoType: t})
+	}
+	result.PathExpr = pathExpr(op.Path)
+	return result, nil
+}
+
+// generate returns the source of operations of spec.
+func generate(spec *openapi.Spec) ([]byte, error) {
+	data := struct {
+		Operations []operation
+		// Strconv is true if any required param is not a string
+		Strconv bool
+	}{}
+	for _, op := range spec.Operations() {
+		o, err := newOperation(op)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range o.Fields {
+			if f.Required && f.GoType != "string" {
+				data.Strconv = true
+			}
+		}
+		data.Operations = append(data.Operations, o)
+	}
+	buf := &bytes.Buffer{}
+	if err := operationTemplate.Execute(buf, data); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+func main() {
+	specPath := flag.String("spec", "../openapi.yaml", "path of the OpenAPI specification")
+	out := flag.String("out", "operations.go", "path of the generated operations")
+	flag.Parse()
+	spec, err := openapi.Load(*specPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	source, err := generate(spec)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = ioutil.WriteFile(*out, source, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/http/openapi"
+)
+
+func TestOperationsAreGenerated(t *testing.T) {
+	spec, err := openapi.Load("../../openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := generate(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := ioutil.ReadFile("../operations.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(source, current) {
+		t.Error("operations of the client are out of date, run go generate in http/client")
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for name, expected := range map[string]string{
+		"fromTime":     "FromTime",
+		"pending_only": "PendingOnly",
+		"order_id":     "OrderID",
+		"exchangeid":   "ExchangeID",
+		"afp_mid":      "AfpMid",
+	} {
+		if result := goName(name); result != expected {
+			t.Errorf("expected Go name of %s to be %s, got %s", name, expected, result)
+		}
+	}
+}
@@ -0,0 +1,1592 @@
+// Code generated by gen from the OpenAPI specification; DO NOT EDIT.
+
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// GetActivitiesParams are the params of GetActivities.
+type GetActivitiesParams struct {
+	Action *string
+	// The next_cursor of the previous page.
+	Cursor      *string
+	Destination *string
+	// Start of the time range in millisecond.
+	FromTime *int64
+	// Max number of records of a page, default to 100, at most 1000.
+	Limit       *int64
+	PendingOnly *bool
+	Status      *string
+	// End of the time range in millisecond, now if it is not given.
+	ToTime *int64
+	Token  *string
+}
+
+// GetActivities returns activities between fromTime and toTime, a page of
+// activities if any page param is given.
+func (self *Client) GetActivities(params GetActivitiesParams) (*Response, error) {
+	query := url.Values{}
+	setString(query, "action", params.Action)
+	setString(query, "cursor", params.Cursor)
+	setString(query, "destination", params.Destination)
+	setInt64(query, "fromTime", params.FromTime)
+	setInt64(query, "limit", params.Limit)
+	setBool(query, "pending_only", params.PendingOnly)
+	setString(query, "status", params.Status)
+	setInt64(query, "toTime", params.ToTime)
+	setString(query, "token", params.Token)
+	return self.call(http.MethodGet, "/activities", query, nil, true)
+}
+
+// GetAPIKeys returns all API keys without their secrets.
+func (self *Client) GetAPIKeys() (*Response, error) {
+	return self.call(http.MethodGet, "/api-keys", nil, nil, true)
+}
+
+// GetAuditLogParams are the params of GetAuditLog.
+type GetAuditLogParams struct {
+	Endpoint *string
+	// Start of the time range in millisecond.
+	FromTime *int64
+	Key      *string
+	// End of the time range in millisecond, now if it is not given.
+	ToTime *int64
+}
+
+// GetAuditLog returns audit records between fromTime and toTime, of a key and
+// an endpoint if given.
+func (self *Client) GetAuditLog(params GetAuditLogParams) (*Response, error) {
+	query := url.Values{}
+	setString(query, "endpoint", params.Endpoint)
+	setInt64(query, "fromTime", params.FromTime)
+	setString(query, "key", params.Key)
+	setInt64(query, "toTime", params.ToTime)
+	return self.call(http.MethodGet, "/audit-log", query, nil, true)
+}
+
+// ExportAuditLogParams are the params of ExportAuditLog.
+type ExportAuditLogParams struct {
+	// Start of the time range in millisecond.
+	FromTime int64
+	// End of the time range in millisecond, now if it is not given.
+	ToTime *int64
+}
+
+// ExportAuditLog returns all audit records between fromTime and toTime as an
+// attachment, with the result of verifying their hash chain.
+func (self *Client) ExportAuditLog(params ExportAuditLogParams) (*Response, error) {
+	query := url.Values{}
+	query.Set("fromTime", strconv.FormatInt(params.FromTime, 10))
+	setInt64(query, "toTime", params.ToTime)
+	return self.call(http.MethodGet, "/audit-log/export", query, nil, true)
+}
+
+// AuthDataParams are the params of AuthData.
+type AuthDataParams struct {
+	// Timepoint in millisecond, the latest if it is not given.
+	Timestamp *int64
+}
+
+// AuthData returns balances of exchanges and the reserve and pending
+// activities.
+func (self *Client) AuthData(params AuthDataParams) (*Response, error) {
+	query := url.Values{}
+	setInt64(query, "timestamp", params.Timestamp)
+	return self.call(http.MethodGet, "/authdata", query, nil, true)
+}
+
+// AuthDataVersionParams are the params of AuthDataVersion.
+type AuthDataVersionParams struct {
+	// Timepoint in millisecond, the latest if it is not given.
+	Timestamp *int64
+}
+
+// AuthDataVersion returns the version of the latest auth data at timestamp.
+func (self *Client) AuthDataVersion(params AuthDataVersionParams) (*Response, error) {
+	query := url.Values{}
+	setInt64(query, "timestamp", params.Timestamp)
+	return self.call(http.MethodGet, "/authdata-version", query, nil, true)
+}
+
+// GetBackup returns the status of the latest backup started with POST /backup.
+func (self *Client) GetBackup() (*Response, error) {
+	return self.call(http.MethodGet, "/backup", nil, nil, true)
+}
+
+// Backup starts writing a consistent bundle of all bolt databases of the
+// running process in background while they stay in use, and ships it to
+// archive if configured. Poll GET /backup for the result.
+func (self *Client) Backup() (*Response, error) {
+	return self.call(http.MethodPost, "/backup", nil, nil, true)
+}
+
+// GetBalanceStatus returns the latest check of operator ETH balances, reserve
+// token balances and token allowances against their thresholds, with days of
+// gas left of each operator.
+func (self *Client) GetBalanceStatus() (*Response, error) {
+	return self.call(http.MethodGet, "/balance-status", nil, nil, true)
+}
+
+// GetBalanceThresholds returns the current balance thresholds.
+func (self *Client) GetBalanceThresholds() (*Response, error) {
+	return self.call(http.MethodGet, "/balance-thresholds", nil, nil, true)
+}
+
+// CancelOrderParams are the params of CancelOrder.
+type CancelOrderParams struct {
+	// Exchange ID, e.g binance.
+	ExchangeID string
+	OrderID    string
+}
+
+// CancelOrder cancels an order on an exchange.
+func (self *Client) CancelOrder(params CancelOrderParams) (*Response, error) {
+	form := url.Values{}
+	form.Set("order_id", params.OrderID)
+	return self.call(http.MethodPost, "/cancelorder/"+url.PathEscape(params.ExchangeID), nil, form, true)
+}
+
+// CancelTargetQty cancels the pending token target quantities.
+func (self *Client) CancelTargetQty() (*Response, error) {
+	return self.call(http.MethodPost, "/canceltargetqty", nil, nil, true)
+}
+
+// GetCapByAddressParams are the params of GetCapByAddress.
+type GetCapByAddressParams struct {
+	Addr string
+}
+
+// GetCapByAddress returns the trade cap of an address.
+func (self *Client) GetCapByAddress(params GetCapByAddressParams) (*Response, error) {
+	return self.call(http.MethodGet, "/cap-by-address/"+url.PathEscape(params.Addr), nil, nil, false)
+}
+
+// GetCapByUserParams are the params of GetCapByUser.
+type GetCapByUserParams struct {
+	User string
+}
+
+// GetCapByUser returns the trade cap of a user.
+func (self *Client) GetCapByUser(params GetCapByUserParams) (*Response, error) {
+	return self.call(http.MethodGet, "/cap-by-user/"+url.PathEscape(params.User), nil, nil, false)
+}
+
+// CatLogsParams are the params of CatLogs.
+type CatLogsParams struct {
+	// Start of the time range in millisecond.
+	FromTime *int64
+	// End of the time range in millisecond, now if it is not given.
+	ToTime *int64
+}
+
+// CatLogs returns category logs between fromTime and toTime.
+func (self *Client) CatLogs(params CatLogsParams) (*Response, error) {
+	query := url.Values{}
+	setInt64(query, "fromTime", params.FromTime)
+	setInt64(query, "toTime", params.ToTime)
+	return self.call(http.MethodGet, "/catlogs", query, nil, false)
+}
+
+// GetConfigTypes returns the config types managed by the config APIs.
+func (self *Client) GetConfigTypes() (*Response, error) {
+	return self.call(http.MethodGet, "/configs", nil, nil, true)
+}
+
+// GetConfigParams are the params of GetConfig.
+type GetConfigParams struct {
+	// Config type, e.g target_qty_v2.
+	Type string
+}
+
+// GetConfig returns the latest version of a config type, which is the current
+// config.
+func (self *Client) GetConfig(params GetConfigParams) (*Response, error) {
+	return self.call(http.MethodGet, "/configs/"+url.PathEscape(params.Type), nil, nil, true)
+}
+
+// ConfirmConfigParams are the params of ConfirmConfig.
+type ConfirmConfigParams struct {
+	// Config type, e.g target_qty_v2.
+	Type string
+	// Hash of the pending proposal, so only the reviewed proposal is confirmed.
+	Hash string
+}
+
+// ConfirmConfig confirms the pending proposal of a config type, storing it as
+// the current config and as its next version in the same transaction.
+func (self *Client) ConfirmConfig(params ConfirmConfigParams) (*Response, error) {
+	form := url.Values{}
+	form.Set("hash", params.Hash)
+	return self.call(http.MethodPost, "/configs/"+url.PathEscape(params.Type)+"/confirm", nil, form, true)
+}
+
+// GetConfigDiffParams are the params of GetConfigDiff.
+type GetConfigDiffParams struct {
+	// Config type, e.g target_qty_v2.
+	Type string
+	From int64
+	// The latest version if it is not given.
+	To *int64
+}
+
+// GetConfigDiff returns the changes between two versions of a config type in
+// data, as JSON pointer paths with their old and new values.
+func (self *Client) GetConfigDiff(params GetConfigDiffParams) (*Response, error) {
+	query := url.Values{}
+	query.Set("from", strconv.FormatInt(params.From, 10))
+	setInt64(query, "to", params.To)
+	return self.call(http.MethodGet, "/configs/"+url.PathEscape(params.Type)+"/diff", query, nil, true)
+}
+
+// GetPendingConfigParams are the params of GetPendingConfig.
+type GetPendingConfigParams struct {
+	// Config type, e.g target_qty_v2.
+	Type string
+}
+
+// GetPendingConfig returns the pending proposal of a config type in data and
+// its changes from the latest version in changes.
+func (self *Client) GetPendingConfig(params GetPendingConfigParams) (*Response, error) {
+	return self.call(http.MethodGet, "/configs/"+url.PathEscape(params.Type)+"/pending", nil, nil, true)
+}
+
+// ProposeConfigParams are the params of ProposeConfig.
+type ProposeConfigParams struct {
+	// Config type, e.g target_qty_v2.
+	Type string
+	// JSON config, e.g {"KNC":{"a":0.7,"b":1.2,"c":1.3}} for rebalance_quadratic.
+	Value string
+}
+
+// ProposeConfig stores a JSON config as the pending proposal of a config type
+// once it is validated by the validator of the type. It fails if the type
+// already has a pending proposal. The response has the proposal in data, whose
+// hash is required to confirm it, and its changes from the latest version in
+// changes.
+func (self *Client) ProposeConfig(params ProposeConfigParams) (*Response, error) {
+	form := url.Values{}
+	form.Set("value", params.Value)
+	return self.call(http.MethodPost, "/configs/"+url.PathEscape(params.Type)+"/propose", nil, form, true)
+}
+
+// RejectConfigParams are the params of RejectConfig.
+type RejectConfigParams struct {
+	// Config type, e.g target_qty_v2.
+	Type string
+}
+
+// RejectConfig rejects the pending proposal of a config type.
+func (self *Client) RejectConfig(params RejectConfigParams) (*Response, error) {
+	return self.call(http.MethodPost, "/configs/"+url.PathEscape(params.Type)+"/reject", nil, nil, true)
+}
+
+// RollbackConfigParams are the params of RollbackConfig.
+type RollbackConfigParams struct {
+	// Config type, e.g target_qty_v2.
+	Type    string
+	Version int64
+}
+
+// RollbackConfig proposes to restore a version of a config type, it takes
+// effect once it is confirmed like other proposals.
+func (self *Client) RollbackConfig(params RollbackConfigParams) (*Response, error) {
+	form := url.Values{}
+	form.Set("version", strconv.FormatInt(params.Version, 10))
+	return self.call(http.MethodPost, "/configs/"+url.PathEscape(params.Type)+"/rollback", nil, form, true)
+}
+
+// GetConfigVersionsParams are the params of GetConfigVersions.
+type GetConfigVersionsParams struct {
+	// Config type, e.g target_qty_v2.
+	Type string
+}
+
+// GetConfigVersions returns all versions of a config type with who proposed
+// and confirmed them.
+func (self *Client) GetConfigVersions(params GetConfigVersionsParams) (*Response, error) {
+	return self.call(http.MethodGet, "/configs/"+url.PathEscape(params.Type)+"/versions", nil, nil, true)
+}
+
+// GetConfigVersionParams are the params of GetConfigVersion.
+type GetConfigVersionParams struct {
+	// Config type, e.g target_qty_v2.
+	Type string
+	// Version number, starting from 1.
+	Version string
+}
+
+// GetConfigVersion returns a version of a config type.
+func (self *Client) GetConfigVersion(params GetConfigVersionParams) (*Response, error) {
+	return self.call(http.MethodGet, "/configs/"+url.PathEscape(params.Type)+"/versions/"+url.PathEscape(params.Version), nil, nil, true)
+}
+
+// ConfirmPWIEquationParams are the params of ConfirmPWIEquation.
+type ConfirmPWIEquationParams struct {
+	Data *string
+}
+
+// ConfirmPWIEquation confirms the pending PWI equation.
+func (self *Client) ConfirmPWIEquation(params ConfirmPWIEquationParams) (*Response, error) {
+	form := url.Values{}
+	setString(form, "data", params.Data)
+	return self.call(http.MethodPost, "/confirm-pwis-equation", nil, form, true)
+}
+
+// ConfirmRebalanceQuadraticParams are the params of ConfirmRebalanceQuadratic.
+type ConfirmRebalanceQuadraticParams struct {
+	Value *string
+}
+
+// ConfirmRebalanceQuadratic confirm configuration for current pending config
+// for rebalance quadratic equation.
+func (self *Client) ConfirmRebalanceQuadratic(params ConfirmRebalanceQuadraticParams) (*Response, error) {
+	form := url.Values{}
+	setString(form, "value", params.Value)
+	return self.call(http.MethodPost, "/confirm-rebalance-quadratic", nil, form, true)
+}
+
+// ConfirmStableTokenParamsParams are the params of ConfirmStableTokenParams.
+type ConfirmStableTokenParamsParams struct {
+	Value *string
+}
+
+// ConfirmStableTokenParams confirms the pending stable token params.
+func (self *Client) ConfirmStableTokenParams(params ConfirmStableTokenParamsParams) (*Response, error) {
+	form := url.Values{}
+	setString(form, "value", params.Value)
+	return self.call(http.MethodPost, "/confirm-stable-token-params", nil, form, true)
+}
+
+// ConfirmStepFunctionsParams are the params of ConfirmStepFunctions.
+type ConfirmStepFunctionsParams struct {
+	Data string
+}
+
+// ConfirmStepFunctions sets the pending step functions to pricing contract,
+// each step function is recorded as an activity. Submitted step functions are
+// recorded with the pending step functions, if any transaction can not be
+// submitted they are kept pending and confirming again only submits the rest.
+func (self *Client) ConfirmStepFunctions(params ConfirmStepFunctionsParams) (*Response, error) {
+	form := url.Values{}
+	form.Set("data", params.Data)
+	return self.call(http.MethodPost, "/confirm-step-functions", nil, form, true)
+}
+
+// ConfirmTargetQtyParams are the params of ConfirmTargetQty.
+type ConfirmTargetQtyParams struct {
+	Data string
+	ID   *string
+	Type string
+}
+
+// ConfirmTargetQty confirms the pending token target quantities.
+func (self *Client) ConfirmTargetQty(params ConfirmTargetQtyParams) (*Response, error) {
+	form := url.Values{}
+	form.Set("data", params.Data)
+	setString(form, "id", params.ID)
+	form.Set("type", params.Type)
+	return self.call(http.MethodPost, "/confirmtargetqty", nil, form, true)
+}
+
+// GetAddress returns addresses of the reserve, its contracts, tokens and
+// exchanges.
+func (self *Client) GetAddress() (*Response, error) {
+	return self.call(http.MethodGet, "/core/addresses", nil, nil, false)
+}
+
+// CreateAPIKeyParams are the params of CreateAPIKey.
+type CreateAPIKeyParams struct {
+	Disabled  *bool
+	ExpiresAt *int64
+	Name      string
+	// Comma separated permissions.
+	Permissions string
+	// Comma separated scopes, e.g trade:binance.
+	Scopes *string
+}
+
+// CreateAPIKey creates a key with comma separated permissions, and optional
+// comma separated scopes and expires_at. The secret is only returned here.
+func (self *Client) CreateAPIKey(params CreateAPIKeyParams) (*Response, error) {
+	form := url.Values{}
+	setBool(form, "disabled", params.Disabled)
+	setInt64(form, "expires_at", params.ExpiresAt)
+	form.Set("name", params.Name)
+	form.Set("permissions", params.Permissions)
+	setString(form, "scopes", params.Scopes)
+	return self.call(http.MethodPost, "/create-api-key", nil, form, true)
+}
+
+// DataHealth returns the age of the latest prices, auth data, rates and gold
+// snapshots, and fetch duration, success rate and data age of every source.
+// Data older than its stale limit is flagged stale, bots should not act on it
+// if the top level stale is true.
+func (self *Client) DataHealth() (*Response, error) {
+	return self.call(http.MethodGet, "/data-health", nil, nil, false)
+}
+
+// DepositParams are the params of Deposit.
+type DepositParams struct {
+	// Exchange ID, e.g binance.
+	ExchangeID string
+	// Timepoint in millisecond, the latest if it is not given.
+	Timestamp *int64
+	Amount    string
+	Token     string
+}
+
+// Deposit deposits a token from the reserve to an exchange.
+func (self *Client) Deposit(params DepositParams) (*Response, error) {
+	query := url.Values{}
+	form := url.Values{}
+	setInt64(query, "timestamp", params.Timestamp)
+	form.Set("amount", params.Amount)
+	form.Set("token", params.Token)
+	return self.call(http.MethodPost, "/deposit/"+url.PathEscape(params.ExchangeID), query, form, true)
+}
+
+// EnableRebalance enables rebalance.
+func (self *Client) EnableRebalance() (*Response, error) {
+	return self.call(http.MethodPost, "/enablerebalance", nil, nil, true)
+}
+
+// EnableSetrate enables setting rates.
+func (self *Client) EnableSetrate() (*Response, error) {
+	return self.call(http.MethodPost, "/enablesetrate", nil, nil, true)
+}
+
+// EventsParams are the params of Events.
+type EventsParams struct {
+	// Comma separated topics, all topics if it is not given.
+	Topics *string
+}
+
+// Events streams events of topics, given as a comma separated list, or all
+// topics as server-sent events until the client disconnects.
+// The caller must close the body of the response.
+func (self *Client) Events(params EventsParams) (*http.Response, error) {
+	query := url.Values{}
+	setString(query, "topics", params.Topics)
+	return self.Do(http.MethodGet, "/events", query, nil, true)
+}
+
+// GetMinDeposit returns min deposit amounts of tokens on all exchanges.
+func (self *Client) GetMinDeposit() (*Response, error) {
+	return self.call(http.MethodGet, "/exchange-min-deposit", nil, nil, false)
+}
+
+// ExchangeNotificationParams are the params of ExchangeNotification.
+type ExchangeNotificationParams struct {
+	Action   string
+	Exchange string
+	// Start of the time range in millisecond.
+	FromTime  int64
+	IsWarning bool
+	Msg       *string
+	// End of the time range in millisecond, now if it is not given.
+	ToTime int64
+	Token  string
+}
+
+// ExchangeNotification records a notification of an exchange action.
+func (self *Client) ExchangeNotification(params ExchangeNotificationParams) (*Response, error) {
+	form := url.Values{}
+	form.Set("action", params.Action)
+	form.Set("exchange", params.Exchange)
+	form.Set("fromTime", strconv.FormatInt(params.FromTime, 10))
+	form.Set("isWarning", strconv.FormatBool(params.IsWarning))
+	setString(form, "msg", params.Msg)
+	form.Set("toTime", strconv.FormatInt(params.ToTime, 10))
+	form.Set("token", params.Token)
+	return self.call(http.MethodPost, "/exchange-notification", nil, form, true)
+}
+
+// GetNotifications returns notifications of exchange actions.
+func (self *Client) GetNotifications() (*Response, error) {
+	return self.call(http.MethodGet, "/exchange-notifications", nil, nil, true)
+}
+
+// GetFee returns trading and funding fees of all exchanges.
+func (self *Client) GetFee() (*Response, error) {
+	return self.call(http.MethodGet, "/exchangefees", nil, nil, false)
+}
+
+// GetExchangeFeeParams are the params of GetExchangeFee.
+type GetExchangeFeeParams struct {
+	// Exchange ID, e.g binance.
+	ExchangeID string
+}
+
+// GetExchangeFee returns trading and funding fees of an exchange.
+func (self *Client) GetExchangeFee(params GetExchangeFeeParams) (*Response, error) {
+	return self.call(http.MethodGet, "/exchangefees/"+url.PathEscape(params.ExchangeID), nil, nil, false)
+}
+
+// GetExchangeInfoParams are the params of GetExchangeInfo.
+type GetExchangeInfoParams struct {
+	// Exchange ID, e.g binance.
+	ExchangeID *string
+}
+
+// GetExchangeInfo return exchange info of one exchange if it is given
+// exchangeID otherwise return all exchanges info.
+func (self *Client) GetExchangeInfo(params GetExchangeInfoParams) (*Response, error) {
+	query := url.Values{}
+	setString(query, "exchangeid", params.ExchangeID)
+	return self.call(http.MethodGet, "/exchangeinfo", query, nil, false)
+}
+
+// GetPairInfoParams are the params of GetPairInfo.
+type GetPairInfoParams struct {
+	Base string
+	// Exchange ID, e.g binance.
+	ExchangeID string
+	Quote      string
+}
+
+// GetPairInfo returns the precision and limits of a token pair on an exchange.
+func (self *Client) GetPairInfo(params GetPairInfoParams) (*Response, error) {
+	return self.call(http.MethodGet, "/exchangeinfo/"+url.PathEscape(params.ExchangeID)+"/"+url.PathEscape(params.Base)+"/"+url.PathEscape(params.Quote), nil, nil, false)
+}
+
+// GetGasSpendParams are the params of GetGasSpend.
+type GetGasSpendParams struct {
+	// Start of the time range in millisecond.
+	FromTime int64
+	// End of the time range in millisecond, now if it is not given.
+	ToTime *int64
+}
+
+// GetGasSpend returns the gas spent by operators for our transactions between
+// fromTime and toTime (in millisecond), broken down by operator, action and
+// day. Fees are in ETH and USD.
+func (self *Client) GetGasSpend(params GetGasSpendParams) (*Response, error) {
+	query := url.Values{}
+	query.Set("fromTime", strconv.FormatInt(params.FromTime, 10))
+	setInt64(query, "toTime", params.ToTime)
+	return self.call(http.MethodGet, "/gas-spend", query, nil, true)
+}
+
+// GetRatesParams are the params of GetRates.
+type GetRatesParams struct {
+	// Start of the time range in millisecond.
+	FromTime *int64
+	// End of the time range in millisecond, now if it is not given.
+	ToTime *int64
+}
+
+// GetRates returns all rates between fromTime and toTime.
+func (self *Client) GetRates(params GetRatesParams) (*Response, error) {
+	query := url.Values{}
+	setInt64(query, "fromTime", params.FromTime)
+	setInt64(query, "toTime", params.ToTime)
+	return self.call(http.MethodGet, "/get-all-rates", query, nil, false)
+}
+
+// GetAssetVolumeParams are the params of GetAssetVolume.
+type GetAssetVolumeParams struct {
+	Asset *string
+	// Frequency of the series: m, h or d.
+	Freq *string
+	// Start of the time range in millisecond.
+	FromTime *int64
+	// End of the time range in millisecond, now if it is not given.
+	ToTime *int64
+}
+
+// GetAssetVolume returns the trade volume of an asset by freq.
+func (self *Client) GetAssetVolume(params GetAssetVolumeParams) (*Response, error) {
+	query := url.Values{}
+	setString(query, "asset", params.Asset)
+	setString(query, "freq", params.Freq)
+	setInt64(query, "fromTime", params.FromTime)
+	setInt64(query, "toTime", params.ToTime)
+	return self.call(http.MethodGet, "/get-asset-volume", query, nil, false)
+}
+
+// GetBurnFeeParams are the params of GetBurnFee.
+type GetBurnFeeParams struct {
+	// Frequency of the series: m, h or d.
+	Freq *string
+	// Start of the time range in millisecond.
+	FromTime    *int64
+	ReserveAddr *string
+	// End of the time range in millisecond, now if it is not given.
+	ToTime *int64
+}
+
+// GetBurnFee returns the burn fee of a reserve by freq.
+func (self *Client) GetBurnFee(params GetBurnFeeParams) (*Response, error) {
+	query := url.Values{}
+	setString(query, "freq", params.Freq)
+	setInt64(query, "fromTime", params.FromTime)
+	setString(query, "reserveAddr", params.ReserveAddr)
+	setInt64(query, "toTime", params.ToTime)
+	return self.call(http.MethodGet, "/get-burn-fee", query, nil, false)
+}
+
+// GetCountries returns all countries of trades.
+func (self *Client) GetCountries() (*Response, error) {
+	return self.call(http.MethodGet, "/get-countries", nil, nil, false)
+}
+
+// GetCountryStatsParams are the params of GetCountryStats.
+type GetCountryStatsParams struct {
+	Country *string
+	// Start of the time range in millisecond.
+	FromTime int64
+	// Time zone offset in hour, between -11 and 14.
+	TimeZone *int64
+	// End of the time range in millisecond, now if it is not given.
+	ToTime *int64
+}
+
+// GetCountryStats returns stats of a country by day in timeZone.
+func (self *Client) GetCountryStats(params GetCountryStatsParams) (*Response, error) {
+	query := url.Values{}
+	setString(query, "country", params.Country)
+	query.Set("fromTime", strconv.FormatInt(params.FromTime, 10))
+	setInt64(query, "timeZone", params.TimeZone)
+	setInt64(query, "toTime", params.ToTime)
+	return self.call(http.MethodGet, "/get-country-stats", query, nil, false)
+}
+
+// GetExchangesStatus returns statuses of all exchanges.
+func (self *Client) GetExchangesStatus() (*Response, error) {
+	return self.call(http.MethodGet, "/get-exchange-status", nil, nil, false)
+}
+
+// GetFeeSetRateByDayParams are the params of GetFeeSetRateByDay.
+type GetFeeSetRateByDayParams struct {
+	// Start of the time range in millisecond.
+	FromTime int64
+	// End of the time range in millisecond, now if it is not given.
+	ToTime *int64
+}
+
+// GetFeeSetRateByDay returns the gas fee of setting rates by day.
+func (self *Client) GetFeeSetRateByDay(params GetFeeSetRateByDayParams) (*Response, error) {
+	query := url.Values{}
+	query.Set("fromTime", strconv.FormatInt(params.FromTime, 10))
+	setInt64(query, "toTime", params.ToTime)
+	return self.call(http.MethodGet, "/get-fee-setrate", query, nil, false)
+}
+
+// GetHeatMapParams are the params of GetHeatMap.
+type GetHeatMapParams struct {
+	// Start of the time range in millisecond.
+	FromTime int64
+	// Time zone offset in hour, between -11 and 14.
+	TimeZone *int64
+	// End of the time range in millisecond, now if it is not given.
+	ToTime *int64
+}
+
+// GetHeatMap returns trade volumes by country.
+func (self *Client) GetHeatMap(params GetHeatMapParams) (*Response, error) {
+	query := url.Values{}
+	query.Set("fromTime", strconv.FormatInt(params.FromTime, 10))
+	setInt64(query, "timeZone", params.TimeZone)
+	setInt64(query, "toTime", params.ToTime)
+	return self.call(http.MethodGet, "/get-heat-map", query, nil, false)
+}
+
+// GetPendingAddresses returns the addresses pending for categorizing.
+func (self *Client) GetPendingAddresses() (*Response, error) {
+	return self.call(http.MethodGet, "/get-pending-addresses", nil, nil, false)
+}
+
+// GetPriceAnalyticDataParams are the params of GetPriceAnalyticData.
+type GetPriceAnalyticDataParams struct {
+	// Start of the time range in millisecond.
+	FromTime int64
+	// End of the time range in millisecond, now if it is not given.
+	ToTime *int64
+}
+
+// GetPriceAnalyticData returns price analytic data between fromTime and
+// toTime.
+func (self *Client) GetPriceAnalyticData(params GetPriceAnalyticDataParams) (*Response, error) {
+	query := url.Values{}
+	query.Set("fromTime", strconv.FormatInt(params.FromTime, 10))
+	setInt64(query, "toTime", params.ToTime)
+	return self.call(http.MethodGet, "/get-price-analytic-data", query, nil, true)
+}
+
+// GetReserveRateParams are the params of GetReserveRate.
+type GetReserveRateParams struct {
+	// Start of the time range in millisecond.
+	FromTime    *int64
+	ReserveAddr *string
+	// End of the time range in millisecond, now if it is not given.
+	ToTime *int64
+}
+
+// GetReserveRate returns rates of a reserve between fromTime and toTime.
+func (self *Client) GetReserveRate(params GetReserveRateParams) (*Response, error) {
+	query := url.Values{}
+	setInt64(query, "fromTime", params.FromTime)
+	setString(query, "reserveAddr", params.ReserveAddr)
+	setInt64(query, "toTime", params.ToTime)
+	return self.call(http.MethodGet, "/get-reserve-rate", query, nil, false)
+}
+
+// GetReserveVolumeParams are the params of GetReserveVolume.
+type GetReserveVolumeParams struct {
+	// Frequency of the series: m, h or d.
+	Freq *string
+	// Start of the time range in millisecond.
+	FromTime    *int64
+	ReserveAddr *string
+	// End of the time range in millisecond, now if it is not given.
+	ToTime *int64
+	Token  *string
+}
+
+// GetReserveVolume returns the trade volume of a token on a reserve by freq.
+func (self *Client) GetReserveVolume(params GetReserveVolumeParams) (*Response, error) {
+	query := url.Values{}
+	setString(query, "freq", params.Freq)
+	setInt64(query, "fromTime", params.FromTime)
+	setString(query, "reserveAddr", params.ReserveAddr)
+	setInt64(query, "toTime", params.ToTime)
+	setString(query, "token", params.Token)
+	return self.call(http.MethodGet, "/get-reserve-volume", query, nil, false)
+}
+
+// GetTokenHeatmapParams are the params of GetTokenHeatmap.
+type GetTokenHeatmapParams struct {
+	// Frequency of the series: m, h or d.
+	Freq *string
+	// Start of the time range in millisecond.
+	FromTime int64
+	// End of the time range in millisecond, now if it is not given.
+	ToTime *int64
+	Token  *string
+}
+
+// GetTokenHeatmap returns trade volumes of a token by country.
+func (self *Client) GetTokenHeatmap(params GetTokenHeatmapParams) (*Response, error) {
+	query := url.Values{}
+	setString(query, "freq", params.Freq)
+	query.Set("fromTime", strconv.FormatInt(params.FromTime, 10))
+	setInt64(query, "toTime", params.ToTime)
+	setString(query, "token", params.Token)
+	return self.call(http.MethodGet, "/get-token-heatmap", query, nil, false)
+}
+
+// GetTradeSummaryParams are the params of GetTradeSummary.
+type GetTradeSummaryParams struct {
+	// Start of the time range in millisecond.
+	FromTime int64
+	// Time zone offset in hour, between -11 and 14.
+	TimeZone *int64
+	// End of the time range in millisecond, now if it is not given.
+	ToTime *int64
+}
+
+// GetTradeSummary returns the trade summary by day in timeZone.
+func (self *Client) GetTradeSummary(params GetTradeSummaryParams) (*Response, error) {
+	query := url.Values{}
+	query.Set("fromTime", strconv.FormatInt(params.FromTime, 10))
+	setInt64(query, "timeZone", params.TimeZone)
+	setInt64(query, "toTime", params.ToTime)
+	return self.call(http.MethodGet, "/get-trade-summary", query, nil, false)
+}
+
+// GetUserListParams are the params of GetUserList.
+type GetUserListParams struct {
+	// Start of the time range in millisecond.
+	FromTime int64
+	// Time zone offset in hour, between -11 and 14.
+	TimeZone int64
+	// End of the time range in millisecond, now if it is not given.
+	ToTime int64
+}
+
+// GetUserList returns users and their volumes between fromTime and toTime.
+func (self *Client) GetUserList(params GetUserListParams) (*Response, error) {
+	query := url.Values{}
+	query.Set("fromTime", strconv.FormatInt(params.FromTime, 10))
+	query.Set("timeZone", strconv.FormatInt(params.TimeZone, 10))
+	query.Set("toTime", strconv.FormatInt(params.ToTime, 10))
+	return self.call(http.MethodGet, "/get-user-list", query, nil, true)
+}
+
+// GetUserVolumeParams are the params of GetUserVolume.
+type GetUserVolumeParams struct {
+	// Frequency of the series: m, h or d.
+	Freq *string
+	// Start of the time range in millisecond.
+	FromTime *int64
+	// End of the time range in millisecond, now if it is not given.
+	ToTime   *int64
+	UserAddr *string
+}
+
+// GetUserVolume returns the trade volume of a user by freq.
+func (self *Client) GetUserVolume(params GetUserVolumeParams) (*Response, error) {
+	query := url.Values{}
+	setString(query, "freq", params.Freq)
+	setInt64(query, "fromTime", params.FromTime)
+	setInt64(query, "toTime", params.ToTime)
+	setString(query, "userAddr", params.UserAddr)
+	return self.call(http.MethodGet, "/get-user-volume", query, nil, false)
+}
+
+// GetUsersVolumeParams are the params of GetUsersVolume.
+type GetUsersVolumeParams struct {
+	// Frequency of the series: m, h or d.
+	Freq *string
+	// Start of the time range in millisecond.
+	FromTime *int64
+	// End of the time range in millisecond, now if it is not given.
+	ToTime   *int64
+	UserAddr *string
+}
+
+// GetUsersVolume returns trade volumes of users by freq.
+func (self *Client) GetUsersVolume(params GetUsersVolumeParams) (*Response, error) {
+	query := url.Values{}
+	setString(query, "freq", params.Freq)
+	setInt64(query, "fromTime", params.FromTime)
+	setInt64(query, "toTime", params.ToTime)
+	setString(query, "userAddr", params.UserAddr)
+	return self.call(http.MethodGet, "/get-users-volume", query, nil, false)
+}
+
+// GetWalletAddress returns addresses of all wallets.
+func (self *Client) GetWalletAddress() (*Response, error) {
+	return self.call(http.MethodGet, "/get-wallet-address", nil, nil, false)
+}
+
+// GetWalletFeeParams are the params of GetWalletFee.
+type GetWalletFeeParams struct {
+	// Frequency of the series: m, h or d.
+	Freq *string
+	// Start of the time range in millisecond.
+	FromTime    *int64
+	ReserveAddr *string
+	// End of the time range in millisecond, now if it is not given.
+	ToTime     *int64
+	WalletAddr *string
+}
+
+// GetWalletFee returns the fee of a wallet by freq.
+func (self *Client) GetWalletFee(params GetWalletFeeParams) (*Response, error) {
+	query := url.Values{}
+	setString(query, "freq", params.Freq)
+	setInt64(query, "fromTime", params.FromTime)
+	setString(query, "reserveAddr", params.ReserveAddr)
+	setInt64(query, "toTime", params.ToTime)
+	setString(query, "walletAddr", params.WalletAddr)
+	return self.call(http.MethodGet, "/get-wallet-fee", query, nil, false)
+}
+
+// GetWalletStatsParams are the params of GetWalletStats.
+type GetWalletStatsParams struct {
+	// Start of the time range in millisecond.
+	FromTime int64
+	// Time zone offset in hour, between -11 and 14.
+	TimeZone *int64
+	// End of the time range in millisecond, now if it is not given.
+	ToTime     *int64
+	WalletAddr *string
+}
+
+// GetWalletStats returns stats of a wallet by day in timeZone.
+func (self *Client) GetWalletStats(params GetWalletStatsParams) (*Response, error) {
+	query := url.Values{}
+	query.Set("fromTime", strconv.FormatInt(params.FromTime, 10))
+	setInt64(query, "timeZone", params.TimeZone)
+	setInt64(query, "toTime", params.ToTime)
+	setString(query, "walletAddr", params.WalletAddr)
+	return self.call(http.MethodGet, "/get-wallet-stats", query, nil, false)
+}
+
+// GetRateParams are the params of GetRate.
+type GetRateParams struct {
+	// Timepoint in millisecond, the latest if it is not given.
+	Timestamp *int64
+}
+
+// GetRate returns the rates of all tokens set to the pricing contract.
+func (self *Client) GetRate(params GetRateParams) (*Response, error) {
+	query := url.Values{}
+	setInt64(query, "timestamp", params.Timestamp)
+	return self.call(http.MethodGet, "/getrates", query, nil, false)
+}
+
+// GetGoldDataParams are the params of GetGoldData.
+type GetGoldDataParams struct {
+	// Timepoint in millisecond, the latest if it is not given.
+	Timestamp *int64
+}
+
+// GetGoldData returns gold prices of all gold feeds.
+func (self *Client) GetGoldData(params GetGoldDataParams) (*Response, error) {
+	query := url.Values{}
+	setInt64(query, "timestamp", params.Timestamp)
+	return self.call(http.MethodGet, "/gold-feed", query, nil, false)
+}
+
+// Healthz returns success as long as the process serves requests, it doesn't
+// check any subsystem.
+func (self *Client) Healthz() (*Response, error) {
+	return self.call(http.MethodGet, "/healthz", nil, nil, false)
+}
+
+// HoldRebalance disables rebalance.
+func (self *Client) HoldRebalance() (*Response, error) {
+	return self.call(http.MethodPost, "/holdrebalance", nil, nil, true)
+}
+
+// HoldSetrate disables setting rates.
+func (self *Client) HoldSetrate() (*Response, error) {
+	return self.call(http.MethodPost, "/holdsetrate", nil, nil, true)
+}
+
+// ImmediatePendingActivities returns the pending activities.
+func (self *Client) ImmediatePendingActivities() (*Response, error) {
+	return self.call(http.MethodGet, "/immediate-pending-activities", nil, nil, true)
+}
+
+// MetricsParams are the params of Metrics.
+type MetricsParams struct {
+	From   int64
+	To     int64
+	Tokens string
+}
+
+// Metrics returns metrics of tokens between from and to.
+func (self *Client) Metrics(params MetricsParams) (*Response, error) {
+	query := url.Values{}
+	query.Set("from", strconv.FormatInt(params.From, 10))
+	query.Set("to", strconv.FormatInt(params.To, 10))
+	query.Set("tokens", params.Tokens)
+	return self.call(http.MethodGet, "/metrics", query, nil, true)
+}
+
+// StoreMetricsParams are the params of StoreMetrics.
+type StoreMetricsParams struct {
+	Data string
+	// Timepoint in millisecond, the latest if it is not given.
+	Timestamp int64
+}
+
+// StoreMetrics stores token metrics of the analytic engine.
+func (self *Client) StoreMetrics(params StoreMetricsParams) (*Response, error) {
+	form := url.Values{}
+	form.Set("data", params.Data)
+	form.Set("timestamp", strconv.FormatInt(params.Timestamp, 10))
+	return self.call(http.MethodPost, "/metrics", nil, form, true)
+}
+
+// PrometheusMetrics returns metrics of the service in the Prometheus text
+// format. Scrapers can't sign requests, so it requires the metrics token as a
+// bearer token instead if authentication is enabled. It responds 401 if the
+// token is missing or invalid and 503 if no token is configured.
+func (self *Client) PrometheusMetrics() (*Response, error) {
+	return self.call(http.MethodGet, "/metrics/prometheus", nil, nil, false)
+}
+
+// GetPendingPWIEquation returns the pending PWI equation.
+func (self *Client) GetPendingPWIEquation() (*Response, error) {
+	return self.call(http.MethodGet, "/pending-pwis-equation", nil, nil, true)
+}
+
+// GetPendingRebalanceQuadratic return currently pending config for rebalance
+// quadratic equation if there is no pending equation return success false.
+func (self *Client) GetPendingRebalanceQuadratic() (*Response, error) {
+	return self.call(http.MethodGet, "/pending-rebalance-quadratic", nil, nil, true)
+}
+
+// GetPendingStableTokenParams returns the pending stable token params.
+func (self *Client) GetPendingStableTokenParams() (*Response, error) {
+	return self.call(http.MethodGet, "/pending-stable-token-params", nil, nil, true)
+}
+
+// GetPendingStepFunctions returns the pending step functions, and activities
+// of the ones already submitted by a failed confirmation in submitted.
+func (self *Client) GetPendingStepFunctions() (*Response, error) {
+	return self.call(http.MethodGet, "/pending-step-functions", nil, nil, true)
+}
+
+// GetPendingTargetQty returns the pending token target quantities.
+func (self *Client) GetPendingTargetQty() (*Response, error) {
+	return self.call(http.MethodGet, "/pendingtargetqty", nil, nil, true)
+}
+
+// PriceHistoryParams are the params of PriceHistory.
+type PriceHistoryParams struct {
+	Base     string
+	Exchange *string
+	// Start of the time range in millisecond.
+	FromTime   int64
+	Quote      string
+	Resolution *string
+	// End of the time range in millisecond, now if it is not given.
+	ToTime *int64
+}
+
+// PriceHistory returns the price time series of a pair between fromTime and
+// toTime, in millisecond. The resolution is raw, 1m, 5m or 1h, candles of all
+// exchanges are returned unless exchange is given.
+func (self *Client) PriceHistory(params PriceHistoryParams) (*Response, error) {
+	query := url.Values{}
+	query.Set("base", params.Base)
+	setString(query, "exchange", params.Exchange)
+	query.Set("fromTime", strconv.FormatInt(params.FromTime, 10))
+	query.Set("quote", params.Quote)
+	setString(query, "resolution", params.Resolution)
+	setInt64(query, "toTime", params.ToTime)
+	return self.call(http.MethodGet, "/price-history", query, nil, false)
+}
+
+// AllPricesParams are the params of AllPrices.
+type AllPricesParams struct {
+	// Timepoint in millisecond, the latest if it is not given.
+	Timestamp *int64
+}
+
+// AllPrices returns order books of all token pairs on all exchanges.
+func (self *Client) AllPrices(params AllPricesParams) (*Response, error) {
+	query := url.Values{}
+	setInt64(query, "timestamp", params.Timestamp)
+	return self.call(http.MethodGet, "/prices", query, nil, false)
+}
+
+// AllPricesVersionParams are the params of AllPricesVersion.
+type AllPricesVersionParams struct {
+	// Timepoint in millisecond, the latest if it is not given.
+	Timestamp *int64
+}
+
+// AllPricesVersion returns the version of the latest prices at timestamp.
+func (self *Client) AllPricesVersion(params AllPricesVersionParams) (*Response, error) {
+	query := url.Values{}
+	setInt64(query, "timestamp", params.Timestamp)
+	return self.call(http.MethodGet, "/prices-version", query, nil, false)
+}
+
+// PriceParams are the params of Price.
+type PriceParams struct {
+	Base  string
+	Quote string
+	// Timepoint in millisecond, the latest if it is not given.
+	Timestamp *int64
+}
+
+// Price returns order books of a token pair on all exchanges.
+func (self *Client) Price(params PriceParams) (*Response, error) {
+	query := url.Values{}
+	setInt64(query, "timestamp", params.Timestamp)
+	return self.call(http.MethodGet, "/prices/"+url.PathEscape(params.Base)+"/"+url.PathEscape(params.Quote), query, nil, false)
+}
+
+// GetPricingStateParams are the params of GetPricingState.
+type GetPricingStateParams struct {
+	Token *string
+}
+
+// GetPricingState returns what pricing contract currently holds for each
+// token: base rates, compact data and its position, validity block, step
+// functions, imbalance limits and listing flags, in both raw and human
+// friendly format. An optional token param limits the result to one token.
+func (self *Client) GetPricingState(params GetPricingStateParams) (*Response, error) {
+	query := url.Values{}
+	setString(query, "token", params.Token)
+	return self.call(http.MethodGet, "/pricing-state", query, nil, true)
+}
+
+// GetPWIEquation returns the current PWI equation.
+func (self *Client) GetPWIEquation() (*Response, error) {
+	return self.call(http.MethodGet, "/pwis-equation", nil, nil, true)
+}
+
+// Readyz checks the databases, the node, the freshness of fetched data, the
+// exchanges and the stat log fetcher. It responds 503 with names of failed
+// checks if any of them fails, so orchestrators can stop routing requests to
+// the instance. Details of checks are served by /readyz/details.
+func (self *Client) Readyz() (*Response, error) {
+	return self.call(http.MethodGet, "/readyz", nil, nil, false)
+}
+
+// ReadyzDetails returns results of all readiness checks with their details.
+func (self *Client) ReadyzDetails() (*Response, error) {
+	return self.call(http.MethodGet, "/readyz/details", nil, nil, true)
+}
+
+// GetRebalanceQuadratic return current confirmed rebalance quadratic equation.
+func (self *Client) GetRebalanceQuadratic() (*Response, error) {
+	return self.call(http.MethodGet, "/rebalance-quadratic", nil, nil, true)
+}
+
+// GetRebalanceStatus returns true if rebalance is enabled.
+func (self *Client) GetRebalanceStatus() (*Response, error) {
+	return self.call(http.MethodGet, "/rebalancestatus", nil, nil, true)
+}
+
+// RejectPWIEquationParams are the params of RejectPWIEquation.
+type RejectPWIEquationParams struct {
+	Data *string
+}
+
+// RejectPWIEquation rejects the pending PWI equation.
+func (self *Client) RejectPWIEquation(params RejectPWIEquationParams) (*Response, error) {
+	form := url.Values{}
+	setString(form, "data", params.Data)
+	return self.call(http.MethodPost, "/reject-pwis-equation", nil, form, true)
+}
+
+// RejectRebalanceQuadratic reject pending configuration for rebalance
+// quadratic function.
+func (self *Client) RejectRebalanceQuadratic() (*Response, error) {
+	return self.call(http.MethodPost, "/reject-rebalance-quadratic", nil, nil, true)
+}
+
+// RejectStableTokenParams rejects the pending stable token params.
+func (self *Client) RejectStableTokenParams() (*Response, error) {
+	return self.call(http.MethodPost, "/reject-stable-token-params", nil, nil, true)
+}
+
+// RejectStepFunctions rejects the pending step functions and removes them from
+// pending storage.
+func (self *Client) RejectStepFunctions() (*Response, error) {
+	return self.call(http.MethodPost, "/reject-step-functions", nil, nil, true)
+}
+
+// RemoveAPIKeyParams are the params of RemoveAPIKey.
+type RemoveAPIKeyParams struct {
+	Name string
+}
+
+// RemoveAPIKey removes a key, requests signed by it are rejected immediately.
+func (self *Client) RemoveAPIKey(params RemoveAPIKeyParams) (*Response, error) {
+	form := url.Values{}
+	form.Set("name", params.Name)
+	return self.call(http.MethodPost, "/remove-api-key", nil, form, true)
+}
+
+// ExceedDailyLimitParams are the params of ExceedDailyLimit.
+type ExceedDailyLimitParams struct {
+	Addr string
+}
+
+// ExceedDailyLimit returns true if an address exceeds its daily trade limit.
+func (self *Client) ExceedDailyLimit(params ExceedDailyLimitParams) (*Response, error) {
+	return self.call(http.MethodGet, "/richguy/"+url.PathEscape(params.Addr), nil, nil, false)
+}
+
+// RotateAPIKeyParams are the params of RotateAPIKey.
+type RotateAPIKeyParams struct {
+	GracePeriod *int64
+	Name        string
+}
+
+// RotateAPIKey adds a new secret to a key and returns it. Current secrets stay
+// valid for grace_period millisecond, default to a day, so clients can switch
+// to the new secret without downtime. Expired secrets are removed.
+func (self *Client) RotateAPIKey(params RotateAPIKeyParams) (*Response, error) {
+	form := url.Values{}
+	setInt64(form, "grace_period", params.GracePeriod)
+	form.Set("name", params.Name)
+	return self.call(http.MethodPost, "/rotate-api-key", nil, form, true)
+}
+
+// SetBalanceThresholdsParams are the params of SetBalanceThresholds.
+type SetBalanceThresholdsParams struct {
+	Data string
+}
+
+// SetBalanceThresholds replaces the balance thresholds, they take effect at
+// the next check.
+func (self *Client) SetBalanceThresholds(params SetBalanceThresholdsParams) (*Response, error) {
+	form := url.Values{}
+	form.Set("data", params.Data)
+	return self.call(http.MethodPost, "/set-balance-thresholds", nil, form, true)
+}
+
+// SetPWIEquationParams are the params of SetPWIEquation.
+type SetPWIEquationParams struct {
+	Data *string
+}
+
+// SetPWIEquation sets the pending PWI equation.
+func (self *Client) SetPWIEquation(params SetPWIEquationParams) (*Response, error) {
+	form := url.Values{}
+	setString(form, "data", params.Data)
+	return self.call(http.MethodPost, "/set-pwis-equation", nil, form, true)
+}
+
+// SetRebalanceQuadraticParams are the params of SetRebalanceQuadratic.
+type SetRebalanceQuadraticParams struct {
+	Value string
+}
+
+// SetRebalanceQuadratic set pending rebalance quadratic equation input data
+// follow json: {"data":{"KNC": {"a": 0.7, "b": 1.2, "c": 1.3}}}.
+func (self *Client) SetRebalanceQuadratic(params SetRebalanceQuadraticParams) (*Response, error) {
+	form := url.Values{}
+	form.Set("value", params.Value)
+	return self.call(http.MethodPost, "/set-rebalance-quadratic", nil, form, true)
+}
+
+// SetStableTokenParamsParams are the params of SetStableTokenParams.
+type SetStableTokenParamsParams struct {
+	Value *string
+}
+
+// SetStableTokenParams sets pending stable token params.
+func (self *Client) SetStableTokenParams(params SetStableTokenParamsParams) (*Response, error) {
+	form := url.Values{}
+	setString(form, "value", params.Value)
+	return self.call(http.MethodPost, "/set-stable-token-params", nil, form, true)
+}
+
+// SetStepFunctionsParams are the params of SetStepFunctions.
+type SetStepFunctionsParams struct {
+	Data string
+}
+
+// SetStepFunctions stores the given step functions to pending for later
+// confirmation.
+func (self *Client) SetStepFunctions(params SetStepFunctionsParams) (*Response, error) {
+	form := url.Values{}
+	form.Set("data", params.Data)
+	return self.call(http.MethodPost, "/set-step-functions", nil, form, true)
+}
+
+// SetRateParams are the params of SetRate.
+type SetRateParams struct {
+	AfpMid string
+	Block  int64
+	Buys   string
+	Msgs   string
+	Sells  string
+	Tokens string
+}
+
+// SetRate sets rates of tokens to the pricing contract.
+func (self *Client) SetRate(params SetRateParams) (*Response, error) {
+	form := url.Values{}
+	form.Set("afp_mid", params.AfpMid)
+	form.Set("block", strconv.FormatInt(params.Block, 10))
+	form.Set("buys", params.Buys)
+	form.Set("msgs", params.Msgs)
+	form.Set("sells", params.Sells)
+	form.Set("tokens", params.Tokens)
+	return self.call(http.MethodPost, "/setrates", nil, form, true)
+}
+
+// GetSetrateStatus returns true if setting rates is enabled.
+func (self *Client) GetSetrateStatus() (*Response, error) {
+	return self.call(http.MethodGet, "/setratestatus", nil, nil, true)
+}
+
+// SetTargetQtyParams are the params of SetTargetQty.
+type SetTargetQtyParams struct {
+	Data string
+	Type string
+}
+
+// SetTargetQty sets pending token target quantities.
+func (self *Client) SetTargetQty(params SetTargetQtyParams) (*Response, error) {
+	form := url.Values{}
+	form.Set("data", params.Data)
+	form.Set("type", params.Type)
+	return self.call(http.MethodPost, "/settargetqty", nil, form, true)
+}
+
+// SimulateRateParams are the params of SimulateRate.
+type SimulateRateParams struct {
+	Block *int64
+	Qty   string
+	Rate  *string
+	Token string
+	Type  string
+}
+
+// SimulateRate preview the rate pricing contract returns for a trade, without
+// calling getRate on chain. params: token, type (buy or sell), qty (hex
+// encoded, ETH wei for buy, token wei for sell), optional block (default
+// current block) and optional rate (hex encoded) to preview a new rate before
+// setting it.
+func (self *Client) SimulateRate(params SimulateRateParams) (*Response, error) {
+	query := url.Values{}
+	setInt64(query, "block", params.Block)
+	query.Set("qty", params.Qty)
+	setString(query, "rate", params.Rate)
+	query.Set("token", params.Token)
+	query.Set("type", params.Type)
+	return self.call(http.MethodGet, "/simulate-rate", query, nil, true)
+}
+
+// GetStableTokenParams returns the current stable token params.
+func (self *Client) GetStableTokenParams() (*Response, error) {
+	return self.call(http.MethodGet, "/stable-token-params", nil, nil, true)
+}
+
+// GetStepFunctionsParams are the params of GetStepFunctions.
+type GetStepFunctionsParams struct {
+	Token *string
+}
+
+// GetStepFunctions returns the step functions currently stored in pricing
+// contract. An optional token param limits the result to one token.
+func (self *Client) GetStepFunctions(params GetStepFunctionsParams) (*Response, error) {
+	query := url.Values{}
+	setString(query, "token", params.Token)
+	return self.call(http.MethodGet, "/step-functions", query, nil, true)
+}
+
+// GetTargetQty returns the current token target quantities.
+func (self *Client) GetTargetQty() (*Response, error) {
+	return self.call(http.MethodGet, "/targetqty", nil, nil, true)
+}
+
+// GetTimeServer returns the server time in millisecond.
+func (self *Client) GetTimeServer() (*Response, error) {
+	return self.call(http.MethodGet, "/timeserver", nil, nil, false)
+}
+
+// TradeParams are the params of Trade.
+type TradeParams struct {
+	// Exchange ID, e.g binance.
+	ExchangeID string
+	// Timepoint in millisecond, the latest if it is not given.
+	Timestamp *int64
+	Amount    string
+	Base      string
+	Quote     string
+	Rate      string
+	Type      string
+}
+
+// Trade places an order on an exchange.
+func (self *Client) Trade(params TradeParams) (*Response, error) {
+	query := url.Values{}
+	form := url.Values{}
+	setInt64(query, "timestamp", params.Timestamp)
+	form.Set("amount", params.Amount)
+	form.Set("base", params.Base)
+	form.Set("quote", params.Quote)
+	form.Set("rate", params.Rate)
+	form.Set("type", params.Type)
+	return self.call(http.MethodPost, "/trade/"+url.PathEscape(params.ExchangeID), query, form, true)
+}
+
+// GetTradeHistoryParams are the params of GetTradeHistory.
+type GetTradeHistoryParams struct {
+	// Start of the time range in millisecond.
+	FromTime int64
+	// End of the time range in millisecond, now if it is not given.
+	ToTime *int64
+}
+
+// GetTradeHistory returns trade histories of exchanges between fromTime and
+// toTime.
+func (self *Client) GetTradeHistory(params GetTradeHistoryParams) (*Response, error) {
+	query := url.Values{}
+	query.Set("fromTime", strconv.FormatInt(params.FromTime, 10))
+	setInt64(query, "toTime", params.ToTime)
+	return self.call(http.MethodGet, "/tradehistory", query, nil, true)
+}
+
+// TradeLogsParams are the params of TradeLogs.
+type TradeLogsParams struct {
+	// The next_cursor of the previous page.
+	Cursor *string
+	// Start of the time range in millisecond.
+	FromTime *int64
+	// Max number of records of a page, default to 100, at most 1000.
+	Limit *int64
+	// End of the time range in millisecond, now if it is not given.
+	ToTime *int64
+}
+
+// TradeLogs returns trade logs between fromTime and toTime, a page of trade
+// logs if any page param is given.
+func (self *Client) TradeLogs(params TradeLogsParams) (*Response, error) {
+	query := url.Values{}
+	setString(query, "cursor", params.Cursor)
+	setInt64(query, "fromTime", params.FromTime)
+	setInt64(query, "limit", params.Limit)
+	setInt64(query, "toTime", params.ToTime)
+	return self.call(http.MethodGet, "/tradelogs", query, nil, false)
+}
+
+// UpdateAPIKeyParams are the params of UpdateAPIKey.
+type UpdateAPIKeyParams struct {
+	Disabled  *bool
+	ExpiresAt *int64
+	Name      string
+	// Comma separated permissions.
+	Permissions *string
+	// Comma separated scopes, e.g trade:binance.
+	Scopes *string
+}
+
+// UpdateAPIKey updates permissions, scopes, expires_at or disabled of a key,
+// only the given ones are changed.
+func (self *Client) UpdateAPIKey(params UpdateAPIKeyParams) (*Response, error) {
+	form := url.Values{}
+	setBool(form, "disabled", params.Disabled)
+	setInt64(form, "expires_at", params.ExpiresAt)
+	form.Set("name", params.Name)
+	setString(form, "permissions", params.Permissions)
+	setString(form, "scopes", params.Scopes)
+	return self.call(http.MethodPost, "/update-api-key", nil, form, true)
+}
+
+// UpdateExchangeStatusParams are the params of UpdateExchangeStatus.
+type UpdateExchangeStatusParams struct {
+	Exchange string
+	Status   string
+	// Timepoint in millisecond, the latest if it is not given.
+	Timestamp int64
+}
+
+// UpdateExchangeStatus enables or disables an exchange.
+func (self *Client) UpdateExchangeStatus(params UpdateExchangeStatusParams) (*Response, error) {
+	form := url.Values{}
+	form.Set("exchange", params.Exchange)
+	form.Set("status", params.Status)
+	form.Set("timestamp", strconv.FormatInt(params.Timestamp, 10))
+	return self.call(http.MethodPost, "/update-exchange-status", nil, form, true)
+}
+
+// UpdatePriceAnalyticDataParams are the params of UpdatePriceAnalyticData.
+type UpdatePriceAnalyticDataParams struct {
+	// Timepoint in millisecond, the latest if it is not given.
+	Timestamp *int64
+	Value     *string
+}
+
+// UpdatePriceAnalyticData stores price analytic data.
+func (self *Client) UpdatePriceAnalyticData(params UpdatePriceAnalyticDataParams) (*Response, error) {
+	form := url.Values{}
+	setInt64(form, "timestamp", params.Timestamp)
+	setString(form, "value", params.Value)
+	return self.call(http.MethodPost, "/update-price-analytic-data", nil, form, true)
+}
+
+// UpdateUserAddressesParams are the params of UpdateUserAddresses.
+type UpdateUserAddressesParams struct {
+	Addresses  string
+	Timestamps string
+	User       string
+}
+
+// UpdateUserAddresses updates the addresses of a user.
+func (self *Client) UpdateUserAddresses(params UpdateUserAddressesParams) (*Response, error) {
+	form := url.Values{}
+	form.Set("addresses", params.Addresses)
+	form.Set("timestamps", params.Timestamps)
+	form.Set("user", params.User)
+	return self.call(http.MethodPost, "/update-user-addresses", nil, form, true)
+}
+
+// CancelTargetQtyV2 cancels the pending token target quantities version 2.
+func (self *Client) CancelTargetQtyV2() (*Response, error) {
+	return self.call(http.MethodPost, "/v2/canceltargetqty", nil, nil, true)
+}
+
+// ConfirmPWIEquationV2Params are the params of ConfirmPWIEquationV2.
+type ConfirmPWIEquationV2Params struct {
+	Data string
+}
+
+// ConfirmPWIEquationV2 accepts the pending PWI equations and remove it from
+// pending bucket.
+func (self *Client) ConfirmPWIEquationV2(params ConfirmPWIEquationV2Params) (*Response, error) {
+	form := url.Values{}
+	form.Set("data", params.Data)
+	return self.call(http.MethodPost, "/v2/confirm-pwis-equation", nil, form, true)
+}
+
+// ConfirmTargetQtyV2Params are the params of ConfirmTargetQtyV2.
+type ConfirmTargetQtyV2Params struct {
+	Value *string
+}
+
+// ConfirmTargetQtyV2 confirms the pending token target quantities version 2.
+func (self *Client) ConfirmTargetQtyV2(params ConfirmTargetQtyV2Params) (*Response, error) {
+	form := url.Values{}
+	setString(form, "value", params.Value)
+	return self.call(http.MethodPost, "/v2/confirmtargetqty", nil, form, true)
+}
+
+// GetPendingPWIEquationV2 returns the pending PWI equations.
+func (self *Client) GetPendingPWIEquationV2() (*Response, error) {
+	return self.call(http.MethodGet, "/v2/pending-pwis-equation", nil, nil, true)
+}
+
+// GetPendingTargetQtyV2 returns the pending token target quantities version 2.
+func (self *Client) GetPendingTargetQtyV2() (*Response, error) {
+	return self.call(http.MethodGet, "/v2/pendingtargetqty", nil, nil, true)
+}
+
+// GetPWIEquationV2 returns the current PWI equations.
+func (self *Client) GetPWIEquationV2() (*Response, error) {
+	return self.call(http.MethodGet, "/v2/pwis-equation", nil, nil, true)
+}
+
+// RejectPWIEquationV2 rejects the PWI equations request and removes it from
+// pending storage.
+func (self *Client) RejectPWIEquationV2() (*Response, error) {
+	return self.call(http.MethodPost, "/v2/reject-pwis-equation", nil, nil, true)
+}
+
+// SetPWIEquationV2Params are the params of SetPWIEquationV2.
+type SetPWIEquationV2Params struct {
+	Data string
+}
+
+// SetPWIEquationV2 stores the given PWI equations to pending for later
+// evaluation.
+func (self *Client) SetPWIEquationV2(params SetPWIEquationV2Params) (*Response, error) {
+	form := url.Values{}
+	form.Set("data", params.Data)
+	return self.call(http.MethodPost, "/v2/set-pwis-equation", nil, form, true)
+}
+
+// SetTargetQtyV2Params are the params of SetTargetQtyV2.
+type SetTargetQtyV2Params struct {
+	Value *string
+}
+
+// SetTargetQtyV2 sets pending token target quantities version 2.
+func (self *Client) SetTargetQtyV2(params SetTargetQtyV2Params) (*Response, error) {
+	form := url.Values{}
+	setString(form, "value", params.Value)
+	return self.call(http.MethodPost, "/v2/settargetqty", nil, form, true)
+}
+
+// GetTargetQtyV2 returns the current token target quantities version 2.
+func (self *Client) GetTargetQtyV2() (*Response, error) {
+	return self.call(http.MethodGet, "/v2/targetqty", nil, nil, true)
+}
+
+// WithdrawParams are the params of Withdraw.
+type WithdrawParams struct {
+	// Exchange ID, e.g binance.
+	ExchangeID string
+	// Timepoint in millisecond, the latest if it is not given.
+	Timestamp *int64
+	Amount    string
+	Token     string
+}
+
+// Withdraw withdraws a token from an exchange to the reserve.
+func (self *Client) Withdraw(params WithdrawParams) (*Response, error) {
+	query := url.Values{}
+	form := url.Values{}
+	setInt64(query, "timestamp", params.Timestamp)
+	form.Set("amount", params.Amount)
+	form.Set("token", params.Token)
+	return self.call(http.MethodPost, "/withdraw/"+url.PathEscape(params.ExchangeID), query, form, true)
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/data"
+	"github.com/KyberNetwork/reserve-data/data/storage"
+	"github.com/KyberNetwork/reserve-data/http/client"
+	"github.com/gin-gonic/gin"
+)
+
+func TestClientSignsRequests(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test_client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}()
+	st, err := storage.NewBoltStorage(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = st.CreateAPIKey(common.APIKey{
+		Name:        "bot",
+		Permissions: []string{"read_only"},
+		Secrets:     []common.APIKeySecret{{Secret: "bot secret"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	s := HTTPServer{
+		app:         data.NewReserveData(st, nil, nil, nil, nil, nil),
+		authEnabled: true,
+		auth:        NewKeyStoreAuthentication(st, KNAuthentication{KNSecret: "shared", KNReadOnly: "readonly"}),
+		nonces:      NewMemoryNonceStore(DefaultNonceStoreCapacity, DefaultNonceStoreKeyCapacity, common.GetTimepoint),
+		r:           gin.New(),
+	}
+	s.r.GET("/activities", s.GetActivities)
+	s.r.GET("/exchange-notifications", s.GetNotifications)
+	s.r.POST("/exchange-notification", s.ExchangeNotification)
+	server := httptest.NewServer(s.r)
+	defer server.Close()
+
+	notification := client.ExchangeNotificationParams{
+		Exchange:  "binance",
+		Action:    "deposit",
+		Token:     "KNC",
+		FromTime:  1,
+		ToTime:    2,
+		IsWarning: true,
+		Msg:       client.String("deposit is delayed"),
+	}
+	if _, err = client.New(server.URL, client.WithSecret("shared")).ExchangeNotification(notification); err != nil {
+		t.Fatalf("expected notification signed by the shared secret to be stored: %s", err)
+	}
+	_, err = client.New(server.URL, client.WithSecret("readonly")).ExchangeNotification(notification)
+	if apiErr, ok := err.(*client.Error); !ok || !strings.Contains(apiErr.Reason, "permission") {
+		t.Errorf("expected notification signed by the read only secret to be rejected, got %v", err)
+	}
+	if _, err = client.New(server.URL, client.WithSecret("wrong")).GetNotifications(); err == nil {
+		t.Error("expected request signed by a wrong secret to be rejected")
+	}
+
+	// requests in the same millisecond are not rejected as replays
+	bot := client.New(server.URL, client.WithAPIKey("bot", "bot secret"))
+	for i := 0; i < 3; i++ {
+		resp, gErr := bot.GetNotifications()
+		if gErr != nil {
+			t.Fatal(gErr)
+		}
+		if len(resp.Data) == 0 {
+			t.Errorf("expected notifications, got %+v", resp)
+		}
+	}
+	resp, err := bot.GetActivities(client.GetActivitiesParams{Limit: client.Int64(10), PendingOnly: client.Bool(true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := common.ActivityPage{}
+	if err = resp.Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Activities) != 0 {
+		t.Errorf("expected no activities, got %+v", page)
+	}
+}
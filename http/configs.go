@@ -0,0 +1,518 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/http/httputil"
+	"github.com/KyberNetwork/reserve-data/metric"
+	"github.com/gin-gonic/gin"
+)
+
+var configTypePattern = regexp.MustCompile(`^[a-z0-9_]{1,64}$`)
+
+// ConfigStorage stores pending proposals and confirmed versions of configs.
+// A config type has at most one pending proposal.
+type ConfigStorage interface {
+	// StoreConfigProposal stores the pending proposal of a config type, it
+	// fails if the type already has one.
+	StoreConfigProposal(proposal common.ConfigProposal) error
+	GetConfigProposal(configType string) (common.ConfigProposal, error)
+	RemoveConfigProposal(configType string) error
+	// ConfirmConfigProposal removes the pending proposal of hash, stores its
+	// value as the current config of its type and as the next version
+	// confirmed by confirmer, all in the same transaction.
+	ConfirmConfigProposal(configType, hash, confirmer string) (common.ConfigVersion, error)
+	// GetConfigVersion returns a version of a config type, the latest one if
+	// version is 0, or common.ErrConfigVersionNotFound.
+	GetConfigVersion(configType string, version uint64) (common.ConfigVersion, error)
+	// GetConfigVersions returns all versions of a config type sorted by
+	// version.
+	GetConfigVersions(configType string) ([]common.ConfigVersion, error)
+}
+
+// ConfigValidator returns an error if value is not a valid config.
+type ConfigValidator func(value []byte) error
+
+// ConfigRegistry is the registry of config types managed by the config APIs,
+// each type registers the validator of its proposals.
+type ConfigRegistry struct {
+	validators map[string]ConfigValidator
+}
+
+// NewConfigRegistry creates an empty registry.
+func NewConfigRegistry() *ConfigRegistry {
+	return &ConfigRegistry{validators: map[string]ConfigValidator{}}
+}
+
+// Register adds a config type validated by validator. Names of types are
+// lower case letters, digits and underscores.
+func (self *ConfigRegistry) Register(configType string, validator ConfigValidator) error {
+	if !configTypePattern.MatchString(configType) {
+		return fmt.Errorf("invalid config type %q", configType)
+	}
+	if _, ok := self.validators[configType]; ok {
+		return fmt.Errorf("config type %s is already registered", configType)
+	}
+	self.validators[configType] = validator
+	return nil
+}
+
+// Validate returns an error if configType is not registered or value is not
+// valid for it.
+func (self *ConfigRegistry) Validate(configType string, value []byte) error {
+	validator, ok := self.validators[configType]
+	if !ok {
+		return fmt.Errorf("config type %s is not supported", configType)
+	}
+	return validator(value)
+}
+
+// Has returns true if configType is registered.
+func (self *ConfigRegistry) Has(configType string) bool {
+	_, ok := self.validators[configType]
+	return ok
+}
+
+// Types returns the registered config types sorted by name.
+func (self *ConfigRegistry) Types() []string {
+	result := []string{}
+	for configType := range self.validators {
+		result = append(result, configType)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// validateJSON returns a validator decoding values to a new value of
+// newConfig, which is validated if it has a Validate method.
+func validateJSON(newConfig func() interface{}) ConfigValidator {
+	return func(value []byte) error {
+		config := newConfig()
+		if err := json.Unmarshal(value, config); err != nil {
+			return err
+		}
+		if validator, ok := config.(interface {
+			Validate() error
+		}); ok {
+			return validator.Validate()
+		}
+		return nil
+	}
+}
+
+// DefaultConfigRegistry returns the registry of configs of the reserve, they
+// are validated the same as by their own APIs.
+func DefaultConfigRegistry() *ConfigRegistry {
+	result := NewConfigRegistry()
+	for configType, validator := range map[string]ConfigValidator{
+		common.TargetQtyConfig: validateJSON(func() interface{} {
+			return &metric.TargetQtyRequest{}
+		}),
+		common.TargetQtyV2Config: validateJSON(func() interface{} {
+			return &metric.TokenTargetQtyV2{}
+		}),
+		common.PWIEquationConfig: validateJSON(func() interface{} {
+			return &metric.PWIEquationRequest{}
+		}),
+		common.PWIEquationV2Config: validateJSON(func() interface{} {
+			return &metric.PWIEquationRequestV2{}
+		}),
+		common.RebalanceQuadraticConfig: validateJSON(func() interface{} {
+			return &metric.RebalanceQuadraticRequest{}
+		}),
+		common.StableTokenParamsConfig: validateJSON(func() interface{} {
+			return &metric.StableTokenParamsRequest{}
+		}),
+	} {
+		if err := result.Register(configType, validator); err != nil {
+			panic(err)
+		}
+	}
+	return result
+}
+
+// configsConfigured responds failure and returns false if versioned configs
+// are not supported by the server.
+func (self *HTTPServer) configsConfigured(c *gin.Context) bool {
+	if self.configs == nil || self.configTypes == nil {
+		httputil.ResponseFailure(c, httputil.WithError(errors.New("versioned configs are not configured")))
+		return false
+	}
+	return true
+}
+
+// principalKey returns the key of the principal of an authenticated
+// request, empty if authentication is disabled.
+func principalKey(c *gin.Context) string {
+	if principal, ok := principalOf(c); ok {
+		return principal.Key
+	}
+	return ""
+}
+
+// configType returns the type param of a request, it responds failure and
+// returns false if the type is not registered.
+func (self *HTTPServer) configType(c *gin.Context) (string, bool) {
+	configType := c.Param("type")
+	if self.configTypes.Has(configType) {
+		return configType, true
+	}
+	httputil.ResponseFailure(c, httputil.WithReason(fmt.Sprintf("config type %s is not supported", configType)))
+	return configType, false
+}
+
+// proposeConfig validates value of configType and stores it as the pending
+// proposal by the principal of the request, restoring version rollbackOf if
+// it is not 0. ProposeConfig, RollbackConfig and the own APIs of configs all
+// propose through it, so their proposals are confirmed the same way. It
+// returns the proposal with its changes from the latest version, or responds
+// failure and returns false if the proposal is not stored.
+func (self *HTTPServer) proposeConfig(c *gin.Context, configType string, value []byte, rollbackOf uint64) (common.ConfigProposal, []common.ConfigChange, bool) {
+	if !self.configsConfigured(c) {
+		return common.ConfigProposal{}, nil, false
+	}
+	if len(value) > MAX_DATA_SIZE {
+		httputil.ResponseFailure(c, httputil.WithError(errDataSizeExceed))
+		return common.ConfigProposal{}, nil, false
+	}
+	if err := self.configTypes.Validate(configType, value); err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return common.ConfigProposal{}, nil, false
+	}
+	proposal, err := common.NewConfigProposal(configType, value, principalKey(c), common.GetTimepoint())
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return proposal, nil, false
+	}
+	proposal.RollbackOf = rollbackOf
+	proposal.Hash = proposal.ComputeHash()
+	changes, err := self.diffFromCurrent(configType, proposal.Value)
+	if err == nil {
+		err = self.configs.StoreConfigProposal(proposal)
+	}
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return proposal, nil, false
+	}
+	return proposal, changes, true
+}
+
+// pendingConfig decodes the pending proposal of configType to result, it
+// responds failure and returns false if there is none.
+func (self *HTTPServer) pendingConfig(c *gin.Context, configType string, result interface{}) (common.ConfigProposal, bool) {
+	if !self.configsConfigured(c) {
+		return common.ConfigProposal{}, false
+	}
+	proposal, err := self.configs.GetConfigProposal(configType)
+	if err == nil {
+		err = json.Unmarshal(proposal.Value, result)
+	}
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return proposal, false
+	}
+	return proposal, true
+}
+
+// confirmPendingConfig confirms the pending proposal of configType by the
+// principal of the request if matches returns nil for it. The own APIs of
+// configs confirm through it, so confirmed configs are stored and recorded
+// as versions in the same transaction.
+func (self *HTTPServer) confirmPendingConfig(c *gin.Context, configType string, matches func(proposal common.ConfigProposal) error) {
+	if !self.configsConfigured(c) {
+		return
+	}
+	proposal, err := self.configs.GetConfigProposal(configType)
+	if err == nil {
+		err = matches(proposal)
+	}
+	if err == nil {
+		_, err = self.configs.ConfirmConfigProposal(configType, proposal.Hash, principalKey(c))
+	}
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c)
+}
+
+// sameConfig returns a matcher of proposals of the same config as value, both
+// are decoded to new values of newConfig to compare.
+func sameConfig(value []byte, newConfig func() interface{}) func(proposal common.ConfigProposal) error {
+	return func(proposal common.ConfigProposal) error {
+		confirmed, pending := newConfig(), newConfig()
+		if err := json.Unmarshal(value, confirmed); err != nil {
+			return fmt.Errorf("Rejected: Data could not be unmarshalled to defined format: %s", err)
+		}
+		if err := json.Unmarshal(proposal.Value, pending); err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(confirmed, pending) {
+			return fmt.Errorf("Rejected: confirmed data doesn't match the pending proposal of config %s", proposal.Type)
+		}
+		return nil
+	}
+}
+
+// rejectPendingConfig removes the pending proposal of configType.
+func (self *HTTPServer) rejectPendingConfig(c *gin.Context, configType string) {
+	if !self.configsConfigured(c) {
+		return
+	}
+	if err := self.configs.RemoveConfigProposal(configType); err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c)
+}
+
+// diffFromCurrent returns changes from the latest version of configType to
+// value, from null if it has no versions.
+func (self *HTTPServer) diffFromCurrent(configType string, value []byte) ([]common.ConfigChange, error) {
+	current := []byte("null")
+	version, err := self.configs.GetConfigVersion(configType, 0)
+	switch err {
+	case nil:
+		current = version.Value
+	case common.ErrConfigVersionNotFound:
+	default:
+		return nil, err
+	}
+	return common.DiffConfigs(current, value)
+}
+
+// GetConfigTypes returns the config types managed by the config APIs.
+func (self *HTTPServer) GetConfigTypes(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok || !self.configsConfigured(c) {
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(self.configTypes.Types()))
+}
+
+// GetConfig returns the latest version of a config type.
+func (self *HTTPServer) GetConfig(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok || !self.configsConfigured(c) {
+		return
+	}
+	configType, ok := self.configType(c)
+	if !ok {
+		return
+	}
+	version, err := self.configs.GetConfigVersion(configType, 0)
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(version))
+}
+
+// GetConfigVersions returns all versions of a config type, the history of
+// who proposed and confirmed them.
+func (self *HTTPServer) GetConfigVersions(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok || !self.configsConfigured(c) {
+		return
+	}
+	configType, ok := self.configType(c)
+	if !ok {
+		return
+	}
+	versions, err := self.configs.GetConfigVersions(configType)
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(versions))
+}
+
+// GetConfigVersion returns a version of a config type.
+func (self *HTTPServer) GetConfigVersion(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok || !self.configsConfigured(c) {
+		return
+	}
+	configType, ok := self.configType(c)
+	if !ok {
+		return
+	}
+	number, err := strconv.ParseUint(c.Param("version"), 10, 64)
+	if err != nil || number == 0 {
+		httputil.ResponseFailure(c, httputil.WithReason(fmt.Sprintf("invalid version %s", c.Param("version"))))
+		return
+	}
+	version, err := self.configs.GetConfigVersion(configType, number)
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(version))
+}
+
+// GetConfigDiff returns changes between two versions of a config type. The
+// to version is the latest one if it is not given.
+func (self *HTTPServer) GetConfigDiff(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{"from"}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok || !self.configsConfigured(c) {
+		return
+	}
+	configType, ok := self.configType(c)
+	if !ok {
+		return
+	}
+	versions := []uint64{0, 0}
+	for i, name := range []string{"from", "to"} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		number, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || number == 0 {
+			httputil.ResponseFailure(c, httputil.WithReason(fmt.Sprintf("invalid %s version %s", name, value)))
+			return
+		}
+		versions[i] = number
+	}
+	from, err := self.configs.GetConfigVersion(configType, versions[0])
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	to, err := self.configs.GetConfigVersion(configType, versions[1])
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	changes, err := common.DiffConfigs(from.Value, to.Value)
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithMultipleFields(gin.H{
+		"from": from.Version,
+		"to":   to.Version,
+		"data": changes,
+	}))
+}
+
+// GetPendingConfig returns the pending proposal of a config type and its
+// changes from the latest version.
+func (self *HTTPServer) GetPendingConfig(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok || !self.configsConfigured(c) {
+		return
+	}
+	configType, ok := self.configType(c)
+	if !ok {
+		return
+	}
+	proposal, err := self.configs.GetConfigProposal(configType)
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	changes, err := self.diffFromCurrent(configType, proposal.Value)
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithMultipleFields(gin.H{
+		"data":    proposal,
+		"changes": changes,
+	}))
+}
+
+// ProposeConfig stores value as the pending proposal of a config type once
+// it is validated by the validator of the type.
+func (self *HTTPServer) ProposeConfig(c *gin.Context) {
+	postForm, ok := self.Authenticated(c, []string{"value"}, []Permission{ConfigurePermission})
+	if !ok || !self.configsConfigured(c) {
+		return
+	}
+	configType, ok := self.configType(c)
+	if !ok {
+		return
+	}
+	proposal, changes, ok := self.proposeConfig(c, configType, []byte(postForm.Get("value")), 0)
+	if !ok {
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithMultipleFields(gin.H{
+		"data":    proposal,
+		"changes": changes,
+	}))
+}
+
+// RollbackConfig proposes to restore a version of a config type, it takes
+// effect once it is confirmed like other proposals.
+func (self *HTTPServer) RollbackConfig(c *gin.Context) {
+	postForm, ok := self.Authenticated(c, []string{"version"}, []Permission{ConfigurePermission})
+	if !ok || !self.configsConfigured(c) {
+		return
+	}
+	configType, ok := self.configType(c)
+	if !ok {
+		return
+	}
+	number, err := strconv.ParseUint(postForm.Get("version"), 10, 64)
+	if err != nil || number == 0 {
+		httputil.ResponseFailure(c, httputil.WithReason(fmt.Sprintf("invalid version %s", postForm.Get("version"))))
+		return
+	}
+	version, err := self.configs.GetConfigVersion(configType, number)
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	// validators may be stricter than when the version was confirmed, so the
+	// version is validated again like a new value
+	proposal, changes, ok := self.proposeConfig(c, configType, version.Value, number)
+	if !ok {
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithMultipleFields(gin.H{
+		"data":    proposal,
+		"changes": changes,
+	}))
+}
+
+// ConfirmConfig confirms the pending proposal of a config type as its next
+// version. The hash must be the hash of the pending proposal, so only the
+// reviewed proposal is confirmed.
+func (self *HTTPServer) ConfirmConfig(c *gin.Context) {
+	postForm, ok := self.Authenticated(c, []string{"hash"}, []Permission{ConfirmConfPermission})
+	if !ok || !self.configsConfigured(c) {
+		return
+	}
+	configType, ok := self.configType(c)
+	if !ok {
+		return
+	}
+	version, err := self.configs.ConfirmConfigProposal(configType, postForm.Get("hash"), principalKey(c))
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(version))
+}
+
+// RejectConfig removes the pending proposal of a config type.
+func (self *HTTPServer) RejectConfig(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ConfirmConfPermission})
+	if !ok || !self.configsConfigured(c) {
+		return
+	}
+	configType, ok := self.configType(c)
+	if !ok {
+		return
+	}
+	self.rejectPendingConfig(c, configType)
+}
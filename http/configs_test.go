@@ -0,0 +1,268 @@
+package http
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/data/storage"
+	"github.com/gin-gonic/gin"
+)
+
+func TestConfigRegistry(t *testing.T) {
+	registry := NewConfigRegistry()
+	validator := func([]byte) error { return nil }
+	if err := registry.Register("target_qty_v3", validator); err != nil {
+		t.Fatal(err)
+	}
+	for _, configType := range []string{"target_qty_v3", "Target-Qty", ""} {
+		if err := registry.Register(configType, validator); err == nil {
+			t.Errorf("expected error registering config type %q", configType)
+		}
+	}
+	if err := registry.Validate("unknown", []byte(`{}`)); err == nil {
+		t.Error("expected error validating unregistered config type")
+	}
+
+	expected := []string{
+		common.PWIEquationConfig,
+		common.PWIEquationV2Config,
+		common.RebalanceQuadraticConfig,
+		common.StableTokenParamsConfig,
+		common.TargetQtyConfig,
+		common.TargetQtyV2Config,
+	}
+	defaults := DefaultConfigRegistry()
+	if types := defaults.Types(); len(types) != len(expected) {
+		t.Errorf("expected config types %v, got %v", expected, types)
+	}
+	for _, configType := range expected {
+		if err := defaults.Validate(configType, []byte(`{`)); err == nil {
+			t.Errorf("expected error validating invalid JSON of %s", configType)
+		}
+	}
+	common.RegisterInternalActiveToken(common.Token{ID: "KNC"})
+	for _, tc := range []struct {
+		configType string
+		value      string
+		valid      bool
+	}{
+		{common.RebalanceQuadraticConfig, `{"UNKNOWN":{"rebalance_quadratic":{"a":1}}}`, false},
+		{common.TargetQtyConfig, `{"data":"KNC_1_2_3_4","type":1}`, true},
+		{common.TargetQtyConfig, `{"data":"KNC_1_2_3","type":1}`, false},
+		{common.TargetQtyConfig, `{"data":"UNKNOWN_1_2_3_4","type":1}`, false},
+		{common.PWIEquationConfig, `{"data":"KNC_1_2_3"}`, true},
+		{common.PWIEquationConfig, `{"data":"KNC_1_2"}`, false},
+		{common.StableTokenParamsConfig, `{"DGX":{"fee":1}}`, true},
+		{common.StableTokenParamsConfig, `null`, false},
+	} {
+		err := defaults.Validate(tc.configType, []byte(tc.value))
+		if tc.valid && err != nil {
+			t.Errorf("expected %s of %s to be valid, got %s", tc.value, tc.configType, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("expected error validating %s of %s", tc.value, tc.configType)
+		}
+	}
+}
+
+func TestHTTPServerConfigs(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test_configs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}()
+	st, err := storage.NewBoltStorage(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := HTTPServer{
+		authEnabled: true,
+		auth:        KNAuthentication{KNReadOnly: "readonly", KNConfiguration: "configure", KNConfirmConf: "confirm"},
+		metric:      st,
+		configs:     st,
+		configTypes: DefaultConfigRegistry(),
+		r:           gin.New(),
+	}
+	s.register()
+	s.r.POST("/set-stable-token-params", s.SetStableTokenParams)
+	s.r.POST("/confirm-stable-token-params", s.ConfirmStableTokenParams)
+	s.r.GET("/stable-token-params", s.GetStableTokenParams)
+
+	type response struct {
+		Success bool                  `json:"success"`
+		Reason  string                `json:"reason"`
+		Data    json.RawMessage       `json:"data"`
+		Changes []common.ConfigChange `json:"changes"`
+	}
+	serve := func(req *http.Request) response {
+		resp := httptest.NewRecorder()
+		s.r.ServeHTTP(resp, req)
+		var result response
+		if err := json.Unmarshal(resp.Body.Bytes(), &result); err != nil {
+			t.Fatalf("invalid response %s: %s", resp.Body.String(), err)
+		}
+		return result
+	}
+	decode := func(resp response, result interface{}) {
+		if !resp.Success {
+			t.Fatalf("expected success, got %s", resp.Reason)
+		}
+		if err := json.Unmarshal(resp.Data, result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	propose := func(value string) common.ConfigProposal {
+		var proposal common.ConfigProposal
+		decode(serve(newSignedRequest(t, http.MethodPost, "/configs/stable_token_params/propose", "configure",
+			map[string]string{"value": value})), &proposal)
+		return proposal
+	}
+	confirm := func(hash string) common.ConfigVersion {
+		var version common.ConfigVersion
+		decode(serve(newSignedRequest(t, http.MethodPost, "/configs/stable_token_params/confirm", "confirm",
+			map[string]string{"hash": hash})), &version)
+		return version
+	}
+
+	if resp := serve(newSignedRequest(t, http.MethodPost, "/configs/step_functions/propose", "configure",
+		map[string]string{"value": `{}`})); resp.Success {
+		t.Error("expected proposal of unregistered config type to fail")
+	}
+	if resp := serve(newSignedRequest(t, http.MethodPost, "/configs/stable_token_params/propose", "configure",
+		map[string]string{"value": `[1]`})); resp.Success {
+		t.Error("expected proposal rejected by validator to fail")
+	}
+	if resp := serve(newSignedRequest(t, http.MethodPost, "/configs/stable_token_params/propose", "confirm",
+		map[string]string{"value": `{"DGX":{"fee":1}}`})); resp.Success {
+		t.Error("expected proposal without configure permission to fail")
+	}
+
+	first := propose(`{"DGX": {"fee": 1}}`)
+	if first.Proposer != "kn_configuration" || string(first.Value) != `{"DGX":{"fee":1}}` {
+		t.Errorf("unexpected proposal %+v", first)
+	}
+	if resp := serve(newSignedRequest(t, http.MethodPost, "/configs/stable_token_params/propose", "configure",
+		map[string]string{"value": `{"DGX":{"fee":2}}`})); resp.Success {
+		t.Error("expected proposal to fail while another one is pending")
+	}
+	if resp := serve(newSignedRequest(t, http.MethodPost, "/configs/stable_token_params/confirm", "confirm",
+		map[string]string{"hash": "unreviewed"})); resp.Success {
+		t.Error("expected confirmation of another hash to fail")
+	}
+	version := confirm(first.Hash)
+	if version.Version != 1 || version.Proposer != "kn_configuration" || version.Confirmer != "kn_confirm_configuration" {
+		t.Errorf("unexpected first version %+v", version)
+	}
+
+	second := propose(`{"DGX":{"fee":2},"ZRX":{"fee":1}}`)
+	resp := serve(newSignedRequest(t, http.MethodGet, "/configs/stable_token_params/pending", "readonly", nil))
+	var pending common.ConfigProposal
+	decode(resp, &pending)
+	if pending.Hash != second.Hash || len(resp.Changes) != 2 || resp.Changes[0].Path != "/DGX/fee" {
+		t.Errorf("unexpected pending proposal %+v with changes %+v", pending, resp.Changes)
+	}
+	if version = confirm(second.Hash); version.Version != 2 {
+		t.Errorf("unexpected second version %+v", version)
+	}
+	if resp = serve(newSignedRequest(t, http.MethodGet, "/configs/stable_token_params/pending", "readonly", nil)); resp.Success {
+		t.Error("expected no pending proposal once it is confirmed")
+	}
+
+	resp = serve(newSignedRequest(t, http.MethodGet, "/configs/stable_token_params/diff", "readonly",
+		map[string]string{"from": "1"}))
+	var changes []common.ConfigChange
+	decode(resp, &changes)
+	expected := []common.ConfigChange{
+		{Op: common.ConfigReplace, Path: "/DGX/fee", From: 1.0, To: 2.0},
+		{Op: common.ConfigAdd, Path: "/ZRX", To: map[string]interface{}{"fee": 1.0}},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected changes %+v, got %+v", expected, changes)
+	}
+	for i := range expected {
+		if changes[i].Op != expected[i].Op || changes[i].Path != expected[i].Path {
+			t.Errorf("expected change %+v, got %+v", expected[i], changes[i])
+		}
+	}
+
+	// rollbacks go through confirmation like other proposals
+	var rollback common.ConfigProposal
+	decode(serve(newSignedRequest(t, http.MethodPost, "/configs/stable_token_params/rollback", "configure",
+		map[string]string{"version": "1"})), &rollback)
+	if rollback.RollbackOf != 1 || string(rollback.Value) != `{"DGX":{"fee":1}}` {
+		t.Errorf("unexpected rollback proposal %+v", rollback)
+	}
+	var current common.ConfigVersion
+	decode(serve(newSignedRequest(t, http.MethodGet, "/configs/stable_token_params", "readonly", nil)), &current)
+	if current.Version != 2 {
+		t.Errorf("expected rollback not to take effect before it is confirmed, got %+v", current)
+	}
+	if version = confirm(rollback.Hash); version.Version != 3 || version.RollbackOf != 1 {
+		t.Errorf("unexpected rollback version %+v", version)
+	}
+	if resp = serve(newSignedRequest(t, http.MethodPost, "/configs/stable_token_params/rollback", "configure",
+		map[string]string{"version": "4"})); resp.Success {
+		t.Error("expected rollback to unknown version to fail")
+	}
+
+	// confirmed versions are stored where the own APIs of configs read them
+	params := map[string]interface{}{}
+	decode(serve(newSignedRequest(t, http.MethodGet, "/stable-token-params", "readonly", nil)), &params)
+	if _, ok := params["ZRX"]; ok || len(params) != 1 {
+		t.Errorf("expected stable token params of the latest version, got %v", params)
+	}
+
+	// the own APIs of configs propose and confirm through the config APIs
+	if resp = serve(newSignedRequest(t, http.MethodPost, "/set-stable-token-params", "configure",
+		map[string]string{"value": `null`})); resp.Success {
+		t.Error("expected invalid stable token params to fail")
+	}
+	value := `{"DGX":{"fee":3}}`
+	if resp = serve(newSignedRequest(t, http.MethodPost, "/set-stable-token-params", "configure",
+		map[string]string{"value": value})); !resp.Success {
+		t.Fatalf("expected success, got %s", resp.Reason)
+	}
+	decode(serve(newSignedRequest(t, http.MethodGet, "/configs/stable_token_params/pending", "readonly", nil)), &pending)
+	if pending.Proposer != "kn_configuration" || string(pending.Value) != value {
+		t.Errorf("unexpected proposal of own API %+v", pending)
+	}
+	if resp = serve(newSignedRequest(t, http.MethodPost, "/confirm-stable-token-params", "confirm",
+		map[string]string{"value": `{"DGX":{"fee":4}}`})); resp.Success {
+		t.Error("expected confirmation of other params to fail")
+	}
+	if resp = serve(newSignedRequest(t, http.MethodPost, "/confirm-stable-token-params", "confirm",
+		map[string]string{"value": `{ "DGX": {"fee": 3} }`})); !resp.Success {
+		t.Fatalf("expected success, got %s", resp.Reason)
+	}
+	var versions []common.ConfigVersion
+	decode(serve(newSignedRequest(t, http.MethodGet, "/configs/stable_token_params/versions", "readonly", nil)), &versions)
+	if len(versions) != 4 {
+		t.Fatalf("expected 4 versions, got %+v", versions)
+	}
+	if last := versions[3]; string(last.Value) != value || last.Confirmer != "kn_confirm_configuration" {
+		t.Errorf("unexpected version recorded by own API %+v", last)
+	}
+	var old common.ConfigVersion
+	decode(serve(newSignedRequest(t, http.MethodGet, "/configs/stable_token_params/versions/2", "readonly", nil)), &old)
+	if string(old.Value) != `{"DGX":{"fee":2},"ZRX":{"fee":1}}` {
+		t.Errorf("unexpected second version %+v", old)
+	}
+
+	propose(`{}`)
+	if resp = serve(newSignedRequest(t, http.MethodPost, "/configs/stable_token_params/reject", "confirm", nil)); !resp.Success {
+		t.Errorf("expected rejection to succeed, got %s", resp.Reason)
+	}
+	if resp = serve(newSignedRequest(t, http.MethodGet, "/configs/stable_token_params/pending", "readonly", nil)); resp.Success {
+		t.Error("expected no pending proposal once it is rejected")
+	}
+}
@@ -0,0 +1,15 @@
+package http
+
+import (
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/http/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+// DataHealth returns the age of the latest prices, auth data, rates and gold
+// snapshots, and fetch duration, success rate and data age of every source.
+// Data older than its stale limit is flagged stale, bots should not act on
+// it if the top level stale is true.
+func (self *HTTPServer) DataHealth(c *gin.Context) {
+	httputil.ResponseSuccess(c, httputil.WithData(self.app.GetDataHealth(common.GetTimepoint())))
+}
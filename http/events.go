@@ -0,0 +1,64 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/http/httputil"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// eventBufferSize is the number of events buffered for each subscriber,
+	// events are dropped if the subscriber is slower.
+	eventBufferSize = 256
+	// heartbeatInterval is the interval of heartbeats keeping idle streams open.
+	heartbeatInterval = 15 * time.Second
+)
+
+// Events streams events of topics, given as a comma separated list, or all
+// topics as server-sent events until the client disconnects.
+func (self *HTTPServer) Events(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	if self.events == nil {
+		httputil.ResponseFailure(c, httputil.WithError(errors.New("events are not configured")))
+		return
+	}
+	subscription, err := self.events.Subscribe(splitList(c.Query("topics")), eventBufferSize)
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	defer self.events.Unsubscribe(subscription)
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	// disable buffering of proxies, e.g nginx
+	c.Header("X-Accel-Buffering", "no")
+	// send headers now, clients wait for them before receiving events
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case e, ok := <-subscription.Events():
+			if !ok {
+				return false
+			}
+			c.Render(-1, sse.Event{Event: e.Topic, Id: strconv.FormatUint(e.ID, 10), Data: e})
+		case <-heartbeat.C:
+			c.SSEvent("heartbeat", common.GetTimepoint())
+		case <-c.Request.Context().Done():
+			return false
+		}
+		return true
+	})
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/common/event"
+	"github.com/gin-gonic/gin"
+)
+
+func TestHTTPServerEvents(t *testing.T) {
+	hub := event.NewHub()
+	s := HTTPServer{authEnabled: false, events: hub, r: gin.Default()}
+	s.r.GET("/events", s.Events)
+	server := httptest.NewServer(s.r)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/events?topics=unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failure struct {
+		Success bool `json:"success"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&failure); err != nil || failure.Success {
+		t.Errorf("expected failure subscribing unknown topic, err %v", err)
+	}
+	if err = resp.Body.Close(); err != nil {
+		t.Error(err)
+	}
+
+	resp, err = http.Get(server.URL + "/events?topics=prices,activities")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if cErr := resp.Body.Close(); cErr != nil {
+			t.Error(cErr)
+		}
+	}()
+	// the stream is subscribed after the response starts, publish until the
+	// first event is received
+	done := make(chan bool)
+	defer close(done)
+	go func() {
+		for {
+			hub.Publish(event.RateTopic, event.VersionData{Version: 1})
+			hub.Publish(event.PriceTopic, event.VersionData{Version: 2})
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}()
+
+	reader := bufio.NewReader(resp.Body)
+	fields := map[string]string{}
+	for len(fields) < 3 {
+		line, rErr := reader.ReadString('\n')
+		if rErr != nil {
+			t.Fatal(rErr)
+		}
+		if parts := strings.SplitN(strings.TrimSpace(line), ":", 2); len(parts) == 2 {
+			fields[parts[0]] = parts[1]
+		}
+	}
+	if fields["event"] != event.PriceTopic {
+		t.Errorf("expected price event, got %v", fields)
+	}
+	var e event.Event
+	if err = json.Unmarshal([]byte(fields["data"]), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Topic != event.PriceTopic || fields["id"] == "" {
+		t.Errorf("unexpected event %+v, id %s", e, fields["id"])
+	}
+}
@@ -0,0 +1,25 @@
+package http
+
+import (
+	"github.com/KyberNetwork/reserve-data/http/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+// GetGasSpend returns the gas spent by operators for our transactions between fromTime and toTime
+// (in millisecond), broken down by operator, action and day. Fees are in ETH and USD.
+func (self *HTTPServer) GetGasSpend(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	fromTime, toTime, ok := self.ValidateTimeInput(c)
+	if !ok {
+		return
+	}
+	data, err := self.app.GetGasSpend(fromTime, toTime)
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(data))
+}
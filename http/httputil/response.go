@@ -64,6 +64,13 @@ func ResponseSuccess(c *gin.Context, options ...ResponseOption) {
 // ResponseFailure responses the request with 200 status code and a
 // failure message.
 func ResponseFailure(c *gin.Context, options ...ResponseOption) {
+	ResponseFailureWithStatus(c, http.StatusOK, options...)
+}
+
+// ResponseFailureWithStatus responses the request with the given status
+// code and a failure message. It is intended for clients which only look at
+// status codes, e.g orchestrators and load balancers.
+func ResponseFailureWithStatus(c *gin.Context, status int, options ...ResponseOption) {
 	h := gin.H{
 		"success": false,
 	}
@@ -73,7 +80,7 @@ func ResponseFailure(c *gin.Context, options ...ResponseOption) {
 	}
 
 	c.JSON(
-		http.StatusOK,
+		status,
 		h,
 	)
 }
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/KyberNetwork/reserve-data/common"
+)
+
+// APIKeyStorage stores API keys.
+type APIKeyStorage interface {
+	// CreateAPIKey stores a new key, it fails if a key of the same name exists.
+	CreateAPIKey(key common.APIKey) error
+	// UpdateAPIKey reads the key of name, changes it with update and stores
+	// it back in one transaction, nothing is stored if update fails.
+	UpdateAPIKey(name string, update func(key *common.APIKey) error) (common.APIKey, error)
+	GetAPIKey(name string) (common.APIKey, error)
+	GetAPIKeys() ([]common.APIKey, error)
+	RemoveAPIKey(name string) error
+}
+
+// KeyStoreAuthentication authenticates requests signed by API keys of a key
+// store, named by the key header. Keys are read on every request, so changes
+// are effective immediately. Requests without the key header are
+// authenticated by shared secrets, if configured.
+type KeyStoreAuthentication struct {
+	store  APIKeyStorage
+	shared Authentication
+}
+
+// NewKeyStoreAuthentication creates an authentication of keys of store,
+// shared is used for requests without the key header and may be nil.
+func NewKeyStoreAuthentication(store APIKeyStorage, shared Authentication) *KeyStoreAuthentication {
+	return &KeyStoreAuthentication{store: store, shared: shared}
+}
+
+// KNSign signs msg with the shared secret.
+func (self *KeyStoreAuthentication) KNSign(msg string) string {
+	if self.shared == nil {
+		return ""
+	}
+	return self.shared.KNSign(msg)
+}
+
+// Authenticate returns the principal of key keyName if message is signed by
+// any of its valid secrets.
+func (self *KeyStoreAuthentication) Authenticate(keyName, signed, message string) (Principal, error) {
+	if keyName == "" {
+		if self.shared == nil {
+			return Principal{}, errors.New("key header is required")
+		}
+		return self.shared.Authenticate(keyName, signed, message)
+	}
+	key, err := self.store.GetAPIKey(keyName)
+	if err != nil {
+		log.Printf("Getting API key %s failed: %s", keyName, err)
+		return Principal{}, errInvalidSignedToken
+	}
+	timepoint := common.GetTimepoint()
+	if key.Disabled {
+		return Principal{}, fmt.Errorf("API key %s is disabled", keyName)
+	}
+	if key.Expired(timepoint) {
+		return Principal{}, fmt.Errorf("API key %s is expired", keyName)
+	}
+	for _, secret := range key.ValidSecrets(timepoint) {
+		if hmac.Equal([]byte(hmacSign(secret, message)), []byte(signed)) {
+			return newKeyPrincipal(key)
+		}
+	}
+	return Principal{}, errInvalidSignedToken
+}
+
+func newKeyPrincipal(key common.APIKey) (Principal, error) {
+	perms, err := parsePermissions(key.Permissions)
+	if err != nil {
+		return Principal{}, fmt.Errorf("API key %s is invalid: %s", key.Name, err)
+	}
+	return Principal{Key: key.Name, Permissions: perms, Scopes: key.Scopes}, nil
+}
+
+func parsePermissions(names []string) ([]Permission, error) {
+	result := []Permission{}
+	for _, name := range names {
+		perm, err := ParsePermission(name)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, perm)
+	}
+	return result, nil
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/data/storage"
+)
+
+func TestKeyStoreAuthentication(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test_key_authentication")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}()
+	st, err := storage.NewBoltStorage(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const message = "nonce=1"
+	now := common.GetTimepoint()
+	keys := []common.APIKey{
+		{
+			Name:        "pricing",
+			Permissions: []string{"read_only", "rebalance"},
+			Scopes:      []string{"trade:binance"},
+			Secrets: []common.APIKeySecret{
+				{Secret: "expired", ExpiresAt: now - 1},
+				{Secret: "old", ExpiresAt: now + 60000},
+				{Secret: "new"},
+			},
+		},
+		{Name: "disabled", Permissions: []string{"read_only"}, Secrets: []common.APIKeySecret{{Secret: "disabled"}}, Disabled: true},
+		{Name: "expired", Permissions: []string{"read_only"}, Secrets: []common.APIKeySecret{{Secret: "expired"}}, ExpiresAt: now - 1},
+	}
+	for _, key := range keys {
+		if err = st.CreateAPIKey(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	auth := NewKeyStoreAuthentication(st, KNAuthentication{KNSecret: "shared", KNReadOnly: "readonly"})
+
+	var tests = []struct {
+		msg       string
+		key       string
+		secret    string
+		principal Principal
+		fail      bool
+	}{
+		{
+			msg:       "new secret",
+			key:       "pricing",
+			secret:    "new",
+			principal: Principal{Key: "pricing", Permissions: []Permission{ReadOnlyPermission, RebalancePermission}, Scopes: []string{"trade:binance"}},
+		},
+		{
+			msg:       "rotated secret in grace period",
+			key:       "pricing",
+			secret:    "old",
+			principal: Principal{Key: "pricing", Permissions: []Permission{ReadOnlyPermission, RebalancePermission}, Scopes: []string{"trade:binance"}},
+		},
+		{msg: "expired secret", key: "pricing", secret: "expired", fail: true},
+		{msg: "secret of another key", key: "pricing", secret: "disabled", fail: true},
+		{msg: "disabled key", key: "disabled", secret: "disabled", fail: true},
+		{msg: "expired key", key: "expired", secret: "expired", fail: true},
+		{msg: "unknown key", key: "unknown", secret: "new", fail: true},
+		{
+			msg:       "shared secret",
+			secret:    "readonly",
+			principal: Principal{Key: "kn_readonly", Permissions: []Permission{ReadOnlyPermission}},
+		},
+		{msg: "key secret without key header", secret: "new", fail: true},
+	}
+	for _, tc := range tests {
+		principal, aErr := auth.Authenticate(tc.key, hmacSign(tc.secret, message), message)
+		if tc.fail {
+			if aErr == nil {
+				t.Errorf("%s: expected authentication to fail", tc.msg)
+			}
+			continue
+		}
+		if aErr != nil {
+			t.Errorf("%s: unexpected error: %s", tc.msg, aErr)
+			continue
+		}
+		if !reflect.DeepEqual(principal, tc.principal) {
+			t.Errorf("%s: expected principal %+v, got %+v", tc.msg, tc.principal, principal)
+		}
+	}
+}
+
+func TestRotateSecrets(t *testing.T) {
+	secrets := []common.APIKeySecret{
+		{Secret: "expired", CreatedAt: 1, ExpiresAt: 1000},
+		{Secret: "expiring", CreatedAt: 2, ExpiresAt: 1500},
+		{Secret: "current", CreatedAt: 3},
+	}
+	expected := []common.APIKeySecret{
+		{Secret: "expiring", CreatedAt: 2, ExpiresAt: 1500},
+		{Secret: "current", CreatedAt: 3, ExpiresAt: 3000},
+		{Secret: "new", CreatedAt: 1000},
+	}
+	result := rotateSecrets(secrets, "new", 1000, 2000)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected secrets %+v, got %+v", expected, result)
+	}
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/http/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute is the route label of requests which match no routes.
+const unmatchedRoute = "unmatched"
+
+var requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name: "reserve_http_request_duration_seconds",
+	Help: "Duration of HTTP requests by route.",
+}, []string{"method", "route", "status"})
+
+// prometheusHandler serves metrics of the default registry.
+var prometheusHandler = gin.WrapH(promhttp.Handler())
+
+// routeKey identifies a route by its method and handler, as the vendored gin
+// doesn't expose the path pattern matching a request.
+func routeKey(method, handler string) string {
+	return method + " " + handler
+}
+
+// indexRoutes maps registered handlers to their path patterns, so request
+// durations are labelled by routes rather than unbounded request paths.
+func (self *HTTPServer) indexRoutes() {
+	self.routes = map[string]string{}
+	for _, route := range self.r.Routes() {
+		key := routeKey(route.Method, route.Handler)
+		if _, ok := self.routes[key]; !ok {
+			self.routes[key] = route.Path
+		}
+	}
+}
+
+// observeRequests records the duration of requests by route.
+func (self *HTTPServer) observeRequests(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+	route, ok := self.routes[routeKey(c.Request.Method, c.HandlerName())]
+	if !ok {
+		route = unmatchedRoute
+	}
+	requestDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Observe(time.Since(start).Seconds())
+}
+
+// PrometheusMetrics returns metrics of the service in the Prometheus text
+// format. Scrapers can't sign requests, so it requires the metrics token as a
+// bearer token instead if authentication is enabled, scrapes without a valid
+// token are responded with 401 Unauthorized.
+func (self *HTTPServer) PrometheusMetrics(c *gin.Context) {
+	if self.authEnabled {
+		if self.metricsToken == "" {
+			httputil.ResponseFailureWithStatus(c, http.StatusServiceUnavailable, httputil.WithError(errors.New("prometheus metrics are not configured")))
+			return
+		}
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(self.metricsToken)) != 1 {
+			httputil.ResponseFailureWithStatus(c, http.StatusUnauthorized, httputil.WithReason("Invalid metrics token"))
+			return
+		}
+	}
+	prometheusHandler(c)
+}
@@ -0,0 +1,60 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/http/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+func TestHTTPServerPrometheusMetrics(t *testing.T) {
+	s := HTTPServer{authEnabled: true, metricsToken: "token", r: gin.Default()}
+	s.r.Use(s.observeRequests)
+	s.r.GET("/prices/:base/:quote", func(c *gin.Context) {
+		httputil.ResponseSuccess(c)
+	})
+	s.r.GET("/metrics/prometheus", s.PrometheusMetrics)
+	s.indexRoutes()
+
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp := httptest.NewRecorder()
+		s.r.ServeHTTP(resp, req)
+		return resp
+	}
+	get("/prices/ETH/KNC", "")
+	get("/prices/OMG/KNC", "")
+	get("/not-found", "")
+
+	for _, token := range []string{"invalid", ""} {
+		if resp := get("/metrics/prometheus", token); resp.Code != http.StatusUnauthorized {
+			t.Errorf("expected scrape with token %q to be unauthorized, got %d %s", token, resp.Code, resp.Body.String())
+		}
+	}
+	resp := get("/metrics/prometheus", "token")
+	if !strings.HasPrefix(resp.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("expected text content, got %s", resp.Header().Get("Content-Type"))
+	}
+	for _, expected := range []string{
+		`reserve_http_request_duration_seconds_count{method="GET",route="/prices/:base/:quote",status="200"} 2`,
+		`reserve_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`,
+	} {
+		if !strings.Contains(resp.Body.String(), expected) {
+			t.Errorf("expected metrics to contain %s, got:\n%s", expected, resp.Body.String())
+		}
+	}
+
+	s.metricsToken = ""
+	if resp = get("/metrics/prometheus", ""); resp.Code != http.StatusServiceUnavailable || !strings.Contains(resp.Body.String(), "not configured") {
+		t.Errorf("expected scrape without configured token to fail, got %s", resp.Body.String())
+	}
+}
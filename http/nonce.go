@@ -0,0 +1,113 @@
+package http
+
+import (
+	"container/heap"
+	"fmt"
+	"sync"
+)
+
+const (
+	// nonceWindow is how far in millisecond nonces of signed requests can be
+	// from server time.
+	nonceWindow = 30000
+	// DefaultNonceStoreCapacity is the max number of nonces kept in memory,
+	// nonces are kept until they are out of the window.
+	DefaultNonceStoreCapacity = 100000
+	// DefaultNonceStoreKeyCapacity is the max number of nonces of a key kept
+	// in memory, so a single key can't fill the store and block the others.
+	DefaultNonceStoreKeyCapacity = 10000
+)
+
+// NonceStore remembers nonces used by keys to reject replayed requests.
+// Stores shared by multiple servers reject replays to any of them.
+type NonceStore interface {
+	// UseNonce returns false if key used nonce before and it hasn't expired,
+	// otherwise it stores nonce to expire at expiresAt and returns true.
+	UseNonce(key string, nonce, expiresAt uint64) (bool, error)
+}
+
+type usedNonce struct {
+	key       string
+	nonce     uint64
+	expiresAt uint64
+}
+
+// nonceHeap is the min heap of used nonces by expiry.
+type nonceHeap []usedNonce
+
+func (self nonceHeap) Len() int            { return len(self) }
+func (self nonceHeap) Less(i, j int) bool  { return self[i].expiresAt < self[j].expiresAt }
+func (self nonceHeap) Swap(i, j int)       { self[i], self[j] = self[j], self[i] }
+func (self *nonceHeap) Push(x interface{}) { *self = append(*self, x.(usedNonce)) }
+func (self *nonceHeap) Pop() interface{} {
+	old := *self
+	item := old[len(old)-1]
+	*self = old[:len(old)-1]
+	return item
+}
+
+// MemoryNonceStore is the NonceStore of a single server. It keeps at most
+// capacity nonces and keyCapacity nonces of each key, new nonces are
+// rejected if it is full of unexpired ones rather than forgetting nonces
+// which could be replayed.
+//
+// Nonces are neither persisted nor shared: a request can be replayed to
+// another server, or to the same server after it restarts within the nonce
+// window. Servers sharing a SQL database use it as their NonceStore instead.
+type MemoryNonceStore struct {
+	mu          sync.Mutex
+	capacity    int
+	keyCapacity int
+	now         func() uint64
+	used        map[string]map[uint64]uint64
+	expiries    nonceHeap
+}
+
+// NewMemoryNonceStore creates a nonce store keeping at most capacity nonces
+// and keyCapacity nonces of each key.
+func NewMemoryNonceStore(capacity, keyCapacity int, now func() uint64) *MemoryNonceStore {
+	return &MemoryNonceStore{
+		capacity:    capacity,
+		keyCapacity: keyCapacity,
+		now:         now,
+		used:        map[string]map[uint64]uint64{},
+	}
+}
+
+// prune removes nonces expired at timepoint.
+func (self *MemoryNonceStore) prune(timepoint uint64) {
+	for len(self.expiries) > 0 && self.expiries[0].expiresAt <= timepoint {
+		item := heap.Pop(&self.expiries).(usedNonce)
+		nonces := self.used[item.key]
+		if nonces[item.nonce] == item.expiresAt {
+			delete(nonces, item.nonce)
+		}
+		if len(nonces) == 0 {
+			delete(self.used, item.key)
+		}
+	}
+}
+
+// UseNonce stores nonce of key if it is not used.
+func (self *MemoryNonceStore) UseNonce(key string, nonce, expiresAt uint64) (bool, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.prune(self.now())
+	if _, used := self.used[key][nonce]; used {
+		return false, nil
+	}
+	if len(self.used[key]) >= self.keyCapacity {
+		return false, fmt.Errorf("too many requests of key %s, %d unexpired nonces are kept", key, len(self.used[key]))
+	}
+	if len(self.expiries) >= self.capacity {
+		return false, fmt.Errorf("too many requests, %d unexpired nonces are kept", len(self.expiries))
+	}
+	nonces, ok := self.used[key]
+	if !ok {
+		nonces = map[uint64]uint64{}
+		self.used[key] = nonces
+	}
+	nonces[nonce] = expiresAt
+	heap.Push(&self.expiries, usedNonce{key: key, nonce: nonce, expiresAt: expiresAt})
+	return true, nil
+}
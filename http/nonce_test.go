@@ -0,0 +1,141 @@
+package http
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/data/storage"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMemoryNonceStore(t *testing.T) {
+	var now uint64 = 1000
+	store := NewMemoryNonceStore(4, 2, func() uint64 { return now })
+	use := func(key string, nonce, expiresAt uint64, expected bool) {
+		t.Helper()
+		ok, err := store.UseNonce(key, nonce, expiresAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != expected {
+			t.Errorf("using nonce %d of key %s: expected %t, got %t", nonce, key, expected, ok)
+		}
+	}
+	use("pricing", 1000, 2000, true)
+	use("pricing", 1000, 2000, false)
+	use("rebalance", 1000, 2000, true)
+	use("pricing", 1001, 1500, true)
+
+	// key is full of unexpired nonces, other keys are not affected
+	if _, err := store.UseNonce("pricing", 1002, 2000); err == nil {
+		t.Error("expected error using nonce of full key")
+	}
+	use("rebalance", 1001, 2000, true)
+	// store is full of unexpired nonces
+	if _, err := store.UseNonce("analytic", 1000, 2000); err == nil {
+		t.Error("expected error using nonce of full store")
+	}
+
+	now = 1500
+	use("pricing", 1001, 3000, true)
+	use("pricing", 1000, 2000, false)
+	now = 2000
+	use("pricing", 1000, 3000, true)
+	if len(store.expiries) != 2 || len(store.used) != 1 {
+		t.Errorf("expected expired nonces to be removed, got %v", store.used)
+	}
+}
+
+func TestMemoryNonceStoreConcurrentReplays(t *testing.T) {
+	store := NewMemoryNonceStore(DefaultNonceStoreCapacity, DefaultNonceStoreKeyCapacity, func() uint64 { return 1000 })
+	const replays = 100
+	var wg sync.WaitGroup
+	results := make(chan bool, 2*replays)
+	for i := 0; i < replays; i++ {
+		for _, key := range []string{"pricing", "rebalance"} {
+			wg.Add(1)
+			go func(key string) {
+				defer wg.Done()
+				ok, err := store.UseNonce(key, 1000, 2000)
+				if err != nil {
+					t.Error(err)
+				}
+				results <- ok
+			}(key)
+		}
+	}
+	wg.Wait()
+	close(results)
+	accepted := 0
+	for ok := range results {
+		if ok {
+			accepted++
+		}
+	}
+	if accepted != 2 {
+		t.Errorf("expected nonce accepted once per key, got %d", accepted)
+	}
+}
+
+func TestHTTPServerNonceReplay(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test_nonce_replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}()
+	st, err := storage.NewBoltStorage(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := HTTPServer{
+		authEnabled: true,
+		auth:        KNAuthentication{KNAdmin: "admin"},
+		apiKeys:     st,
+		nonces:      NewMemoryNonceStore(DefaultNonceStoreCapacity, DefaultNonceStoreKeyCapacity, func() uint64 { return 0 }),
+		r:           gin.Default(),
+	}
+	s.register()
+
+	req := newSignedRequest(t, http.MethodGet, "/api-keys", "admin", nil)
+	const replays = 20
+	var wg sync.WaitGroup
+	results := make(chan bool, replays)
+	for i := 0; i < replays; i++ {
+		// replays are copies of the same signed request
+		replay := req.WithContext(req.Context())
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := httptest.NewRecorder()
+			s.r.ServeHTTP(resp, replay)
+			var result struct {
+				Success bool `json:"success"`
+			}
+			if dErr := json.Unmarshal(resp.Body.Bytes(), &result); dErr != nil {
+				t.Error(dErr)
+			}
+			results <- result.Success
+		}()
+	}
+	wg.Wait()
+	close(results)
+	accepted := 0
+	for ok := range results {
+		if ok {
+			accepted++
+		}
+	}
+	if accepted != 1 {
+		t.Errorf("expected signed request accepted once, got %d", accepted)
+	}
+}
@@ -0,0 +1,178 @@
+// Package openapi reads the OpenAPI specification of the HTTP APIs, which is
+// checked against the registered routes and used to generate the API client.
+package openapi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// SignedSecurity is the security scheme of operations whose requests are
+	// signed by a secret.
+	SignedSecurity = "signed"
+	// InForm is the location of params given in the url encoded form body.
+	InForm = "form"
+	// formContentType is the content type of form bodies.
+	formContentType = "application/x-www-form-urlencoded"
+	// jsonContentType is the content type of JSON responses.
+	jsonContentType = "application/json"
+	// envelopeRef is the response of operations responding the envelope.
+	envelopeRef = "#/components/responses/Envelope"
+)
+
+// Spec is the part of an OpenAPI specification describing operations.
+type Spec struct {
+	Paths map[string]map[string]*Operation `yaml:"paths"`
+}
+
+// Operation is an API of a path and method.
+type Operation struct {
+	// Method and Path are set by Load, Path is in the OpenAPI format,
+	// e.g /prices/{base}/{quote}.
+	Method string `yaml:"-"`
+	Path   string `yaml:"-"`
+
+	OperationID string                `yaml:"operationId"`
+	Summary     string                `yaml:"summary"`
+	Description string                `yaml:"description"`
+	Tags        []string              `yaml:"tags"`
+	Security    []map[string][]string `yaml:"security"`
+	Permissions []string              `yaml:"x-permissions"`
+	Parameters  []Parameter           `yaml:"parameters"`
+	RequestBody *RequestBody          `yaml:"requestBody"`
+	Responses   map[string]Response   `yaml:"responses"`
+}
+
+// Parameter is a query or path param of an operation.
+type Parameter struct {
+	Name        string `yaml:"name"`
+	In          string `yaml:"in"`
+	Required    bool   `yaml:"required"`
+	Description string `yaml:"description"`
+	Schema      Schema `yaml:"schema"`
+}
+
+// Schema is the type of a param or body.
+type Schema struct {
+	Type        string            `yaml:"type"`
+	Format      string            `yaml:"format"`
+	Description string            `yaml:"description"`
+	Required    []string          `yaml:"required"`
+	Properties  map[string]Schema `yaml:"properties"`
+}
+
+// RequestBody is the body of an operation by content type.
+type RequestBody struct {
+	Content map[string]MediaType `yaml:"content"`
+}
+
+// Response is a response of an operation, either a reference to a shared
+// response or its content by content type.
+type Response struct {
+	Ref     string               `yaml:"$ref"`
+	Content map[string]MediaType `yaml:"content"`
+}
+
+// MediaType is the schema of a content type.
+type MediaType struct {
+	Schema Schema `yaml:"schema"`
+}
+
+// Load reads the specification at path.
+func Load(path string) (*Spec, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	spec := &Spec{}
+	if err = yaml.Unmarshal(raw, spec); err != nil {
+		return nil, fmt.Errorf("invalid specification %s: %s", path, err)
+	}
+	for path, operations := range spec.Paths {
+		for method, operation := range operations {
+			operation.Method = strings.ToUpper(method)
+			operation.Path = path
+		}
+	}
+	return spec, nil
+}
+
+// Operations returns all operations ordered by path and method.
+func (self *Spec) Operations() []*Operation {
+	result := []*Operation{}
+	for _, operations := range self.Paths {
+		for _, operation := range operations {
+			result = append(result, operation)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Path != result[j].Path {
+			return result[i].Path < result[j].Path
+		}
+		return result[i].Method < result[j].Method
+	})
+	return result
+}
+
+// Signed returns true if requests of the operation must be signed.
+func (self *Operation) Signed() bool {
+	for _, requirement := range self.Security {
+		if _, ok := requirement[SignedSecurity]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Params returns path and query params followed by form params ordered by
+// name, form params are in InForm.
+func (self *Operation) Params() []Parameter {
+	result := append([]Parameter{}, self.Parameters...)
+	if self.RequestBody == nil {
+		return result
+	}
+	form := self.RequestBody.Content[formContentType].Schema
+	names := []string{}
+	for name := range form.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		schema := form.Properties[name]
+		result = append(result, Parameter{
+			Name:        name,
+			In:          InForm,
+			Required:    contains(form.Required, name),
+			Description: schema.Description,
+			Schema:      schema,
+		})
+	}
+	return result
+}
+
+// JSON returns true if the operation responds JSON.
+func (self *Operation) JSON() bool {
+	for _, response := range self.Responses {
+		if response.Ref == envelopeRef {
+			return true
+		}
+		if _, ok := response.Content[jsonContentType]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package http
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/core"
+	"github.com/KyberNetwork/reserve-data/data"
+	"github.com/KyberNetwork/reserve-data/http/openapi"
+	"github.com/KyberNetwork/reserve-data/stat"
+	"github.com/gin-gonic/gin"
+)
+
+// ginParamRegexp matches path params of gin routes, e.g :exchangeid.
+var ginParamRegexp = regexp.MustCompile(`:(\w+)`)
+
+func TestOpenAPISpecCoversRoutes(t *testing.T) {
+	spec, err := openapi.Load("openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	operations := map[string]*openapi.Operation{}
+	ids := map[string]bool{}
+	for _, operation := range spec.Operations() {
+		operations[fmt.Sprintf("%s %s", operation.Method, operation.Path)] = operation
+		if ids[operation.OperationID] {
+			t.Errorf("operation ID %s is not unique", operation.OperationID)
+		}
+		ids[operation.OperationID] = true
+	}
+
+	// routes are registered if subsystems are not nil, they are not called
+	s := HTTPServer{app: &data.ReserveData{}, core: &core.ReserveCore{}, stat: &stat.ReserveStats{}, r: gin.New()}
+	s.register()
+	routes := s.r.Routes()
+	if len(routes) == 0 {
+		t.Fatal("expected registered routes")
+	}
+	for _, route := range routes {
+		key := fmt.Sprintf("%s %s", route.Method, ginParamRegexp.ReplaceAllString(route.Path, "{$1}"))
+		operation, ok := operations[key]
+		if !ok {
+			t.Errorf("route %s is missing from the OpenAPI spec", key)
+			continue
+		}
+		delete(operations, key)
+		// handlers are named like reserve-data/http.(*HTTPServer).GetRates-fm
+		handler := strings.TrimSuffix(route.Handler[strings.LastIndex(route.Handler, ".")+1:], "-fm")
+		if operation.OperationID != handler {
+			t.Errorf("operation ID of %s is %s, expected the handler name %s", key, operation.OperationID, handler)
+		}
+	}
+	for key := range operations {
+		t.Errorf("operation %s of the OpenAPI spec is not a registered route", key)
+	}
+}
+
+func TestOpenAPISpecParams(t *testing.T) {
+	spec, err := openapi.Load("openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, operation := range spec.Operations() {
+		names := map[string]bool{}
+		for _, param := range operation.Params() {
+			if names[param.Name] {
+				t.Errorf("param %s of %s is duplicated", param.Name, operation.OperationID)
+			}
+			names[param.Name] = true
+			if param.Name == "nonce" {
+				t.Errorf("nonce of %s is a param of the signed security, not the operation", operation.OperationID)
+			}
+			if param.In == "path" && !strings.Contains(operation.Path, "{"+param.Name+"}") {
+				t.Errorf("path param %s is not in path %s", param.Name, operation.Path)
+			}
+		}
+		if operation.Method == "GET" && operation.RequestBody != nil {
+			t.Errorf("GET operation %s has a request body", operation.OperationID)
+		}
+		if operation.Signed() && len(operation.Permissions) == 0 {
+			t.Errorf("signed operation %s has no permissions", operation.OperationID)
+		}
+	}
+}
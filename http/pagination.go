@@ -0,0 +1,174 @@
+package http
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/http/httputil"
+	ethereum "github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultPageLimit is the number of records of a page if limit is not given.
+	defaultPageLimit = 100
+	// maxPageLimit is the max number of records of a page.
+	maxPageLimit = 1000
+)
+
+var (
+	// activityPageParams are params of paginated activities.
+	activityPageParams = []string{"limit", "cursor", "action", "destination", "token", "status", "pending_only"}
+	// tradeLogPageParams are params of paginated trade logs.
+	tradeLogPageParams = []string{"limit", "cursor", "reserve", "token", "user", "wallet"}
+)
+
+// isPageRequest returns true if any of params is given, those requests are
+// answered with a page instead of all records of the time range.
+func isPageRequest(c *gin.Context, params []string) bool {
+	for _, param := range params {
+		if _, ok := c.GetQuery(param); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// pageTimeRange returns fromTime and toTime params in nanosecond. Pages have
+// no max time range, toTime is now if it is not given. Both are inclusive, so
+// toTime is the last nanosecond of its millisecond.
+func pageTimeRange(c *gin.Context) (uint64, uint64, bool) {
+	var fromTime uint64
+	if param := c.Query("fromTime"); param != "" {
+		var err error
+		if fromTime, err = strconv.ParseUint(param, 10, 64); err != nil {
+			httputil.ResponseFailure(c, httputil.WithReason(fmt.Sprintf("fromTime param is invalid: %s", err)))
+			return 0, 0, false
+		}
+	}
+	toTime, _ := strconv.ParseUint(c.Query("toTime"), 10, 64)
+	if toTime == 0 {
+		toTime = common.GetTimepoint()
+	}
+	return fromTime * 1000000, (toTime+1)*1000000 - 1, true
+}
+
+// pageLimit returns the limit param, defaultPageLimit if it is not given.
+func pageLimit(c *gin.Context) (int, bool) {
+	param := c.Query("limit")
+	if param == "" {
+		return defaultPageLimit, true
+	}
+	limit, err := strconv.Atoi(param)
+	if err != nil || limit <= 0 || limit > maxPageLimit {
+		httputil.ResponseFailure(c, httputil.WithReason(fmt.Sprintf("limit must be between 1 and %d", maxPageLimit)))
+		return 0, false
+	}
+	return limit, true
+}
+
+// getActivityPage responds a page of activities selected by params, which
+// are older than the cursor param.
+func (self *HTTPServer) getActivityPage(c *gin.Context) {
+	fromTime, toTime, ok := pageTimeRange(c)
+	if !ok {
+		return
+	}
+	limit, ok := pageLimit(c)
+	if !ok {
+		return
+	}
+	var cursor *common.ActivityID
+	if param := c.Query("cursor"); param != "" {
+		id, err := common.ParseActivityCursor(param)
+		if err != nil {
+			httputil.ResponseFailure(c, httputil.WithError(err))
+			return
+		}
+		cursor = &id
+	}
+	pendingOnly := false
+	if param := c.Query("pending_only"); param != "" {
+		var err error
+		if pendingOnly, err = strconv.ParseBool(param); err != nil {
+			httputil.ResponseFailure(c, httputil.WithReason(fmt.Sprintf("pending_only param is invalid: %s", err)))
+			return
+		}
+	}
+	filter := common.ActivityFilter{
+		FromTime:    fromTime,
+		ToTime:      toTime,
+		Action:      c.Query("action"),
+		Destination: c.Query("destination"),
+		Token:       c.Query("token"),
+		Status:      c.Query("status"),
+		PendingOnly: pendingOnly,
+	}
+	page, err := self.app.GetActivityPage(filter, cursor, limit)
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(page))
+}
+
+// addressParam returns the address of param, the zero address if it is not
+// given. The token param can also be a token ID.
+func addressParam(c *gin.Context, param string) (ethereum.Address, bool) {
+	value := c.Query(param)
+	switch {
+	case value == "":
+		return ethereum.Address{}, true
+	case ethereum.IsHexAddress(value):
+		return ethereum.HexToAddress(value), true
+	case param == "token":
+		if token, err := common.GetNetworkToken(value); err == nil {
+			return ethereum.HexToAddress(token.Address), true
+		}
+	}
+	httputil.ResponseFailure(c, httputil.WithReason(fmt.Sprintf("%s param is invalid: %s", param, value)))
+	return ethereum.Address{}, false
+}
+
+// getTradeLogPage responds a page of trade logs selected by params, which
+// are older than the cursor param.
+func (self *HTTPServer) getTradeLogPage(c *gin.Context) {
+	fromTime, toTime, ok := pageTimeRange(c)
+	if !ok {
+		return
+	}
+	limit, ok := pageLimit(c)
+	if !ok {
+		return
+	}
+	var cursor *uint64
+	if param := c.Query("cursor"); param != "" {
+		timestamp, err := common.ParseTradeLogCursor(param)
+		if err != nil {
+			httputil.ResponseFailure(c, httputil.WithError(err))
+			return
+		}
+		cursor = &timestamp
+	}
+	filter := common.TradeLogFilter{FromTime: fromTime, ToTime: toTime}
+	for _, param := range []struct {
+		name    string
+		address *ethereum.Address
+	}{
+		{"reserve", &filter.Reserve},
+		{"token", &filter.Token},
+		{"user", &filter.User},
+		{"wallet", &filter.Wallet},
+	} {
+		if *param.address, ok = addressParam(c, param.name); !ok {
+			return
+		}
+	}
+	page, err := self.stat.GetTradeLogPage(filter, cursor, limit)
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(page))
+}
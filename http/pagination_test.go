@@ -0,0 +1,113 @@
+package http
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/data"
+	"github.com/KyberNetwork/reserve-data/data/storage"
+	"github.com/gin-gonic/gin"
+)
+
+func TestHTTPServerActivityPages(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test_activity_pages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}()
+	st, err := storage.NewBoltStorage(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// activity IDs are in nanosecond, query times in millisecond
+	for i, token := range []string{"KNC", "OMG", "KNC", "KNC"} {
+		timepoint := uint64(i+1) * 1000000000
+		id := common.NewActivityID(timepoint, token)
+		if err = st.Record("deposit", id, "binance", map[string]interface{}{"token": token}, map[string]interface{}{}, "", "submitted", timepoint/1000000); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := HTTPServer{app: data.NewReserveData(st, nil, nil, nil, nil, nil), r: gin.Default()}
+	s.r.GET("/activities", s.GetActivities)
+
+	get := func(params url.Values) (bool, json.RawMessage) {
+		req, gErr := http.NewRequest(http.MethodGet, "/activities?"+params.Encode(), nil)
+		if gErr != nil {
+			t.Fatal(gErr)
+		}
+		resp := httptest.NewRecorder()
+		s.r.ServeHTTP(resp, req)
+		result := struct {
+			Success bool            `json:"success"`
+			Data    json.RawMessage `json:"data"`
+		}{}
+		if gErr = json.Unmarshal(resp.Body.Bytes(), &result); gErr != nil {
+			t.Fatal(gErr)
+		}
+		return result.Success, result.Data
+	}
+	getPage := func(params url.Values) common.ActivityPage {
+		ok, data := get(params)
+		page := common.ActivityPage{}
+		if !ok {
+			t.Fatalf("expected a page of %v", params)
+		}
+		if uErr := json.Unmarshal(data, &page); uErr != nil {
+			t.Fatal(uErr)
+		}
+		return page
+	}
+
+	page := getPage(url.Values{"token": {"KNC"}, "limit": {"2"}, "toTime": {"5000"}})
+	if len(page.Activities) != 2 || page.Activities[0].ID.Timepoint != 4000000000 || page.NextCursor == "" {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+	page = getPage(url.Values{"token": {"KNC"}, "limit": {"2"}, "toTime": {"5000"}, "cursor": {page.NextCursor}})
+	if len(page.Activities) != 1 || page.Activities[0].ID.Timepoint != 1000000000 || page.NextCursor != "" {
+		t.Fatalf("unexpected last page %+v", page)
+	}
+	if page = getPage(url.Values{"fromTime": {"2000"}, "toTime": {"3000"}, "pending_only": {"true"}}); len(page.Activities) != 2 {
+		t.Errorf("expected activities in the time range, got %+v", page)
+	}
+
+	for _, params := range []url.Values{
+		{"limit": {"0"}},
+		{"limit": {"1001"}},
+		{"cursor": {"not a cursor"}},
+		{"pending_only": {"maybe"}},
+	} {
+		if ok, _ := get(params); ok {
+			t.Errorf("expected params %v to be rejected", params)
+		}
+	}
+
+	// requests without page params get all activities of the time range
+	ok, body := get(url.Values{"toTime": {"5000"}})
+	records := []common.ActivityRecord{}
+	if !ok || json.Unmarshal(body, &records) != nil || len(records) != 4 {
+		t.Errorf("expected all activities, got %s", body)
+	}
+
+	// toTime includes activities in the last nanosecond of its millisecond
+	last := common.NewActivityID(5000999999, "EOS")
+	if err = st.Record("deposit", last, "binance", map[string]interface{}{"token": "EOS"}, map[string]interface{}{}, "", "submitted", 5000); err != nil {
+		t.Fatal(err)
+	}
+	if page = getPage(url.Values{"token": {"EOS"}, "toTime": {"5000"}, "limit": {"10"}}); len(page.Activities) != 1 {
+		t.Errorf("expected activity in the last millisecond, got %+v", page)
+	}
+	if page = getPage(url.Values{"token": {"EOS"}, "toTime": {"4999"}, "limit": {"10"}}); len(page.Activities) != 0 {
+		t.Errorf("expected no activity after toTime, got %+v", page)
+	}
+}
@@ -1,5 +1,9 @@
 package http
 
+import (
+	"fmt"
+)
+
 type Permission int
 
 const (
@@ -7,4 +11,36 @@ const (
 	RebalancePermission                     // can do everything except configure setting
 	ConfigurePermission                     // can read data and configure setting, cannot set rates, deposit, withdraw, trade, cancel activities
 	ConfirmConfPermission                   // can read data and confirm configuration proposal
+	AdminPermission                         // can manage API keys
 )
+
+// permissionNames are the names of permissions granted to API keys.
+var permissionNames = map[Permission]string{
+	ReadOnlyPermission:    "read_only",
+	RebalancePermission:   "rebalance",
+	ConfigurePermission:   "configure",
+	ConfirmConfPermission: "confirm_configuration",
+	AdminPermission:       "admin",
+}
+
+// ParsePermission returns the permission of name.
+func ParsePermission(name string) (Permission, error) {
+	for perm, permName := range permissionNames {
+		if permName == name {
+			return perm, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown permission %s", name)
+}
+
+func (self Permission) String() string {
+	if name, ok := permissionNames[self]; ok {
+		return name
+	}
+	return fmt.Sprintf("permission(%d)", int(self))
+}
+
+// MarshalText encodes the permission as its name.
+func (self Permission) MarshalText() ([]byte, error) {
+	return []byte(self.String()), nil
+}
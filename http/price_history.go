@@ -0,0 +1,35 @@
+package http
+
+import (
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/http/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultPriceHistoryResolution is the resolution of /price-history if it isn't given.
+const defaultPriceHistoryResolution = "1m"
+
+// PriceHistory returns the price time series of a pair between fromTime and
+// toTime, in millisecond. The resolution is raw, 1m, 5m or 1h, candles of
+// all exchanges are returned unless exchange is given.
+func (self *HTTPServer) PriceHistory(c *gin.Context) {
+	fromTime, toTime, ok := self.ValidateTimeInput(c)
+	if !ok {
+		return
+	}
+	pair, err := common.NewTokenPair(c.Query("base"), c.Query("quote"))
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithReason("Token pair is not supported"))
+		return
+	}
+	resolution := c.Query("resolution")
+	if resolution == "" {
+		resolution = defaultPriceHistoryResolution
+	}
+	data, err := self.app.GetPriceHistory(pair.PairID(), common.ExchangeID(c.Query("exchange")), resolution, fromTime, toTime)
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(data))
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"fmt"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/http/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+// pricingTokens returns the given token, or all internal tokens listed in pricing contract
+// if tokenID is empty.
+func pricingTokens(tokenID string) ([]common.Token, error) {
+	if tokenID != "" {
+		token, err := common.GetInternalToken(tokenID)
+		if err != nil {
+			return nil, err
+		}
+		return []common.Token{token}, nil
+	}
+	tokens := []common.Token{}
+	for _, token := range common.InternalTokens() {
+		if !token.IsETH() {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+// GetPricingState returns what pricing contract currently holds for each token:
+// base rates, compact data and its position, validity block, step functions,
+// imbalance limits and listing flags, in both raw and human friendly format.
+// An optional token param limits the result to one token.
+func (self *HTTPServer) GetPricingState(c *gin.Context) {
+	postForm, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	tokens, err := pricingTokens(postForm.Get("token"))
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	result := map[string]common.TokenPricingStateResponse{}
+	for _, token := range tokens {
+		state, err := self.core.GetTokenPricingState(token)
+		if err != nil {
+			httputil.ResponseFailure(c, httputil.WithReason(fmt.Sprintf("cannot get pricing state of %s: %s", token.ID, err)))
+			return
+		}
+		result[token.ID] = state.ToResponse()
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(result))
+}
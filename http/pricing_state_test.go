@@ -0,0 +1,96 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/http/httputil"
+)
+
+func newAssertPricingState(expectedTokens []string) assertFn {
+	return func(t *testing.T, resp *httptest.ResponseRecorder) {
+		t.Helper()
+		decoded := struct {
+			Success bool                                        `json:"success"`
+			Data    map[string]common.TokenPricingStateResponse `json:"data"`
+		}{}
+		if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Success {
+			t.Fatalf("expected success response")
+		}
+		tokens := []string{}
+		for token := range decoded.Data {
+			tokens = append(tokens, token)
+		}
+		sort.Strings(tokens)
+		if !reflect.DeepEqual(tokens, expectedTokens) {
+			t.Fatalf("expected pricing state of %v, got %v", expectedTokens, tokens)
+		}
+		for token, state := range decoded.Data {
+			if !state.Listed || !state.Enabled || state.Buy != 500 || state.Sell != 0.002 ||
+				state.RawBaseBuy.Cmp(common.EthToWei(500)) != 0 || state.ValidUntilBlock != 110 {
+				t.Errorf("unexpected pricing state of %s: %+v", token, state)
+			}
+		}
+	}
+}
+
+func TestHTTPServerPricingState(t *testing.T) {
+	const pricingState = "/pricing-state"
+	common.RegisterInternalActiveToken(common.Token{ID: "ETH", Decimal: 18})
+	common.RegisterInternalActiveToken(common.Token{ID: "KNC", Decimal: 18})
+
+	bc := &testPricingStateBlockchain{}
+	s, cleanup := newTestPricingStateServer(t, bc)
+	defer cleanup()
+
+	// ETH is not listed in pricing contract, tokens registered by other tests
+	// are listed too, some of them more than once
+	listed := map[string]bool{}
+	allTokens := []string{}
+	for _, token := range common.InternalTokens() {
+		if !token.IsETH() && !listed[token.ID] {
+			listed[token.ID] = true
+			allTokens = append(allTokens, token.ID)
+		}
+	}
+	sort.Strings(allTokens)
+
+	var tests = []testCase{
+		{
+			msg:      "unknown token",
+			endpoint: pricingState + "?token=XXX",
+			method:   http.MethodGet,
+			assert:   httputil.ExpectFailure,
+		},
+		{
+			msg:      "pricing state of a token",
+			endpoint: pricingState + "?token=KNC",
+			method:   http.MethodGet,
+			assert:   newAssertPricingState([]string{"KNC"}),
+		},
+		{
+			msg:      "pricing state of all tokens",
+			endpoint: pricingState,
+			method:   http.MethodGet,
+			assert:   newAssertPricingState(allTokens),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.msg, func(t *testing.T) { testHTTPRequest(t, tc, s.r) })
+	}
+
+	// the pricing state is always the latest one
+	for _, block := range bc.atBlocks {
+		if block != 0 {
+			t.Errorf("expected current pricing states, got state at block %d", block)
+		}
+	}
+}
@@ -1,8 +1,7 @@
 package http
 
 import (
-	"encoding/json"
-
+	"github.com/KyberNetwork/reserve-data/common"
 	"github.com/KyberNetwork/reserve-data/http/httputil"
 	"github.com/KyberNetwork/reserve-data/metric"
 	"github.com/gin-gonic/gin"
@@ -31,24 +30,7 @@ func (self *HTTPServer) SetPWIEquationV2(c *gin.Context) {
 		return
 	}
 
-	data := []byte(postForm.Get(dataPostFormKey))
-	if len(data) > MAX_DATA_SIZE {
-		httputil.ResponseFailure(c, httputil.WithError(errDataSizeExceed))
-		return
-	}
-
-	var input metric.PWIEquationRequestV2
-	if err := json.Unmarshal(data, &input); err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
-		return
-	}
-	if err := input.Validate(); err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
-		return
-	}
-
-	if err := self.metric.StorePendingPWIEquationV2(data); err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
+	if _, _, ok = self.proposeConfig(c, common.PWIEquationV2Config, []byte(postForm.Get(dataPostFormKey)), 0); !ok {
 		return
 	}
 	httputil.ResponseSuccess(c)
@@ -60,9 +42,8 @@ func (self *HTTPServer) GetPendingPWIEquationV2(c *gin.Context) {
 	if !ok {
 		return
 	}
-	data, err := self.metric.GetPendingPWIEquationV2()
-	if err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
+	data := metric.PWIEquationRequestV2{}
+	if _, ok = self.pendingConfig(c, common.PWIEquationV2Config, &data); !ok {
 		return
 	}
 	httputil.ResponseSuccess(c, httputil.WithData(data))
@@ -77,12 +58,9 @@ func (self *HTTPServer) ConfirmPWIEquationV2(c *gin.Context) {
 		return
 	}
 	postData := postForm.Get(dataPostFormKey)
-	err := self.metric.StorePWIEquationV2(postData)
-	if err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
-		return
-	}
-	httputil.ResponseSuccess(c)
+	self.confirmPendingConfig(c, common.PWIEquationV2Config, sameConfig([]byte(postData), func() interface{} {
+		return &metric.PWIEquationRequestV2{}
+	}))
 }
 
 // RejectPWIEquationV2 rejects the PWI equations request and removes
@@ -93,9 +71,5 @@ func (self *HTTPServer) RejectPWIEquationV2(c *gin.Context) {
 		return
 	}
 
-	if err := self.metric.RemovePendingPWIEquationV2(); err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
-		return
-	}
-	httputil.ResponseSuccess(c)
+	self.rejectPendingConfig(c, common.PWIEquationV2Config)
 }
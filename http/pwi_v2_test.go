@@ -172,6 +172,8 @@ func TestHTTPServerPWIEquationV2(t *testing.T) {
 		app:         data.NewReserveData(st, nil, nil, nil, nil, nil),
 		core:        core.NewReserveCore(nil, st, ethereum.Address{}),
 		metric:      st,
+		configs:     st,
+		configTypes: DefaultConfigRegistry(),
 		authEnabled: false,
 		r:           gin.Default()}
 	s.register()
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/common/ratelimit"
+	"github.com/KyberNetwork/reserve-data/http/httputil"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var limitedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "reserve_http_requests_limited_total",
+	Help: "Number of HTTP requests rejected by limits by route and limit, which is body_size, time_range or rate.",
+}, []string{"route", "limit"})
+
+// limitKey returns the key limits of a request are counted by. It is the key
+// of the principal if the request is signed by a valid signature, otherwise
+// the client IP so unsigned requests and requests with a spoofed key name
+// can't drain the budget of other keys. The nonce is not used here, it is
+// still checked once the request is authenticated by its handler, which
+// reuses the result of verifying the signature.
+func (self *HTTPServer) limitKey(c *gin.Context) string {
+	if self.authEnabled && c.GetHeader("signed") != "" {
+		if err := c.Request.ParseForm(); err == nil {
+			if principal, err := self.authenticate(c); err == nil {
+				return principal.Key
+			}
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// inTimeRange responds failure and returns false if the fromTime and toTime
+// params of a query of route span longer than its max time range. toTime is
+// now if it is not given, invalid params are left to the handler to report.
+func (self *HTTPServer) inTimeRange(c *gin.Context, route string) bool {
+	maxRange := self.limiter.MaxTimeRange(route)
+	if maxRange <= 0 {
+		return true
+	}
+	fromTime, err := strconv.ParseUint(c.Query("fromTime"), 10, 64)
+	if err != nil {
+		return true
+	}
+	toTime := common.GetTimepoint()
+	if c.Query("toTime") != "" {
+		if toTime, err = strconv.ParseUint(c.Query("toTime"), 10, 64); err != nil {
+			return true
+		}
+	}
+	maxRangeMs := uint64(maxRange / time.Millisecond)
+	if toTime > fromTime && toTime-fromTime > maxRangeMs {
+		limitedRequests.WithLabelValues(route, "time_range").Inc()
+		httputil.ResponseFailure(c, httputil.WithReason(
+			fmt.Sprintf("time range is too broad, it must be at most %d milliseconds", maxRangeMs)))
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// limitRequests rejects requests exceeding the limits of the limiter: bodies
+// larger than the max body size with 413, queries of longer time ranges than
+// routes allow, and requests over rate limits or concurrency caps with 429
+// and a Retry-After header.
+func (self *HTTPServer) limitRequests(c *gin.Context) {
+	if self.limiter == nil {
+		c.Next()
+		return
+	}
+	route, ok := self.routes[routeKey(c.Request.Method, c.HandlerName())]
+	if !ok {
+		route = unmatchedRoute
+	}
+	if maxSize := self.limiter.MaxBodySize(); maxSize > 0 {
+		if c.Request.ContentLength > maxSize {
+			limitedRequests.WithLabelValues(route, "body_size").Inc()
+			httputil.ResponseFailureWithStatus(c, http.StatusRequestEntityTooLarge,
+				httputil.WithReason(fmt.Sprintf("request body is larger than %d bytes", maxSize)))
+			c.Abort()
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+	}
+	if !ok {
+		c.Next()
+		return
+	}
+	if !self.inTimeRange(c, route) {
+		return
+	}
+	release, err := self.limiter.Acquire(self.limitKey(c), route)
+	if err != nil {
+		if limitErr, isLimit := err.(*ratelimit.Error); isLimit {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(limitErr.RetryAfter.Seconds()))))
+		}
+		limitedRequests.WithLabelValues(route, "rate").Inc()
+		httputil.ResponseFailureWithStatus(c, http.StatusTooManyRequests, httputil.WithError(err))
+		c.Abort()
+		return
+	}
+	defer release()
+	c.Next()
+}
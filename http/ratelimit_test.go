@@ -0,0 +1,129 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KyberNetwork/reserve-data/common/ratelimit"
+	"github.com/KyberNetwork/reserve-data/http/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+func TestHTTPServerLimitRequests(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := HTTPServer{
+		authEnabled: true,
+		auth:        KNAuthentication{KNReadOnly: "readonly", KNAdmin: "admin"},
+		limiter: ratelimit.NewLimiter(ratelimit.Limits{
+			Routes: map[string]ratelimit.RouteLimit{
+				"/heavy": {PerKey: ratelimit.Limit{Rate: 1}, MaxTimeRange: time.Hour},
+			},
+			MaxBodySize: 64,
+		}, func() time.Time { return now }),
+		r: gin.New(),
+	}
+	s.r.Use(s.limitRequests)
+	s.r.GET("/heavy", func(c *gin.Context) {
+		httputil.ResponseSuccess(c)
+	})
+	s.r.POST("/store", func(c *gin.Context) {
+		if err := c.Request.ParseForm(); err != nil {
+			httputil.ResponseFailure(c, httputil.WithError(err))
+			return
+		}
+		httputil.ResponseSuccess(c)
+	})
+	s.indexRoutes()
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		s.r.ServeHTTP(resp, req)
+		return resp
+	}
+	params := map[string]string{"fromTime": "0", "toTime": "3600000"}
+
+	if resp := serve(newSignedRequest(t, http.MethodGet, "/heavy", "readonly", params)); resp.Code != http.StatusOK {
+		t.Fatalf("expected first request to be admitted, got %d %s", resp.Code, resp.Body.String())
+	}
+	resp := serve(newSignedRequest(t, http.MethodGet, "/heavy", "readonly", params))
+	if resp.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected request over rate limit to be rejected with 429, got %d %s", resp.Code, resp.Body.String())
+	}
+	if retryAfter := resp.Header().Get("Retry-After"); retryAfter != "1" {
+		t.Errorf("expected to retry after 1 second, got %q", retryAfter)
+	}
+	// other keys have their own budget, unsigned requests are limited by client IP
+	if resp = serve(newSignedRequest(t, http.MethodGet, "/heavy", "admin", params)); resp.Code != http.StatusOK {
+		t.Errorf("expected request of another key to be admitted, got %d %s", resp.Code, resp.Body.String())
+	}
+	unsigned := func() *http.Request {
+		req, err := http.NewRequest(http.MethodGet, "/heavy?fromTime=0&toTime=3600000", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.RemoteAddr = "10.0.0.1:1234"
+		return req
+	}
+	if resp = serve(unsigned()); resp.Code != http.StatusOK {
+		t.Errorf("expected unsigned request to be admitted, got %d %s", resp.Code, resp.Body.String())
+	}
+	if resp = serve(unsigned()); resp.Code != http.StatusTooManyRequests {
+		t.Errorf("expected unsigned request over rate limit to be rejected, got %d %s", resp.Code, resp.Body.String())
+	}
+	now = now.Add(time.Second)
+	if resp = serve(newSignedRequest(t, http.MethodGet, "/heavy", "readonly", params)); resp.Code != http.StatusOK {
+		t.Errorf("expected request to be admitted after refill, got %d %s", resp.Code, resp.Body.String())
+	}
+
+	now = now.Add(time.Second)
+	resp = serve(newSignedRequest(t, http.MethodGet, "/heavy", "readonly", map[string]string{"fromTime": "0", "toTime": "3600001"}))
+	if !strings.Contains(resp.Body.String(), "time range is too broad") {
+		t.Errorf("expected query over max time range to be rejected, got %s", resp.Body.String())
+	}
+
+	if resp = serve(newSignedRequest(t, http.MethodPost, "/store", "admin", map[string]string{"msg": "ok"})); resp.Code != http.StatusOK {
+		t.Errorf("expected small body to be admitted, got %d %s", resp.Code, resp.Body.String())
+	}
+	resp = serve(newSignedRequest(t, http.MethodPost, "/store", "admin", map[string]string{"msg": strings.Repeat("a", 64)}))
+	if resp.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected large body to be rejected with 413, got %d %s", resp.Code, resp.Body.String())
+	}
+}
+
+// countingAuthentication counts verified signatures.
+type countingAuthentication struct {
+	Authentication
+	count int
+}
+
+func (self *countingAuthentication) Authenticate(keyName, signed, message string) (Principal, error) {
+	self.count++
+	return self.Authentication.Authenticate(keyName, signed, message)
+}
+
+func TestHTTPServerLimitRequestsAuthenticatesOnce(t *testing.T) {
+	auth := &countingAuthentication{Authentication: KNAuthentication{KNReadOnly: "readonly"}}
+	s := HTTPServer{
+		authEnabled: true,
+		auth:        auth,
+		limiter: ratelimit.NewLimiter(ratelimit.Limits{
+			Routes: map[string]ratelimit.RouteLimit{"/heavy": {PerKey: ratelimit.Limit{Rate: 1}}},
+		}, time.Now),
+		r: gin.New(),
+	}
+	s.r.Use(s.limitRequests)
+	s.r.GET("/heavy", func(c *gin.Context) {
+		if _, ok := s.Authenticated(c, []string{}, []Permission{ReadOnlyPermission}); ok {
+			httputil.ResponseSuccess(c)
+		}
+	})
+	s.indexRoutes()
+	resp := httptest.NewRecorder()
+	s.r.ServeHTTP(resp, newSignedRequest(t, http.MethodGet, "/heavy", "readonly", nil))
+	httputil.ExpectSuccess(t, resp)
+	if auth.count != 1 {
+		t.Errorf("expected signature to be verified once, got %d", auth.count)
+	}
+}
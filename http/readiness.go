@@ -0,0 +1,50 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/http/httputil"
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz returns success as long as the process serves requests, it
+// doesn't check any subsystem.
+func (self *HTTPServer) Healthz(c *gin.Context) {
+	httputil.ResponseSuccess(c, httputil.WithField("timestamp", common.GetTimepoint()))
+}
+
+// Readyz checks the databases, the node, the freshness of fetched data, the
+// exchanges and the stat log fetcher. It responds 503 with names of failed
+// checks if any of them fails, so orchestrators can stop routing requests
+// to the instance. Details of checks are only served by ReadyzDetails, which
+// requires authentication.
+func (self *HTTPServer) Readyz(c *gin.Context) {
+	if self.readiness == nil {
+		httputil.ResponseFailureWithStatus(c, http.StatusServiceUnavailable, httputil.WithReason("readiness checks are not configured"))
+		return
+	}
+	report := self.readiness.Check()
+	if !report.Ready {
+		httputil.ResponseFailureWithStatus(c, http.StatusServiceUnavailable,
+			httputil.WithReason(fmt.Sprintf("failed checks: %s", strings.Join(report.Failed(), ", "))),
+		)
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithField("timestamp", report.Timestamp))
+}
+
+// ReadyzDetails returns results of all readiness checks with their details.
+func (self *HTTPServer) ReadyzDetails(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission, AdminPermission})
+	if !ok {
+		return
+	}
+	if self.readiness == nil {
+		httputil.ResponseFailure(c, httputil.WithReason("readiness checks are not configured"))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(self.readiness.Check()))
+}
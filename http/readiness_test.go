@@ -0,0 +1,60 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/common/readiness"
+	"github.com/gin-gonic/gin"
+)
+
+func TestHTTPServerReadyz(t *testing.T) {
+	checker := readiness.NewChecker(readiness.DefaultThresholds())
+	s := HTTPServer{r: gin.Default(), readiness: checker}
+	s.r.GET("/healthz", s.Healthz)
+	s.r.GET("/readyz", s.Readyz)
+	s.r.GET("/readyz/details", s.ReadyzDetails)
+
+	get := func(path string) (int, map[string]interface{}) {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := httptest.NewRecorder()
+		s.r.ServeHTTP(resp, req)
+		result := map[string]interface{}{}
+		if err = json.Unmarshal(resp.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Code, result
+	}
+
+	checker.Add("node", func() (interface{}, error) { return nil, nil })
+	if code, result := get("/readyz"); code != http.StatusOK || result["success"] != true {
+		t.Errorf("expected instance to be ready, got %d %v", code, result)
+	}
+
+	checker.Add("data", func() (interface{}, error) { return nil, errors.New("stale data: rates") })
+	code, result := get("/readyz")
+	if code != http.StatusServiceUnavailable || result["reason"] != "failed checks: data" {
+		t.Errorf("expected instance not to be ready, got %d %v", code, result)
+	}
+	if _, ok := result["data"]; ok {
+		t.Errorf("expected no details of checks without authentication, got %v", result["data"])
+	}
+
+	code, result = get("/readyz/details")
+	if code != http.StatusOK || result["success"] != true {
+		t.Fatalf("expected details of checks, got %d %v", code, result)
+	}
+	if checks := result["data"].(map[string]interface{})["checks"].([]interface{}); len(checks) != 2 {
+		t.Errorf("expected results of all checks, got %v", checks)
+	}
+
+	if code, result = get("/healthz"); code != http.StatusOK || result["success"] != true {
+		t.Errorf("expected instance to be alive, got %d %v", code, result)
+	}
+}
@@ -1,8 +1,7 @@
 package http
 
 import (
-	"encoding/json"
-
+	"github.com/KyberNetwork/reserve-data/common"
 	"github.com/KyberNetwork/reserve-data/http/httputil"
 	"github.com/KyberNetwork/reserve-data/metric"
 	"github.com/gin-gonic/gin"
@@ -15,22 +14,7 @@ func (h *HTTPServer) SetRebalanceQuadratic(c *gin.Context) {
 	if !ok {
 		return
 	}
-	value := []byte(postForm.Get("value"))
-	if len(value) > MAX_DATA_SIZE {
-		httputil.ResponseFailure(c, httputil.WithReason(errDataSizeExceed.Error()))
-		return
-	}
-	var rq metric.RebalanceQuadraticRequest
-	if err := json.Unmarshal(value, &rq); err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
-		return
-	}
-	if err := rq.Validate(); err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
-		return
-	}
-	if err := h.metric.StorePendingRebalanceQuadratic(value); err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
+	if _, _, ok = h.proposeConfig(c, common.RebalanceQuadraticConfig, []byte(postForm.Get("value")), 0); !ok {
 		return
 	}
 	httputil.ResponseSuccess(c)
@@ -44,9 +28,8 @@ func (h *HTTPServer) GetPendingRebalanceQuadratic(c *gin.Context) {
 		return
 	}
 
-	data, err := h.metric.GetPendingRebalanceQuadratic()
-	if err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
+	data := metric.RebalanceQuadraticRequest{}
+	if _, ok = h.pendingConfig(c, common.RebalanceQuadraticConfig, &data); !ok {
 		return
 	}
 	httputil.ResponseSuccess(c, httputil.WithData(data))
@@ -63,12 +46,9 @@ func (h *HTTPServer) ConfirmRebalanceQuadratic(c *gin.Context) {
 		httputil.ResponseFailure(c, httputil.WithReason(errDataSizeExceed.Error()))
 		return
 	}
-	err := h.metric.ConfirmRebalanceQuadratic(value)
-	if err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
-		return
-	}
-	httputil.ResponseSuccess(c)
+	h.confirmPendingConfig(c, common.RebalanceQuadraticConfig, sameConfig(value, func() interface{} {
+		return &metric.RebalanceQuadraticRequest{}
+	}))
 }
 
 //RejectRebalanceQuadratic reject pending configuration for rebalance quadratic function
@@ -77,11 +57,7 @@ func (h *HTTPServer) RejectRebalanceQuadratic(c *gin.Context) {
 	if !ok {
 		return
 	}
-	if err := h.metric.RemovePendingRebalanceQuadratic(); err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
-		return
-	}
-	httputil.ResponseSuccess(c)
+	h.rejectPendingConfig(c, common.RebalanceQuadraticConfig)
 }
 
 //GetRebalanceQuadratic return current confirmed rebalance quadratic equation
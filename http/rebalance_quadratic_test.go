@@ -96,6 +96,8 @@ func TestHTTPServerRebalanceQuadratic(t *testing.T) {
 		app:         data.NewReserveData(rqStorage, nil, nil, nil, nil, nil),
 		core:        core.NewReserveCore(nil, rqStorage, ethereum.Address{}),
 		metric:      rqStorage,
+		configs:     rqStorage,
+		configTypes: DefaultConfigRegistry(),
 		authEnabled: false,
 		r:           gin.Default()}
 	s.register()
@@ -13,6 +13,10 @@ import (
 
 	"github.com/KyberNetwork/reserve-data"
 	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/common/backup"
+	"github.com/KyberNetwork/reserve-data/common/event"
+	"github.com/KyberNetwork/reserve-data/common/ratelimit"
+	"github.com/KyberNetwork/reserve-data/common/readiness"
 	"github.com/KyberNetwork/reserve-data/http/httputil"
 	"github.com/KyberNetwork/reserve-data/metric"
 	ethereum "github.com/ethereum/go-ethereum/common"
@@ -44,6 +48,27 @@ type HTTPServer struct {
 	authEnabled bool
 	auth        Authentication
 	r           *gin.Engine
+	backuper    *backup.Backuper
+	// apiKeys stores API keys managed by admin APIs, nil if they are not supported
+	apiKeys APIKeyStorage
+	// audit stores audit records of mutating APIs, nil if they are not audited
+	audit AuditStorage
+	// nonces rejects replayed requests, nil if they are not checked
+	nonces NonceStore
+	// events publishes events of stored data, nil if core is not enabled
+	events *event.Hub
+	// metricsToken is the bearer token of Prometheus scrapes
+	metricsToken string
+	// routes maps handlers to path patterns of routes, see indexRoutes
+	routes map[string]string
+	// readiness checks subsystems for /readyz, nil if it is not configured
+	readiness *readiness.Checker
+	// limiter limits requests by keys and routes, nil if they are not limited
+	limiter *ratelimit.Limiter
+	// configs stores proposals and versions of configs, nil if they are not supported
+	configs ConfigStorage
+	// configTypes are the config types managed by the config APIs
+	configTypes *ConfigRegistry
 }
 
 func getTimePoint(c *gin.Context, useDefault bool) uint64 {
@@ -78,7 +103,7 @@ func IsIntime(nonce string) bool {
 		return false
 	}
 	difference := nonceInt - int64(serverTime)
-	if difference < -30000 || difference > 30000 {
+	if difference < -nonceWindow || difference > nonceWindow {
 		log.Printf("IsIntime returns false, nonce: %d, serverTime: %d, difference: %d", nonceInt, int64(serverTime), difference)
 		return false
 	}
@@ -108,6 +133,7 @@ func (self *HTTPServer) Authenticated(c *gin.Context, requiredParams []string, p
 	}
 
 	if !self.authEnabled {
+		markAudited(c)
 		return c.Request.Form, true
 	}
 
@@ -125,19 +151,94 @@ func (self *HTTPServer) Authenticated(c *gin.Context, requiredParams []string, p
 		}
 	}
 
-	signed := c.GetHeader("signed")
-	message := c.Request.Form.Encode()
-	userPerms := self.auth.GetPermission(signed, message)
-	if eligible(userPerms, perms) {
-		return params, true
-	} else {
-		if len(userPerms) == 0 {
-			httputil.ResponseFailure(c, httputil.WithReason("Invalid signed token"))
-		} else {
-			httputil.ResponseFailure(c, httputil.WithReason("You don't have permission to proceed"))
-		}
+	principal, err := self.authenticate(c)
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithReason(err.Error()))
 		return params, false
 	}
+	c.Set(principalContextKey, principal)
+	markAudited(c)
+	if !self.useNonce(c, principal.Key, params.Get("nonce")) {
+		return params, false
+	}
+	if eligible(principal.Permissions, perms) {
+		return params, true
+	}
+	httputil.ResponseFailure(c, httputil.WithReason("You don't have permission to proceed"))
+	return params, false
+}
+
+// useNonce responds failure and returns false if key used nonce before.
+func (self *HTTPServer) useNonce(c *gin.Context, key, nonce string) bool {
+	if self.nonces == nil {
+		return true
+	}
+	// nonce is checked by IsIntime, it is kept for an extra window to
+	// tolerate clock differences between servers sharing the nonce store
+	nonceInt, _ := strconv.ParseUint(nonce, 10, 64)
+	ok, err := self.nonces.UseNonce(key, nonceInt, nonceInt+2*nonceWindow)
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return false
+	}
+	if !ok {
+		log.Printf("Nonce %s of key %s is replayed", nonce, key)
+		httputil.ResponseFailure(c, httputil.WithReason("Your nonce is already used"))
+		return false
+	}
+	return true
+}
+
+// principalContextKey is the key of the principal of authenticated requests in gin context.
+const principalContextKey = "principal"
+
+// authenticationContextKey is the key of the result of authenticating the
+// signature of a request in gin context.
+const authenticationContextKey = "authentication"
+
+// authentication is the result of authenticating the signature of a request.
+type authentication struct {
+	principal Principal
+	err       error
+}
+
+// authenticate verifies the signature of the parsed form of a request. The
+// result is kept in gin context, so the signature is verified once even if
+// both the rate limiter and the handler need the principal.
+func (self *HTTPServer) authenticate(c *gin.Context) (Principal, error) {
+	if value, ok := c.Get(authenticationContextKey); ok {
+		result := value.(authentication)
+		return result.principal, result.err
+	}
+	principal, err := self.auth.Authenticate(c.GetHeader("key"), c.GetHeader("signed"), c.Request.Form.Encode())
+	c.Set(authenticationContextKey, authentication{principal, err})
+	return principal, err
+}
+
+// principalOf returns the principal of an authenticated request, false if
+// authentication is disabled.
+func principalOf(c *gin.Context) (Principal, bool) {
+	value, ok := c.Get(principalContextKey)
+	if !ok {
+		return Principal{}, false
+	}
+	principal, ok := value.(Principal)
+	return principal, ok
+}
+
+// inScope responds failure and returns false if the key of an authenticated
+// request is not allowed to do action on exchange.
+func (self *HTTPServer) inScope(c *gin.Context, action string, exchange common.Exchange) bool {
+	principal, ok := principalOf(c)
+	if !ok {
+		return true
+	}
+	scope := fmt.Sprintf("%s:%s", action, exchange.ID())
+	if principal.HasScope(scope) {
+		return true
+	}
+	httputil.ResponseFailure(c, httputil.WithReason(fmt.Sprintf("API key %s is not allowed to %s", principal.Key, scope)))
+	return false
 }
 
 func (self *HTTPServer) AllPricesVersion(c *gin.Context) {
@@ -327,6 +428,9 @@ func (self *HTTPServer) Trade(c *gin.Context) {
 		httputil.ResponseFailure(c, httputil.WithError(err))
 		return
 	}
+	if !self.inScope(c, common.TradeScope, exchange) {
+		return
+	}
 	base, err := common.GetInternalToken(baseTokenParam)
 	if err != nil {
 		httputil.ResponseFailure(c, httputil.WithError(err))
@@ -380,6 +484,9 @@ func (self *HTTPServer) CancelOrder(c *gin.Context) {
 		httputil.ResponseFailure(c, httputil.WithError(err))
 		return
 	}
+	if !self.inScope(c, common.TradeScope, exchange) {
+		return
+	}
 	log.Printf("Cancel order id: %s from %s\n", id, exchange.ID())
 	activityID, err := common.StringToActivityID(id)
 	if err != nil {
@@ -409,6 +516,9 @@ func (self *HTTPServer) Withdraw(c *gin.Context) {
 		httputil.ResponseFailure(c, httputil.WithError(err))
 		return
 	}
+	if !self.inScope(c, common.WithdrawScope, exchange) {
+		return
+	}
 	token, err := common.GetInternalToken(tokenParam)
 	if err != nil {
 		httputil.ResponseFailure(c, httputil.WithError(err))
@@ -443,6 +553,9 @@ func (self *HTTPServer) Deposit(c *gin.Context) {
 		httputil.ResponseFailure(c, httputil.WithError(err))
 		return
 	}
+	if !self.inScope(c, common.DepositScope, exchange) {
+		return
+	}
 	token, err := common.GetInternalToken(tokenParam)
 	if err != nil {
 		httputil.ResponseFailure(c, httputil.WithError(err))
@@ -468,6 +581,10 @@ func (self *HTTPServer) GetActivities(c *gin.Context) {
 	if !ok {
 		return
 	}
+	if isPageRequest(c, activityPageParams) {
+		self.getActivityPage(c)
+		return
+	}
 	fromTime, _ := strconv.ParseUint(c.Query("fromTime"), 10, 64)
 	toTime, _ := strconv.ParseUint(c.Query("toTime"), 10, 64)
 	if toTime == 0 {
@@ -503,6 +620,10 @@ func (self *HTTPServer) CatLogs(c *gin.Context) {
 
 func (self *HTTPServer) TradeLogs(c *gin.Context) {
 	log.Printf("Getting trade logs")
+	if isPageRequest(c, tradeLogPageParams) {
+		self.getTradeLogPage(c)
+		return
+	}
 	fromTime, err := strconv.ParseUint(c.Query("fromTime"), 10, 64)
 	if err != nil {
 		fromTime = 0
@@ -768,13 +889,23 @@ func (self *HTTPServer) GetPendingTargetQty(c *gin.Context) {
 	if !ok {
 		return
 	}
-	data, err := self.metric.GetPendingTargetQty()
-	if err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
+	request := metric.TargetQtyRequest{}
+	proposal, ok := self.pendingConfig(c, common.TargetQtyConfig, &request)
+	if !ok {
 		return
 	}
-	httputil.ResponseSuccess(c, httputil.WithData(data))
-	return
+	httputil.ResponseSuccess(c, httputil.WithData(pendingTargetQty(proposal, request)))
+}
+
+// pendingTargetQty returns the pending target quantity of proposal, its ID is
+// the time it is proposed.
+func pendingTargetQty(proposal common.ConfigProposal, request metric.TargetQtyRequest) metric.TokenTargetQty {
+	return metric.TokenTargetQty{
+		ID:     proposal.ProposedAt,
+		Data:   request.Data,
+		Status: "unconfirmed",
+		Type:   request.Type,
+	}
 }
 
 // func targetQtySanityCheck(total, reserve, rebalanceThresold, transferThresold float64) error {
@@ -795,13 +926,19 @@ func (self *HTTPServer) ConfirmTargetQty(c *gin.Context) {
 	}
 	data := postForm.Get("data")
 	id := postForm.Get("id")
-	err := self.metric.StoreTokenTargetQty(id, data)
-	if err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
-		return
-	}
-	httputil.ResponseSuccess(c)
-	return
+	self.confirmPendingConfig(c, common.TargetQtyConfig, func(proposal common.ConfigProposal) error {
+		pending := metric.TargetQtyRequest{}
+		if err := json.Unmarshal(proposal.Value, &pending); err != nil {
+			return err
+		}
+		if strconv.FormatUint(proposal.ProposedAt, 10) != id {
+			return errors.New("pending target quantity ID does not match")
+		}
+		if data != pending.Data {
+			return errors.New("pending target quantity data does not match")
+		}
+		return nil
+	})
 }
 
 func (self *HTTPServer) CancelTargetQty(c *gin.Context) {
@@ -810,13 +947,7 @@ func (self *HTTPServer) CancelTargetQty(c *gin.Context) {
 	if !ok {
 		return
 	}
-	err := self.metric.RemovePendingTargetQty()
-	if err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
-		return
-	}
-	httputil.ResponseSuccess(c)
-	return
+	self.rejectPendingConfig(c, common.TargetQtyConfig)
 }
 
 func (self *HTTPServer) SetTargetQty(c *gin.Context) {
@@ -827,37 +958,26 @@ func (self *HTTPServer) SetTargetQty(c *gin.Context) {
 	data := postForm.Get("data")
 	dataType := postForm.Get("type")
 	log.Println("Setting target qty")
-	var err error
-	for _, dataConfig := range strings.Split(data, "|") {
-		dataParts := strings.Split(dataConfig, "_")
-		if dataType == "" || (dataType == "1" && len(dataParts) != 5) {
-			httputil.ResponseFailure(c, httputil.WithReason("Data submitted not enough information"))
-			return
-		}
-		token := dataParts[0]
-		// total, _ := strconv.ParseFloat(dataParts[1], 64)
-		// reserve, _ := strconv.ParseFloat(dataParts[2], 64)
-		// rebalanceThresold, _ := strconv.ParseFloat(dataParts[3], 64)
-		// transferThresold, _ := strconv.ParseFloat(dataParts[4], 64)
-		_, err = common.GetInternalToken(token)
-		if err != nil {
-			httputil.ResponseFailure(c, httputil.WithError(err))
-			return
-		}
+	if dataType == "" {
+		httputil.ResponseFailure(c, httputil.WithReason("Data submitted not enough information"))
+		return
 	}
-	err = self.metric.StorePendingTargetQty(data, dataType)
-	if err != nil {
+	request := metric.TargetQtyRequest{Data: data}
+	var err error
+	if request.Type, err = strconv.ParseInt(dataType, 10, 64); err != nil {
 		httputil.ResponseFailure(c, httputil.WithError(err))
 		return
 	}
-
-	pendingData, err := self.metric.GetPendingTargetQty()
+	value, err := json.Marshal(request)
 	if err != nil {
 		httputil.ResponseFailure(c, httputil.WithError(err))
 		return
 	}
-	httputil.ResponseSuccess(c, httputil.WithData(pendingData))
-	return
+	proposal, _, ok := self.proposeConfig(c, common.TargetQtyConfig, value, 0)
+	if !ok {
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(pendingTargetQty(proposal, request)))
 }
 
 func (self *HTTPServer) GetAddress(c *gin.Context) {
@@ -1002,12 +1122,12 @@ func (self *HTTPServer) GetPendingPWIEquation(c *gin.Context) {
 	if !ok {
 		return
 	}
-	data, err := self.metric.GetPendingPWIEquation()
-	if err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
+	request := metric.PWIEquationRequest{}
+	proposal, ok := self.pendingConfig(c, common.PWIEquationConfig, &request)
+	if !ok {
 		return
 	}
-	httputil.ResponseSuccess(c, httputil.WithData(data))
+	httputil.ResponseSuccess(c, httputil.WithData(metric.PWIEquation{ID: proposal.ProposedAt, Data: request.Data}))
 }
 
 func (self *HTTPServer) GetBurnFee(c *gin.Context) {
@@ -1028,30 +1148,18 @@ func (self *HTTPServer) GetBurnFee(c *gin.Context) {
 }
 
 func (self *HTTPServer) SetPWIEquation(c *gin.Context) {
-	var err error
 	postForm, ok := self.Authenticated(c, []string{}, []Permission{ConfigurePermission})
 	if !ok {
 		return
 	}
-	data := postForm.Get("data")
-	for _, dataConfig := range strings.Split(data, "|") {
-		dataParts := strings.Split(dataConfig, "_")
-		if len(dataParts) != 4 {
-			httputil.ResponseFailure(c, httputil.WithReason("The input data is not correct"))
-			return
-		}
-		token := dataParts[0]
-		_, err = common.GetInternalToken(token)
-		if err != nil {
-			httputil.ResponseFailure(c, httputil.WithError(err))
-			return
-		}
-	}
-	err = self.metric.StorePendingPWIEquation(data)
+	value, err := json.Marshal(metric.PWIEquationRequest{Data: postForm.Get("data")})
 	if err != nil {
 		httputil.ResponseFailure(c, httputil.WithError(err))
 		return
 	}
+	if _, _, ok = self.proposeConfig(c, common.PWIEquationConfig, value, 0); !ok {
+		return
+	}
 	httputil.ResponseSuccess(c)
 }
 
@@ -1075,12 +1183,16 @@ func (self *HTTPServer) ConfirmPWIEquation(c *gin.Context) {
 		return
 	}
 	postData := postForm.Get("data")
-	err := self.metric.StorePWIEquation(postData)
-	if err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
-		return
-	}
-	httputil.ResponseSuccess(c)
+	self.confirmPendingConfig(c, common.PWIEquationConfig, func(proposal common.ConfigProposal) error {
+		pending := metric.PWIEquationRequest{}
+		if err := json.Unmarshal(proposal.Value, &pending); err != nil {
+			return err
+		}
+		if pending.Data != postData {
+			return errors.New("Confirm data does not match pending data")
+		}
+		return nil
+	})
 }
 
 func (self *HTTPServer) ExceedDailyLimit(c *gin.Context) {
@@ -1170,13 +1282,7 @@ func (self *HTTPServer) RejectPWIEquation(c *gin.Context) {
 	if !ok {
 		return
 	}
-	// postData := postForm.Get("data")
-	err := self.metric.RemovePendingPWIEquation()
-	if err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
-		return
-	}
-	httputil.ResponseSuccess(c)
+	self.rejectPendingConfig(c, common.PWIEquationConfig)
 }
 
 func (self *HTTPServer) GetCapByAddress(c *gin.Context) {
@@ -1524,14 +1630,7 @@ func (self *HTTPServer) SetStableTokenParams(c *gin.Context) {
 	if !ok {
 		return
 	}
-	value := []byte(postForm.Get("value"))
-	if len(value) > MAX_DATA_SIZE {
-		httputil.ResponseFailure(c, httputil.WithReason(errDataSizeExceed.Error()))
-		return
-	}
-	err := self.metric.SetStableTokenParams(value)
-	if err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
+	if _, _, ok = self.proposeConfig(c, common.StableTokenParamsConfig, []byte(postForm.Get("value")), 0); !ok {
 		return
 	}
 	httputil.ResponseSuccess(c)
@@ -1547,12 +1646,9 @@ func (self *HTTPServer) ConfirmStableTokenParams(c *gin.Context) {
 		httputil.ResponseFailure(c, httputil.WithReason(errDataSizeExceed.Error()))
 		return
 	}
-	err := self.metric.ConfirmStableTokenParams(value)
-	if err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
-		return
-	}
-	httputil.ResponseSuccess(c)
+	self.confirmPendingConfig(c, common.StableTokenParamsConfig, sameConfig(value, func() interface{} {
+		return &metric.StableTokenParamsRequest{}
+	}))
 }
 
 func (self *HTTPServer) RejectStableTokenParams(c *gin.Context) {
@@ -1560,12 +1656,7 @@ func (self *HTTPServer) RejectStableTokenParams(c *gin.Context) {
 	if !ok {
 		return
 	}
-	err := self.metric.RemovePendingStableTokenParams()
-	if err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
-		return
-	}
-	httputil.ResponseSuccess(c)
+	self.rejectPendingConfig(c, common.StableTokenParamsConfig)
 }
 
 func (self *HTTPServer) GetPendingStableTokenParams(c *gin.Context) {
@@ -1574,9 +1665,8 @@ func (self *HTTPServer) GetPendingStableTokenParams(c *gin.Context) {
 		return
 	}
 
-	data, err := self.metric.GetPendingStableTokenParams()
-	if err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
+	data := metric.StableTokenParamsRequest{}
+	if _, ok = self.pendingConfig(c, common.StableTokenParamsConfig, &data); !ok {
 		return
 	}
 	httputil.ResponseSuccess(c, httputil.WithData(data))
@@ -1622,23 +1712,7 @@ func (self *HTTPServer) SetTargetQtyV2(c *gin.Context) {
 	if !ok {
 		return
 	}
-	value := []byte(postForm.Get("value"))
-	if len(value) > MAX_DATA_SIZE {
-		httputil.ResponseFailure(c, httputil.WithReason(errDataSizeExceed.Error()))
-		return
-	}
-	var tokenTargetQty metric.TokenTargetQtyV2
-	if err := json.Unmarshal(value, &tokenTargetQty); err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
-		return
-	}
-	if err := tokenTargetQty.Validate(); err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
-		return
-	}
-	err := self.metric.StorePendingTargetQtyV2(value)
-	if err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
+	if _, _, ok = self.proposeConfig(c, common.TargetQtyV2Config, []byte(postForm.Get("value")), 0); !ok {
 		return
 	}
 	httputil.ResponseSuccess(c)
@@ -1650,9 +1724,8 @@ func (self *HTTPServer) GetPendingTargetQtyV2(c *gin.Context) {
 		return
 	}
 
-	data, err := self.metric.GetPendingTargetQtyV2()
-	if err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
+	data := metric.TokenTargetQtyV2{}
+	if _, ok = self.pendingConfig(c, common.TargetQtyV2Config, &data); !ok {
 		return
 	}
 	httputil.ResponseSuccess(c, httputil.WithData(data))
@@ -1668,11 +1741,9 @@ func (self *HTTPServer) ConfirmTargetQtyV2(c *gin.Context) {
 		httputil.ResponseFailure(c, httputil.WithReason(errDataSizeExceed.Error()))
 		return
 	}
-	err := self.metric.ConfirmTargetQtyV2(value)
-	if err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
-	}
-	httputil.ResponseSuccess(c)
+	self.confirmPendingConfig(c, common.TargetQtyV2Config, sameConfig(value, func() interface{} {
+		return &metric.TokenTargetQtyV2{}
+	}))
 }
 
 func (self *HTTPServer) CancelTargetQtyV2(c *gin.Context) {
@@ -1680,12 +1751,7 @@ func (self *HTTPServer) CancelTargetQtyV2(c *gin.Context) {
 	if !ok {
 		return
 	}
-	err := self.metric.RemovePendingTargetQtyV2()
-	if err != nil {
-		httputil.ResponseFailure(c, httputil.WithError(err))
-		return
-	}
-	httputil.ResponseSuccess(c)
+	self.rejectPendingConfig(c, common.TargetQtyV2Config)
 }
 
 func (self *HTTPServer) GetTargetQtyV2(c *gin.Context) {
@@ -1716,12 +1782,16 @@ func (self *HTTPServer) GetFeeSetRateByDay(c *gin.Context) {
 }
 
 func (self *HTTPServer) register() {
+	self.r.Use(self.observeRequests)
+	self.r.Use(self.auditRequests)
+	self.r.Use(self.limitRequests)
 	if self.core != nil && self.app != nil {
 		v2 := self.r.Group("/v2")
 
 		self.r.GET("/prices-version", self.AllPricesVersion)
 		self.r.GET("/prices", self.AllPrices)
 		self.r.GET("/prices/:base/:quote", self.Price)
+		self.r.GET("/price-history", self.PriceHistory)
 		self.r.GET("/getrates", self.GetRate)
 		self.r.GET("/get-all-rates", self.GetRates)
 
@@ -1737,6 +1807,8 @@ func (self *HTTPServer) register() {
 		self.r.POST("/withdraw/:exchangeid", self.Withdraw)
 		self.r.POST("/trade/:exchangeid", self.Trade)
 		self.r.POST("/setrates", self.SetRate)
+		self.r.GET("/simulate-rate", self.SimulateRate)
+		self.r.GET("/pricing-state", self.GetPricingState)
 		self.r.GET("/exchangeinfo", self.GetExchangeInfo)
 		self.r.GET("/exchangeinfo/:exchangeid/:base/:quote", self.GetPairInfo)
 		self.r.GET("/exchangefees", self.GetFee)
@@ -1744,6 +1816,10 @@ func (self *HTTPServer) register() {
 		self.r.GET("/exchangefees/:exchangeid", self.GetExchangeFee)
 		self.r.GET("/core/addresses", self.GetAddress)
 		self.r.GET("/tradehistory", self.GetTradeHistory)
+		self.r.GET("/gas-spend", self.GetGasSpend)
+		self.r.GET("/balance-status", self.GetBalanceStatus)
+		self.r.GET("/balance-thresholds", self.GetBalanceThresholds)
+		self.r.POST("/set-balance-thresholds", self.SetBalanceThresholds)
 
 		self.r.GET("/targetqty", self.GetTargetQty)
 		self.r.GET("/pendingtargetqty", self.GetPendingTargetQty)
@@ -1785,6 +1861,12 @@ func (self *HTTPServer) register() {
 		self.r.POST("/confirm-rebalance-quadratic", self.ConfirmRebalanceQuadratic)
 		self.r.POST("/reject-rebalance-quadratic", self.RejectRebalanceQuadratic)
 
+		self.r.GET("/step-functions", self.GetStepFunctions)
+		self.r.GET("/pending-step-functions", self.GetPendingStepFunctions)
+		self.r.POST("/set-step-functions", self.SetStepFunctions)
+		self.r.POST("/confirm-step-functions", self.ConfirmStepFunctions)
+		self.r.POST("/reject-step-functions", self.RejectStepFunctions)
+
 		self.r.GET("/get-exchange-status", self.GetExchangesStatus)
 		self.r.POST("/update-exchange-status", self.UpdateExchangeStatus)
 
@@ -1798,6 +1880,8 @@ func (self *HTTPServer) register() {
 		self.r.GET("/stable-token-params", self.GetStableTokenParams)
 
 		self.r.GET("/gold-feed", self.GetGoldData)
+		self.r.GET("/data-health", self.DataHealth)
+		self.r.GET("/events", self.Events)
 	}
 
 	if self.stat != nil {
@@ -1827,6 +1911,32 @@ func (self *HTTPServer) register() {
 		self.r.GET("/get-token-heatmap", self.GetTokenHeatmap)
 		self.r.GET("/get-fee-setrate", self.GetFeeSetRateByDay)
 	}
+
+	self.r.GET("/backup", self.GetBackup)
+	self.r.POST("/backup", self.Backup)
+
+	self.r.GET("/api-keys", self.GetAPIKeys)
+	self.r.POST("/create-api-key", self.CreateAPIKey)
+	self.r.POST("/update-api-key", self.UpdateAPIKey)
+	self.r.POST("/rotate-api-key", self.RotateAPIKey)
+	self.r.POST("/remove-api-key", self.RemoveAPIKey)
+	self.r.GET("/audit-log", self.GetAuditLog)
+	self.r.GET("/audit-log/export", self.ExportAuditLog)
+	self.r.GET("/configs", self.GetConfigTypes)
+	self.r.GET("/configs/:type", self.GetConfig)
+	self.r.GET("/configs/:type/pending", self.GetPendingConfig)
+	self.r.GET("/configs/:type/versions", self.GetConfigVersions)
+	self.r.GET("/configs/:type/versions/:version", self.GetConfigVersion)
+	self.r.GET("/configs/:type/diff", self.GetConfigDiff)
+	self.r.POST("/configs/:type/propose", self.ProposeConfig)
+	self.r.POST("/configs/:type/rollback", self.RollbackConfig)
+	self.r.POST("/configs/:type/confirm", self.ConfirmConfig)
+	self.r.POST("/configs/:type/reject", self.RejectConfig)
+	self.r.GET("/metrics/prometheus", self.PrometheusMetrics)
+	self.r.GET("/healthz", self.Healthz)
+	self.r.GET("/readyz", self.Readyz)
+	self.r.GET("/readyz/details", self.ReadyzDetails)
+	self.indexRoutes()
 }
 
 func (self *HTTPServer) Run() {
@@ -1844,7 +1954,16 @@ func NewHTTPServer(
 	host string,
 	enableAuth bool,
 	authEngine Authentication,
-	env string) *HTTPServer {
+	env string,
+	backuper *backup.Backuper,
+	apiKeys APIKeyStorage,
+	audit AuditStorage,
+	nonces NonceStore,
+	events *event.Hub,
+	metricsToken string,
+	readiness *readiness.Checker,
+	limiter *ratelimit.Limiter,
+	configs ConfigStorage) *HTTPServer {
 
 	r := gin.Default()
 	sentryCli, err := raven.NewWithTags(
@@ -1861,12 +1980,12 @@ func NewHTTPServer(
 		false,
 	))
 	corsConfig := cors.DefaultConfig()
-	corsConfig.AddAllowHeaders("signed")
+	corsConfig.AddAllowHeaders("signed", "key")
 	corsConfig.AllowAllOrigins = true
 	corsConfig.MaxAge = 5 * time.Minute
 	r.Use(cors.New(corsConfig))
 
 	return &HTTPServer{
-		app, core, stat, metric, host, enableAuth, authEngine, r,
+		app, core, stat, metric, host, enableAuth, authEngine, r, backuper, apiKeys, audit, nonces, events, metricsToken, nil, readiness, limiter, configs, DefaultConfigRegistry(),
 	}
 }
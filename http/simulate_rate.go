@@ -0,0 +1,64 @@
+package http
+
+import (
+	"math/big"
+	"strconv"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/http/httputil"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gin-gonic/gin"
+)
+
+// SimulateRate preview the rate pricing contract returns for a trade, without calling getRate on chain.
+// params: token, type (buy or sell), qty (hex encoded, ETH wei for buy, token wei for sell),
+// optional block (default current block) and optional rate (hex encoded) to preview a new rate before setting it.
+func (self *HTTPServer) SimulateRate(c *gin.Context) {
+	postForm, ok := self.Authenticated(c, []string{"token", "type", "qty"}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	token, err := common.GetInternalToken(postForm.Get("token"))
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	var buy bool
+	switch postForm.Get("type") {
+	case "buy":
+		buy = true
+	case "sell":
+		buy = false
+	default:
+		httputil.ResponseFailure(c, httputil.WithReason("type must be buy or sell"))
+		return
+	}
+	qty, err := hexutil.DecodeBig(postForm.Get("qty"))
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	var block uint64
+	if blockStr := postForm.Get("block"); blockStr != "" {
+		if block, err = strconv.ParseUint(blockStr, 10, 64); err != nil {
+			httputil.ResponseFailure(c, httputil.WithError(err))
+			return
+		}
+	}
+	var newRate *big.Int
+	if rateStr := postForm.Get("rate"); rateStr != "" {
+		if newRate, err = hexutil.DecodeBig(rateStr); err != nil {
+			httputil.ResponseFailure(c, httputil.WithError(err))
+			return
+		}
+	}
+	rate, err := self.core.SimulateRate(token, buy, qty, block, newRate)
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithMultipleFields(gin.H{
+		"rate":      rate.Text(10),
+		"humanRate": common.BigToFloat(rate, 18),
+	}))
+}
@@ -0,0 +1,176 @@
+package http
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/core"
+	"github.com/KyberNetwork/reserve-data/data"
+	"github.com/KyberNetwork/reserve-data/data/storage"
+	"github.com/KyberNetwork/reserve-data/http/httputil"
+	ethereum "github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// testPricingStateBlockchain is at block 100 and returns a pricing state of
+// 500 tokens per ETH for buy and 0.002 ETH per token for sell, valid until
+// block 110, and records the blocks of requested states.
+type testPricingStateBlockchain struct {
+	testPricingBlockchain
+	atBlocks []uint64
+}
+
+func (self *testPricingStateBlockchain) CurrentBlock() (uint64, error) {
+	return 100, nil
+}
+
+func (self *testPricingStateBlockchain) GetTokenPricingState(token common.Token, atBlock uint64) (common.TokenPricingState, error) {
+	self.atBlocks = append(self.atBlocks, atBlock)
+	return common.TokenPricingState{
+		Listed:                    true,
+		Enabled:                   true,
+		Decimal:                   18,
+		BaseBuy:                   common.EthToWei(500),
+		BaseSell:                  common.EthToWei(0.002),
+		RateUpdateBlock:           90,
+		ValidRateDurationInBlocks: 20,
+		MinimalRecordResolution:   big.NewInt(100000000000000),
+	}, nil
+}
+
+func newTestPricingStateServer(t *testing.T, bc *testPricingStateBlockchain) (HTTPServer, func()) {
+	tmpDir, err := ioutil.TempDir("", "test_pricing_state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup := func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}
+	st, err := storage.NewBoltStorage(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	s := HTTPServer{
+		app:         data.NewReserveData(st, nil, nil, nil, nil, nil),
+		core:        core.NewReserveCore(bc, st, ethereum.Address{}),
+		metric:      st,
+		authEnabled: false,
+		r:           gin.Default()}
+	s.register()
+	return s, cleanup
+}
+
+func newAssertSimulatedRate(expected string) assertFn {
+	return func(t *testing.T, resp *httptest.ResponseRecorder) {
+		t.Helper()
+		decoded := struct {
+			Success   bool    `json:"success"`
+			Rate      string  `json:"rate"`
+			HumanRate float64 `json:"humanRate"`
+		}{}
+		if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Success {
+			t.Fatalf("expected success response, got %+v", decoded)
+		}
+		rate, ok := new(big.Int).SetString(expected, 10)
+		if !ok {
+			t.Fatalf("invalid expected rate %s", expected)
+		}
+		if decoded.Rate != expected || decoded.HumanRate != common.BigToFloat(rate, 18) {
+			t.Errorf("expected rate %s, got %+v", expected, decoded)
+		}
+	}
+}
+
+func TestHTTPServerSimulateRate(t *testing.T) {
+	const simulateRate = "/simulate-rate"
+	common.RegisterInternalActiveToken(common.Token{ID: "KNC", Decimal: 18})
+
+	bc := &testPricingStateBlockchain{}
+	s, cleanup := newTestPricingStateServer(t, bc)
+	defer cleanup()
+
+	var tests = []testCase{
+		{
+			msg:      "missing qty",
+			endpoint: simulateRate + "?token=KNC&type=buy",
+			method:   http.MethodGet,
+			assert:   httputil.ExpectFailure,
+		},
+		{
+			msg:      "unknown token",
+			endpoint: simulateRate + "?token=XXX&type=buy&qty=0xde0b6b3a7640000",
+			method:   http.MethodGet,
+			assert:   httputil.ExpectFailure,
+		},
+		{
+			msg:      "invalid type",
+			endpoint: simulateRate + "?token=KNC&type=trade&qty=0xde0b6b3a7640000",
+			method:   http.MethodGet,
+			assert:   httputil.ExpectFailure,
+		},
+		{
+			msg:      "qty is not hex encoded",
+			endpoint: simulateRate + "?token=KNC&type=buy&qty=1000000000000000000",
+			method:   http.MethodGet,
+			assert:   httputil.ExpectFailure,
+		},
+		{
+			msg:      "invalid block",
+			endpoint: simulateRate + "?token=KNC&type=buy&qty=0xde0b6b3a7640000&block=latest",
+			method:   http.MethodGet,
+			assert:   httputil.ExpectFailure,
+		},
+		{
+			msg:      "invalid rate",
+			endpoint: simulateRate + "?token=KNC&type=buy&qty=0xde0b6b3a7640000&rate=500",
+			method:   http.MethodGet,
+			assert:   httputil.ExpectFailure,
+		},
+		{
+			msg:      "buy at current block",
+			endpoint: simulateRate + "?token=KNC&type=buy&qty=0xde0b6b3a7640000",
+			method:   http.MethodGet,
+			assert:   newAssertSimulatedRate("500000000000000000000"),
+		},
+		{
+			msg:      "sell at past block",
+			endpoint: simulateRate + "?token=KNC&type=sell&qty=0xde0b6b3a7640000&block=95",
+			method:   http.MethodGet,
+			assert:   newAssertSimulatedRate("2000000000000000"),
+		},
+		{
+			msg:      "buy after rate expires",
+			endpoint: simulateRate + "?token=KNC&type=buy&qty=0xde0b6b3a7640000&block=110",
+			method:   http.MethodGet,
+			assert:   newAssertSimulatedRate("0"),
+		},
+		{
+			msg:      "preview a new sell rate",
+			endpoint: simulateRate + "?token=KNC&type=sell&qty=0xde0b6b3a7640000&block=110&rate=0x8e1bc9bf040000",
+			method:   http.MethodGet,
+			assert:   newAssertSimulatedRate("40000000000000000"),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.msg, func(t *testing.T) { testHTTPRequest(t, tc, s.r) })
+	}
+
+	// past blocks use the state at that block, the current and future ones the latest state
+	if expected := []uint64{0, 95, 0, 0}; !reflect.DeepEqual(bc.atBlocks, expected) {
+		t.Errorf("expected pricing states at blocks %v, got %v", expected, bc.atBlocks)
+	}
+}
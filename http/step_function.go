@@ -0,0 +1,196 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/http/httputil"
+	"github.com/KyberNetwork/reserve-data/metric"
+	"github.com/gin-gonic/gin"
+)
+
+// GetStepFunctions returns the step functions currently stored in pricing contract.
+// An optional token param limits the result to one token.
+func (self *HTTPServer) GetStepFunctions(c *gin.Context) {
+	postForm, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	tokens, err := pricingTokens(postForm.Get("token"))
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	result := metric.StepFunctionsRequest{}
+	for _, token := range tokens {
+		state, err := self.core.GetTokenPricingState(token)
+		if err != nil {
+			httputil.ResponseFailure(c, httputil.WithReason(fmt.Sprintf("cannot get step functions of %s: %s", token.ID, err)))
+			return
+		}
+		result[token.ID] = metric.TokenStepFunctions{
+			Qty: &metric.StepFunctionPoints{
+				XBuy:  state.BuyQtyStepFunction.X,
+				YBuy:  state.BuyQtyStepFunction.Y,
+				XSell: state.SellQtyStepFunction.X,
+				YSell: state.SellQtyStepFunction.Y,
+			},
+			Imbalance: &metric.StepFunctionPoints{
+				XBuy:  state.BuyImbalanceStepFunction.X,
+				YBuy:  state.BuyImbalanceStepFunction.Y,
+				XSell: state.SellImbalanceStepFunction.X,
+				YSell: state.SellImbalanceStepFunction.Y,
+			},
+		}
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(result))
+}
+
+// SetStepFunctions stores the given step functions to pending for later confirmation.
+func (self *HTTPServer) SetStepFunctions(c *gin.Context) {
+	const dataPostFormKey = "data"
+
+	postForm, ok := self.Authenticated(c, []string{dataPostFormKey}, []Permission{ConfigurePermission})
+	if !ok {
+		return
+	}
+
+	data := []byte(postForm.Get(dataPostFormKey))
+	if len(data) > MAX_DATA_SIZE {
+		httputil.ResponseFailure(c, httputil.WithError(errDataSizeExceed))
+		return
+	}
+
+	var input metric.StepFunctionsRequest
+	if err := json.Unmarshal(data, &input); err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	if err := input.Validate(); err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+
+	if err := self.metric.StorePendingStepFunctions(data); err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c)
+}
+
+// GetPendingStepFunctions returns the pending step functions, and activities of
+// the ones already submitted by a failed confirmation in submitted.
+func (self *HTTPServer) GetPendingStepFunctions(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ReadOnlyPermission, RebalancePermission, ConfigurePermission, ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	data, err := self.metric.GetPendingStepFunctions()
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	submissions, err := self.metric.GetStepFunctionSubmissions()
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(data), httputil.WithField("submitted", submissions))
+}
+
+// ConfirmStepFunctions sets the pending step functions to pricing contract,
+// each step function is recorded as an activity. Submitted step functions are
+// recorded with the pending step functions, if any transaction can not be
+// submitted they are kept pending and confirming again only submits the rest.
+func (self *HTTPServer) ConfirmStepFunctions(c *gin.Context) {
+	const dataPostFormKey = "data"
+
+	postForm, ok := self.Authenticated(c, []string{dataPostFormKey}, []Permission{ConfirmConfPermission})
+	if !ok {
+		return
+	}
+	data := []byte(postForm.Get(dataPostFormKey))
+	var confirmData metric.StepFunctionsRequest
+	if err := json.Unmarshal(data, &confirmData); err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	pending, err := self.metric.GetPendingStepFunctions()
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	if !reflect.DeepEqual(pending, confirmData) {
+		httputil.ResponseFailure(c, httputil.WithReason("confirm data does not match pending step functions"))
+		return
+	}
+	submissions, err := self.metric.GetStepFunctionSubmissions()
+	if err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+
+	tokenIDs := []string{}
+	for tokenID := range pending {
+		tokenIDs = append(tokenIDs, tokenID)
+	}
+	sort.Strings(tokenIDs)
+	ids := map[string][]common.ActivityID{}
+	submit := func(token common.Token, stepType string, sf *metric.StepFunctionPoints) error {
+		if sf == nil {
+			return nil
+		}
+		if id, ok := submissions[token.ID][stepType]; ok {
+			ids[token.ID] = append(ids[token.ID], id)
+			return nil
+		}
+		id, err := self.core.SetStepFunction(token, stepType, sf.XBuy, sf.YBuy, sf.XSell, sf.YSell)
+		if err != nil {
+			return fmt.Errorf("cannot set %s step function of %s: %s", stepType, token.ID, err)
+		}
+		ids[token.ID] = append(ids[token.ID], id)
+		if err = self.metric.StoreStepFunctionSubmission(token.ID, stepType, id); err != nil {
+			return fmt.Errorf("%s step function of %s is submitted but can not be recorded: %s", stepType, token.ID, err)
+		}
+		return nil
+	}
+	for _, tokenID := range tokenIDs {
+		token, err := common.GetInternalToken(tokenID)
+		if err != nil {
+			httputil.ResponseFailure(c, httputil.WithError(err))
+			return
+		}
+		sf := pending[tokenID]
+		if err = submit(token, "qty", sf.Qty); err == nil {
+			err = submit(token, "imbalance", sf.Imbalance)
+		}
+		if err != nil {
+			httputil.ResponseFailure(c, httputil.WithError(err), httputil.WithField("submitted", ids))
+			return
+		}
+	}
+
+	if err := self.metric.ConfirmStepFunctions(data); err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err), httputil.WithField("submitted", ids))
+		return
+	}
+	httputil.ResponseSuccess(c, httputil.WithData(ids))
+}
+
+// RejectStepFunctions rejects the pending step functions and removes
+// them from pending storage.
+func (self *HTTPServer) RejectStepFunctions(c *gin.Context) {
+	_, ok := self.Authenticated(c, []string{}, []Permission{ConfirmConfPermission})
+	if !ok {
+		return
+	}
+
+	if err := self.metric.RemovePendingStepFunctions(); err != nil {
+		httputil.ResponseFailure(c, httputil.WithError(err))
+		return
+	}
+	httputil.ResponseSuccess(c)
+}
@@ -0,0 +1,373 @@
+package http
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/KyberNetwork/reserve-data/core"
+	"github.com/KyberNetwork/reserve-data/data"
+	"github.com/KyberNetwork/reserve-data/data/storage"
+	"github.com/KyberNetwork/reserve-data/http/httputil"
+	ethereum "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/gin-gonic/gin"
+)
+
+// testPricingBlockchain is a core.Blockchain that accepts every pricing transaction,
+// except imbalance step functions while failImbalance is true.
+type testPricingBlockchain struct {
+	setCalls      int
+	failImbalance bool
+}
+
+func (self *testPricingBlockchain) newTx(token ethereum.Address) *types.Transaction {
+	self.setCalls++
+	return types.NewTransaction(uint64(self.setCalls), token, big.NewInt(0), 300000, big.NewInt(1000000000), []byte{})
+}
+
+func (self *testPricingBlockchain) StandardGasPrice() float64 {
+	return 0
+}
+
+func (self *testPricingBlockchain) Send(token common.Token, amount *big.Int, address ethereum.Address) (*types.Transaction, error) {
+	return self.newTx(address), nil
+}
+
+func (self *testPricingBlockchain) SetRates(tokens []ethereum.Address, buys, sells []*big.Int, block, nonce, gasPrice *big.Int) (*types.Transaction, error) {
+	return self.newTx(ethereum.Address{}), nil
+}
+
+func (self *testPricingBlockchain) SetQtyStepFunction(token ethereum.Address, xBuy, yBuy, xSell, ySell []*big.Int, nonce, gasPrice *big.Int) (*types.Transaction, error) {
+	return self.newTx(token), nil
+}
+
+func (self *testPricingBlockchain) SetImbalanceStepFunction(token ethereum.Address, xBuy, yBuy, xSell, ySell []*big.Int, nonce, gasPrice *big.Int) (*types.Transaction, error) {
+	if self.failImbalance {
+		return nil, errors.New("node is unavailable")
+	}
+	return self.newTx(token), nil
+}
+
+func (self *testPricingBlockchain) SetRateMinedNonce() (uint64, error) {
+	return 0, nil
+}
+
+func (self *testPricingBlockchain) GetAddresses() *common.Addresses {
+	return &common.Addresses{}
+}
+
+func (self *testPricingBlockchain) CurrentBlock() (uint64, error) {
+	return 0, nil
+}
+
+func (self *testPricingBlockchain) GetTokenPricingState(token common.Token, atBlock uint64) (common.TokenPricingState, error) {
+	return common.TokenPricingState{
+		BuyQtyStepFunction: common.NewStepFunction([]*big.Int{big.NewInt(100)}, []*big.Int{big.NewInt(-10)}),
+	}, nil
+}
+
+func TestHTTPServerStepFunctions(t *testing.T) {
+	const (
+		setStepFunctions        = "/set-step-functions"
+		getPendingStepFunctions = "/pending-step-functions"
+		confirmStepFunctions    = "/confirm-step-functions"
+		rejectStepFunctions     = "/reject-step-functions"
+		getStepFunctions        = "/step-functions"
+		testData                = `{
+			"KNC": {
+				"qty": {
+					"x_buy": [100000000000000000000, 200000000000000000000],
+					"y_buy": [0, -30],
+					"x_sell": [100000000000000000000, 200000000000000000000],
+					"y_sell": [0, -30]
+				},
+				"imbalance": {
+					"x_buy": [-500000000000000000000, 0, 500000000000000000000],
+					"y_buy": [10, 0, -20],
+					"x_sell": [-500000000000000000000, 0, 500000000000000000000],
+					"y_sell": [-20, 0, 10]
+				}
+			}
+		}`
+		testWrongDataConfirmation = `{
+			"KNC": {
+				"qty": {
+					"x_buy": [100000000000000000000, 200000000000000000000],
+					"y_buy": [0, -40],
+					"x_sell": [100000000000000000000, 200000000000000000000],
+					"y_sell": [0, -30]
+				}
+			}
+		}`
+		testDataNotMonotonic = `{
+			"KNC": {
+				"qty": {
+					"x_buy": [200000000000000000000, 100000000000000000000],
+					"y_buy": [0, -30],
+					"x_sell": [100000000000000000000, 200000000000000000000],
+					"y_sell": [0, -30]
+				}
+			}
+		}`
+		testDataBpsOutOfRange = `{
+			"KNC": {
+				"imbalance": {
+					"x_buy": [0],
+					"y_buy": [-10001],
+					"x_sell": [0],
+					"y_sell": [0]
+				}
+			}
+		}`
+		testDataETH = `{
+			"ETH": {
+				"qty": {"x_buy": [0], "y_buy": [0], "x_sell": [0], "y_sell": [0]}
+			}
+		}`
+	)
+
+	common.RegisterInternalActiveToken(common.Token{ID: "KNC"})
+	common.RegisterInternalActiveToken(common.Token{ID: "ETH"})
+
+	tmpDir, err := ioutil.TempDir("", "test_step_functions")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}()
+
+	st, err := storage.NewBoltStorage(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bc := &testPricingBlockchain{}
+	s := HTTPServer{
+		app:         data.NewReserveData(st, nil, nil, nil, nil, nil),
+		core:        core.NewReserveCore(bc, st, ethereum.Address{}),
+		metric:      st,
+		authEnabled: false,
+		r:           gin.Default()}
+	s.register()
+
+	var tests = []testCase{
+		{
+			msg:      "getting non exists pending step functions",
+			endpoint: getPendingStepFunctions,
+			method:   http.MethodGet,
+			assert:   httputil.ExpectFailure,
+		},
+		{
+			msg:      "x values not increasing",
+			endpoint: setStepFunctions,
+			method:   http.MethodPost,
+			data:     map[string]string{"data": testDataNotMonotonic},
+			assert:   httputil.ExpectFailure,
+		},
+		{
+			msg:      "bps out of range",
+			endpoint: setStepFunctions,
+			method:   http.MethodPost,
+			data:     map[string]string{"data": testDataBpsOutOfRange},
+			assert:   httputil.ExpectFailure,
+		},
+		{
+			msg:      "ETH has no step functions",
+			endpoint: setStepFunctions,
+			method:   http.MethodPost,
+			data:     map[string]string{"data": testDataETH},
+			assert:   httputil.ExpectFailure,
+		},
+		{
+			msg:      "confirm when no pending step functions exist",
+			endpoint: confirmStepFunctions,
+			method:   http.MethodPost,
+			data:     map[string]string{"data": testData},
+			assert:   httputil.ExpectFailure,
+		},
+		{
+			msg:      "valid post form",
+			endpoint: setStepFunctions,
+			method:   http.MethodPost,
+			data:     map[string]string{"data": testData},
+			assert:   httputil.ExpectSuccess,
+		},
+		{
+			msg:      "setting when pending exists",
+			endpoint: setStepFunctions,
+			method:   http.MethodPost,
+			data:     map[string]string{"data": testData},
+			assert:   httputil.ExpectFailure,
+		},
+		{
+			msg:      "getting existing pending step functions",
+			endpoint: getPendingStepFunctions,
+			method:   http.MethodGet,
+			assert:   httputil.ExpectSuccess,
+		},
+		{
+			msg:      "confirm with wrong data",
+			endpoint: confirmStepFunctions,
+			method:   http.MethodPost,
+			data:     map[string]string{"data": testWrongDataConfirmation},
+			assert:   httputil.ExpectFailure,
+		},
+		{
+			msg:      "confirm with correct data",
+			endpoint: confirmStepFunctions,
+			method:   http.MethodPost,
+			data:     map[string]string{"data": testData},
+			assert:   httputil.ExpectSuccess,
+		},
+		{
+			msg:      "pending step functions are removed after confirmation",
+			endpoint: getPendingStepFunctions,
+			method:   http.MethodGet,
+			assert:   httputil.ExpectFailure,
+		},
+		{
+			msg:      "getting on-chain step functions",
+			endpoint: getStepFunctions + "?token=KNC",
+			method:   http.MethodGet,
+			assert:   httputil.ExpectSuccess,
+		},
+		{
+			msg:      "valid post form",
+			endpoint: setStepFunctions,
+			method:   http.MethodPost,
+			data:     map[string]string{"data": testData},
+			assert:   httputil.ExpectSuccess,
+		},
+		{
+			msg:      "reject when there are pending step functions",
+			endpoint: rejectStepFunctions,
+			method:   http.MethodPost,
+			data:     map[string]string{"data": "some random post form or this request will be unauthenticated"},
+			assert:   httputil.ExpectSuccess,
+		},
+		{
+			msg:      "reject when no pending step functions exist",
+			endpoint: rejectStepFunctions,
+			method:   http.MethodPost,
+			data:     map[string]string{"data": "some random post form or this request will be unauthenticated"},
+			assert:   httputil.ExpectFailure,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.msg, func(t *testing.T) { testHTTPRequest(t, tc, s.r) })
+	}
+
+	if bc.setCalls != 2 {
+		t.Errorf("expected 2 step function transactions, got %d", bc.setCalls)
+	}
+	activities, err := st.GetPendingActivities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activities) != 2 {
+		t.Fatalf("expected 2 pending set_step_function activities, got %d", len(activities))
+	}
+	for _, act := range activities {
+		if act.Action != "set_step_function" || act.MiningStatus != "submitted" {
+			t.Errorf("unexpected activity %+v", act)
+		}
+	}
+}
+
+func TestHTTPServerConfirmStepFunctionsPartially(t *testing.T) {
+	const testData = `{
+		"KNC": {
+			"qty": {"x_buy": [0], "y_buy": [0], "x_sell": [0], "y_sell": [0]},
+			"imbalance": {"x_buy": [0], "y_buy": [0], "x_sell": [0], "y_sell": [0]}
+		},
+		"OMG": {
+			"qty": {"x_buy": [0], "y_buy": [0], "x_sell": [0], "y_sell": [0]}
+		}
+	}`
+	common.RegisterInternalActiveToken(common.Token{ID: "KNC"})
+	common.RegisterInternalActiveToken(common.Token{ID: "OMG"})
+
+	tmpDir, err := ioutil.TempDir("", "test_step_functions_partially")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if rErr := os.RemoveAll(tmpDir); rErr != nil {
+			t.Error(rErr)
+		}
+	}()
+	st, err := storage.NewBoltStorage(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc := &testPricingBlockchain{failImbalance: true}
+	s := HTTPServer{
+		app:         data.NewReserveData(st, nil, nil, nil, nil, nil),
+		core:        core.NewReserveCore(bc, st, ethereum.Address{}),
+		metric:      st,
+		authEnabled: false,
+		r:           gin.Default()}
+	s.register()
+
+	var tests = []testCase{
+		{
+			msg:      "valid post form",
+			endpoint: "/set-step-functions",
+			method:   http.MethodPost,
+			data:     map[string]string{"data": testData},
+			assert:   httputil.ExpectSuccess,
+		},
+		{
+			msg:      "confirm when imbalance step function can not be submitted",
+			endpoint: "/confirm-step-functions",
+			method:   http.MethodPost,
+			data:     map[string]string{"data": testData},
+			assert:   httputil.ExpectFailure,
+		},
+		{
+			msg:      "pending step functions are kept with their submissions",
+			endpoint: "/pending-step-functions",
+			method:   http.MethodGet,
+			assert:   httputil.ExpectSuccess,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.msg, func(t *testing.T) { testHTTPRequest(t, tc, s.r) })
+	}
+	if bc.setCalls != 1 {
+		t.Fatalf("expected only qty step function of KNC to be submitted, got %d transactions", bc.setCalls)
+	}
+	submissions, err := st.GetStepFunctionSubmissions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := submissions["KNC"]["qty"]; !ok || len(submissions) != 1 {
+		t.Fatalf("unexpected step function submissions %+v", submissions)
+	}
+
+	bc.failImbalance = false
+	testHTTPRequest(t, testCase{
+		msg:      "confirm again only submits the rest",
+		endpoint: "/confirm-step-functions",
+		method:   http.MethodPost,
+		data:     map[string]string{"data": testData},
+		assert:   httputil.ExpectSuccess,
+	}, s.r)
+	if bc.setCalls != 3 {
+		t.Errorf("expected 3 step function transactions, got %d", bc.setCalls)
+	}
+	if _, err = st.GetPendingStepFunctions(); err == nil {
+		t.Error("expected pending step functions to be removed after confirmation")
+	}
+}
@@ -129,6 +129,8 @@ func TestHTTPServerTargetQtyV2(t *testing.T) {
 		app:         data.NewReserveData(st, nil, nil, nil, nil, nil),
 		core:        core.NewReserveCore(nil, st, ethereum.Address{}),
 		metric:      st,
+		configs:     st,
+		configTypes: DefaultConfigRegistry(),
 		authEnabled: false,
 		r:           gin.Default()}
 	s.register()
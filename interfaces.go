@@ -10,6 +10,9 @@ import (
 // ReserveStats is the interface of all statistic methods.
 type ReserveStats interface {
 	GetTradeLogs(fromTime uint64, toTime uint64) ([]common.TradeLog, error)
+	// GetTradeLogPage returns at most limit trade logs selected by filter,
+	// which are older than cursor if it is not nil, the latest first.
+	GetTradeLogPage(filter common.TradeLogFilter, cursor *uint64, limit int) (common.TradeLogPage, error)
 	GetCatLogs(fromTime uint64, toTime uint64) ([]common.SetCatLog, error)
 	GetAssetVolume(fromTime, toTime uint64, freq, asset string) (common.StatTicks, error)
 	GetBurnFee(fromTime, toTime uint64, freq, reserveAddr string) (common.StatTicks, error)
@@ -49,6 +52,9 @@ type ReserveData interface {
 	CurrentPriceVersion(timestamp uint64) (common.Version, error)
 	GetAllPrices(timestamp uint64) (common.AllPriceResponse, error)
 	GetOnePrice(id common.TokenPairID, timestamp uint64) (common.OnePriceResponse, error)
+	// GetPriceHistory returns the price time series of a pair on an exchange, or on
+	// all exchanges if it is empty, at a resolution between fromTime and toTime.
+	GetPriceHistory(id common.TokenPairID, exchange common.ExchangeID, resolution string, fromTime, toTime uint64) (common.PriceHistory, error)
 
 	CurrentAuthDataVersion(timestamp uint64) (common.Version, error)
 	GetAuthData(timestamp uint64) (common.AuthDataResponse, error)
@@ -59,9 +65,19 @@ type ReserveData interface {
 	GetRates(fromTime, toTime uint64) ([]common.AllRateResponse, error)
 
 	GetRecords(fromTime, toTime uint64) ([]common.ActivityRecord, error)
+	// GetActivityPage returns at most limit activities selected by filter,
+	// which are older than cursor if it is not nil, the latest first.
+	GetActivityPage(filter common.ActivityFilter, cursor *common.ActivityID, limit int) (common.ActivityPage, error)
 	GetPendingActivities() ([]common.ActivityRecord, error)
+	// GetGasSpend returns gas spent by operators in [fromTime, toTime] with daily rollups.
+	GetGasSpend(fromTime, toTime uint64) (common.GasSpendReport, error)
+	// GetBalanceStatus returns the latest operator and reserve balances checked against thresholds.
+	GetBalanceStatus() (common.BalanceStatus, error)
 
 	GetGoldData(timepoint uint64) (common.GoldData, error)
+	// GetDataHealth returns the age of the latest snapshots and the fetch health
+	// of every source at timepoint.
+	GetDataHealth(timepoint uint64) common.DataHealth
 
 	GetExchangeStatus() (common.ExchangesStatus, error)
 	UpdateExchangeStatus(exchange string, status bool, timestamp uint64) error
@@ -106,5 +122,15 @@ type ReserveCore interface {
 	// blockchain related action
 	SetRates(tokens []common.Token, buys, sells []*big.Int, block *big.Int, afpMid []*big.Int, msgs []string) (common.ActivityID, error)
 
+	// SetStepFunction sets the qty or imbalance step function of a token in pricing contract.
+	SetStepFunction(token common.Token, stepType string, xBuy, yBuy, xSell, ySell []*big.Int) (common.ActivityID, error)
+
+	// GetTokenPricingState returns the current pricing contract data of given token.
+	GetTokenPricingState(token common.Token) (common.TokenPricingState, error)
+
+	// SimulateRate previews the on-chain rate of trading qty of token at given block
+	// with an optional new rate replacing the current one.
+	SimulateRate(token common.Token, buy bool, qty *big.Int, block uint64, newRate *big.Int) (*big.Int, error)
+
 	GetAddresses() *common.Addresses
 }
@@ -1,7 +1,10 @@
 package metric
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
+	"strings"
 
 	"github.com/KyberNetwork/reserve-data/common"
 )
@@ -45,6 +48,47 @@ type PWIEquation struct {
 	Data string `json:"data"`
 }
 
+// TargetQtyRequest is the target quantity config of version 1, data of type 1 follows
+// token_totalTarget_reserveTarget_rebalanceThreshold_transferThreshold|...
+type TargetQtyRequest struct {
+	Data string `json:"data"`
+	Type int64  `json:"type"`
+}
+
+// Validate returns error if the target quantity is malformed or of unsupported tokens.
+func (tq TargetQtyRequest) Validate() error {
+	for _, dataConfig := range strings.Split(tq.Data, "|") {
+		dataParts := strings.Split(dataConfig, "_")
+		if tq.Type == 1 && len(dataParts) != 5 {
+			return errors.New("Data submitted not enough information")
+		}
+		if _, err := common.GetInternalToken(dataParts[0]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PWIEquationRequest is the PWI equation config of version 1, data follows
+// token_a_b_c|token_a_b_c|...
+type PWIEquationRequest struct {
+	Data string `json:"data"`
+}
+
+// Validate returns error if the equation is malformed or of unsupported tokens.
+func (input PWIEquationRequest) Validate() error {
+	for _, dataConfig := range strings.Split(input.Data, "|") {
+		dataParts := strings.Split(dataConfig, "_")
+		if len(dataParts) != 4 {
+			return errors.New("The input data is not correct")
+		}
+		if _, err := common.GetInternalToken(dataParts[0]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 //RebalanceControl represent status of rebalance, true is enable and false is disable
 type RebalanceControl struct {
 	Status bool `json:"status"`
@@ -168,3 +212,120 @@ func (rq RebalanceQuadraticRequest) Validate() error {
 	}
 	return nil
 }
+
+// StableTokenParamsRequest is the params of stable tokens, map[token]params.
+type StableTokenParamsRequest map[string]interface{}
+
+// Validate returns error if the params are not a JSON object.
+func (input StableTokenParamsRequest) Validate() error {
+	if input == nil {
+		return errors.New("stable token params must be an object")
+	}
+	return nil
+}
+
+const (
+	// maxStepsInFunction is the maximum number of steps a step function of pricing contract can have.
+	maxStepsInFunction = 10
+	// maxBpsAdjustment is the maximum absolute bps value of a step that pricing contract accepts.
+	maxBpsAdjustment = 100 * 100
+)
+
+// StepFunctionPoints contains the buy and sell sides of a pricing contract step function.
+// X values are token amounts in token wei (imbalance may be negative), Y values are bps.
+type StepFunctionPoints struct {
+	XBuy  []*big.Int `json:"x_buy"`
+	YBuy  []*big.Int `json:"y_buy"`
+	XSell []*big.Int `json:"x_sell"`
+	YSell []*big.Int `json:"y_sell"`
+}
+
+// validateSide checks a single side of a step function.
+func validateSide(x, y []*big.Int) error {
+	if len(x) != len(y) {
+		return fmt.Errorf("x and y must have the same length, got %d and %d", len(x), len(y))
+	}
+	if len(x) > maxStepsInFunction {
+		return fmt.Errorf("step function can have at most %d steps", maxStepsInFunction)
+	}
+	bpsLimit := big.NewInt(maxBpsAdjustment)
+	for i := range x {
+		if x[i] == nil || y[i] == nil {
+			return fmt.Errorf("missing value at step %d", i)
+		}
+		if i > 0 && x[i].Cmp(x[i-1]) <= 0 {
+			return fmt.Errorf("x values must be strictly increasing, step %d is not", i)
+		}
+		if new(big.Int).Abs(y[i]).Cmp(bpsLimit) > 0 {
+			return fmt.Errorf("y value %s at step %d is out of range [-%d, %d] bps", y[i].Text(10), i, maxBpsAdjustment, maxBpsAdjustment)
+		}
+	}
+	return nil
+}
+
+func (sf StepFunctionPoints) validate() error {
+	if err := validateSide(sf.XBuy, sf.YBuy); err != nil {
+		return fmt.Errorf("invalid buy step function: %s", err)
+	}
+	if err := validateSide(sf.XSell, sf.YSell); err != nil {
+		return fmt.Errorf("invalid sell step function: %s", err)
+	}
+	return nil
+}
+
+// TokenStepFunctions contains the qty and imbalance step functions of a token.
+// A nil step function is left unchanged on confirmation.
+type TokenStepFunctions struct {
+	Qty       *StepFunctionPoints `json:"qty,omitempty"`
+	Imbalance *StepFunctionPoints `json:"imbalance,omitempty"`
+}
+
+// StepFunctionSubmissions are the activities of step functions of the pending
+// step functions already submitted to pricing contract.
+// map[tokenID]map[qty or imbalance]activity ID
+type StepFunctionSubmissions map[string]map[string]common.ActivityID
+
+// Add records the activity of the stepType step function of a token.
+func (self StepFunctionSubmissions) Add(tokenID, stepType string, id common.ActivityID) {
+	if self[tokenID] == nil {
+		self[tokenID] = map[string]common.ActivityID{}
+	}
+	self[tokenID][stepType] = id
+}
+
+// StepFunctionsRequest is the input of SetStepFunctions api.
+// map[tokenID]step functions
+type StepFunctionsRequest map[string]TokenStepFunctions
+
+// Validate returns nil if the input is valid, otherwise it will
+// return an error with detail which field is invalid.
+// Example input:
+// {"KNC": {"qty": {"x_buy": [...], "y_buy": [...], "x_sell": [...], "y_sell": [...]}, "imbalance": {...}}}
+func (input StepFunctionsRequest) Validate() error {
+	if len(input) == 0 {
+		return errors.New("no step function is given")
+	}
+	for tokenID, sf := range input {
+		token, err := common.GetInternalToken(tokenID)
+		if err != nil {
+			return fmt.Errorf("unsupported token %s", tokenID)
+		}
+		if token.IsETH() {
+			return errors.New("ETH does not have step functions")
+		}
+		if sf.Qty == nil && sf.Imbalance == nil {
+			return fmt.Errorf("no step function is given for token %s", tokenID)
+		}
+		if sf.Qty != nil {
+			if err := sf.Qty.validate(); err != nil {
+				return fmt.Errorf("invalid qty step function for token %s: %s", tokenID, err)
+			}
+		}
+		if sf.Imbalance != nil {
+			if err := sf.Imbalance.validate(); err != nil {
+				return fmt.Errorf("invalid imbalance step function for token %s: %s", tokenID, err)
+			}
+		}
+	}
+	return nil
+}
@@ -644,6 +644,7 @@ func (self *Fetcher) GetReserveRates(
 	rates, err := self.blockchain.GetReserveRates(currentBlock-1, currentBlock, reserveAddr, tokens)
 	if err != nil {
 		log.Println(err.Error())
+		statFetchErrors.WithLabelValues(reserveRatesDataKind).Inc()
 	}
 	data.Store(reserveAddr, rates)
 }
@@ -667,6 +668,7 @@ func (self *Fetcher) ReserveSupportedTokens(reserve ethereum.Address) []common.T
 }
 
 func (self *Fetcher) FetchReserveRates(timepoint uint64) {
+	defer observeFetch(reserveRatesDataKind, time.Now(), nil)
 	log.Printf("Fetching reserve and sanity rate from blockchain")
 	supportedReserves := append(self.thirdPartyReserves, self.reserveAddress)
 	data := sync.Map{}
@@ -855,6 +857,16 @@ func (self *Fetcher) CheckDupAndStoreCatLog(l common.SetCatLog, timepoint uint64
 
 // FetchLogs return block number that we just fetched the logs
 func (self *Fetcher) FetchLogs(fromBlock uint64, toBlock uint64, timepoint uint64) (uint64, error) {
+	start := time.Now()
+	nextBlock, err := self.fetchLogs(fromBlock, toBlock, timepoint)
+	observeFetch(logsDataKind, start, err)
+	if err == nil {
+		statBlocks.WithLabelValues("logs").Set(float64(nextBlock))
+	}
+	return nextBlock, err
+}
+
+func (self *Fetcher) fetchLogs(fromBlock uint64, toBlock uint64, timepoint uint64) (uint64, error) {
 	logs, err := self.blockchain.GetLogs(fromBlock, toBlock)
 	if err != nil {
 		log.Printf("LogFetcher - fetching logs data from block %d failed, error: %v", fromBlock, err)
@@ -1238,7 +1250,9 @@ func (self *Fetcher) aggregateMetricStat(trade common.TradeLog, statKey string,
 }
 
 func (self *Fetcher) FetchCurrentBlock() {
+	start := time.Now()
 	block, err := self.blockchain.CurrentBlock()
+	observeFetch(blockDataKind, start, err)
 	if err != nil {
 		log.Printf("Fetching current block failed: %v. Ignored.", err)
 	} else {
@@ -1246,5 +1260,6 @@ func (self *Fetcher) FetchCurrentBlock() {
 		// where fetcher is trying to fetch new rate
 		self.currentBlockUpdateTime = common.GetTimepoint()
 		self.currentBlock = block
+		statBlocks.WithLabelValues("current").Set(float64(block))
 	}
 }
@@ -13,6 +13,9 @@ type LogStorage interface {
 
 	StoreTradeLog(stat common.TradeLog, timepoint uint64) error
 	GetTradeLogs(fromTime uint64, toTime uint64) ([]common.TradeLog, error)
+	// GetTradeLogPage returns at most limit trade logs selected by filter,
+	// which are older than cursor if it is not nil, the latest first.
+	GetTradeLogPage(filter common.TradeLogFilter, cursor *uint64, limit int) (common.TradeLogPage, error)
 	GetFirstTradeLog() (common.TradeLog, error)
 	GetLastTradeLog() (common.TradeLog, error)
 
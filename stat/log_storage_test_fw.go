@@ -113,6 +113,85 @@ func (self *LogStorageTest) TestTradeLog() error {
 	return err
 }
 
+func (self *LogStorageTest) TestTradeLogPage() error {
+	var (
+		reserve = ethereum.HexToAddress("0x63825c174ab367968ec60f061753d3bbd36a0d8f")
+		other   = ethereum.HexToAddress("0x21433dec9cb634a23c6a4bbcce08c83f5ac2ec18")
+		knc     = ethereum.HexToAddress("0xdd974d5c2e2928dea5f71b9825b8b646686bd200")
+		eth     = ethereum.HexToAddress("0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee")
+		omg     = ethereum.HexToAddress("0xd26114cd6ee289accf82350c8d8487fedb8a0c07")
+		user    = ethereum.HexToAddress(TESTUSERADDR)
+		wallet  = ethereum.HexToAddress("0xb9e29984fe50602e7a619662ebed4f90d93824c7")
+	)
+	for i, tradeLog := range []common.TradeLog{
+		{ReserveAddress: reserve, SrcAddress: eth, DestAddress: knc, UserAddress: user},
+		{ReserveAddress: other, SrcAddress: omg, DestAddress: eth, WalletAddress: wallet},
+		{ReserveAddress: reserve, SrcAddress: knc, DestAddress: eth, UserAddress: user, WalletAddress: wallet},
+		{ReserveAddress: reserve, SrcAddress: eth, DestAddress: omg},
+	} {
+		tradeLog.Timestamp = uint64(i+1) * 1000
+		tradeLog.BlockNumber = uint64(i + 1)
+		if err := self.storage.StoreTradeLog(tradeLog, tradeLog.Timestamp); err != nil {
+			return err
+		}
+	}
+	blocks := func(page common.TradeLogPage) []uint64 {
+		result := []uint64{}
+		for _, tradeLog := range page.TradeLogs {
+			result = append(result, tradeLog.BlockNumber)
+		}
+		return result
+	}
+	for _, tc := range []struct {
+		name     string
+		filter   common.TradeLogFilter
+		expected []uint64
+	}{
+		{"all logs", common.TradeLogFilter{}, []uint64{4, 3, 2, 1}},
+		{"time range", common.TradeLogFilter{FromTime: 2000, ToTime: 3000}, []uint64{3, 2}},
+		{"reserve", common.TradeLogFilter{Reserve: reserve}, []uint64{4, 3, 1}},
+		{"source or destination token", common.TradeLogFilter{Token: knc}, []uint64{3, 1}},
+		{"user", common.TradeLogFilter{User: user}, []uint64{3, 1}},
+		{"wallet", common.TradeLogFilter{Wallet: wallet}, []uint64{3, 2}},
+		{"many filters", common.TradeLogFilter{Reserve: reserve, Token: omg}, []uint64{4}},
+		{"no match", common.TradeLogFilter{Reserve: other, User: user}, []uint64{}},
+	} {
+		if tc.filter.ToTime == 0 {
+			tc.filter.ToTime = 10000
+		}
+		page, err := self.storage.GetTradeLogPage(tc.filter, nil, 10)
+		if err != nil {
+			return err
+		}
+		if fmt.Sprint(blocks(page)) != fmt.Sprint(tc.expected) || page.NextCursor != "" {
+			return fmt.Errorf("%s: expected trade logs of blocks %v on one page, got %v, next cursor %q", tc.name, tc.expected, blocks(page), page.NextCursor)
+		}
+	}
+	// walking pages of logs of the reserve
+	filter := common.TradeLogFilter{ToTime: 10000, Reserve: reserve}
+	pages := [][]uint64{}
+	var cursor *uint64
+	for {
+		page, err := self.storage.GetTradeLogPage(filter, cursor, 2)
+		if err != nil {
+			return err
+		}
+		pages = append(pages, blocks(page))
+		if page.NextCursor == "" {
+			break
+		}
+		timestamp, err := common.ParseTradeLogCursor(page.NextCursor)
+		if err != nil {
+			return err
+		}
+		cursor = &timestamp
+	}
+	if fmt.Sprint(pages) != "[[4 3] [1]]" {
+		return fmt.Errorf("expected pages [[4 3] [1]], got %v", pages)
+	}
+	return nil
+}
+
 func (self *LogStorageTest) TestUtil() error {
 	var err error
 	err = self.storage.UpdateLogBlock(222, 111)
@@ -0,0 +1,39 @@
+package stat
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Kinds of data fetched by the stat fetcher.
+const (
+	logsDataKind         = "logs"
+	reserveRatesDataKind = "reserve_rates"
+	blockDataKind        = "block"
+)
+
+var (
+	statFetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "reserve_stat_fetch_duration_seconds",
+		Help: "Duration of fetches of the stat fetcher.",
+	}, []string{"data"})
+	statFetchErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "reserve_stat_fetch_errors_total",
+		Help: "Number of failed fetches of the stat fetcher.",
+	}, []string{"data"})
+	statBlocks = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "reserve_stat_block",
+		Help: "Current block of the stat fetcher and the block logs are fetched to.",
+	}, []string{"type"})
+)
+
+// observeFetch records a fetch of a kind of data started at start, it failed
+// if err is not nil.
+func observeFetch(data string, start time.Time, err error) {
+	statFetchDuration.WithLabelValues(data).Observe(time.Since(start).Seconds())
+	if err != nil {
+		statFetchErrors.WithLabelValues(data).Inc()
+	}
+}
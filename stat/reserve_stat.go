@@ -187,6 +187,12 @@ func (self ReserveStats) GetTradeLogs(fromTime uint64, toTime uint64) ([]common.
 	return result, err
 }
 
+// GetTradeLogPage returns at most limit trade logs selected by filter, which
+// are older than cursor if it is not nil, the latest first.
+func (self ReserveStats) GetTradeLogPage(filter common.TradeLogFilter, cursor *uint64, limit int) (common.TradeLogPage, error) {
+	return self.logStorage.GetTradeLogPage(filter, cursor, limit)
+}
+
 func (self ReserveStats) GetGeoData(fromTime, toTime uint64, country string, tzparam int64) (common.StatTicks, error) {
 	var err error
 	result := common.StatTicks{}
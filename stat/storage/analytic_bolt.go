@@ -28,7 +28,7 @@ type BoltAnalyticStorage struct {
 func NewBoltAnalyticStorage(dbPath string) (*BoltAnalyticStorage, error) {
 	var err error
 	var db *bolt.DB
-	db, err = bolt.Open(dbPath, 0600, nil)
+	db, err = boltutil.Open(dbPath, AnalyticMigrations)
 	if err != nil {
 		panic(err)
 	}
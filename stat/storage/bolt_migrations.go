@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"encoding/json"
+
+	"github.com/KyberNetwork/reserve-data/boltutil"
+	"github.com/KyberNetwork/reserve-data/common"
+	"github.com/boltdb/bolt"
+)
+
+// Schema migrations of the stat bolt databases, applied when the storages are
+// created.
+var (
+	AnalyticMigrations = boltutil.Migrations{}
+	StatMigrations     = boltutil.Migrations{}
+	LogMigrations      = boltutil.Migrations{
+		{
+			Version:     1,
+			Description: "index trade logs by reserve, token, user and wallet",
+			Migrate:     indexTradeLogs,
+		},
+	}
+	RateMigrations       = boltutil.Migrations{}
+	UserMigrations       = boltutil.Migrations{}
+	FeeSetRateMigrations = boltutil.Migrations{}
+)
+
+// indexTradeLogs adds index entries of all trade logs.
+func indexTradeLogs(tx *bolt.Tx) error {
+	index, err := tx.CreateBucketIfNotExists([]byte(TRADELOG_INDEX_BUCKET))
+	if err != nil {
+		return err
+	}
+	b := tx.Bucket([]byte(TRADELOG_BUCKET))
+	if b == nil {
+		return nil
+	}
+	return b.ForEach(func(k, v []byte) error {
+		record := common.TradeLog{}
+		if err := json.Unmarshal(v, &record); err != nil {
+			return err
+		}
+		for _, key := range tradeLogIndexKeys(k, record) {
+			if err := index.Put(key, []byte{}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
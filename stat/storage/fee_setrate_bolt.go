@@ -31,7 +31,7 @@ type BoltFeeSetRateStorage struct {
 func NewBoltFeeSetRateStorage(path string) (*BoltFeeSetRateStorage, error) {
 	var err error
 	var db *bolt.DB
-	db, err = bolt.Open(path, 0600, nil)
+	db, err = boltutil.Open(path, FeeSetRateMigrations)
 	if err != nil {
 		panic(err)
 	}
@@ -10,12 +10,15 @@ import (
 	"github.com/KyberNetwork/reserve-data/boltutil"
 	"github.com/KyberNetwork/reserve-data/common"
 	"github.com/boltdb/bolt"
+	ethereum "github.com/ethereum/go-ethereum/common"
 )
 
 const (
 	MAX_GET_LOG_PERIOD uint64 = 86400000000000 //1 days in nanosecond
 	TRADELOG_BUCKET    string = "logs"
 	CATLOG_BUCKET      string = "cat_logs"
+	// TRADELOG_INDEX_BUCKET indexes trade logs by reserve, token, user and wallet
+	TRADELOG_INDEX_BUCKET string = "trade_log_index"
 )
 
 type BoltLogStorage struct {
@@ -30,7 +33,7 @@ func NewBoltLogStorage(path string) (*BoltLogStorage, error) {
 		err error
 		db  *bolt.DB
 	)
-	db, err = bolt.Open(path, 0600, nil)
+	db, err = boltutil.Open(path, LogMigrations)
 	if err != nil {
 		return nil, err
 	}
@@ -39,6 +42,9 @@ func NewBoltLogStorage(path string) (*BoltLogStorage, error) {
 		if _, uErr := tx.CreateBucketIfNotExists([]byte(TRADELOG_BUCKET)); uErr != nil {
 			return uErr
 		}
+		if _, uErr := tx.CreateBucketIfNotExists([]byte(TRADELOG_INDEX_BUCKET)); uErr != nil {
+			return uErr
+		}
 		_, uErr := tx.CreateBucketIfNotExists([]byte(CATLOG_BUCKET))
 		return uErr
 	})
@@ -197,6 +203,9 @@ func (self *BoltLogStorage) StoreTradeLog(stat common.TradeLog, timepoint uint64
 		}
 		// log.Printf("Storing log: %d", stat.Timestamp)
 		idByte := boltutil.Uint64ToBytes(stat.Timestamp)
+		if uErr = indexTradeLog(tx, idByte, stat); uErr != nil {
+			return uErr
+		}
 		return b.Put(idByte, dataJson)
 	})
 	return err
@@ -334,3 +343,124 @@ func (self *BoltLogStorage) LastBlock() (uint64, error) {
 	defer self.mu.RUnlock()
 	return self.block, nil
 }
+
+// Fields of the trade log index.
+const (
+	tradeLogReserveField = "reserve"
+	tradeLogTokenField   = "token"
+	tradeLogUserField    = "user"
+	tradeLogWalletField  = "wallet"
+)
+
+// tradeLogIndexKeys returns keys of index entries of the trade log stored at
+// key. Both source and destination tokens are indexed as token.
+func tradeLogIndexKeys(key []byte, l common.TradeLog) [][]byte {
+	return [][]byte{
+		boltutil.IndexKey(tradeLogReserveField, l.ReserveAddress.Hex(), key),
+		boltutil.IndexKey(tradeLogTokenField, l.SrcAddress.Hex(), key),
+		boltutil.IndexKey(tradeLogTokenField, l.DestAddress.Hex(), key),
+		boltutil.IndexKey(tradeLogUserField, l.UserAddress.Hex(), key),
+		boltutil.IndexKey(tradeLogWalletField, l.WalletAddress.Hex(), key),
+	}
+}
+
+// indexTradeLog replaces index entries of the trade log stored at key with
+// those of l, it must be called before l is stored.
+func indexTradeLog(tx *bolt.Tx, key []byte, l common.TradeLog) error {
+	index := tx.Bucket([]byte(TRADELOG_INDEX_BUCKET))
+	if v := tx.Bucket([]byte(TRADELOG_BUCKET)).Get(key); v != nil {
+		old := common.TradeLog{}
+		if err := json.Unmarshal(v, &old); err != nil {
+			return err
+		}
+		for _, k := range tradeLogIndexKeys(key, old) {
+			if err := index.Delete(k); err != nil {
+				return err
+			}
+		}
+	}
+	for _, k := range tradeLogIndexKeys(key, l) {
+		if err := index.Put(k, []byte{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tradeLogIndexOf returns the most selective index field of the filter, it
+// returns false if the filter has none.
+func tradeLogIndexOf(filter common.TradeLogFilter) (string, string, bool) {
+	var zero ethereum.Address
+	switch {
+	case filter.User != zero:
+		return tradeLogUserField, filter.User.Hex(), true
+	case filter.Wallet != zero:
+		return tradeLogWalletField, filter.Wallet.Hex(), true
+	case filter.Token != zero:
+		return tradeLogTokenField, filter.Token.Hex(), true
+	case filter.Reserve != zero:
+		return tradeLogReserveField, filter.Reserve.Hex(), true
+	}
+	return "", "", false
+}
+
+// GetTradeLogPage returns at most limit trade logs selected by filter, which
+// are older than cursor if it is not nil, the latest first. An index of the
+// filter is scanned if there is one, so logs out of the filter are not read.
+func (self *BoltLogStorage) GetTradeLogPage(filter common.TradeLogFilter, cursor *uint64, limit int) (common.TradeLogPage, error) {
+	result := common.TradeLogPage{TradeLogs: []common.TradeLog{}}
+	if limit <= 0 {
+		return result, errors.New("limit must be positive")
+	}
+	toTime := filter.ToTime
+	if cursor != nil {
+		if *cursor == 0 {
+			return result, nil
+		}
+		if *cursor-1 < toTime {
+			toTime = *cursor - 1
+		}
+	}
+	if toTime < filter.FromTime {
+		return result, nil
+	}
+	min := boltutil.Uint64ToBytes(filter.FromTime)
+	max := boltutil.Uint64ToBytes(toTime)
+	err := self.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(TRADELOG_BUCKET))
+		collect := func(v []byte) (bool, error) {
+			record := common.TradeLog{}
+			if err := json.Unmarshal(v, &record); err != nil {
+				return false, err
+			}
+			if filter.Match(record) {
+				result.TradeLogs = append(result.TradeLogs, record)
+			}
+			// one more log tells if there is a next page
+			return len(result.TradeLogs) <= limit, nil
+		}
+		field, value, ok := tradeLogIndexOf(filter)
+		if !ok {
+			return boltutil.ReverseScan(b.Cursor(), min, max, func(k, v []byte) (bool, error) {
+				return collect(v)
+			})
+		}
+		return boltutil.ReverseScan(
+			tx.Bucket([]byte(TRADELOG_INDEX_BUCKET)).Cursor(),
+			boltutil.IndexKey(field, value, min),
+			boltutil.IndexKey(field, value, max),
+			func(k, _ []byte) (bool, error) {
+				v := b.Get(boltutil.IndexedKey(field, value, k))
+				if v == nil {
+					return true, nil
+				}
+				return collect(v)
+			},
+		)
+	})
+	if len(result.TradeLogs) > limit {
+		result.TradeLogs = result.TradeLogs[:limit]
+		result.NextCursor = common.TradeLogCursor(result.TradeLogs[limit-1].Timestamp)
+	}
+	return result, err
+}
@@ -23,7 +23,7 @@ func NewBoltRateStorage(path string) (*BoltRateStorage, error) {
 	// init instance
 	var err error
 	var db *bolt.DB
-	db, err = bolt.Open(path, 0600, nil)
+	db, err = boltutil.Open(path, RateMigrations)
 	if err != nil {
 		return nil, err
 	}
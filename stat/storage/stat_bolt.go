@@ -58,7 +58,7 @@ func NewBoltStatStorage(path string) (*BoltStatStorage, error) {
 	// init instance
 	var err error
 	var db *bolt.DB
-	db, err = bolt.Open(path, 0600, nil)
+	db, err = boltutil.Open(path, StatMigrations)
 	if err != nil {
 		return nil, err
 	}
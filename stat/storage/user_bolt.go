@@ -29,7 +29,7 @@ type BoltUserStorage struct {
 func NewBoltUserStorage(path string) (*BoltUserStorage, error) {
 	var err error
 	var db *bolt.DB
-	db, err = bolt.Open(path, 0600, nil)
+	db, err = boltutil.Open(path, UserMigrations)
 	if db == nil {
 		return nil, err
 	}